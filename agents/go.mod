@@ -14,8 +14,11 @@ require (
 )
 
 require (
+	github.com/alicebob/miniredis/v2 v2.35.0
 	github.com/hibiken/asynq v0.25.1
 	github.com/lighthought/app-maker/shared-models v0.0.0
+	github.com/redis/go-redis/v9 v9.13.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -25,8 +28,8 @@ require (
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/joho/godotenv v1.5.1 // indirect
 	github.com/ollama/ollama v0.12.3 // indirect
-	github.com/redis/go-redis/v9 v9.13.0 // indirect
 	github.com/robfig/cron/v3 v3.0.1 // indirect
+	github.com/yuin/gopher-lua v1.1.1 // indirect
 	golang.org/x/sync v0.17.0 // indirect
 	golang.org/x/time v0.9.0 // indirect
 )
@@ -84,7 +87,6 @@ require (
 	google.golang.org/protobuf v1.36.5 // indirect
 	gopkg.in/ini.v1 v1.67.0 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
 replace github.com/lighthought/app-maker/shared-models => ../shared-models
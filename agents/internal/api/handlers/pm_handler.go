@@ -5,6 +5,7 @@ import (
 
 	"github.com/lighthought/app-maker/shared-models/agent"
 	"github.com/lighthought/app-maker/shared-models/common"
+	"github.com/lighthought/app-maker/shared-models/prompt"
 	"github.com/lighthought/app-maker/shared-models/utils"
 
 	"github.com/lighthought/app-maker/agents/internal/services"
@@ -15,11 +16,12 @@ import (
 // PmHandler 负责产品经理 Agent 的接口
 type PmHandler struct {
 	agentTaskService services.AgentTaskService
+	promptRegistry   prompt.Registry
 }
 
 // NewPmHandler 创建新的 PM Handler
-func NewPmHandler(agentTaskService services.AgentTaskService) *PmHandler {
-	return &PmHandler{agentTaskService: agentTaskService}
+func NewPmHandler(agentTaskService services.AgentTaskService, promptRegistry prompt.Registry) *PmHandler {
+	return &PmHandler{agentTaskService: agentTaskService, promptRegistry: promptRegistry}
 }
 
 // GetPRD godoc
@@ -40,23 +42,13 @@ func (s *PmHandler) GetPRD(c *gin.Context) {
 		return
 	}
 
-	// 根据 CLI 类型选择不同的 prompt
-	var agentPrompt string
-	if req.CliTool == common.CliToolGemini {
-		agentPrompt = "@.bmad-core/agents/pm.md"
-	} else {
-		agentPrompt = "@bmad/pm.mdc"
+	renderedPrompt, err := s.promptRegistry.Render(common.PromptNamePmPrd, req.Language, req.PromptOverride, &req)
+	if err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.ERROR_CODE, "渲染提示词失败: "+err.Error()))
+		return
 	}
 
-	message := agentPrompt + " 我希望你根据 @docs/analyse目录下的项目简介和市场研究，以及我的需求帮我输出 PRD.md 文档到 docs 目录下，用 UTF-8 格式编码。\n" +
-		"我的需求是：" + req.Requirements +
-		"注意：1. 始终用中文回答我，文件内容也使用中文（专有名词、代码片段和一些简单的英文除外）。" +
-		"2. 简化部署和运维、商业模式、成功指标、风险评估中的市场和运营风险。\n" +
-		"3. 技术选型我后续再和架构师深入讨论，主题颜色我后续再和 ux 专家讨论，不需要你在 PRD 中体现。\n" +
-		"4. 不需要你做额外的调查，也不要问我要不要创建文件，直接输出PRD到 docs/PRD.md 文件中。\n" +
-		"5. 如果 docs/ 目录下已经有完善的 PRD.md 文件，直接返回概要信息，不用再尝试生成 PRD.md，原来的文档保持不变。"
-
-	taskInfo, err := s.agentTaskService.EnqueueWithCli(req.ProjectGuid, common.AgentTypePM, message,
+	taskInfo, err := s.agentTaskService.EnqueueWithCli(req.ProjectGuid, common.AgentTypePM, renderedPrompt,
 		req.CliTool, common.DevStatusGeneratePRD)
 	if err != nil {
 		c.JSON(http.StatusOK, utils.GetErrorResponse(common.ERROR_CODE, "PRD 生成失败: "+err.Error()))
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/lighthought/app-maker/shared-models/agent"
 	"github.com/lighthought/app-maker/shared-models/common"
@@ -16,11 +17,15 @@ import (
 type ProjectHandler struct {
 	agentTaskService services.AgentTaskService
 	projectService   services.ProjectService
+	redisService     services.RedisService
+	sessionService   services.SessionService
 }
 
 // NewProjectHandler 创建 ProjectHandler
-func NewProjectHandler(agentTaskService services.AgentTaskService, projectService services.ProjectService) *ProjectHandler {
-	return &ProjectHandler{agentTaskService: agentTaskService, projectService: projectService}
+func NewProjectHandler(agentTaskService services.AgentTaskService, projectService services.ProjectService,
+	redisService services.RedisService, sessionService services.SessionService) *ProjectHandler {
+	return &ProjectHandler{agentTaskService: agentTaskService, projectService: projectService,
+		redisService: redisService, sessionService: sessionService}
 }
 
 // SetupProjectEnvironment godoc
@@ -49,3 +54,216 @@ func (h *ProjectHandler) SetupProjectEnvironment(c *gin.Context) {
 
 	c.JSON(http.StatusOK, utils.GetSuccessResponse("项目环境准备成功", taskInfo.ID))
 }
+
+// GetProjectLogs godoc
+// @Summary 获取项目 CLI 输出日志
+// @Description 获取项目最近的 Agent CLI 输出日志（环形缓冲区），用于客户端重连后补齐日志
+// @Tags Project
+// @Accept json
+// @Produce json
+// @Param guid path string true "项目GUID"
+// @Param task_id query string false "任务ID，为空时返回项目下所有任务的日志"
+// @Param limit query int false "返回的最大行数" default(500)
+// @Success 200 {object} common.Response{data=[]agent.AgentLogMessage} "成功响应"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/project/{guid}/logs [get]
+func (h *ProjectHandler) GetProjectLogs(c *gin.Context) {
+	projectGuid := c.Param("guid")
+	if projectGuid == "" {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.VALIDATION_ERROR, "项目GUID不能为空"))
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(common.AgentLogBufferSize)))
+	if err != nil {
+		limit = common.AgentLogBufferSize
+	}
+
+	logs, err := h.redisService.GetProjectLogs(projectGuid, c.Query("task_id"), limit)
+	if err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.ERROR_CODE, "获取项目日志失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("获取项目日志成功", logs))
+}
+
+// ListSessions godoc
+// @Summary 获取项目 Agent 会话列表
+// @Description 获取项目下各 Agent 的会话信息：原生会话ID或对话记录轮数
+// @Tags Project
+// @Accept json
+// @Produce json
+// @Param guid path string true "项目GUID"
+// @Success 200 {object} common.Response{data=[]agent.AgentSessionInfo} "成功响应"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/project/{guid}/sessions [get]
+func (h *ProjectHandler) ListSessions(c *gin.Context) {
+	projectGuid := c.Param("guid")
+	if projectGuid == "" {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.VALIDATION_ERROR, "项目GUID不能为空"))
+		return
+	}
+
+	sessions, err := h.sessionService.ListSessions(projectGuid)
+	if err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.ERROR_CODE, "获取会话列表失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("获取会话列表成功", sessions))
+}
+
+// ResetSession godoc
+// @Summary 重置项目 Agent 会话
+// @Description 清除 Agent 的原生会话ID和对话记录，agent_type 为空时重置项目下所有 Agent 的会话
+// @Tags Project
+// @Accept json
+// @Produce json
+// @Param guid path string true "项目GUID"
+// @Param agent_type query string false "Agent 类型"
+// @Success 200 {object} common.Response "成功响应"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/project/{guid}/sessions [delete]
+func (h *ProjectHandler) ResetSession(c *gin.Context) {
+	projectGuid := c.Param("guid")
+	if projectGuid == "" {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.VALIDATION_ERROR, "项目GUID不能为空"))
+		return
+	}
+
+	if err := h.sessionService.ResetSession(projectGuid, c.Query("agent_type")); err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.ERROR_CODE, "重置会话失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("重置会话成功", projectGuid))
+}
+
+// UpdateModelConfig godoc
+// @Summary 更新项目模型配置
+// @Description 更新项目使用的 CLI、模型提供商、模型、API 地址和 Token，后续执行 CLI 时注入到进程环境变量
+// @Tags Project
+// @Accept json
+// @Produce json
+// @Param guid path string true "项目GUID"
+// @Param request body agent.ProjectModelConfig true "项目模型配置"
+// @Success 200 {object} common.Response "成功响应"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/project/{guid}/model-config [post]
+func (h *ProjectHandler) UpdateModelConfig(c *gin.Context) {
+	projectGuid := c.Param("guid")
+	if projectGuid == "" {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.VALIDATION_ERROR, "项目GUID不能为空"))
+		return
+	}
+
+	var req agent.ProjectModelConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.VALIDATION_ERROR, "参数校验失败: "+err.Error()))
+		return
+	}
+
+	if err := h.redisService.SaveModelConfig(projectGuid, &req); err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.ERROR_CODE, "更新项目模型配置失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("更新项目模型配置成功", projectGuid))
+}
+
+// DeleteModelConfig godoc
+// @Summary 删除项目模型配置
+// @Description 删除项目保存的模型配置和 API Token，项目删除时由后端调用
+// @Tags Project
+// @Accept json
+// @Produce json
+// @Param guid path string true "项目GUID"
+// @Success 200 {object} common.Response "成功响应"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/project/{guid}/model-config [delete]
+func (h *ProjectHandler) DeleteModelConfig(c *gin.Context) {
+	projectGuid := c.Param("guid")
+	if projectGuid == "" {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.VALIDATION_ERROR, "项目GUID不能为空"))
+		return
+	}
+
+	if err := h.redisService.DeleteModelConfig(projectGuid); err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.ERROR_CODE, "删除项目模型配置失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("删除项目模型配置成功", projectGuid))
+}
+
+// GetGitHead godoc
+// @Summary 获取项目主干分支的最新提交
+// @Description 后端在阶段执行前后记录主干提交，用于回滚阶段
+// @Tags Project
+// @Accept json
+// @Produce json
+// @Param guid path string true "项目GUID"
+// @Success 200 {object} common.Response{data=agent.GitHeadInfo} "成功响应"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/project/{guid}/git/head [get]
+func (h *ProjectHandler) GetGitHead(c *gin.Context) {
+	projectGuid := c.Param("guid")
+	if projectGuid == "" {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.VALIDATION_ERROR, "项目GUID不能为空"))
+		return
+	}
+
+	head, err := h.projectService.GetGitHead(c.Request.Context(), projectGuid)
+	if err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.ERROR_CODE, "获取项目最新提交失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("获取项目最新提交成功", head))
+}
+
+// RollbackGit godoc
+// @Summary 回滚项目主干分支
+// @Description 持有项目工作区锁，把主干分支硬重置到指定提交并强制推送，删除所有阶段、故事分支
+// @Tags Project
+// @Accept json
+// @Produce json
+// @Param guid path string true "项目GUID"
+// @Param request body agent.GitRollbackReq true "回滚请求"
+// @Success 200 {object} common.Response{data=agent.GitHeadInfo} "成功响应"
+// @Failure 400 {object} common.Response "参数错误"
+// @Failure 409 {object} common.Response "项目工作区被其他任务占用"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/project/{guid}/git/rollback [post]
+func (h *ProjectHandler) RollbackGit(c *gin.Context) {
+	projectGuid := c.Param("guid")
+	if projectGuid == "" {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.VALIDATION_ERROR, "项目GUID不能为空"))
+		return
+	}
+
+	var req agent.GitRollbackReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.VALIDATION_ERROR, "参数校验失败: "+err.Error()))
+		return
+	}
+
+	head, err := h.projectService.RollbackGit(c.Request.Context(), projectGuid, req.CommitSha)
+	if err != nil {
+		if services.IsProjectLockedError(err) {
+			c.JSON(http.StatusOK, utils.GetErrorResponse(common.CONFLICT, err.Error()))
+			return
+		}
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.ERROR_CODE, "回滚项目失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("回滚项目成功", head))
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/common"
+	"github.com/lighthought/app-maker/shared-models/prompt"
+	"github.com/lighthought/app-maker/shared-models/utils"
+
+	"github.com/lighthought/app-maker/agents/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type QaHandler struct {
+	agentTaskService services.AgentTaskService
+	promptRegistry   prompt.Registry
+}
+
+func NewQaHandler(agentTaskService services.AgentTaskService, promptRegistry prompt.Registry) *QaHandler {
+	return &QaHandler{agentTaskService: agentTaskService, promptRegistry: promptRegistry}
+}
+
+// Review godoc
+// @Summary 评审用户故事
+// @Description QA 按验收标准评审用户故事，把 pass、concerns 或 fail 的门禁结论写入 docs/qa/gates/<story-number>.yml，任务结果的 qa_gate 字段为解析后的结论
+// @Tags QA
+// @Accept json
+// @Produce json
+// @Param request body agent.QAReviewReq true "评审故事请求"
+// @Success 200 {object} common.Response "成功响应"
+// @Failure 400 {object} common.ErrorResponse "参数错误"
+// @Failure 500 {object} common.ErrorResponse "服务器错误"
+// @Router /api/v1/agent/qa/review [post]
+func (h *QaHandler) Review(c *gin.Context) {
+	var req agent.QAReviewReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.ERROR_CODE, "参数校验失败: "+err.Error()))
+		return
+	}
+	req.GateFile = agent.QAGateFilePath(req.StoryNumber)
+
+	renderedPrompt, err := h.promptRegistry.Render(common.PromptNameQAReview, req.Language, req.PromptOverride, &req)
+	if err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.ERROR_CODE, "渲染提示词失败: "+err.Error()))
+		return
+	}
+
+	taskInfo, err := h.agentTaskService.EnqueueStoryWithCli(req.ProjectGuid, common.AgentTypeQA, renderedPrompt,
+		req.CliTool, common.DevStatusQAReview, req.StoryNumber)
+	if err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.ERROR_CODE, "评审用户故事任务失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("评审用户故事任务创建成功", taskInfo.ID))
+}
+
+// TestPlan godoc
+// @Summary 生成测试计划
+// @Description QA 基于PRD、架构设计和用户故事生成测试计划，输出到 docs/qa/test-plan.md
+// @Tags QA
+// @Accept json
+// @Produce json
+// @Param request body agent.QATestPlanReq true "测试计划请求"
+// @Success 200 {object} common.Response "成功响应"
+// @Failure 400 {object} common.ErrorResponse "参数错误"
+// @Failure 500 {object} common.ErrorResponse "服务器错误"
+// @Router /api/v1/agent/qa/test-plan [post]
+func (h *QaHandler) TestPlan(c *gin.Context) {
+	var req agent.QATestPlanReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.ERROR_CODE, "参数校验失败: "+err.Error()))
+		return
+	}
+
+	renderedPrompt, err := h.promptRegistry.Render(common.PromptNameQATestPlan, req.Language, req.PromptOverride, &req)
+	if err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.ERROR_CODE, "渲染提示词失败: "+err.Error()))
+		return
+	}
+
+	// 测试计划不属于开发流程的阶段，按对话处理
+	taskInfo, err := h.agentTaskService.EnqueueWithCli(req.ProjectGuid, common.AgentTypeQA, renderedPrompt,
+		req.CliTool, common.DevStatusUnknown)
+	if err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.ERROR_CODE, "生成测试计划任务失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("生成测试计划任务创建成功", taskInfo.ID))
+}
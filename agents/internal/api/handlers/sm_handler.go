@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/common"
+	"github.com/lighthought/app-maker/shared-models/prompt"
+	"github.com/lighthought/app-maker/shared-models/utils"
+
+	"github.com/lighthought/app-maker/agents/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SmHandler struct {
+	agentTaskService services.AgentTaskService
+	promptRegistry   prompt.Registry
+}
+
+func NewSmHandler(agentTaskService services.AgentTaskService, promptRegistry prompt.Registry) *SmHandler {
+	return &SmHandler{agentTaskService: agentTaskService, promptRegistry: promptRegistry}
+}
+
+// DraftStory godoc
+// @Summary 起草用户故事
+// @Description SM 为一个用户故事起草可以独立开发的故事文件，包括任务、开发说明和文件引用，写入 docs/stories/<story-number>.story.md
+// @Tags SM
+// @Accept json
+// @Produce json
+// @Param request body agent.DraftStoryReq true "起草故事请求"
+// @Success 200 {object} common.Response "成功响应"
+// @Failure 400 {object} common.ErrorResponse "参数错误"
+// @Failure 500 {object} common.ErrorResponse "服务器错误"
+// @Router /api/v1/agent/sm/draft-story [post]
+func (h *SmHandler) DraftStory(c *gin.Context) {
+	var req agent.DraftStoryReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.ERROR_CODE, "参数校验失败: "+err.Error()))
+		return
+	}
+	req.StoryFile = agent.StoryFilePath(req.StoryNumber)
+
+	renderedPrompt, err := h.promptRegistry.Render(common.PromptNameSMDraftStory, req.Language, req.PromptOverride, &req)
+	if err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.ERROR_CODE, "渲染提示词失败: "+err.Error()))
+		return
+	}
+
+	taskInfo, err := h.agentTaskService.EnqueueStoryWithCli(req.ProjectGuid, common.AgentTypeSM, renderedPrompt,
+		req.CliTool, common.DevStatusDraftStory, req.StoryNumber)
+	if err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.ERROR_CODE, "起草用户故事任务失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("起草用户故事任务创建成功", taskInfo.ID))
+}
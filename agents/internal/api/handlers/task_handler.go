@@ -4,34 +4,77 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/lighthought/app-maker/shared-models/agent"
 	"github.com/lighthought/app-maker/shared-models/common"
 	"github.com/lighthought/app-maker/shared-models/logger"
 	"github.com/lighthought/app-maker/shared-models/tasks"
 	"github.com/lighthought/app-maker/shared-models/utils"
 
+	"github.com/lighthought/app-maker/agents/internal/services"
+
 	"github.com/gin-gonic/gin"
 	"github.com/hibiken/asynq"
 )
 
 // TaskHandler 任务处理器
 type TaskHandler struct {
-	inspector *asynq.Inspector
+	inspector          *asynq.Inspector
+	agentTaskService   services.AgentTaskService
+	projectLockService services.ProjectLockService
+	taskInspectService services.TaskInspectService
 }
 
 // NewTaskHandler 创建任务处理器实例
-func NewTaskHandler(inspector *asynq.Inspector) *TaskHandler {
+func NewTaskHandler(inspector *asynq.Inspector, agentTaskService services.AgentTaskService,
+	projectLockService services.ProjectLockService, taskInspectService services.TaskInspectService) *TaskHandler {
 	if inspector == nil {
 		logger.Error("inspector is nil!")
 		return nil
 	}
 	return &TaskHandler{
-		inspector: inspector,
+		inspector:          inspector,
+		agentTaskService:   agentTaskService,
+		projectLockService: projectLockService,
+		taskInspectService: taskInspectService,
+	}
+}
+
+// ListTasks godoc
+// @Summary 获取任务列表
+// @Description 返回所有队列的统计，以及按状态、队列、项目、Agent 类型、任务类型筛选的任务，负载中的密钥已脱敏
+// @Tags Task
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param state query string false "任务状态：pending、active、scheduled、retry、archived、completed"
+// @Param queue query string false "队列名：critical、default、low"
+// @Param project_guid query string false "项目GUID"
+// @Param agent_type query string false "Agent 类型"
+// @Param type query string false "任务类型"
+// @Param limit query int false "最多返回的任务数，默认100，最大500"
+// @Success 200 {object} common.Response{data=agent.TaskListResp} "成功响应"
+// @Failure 400 {object} common.ErrorResponse "参数错误"
+// @Failure 500 {object} common.ErrorResponse "服务器内部错误"
+// @Router /api/v1/tasks [get]
+func (s *TaskHandler) ListTasks(c *gin.Context) {
+	var req agent.TaskListReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.VALIDATION_ERROR, "参数校验失败: "+err.Error()))
+		return
+	}
+
+	resp, err := s.taskInspectService.ListTasks(&req)
+	if err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "获取任务列表失败: "+err.Error()))
+		return
 	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("获取任务列表成功", resp))
 }
 
 // GetTaskStatus godoc
 // @Summary 获取任务状态
-// @Description 获取任务状态
+// @Description 获取任务状态，任务所属项目的工作区锁被占用时返回锁的持有者
 // @Tags Task
 // @Accept json
 // @Produce json
@@ -44,26 +87,10 @@ func NewTaskHandler(inspector *asynq.Inspector) *TaskHandler {
 func (s *TaskHandler) GetTaskStatus(c *gin.Context) {
 	taskID := c.Param("id")
 
-	// 查询任务信息
-	info, err := s.inspector.GetTaskInfo("default", taskID) // "default" 是队列名
+	// 任务按类型进入不同的队列，在所有队列中查找
+	info, err := tasks.FindTaskInQueues(s.inspector, taskID)
 	if err != nil {
-		completedTasks, err := s.inspector.ListCompletedTasks("default")
-		if err != nil {
-			c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "获取任务状态失败: "+err.Error()))
-			return
-		}
-
-		for _, task := range completedTasks {
-			if task.ID == taskID {
-				logger.Info("任务已完成", logger.String("taskID", taskID))
-				info = task
-				break
-			}
-		}
-	}
-
-	if info == nil {
-		c.JSON(http.StatusNotFound, utils.GetErrorResponse(common.NOT_FOUND, "任务不存在, "+err.Error()))
+		c.JSON(http.StatusNotFound, utils.GetErrorResponse(common.NOT_FOUND, err.Error()))
 		return
 	}
 
@@ -73,21 +100,131 @@ func (s *TaskHandler) GetTaskStatus(c *gin.Context) {
 		Progress: 0,
 		Message:  "任务执行中",
 	}
-	if info.Result == nil {
-		c.JSON(http.StatusOK, utils.GetSuccessResponse("获取任务状态成功", taskResult))
+	if len(info.Result) > 0 {
+		if err := json.Unmarshal(info.Result, &taskResult); err != nil {
+			c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "解析任务结果失败: "+err.Error()))
+			return
+		}
+	}
+	taskResult.LockHolder = s.getLockHolder(info)
+	taskResult.Prompt = getTaskPrompt(info)
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("获取任务状态成功", taskResult))
+}
+
+// CancelTask godoc
+// @Summary 取消任务
+// @Description 取消任务：执行中的任务会杀掉对应的 CLI 进程组，排队中的任务直接删除，并发布 cancelled 状态
+// @Tags Task
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "任务ID"
+// @Success 200 {object} common.Response "成功响应"
+// @Failure 500 {object} common.ErrorResponse "服务器内部错误"
+// @Router /api/v1/tasks/{id}/cancel [post]
+func (s *TaskHandler) CancelTask(c *gin.Context) {
+	taskID := c.Param("id")
+
+	if _, err := s.agentTaskService.CancelTask(c.Request.Context(), taskID); err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.ERROR_CODE, "取消任务失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("取消任务成功", taskID))
+}
+
+// DeleteTask godoc
+// @Summary 删除任务
+// @Description 删除未在执行的任务，不发布状态，执行中的任务需要先取消
+// @Tags Task
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "任务ID"
+// @Param queue query string false "队列名，为空时在所有队列中查找"
+// @Success 200 {object} common.Response "成功响应"
+// @Failure 500 {object} common.ErrorResponse "服务器内部错误"
+// @Router /api/v1/tasks/{id} [delete]
+func (s *TaskHandler) DeleteTask(c *gin.Context) {
+	taskID := c.Param("id")
+
+	if err := s.taskInspectService.DeleteTask(taskID, c.Query("queue")); err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.ERROR_CODE, "删除任务失败: "+err.Error()))
 		return
 	}
 
-	if len(info.Result) == 0 {
-		c.JSON(http.StatusOK, utils.GetSuccessResponse("获取任务状态成功", taskResult))
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("删除任务成功", taskID))
+}
+
+// ArchiveTask godoc
+// @Summary 归档任务
+// @Description 归档未在执行的任务，归档后不再执行，可以通过立即执行恢复
+// @Tags Task
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "任务ID"
+// @Param queue query string false "队列名，为空时在所有队列中查找"
+// @Success 200 {object} common.Response "成功响应"
+// @Failure 500 {object} common.ErrorResponse "服务器内部错误"
+// @Router /api/v1/tasks/{id}/archive [post]
+func (s *TaskHandler) ArchiveTask(c *gin.Context) {
+	taskID := c.Param("id")
+
+	if err := s.taskInspectService.ArchiveTask(taskID, c.Query("queue")); err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.ERROR_CODE, "归档任务失败: "+err.Error()))
 		return
 	}
 
-	err = json.Unmarshal(info.Result, &taskResult)
-	if err != nil {
-		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "解析任务结果失败: "+err.Error()))
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("归档任务成功", taskID))
+}
+
+// RunTask godoc
+// @Summary 立即执行任务
+// @Description 把等待调度、等待重试或已归档的任务移入排队，尽快执行
+// @Tags Task
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "任务ID"
+// @Param queue query string false "队列名，为空时在所有队列中查找"
+// @Success 200 {object} common.Response "成功响应"
+// @Failure 500 {object} common.ErrorResponse "服务器内部错误"
+// @Router /api/v1/tasks/{id}/run [post]
+func (s *TaskHandler) RunTask(c *gin.Context) {
+	taskID := c.Param("id")
+
+	if err := s.taskInspectService.RunTask(taskID, c.Query("queue")); err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.ERROR_CODE, "立即执行任务失败: "+err.Error()))
 		return
 	}
 
-	c.JSON(http.StatusOK, utils.GetSuccessResponse("获取任务状态成功", taskResult))
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("立即执行任务成功", taskID))
+}
+
+// getTaskPrompt 获取任务负载上记录的提示词
+func getTaskPrompt(info *asynq.TaskInfo) *agent.RenderedPrompt {
+	var payload struct {
+		Prompt *agent.RenderedPrompt `json:"prompt"`
+	}
+	if err := json.Unmarshal(info.Payload, &payload); err != nil {
+		return nil
+	}
+	return payload.Prompt
+}
+
+// getLockHolder 获取任务所属项目的工作区锁持有者，任务未结束时才返回
+func (s *TaskHandler) getLockHolder(info *asynq.TaskInfo) *agent.ProjectLockInfo {
+	if s.projectLockService == nil || info.State == asynq.TaskStateCompleted || info.State == asynq.TaskStateArchived {
+		return nil
+	}
+
+	var payload struct {
+		ProjectGuid string `json:"project_guid"`
+	}
+	if err := json.Unmarshal(info.Payload, &payload); err != nil || payload.ProjectGuid == "" {
+		return nil
+	}
+	return s.projectLockService.GetHolder(payload.ProjectGuid)
 }
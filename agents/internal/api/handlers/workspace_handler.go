@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/lighthought/app-maker/shared-models/common"
+	"github.com/lighthought/app-maker/shared-models/utils"
+
+	"github.com/lighthought/app-maker/agents/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WorkspaceHandler 项目工作区处理器
+type WorkspaceHandler struct {
+	workspaceService services.WorkspaceService
+}
+
+// NewWorkspaceHandler 创建项目工作区处理器
+func NewWorkspaceHandler(workspaceService services.WorkspaceService) *WorkspaceHandler {
+	return &WorkspaceHandler{
+		workspaceService: workspaceService,
+	}
+}
+
+// ListWorkspaces godoc
+// @Summary 获取项目工作区列表
+// @Description 返回每个项目工作区的磁盘占用、最近活动时间和回收状态，以及磁盘总占用和预算
+// @Tags Workspace
+// @Accept json
+// @Produce json
+// @Success 200 {object} common.Response{data=agent.WorkspaceListResp} "成功响应"
+// @Failure 500 {object} common.Response "服务器错误"
+// @Router /api/v1/workspaces [get]
+func (h *WorkspaceHandler) ListWorkspaces(c *gin.Context) {
+	resp, err := h.workspaceService.ListWorkspaces(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.ERROR_CODE, "获取项目工作区列表失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("获取项目工作区列表成功", resp))
+}
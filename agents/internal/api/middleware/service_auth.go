@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/lighthought/app-maker/shared-models/auth"
+	"github.com/lighthought/app-maker/shared-models/common"
+	"github.com/lighthought/app-maker/shared-models/logger"
+	"github.com/lighthought/app-maker/shared-models/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 签名请求体的最大字节数，超出时拒绝，避免未认证的请求占用内存
+const maxSignedBodyBytes = 16 << 20
+
+// ServiceAuthMiddleware 校验后端调用 Agents API 的服务签名，没有配置签名密钥时不校验
+func ServiceAuthMiddleware(verifier *auth.RequestVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !verifier.Enabled() {
+			c.Next()
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil {
+			var err error
+			body, err = io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxSignedBodyBytes))
+			if err != nil {
+				c.JSON(http.StatusRequestEntityTooLarge, utils.GetErrorResponse(common.ERROR_CODE, "读取请求体失败: "+err.Error()))
+				c.Abort()
+				return
+			}
+			// 请求体已读取，重新放回供处理器绑定参数
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		keyID, err := verifier.Verify(c.Request, body)
+		if err != nil {
+			logger.Warn("服务签名校验失败",
+				logger.String("path", c.Request.URL.Path),
+				logger.String("clientIP", c.ClientIP()),
+				logger.String("keyID", c.GetHeader(common.HeaderServiceKeyID)),
+				logger.String("error", err.Error()))
+			c.JSON(http.StatusUnauthorized, utils.GetErrorResponse(common.UNAUTHORIZED, err.Error()))
+			c.Abort()
+			return
+		}
+
+		c.Set("service_key_id", keyID)
+		c.Next()
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lighthought/app-maker/shared-models/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newServiceAuthEngine(verifier *auth.RequestVerifier) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.POST("/api/v1/agent/chat", ServiceAuthMiddleware(verifier), func(c *gin.Context) {
+		// 处理器仍能读取完整的请求体
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, string(body))
+	})
+	return engine
+}
+
+func TestServiceAuthMiddleware(t *testing.T) {
+	verifier := auth.NewRequestVerifier([]auth.ServiceKey{{ID: "k1", Secret: "secret-1"}}, 0)
+	engine := newServiceAuthEngine(verifier)
+	body := `{"project_guid":"p1"}`
+
+	unsigned := httptest.NewRequest(http.MethodPost, "/api/v1/agent/chat", strings.NewReader(body))
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, unsigned)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("unsigned status = %d, want 401", recorder.Code)
+	}
+
+	signed := httptest.NewRequest(http.MethodPost, "/api/v1/agent/chat", strings.NewReader(body))
+	if err := auth.NewRequestSigner("k1", "secret-1").Sign(signed, []byte(body)); err != nil {
+		t.Fatal(err)
+	}
+	recorder = httptest.NewRecorder()
+	engine.ServeHTTP(recorder, signed)
+	if recorder.Code != http.StatusOK || recorder.Body.String() != body {
+		t.Errorf("signed status = %d, body = %q", recorder.Code, recorder.Body.String())
+	}
+
+	// 没有配置密钥时不校验
+	recorder = httptest.NewRecorder()
+	newServiceAuthEngine(auth.NewRequestVerifier(nil, 0)).ServeHTTP(recorder,
+		httptest.NewRequest(http.MethodPost, "/api/v1/agent/chat", strings.NewReader(body)))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("disabled status = %d, want 200", recorder.Code)
+	}
+}
@@ -1,6 +1,10 @@
 package models
 
-import "encoding/json"
+import (
+	"encoding/json"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+)
 
 // CommandResult 命令执行结果
 type CommandResult struct {
@@ -11,19 +15,40 @@ type CommandResult struct {
 
 // claude 命令的 json 输出结果
 type ClaudeResponse struct {
-	Type          string `json:"type"`
-	Result        string `json:"result"`
-	Subtype       string `json:"subtype"`
-	IsError       bool   `json:"is_error"`
-	DurationMs    int    `json:"duration_ms"`
-	DurationApiMs int    `json:"duration_api_ms"`
-	SessionID     string `json:"session_id"`
+	Type          string  `json:"type"`
+	Result        string  `json:"result"`
+	Subtype       string  `json:"subtype"`
+	IsError       bool    `json:"is_error"`
+	DurationMs    int     `json:"duration_ms"`
+	DurationApiMs int     `json:"duration_api_ms"`
+	SessionID     string  `json:"session_id"`
+	NumTurns      int     `json:"num_turns"`
+	TotalCostUsd  float64 `json:"total_cost_usd"`
 	Usage         struct {
-		InputTokens  int `json:"input_tokens"`
-		OutputTokens int `json:"output_tokens"`
+		InputTokens              int `json:"input_tokens"`
+		OutputTokens             int `json:"output_tokens"`
+		CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+		CacheReadInputTokens     int `json:"cache_read_input_tokens"`
 	} `json:"usage"`
 }
 
+// ToAgentUsage 转换为上报给后端的用量，CLI 没有输出用量时返回 nil
+func (c *ClaudeResponse) ToAgentUsage(cliTool string) *agent.AgentUsage {
+	if c.TotalCostUsd == 0 && c.Usage.InputTokens == 0 && c.Usage.OutputTokens == 0 &&
+		c.Usage.CacheCreationInputTokens == 0 && c.Usage.CacheReadInputTokens == 0 {
+		return nil
+	}
+	return &agent.AgentUsage{
+		CliTool:                  cliTool,
+		InputTokens:              c.Usage.InputTokens,
+		OutputTokens:             c.Usage.OutputTokens,
+		CacheCreationInputTokens: c.Usage.CacheCreationInputTokens,
+		CacheReadInputTokens:     c.Usage.CacheReadInputTokens,
+		CostUsd:                  c.TotalCostUsd,
+		DurationMs:               c.DurationMs,
+	}
+}
+
 func (c *ClaudeResponse) ToJsonString() string {
 	json, err := json.Marshal(c)
 	if err != nil {
@@ -2,6 +2,7 @@ package routes
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/lighthought/app-maker/agents/internal/api/middleware"
 	"github.com/lighthought/app-maker/agents/internal/container"
 	"github.com/lighthought/app-maker/shared-models/common"
 )
@@ -57,6 +58,16 @@ func registerAgentApiRoutes(routers *gin.RouterGroup, container *container.Conta
 			}
 		}
 
+		sm := agent.Group("/sm") // Scrum Master Agent
+		{
+			smHandler := container.SmHandler
+			if smHandler != nil {
+				sm.POST("/draft-story", smHandler.DraftStory) // 起草故事文件
+			} else {
+				setPostEmptyEndpoint(sm, "/draft-story", "Sm draft story endpoint - TODO")
+			}
+		}
+
 		dev := agent.Group("/dev") // 开发 Agent
 		{
 			devHandler := container.DevHandler
@@ -73,6 +84,18 @@ func registerAgentApiRoutes(routers *gin.RouterGroup, container *container.Conta
 			}
 		}
 
+		qa := agent.Group("/qa") // 测试和质量工程师 Agent
+		{
+			qaHandler := container.QaHandler
+			if qaHandler != nil {
+				qa.POST("/review", qaHandler.Review)      // 按验收标准评审故事
+				qa.POST("/test-plan", qaHandler.TestPlan) // 生成测试计划
+			} else {
+				setPostEmptyEndpoint(qa, "/review", "Qa review story endpoint - TODO")
+				setPostEmptyEndpoint(qa, "/test-plan", "Qa test plan endpoint - TODO")
+			}
+		}
+
 		architect := agent.Group("/architect") // 架构师 Agent
 		{
 			architectHandler := container.ArchitectHandler
@@ -101,30 +124,76 @@ func registerAgentApiRoutes(routers *gin.RouterGroup, container *container.Conta
 
 // Register 注册路由
 func Register(engine *gin.Engine, container *container.Container) {
-	routers := engine.Group(common.DefaultApiPrefix)
+	// 健康检查不校验服务签名，供容器和监控探测
+	engine.Group(common.DefaultApiPrefix).GET("/health", container.HealthHandler.CheckHealth)
+
+	// 其余接口可以在项目工作区执行提示词和命令，只接受后端签名的请求
+	routers := engine.Group(common.DefaultApiPrefix, middleware.ServiceAuthMiddleware(container.ServiceAuth))
 	{
 		routers.GET("/version", container.HealthHandler.CheckVersion)
-		routers.GET("/health", container.HealthHandler.CheckHealth)
 
 		projectHandler := container.ProjectHandler
 		project := routers.Group("/project") // 项目API路由
 		{
 			if projectHandler != nil {
-				project.POST("/setup", projectHandler.SetupProjectEnvironment) // 准备项目环境
+				project.POST("/setup", projectHandler.SetupProjectEnvironment)          // 准备项目环境
+				project.GET("/:guid/logs", projectHandler.GetProjectLogs)               // 获取项目 CLI 输出日志
+				project.GET("/:guid/sessions", projectHandler.ListSessions)             // 获取项目 Agent 会话列表
+				project.DELETE("/:guid/sessions", projectHandler.ResetSession)          // 重置项目 Agent 会话
+				project.POST("/:guid/model-config", projectHandler.UpdateModelConfig)   // 更新项目模型配置
+				project.DELETE("/:guid/model-config", projectHandler.DeleteModelConfig) // 删除项目模型配置
+				project.GET("/:guid/git/head", projectHandler.GetGitHead)               // 获取主干分支最新提交
+				project.POST("/:guid/git/rollback", projectHandler.RollbackGit)         // 回滚主干分支
 			} else {
 				setPostEmptyEndpoint(project, "/setup", "Project setup endpoint - TODO")
+				project.GET("/:guid/logs", func(c *gin.Context) {
+					c.JSON(200, gin.H{"message": "Project logs endpoint - TODO"})
+				})
+				project.GET("/:guid/sessions", func(c *gin.Context) {
+					c.JSON(200, gin.H{"message": "Project sessions endpoint - TODO"})
+				})
+				setPostEmptyEndpoint(project, "/:guid/model-config", "Project model config endpoint - TODO")
+				project.DELETE("/:guid/model-config", func(c *gin.Context) {
+					c.JSON(200, gin.H{"message": "Project delete model config endpoint - TODO"})
+				})
+				project.GET("/:guid/git/head", func(c *gin.Context) {
+					c.JSON(200, gin.H{"message": "Project git head endpoint - TODO"})
+				})
+				setPostEmptyEndpoint(project, "/:guid/git/rollback", "Project git rollback endpoint - TODO")
 			}
 		}
 
+		if container.WorkspaceHandler != nil {
+			routers.GET("/workspaces", container.WorkspaceHandler.ListWorkspaces) // 获取项目工作区列表
+		} else {
+			routers.GET("/workspaces", func(c *gin.Context) {
+				c.JSON(200, gin.H{"message": "Workspace list endpoint - TODO"})
+			})
+		}
+
 		var taskHandler = container.TaskHandler
 		tasks := routers.Group("/tasks") // 异步任务路由
 		{
 			if taskHandler != nil {
-				tasks.GET("/:id", taskHandler.GetTaskStatus) // 获取任务状
+				tasks.GET("", taskHandler.ListTasks)                // 获取任务列表
+				tasks.GET("/:id", taskHandler.GetTaskStatus)        // 获取任务状
+				tasks.POST("/:id/cancel", taskHandler.CancelTask)   // 取消任务
+				tasks.DELETE("/:id", taskHandler.DeleteTask)        // 删除任务
+				tasks.POST("/:id/archive", taskHandler.ArchiveTask) // 归档任务
+				tasks.POST("/:id/run", taskHandler.RunTask)         // 立即执行任务
 			} else {
+				tasks.GET("", func(c *gin.Context) {
+					c.JSON(200, gin.H{"message": "Task list endpoint - TODO"})
+				})
 				tasks.GET("/:id", func(c *gin.Context) {
 					c.JSON(200, gin.H{"message": "Task status endpoint - TODO"})
 				})
+				setPostEmptyEndpoint(tasks, "/:id/cancel", "Task cancel endpoint - TODO")
+				tasks.DELETE("/:id", func(c *gin.Context) {
+					c.JSON(200, gin.H{"message": "Task delete endpoint - TODO"})
+				})
+				setPostEmptyEndpoint(tasks, "/:id/archive", "Task archive endpoint - TODO")
+				setPostEmptyEndpoint(tasks, "/:id/run", "Task run endpoint - TODO")
 			}
 		}
 
@@ -1,8 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"time"
 
+	"github.com/lighthought/app-maker/shared-models/auth"
 	"github.com/lighthought/app-maker/shared-models/common"
 	"github.com/lighthought/app-maker/shared-models/utils"
 
@@ -32,8 +34,47 @@ type RedisConfig struct {
 
 // CommandConfig 命令配置
 type CommandConfig struct {
-	Timeout time.Duration `mapstructure:"timeout"`  // 超时时间
-	CliTool string        `mapstructure:"cli_tool"` // 命令行工具
+	Timeout          time.Duration `mapstructure:"timeout"`            // 超时时间
+	CliTool          string        `mapstructure:"cli_tool"`           // 命令行工具
+	MockFixturesPath string        `mapstructure:"mock_fixtures_path"` // mock CLI 剧本目录，为空时使用内置剧本
+	FixMaxAttempts   int           `mapstructure:"fix_max_attempts"`   // 部署命令失败时 Dev Agent 修复的最大次数，0 表示不修复
+}
+
+// PromptConfig 提示词模板配置
+type PromptConfig struct {
+	TemplatesPath string `mapstructure:"templates_path"` // 自定义提示词模板目录，同名模板覆盖内置模板，为空时只使用内置模板
+}
+
+// GitWorkflowConfig 阶段、故事分支工作流配置
+type GitWorkflowConfig struct {
+	Provider       string        `mapstructure:"provider"`        // 合并请求提供方：gitlab、local，默认 local
+	BaseBranch     string        `mapstructure:"base_branch"`     // 主干分支，为空时自动检测 master、main
+	GitlabURL      string        `mapstructure:"gitlab_url"`      // GitLab 地址，如 http://gitlab.app-maker.localhost
+	GitlabToken    string        `mapstructure:"gitlab_token"`    // GitLab 访问令牌，需要 api 权限
+	VerifyCommands []string      `mapstructure:"verify_commands"` // 合并前在项目根目录执行的构建、测试命令，为空时使用项目清单 app-maker.yaml
+	SkipVerify     bool          `mapstructure:"skip_verify"`     // 合并前不执行构建、测试，mock CLI 始终跳过
+	VerifyTimeout  time.Duration `mapstructure:"verify_timeout"`  // 每条校验命令的超时时间，默认 10m
+}
+
+// WorkspaceConfig 项目工作区回收配置
+type WorkspaceConfig struct {
+	IdleTTL         time.Duration `mapstructure:"idle_ttl"`         // 闲置超过该时间的工作区被回收，再次使用时重新克隆，0 表示不回收
+	PruneAfter      time.Duration `mapstructure:"prune_after"`      // 闲置超过该时间的工作区清理依赖目录，再次使用时重新安装，0 表示不清理
+	DiskBudgetMB    int64         `mapstructure:"disk_budget_mb"`   // 所有工作区的磁盘预算（MB），超出时按最久未使用清理、回收，0 表示不限制
+	CleanupInterval time.Duration `mapstructure:"cleanup_interval"` // 回收检查间隔，默认 1h
+}
+
+// DeployConfig 部署后健康检查配置
+type DeployConfig struct {
+	HealthTimeout  time.Duration `mapstructure:"health_timeout"`  // 启动后等待组件通过健康检查的最长时间，0 表示不检查
+	HealthInterval time.Duration `mapstructure:"health_interval"` // 健康检查轮询间隔，默认 3s
+}
+
+// SecurityConfig 安全配置
+type SecurityConfig struct {
+	SecretKey    string        `mapstructure:"secret_key"`    // 加密 Redis 中项目模型 API Token 的密钥，为空时每次启动随机生成；生产环境必须配置
+	ServiceKeys  []string      `mapstructure:"service_keys"`  // 后端调用 Agents API 的签名密钥，格式 keyID:secret，轮换时同时配置新旧密钥；生产环境必须配置
+	ReplayWindow time.Duration `mapstructure:"replay_window"` // 签名时间允许的偏差，窗口内同一签名请求只能使用一次，默认 5m
 }
 
 // Asynq 异步配置
@@ -43,11 +84,16 @@ type AsynqConfig struct {
 
 // Config 配置
 type Config struct {
-	App     AppConfig     `mapstructure:"app"`     // App配置
-	Log     LogConfig     `mapstructure:"log"`     // 日志配置
-	Command CommandConfig `mapstructure:"command"` // 命令配置
-	Redis   RedisConfig   `mapstructure:"redis"`   // Redis配置
-	Asynq   AsynqConfig   `mapstructure:"asynq"`   // 异步配置
+	App       AppConfig         `mapstructure:"app"`       // App配置
+	Log       LogConfig         `mapstructure:"log"`       // 日志配置
+	Command   CommandConfig     `mapstructure:"command"`   // 命令配置
+	Redis     RedisConfig       `mapstructure:"redis"`     // Redis配置
+	Asynq     AsynqConfig       `mapstructure:"asynq"`     // 异步配置
+	Prompt    PromptConfig      `mapstructure:"prompt"`    // 提示词模板配置
+	Git       GitWorkflowConfig `mapstructure:"git"`       // Git 分支工作流配置
+	Security  SecurityConfig    `mapstructure:"security"`  // 安全配置
+	Workspace WorkspaceConfig   `mapstructure:"workspace"` // 项目工作区回收配置
+	Deploy    DeployConfig      `mapstructure:"deploy"`    // 部署后健康检查配置
 }
 
 // GitConfig Git配置
@@ -69,13 +115,27 @@ func Load() (*Config, error) {
 	v.SetDefault("app.workspace_path", utils.GetEnvOrDefault(common.EnvKeyWorkspacePath, utils.LOCAL_WORKSPACE_PATH))
 	v.SetDefault("log.level", "debug")
 	v.SetDefault("log.file", "./logs/app-maker-agents.log")
-	v.SetDefault("command.timeout", "5m")
+	v.SetDefault("command.timeout", "30m")
 	v.SetDefault("command.cli_tool", "claude")
+	v.SetDefault("command.fix_max_attempts", utils.GetEnvOrDefault("COMMAND_FIX_MAX_ATTEMPTS", "3"))
 	v.SetDefault("redis.host", utils.GetEnvOrDefault("REDIS_HOST", "localhost"))
 	v.SetDefault("redis.port", 6379)
 	v.SetDefault("redis.password", utils.GetEnvOrDefault("REDIS_PASSWORD", ""))
 	v.SetDefault("redis.db", 1)
 	v.SetDefault("asynq.concurrency", 100)
+	v.SetDefault("git.provider", utils.GetEnvOrDefault("GIT_PROVIDER", common.GitProviderLocal))
+	v.SetDefault("git.gitlab_url", utils.GetEnvOrDefault("GITLAB_URL", "http://gitlab.app-maker.localhost"))
+	v.SetDefault("git.gitlab_token", utils.GetEnvOrDefault("GITLAB_TOKEN", ""))
+	v.SetDefault("git.verify_timeout", "10m")
+	v.SetDefault("security.secret_key", utils.GetEnvOrDefault("AGENTS_SECRET_KEY", ""))
+	v.SetDefault("security.service_keys", utils.GetEnvOrDefault("AGENTS_SERVICE_KEYS", ""))
+	v.SetDefault("security.replay_window", "5m")
+	v.SetDefault("workspace.idle_ttl", utils.GetEnvOrDefault("WORKSPACE_IDLE_TTL", "168h"))
+	v.SetDefault("workspace.prune_after", utils.GetEnvOrDefault("WORKSPACE_PRUNE_AFTER", "24h"))
+	v.SetDefault("workspace.disk_budget_mb", utils.GetEnvOrDefault("WORKSPACE_DISK_BUDGET_MB", "0"))
+	v.SetDefault("workspace.cleanup_interval", "1h")
+	v.SetDefault("deploy.health_timeout", utils.GetEnvOrDefault("DEPLOY_HEALTH_TIMEOUT", "3m"))
+	v.SetDefault("deploy.health_interval", "3s")
 
 	if err := v.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
@@ -89,7 +149,30 @@ func Load() (*Config, error) {
 	}
 
 	if cfg.Command.Timeout == 0 {
-		cfg.Command.Timeout = 5 * time.Minute
+		cfg.Command.Timeout = 30 * time.Minute
+	}
+	if cfg.Git.VerifyTimeout == 0 {
+		cfg.Git.VerifyTimeout = 10 * time.Minute
+	}
+	if cfg.Workspace.CleanupInterval == 0 {
+		cfg.Workspace.CleanupInterval = time.Hour
+	}
+	if cfg.Deploy.HealthInterval == 0 {
+		cfg.Deploy.HealthInterval = 3 * time.Second
+	}
+
+	// Agents API 可以在任意工作区执行提示词，生产环境不允许不校验签名启动
+	serviceKeys, err := auth.ParseServiceKeys(cfg.Security.ServiceKeys)
+	if err != nil {
+		return nil, err
+	}
+	if len(serviceKeys) == 0 && cfg.App.Environment == common.EnvironmentProduction {
+		return nil, fmt.Errorf("生产环境必须配置 security.service_keys（AGENTS_SERVICE_KEYS）")
+	}
+
+	// 随机密钥在重启后无法解密已保存的模型 API Token，生产环境必须使用固定密钥
+	if cfg.Security.SecretKey == "" && cfg.App.Environment == common.EnvironmentProduction {
+		return nil, fmt.Errorf("生产环境必须配置 security.secret_key（AGENTS_SECRET_KEY）")
 	}
 
 	return cfg, nil
@@ -1,6 +1,7 @@
 package container
 
 import (
+	"context"
 	"fmt"
 	"log"
 
@@ -12,6 +13,7 @@ import (
 	"github.com/lighthought/app-maker/shared-models/cache"
 	"github.com/lighthought/app-maker/shared-models/common"
 	"github.com/lighthought/app-maker/shared-models/logger"
+	"github.com/lighthought/app-maker/shared-models/prompt"
 
 	"github.com/hibiken/asynq"
 )
@@ -22,15 +24,19 @@ type Container struct {
 	AsyncInspector *asynq.Inspector
 	AsyncServer    *asynq.Server
 	JWTService     *auth.JWTService
+	ServiceAuth    *auth.RequestVerifier // 校验后端调用 Agents API 的服务签名
 	CacheInstance  cache.Cache
+	PromptRegistry prompt.Registry
 
 	// Internal Services
 	CommandService   services.CommandService
 	GitService       services.GitService
 	FileService      services.FileService
 	RedisService     services.RedisService
+	SessionService   services.SessionService
 	AgentTaskService services.AgentTaskService
 	ProjectService   services.ProjectService
+	WorkspaceService services.WorkspaceService
 
 	// API Handlers
 	ProjectHandler   *handlers.ProjectHandler
@@ -40,9 +46,14 @@ type Container struct {
 	UxHandler        *handlers.UxHandler
 	ArchitectHandler *handlers.ArchitectHandler
 	PoHandler        *handlers.PoHandler
+	SmHandler        *handlers.SmHandler
 	DevHandler       *handlers.DevHandler
+	QaHandler        *handlers.QaHandler
 	TaskHandler      *handlers.TaskHandler
 	HealthHandler    *handlers.HealthHandler
+	WorkspaceHandler *handlers.WorkspaceHandler
+
+	stopWorkspaceCleanup context.CancelFunc
 }
 
 func NewContainer(cfg *config.Config) *Container {
@@ -65,51 +76,86 @@ func NewContainer(cfg *config.Config) *Container {
 	asyncClient := asynq.NewClient(asyncOpt)
 	asyncInspector := asynq.NewInspector(asyncOpt)
 
-	commandSvc := services.NewCommandService(cfg.Command, cfg.App.WorkspacePath)
-	gitService := services.NewGitService(commandSvc)
-	redisService := services.NewRedisService(cacheInstance)
-	fileSvc := services.NewFileService(commandSvc, cfg.App.WorkspacePath)
-	agentTaskService := services.NewAgentTaskService(commandSvc, fileSvc, gitService, redisService, asyncClient)
-	projectSvc := services.NewProjectService(commandSvc, agentTaskService, redisService, fileSvc)
-
-	asynqServer := initAsynqWorker(&asyncOpt, cfg.Asynq.Concurrency, agentTaskService, projectSvc)
+	// 配置已在加载时校验过密钥格式
+	serviceKeys, _ := auth.ParseServiceKeys(cfg.Security.ServiceKeys)
+	serviceAuth := auth.NewRequestVerifier(serviceKeys, cfg.Security.ReplayWindow)
+	if !serviceAuth.Enabled() {
+		logger.Warn("未配置 security.service_keys（AGENTS_SERVICE_KEYS），Agents API 不校验服务签名，任何能访问该端口的人都可以执行提示词，只能用于本地开发")
+	}
 
-	projectHandler := handlers.NewProjectHandler(agentTaskService, projectSvc)
+	commandSvc := services.NewCommandService(cfg.Command, cfg.App.WorkspacePath)
+	redisService := services.NewRedisService(cacheInstance, cfg.Security.SecretKey)
+	projectLockService := services.NewProjectLockService(cacheInstance)
+	gitProvider := services.NewGitProvider(cfg.Git, commandSvc, cacheInstance)
+	gitService := services.NewGitService(commandSvc, projectLockService, gitProvider, cfg.Git, cfg.App.WorkspacePath)
+	cliAdapters := services.NewCliAdapterRegistry(cfg.Command.MockFixturesPath)
+	fileSvc := services.NewFileService(commandSvc, cliAdapters, cfg.App.WorkspacePath)
+	sessionService := services.NewSessionService(cacheInstance, redisService)
+	promptRegistry := prompt.NewRegistry(cfg.Prompt.TemplatesPath)
+	agentTaskService := services.NewAgentTaskService(commandSvc, fileSvc, gitService, redisService, sessionService, projectLockService, cliAdapters,
+		promptRegistry, asyncClient, asyncInspector)
+	workspaceService := services.NewWorkspaceService(commandSvc, projectLockService, cacheInstance, cfg.Workspace, cfg.App.WorkspacePath)
+	deployHealthChecker := services.NewDeployHealthChecker(commandSvc, cfg.Deploy)
+	projectSvc := services.NewProjectService(commandSvc, agentTaskService, redisService, fileSvc, gitService, projectLockService, workspaceService, cliAdapters, deployHealthChecker, cfg.Command.FixMaxAttempts)
+
+	asynqServer := initAsynqWorker(&asyncOpt, cfg.Asynq.Concurrency, agentTaskService, projectSvc, redisService, projectLockService, workspaceService)
+
+	// 定时回收闲置的项目工作区
+	cleanupCtx, stopWorkspaceCleanup := context.WithCancel(context.Background())
+	workspaceService.Start(cleanupCtx)
+
+	projectHandler := handlers.NewProjectHandler(agentTaskService, projectSvc, redisService, sessionService)
 	chatHandler := handlers.NewChatHandler(agentTaskService)
-	analyseHandler := handlers.NewAnalyseHandler(agentTaskService)
-	pmHandler := handlers.NewPmHandler(agentTaskService)
-	poHandler := handlers.NewPoHandler(agentTaskService)
-	devHandler := handlers.NewDevHandler(agentTaskService, commandSvc)
-	architectHandler := handlers.NewArchitectHandler(agentTaskService)
-	uxHandler := handlers.NewUxHandler(agentTaskService)
-	taskHandler := handlers.NewTaskHandler(asyncInspector)
+	analyseHandler := handlers.NewAnalyseHandler(agentTaskService, promptRegistry)
+	pmHandler := handlers.NewPmHandler(agentTaskService, promptRegistry)
+	poHandler := handlers.NewPoHandler(agentTaskService, promptRegistry)
+	smHandler := handlers.NewSmHandler(agentTaskService, promptRegistry)
+	devHandler := handlers.NewDevHandler(agentTaskService, commandSvc, promptRegistry)
+	qaHandler := handlers.NewQaHandler(agentTaskService, promptRegistry)
+	architectHandler := handlers.NewArchitectHandler(agentTaskService, promptRegistry)
+	uxHandler := handlers.NewUxHandler(agentTaskService, promptRegistry)
+	taskInspectService := services.NewTaskInspectService(asyncInspector)
+	taskHandler := handlers.NewTaskHandler(asyncInspector, agentTaskService, projectLockService, taskInspectService)
 	healthHandler := handlers.NewHealthHandler(cacheInstance)
+	workspaceHandler := handlers.NewWorkspaceHandler(workspaceService)
 
 	return &Container{
 		AsyncClient:      asyncClient,
 		AsyncInspector:   asyncInspector,
+		ServiceAuth:      serviceAuth,
 		AgentTaskService: agentTaskService,
+		SessionService:   sessionService,
 		AsyncServer:      asynqServer,
 		CommandService:   commandSvc,
 		GitService:       gitService,
 		RedisService:     redisService,
 		CacheInstance:    cacheInstance,
+		PromptRegistry:   promptRegistry,
 		ProjectHandler:   projectHandler,
 		ChatHandler:      chatHandler,
 		AnalyseHandler:   analyseHandler,
 		PmHandler:        pmHandler,
 		PoHandler:        poHandler,
+		SmHandler:        smHandler,
 		DevHandler:       devHandler,
+		QaHandler:        qaHandler,
 		ArchitectHandler: architectHandler,
 		UxHandler:        uxHandler,
 		TaskHandler:      taskHandler,
 		HealthHandler:    healthHandler,
+		WorkspaceService: workspaceService,
+		WorkspaceHandler: workspaceHandler,
+
+		stopWorkspaceCleanup: stopWorkspaceCleanup,
 	}
 }
 
 func initAsynqWorker(redisClientOpt *asynq.RedisClientOpt, concurrency int,
 	agentTaskService services.AgentTaskService,
-	projectSvc services.ProjectService) *asynq.Server {
+	projectSvc services.ProjectService,
+	redisService services.RedisService,
+	projectLockService services.ProjectLockService,
+	workspaceService services.WorkspaceService) *asynq.Server {
 	// 配置 Worker
 	server := asynq.NewServer(
 		redisClientOpt,
@@ -117,19 +163,28 @@ func initAsynqWorker(redisClientOpt *asynq.RedisClientOpt, concurrency int,
 			Concurrency: concurrency, // 并发 worker 数量
 			// 可以按权重指定优先处理哪些队列
 			Queues: map[string]int{
-				"critical": common.TaskQueueCritical,
-				"default":  common.TaskQueueDefault,
-				"low":      common.TaskQueueLow,
+				common.TaskQueueNameCritical: common.TaskQueueCritical,
+				common.TaskQueueNameDefault:  common.TaskQueueDefault,
+				common.TaskQueueNameLow:      common.TaskQueueLow,
 			},
+			// 未拿到项目工作区锁的任务按固定延迟重新排队，不计入失败和重试次数
+			IsFailure: func(err error) bool {
+				return !services.IsProjectLockedError(err)
+			},
+			RetryDelayFunc: services.ProjectLockRetryDelay,
 		},
 	)
 
 	// 注册任务处理器
 	mux := asynq.NewServeMux()
+	mux.Use(services.NewTaskCancelMiddleware(redisService))        // 被取消的任务不再重试
+	mux.Use(services.NewProjectLockMiddleware(projectLockService)) // 同一项目的任务串行修改工作区
+	mux.Use(services.NewWorkspaceMiddleware(workspaceService))     // 恢复已回收的工作区，记录工作区活动
 	mux.Handle(common.TaskTypeAgentExecute, agentTaskService)
 	mux.Handle(common.TaskTypeAgentChat, agentTaskService)
 	mux.Handle(common.TaskTypeAgentSetup, projectSvc)
 	mux.Handle(common.TaskTypeProjectDeploy, projectSvc)
+	mux.Handle(common.TaskTypeProjectTest, projectSvc)
 	// ... 注册其他任务处理器
 
 	// 启动服务器
@@ -146,6 +201,9 @@ func initAsynqWorker(redisClientOpt *asynq.RedisClientOpt, concurrency int,
 
 func (c *Container) Stop() {
 	logger.Info("Stopping container... ")
+	if c.stopWorkspaceCleanup != nil {
+		c.stopWorkspaceCleanup()
+	}
 	if c.AsyncServer != nil {
 		c.AsyncServer.Shutdown()
 	}
@@ -0,0 +1,244 @@
+package mockcli
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/lighthought/app-maker/shared-models/common"
+)
+
+//go:embed fixtures/*.json
+var builtinFixtures embed.FS
+
+// Request mock CLI 的一次调用
+type Request struct {
+	ProjectGuid string
+	ProjectPath string
+	AgentType   string
+	DevStage    string
+	StoryNumber string // 故事编号，剧本可以据此写入故事文件
+	Message     string
+	SessionID   string
+	Language    string // 输出语言，优先使用该语言的剧本
+}
+
+// 回放过程中输出的提示，按输出语言区分
+var runnerMessages = map[string]struct {
+	UseFixture string
+	WriteFile  string
+}{
+	common.LanguageZhCN: {UseFixture: "[mock] 使用剧本 %s (agent=%s, stage=%s)", WriteFile: "[mock] 写入文件 %s"},
+	common.LanguageEnUS: {UseFixture: "[mock] Using fixture %s (agent=%s, stage=%s)", WriteFile: "[mock] Wrote file %s"},
+}
+
+// Fixture 剧本，按开发阶段或 Agent 类型命名，如 generate_prd.json、dev.json；
+// 其他语言的剧本在名称后加语言，如 generate_prd.en-US.json，缺少时使用默认语言的剧本
+type Fixture struct {
+	Result  string            `json:"result"`   // 返回给后端的 markdown 结果，支持 text/template
+	Files   map[string]string `json:"files"`    // 写入项目的文件，key 为相对项目根目录的路径，路径和内容都支持 text/template
+	Lines   []string          `json:"lines"`    // 逐行输出的过程日志
+	DelayMs int               `json:"delay_ms"` // 每行日志的输出间隔
+	IsError bool              `json:"is_error"` // 模拟 CLI 执行失败
+}
+
+// claudeOutput 与 claude --output-format json 的输出保持一致
+type claudeOutput struct {
+	Type          string `json:"type"`
+	Subtype       string `json:"subtype"`
+	IsError       bool   `json:"is_error"`
+	DurationMs    int    `json:"duration_ms"`
+	DurationApiMs int    `json:"duration_api_ms"`
+	Result        string `json:"result"`
+	SessionID     string `json:"session_id"`
+	Usage         struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// Runner mock CLI 执行器
+type Runner interface {
+	// 回放剧本：写入文件、逐行输出日志，返回 Claude 风格的 JSON
+	Run(ctx context.Context, req *Request, onLine func(line string)) (string, error)
+}
+
+type runner struct {
+	fixturesPath string
+}
+
+// NewRunner 创建 mock CLI 执行器，fixturesPath 为空时只使用内置剧本
+func NewRunner(fixturesPath string) Runner {
+	return &runner{fixturesPath: fixturesPath}
+}
+
+// Run 回放剧本
+func (r *runner) Run(ctx context.Context, req *Request, onLine func(line string)) (string, error) {
+	start := time.Now()
+	fixture, name, err := r.loadFixture(req)
+	if err != nil {
+		return "", err
+	}
+	messages := runnerMessages[common.NormalizeLanguage(req.Language)]
+	emit(onLine, fmt.Sprintf(messages.UseFixture, name, req.AgentType, req.DevStage))
+
+	for _, line := range fixture.Lines {
+		if err := sleep(ctx, fixture.DelayMs); err != nil {
+			return "", err
+		}
+		emit(onLine, line)
+	}
+
+	paths := make([]string, 0, len(fixture.Files))
+	for path := range fixture.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		filePath, err := render(name, path, req)
+		if err != nil {
+			return "", err
+		}
+		rendered, err := render(filePath, fixture.Files[path], req)
+		if err != nil {
+			return "", err
+		}
+		if err := writeProjectFile(req.ProjectPath, filePath, rendered); err != nil {
+			return "", err
+		}
+		emit(onLine, fmt.Sprintf(messages.WriteFile, filePath))
+	}
+
+	result, err := render(name, fixture.Result, req)
+	if err != nil {
+		return "", err
+	}
+
+	output := claudeOutput{
+		Type:       "result",
+		Subtype:    "success",
+		IsError:    fixture.IsError,
+		DurationMs: int(time.Since(start).Milliseconds()),
+		Result:     result,
+		SessionID:  req.SessionID,
+	}
+	if output.IsError {
+		output.Subtype = "error"
+	}
+	output.DurationApiMs = output.DurationMs
+	if output.SessionID == "" {
+		output.SessionID = "mock-" + req.ProjectGuid + "-" + req.AgentType
+	}
+	// 粗略按 4 个字符一个 token 估算，便于调试用量统计
+	output.Usage.InputTokens = len(req.Message) / 4
+	output.Usage.OutputTokens = len(result) / 4
+
+	data, err := json.Marshal(output)
+	if err != nil {
+		return "", fmt.Errorf("序列化 mock 输出失败: %w", err)
+	}
+	// 最终的 JSON 作为任务结果返回，不再推送到日志流
+	return string(data), nil
+}
+
+// loadFixture 按 开发阶段 -> Agent 类型 -> default 的顺序查找剧本，同一名称优先使用输出语言的剧本，
+// 自定义目录优先于内置剧本
+func (r *runner) loadFixture(req *Request) (*Fixture, string, error) {
+	var names []string
+	language := common.NormalizeLanguage(req.Language)
+	for _, candidate := range []string{req.DevStage, req.AgentType, "default"} {
+		if candidate == "" {
+			continue
+		}
+		if language != common.DefaultLanguage {
+			names = append(names, candidate+"."+language+".json")
+		}
+		names = append(names, candidate+".json")
+	}
+
+	for _, name := range names {
+		data, err := r.readFixture(name)
+		if err != nil {
+			continue
+		}
+
+		var fixture Fixture
+		if err := json.Unmarshal(data, &fixture); err != nil {
+			return nil, name, fmt.Errorf("解析 mock 剧本 %s 失败: %w", name, err)
+		}
+		return &fixture, name, nil
+	}
+	return nil, "", fmt.Errorf("未找到 mock 剧本: agent=%s, stage=%s", req.AgentType, req.DevStage)
+}
+
+// readFixture 读取剧本文件
+func (r *runner) readFixture(name string) ([]byte, error) {
+	if r.fixturesPath != "" {
+		if data, err := os.ReadFile(filepath.Join(r.fixturesPath, name)); err == nil {
+			return data, nil
+		}
+	}
+	return builtinFixtures.ReadFile("fixtures/" + name)
+}
+
+// writeProjectFile 写入项目文件，不允许写到项目目录之外
+func writeProjectFile(projectPath, relPath, content string) error {
+	cleaned := filepath.Clean(filepath.FromSlash(relPath))
+	if filepath.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("mock 剧本文件路径非法: %s", relPath)
+	}
+
+	fullPath := filepath.Join(projectPath, cleaned)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+	if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+		return fmt.Errorf("写入文件 %s 失败: %w", relPath, err)
+	}
+	return nil
+}
+
+// render 渲染剧本中的模板
+func render(name, text string, req *Request) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("解析 mock 模板 %s 失败: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, req); err != nil {
+		return "", fmt.Errorf("渲染 mock 模板 %s 失败: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+// sleep 等待指定毫秒数，任务取消时立即返回
+func sleep(ctx context.Context, delayMs int) error {
+	if delayMs <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(time.Duration(delayMs) * time.Millisecond)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("命令已取消: %w", ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
+
+func emit(onLine func(line string), line string) {
+	if onLine != nil {
+		onLine(line)
+	}
+}
@@ -0,0 +1,93 @@
+package mockcli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+)
+
+func TestWriteProjectFileRejectsPathTraversal(t *testing.T) {
+	projectPath := t.TempDir()
+	tests := []struct {
+		path    string
+		wantErr bool
+	}{
+		{path: "docs/PRD.md"},
+		{path: "./docs/../docs/arch/architecture.md"},
+		{path: "..", wantErr: true},
+		{path: "../outside.md", wantErr: true},
+		{path: "docs/../../outside.md", wantErr: true},
+		{path: "/etc/passwd", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			err := writeProjectFile(projectPath, tt.path, "content")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("writeProjectFile(%q) err = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(projectPath), "outside.md")); err == nil {
+		t.Error("file written outside the project directory")
+	}
+}
+
+func TestRunSelectsFixtureByLanguage(t *testing.T) {
+	tests := []struct {
+		name       string
+		language   string
+		devStage   string
+		agentType  string
+		wantResult string
+	}{
+		{name: "default language", devStage: "generate_prd", wantResult: "PRD 已生成"},
+		{name: "english fixture", language: "en-US", devStage: "generate_prd", wantResult: "PRD generated"},
+		{name: "unsupported language falls back", language: "fr-FR", devStage: "generate_prd", wantResult: "PRD 已生成"},
+		{name: "falls back to default fixture", language: "en-US", devStage: "unknown", agentType: "unknown", wantResult: "no fixture is configured"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lines []string
+			output, err := NewRunner("").Run(context.Background(), &Request{
+				ProjectGuid: "guid",
+				ProjectPath: t.TempDir(),
+				AgentType:   tt.agentType,
+				DevStage:    tt.devStage,
+				Language:    tt.language,
+			}, func(line string) { lines = append(lines, line) })
+			if err != nil {
+				t.Fatalf("Run() err = %v", err)
+			}
+			if !strings.Contains(output, tt.wantResult) {
+				t.Errorf("output = %s, want to contain %q", output, tt.wantResult)
+			}
+			for _, line := range lines {
+				if strings.HasPrefix(line, "{") {
+					t.Errorf("final JSON should not be emitted as a log line: %s", line)
+				}
+			}
+		})
+	}
+}
+
+func TestRunWritesStoryFile(t *testing.T) {
+	projectPath := t.TempDir()
+	_, err := NewRunner("").Run(context.Background(), &Request{
+		ProjectGuid: "guid",
+		ProjectPath: projectPath,
+		AgentType:   "sm",
+		DevStage:    "draft_story",
+		StoryNumber: "1.1",
+	}, nil)
+	if err != nil {
+		t.Fatalf("Run() err = %v", err)
+	}
+	// 起草的故事文件与 agents 检查的路径一致
+	if _, err := os.Stat(filepath.Join(projectPath, filepath.FromSlash(agent.StoryFilePath("1.1")))); err != nil {
+		t.Errorf("story file not written: %v", err)
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"path/filepath"
 
 	"github.com/lighthought/app-maker/shared-models/agent"
 	"github.com/lighthought/app-maker/shared-models/common"
 	"github.com/lighthought/app-maker/shared-models/logger"
+	"github.com/lighthought/app-maker/shared-models/prompt"
 	"github.com/lighthought/app-maker/shared-models/tasks"
 	"github.com/lighthought/app-maker/shared-models/utils"
 
@@ -19,16 +21,26 @@ import (
 type AgentTaskService interface {
 	// 处理任务
 	ProcessTask(ctx context.Context, task *asynq.Task) error
-	// Agent 执行任务（带CLI工具）
-	EnqueueWithCli(projectGuid, agentType, message, cliTool string, stageName common.DevStatus) (*asynq.TaskInfo, error)
+	// Agent 执行任务（带CLI工具），渲染后的提示词记录在任务负载上
+	EnqueueWithCli(projectGuid, agentType string, prompt *agent.RenderedPrompt, cliTool string, stageName common.DevStatus) (*asynq.TaskInfo, error)
+	// 实现指定故事的 Agent 执行任务，storyNumber 用于命名故事分支
+	EnqueueStoryWithCli(projectGuid, agentType string, prompt *agent.RenderedPrompt, cliTool string, stageName common.DevStatus,
+		storyNumber string) (*asynq.TaskInfo, error)
+	// 实现指定故事并评审提交的 Agent 执行任务，review 为空时不评审
+	EnqueueStoryWithReview(projectGuid, agentType string, prompt *agent.RenderedPrompt, cliTool string, stageName common.DevStatus,
+		storyNumber string, review *agent.CodeReviewConfig) (*asynq.TaskInfo, error)
 	// 项目环境准备
 	EnqueueSetupReq(req *agent.SetupProjEnvReq) (*asynq.TaskInfo, error)
 	// 部署项目
 	EnqueueDeployReq(req *agent.DeployReq) (*asynq.TaskInfo, error)
+	// 运行项目测试，fixPrompt 为测试失败时交给 Dev Agent 的修复要求
+	EnqueueRunTestReq(req *agent.RunTestReq, fixPrompt string) (*asynq.TaskInfo, error)
 	// 与 Agent 对话
 	EnqueueChatWithAgent(req *agent.ChatReq) (*asynq.TaskInfo, error)
 	// 与指定代理对话
 	ChatWithAgent(ctx context.Context, projectGuid, agentType, message string) (*models.CommandResult, error)
+	// 取消任务：执行中的任务杀掉 CLI 进程，排队中的任务直接删除
+	CancelTask(ctx context.Context, taskID string) (*tasks.AgentExecuteTaskPayload, error)
 }
 
 type agentTaskService struct {
@@ -36,7 +48,12 @@ type agentTaskService struct {
 	fileService    FileService
 	gitService     GitService
 	redisService   RedisService
+	sessionService SessionService
+	lockService    ProjectLockService
+	cliAdapters    CliAdapterRegistry
+	promptRegistry prompt.Registry
 	asyncClient    *asynq.Client
+	asyncInspector *asynq.Inspector
 }
 
 const (
@@ -48,12 +65,22 @@ func NewAgentTaskService(commandService CommandService,
 	fileService FileService,
 	gitService GitService,
 	redisService RedisService,
-	asyncClient *asynq.Client) AgentTaskService {
+	sessionService SessionService,
+	lockService ProjectLockService,
+	cliAdapters CliAdapterRegistry,
+	promptRegistry prompt.Registry,
+	asyncClient *asynq.Client,
+	asyncInspector *asynq.Inspector) AgentTaskService {
 	return &agentTaskService{
 		commandService: commandService,
 		fileService:    fileService,
 		gitService:     gitService,
+		sessionService: sessionService,
+		lockService:    lockService,
+		cliAdapters:    cliAdapters,
+		promptRegistry: promptRegistry,
 		asyncClient:    asyncClient,
+		asyncInspector: asyncInspector,
 		redisService:   redisService,
 	}
 }
@@ -67,11 +94,38 @@ func (h *agentTaskService) Enqueue(projectGuid, agentType, message string, stage
 }
 
 // EnqueueWithCli 创建带CLI工具的代理执行任务
-func (h *agentTaskService) EnqueueWithCli(projectGuid, agentType, message, cliTool string, stageName common.DevStatus) (*asynq.TaskInfo, error) {
+func (h *agentTaskService) EnqueueWithCli(projectGuid, agentType string, prompt *agent.RenderedPrompt, cliTool string, stageName common.DevStatus) (*asynq.TaskInfo, error) {
 	if h.asyncClient == nil {
 		return nil, fmt.Errorf("%s", ASYNC_IS_NIL)
 	}
-	return h.asyncClient.Enqueue(tasks.NewAgentExecuteTaskWithCli(projectGuid, agentType, message, cliTool, stageName))
+	if prompt == nil {
+		return nil, fmt.Errorf("EnqueueWithCli, prompt is nil")
+	}
+	return h.asyncClient.Enqueue(tasks.NewAgentExecuteTaskWithCli(projectGuid, agentType, prompt, cliTool, stageName))
+}
+
+// EnqueueStoryWithCli 创建实现指定故事的代理执行任务
+func (h *agentTaskService) EnqueueStoryWithCli(projectGuid, agentType string, prompt *agent.RenderedPrompt, cliTool string,
+	stageName common.DevStatus, storyNumber string) (*asynq.TaskInfo, error) {
+	if h.asyncClient == nil {
+		return nil, fmt.Errorf("%s", ASYNC_IS_NIL)
+	}
+	if prompt == nil {
+		return nil, fmt.Errorf("EnqueueStoryWithCli, prompt is nil")
+	}
+	return h.asyncClient.Enqueue(tasks.NewAgentStoryTaskWithCli(projectGuid, agentType, prompt, cliTool, stageName, storyNumber))
+}
+
+// EnqueueStoryWithReview 创建实现指定故事并评审提交的代理执行任务
+func (h *agentTaskService) EnqueueStoryWithReview(projectGuid, agentType string, prompt *agent.RenderedPrompt, cliTool string,
+	stageName common.DevStatus, storyNumber string, review *agent.CodeReviewConfig) (*asynq.TaskInfo, error) {
+	if h.asyncClient == nil {
+		return nil, fmt.Errorf("%s", ASYNC_IS_NIL)
+	}
+	if prompt == nil {
+		return nil, fmt.Errorf("EnqueueStoryWithReview, prompt is nil")
+	}
+	return h.asyncClient.Enqueue(tasks.NewAgentStoryTaskWithReview(projectGuid, agentType, prompt, cliTool, stageName, storyNumber, review))
 }
 
 // EnqueueReq 创建项目环境准备任务
@@ -96,6 +150,17 @@ func (h *agentTaskService) EnqueueDeployReq(req *agent.DeployReq) (*asynq.TaskIn
 	return h.asyncClient.Enqueue(tasks.NewProjectDeployTask(req))
 }
 
+// 运行项目测试
+func (h *agentTaskService) EnqueueRunTestReq(req *agent.RunTestReq, fixPrompt string) (*asynq.TaskInfo, error) {
+	if h.asyncClient == nil {
+		return nil, fmt.Errorf("%s", ASYNC_IS_NIL)
+	}
+	if req == nil {
+		return nil, fmt.Errorf("EnqueueRunTestReq, req is nil")
+	}
+	return h.asyncClient.Enqueue(tasks.NewProjectTestTask(&tasks.ProjectTestTaskPayload{RunTestReq: *req, FixPrompt: fixPrompt}))
+}
+
 // 与 Agent 对话
 func (h *agentTaskService) EnqueueChatWithAgent(req *agent.ChatReq) (*asynq.TaskInfo, error) {
 	if h.asyncClient == nil {
@@ -129,10 +194,11 @@ func (s *agentTaskService) innerProcessAgentExecuteTask(ctx context.Context, tas
 	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
 		return fmt.Errorf("json.Unmarshal failed: %v: %w", err, asynq.SkipRetry)
 	}
-	tasks.UpdateResult(task.ResultWriter(), common.CommonStatusInProgress, 5, "正在执行代理任务...")
-	s.redisService.PublishTaskStatus(&payload, task.ResultWriter().TaskID(), common.CommonStatusInProgress, "正在执行代理任务...")
+	queuedMessage := getAgentMessage(payload.Language, messageKeyTaskQueued)
+	tasks.UpdateResult(task.ResultWriter(), common.CommonStatusInProgress, 5, queuedMessage)
+	s.redisService.PublishTaskStatus(&payload, task.ResultWriter().TaskID(), common.CommonStatusInProgress, queuedMessage)
 
-	_, err := s.innerProcessTask(ctx, payload, task)
+	_, err := s.innerProcessTask(ctx, payload, task, true)
 	if err != nil {
 		return err
 	}
@@ -156,50 +222,58 @@ func (h *agentTaskService) innerProcessAgentChatTask(ctx context.Context, task *
 		Message:     req.Message,
 		DevStage:    common.DevStatus(req.DevStage),
 		CliTool:     req.CliTool,
+		Prompt:      req.Prompt,
+		Language:    req.Language,
 	}
-	_, err := h.innerProcessTask(ctx, payload, task)
+	_, err := h.innerProcessTask(ctx, payload, task, false)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-// 构建 CLI 命令
-func (h *agentTaskService) buildCliCommand(cliTool, sessionID, message string) (string, []string, bool) {
-	var cliCommand string
-	var args []string
-	var useJsonOutput bool
-
-	switch cliTool {
-	case common.CliToolQwenCode:
-		cliCommand = "qwen"
-		useJsonOutput = false
-		args = []string{"-y", "-p", "\"" + message + "\""}
-
-	case common.CliToolGemini:
-		cliCommand = "gemini"
-		useJsonOutput = false
-		args = []string{"-y", "-p", "\"" + message + "\""}
+// 创建 CLI 输出回调，把每一行发布到任务日志频道；同步对话没有任务ID，不发布
+func (h *agentTaskService) newTaskLogHandler(task *asynq.Task, payload *tasks.AgentExecuteTaskPayload) OutputLineHandler {
+	if task == nil {
+		return nil
+	}
 
-	default:
-		cliCommand = "claude"
-		useJsonOutput = true
-		if sessionID == "" {
-			args = []string{"--dangerously-skip-permissions", "--output-format", "json", "-p", "\"" + message + "\""}
-		} else {
-			args = []string{"--dangerously-skip-permissions", "--resume", sessionID, "--output-format", "json", "-p", "\"" + message + "\""}
+	taskID := task.ResultWriter().TaskID()
+	var seq int64
+	return func(stream, line string) {
+		seq++
+		if err := h.redisService.PublishTaskLog(payload, taskID, stream, line, seq); err != nil {
+			logger.Warn("发布任务日志失败",
+				logger.String("taskID", taskID),
+				logger.String("error", err.Error()))
 		}
 	}
+}
 
-	return cliCommand, args, useJsonOutput
+// formatTaskLog 适配器输出结构化事件时，把标准输出转换为可读日志后再推送
+func formatTaskLog(adapter CliAdapter, onLine OutputLineHandler) OutputLineHandler {
+	formatter, ok := adapter.(CliLogFormatter)
+	if !ok || onLine == nil {
+		return onLine
+	}
+	return func(stream, line string) {
+		if stream != common.AgentLogStreamStdout {
+			onLine(stream, line)
+			return
+		}
+		if formatted, ok := formatter.FormatLogLine(line); ok {
+			onLine(stream, formatted)
+		}
+	}
 }
 
 // 处理情况
-func (h *agentTaskService) handleAgentExecuteFailed(task *asynq.Task, payload tasks.AgentExecuteTaskPayload, result models.CommandResult) {
+func (h *agentTaskService) handleAgentExecuteFailed(task *asynq.Task, payload tasks.AgentExecuteTaskPayload, result models.CommandResult,
+	usage *agent.AgentUsage) {
 	if task != nil {
 		tasks.UpdateResult(task.ResultWriter(), common.CommonStatusFailed, 0, result.Error)
-		// 发布任务失败状态
-		h.redisService.PublishTaskStatus(&payload, task.ResultWriter().TaskID(), common.CommonStatusFailed, result.Error)
+		// 发布任务失败状态，失败的调用同样消耗了 token
+		h.redisService.PublishTaskStatusWithUsage(&payload, task.ResultWriter().TaskID(), common.CommonStatusFailed, result.Error, usage)
 		logger.Error("代理任务执行失败",
 			logger.String("taskID", task.ResultWriter().TaskID()),
 			logger.String("agentType", payload.AgentType),
@@ -213,11 +287,11 @@ func (h *agentTaskService) handleAgentExecuteFailed(task *asynq.Task, payload ta
 	}
 }
 
-// 异步任务本身、对话方法公用这个内部方法
-func (h *agentTaskService) innerProcessTask(ctx context.Context, payload tasks.AgentExecuteTaskPayload, task *asynq.Task) (*models.CommandResult, error) {
+// 异步任务本身、对话方法公用这个内部方法，withAgentPrompt 为 true 时在消息前加上 Agent 提示词文件引用
+func (h *agentTaskService) innerProcessTask(ctx context.Context, payload tasks.AgentExecuteTaskPayload, task *asynq.Task,
+	withAgentPrompt bool) (*models.CommandResult, error) {
 	var result models.CommandResult
 	timeBefor := utils.GetTimeNow()
-	sessionID := h.redisService.GetSessionByProjectGuid(payload.ProjectGUID, payload.AgentType)
 
 	logger.Info("\n===> 开始执行代理任务",
 		logger.String("startTime", utils.GetCurrentTime()),
@@ -227,18 +301,85 @@ func (h *agentTaskService) innerProcessTask(ctx context.Context, payload tasks.A
 
 	// 发布任务开始状态
 	if task != nil {
-		h.redisService.PublishTaskStatus(&payload, task.ResultWriter().TaskID(), common.CommonStatusInProgress, "任务开始执行")
+		h.redisService.PublishTaskStatus(&payload, task.ResultWriter().TaskID(), common.CommonStatusInProgress,
+			getAgentMessage(payload.Language, messageKeyTaskStarted))
 	}
 
-	// 从 payload 或项目检测获取 CLI 类型
+	// 从 payload、项目模型配置或项目检测获取 CLI 类型；模型配置无法解密时直接失败，不使用 agents 进程自身的凭证
+	modelConfig, err := h.redisService.GetModelConfig(payload.ProjectGUID)
+	if err != nil {
+		result = models.CommandResult{Success: false, Error: err.Error()}
+		h.handleAgentExecuteFailed(task, payload, result, nil)
+		return nil, fmt.Errorf("%w: %w", err, asynq.SkipRetry)
+	}
 	cliTool := payload.CliTool
+	if cliTool == "" && modelConfig != nil {
+		cliTool = modelConfig.CliTool
+	}
 	if cliTool == "" {
 		cliTool = h.fileService.DetectCliTool(payload.ProjectGUID)
 	}
 
-	// 根据 CLI 类型构建命令
-	cliCommand, args, useJsonOutput := h.buildCliCommand(cliTool, sessionID, payload.Message)
-	result = h.commandService.SimpleExecute(ctx, payload.ProjectGUID, cliCommand, args...)
+	// 根据 CLI 类型获取适配器并构建命令
+	adapter := h.cliAdapters.Get(cliTool)
+	// 按项目的模型配置注入环境变量，不修改 agents 进程本身的环境；CLI 不支持该模型提供商时直接失败
+	env, err := adapter.BuildEnv(modelConfig)
+	if err != nil {
+		result = models.CommandResult{Success: false, Error: err.Error()}
+		h.handleAgentExecuteFailed(task, payload, result, nil)
+		return nil, err
+	}
+	message := payload.Message
+	if withAgentPrompt {
+		message = buildAgentMessage(adapter, payload.AgentType, message)
+	}
+	// 原生会话返回会话ID，否则把精简的历史对话拼接到消息中
+	sessionID, message := h.sessionService.Prepare(adapter, payload.ProjectGUID, payload.AgentType, payload.Language, message)
+	cliReq := &CliRequest{
+		ProjectGuid: payload.ProjectGUID,
+		ProjectPath: h.fileService.GetProjectPath(payload.ProjectGUID),
+		AgentType:   payload.AgentType,
+		DevStage:    string(payload.DevStage),
+		StoryNumber: payload.StoryNumber,
+		Message:     message,
+		SessionID:   sessionID,
+		Language:    payload.Language,
+	}
+	// 每个阶段、故事在自己的分支上执行，构建和测试通过后再合并回主干
+	branch, err := h.gitService.StartBranch(ctx, payload.ProjectGUID, string(payload.DevStage), payload.StoryNumber)
+	if err != nil {
+		result = models.CommandResult{Success: false, Error: "准备 Git 分支失败: " + err.Error()}
+		h.handleAgentExecuteFailed(task, payload, result, nil)
+		return nil, fmt.Errorf("准备 Git 分支失败: %w", err)
+	}
+
+	var taskID string
+	if task != nil {
+		taskID = task.ResultWriter().TaskID()
+	}
+	// CLI 失败、被取消或返回错误时，把未完成的变更提交到分支并切回主干，避免工作区停留在分支上且有未提交的变更
+	merging := false
+	defer func() {
+		if merging {
+			return
+		}
+		// 任务被取消后 ctx 已失效，使用不会被取消的上下文清理，保留其中的工作区锁
+		cleanupCtx := context.WithoutCancel(ctx)
+		if err := h.gitService.SuspendBranch(cleanupCtx, payload.ProjectGUID, branch, buildSuspendCommitMessage(&payload, taskID)); err != nil {
+			logger.Warn("保存未完成任务的变更失败",
+				logger.String("GUID", payload.ProjectGUID),
+				logger.String("branch", branch),
+				logger.String("error", err.Error()))
+		}
+	}()
+
+	onLine := formatTaskLog(adapter, h.newTaskLogHandler(task, &payload))
+	if runner, ok := adapter.(CliRunner); ok {
+		result = runner.Run(ctx, cliReq, onLine)
+	} else {
+		cliCommand, args := adapter.BuildCommand(cliReq)
+		result = h.commandService.StreamExecuteWithEnv(ctx, payload.ProjectGUID, env, onLine, cliCommand, args...)
+	}
 
 	logger.Info("\n===> 代理任务执行完成",
 		logger.String("endTime", utils.GetCurrentTime()),
@@ -250,78 +391,104 @@ func (h *agentTaskService) innerProcessTask(ctx context.Context, payload tasks.A
 	duration := timeAfter.Sub(timeBefor)
 	durationMs := int(duration.Milliseconds())
 	if !result.Success {
-		h.handleAgentExecuteFailed(task, payload, result)
+		// 进程失败前可能已经消耗了 token，尽量从输出中解析出部分用量一并上报
+		partial, _ := adapter.ParseOutput(result.Output, durationMs)
+		h.handleAgentExecuteFailed(task, payload, result, buildAgentUsage(adapter, partial, durationMs))
 		return nil, fmt.Errorf("agent execute task failed: %s", result.Error)
 	}
 
-	claudeResponse := models.ClaudeResponse{
-		Type:          "result",
-		Subtype:       "success",
-		DurationMs:    durationMs,
-		DurationApiMs: durationMs,
-		IsError:       result.Error != "",
-		Result:        result.Output,
-	}
-
-	// 根据输出格式处理结果
-	if useJsonOutput {
-		// 处理 JSON 输出（Claude）
-		if err := json.Unmarshal([]byte(result.Output), &claudeResponse); err != nil {
-			logger.Error(" ===> CLI 结果解析失败",
-				logger.String("agentType", payload.AgentType),
-				logger.String("message", payload.Message),
-				logger.String("error", result.Error))
-		} else {
-			// 转换成功了，就可以直接取执行的结果了，去掉外层的 json 包装
-			result.Output = claudeResponse.Result
-		}
+	// 由适配器把输出解析为统一的结果，解析失败时保留原始输出
+	claudeResponse, err := adapter.ParseOutput(result.Output, durationMs)
+	if err != nil {
+		logger.Error(" ===> CLI 结果解析失败",
+			logger.String("cliTool", adapter.Name()),
+			logger.String("agentType", payload.AgentType),
+			logger.String("message", payload.Message),
+			logger.String("error", err.Error()))
 	} else {
-		// 处理纯文本输出（qwen、gemini）
-		// 直接使用原始输出文本
-		claudeResponse.Result = result.Output
+		// 去掉外层的包装，直接取执行的结果
+		result.Output = claudeResponse.Result
 	}
 
+	usage := buildAgentUsage(adapter, claudeResponse, durationMs)
 	if claudeResponse.IsError {
-		h.handleAgentExecuteFailed(task, payload, result)
+		h.handleAgentExecuteFailed(task, payload, result, usage)
 		return nil, fmt.Errorf("agent execute task, claude failed: %s", claudeResponse.Result)
 	}
 
-	// 保存会话ID
-	if claudeResponse.SessionID != "" {
-		h.redisService.SaveSessionByProjectGuid(payload.ProjectGUID, payload.AgentType, claudeResponse.SessionID)
+	// SM 起草故事必须写出故事文件，否则 Dev Agent 没有可以实现的故事
+	if payload.DevStage == common.DevStatusDraftStory && payload.StoryNumber != "" {
+		storyFile := agent.StoryFilePath(payload.StoryNumber)
+		if !utils.IsFileExists(filepath.Join(cliReq.ProjectPath, filepath.FromSlash(storyFile))) {
+			result = models.CommandResult{Success: false, Error: fmt.Sprintf(getAgentMessage(payload.Language, messageKeyStoryFileMissing), storyFile)}
+			h.handleAgentExecuteFailed(task, payload, result, usage)
+			return nil, fmt.Errorf("故事文件不存在: %s", storyFile)
+		}
 	}
+
+	// 保存会话ID和对话记录
+	h.sessionService.Record(adapter, payload.ProjectGUID, payload.AgentType, payload.Message, claudeResponse)
 	logger.Info(" ===> 代理任务执行成功",
 		logger.String("agentType", payload.AgentType),
 		logger.String("message", payload.Message),
 		logger.String("claudeResponse", claudeResponse.ToJsonString()))
 
-	err := h.gitService.CommitAndPush(ctx, payload.ProjectGUID, claudeResponse.Result)
+	commitMsg := buildCommitMessage(&payload, taskID, claudeResponse.Result)
+	merging = true
+	gitResult, err := h.gitService.CommitAndMerge(ctx, payload.ProjectGUID, branch, commitMsg, adapter.Name())
 	if err != nil {
-		logger.Error("项目文档、代码提交并推送失败",
+		logger.Error("项目文档、代码提交并合并失败",
 			logger.String("GUID", payload.ProjectGUID),
+			logger.String("branch", branch),
 			logger.String("error", err.Error()))
 
 		if task != nil {
-			tasks.UpdateResult(task.ResultWriter(), common.CommonStatusFailed, 0, err.Error())
+			// 合并冲突、校验失败等结果记录在任务结果的 git 字段中
+			tasks.UpdateResultWithGit(task.ResultWriter(), common.CommonStatusFailed, 0, err.Error(), gitResult)
 			// 发布任务失败状态
-			h.redisService.PublishTaskStatus(&payload, task.ResultWriter().TaskID(), common.CommonStatusFailed, "项目文档、代码提交并推送失败: "+err.Error())
+			h.redisService.PublishTaskStatusWithUsage(&payload, task.ResultWriter().TaskID(), common.CommonStatusFailed,
+				fmt.Sprintf(getAgentMessage(payload.Language, messageKeyCommitAndMergeFailed), err.Error()), usage)
 		}
-		return nil, fmt.Errorf("项目文档、代码提交并推送失败: %w", err)
+		return nil, fmt.Errorf("项目文档、代码提交并合并失败: %w", err)
 	}
 
 	if task != nil {
-		tasks.UpdateResult(task.ResultWriter(), common.CommonStatusDone, 100, claudeResponse.Result)
+		var review *agent.CodeReview
+		if payload.Review != nil && gitResult.Status == common.GitMergeStatusMerged && gitResult.CommitSha != "" {
+			// 合并回主干后评审 Dev Agent 的提交，阻塞的意见交给 Dev Agent 修复
+			review = h.reviewCommit(ctx, payload, gitResult.CommitSha)
+		}
+		switch {
+		case payload.DevStage == common.DevStatusQAReview && payload.StoryNumber != "":
+			// QA 评审故事的门禁结论在合并回主干后读取，后端据此决定是否允许部署；任务开始前已有的门禁文件不算数
+			gate := readQAGate(cliReq.ProjectPath, payload.StoryNumber, payload.Language, claudeResponse.Result, timeBefor)
+			tasks.UpdateResultWithQAGate(task.ResultWriter(), common.CommonStatusDone, 100, claudeResponse.Result, gitResult, gate)
+		case review != nil && review.Status == common.CodeReviewStatusBlocked:
+			// 修复轮数用完后仍有阻塞的意见，故事退回给用户处理，重试会重新实现整个故事，不再重试
+			message := codeReviewBlockedMessage(payload.Language, review)
+			tasks.UpdateResultWithCodeReview(task.ResultWriter(), common.CommonStatusFailed, 0, message, gitResult, review)
+			h.redisService.PublishTaskStatusWithUsage(&payload, task.ResultWriter().TaskID(), common.CommonStatusFailed, message, usage)
+			return nil, fmt.Errorf("代码评审仍有阻塞的意见: %w", asynq.SkipRetry)
+		case review != nil:
+			tasks.UpdateResultWithCodeReview(task.ResultWriter(), common.CommonStatusDone, 100, claudeResponse.Result, gitResult, review)
+		default:
+			tasks.UpdateResultWithGit(task.ResultWriter(), common.CommonStatusDone, 100, claudeResponse.Result, gitResult)
+		}
 		// 发布任务完成状态
-		h.redisService.PublishTaskStatus(&payload, task.ResultWriter().TaskID(), common.CommonStatusDone, "任务执行完成")
+		h.redisService.PublishTaskStatusWithUsage(&payload, task.ResultWriter().TaskID(), common.CommonStatusDone,
+			getAgentMessage(payload.Language, messageKeyTaskDone), usage)
 	}
 	return &result, nil
 }
 
-// 与指定代理对话
+// 与指定代理对话，同步调用不经过任务中间件，需要自己获取项目工作区锁（上下文已持有时直接复用）
 func (h *agentTaskService) ChatWithAgent(ctx context.Context, projectGuid, agentType, message string) (*models.CommandResult, error) {
 	if h.commandService == nil {
 		return nil, fmt.Errorf("command service is nil")
 	}
+	if h.lockService == nil {
+		return nil, fmt.Errorf("project lock service is nil")
+	}
 
 	payload := tasks.AgentExecuteTaskPayload{
 		ProjectGUID: projectGuid,
@@ -329,5 +496,76 @@ func (h *agentTaskService) ChatWithAgent(ctx context.Context, projectGuid, agent
 		Message:     message,
 		DevStage:    common.DevStatusUnknown, // 阵列用 Unknown 表示聊天
 	}
-	return h.innerProcessTask(ctx, payload, nil)
+	var result *models.CommandResult
+	err := runWithProjectLock(ctx, h.lockService, projectGuid, "chat-"+utils.GenerateUUID(), common.TaskTypeAgentChat, nil,
+		func(ctx context.Context) error {
+			var err error
+			result, err = h.innerProcessTask(ctx, payload, nil, false)
+			return err
+		})
+	return result, err
+}
+
+// CancelTask 取消任务
+func (h *agentTaskService) CancelTask(ctx context.Context, taskID string) (*tasks.AgentExecuteTaskPayload, error) {
+	if h.asyncInspector == nil {
+		return nil, fmt.Errorf("async inspector is nil")
+	}
+
+	info, err := h.findTaskInfo(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 各类任务的 payload 都包含 project_guid，对话和执行任务还包含 agent_type、dev_stage
+	payload := tasks.AgentExecuteTaskPayload{}
+	if err := json.Unmarshal(info.Payload, &payload); err != nil {
+		return nil, fmt.Errorf("解析任务数据失败: %w", err)
+	}
+
+	// 先打标记，CLI 被杀掉后任务处理方法据此忽略失败状态
+	if err := h.redisService.MarkTaskCancelled(taskID); err != nil {
+		return nil, fmt.Errorf("标记任务取消失败: %w", err)
+	}
+
+	switch info.State {
+	case asynq.TaskStateActive:
+		// 取消任务的 context，CommandContext 会杀掉整个 CLI 进程组
+		err = h.asyncInspector.CancelProcessing(taskID)
+	case asynq.TaskStatePending, asynq.TaskStateScheduled, asynq.TaskStateRetry, asynq.TaskStateAggregating:
+		err = h.asyncInspector.DeleteTask(info.Queue, taskID)
+	default:
+		return nil, fmt.Errorf("任务已结束，无法取消: %s", info.State.String())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("取消任务失败: %w", err)
+	}
+
+	h.redisService.PublishTaskStatus(&payload, taskID, common.CommonStatusCancelled, getAgentMessage(payload.Language, messageKeyTaskCancelled))
+	logger.Info("任务已取消",
+		logger.String("taskID", taskID),
+		logger.String("state", info.State.String()),
+		logger.String("projectGuid", payload.ProjectGUID))
+	return &payload, nil
+}
+
+// findTaskInfo 在所有队列中查找任务
+func (h *agentTaskService) findTaskInfo(taskID string) (*asynq.TaskInfo, error) {
+	return tasks.FindTaskInQueues(h.asyncInspector, taskID)
+}
+
+// NewTaskCancelMiddleware 创建任务取消中间件：被取消的任务不再重试
+func NewTaskCancelMiddleware(redisService RedisService) asynq.MiddlewareFunc {
+	return func(next asynq.Handler) asynq.Handler {
+		return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+			err := next.ProcessTask(ctx, task)
+			if err == nil {
+				return nil
+			}
+			if taskID, ok := asynq.GetTaskID(ctx); ok && redisService.IsTaskCancelled(taskID) {
+				return fmt.Errorf("task cancelled: %v: %w", err, asynq.SkipRetry)
+			}
+			return err
+		})
+	}
 }
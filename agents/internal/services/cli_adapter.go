@@ -0,0 +1,521 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/common"
+	"github.com/lighthought/app-maker/shared-models/utils"
+
+	"github.com/lighthought/app-maker/agents/internal/api/models"
+	"github.com/lighthought/app-maker/agents/internal/mockcli"
+)
+
+// CliRequest 一次 CLI 调用的上下文
+type CliRequest struct {
+	ProjectGuid string
+	ProjectPath string
+	AgentType   string
+	DevStage    string
+	StoryNumber string // 故事编号，不区分故事的阶段为空
+	Message     string // 已加上 Agent 提示词的完整消息
+	SessionID   string // 为空时开启新会话
+	Language    string // 项目输出语言
+}
+
+// CliAdapter 编码 CLI 适配器，新增一种 CLI 只需要实现并注册一个适配器
+type CliAdapter interface {
+	// 适配器名称，与 common.CliToolXXX 一致
+	Name() string
+
+	// 项目内的 CLI 配置目录，用于检测项目使用的 CLI 以及 bmad-method 是否已安装
+	ConfigDir() string
+
+	// 构建 CLI 命令和参数
+	BuildCommand(req *CliRequest) (string, []string)
+
+	// 解析 CLI 输出为统一的结果
+	ParseOutput(output string, durationMs int) (*models.ClaudeResponse, error)
+
+	// 是否支持通过会话ID恢复上下文
+	SupportsSession() bool
+
+	// 输出中是否包含 token 用量，不包含时上报的用量标记为未知
+	ReportsUsage() bool
+
+	// 获取指定 Agent 的提示词文件路径（相对项目根目录）
+	AgentPromptPath(agentType string) string
+
+	// 把项目的模型配置转换为 CLI 进程的环境变量（KEY=VALUE），cfg 为空时返回 nil；CLI 不支持该模型提供商时返回错误
+	BuildEnv(cfg *agent.ProjectModelConfig) ([]string, error)
+}
+
+// CliRunner 在进程内执行的 CLI，适配器实现该接口时不再通过 BuildCommand 启动外部进程
+type CliRunner interface {
+	Run(ctx context.Context, req *CliRequest, onLine OutputLineHandler) models.CommandResult
+}
+
+// CliLogFormatter 把 CLI 的原始输出行转换为可读的日志，适配器输出结构化事件时实现该接口
+type CliLogFormatter interface {
+	// 返回转换后的日志行，ok 为 false 时该行不推送到日志流
+	FormatLogLine(line string) (formatted string, ok bool)
+}
+
+// CliAdapterRegistry CLI 适配器注册表
+type CliAdapterRegistry interface {
+	// 注册适配器，同名覆盖
+	Register(adapter CliAdapter)
+
+	// 获取适配器，未注册的名称返回默认适配器
+	Get(cliTool string) CliAdapter
+
+	// 根据项目目录下的 CLI 配置目录检测项目使用的 CLI，检测不到返回默认适配器名称
+	Detect(projectPath string) string
+
+	// 已注册的适配器名称，按注册顺序
+	Names() []string
+}
+
+type cliAdapterRegistry struct {
+	mu          sync.RWMutex
+	adapters    map[string]CliAdapter
+	names       []string
+	defaultName string
+}
+
+// NewCliAdapterRegistry 创建 CLI 适配器注册表，并注册内置的 claude、qwen、gemini、mock 适配器
+// mockFixturesPath 为 mock CLI 的剧本目录，为空时使用内置剧本
+func NewCliAdapterRegistry(mockFixturesPath string) CliAdapterRegistry {
+	registry := &cliAdapterRegistry{
+		adapters:    make(map[string]CliAdapter),
+		defaultName: common.CliToolClaudeCode,
+	}
+	registry.Register(&claudeCodeAdapter{})
+	registry.Register(&textCliAdapter{
+		name:       common.CliToolQwenCode,
+		command:    "qwen",
+		configDir:  ".qwen",
+		promptPath: "bmad/%s.mdc",
+		envKeys: modelEnvKeys{
+			BaseURL: "OPENAI_BASE_URL",
+			ApiKey:  "OPENAI_API_KEY",
+			Model:   "OPENAI_MODEL",
+		},
+		openAICompatible: true,
+	})
+	registry.Register(&textCliAdapter{
+		name:       common.CliToolGemini,
+		command:    "gemini",
+		configDir:  ".gemini",
+		promptPath: ".bmad-core/agents/%s.md",
+		envKeys: modelEnvKeys{
+			BaseURL: "GOOGLE_GEMINI_BASE_URL",
+			ApiKey:  "GEMINI_API_KEY",
+			Model:   "GEMINI_MODEL",
+		},
+		// gemini 只支持 Gemini 协议，本地部署的 OpenAI 兼容服务无法使用
+		unsupportedProviders: []string{common.ModelProviderOllama, common.ModelProviderVLLM},
+	})
+	registry.Register(&mockCliAdapter{runner: mockcli.NewRunner(mockFixturesPath)})
+	return registry
+}
+
+// Register 注册适配器
+func (r *cliAdapterRegistry) Register(adapter CliAdapter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.adapters[adapter.Name()]; !ok {
+		r.names = append(r.names, adapter.Name())
+	}
+	r.adapters[adapter.Name()] = adapter
+}
+
+// Get 获取适配器
+func (r *cliAdapterRegistry) Get(cliTool string) CliAdapter {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if adapter, ok := r.adapters[cliTool]; ok {
+		return adapter
+	}
+	return r.adapters[r.defaultName]
+}
+
+// Detect 检测项目使用的 CLI
+func (r *cliAdapterRegistry) Detect(projectPath string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, name := range r.names {
+		configDir := r.adapters[name].ConfigDir()
+		if configDir != "" && utils.IsDirectoryExists(filepath.Join(projectPath, configDir)) {
+			return name
+		}
+	}
+	return r.defaultName // 默认
+}
+
+// Names 已注册的适配器名称
+func (r *cliAdapterRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]string(nil), r.names...)
+}
+
+// buildAgentMessage 在消息前加上 Agent 提示词文件引用，如 @bmad/dev.mdc
+func buildAgentMessage(adapter CliAdapter, agentType, message string) string {
+	promptPath := adapter.AgentPromptPath(agentType)
+	if promptPath == "" {
+		return message
+	}
+	return "@" + promptPath + " " + message
+}
+
+// modelEnvKeys CLI 读取模型配置使用的环境变量名
+type modelEnvKeys struct {
+	BaseURL string
+	ApiKey  string
+	Model   string
+}
+
+// buildModelEnv 按环境变量名生成 KEY=VALUE 列表，跳过为空的值，保留进程原有的配置
+func buildModelEnv(keys modelEnvKeys, baseURL, apiKey, model string) []string {
+	var env []string
+	for _, item := range [][2]string{{keys.BaseURL, baseURL}, {keys.ApiKey, apiKey}, {keys.Model, model}} {
+		if item[0] != "" && item[1] != "" {
+			env = append(env, item[0]+"="+item[1])
+		}
+	}
+	return env
+}
+
+// claudeCodeAdapter Claude Code 适配器，按行输出 stream-json 事件，支持 --resume 恢复会话
+type claudeCodeAdapter struct{}
+
+func (a *claudeCodeAdapter) Name() string {
+	return common.CliToolClaudeCode
+}
+
+func (a *claudeCodeAdapter) ConfigDir() string {
+	return ".claude"
+}
+
+func (a *claudeCodeAdapter) BuildCommand(req *CliRequest) (string, []string) {
+	args := []string{"--dangerously-skip-permissions"}
+	if req.SessionID != "" {
+		args = append(args, "--resume", req.SessionID)
+	}
+	// stream-json 需要同时指定 --verbose，运行过程中逐条输出事件，最后一行为 result 事件
+	args = append(args, "--output-format", "stream-json", "--verbose", "-p", "\""+req.Message+"\"")
+	return "claude", args
+}
+
+func (a *claudeCodeAdapter) ParseOutput(output string, durationMs int) (*models.ClaudeResponse, error) {
+	return parseClaudeStreamOutput(output, durationMs)
+}
+
+// FormatLogLine 把 stream-json 事件转换为可读日志：助手文本和工具调用推送到日志流，
+// 工具结果和最终的 result 事件已包含在任务结果中，不再推送
+func (a *claudeCodeAdapter) FormatLogLine(line string) (string, bool) {
+	var event claudeStreamEvent
+	if err := json.Unmarshal([]byte(line), &event); err != nil || event.Type == "" {
+		return line, true // 非 JSON 行原样输出
+	}
+
+	switch event.Type {
+	case "system":
+		if event.SessionID == "" {
+			return "", false
+		}
+		return "[session] " + event.SessionID, true
+	case "assistant":
+		if event.Message == nil {
+			return "", false
+		}
+		var parts []string
+		for _, content := range event.Message.Content {
+			switch content.Type {
+			case "text":
+				if text := strings.TrimSpace(content.Text); text != "" {
+					parts = append(parts, text)
+				}
+			case "tool_use":
+				parts = append(parts, "[tool] "+content.Name)
+			}
+		}
+		if len(parts) == 0 {
+			return "", false
+		}
+		return strings.Join(parts, "\n"), true
+	default:
+		return "", false
+	}
+}
+
+func (a *claudeCodeAdapter) SupportsSession() bool {
+	return true
+}
+
+func (a *claudeCodeAdapter) ReportsUsage() bool {
+	return true
+}
+
+func (a *claudeCodeAdapter) AgentPromptPath(agentType string) string {
+	return "bmad/" + agentType + ".mdc"
+}
+
+// BuildEnv claude 通过 ANTHROPIC_* 环境变量切换兼容 Anthropic 协议的服务（如智谱），
+// ollama、vllm、openai 只提供 OpenAI 兼容协议，claude 无法使用
+func (a *claudeCodeAdapter) BuildEnv(cfg *agent.ProjectModelConfig) ([]string, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if err := checkModelProvider(a.Name(), cfg.ModelProvider,
+		common.ModelProviderOllama, common.ModelProviderVLLM, common.ModelProviderOpenAI); err != nil {
+		return nil, err
+	}
+	return buildModelEnv(modelEnvKeys{
+		BaseURL: "ANTHROPIC_BASE_URL",
+		ApiKey:  "ANTHROPIC_AUTH_TOKEN",
+		Model:   "ANTHROPIC_MODEL",
+	}, cfg.ModelApiUrl, cfg.ApiToken, cfg.AiModel), nil
+}
+
+// checkModelProvider 模型提供商在 unsupported 中时返回错误
+func checkModelProvider(cliTool, provider string, unsupported ...string) error {
+	for _, item := range unsupported {
+		if provider == item {
+			return fmt.Errorf("%s 不支持模型提供商 %s，请更换 CLI 工具或模型提供商", cliTool, provider)
+		}
+	}
+	return nil
+}
+
+// textCliAdapter 纯文本输出的 CLI 适配器（qwen、gemini），不支持恢复会话
+type textCliAdapter struct {
+	name                 string
+	command              string
+	configDir            string
+	promptPath           string       // 提示词路径模板，%s 为 Agent 类型
+	envKeys              modelEnvKeys // 模型配置对应的环境变量名
+	openAICompatible     bool         // 是否使用 OpenAI 兼容协议，本地部署的 ollama、vllm 需要补全 /v1
+	unsupportedProviders []string     // 协议不兼容的模型提供商
+}
+
+func (a *textCliAdapter) Name() string {
+	return a.name
+}
+
+func (a *textCliAdapter) ConfigDir() string {
+	return a.configDir
+}
+
+func (a *textCliAdapter) BuildCommand(req *CliRequest) (string, []string) {
+	return a.command, []string{"-y", "-p", "\"" + req.Message + "\""}
+}
+
+func (a *textCliAdapter) ParseOutput(output string, durationMs int) (*models.ClaudeResponse, error) {
+	return newTextResponse(output, durationMs), nil
+}
+
+func (a *textCliAdapter) SupportsSession() bool {
+	return false
+}
+
+// ReportsUsage qwen、gemini 以纯文本输出，不包含 token 用量
+func (a *textCliAdapter) ReportsUsage() bool {
+	return false
+}
+
+func (a *textCliAdapter) AgentPromptPath(agentType string) string {
+	if a.promptPath == "" {
+		return ""
+	}
+	return fmt.Sprintf(a.promptPath, agentType)
+}
+
+func (a *textCliAdapter) BuildEnv(cfg *agent.ProjectModelConfig) ([]string, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if err := checkModelProvider(a.name, cfg.ModelProvider, a.unsupportedProviders...); err != nil {
+		return nil, err
+	}
+	baseURL, apiKey := cfg.ModelApiUrl, cfg.ApiToken
+	if a.openAICompatible && (cfg.ModelProvider == common.ModelProviderOllama || cfg.ModelProvider == common.ModelProviderVLLM) {
+		if baseURL != "" && !strings.HasSuffix(strings.TrimRight(baseURL, "/"), "/v1") {
+			baseURL = strings.TrimRight(baseURL, "/") + "/v1"
+		}
+		// 本地部署的模型不校验 key，但 OpenAI 兼容的 CLI 要求 key 不能为空
+		if apiKey == "" {
+			apiKey = "EMPTY"
+		}
+	}
+	return buildModelEnv(a.envKeys, baseURL, apiKey, cfg.AiModel), nil
+}
+
+// mockCliAdapter 离线调试用的 mock CLI，按 Agent 类型和开发阶段回放剧本，写入预期的文档并输出 Claude 风格的 JSON
+type mockCliAdapter struct {
+	runner mockcli.Runner
+}
+
+func (a *mockCliAdapter) Name() string {
+	return common.CliToolMock
+}
+
+// ConfigDir mock 不需要安装 bmad-method，也不参与项目 CLI 检测
+func (a *mockCliAdapter) ConfigDir() string {
+	return ""
+}
+
+func (a *mockCliAdapter) BuildCommand(req *CliRequest) (string, []string) {
+	return common.CliToolMock, nil
+}
+
+func (a *mockCliAdapter) ParseOutput(output string, durationMs int) (*models.ClaudeResponse, error) {
+	return parseClaudeJSONOutput(output, durationMs)
+}
+
+func (a *mockCliAdapter) SupportsSession() bool {
+	return true
+}
+
+func (a *mockCliAdapter) ReportsUsage() bool {
+	return true
+}
+
+func (a *mockCliAdapter) AgentPromptPath(agentType string) string {
+	return "bmad/" + agentType + ".mdc"
+}
+
+// BuildEnv mock 在进程内执行，不需要模型配置
+func (a *mockCliAdapter) BuildEnv(cfg *agent.ProjectModelConfig) ([]string, error) {
+	return nil, nil
+}
+
+func (a *mockCliAdapter) Run(ctx context.Context, req *CliRequest, onLine OutputLineHandler) models.CommandResult {
+	output, err := a.runner.Run(ctx, &mockcli.Request{
+		ProjectGuid: req.ProjectGuid,
+		ProjectPath: req.ProjectPath,
+		AgentType:   req.AgentType,
+		DevStage:    req.DevStage,
+		StoryNumber: req.StoryNumber,
+		Message:     req.Message,
+		SessionID:   req.SessionID,
+		Language:    req.Language,
+	}, func(line string) {
+		if onLine != nil {
+			onLine(common.AgentLogStreamStdout, line)
+		}
+	})
+	if err != nil {
+		return models.CommandResult{Success: false, Output: output, Error: err.Error()}
+	}
+	return models.CommandResult{Success: true, Output: output}
+}
+
+// buildAgentUsage 转换上报给后端的用量，CLI 不输出用量时标记为未知，response 为空时只上报耗时
+func buildAgentUsage(adapter CliAdapter, response *models.ClaudeResponse, durationMs int) *agent.AgentUsage {
+	if !adapter.ReportsUsage() {
+		return &agent.AgentUsage{CliTool: adapter.Name(), DurationMs: durationMs, UsageUnknown: true}
+	}
+	if response == nil {
+		return nil
+	}
+	return response.ToAgentUsage(adapter.Name())
+}
+
+// claudeStreamEvent claude --output-format stream-json 输出的一行事件，只保留日志需要的字段
+type claudeStreamEvent struct {
+	Type      string `json:"type"`
+	Subtype   string `json:"subtype"`
+	SessionID string `json:"session_id"`
+	Message   *struct {
+		ID      string `json:"id"`
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+			Name string `json:"name"`
+		} `json:"content"`
+		Usage *claudeStreamUsage `json:"usage"`
+	} `json:"message"`
+}
+
+// claudeStreamUsage assistant 事件中单条消息的用量
+type claudeStreamUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+// parseClaudeStreamOutput 从 stream-json 输出中取最后一个 result 事件，整段输出是单个 JSON 时按 json 格式解析
+func parseClaudeStreamOutput(output string, durationMs int) (*models.ClaudeResponse, error) {
+	lines := strings.Split(output, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if !strings.HasPrefix(line, "{") || !strings.Contains(line, `"result"`) {
+			continue
+		}
+		var event claudeStreamEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil || event.Type != "result" {
+			continue
+		}
+		return parseClaudeJSONOutput(line, durationMs)
+	}
+
+	response, err := parseClaudeJSONOutput(output, durationMs)
+	if err != nil {
+		// 进程中途退出时没有 result 事件，累计 assistant 事件中已经消耗的 token
+		sumClaudeStreamUsage(lines, response)
+	}
+	return response, err
+}
+
+// sumClaudeStreamUsage 按消息 ID 去重后累计 assistant 事件的用量
+func sumClaudeStreamUsage(lines []string, response *models.ClaudeResponse) {
+	usages := make(map[string]*claudeStreamUsage)
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "{") || !strings.Contains(line, `"usage"`) {
+			continue
+		}
+		var event claudeStreamEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil || event.Type != "assistant" ||
+			event.Message == nil || event.Message.Usage == nil {
+			continue
+		}
+		// 同一条消息的多个内容块会重复携带用量，以最后一次为准
+		usages[event.Message.ID] = event.Message.Usage
+	}
+	for _, usage := range usages {
+		response.Usage.InputTokens += usage.InputTokens
+		response.Usage.OutputTokens += usage.OutputTokens
+		response.Usage.CacheCreationInputTokens += usage.CacheCreationInputTokens
+		response.Usage.CacheReadInputTokens += usage.CacheReadInputTokens
+	}
+}
+
+// parseClaudeJSONOutput 解析 Claude 风格的 JSON 输出
+func parseClaudeJSONOutput(output string, durationMs int) (*models.ClaudeResponse, error) {
+	response := newTextResponse(output, durationMs)
+	if err := json.Unmarshal([]byte(output), response); err != nil {
+		// 解析失败时保留原始输出，由调用方决定如何处理
+		return newTextResponse(output, durationMs), fmt.Errorf("解析 CLI JSON 输出失败: %w", err)
+	}
+	return response, nil
+}
+
+// newTextResponse 用原始输出构建成功结果
+func newTextResponse(output string, durationMs int) *models.ClaudeResponse {
+	return &models.ClaudeResponse{
+		Type:          "result",
+		Subtype:       "success",
+		DurationMs:    durationMs,
+		DurationApiMs: durationMs,
+		Result:        output,
+	}
+}
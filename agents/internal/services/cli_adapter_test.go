@@ -0,0 +1,151 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/common"
+)
+
+func TestParseClaudeStreamOutput(t *testing.T) {
+	tests := []struct {
+		name      string
+		output    string
+		want      string
+		wantCost  float64
+		wantError bool
+	}{
+		{
+			name: "stream json",
+			output: strings.Join([]string{
+				`{"type":"system","subtype":"init","session_id":"s-1"}`,
+				`{"type":"assistant","message":{"content":[{"type":"text","text":"working"}]}}`,
+				`{"type":"result","subtype":"success","result":"done","session_id":"s-1","total_cost_usd":0.5}`,
+			}, "\n"),
+			want:     "done",
+			wantCost: 0.5,
+		},
+		{
+			name:   "single json",
+			output: `{"type":"result","subtype":"success","result":"ok"}`,
+			want:   "ok",
+		},
+		{
+			name:      "plain text",
+			output:    "command not found",
+			want:      "command not found",
+			wantError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := parseClaudeStreamOutput(tt.output, 10)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("err = %v, wantError %v", err, tt.wantError)
+			}
+			if response.Result != tt.want || response.TotalCostUsd != tt.wantCost {
+				t.Errorf("response = %+v", response)
+			}
+		})
+	}
+}
+
+func TestParseClaudeStreamOutputPartialUsage(t *testing.T) {
+	output := strings.Join([]string{
+		`{"type":"system","subtype":"init","session_id":"s-1"}`,
+		`{"type":"assistant","message":{"id":"m-1","content":[{"type":"text","text":"a"}],"usage":{"input_tokens":10,"output_tokens":5}}}`,
+		`{"type":"assistant","message":{"id":"m-1","content":[{"type":"tool_use","name":"Bash"}],"usage":{"input_tokens":10,"output_tokens":5}}}`,
+		`{"type":"assistant","message":{"id":"m-2","content":[{"type":"text","text":"b"}],"usage":{"input_tokens":20,"output_tokens":7,"cache_read_input_tokens":3}}}`,
+		"Error: process killed",
+	}, "\n")
+
+	response, err := parseClaudeStreamOutput(output, 10)
+	if err == nil {
+		t.Fatal("expected error without result event")
+	}
+	if response.Usage.InputTokens != 30 || response.Usage.OutputTokens != 12 || response.Usage.CacheReadInputTokens != 3 {
+		t.Errorf("usage = %+v", response.Usage)
+	}
+	if response.ToAgentUsage("claude") == nil {
+		t.Error("partial usage should be reported")
+	}
+}
+
+func TestBuildAgentUsage(t *testing.T) {
+	registry := NewCliAdapterRegistry("")
+
+	// qwen、gemini 不输出用量，标记为未知并保留耗时
+	for _, cliTool := range []string{common.CliToolQwenCode, common.CliToolGemini} {
+		adapter := registry.Get(cliTool)
+		response, _ := adapter.ParseOutput("done", 100)
+		usage := buildAgentUsage(adapter, response, 100)
+		if usage == nil || !usage.UsageUnknown || usage.CliTool != cliTool || usage.DurationMs != 100 {
+			t.Errorf("%s usage = %+v", cliTool, usage)
+		}
+	}
+
+	claude := registry.Get(common.CliToolClaudeCode)
+	response, _ := claude.ParseOutput(`{"type":"result","result":"ok","usage":{"input_tokens":10,"output_tokens":5}}`, 100)
+	if usage := buildAgentUsage(claude, response, 100); usage == nil || usage.UsageUnknown || usage.InputTokens != 10 {
+		t.Errorf("claude usage = %+v", usage)
+	}
+	if usage := buildAgentUsage(claude, nil, 100); usage != nil {
+		t.Errorf("claude usage without output = %+v", usage)
+	}
+}
+
+func TestClaudeFormatLogLine(t *testing.T) {
+	adapter := &claudeCodeAdapter{}
+	tests := []struct {
+		line   string
+		want   string
+		wantOk bool
+	}{
+		{line: "plain stderr text", want: "plain stderr text", wantOk: true},
+		{line: `{"type":"system","subtype":"init","session_id":"s-1"}`, want: "[session] s-1", wantOk: true},
+		{line: `{"type":"assistant","message":{"content":[{"type":"text","text":"hello"},{"type":"tool_use","name":"Write"}]}}`, want: "hello\n[tool] Write", wantOk: true},
+		{line: `{"type":"user","message":{"content":[{"type":"tool_result"}]}}`},
+		{line: `{"type":"result","result":"done"}`},
+	}
+	for _, tt := range tests {
+		got, ok := adapter.FormatLogLine(tt.line)
+		if got != tt.want || ok != tt.wantOk {
+			t.Errorf("FormatLogLine(%s) = %q, %v; want %q, %v", tt.line, got, ok, tt.want, tt.wantOk)
+		}
+	}
+}
+
+func TestBuildEnvModelProvider(t *testing.T) {
+	registry := NewCliAdapterRegistry("")
+	tests := []struct {
+		cliTool  string
+		provider string
+		wantEnv  string
+		wantErr  bool
+	}{
+		{cliTool: common.CliToolClaudeCode, provider: common.ModelProviderZhipu, wantEnv: "ANTHROPIC_BASE_URL=http://model"},
+		{cliTool: common.CliToolClaudeCode, provider: common.ModelProviderAnthropic, wantEnv: "ANTHROPIC_BASE_URL=http://model"},
+		{cliTool: common.CliToolClaudeCode, provider: common.ModelProviderOllama, wantErr: true},
+		{cliTool: common.CliToolClaudeCode, provider: common.ModelProviderVLLM, wantErr: true},
+		{cliTool: common.CliToolClaudeCode, provider: common.ModelProviderOpenAI, wantErr: true},
+		{cliTool: common.CliToolQwenCode, provider: common.ModelProviderOllama, wantEnv: "OPENAI_BASE_URL=http://model/v1"},
+		{cliTool: common.CliToolGemini, provider: common.ModelProviderVLLM, wantErr: true},
+		{cliTool: common.CliToolMock, provider: common.ModelProviderOllama},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cliTool+"/"+tt.provider, func(t *testing.T) {
+			env, err := registry.Get(tt.cliTool).BuildEnv(&agent.ProjectModelConfig{
+				ModelProvider: tt.provider,
+				ModelApiUrl:   "http://model",
+				AiModel:       "model-1",
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BuildEnv() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantEnv != "" && !strings.Contains(strings.Join(env, "\n"), tt.wantEnv) {
+				t.Errorf("BuildEnv() = %v, want %s", env, tt.wantEnv)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/common"
+	"github.com/lighthought/app-maker/shared-models/logger"
+	"github.com/lighthought/app-maker/shared-models/tasks"
+)
+
+// 评审提示词中 diff 的最大字节数，超出部分截断
+const maxReviewDiffBytes = 64 * 1024
+
+// 没有配置修复轮数时 Dev Agent 最多修复的轮数
+const defaultReviewFixRounds = 2
+
+// 评审 Agent 回答中的 json 代码块
+var reviewJSONBlockPattern = regexp.MustCompile("(?s)```json\\s*(.*?)```")
+
+// codeReviewPromptData code_review 提示词模板的数据
+type codeReviewPromptData struct {
+	StoryNumber        string
+	StoryFile          string
+	CommitSha          string // 被评审的提交，修复后为修复前后主干提交的范围
+	Round              int
+	Diff               string
+	BlockingSeverities string // 阻塞的严重程度，逗号分隔，为空时意见只记录
+}
+
+// reviewAnswer 评审 Agent 回答最后的 json 代码块
+type reviewAnswer struct {
+	Summary  string `json:"summary"`
+	Comments []struct {
+		File     string `json:"file"`
+		Line     int    `json:"line"`
+		Severity string `json:"severity"`
+		Message  string `json:"message"`
+	} `json:"comments"`
+}
+
+// reviewCommit 评审 Dev Agent 合并的提交：有阻塞的意见且还有修复轮数时交给 Dev Agent 修复，再评审修复的变更，
+// 直到没有阻塞的意见或轮数用完。评审、修复都在当前任务持有的工作区锁内同步执行
+func (h *agentTaskService) reviewCommit(ctx context.Context, payload tasks.AgentExecuteTaskPayload, commitSha string) *agent.CodeReview {
+	config := payload.Review
+	maxFixRounds := config.MaxFixRounds
+	if maxFixRounds <= 0 {
+		maxFixRounds = defaultReviewFixRounds
+	}
+	review := &agent.CodeReview{StoryNumber: payload.StoryNumber, CommitSha: commitSha, Status: common.CodeReviewStatusPassed}
+	fail := func(err error) *agent.CodeReview {
+		logger.Warn("代码评审失败",
+			logger.String("projectGuid", payload.ProjectGUID),
+			logger.String("storyNumber", payload.StoryNumber),
+			logger.String("error", err.Error()))
+		review.Status = common.CodeReviewStatusFailed
+		review.Error = err.Error()
+		return review
+	}
+
+	reviewed := commitSha
+	diff, err := h.gitService.DiffCommits(ctx, payload.ProjectGUID, "", commitSha)
+	if err != nil {
+		return fail(err)
+	}
+	for round := 1; strings.TrimSpace(diff) != ""; round++ {
+		review.Rounds = round
+		summary, comments, err := h.runReviewer(ctx, payload, reviewed, round, diff)
+		if err != nil {
+			return fail(err)
+		}
+		review.Summary = summary
+		review.Comments = append(review.Comments, comments...)
+
+		blocking := blockingComments(comments)
+		if len(blocking) == 0 {
+			if round > 1 {
+				review.Status = common.CodeReviewStatusFixed
+			}
+			return review
+		}
+		if round > maxFixRounds {
+			review.Status = common.CodeReviewStatusBlocked
+			return review
+		}
+
+		// 把阻塞的意见交给 Dev Agent 修复，修复在自己的分支上执行，构建和测试通过后合并回主干
+		before, err := h.gitService.GetBaseHead(ctx, payload.ProjectGUID)
+		if err != nil {
+			return fail(err)
+		}
+		fixPayload := payload
+		fixPayload.AgentType = common.AgentTypeDev
+		fixPayload.DevStage = common.DevStatusCodeReview
+		fixPayload.Message = fmt.Sprintf(getAgentMessage(payload.Language, messageKeyCodeReviewFixPrompt),
+			round, maxFixRounds, formatReviewComments(blocking))
+		fixPayload.Prompt = nil
+		fixPayload.Review = nil
+		review.FixRounds = round
+		if _, err := h.innerProcessTask(ctx, fixPayload, nil, true); err != nil {
+			// 修复失败（如构建、测试未通过）时阻塞的意见仍未解决
+			fail(err)
+			review.Status = common.CodeReviewStatusBlocked
+			return review
+		}
+		after, err := h.gitService.GetBaseHead(ctx, payload.ProjectGUID)
+		if err != nil {
+			return fail(err)
+		}
+		if after.CommitSha == before.CommitSha {
+			// Dev Agent 没有做任何修改，再评审也不会有变化
+			review.Status = common.CodeReviewStatusBlocked
+			return review
+		}
+		reviewed = shortSha(before.CommitSha) + ".." + shortSha(after.CommitSha)
+		if diff, err = h.gitService.DiffCommits(ctx, payload.ProjectGUID, before.CommitSha, after.CommitSha); err != nil {
+			return fail(err)
+		}
+	}
+	return review
+}
+
+// runReviewer 渲染评审提示词，由 QA Agent 评审 diff，返回评审摘要和意见
+func (h *agentTaskService) runReviewer(ctx context.Context, payload tasks.AgentExecuteTaskPayload, commitSha string, round int,
+	diff string) (string, []*agent.ReviewComment, error) {
+	config := payload.Review
+	data := &codeReviewPromptData{
+		StoryNumber:        payload.StoryNumber,
+		CommitSha:          commitSha,
+		Round:              round,
+		Diff:               truncateDiff(diff, maxReviewDiffBytes),
+		BlockingSeverities: strings.Join(config.BlockingSeverities, ", "),
+	}
+	if payload.StoryNumber != "" {
+		data.StoryFile = agent.StoryFilePath(payload.StoryNumber)
+	}
+	renderedPrompt, err := h.promptRegistry.Render(common.PromptNameCodeReview, payload.Language, config.PromptOverride, data)
+	if err != nil {
+		return "", nil, err
+	}
+
+	reviewPayload := payload
+	reviewPayload.AgentType = common.AgentTypeQA
+	reviewPayload.DevStage = common.DevStatusCodeReview
+	reviewPayload.Message = renderedPrompt.Content
+	reviewPayload.Prompt = renderedPrompt
+	reviewPayload.Review = nil
+	result, err := h.innerProcessTask(ctx, reviewPayload, nil, true)
+	if err != nil {
+		return "", nil, err
+	}
+
+	summary, comments, ok := parseReviewAnswer(result.Output, round, config)
+	if !ok {
+		return "", nil, fmt.Errorf("%s", getAgentMessage(payload.Language, messageKeyCodeReviewNoFindings))
+	}
+	return summary, comments, nil
+}
+
+// parseReviewAnswer 解析评审 Agent 回答中最后一个 json 代码块，没有代码块时把整个回答当作 json；
+// 无法识别的严重程度按 medium 处理
+func parseReviewAnswer(answer string, round int, config *agent.CodeReviewConfig) (string, []*agent.ReviewComment, bool) {
+	content := strings.TrimSpace(answer)
+	if matches := reviewJSONBlockPattern.FindAllStringSubmatch(answer, -1); len(matches) > 0 {
+		content = matches[len(matches)-1][1]
+	}
+
+	var parsed reviewAnswer
+	if err := json.Unmarshal([]byte(content), &parsed); err != nil {
+		return "", nil, false
+	}
+
+	comments := make([]*agent.ReviewComment, 0, len(parsed.Comments))
+	for _, item := range parsed.Comments {
+		if strings.TrimSpace(item.Message) == "" {
+			continue
+		}
+		severity := normalizeReviewSeverity(item.Severity)
+		comments = append(comments, &agent.ReviewComment{
+			Round:    round,
+			File:     strings.TrimPrefix(strings.TrimSpace(item.File), "./"),
+			Line:     max(item.Line, 0),
+			Severity: severity,
+			Message:  strings.TrimSpace(item.Message),
+			Blocking: config.IsBlocking(severity),
+		})
+	}
+	return strings.TrimSpace(parsed.Summary), comments, true
+}
+
+// normalizeReviewSeverity 严重程度转换为小写，无法识别时为 medium
+func normalizeReviewSeverity(severity string) string {
+	severity = strings.ToLower(strings.TrimSpace(severity))
+	for _, known := range common.ReviewSeverities {
+		if severity == known {
+			return severity
+		}
+	}
+	return common.ReviewSeverityMedium
+}
+
+// blockingComments 筛选阻塞的评审意见
+func blockingComments(comments []*agent.ReviewComment) []*agent.ReviewComment {
+	var blocking []*agent.ReviewComment
+	for _, comment := range comments {
+		if comment.Blocking {
+			blocking = append(blocking, comment)
+		}
+	}
+	return blocking
+}
+
+// formatReviewComments 评审意见列表，每条一行，如 - [high] internal/user.go:42 缺少错误处理
+func formatReviewComments(comments []*agent.ReviewComment) string {
+	var builder strings.Builder
+	for _, comment := range comments {
+		location := comment.File
+		if comment.Line > 0 {
+			location += fmt.Sprintf(":%d", comment.Line)
+		}
+		builder.WriteString(fmt.Sprintf("- [%s] %s %s\n", comment.Severity, location, comment.Message))
+	}
+	return strings.TrimRight(builder.String(), "\n")
+}
+
+// codeReviewBlockedMessage 修复轮数用完后仍有阻塞意见时的任务失败原因，列出最后一轮的阻塞意见
+func codeReviewBlockedMessage(language string, review *agent.CodeReview) string {
+	var last []*agent.ReviewComment
+	for _, comment := range blockingComments(review.Comments) {
+		if comment.Round == review.Rounds {
+			last = append(last, comment)
+		}
+	}
+	return fmt.Sprintf(getAgentMessage(language, messageKeyCodeReviewBlocked), len(last), review.FixRounds, formatReviewComments(last))
+}
+
+// shortSha 提交 SHA 的前 8 位
+func shortSha(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/common"
+)
+
+func TestParseReviewAnswer(t *testing.T) {
+	config := &agent.CodeReviewConfig{BlockingSeverities: []string{"critical", "HIGH"}}
+	answer := "## 评审完成\n\n示例格式：\n```json\n{\"summary\": \"示例\", \"comments\": []}\n```\n\n" +
+		"```json\n{\"summary\": \"缺少错误处理\", \"comments\": [" +
+		"{\"file\": \"./backend/internal/user.go\", \"line\": 42, \"severity\": \"High\", \"message\": \"忽略了 Save 返回的错误\"}," +
+		"{\"file\": \"README.md\", \"line\": -1, \"severity\": \"nit\", \"message\": \"补充说明\"}," +
+		"{\"file\": \"main.go\", \"severity\": \"low\", \"message\": \" \"}]}\n```"
+
+	// 以最后一个 json 代码块为准，空意见忽略
+	summary, comments, ok := parseReviewAnswer(answer, 2, config)
+	if !ok || summary != "缺少错误处理" || len(comments) != 2 {
+		t.Fatalf("parseReviewAnswer() = %q, %+v, %v", summary, comments, ok)
+	}
+	first := comments[0]
+	if first.Round != 2 || first.File != "backend/internal/user.go" || first.Line != 42 ||
+		first.Severity != common.ReviewSeverityHigh || !first.Blocking {
+		t.Errorf("comments[0] = %+v", first)
+	}
+	// 无法识别的严重程度按 medium 处理，负数行号为 0
+	if second := comments[1]; second.Severity != common.ReviewSeverityMedium || second.Line != 0 || second.Blocking {
+		t.Errorf("comments[1] = %+v", second)
+	}
+
+	// 没有代码块时把整个回答当作 json
+	if _, comments, ok := parseReviewAnswer(`{"summary": "ok", "comments": []}`, 1, config); !ok || len(comments) != 0 {
+		t.Errorf("plain json = %+v, %v", comments, ok)
+	}
+	if _, _, ok := parseReviewAnswer("看起来没有问题", 1, config); ok {
+		t.Error("answer without json should not be parsed")
+	}
+}
+
+func TestCodeReviewBlockedMessage(t *testing.T) {
+	review := &agent.CodeReview{Status: common.CodeReviewStatusBlocked, Rounds: 3, FixRounds: 2, Comments: []*agent.ReviewComment{
+		{Round: 1, File: "a.go", Line: 1, Severity: "high", Message: "old", Blocking: true},
+		{Round: 3, File: "b.go", Line: 7, Severity: "critical", Message: "SQL 注入", Blocking: true},
+		{Round: 3, File: "c.go", Severity: "low", Message: "命名"},
+	}}
+	// 只列出最后一轮的阻塞意见
+	message := codeReviewBlockedMessage(common.LanguageZhCN, review)
+	if !strings.Contains(message, "1 条阻塞的意见") || !strings.Contains(message, "修复 2 轮") ||
+		!strings.Contains(message, "- [critical] b.go:7 SQL 注入") || strings.Contains(message, "a.go") || strings.Contains(message, "c.go") {
+		t.Errorf("codeReviewBlockedMessage() = %q", message)
+	}
+}
@@ -1,16 +1,20 @@
 package services
 
 import (
+	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/lighthought/app-maker/agents/internal/api/models"
 	"github.com/lighthought/app-maker/agents/internal/config"
+	"github.com/lighthought/app-maker/shared-models/common"
 )
 
 // CommandService 命令执行服务，负责按项目维护会话执行命令
@@ -19,8 +23,15 @@ type commandService struct {
 	WorkspacePath string
 }
 
+// OutputLineHandler 命令输出行回调，stream 为 stdout 或 stderr
+type OutputLineHandler func(stream, line string)
+
 type CommandService interface {
 	SimpleExecute(ctx context.Context, subfolder, process string, arg ...string) models.CommandResult
+	// 执行命令，并把 stdout/stderr 按行实时回调
+	StreamExecute(ctx context.Context, subfolder string, onLine OutputLineHandler, process string, arg ...string) models.CommandResult
+	// 执行命令，在当前进程环境变量的基础上追加 env（KEY=VALUE，同名覆盖）
+	StreamExecuteWithEnv(ctx context.Context, subfolder string, env []string, onLine OutputLineHandler, process string, arg ...string) models.CommandResult
 }
 
 // NewCommandService 创建命令执行服务
@@ -33,8 +44,26 @@ func NewCommandService(cfg config.CommandConfig, workspacePath string) CommandSe
 
 // SimpleExecute 直接执行命令，不使用 session 管理
 func (s *commandService) SimpleExecute(ctx context.Context, subfolder, process string, arg ...string) models.CommandResult {
+	return s.StreamExecute(ctx, subfolder, nil, process, arg...)
+}
+
+// StreamExecute 执行命令，按行读取 stdout/stderr 并回调，最终返回合并后的完整输出
+func (s *commandService) StreamExecute(ctx context.Context, subfolder string, onLine OutputLineHandler, process string, arg ...string) models.CommandResult {
+	return s.StreamExecuteWithEnv(ctx, subfolder, nil, onLine, process, arg...)
+}
+
+// StreamExecuteWithEnv 执行命令并追加环境变量，用于按项目注入模型配置
+func (s *commandService) StreamExecuteWithEnv(ctx context.Context, subfolder string, env []string, onLine OutputLineHandler, process string, arg ...string) models.CommandResult {
+	// 超时后杀掉整个进程组
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
 	// 根据操作系统选择 shell 和参数
-	cmd := exec.Command(process, arg...)
+	cmd := exec.CommandContext(ctx, process, arg...)
+	setProcessGroup(cmd)
 
 	// 设置工作目录
 	if subfolder != "" {
@@ -45,18 +74,25 @@ func (s *commandService) SimpleExecute(ctx context.Context, subfolder, process s
 
 	fmt.Printf("🔧 直接执行命令: %s (工作目录: %s, 超时: %v)\n", process, cmd.Dir, s.timeout)
 
-	// 设置环境变量 - 继承当前进程的环境变量
-	cmd.Env = os.Environ()
+	// 设置环境变量 - 继承当前进程的环境变量，追加的同名变量覆盖继承的值
+	cmd.Env = append(os.Environ(), env...)
 
-	// 执行命令并获取输出
-	output, err := cmd.CombinedOutput()
-	outputStr := strings.TrimSpace(string(output))
+	// 执行命令并按行读取输出
+	output, err := s.runAndCollect(ctx, cmd, onLine)
+	outputStr := strings.TrimSpace(output)
 
 	// 判断执行结果
 	success := err == nil
 	var errorMsg string
 	if err != nil {
-		errorMsg = err.Error()
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			errorMsg = fmt.Sprintf("命令执行超时(%v): %s", s.timeout, err.Error())
+		case context.Canceled:
+			errorMsg = "命令已取消: " + err.Error()
+		default:
+			errorMsg = err.Error()
+		}
 	}
 
 	if success {
@@ -78,3 +114,72 @@ func (s *commandService) SimpleExecute(ctx context.Context, subfolder, process s
 		Error:   errorMsg,
 	}
 }
+
+// runAndCollect 启动命令，同时读取 stdout 和 stderr，按到达顺序合并输出
+func (s *commandService) runAndCollect(ctx context.Context, cmd *exec.Cmd, onLine OutputLineHandler) (string, error) {
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return "", err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+
+	var (
+		mu       sync.Mutex
+		combined strings.Builder
+		wg       sync.WaitGroup
+	)
+
+	readLines := func(reader io.Reader, stream string) {
+		defer wg.Done()
+		// 不使用 bufio.Scanner，避免 Claude JSON 等超长单行输出超出缓冲区
+		bufReader := bufio.NewReader(reader)
+		for {
+			line, readErr := bufReader.ReadString('\n')
+			if line != "" {
+				mu.Lock()
+				combined.WriteString(line)
+				mu.Unlock()
+				if onLine != nil {
+					onLine(stream, strings.TrimRight(line, "\r\n"))
+				}
+			}
+			if readErr != nil {
+				return
+			}
+		}
+	}
+
+	wg.Add(2)
+	go readLines(stdout, common.AgentLogStreamStdout)
+	go readLines(stderr, common.AgentLogStreamStderr)
+	readDone := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(readDone)
+	}()
+
+	// 必须先读完管道再 Wait，否则 Wait 会关闭管道导致输出丢失
+	select {
+	case <-readDone:
+	case <-ctx.Done():
+		// 进程组已被杀掉，给读取协程留一点时间读完剩余输出；
+		// 如果有脱离进程组的子进程仍占用管道，由 Wait 关闭管道
+		select {
+		case <-readDone:
+		case <-time.After(processWaitDelay):
+		}
+	}
+
+	err = cmd.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	return combined.String(), err
+}
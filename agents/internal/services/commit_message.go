@@ -0,0 +1,168 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lighthought/app-maker/shared-models/common"
+	"github.com/lighthought/app-maker/shared-models/tasks"
+	"github.com/lighthought/app-maker/shared-models/utils"
+)
+
+// 各开发阶段的约定式提交类型，未列出的阶段（如对话）使用 chore
+var stageCommitTypes = map[common.DevStatus]string{
+	common.DevStatusSetupAgents:        "chore",
+	common.DevStatusCheckRequirement:   "docs",
+	common.DevStatusGeneratePRD:        "docs",
+	common.DevStatusDefineUXStandard:   "docs",
+	common.DevStatusDesignArchitecture: "docs",
+	common.DevStatusDefineDataModel:    "docs",
+	common.DevStatusDefineAPI:          "docs",
+	common.DevStatusPlanEpicAndStory:   "docs",
+	common.DevStatusGeneratePages:      "feat",
+	common.DevStatusDraftStory:         "docs",
+	common.DevStatusDevelopStory:       "feat",
+	common.DevStatusCodeReview:         "fix",
+	common.DevStatusQAReview:           "test",
+	common.DevStatusFixBug:             "fix",
+	common.DevStatusRunTest:            "test",
+	common.DevStatusDeploy:             "build",
+}
+
+// commitSummaryStory、commitSummaryDraftStory、commitSummaryQAStory、commitSummaryReviewStory、commitSummaryChat、
+// commitSummarySuspend 提交摘要中非开发阶段的 key
+const (
+	commitSummaryStory       = "story"        // 实现单个故事，格式参数为故事编号
+	commitSummaryDraftStory  = "story_draft"  // 起草单个故事，格式参数为故事编号
+	commitSummaryQAStory     = "qa_story"     // 评审单个故事，格式参数为故事编号
+	commitSummaryReviewStory = "review_story" // 修复单个故事的代码评审意见，格式参数为故事编号
+	commitSummaryChat        = "chat"         // 对话产生的修改
+	commitSummarySuspend     = "suspend"      // 任务未完成时保存的变更，格式参数为开发阶段的摘要
+)
+
+// 各开发阶段的提交摘要，按项目输出语言区分；类型、范围和 trailer 保持英文，便于解析
+var commitSummaries = map[string]map[string]string{
+	common.LanguageZhCN: {
+		string(common.DevStatusSetupAgents):        "准备 Agents 环境",
+		string(common.DevStatusCheckRequirement):   "添加项目简介",
+		string(common.DevStatusGeneratePRD):        "生成 PRD",
+		string(common.DevStatusDefineUXStandard):   "定义 UX 标准",
+		string(common.DevStatusDesignArchitecture): "设计系统架构",
+		string(common.DevStatusDefineDataModel):    "定义数据模型",
+		string(common.DevStatusDefineAPI):          "定义 API",
+		string(common.DevStatusPlanEpicAndStory):   "规划 Epic 和 Story",
+		string(common.DevStatusGeneratePages):      "生成前端页面",
+		string(common.DevStatusDraftStory):         "起草故事",
+		string(common.DevStatusDevelopStory):       "实现故事",
+		string(common.DevStatusCodeReview):         "修复代码评审意见",
+		string(common.DevStatusQAReview):           "评审故事",
+		string(common.DevStatusFixBug):             "修复反馈的问题",
+		string(common.DevStatusRunTest):            "运行自动化测试",
+		string(common.DevStatusDeploy):             "修复部署构建",
+		commitSummaryStory:                         "实现故事 %s",
+		commitSummaryDraftStory:                    "起草故事 %s",
+		commitSummaryQAStory:                       "评审故事 %s 的验收标准",
+		commitSummaryReviewStory:                   "修复故事 %s 的代码评审意见",
+		commitSummaryChat:                          "应用对话中的修改",
+		commitSummarySuspend:                       "保存未完成的变更（%s）",
+	},
+	common.LanguageEnUS: {
+		string(common.DevStatusSetupAgents):        "set up agents environment",
+		string(common.DevStatusCheckRequirement):   "add project brief",
+		string(common.DevStatusGeneratePRD):        "generate PRD",
+		string(common.DevStatusDefineUXStandard):   "define UX standard",
+		string(common.DevStatusDesignArchitecture): "design system architecture",
+		string(common.DevStatusDefineDataModel):    "define data model",
+		string(common.DevStatusDefineAPI):          "define API",
+		string(common.DevStatusPlanEpicAndStory):   "plan epics and stories",
+		string(common.DevStatusGeneratePages):      "generate frontend pages",
+		string(common.DevStatusDraftStory):         "draft stories",
+		string(common.DevStatusDevelopStory):       "implement stories",
+		string(common.DevStatusCodeReview):         "address code review comments",
+		string(common.DevStatusQAReview):           "review stories",
+		string(common.DevStatusFixBug):             "fix reported bug",
+		string(common.DevStatusRunTest):            "run automated tests",
+		string(common.DevStatusDeploy):             "fix build for deployment",
+		commitSummaryStory:                         "implement story %s",
+		commitSummaryDraftStory:                    "draft story %s",
+		commitSummaryQAStory:                       "review acceptance criteria of story %s",
+		commitSummaryReviewStory:                   "address code review of story %s",
+		commitSummaryChat:                          "apply agent chat changes",
+		commitSummarySuspend:                       "save unfinished changes (%s)",
+	},
+}
+
+const (
+	commitSubjectMaxLength = 72 // 提交标题的最大长度
+	commitBodyMaxLines     = 20 // 正文保留的 Agent 结果行数
+)
+
+// buildCommitMessage 生成约定式提交信息，如 feat(dev): implement story 1.1，摘要按项目输出语言生成，
+// 正文为精简的 Agent 结果，末尾的 App-Maker-* trailer 用于把提交关联回开发阶段、任务、故事和 Agent
+func buildCommitMessage(payload *tasks.AgentExecuteTaskPayload, taskID, result string) string {
+	commitType, summary := commitTypeAndSummary(payload)
+	return formatCommitMessage(payload, taskID, commitType, summary, condenseCommitBody(result))
+}
+
+// buildSuspendCommitMessage 任务未完成时保存变更的提交信息，如 wip(dev): save unfinished changes (implement story 1.1)
+func buildSuspendCommitMessage(payload *tasks.AgentExecuteTaskPayload, taskID string) string {
+	_, summary := commitTypeAndSummary(payload)
+	summaries := commitSummaries[common.NormalizeLanguage(payload.Language)]
+	return formatCommitMessage(payload, taskID, "wip", fmt.Sprintf(summaries[commitSummarySuspend], summary), "")
+}
+
+// commitTypeAndSummary 按开发阶段获取提交类型和摘要
+func commitTypeAndSummary(payload *tasks.AgentExecuteTaskPayload) (string, string) {
+	summaries := commitSummaries[common.NormalizeLanguage(payload.Language)]
+	commitType, ok := stageCommitTypes[payload.DevStage]
+	summary := summaries[string(payload.DevStage)]
+	if !ok || summary == "" {
+		commitType, summary = "chore", summaries[commitSummaryChat]
+	}
+	if payload.StoryNumber != "" {
+		switch payload.DevStage {
+		case common.DevStatusDraftStory:
+			summary = fmt.Sprintf(summaries[commitSummaryDraftStory], payload.StoryNumber)
+		case common.DevStatusDevelopStory:
+			summary = fmt.Sprintf(summaries[commitSummaryStory], payload.StoryNumber)
+		case common.DevStatusQAReview:
+			summary = fmt.Sprintf(summaries[commitSummaryQAStory], payload.StoryNumber)
+		case common.DevStatusCodeReview:
+			summary = fmt.Sprintf(summaries[commitSummaryReviewStory], payload.StoryNumber)
+		}
+	}
+	return commitType, summary
+}
+
+// formatCommitMessage 拼接提交标题、正文和 trailer
+func formatCommitMessage(payload *tasks.AgentExecuteTaskPayload, taskID, commitType, summary, body string) string {
+	title := commitType
+	if payload.AgentType != "" {
+		title += "(" + payload.AgentType + ")"
+	}
+	title += ": " + summary
+	if runes := []rune(title); len(runes) > commitSubjectMaxLength {
+		title = string(runes[:commitSubjectMaxLength])
+	}
+
+	return utils.BuildCommitMessage(title, body, &utils.CommitTrailers{
+		Stage: string(payload.DevStage),
+		Task:  taskID,
+		Story: payload.StoryNumber,
+		Agent: payload.AgentType,
+	})
+}
+
+// condenseCommitBody 保留 Agent 结果的前几行，去掉空行，保留缩进
+func condenseCommitBody(result string) string {
+	var lines []string
+	for _, line := range strings.Split(result, "\n") {
+		if line = strings.TrimRight(line, " \t\r"); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > commitBodyMaxLines {
+		lines = append(lines[:commitBodyMaxLines], "...")
+	}
+	return strings.Join(lines, "\n")
+}
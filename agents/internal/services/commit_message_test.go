@@ -0,0 +1,104 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lighthought/app-maker/shared-models/common"
+	"github.com/lighthought/app-maker/shared-models/tasks"
+	"github.com/lighthought/app-maker/shared-models/utils"
+)
+
+func TestBuildCommitMessage(t *testing.T) {
+	tests := []struct {
+		name        string
+		payload     tasks.AgentExecuteTaskPayload
+		taskID      string
+		result      string
+		wantSubject string
+		wantStory   string
+	}{
+		{
+			name:        "stage subject",
+			payload:     tasks.AgentExecuteTaskPayload{AgentType: "pm", DevStage: common.DevStatusGeneratePRD, Language: common.LanguageEnUS},
+			taskID:      "task-1",
+			result:      "# PRD\n\ndone",
+			wantSubject: "docs(pm): generate PRD",
+		},
+		{
+			name:        "story subject",
+			payload:     tasks.AgentExecuteTaskPayload{AgentType: "dev", DevStage: common.DevStatusDevelopStory, StoryNumber: "1.2", Language: common.LanguageEnUS},
+			taskID:      "task-2",
+			wantSubject: "feat(dev): implement story 1.2",
+			wantStory:   "1.2",
+		},
+		{
+			name:        "qa story subject",
+			payload:     tasks.AgentExecuteTaskPayload{AgentType: "qa", DevStage: common.DevStatusQAReview, StoryNumber: "US-001", Language: common.LanguageEnUS},
+			taskID:      "task-4",
+			wantSubject: "test(qa): review acceptance criteria of story US-001",
+			wantStory:   "US-001",
+		},
+		{
+			name:        "draft story subject",
+			payload:     tasks.AgentExecuteTaskPayload{AgentType: "sm", DevStage: common.DevStatusDraftStory, StoryNumber: "2.1"},
+			taskID:      "task-5",
+			wantSubject: "docs(sm): 起草故事 2.1",
+			wantStory:   "2.1",
+		},
+		{
+			name:        "code review fix subject",
+			payload:     tasks.AgentExecuteTaskPayload{AgentType: "dev", DevStage: common.DevStatusCodeReview, StoryNumber: "1.3", Language: common.LanguageEnUS},
+			taskID:      "task-6",
+			wantSubject: "fix(dev): address code review of story 1.3",
+			wantStory:   "1.3",
+		},
+		{
+			name:        "chat without stage",
+			payload:     tasks.AgentExecuteTaskPayload{AgentType: "dev", Language: common.LanguageEnUS},
+			wantSubject: "chore(dev): apply agent chat changes",
+		},
+		{
+			name:        "default language story subject",
+			payload:     tasks.AgentExecuteTaskPayload{AgentType: "dev", DevStage: common.DevStatusDevelopStory, StoryNumber: "2.1"},
+			taskID:      "task-3",
+			wantSubject: "feat(dev): 实现故事 2.1",
+			wantStory:   "2.1",
+		},
+		{
+			name:        "default language chat",
+			payload:     tasks.AgentExecuteTaskPayload{AgentType: "dev", DevStage: common.DevStatusUnknown},
+			wantSubject: "chore(dev): 应用对话中的修改",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := buildCommitMessage(&tt.payload, tt.taskID, tt.result)
+			if subject := strings.SplitN(msg, "\n", 2)[0]; subject != tt.wantSubject {
+				t.Errorf("subject = %q, want %q", subject, tt.wantSubject)
+			}
+			trailers := utils.ParseCommitTrailers(msg)
+			if trailers == nil {
+				t.Fatalf("no trailers in %q", msg)
+			}
+			if trailers.Stage != string(tt.payload.DevStage) || trailers.Task != tt.taskID ||
+				trailers.Story != tt.wantStory || trailers.Agent != tt.payload.AgentType {
+				t.Errorf("trailers = %+v", trailers)
+			}
+		})
+	}
+}
+
+func TestCondenseCommitBody(t *testing.T) {
+	var long []string
+	for i := 0; i < commitBodyMaxLines+5; i++ {
+		long = append(long, "line")
+	}
+	body := condenseCommitBody(strings.Join(long, "\n\n"))
+	if got := len(strings.Split(body, "\n")); got != commitBodyMaxLines+1 {
+		t.Errorf("condensed lines = %d, want %d", got, commitBodyMaxLines+1)
+	}
+	if !strings.HasSuffix(body, "...") {
+		t.Errorf("condensed body should end with ellipsis: %q", body)
+	}
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lighthought/app-maker/agents/internal/config"
+	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/common"
+	"github.com/lighthought/app-maker/shared-models/logger"
+	"github.com/lighthought/app-maker/shared-models/utils"
+)
+
+// 健康检查失败时附带的组件日志行数
+const deployLogTailLines = 50
+
+// 单次健康检查请求的超时时间
+const deployProbeTimeout = 5 * time.Second
+
+// DeployHealthChecker 部署后检查项目组件是否启动成功
+type DeployHealthChecker interface {
+	// 轮询各组件的健康检查地址、端口，直到全部就绪或超时；runOutput 为启动命令的输出，
+	// 组件没有声明 logs 命令时用于生成日志
+	Check(ctx context.Context, projectGuid string, manifest *ProjectManifest, runOutput string) *agent.DeployResp
+}
+
+// deployHealthChecker 部署健康检查实现
+type deployHealthChecker struct {
+	commandService CommandService
+	httpClient     *http.Client
+	timeout        time.Duration
+	interval       time.Duration
+}
+
+// NewDeployHealthChecker 创建部署健康检查
+func NewDeployHealthChecker(commandService CommandService, cfg config.DeployConfig) DeployHealthChecker {
+	return &deployHealthChecker{
+		commandService: commandService,
+		httpClient:     &http.Client{Timeout: deployProbeTimeout},
+		timeout:        cfg.HealthTimeout,
+		interval:       cfg.HealthInterval,
+	}
+}
+
+// Check 轮询各组件的健康检查地址、端口，直到全部就绪或超时
+func (c *deployHealthChecker) Check(ctx context.Context, projectGuid string, manifest *ProjectManifest, runOutput string) *agent.DeployResp {
+	resp := &agent.DeployResp{Status: common.DeployHealthHealthy}
+	pending := make(map[*agent.DeployComponentStatus]*ManifestComponent)
+	for i := range manifest.Components {
+		component := &manifest.Components[i]
+		status := &agent.DeployComponentStatus{
+			Name:      component.Name,
+			State:     common.DeployHealthUnchecked,
+			URL:       component.accessURL(),
+			HealthURL: component.HealthURL,
+			Ports:     component.Ports,
+		}
+		resp.Components = append(resp.Components, status)
+		if c.timeout > 0 && (component.HealthURL != "" || len(component.Ports) > 0) {
+			pending[status] = component
+		}
+	}
+
+	deadline := time.Now().Add(c.timeout)
+	for len(pending) > 0 {
+		for status, component := range pending {
+			status.CheckedAt = utils.GetCurrentTime()
+			if err := c.probe(ctx, component); err != nil {
+				status.Error = err.Error()
+				continue
+			}
+			status.State = common.DeployHealthHealthy
+			status.Error = ""
+			delete(pending, status)
+		}
+		if len(pending) == 0 || !time.Now().Add(c.interval).Before(deadline) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			deadline = time.Now()
+		case <-time.After(c.interval):
+		}
+	}
+
+	for status, component := range pending {
+		status.State = common.DeployHealthUnhealthy
+		status.LogTail = c.logTail(ctx, projectGuid, component, runOutput)
+		resp.Status = common.DeployHealthUnhealthy
+		logger.Warn("组件未通过健康检查",
+			logger.String("projectGuid", projectGuid),
+			logger.String("component", component.Name),
+			logger.String("error", status.Error))
+	}
+	if resp.Status == common.DeployHealthHealthy {
+		resp.PreviewURL = previewURL(resp.Components)
+	}
+	return resp
+}
+
+// probe 检查一次组件：有健康检查地址时请求该地址，否则检查端口是否可以连接
+func (c *deployHealthChecker) probe(ctx context.Context, component *ManifestComponent) error {
+	if component.HealthURL != "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, component.HealthURL, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= http.StatusBadRequest {
+			return fmt.Errorf("GET %s: %s", component.HealthURL, resp.Status)
+		}
+		return nil
+	}
+
+	dialer := net.Dialer{Timeout: deployProbeTimeout}
+	for _, port := range component.Ports {
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort("localhost", strconv.Itoa(port)))
+		if err != nil {
+			return err
+		}
+		conn.Close()
+	}
+	return nil
+}
+
+// logTail 组件日志的最后几行：执行组件的 logs 命令，没有声明时使用启动命令的输出
+func (c *deployHealthChecker) logTail(ctx context.Context, projectGuid string, component *ManifestComponent, runOutput string) string {
+	output := runOutput
+	if component.Logs != "" {
+		command := newProjectCommand(component.Name+" logs", component.subfolder(projectGuid), component.Logs)
+		result := c.commandService.SimpleExecute(ctx, command.Subfolder, command.Process, command.Args...)
+		output = result.Output
+		if !result.Success {
+			output += "\n" + result.Error
+		}
+	}
+	return tailLines(strings.TrimSpace(ansiEscape.ReplaceAllString(output, "")), deployLogTailLines)
+}
+
+// previewURL 项目预览地址：优先使用名为 frontend 的组件，否则使用第一个有访问地址的组件
+func previewURL(components []*agent.DeployComponentStatus) string {
+	var preview string
+	for _, component := range components {
+		if component.URL == "" {
+			continue
+		}
+		if component.Name == "frontend" {
+			return component.URL
+		}
+		if preview == "" {
+			preview = component.URL
+		}
+	}
+	return preview
+}
+
+// deploySummary 部署结果的 Markdown 摘要：整体状态、预览地址、各组件状态，未通过健康检查的组件附带日志
+func deploySummary(language string, resp *agent.DeployResp) string {
+	var builder strings.Builder
+	if resp.Status == common.DeployHealthHealthy {
+		builder.WriteString(getAgentMessage(language, messageKeyDeployHealthy))
+	} else {
+		var names []string
+		for _, component := range resp.Components {
+			if component.State == common.DeployHealthUnhealthy {
+				names = append(names, component.Name)
+			}
+		}
+		builder.WriteString(fmt.Sprintf(getAgentMessage(language, messageKeyDeployUnhealthy), strings.Join(names, ", ")))
+	}
+	builder.WriteString("\n\n")
+	if resp.PreviewURL != "" {
+		builder.WriteString(fmt.Sprintf(getAgentMessage(language, messageKeyDeployPreviewURL), resp.PreviewURL) + "\n\n")
+	}
+	for _, component := range resp.Components {
+		builder.WriteString(fmt.Sprintf("* %s: %s", component.Name, component.State))
+		if component.URL != "" {
+			builder.WriteString(" " + component.URL)
+		}
+		if component.Error != "" {
+			builder.WriteString(" (" + component.Error + ")")
+		}
+		builder.WriteString("\n")
+		if component.LogTail != "" {
+			builder.WriteString("\n```\n" + component.LogTail + "\n```\n")
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lighthought/app-maker/agents/internal/config"
+	"github.com/lighthought/app-maker/shared-models/common"
+)
+
+func TestDeployHealthCheck(t *testing.T) {
+	// 前端前两次返回 503，之后就绪
+	var calls int32
+	frontend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer frontend.Close()
+
+	// 后端端口无人监听
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedPort := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	workspace := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workspace, "p1", "backend"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	checker := NewDeployHealthChecker(NewCommandService(config.CommandConfig{Timeout: time.Minute}, workspace),
+		config.DeployConfig{HealthTimeout: 2 * time.Second, HealthInterval: 50 * time.Millisecond})
+
+	t.Run("healthy", func(t *testing.T) {
+		manifest := &ProjectManifest{Version: 1, Components: []ManifestComponent{
+			{Name: "backend", Ports: []int{frontend.Listener.Addr().(*net.TCPAddr).Port}},
+			{Name: "frontend", HealthURL: frontend.URL + "/health"},
+			{Name: "worker"},
+		}}
+		resp := checker.Check(context.Background(), "p1", manifest, "")
+		if resp.Status != common.DeployHealthHealthy || resp.PreviewURL != frontend.URL {
+			t.Fatalf("Check() = %+v, want healthy with preview %s", resp, frontend.URL)
+		}
+		states := []string{common.DeployHealthHealthy, common.DeployHealthHealthy, common.DeployHealthUnchecked}
+		for i, component := range resp.Components {
+			if component.State != states[i] {
+				t.Errorf("component %s state = %s, want %s", component.Name, component.State, states[i])
+			}
+		}
+	})
+
+	t.Run("unhealthy", func(t *testing.T) {
+		manifest := &ProjectManifest{Version: 1, Components: []ManifestComponent{
+			{Name: "backend", Path: "backend", Ports: []int{closedPort}, Logs: "echo backend crashed"},
+			{Name: "frontend", Ports: []int{closedPort}},
+		}}
+		resp := checker.Check(context.Background(), "p1", manifest, "line 1\nfrontend exited with code 1")
+		if resp.Status != common.DeployHealthUnhealthy || resp.PreviewURL != "" {
+			t.Fatalf("Check() = %+v, want unhealthy without preview", resp)
+		}
+		backend, frontendStatus := resp.Components[0], resp.Components[1]
+		if backend.State != common.DeployHealthUnhealthy || backend.Error == "" || backend.LogTail != "backend crashed" {
+			t.Errorf("backend = %+v", backend)
+		}
+		if !strings.Contains(frontendStatus.LogTail, "frontend exited with code 1") {
+			t.Errorf("frontend log tail = %q, want run output", frontendStatus.LogTail)
+		}
+
+		summary := deploySummary(common.LanguageEnUS, resp)
+		if !strings.Contains(summary, "components backend, frontend did not pass") || !strings.Contains(summary, "```\nbackend crashed\n```") {
+			t.Errorf("deploySummary() = %q", summary)
+		}
+	})
+}
+
+func TestManifestComponentAccessURL(t *testing.T) {
+	tests := []struct {
+		component ManifestComponent
+		want      string
+	}{
+		{ManifestComponent{HealthURL: "http://localhost:9501/api/v1/health", Ports: []int{9501}}, "http://localhost:9501"},
+		{ManifestComponent{Ports: []int{3501, 3502}}, "http://localhost:3501"},
+		{ManifestComponent{}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.component.accessURL(); got != tt.want {
+			t.Errorf("accessURL(%+v) = %q, want %q", tt.component, got, tt.want)
+		}
+	}
+}
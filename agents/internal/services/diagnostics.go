@@ -0,0 +1,77 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+)
+
+// 发送给 Dev Agent 的错误行数上限，避免把整个构建日志塞进提示词
+const maxDiagnosticLines = 40
+
+// 没有匹配到已知错误格式时，保留输出的最后几行
+const diagnosticTailLines = 30
+
+// 终端颜色控制符
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+// diagnosticPatterns 常见构建工具的错误行
+var diagnosticPatterns = []*regexp.Regexp{
+	// Go 编译、vet、测试：main.go:12:5: undefined: foo、--- FAIL: TestX、panic:
+	regexp.MustCompile(`^\S+\.go:\d+(:\d+)?: `),
+	regexp.MustCompile(`^(--- FAIL: |FAIL\s|panic: )`),
+	// TypeScript、vue-tsc：src/a.ts(3,5): error TS2304: ...、src/a.ts:3:5 - error TS2304: ...
+	regexp.MustCompile(`\berror TS\d+:`),
+	// Vite、Rollup、esbuild
+	regexp.MustCompile(`^(error during build:|\[vite\]|\[plugin:|\[commonjs--resolver\]|✘ \[ERROR\])`),
+	regexp.MustCompile(`(RollupError|Could not resolve |Failed to resolve import )`),
+	// Docker、docker compose
+	regexp.MustCompile(`^(ERROR:|ERROR \[|Error response from daemon:|failed to solve:|target \S+: failed to solve)`),
+	regexp.MustCompile(`(=> ERROR |did not complete successfully: exit code|returned a non-zero code)`),
+	// npm、pnpm、make
+	regexp.MustCompile(`^(npm ERR! |npm error |ERR_PNPM_|make(\[\d+\])?: \*\*\* )`),
+}
+
+// 没有匹配到已知格式时按关键字查找错误行
+var genericErrorPattern = regexp.MustCompile(`(?i)\b(error|failed|fatal|cannot find|not found)\b`)
+
+// extractDiagnostics 从 Go、TypeScript、Vite、Docker 等工具的输出中提取错误行，去掉颜色控制符并去重；
+// 没有已知格式的错误时按关键字查找，仍然没有时返回输出的最后几行
+func extractDiagnostics(output string) []string {
+	lines := strings.Split(ansiEscape.ReplaceAllString(output, ""), "\n")
+
+	diagnostics := matchDiagnostics(lines, func(line string) bool {
+		for _, pattern := range diagnosticPatterns {
+			if pattern.MatchString(line) {
+				return true
+			}
+		}
+		return false
+	})
+	if len(diagnostics) == 0 {
+		diagnostics = matchDiagnostics(lines, genericErrorPattern.MatchString)
+	}
+	if len(diagnostics) == 0 {
+		diagnostics = matchDiagnostics(strings.Split(tailLines(strings.TrimSpace(strings.Join(lines, "\n")), diagnosticTailLines), "\n"),
+			func(line string) bool { return true })
+	}
+	return diagnostics
+}
+
+// matchDiagnostics 收集匹配的非空行，去重并限制数量
+func matchDiagnostics(lines []string, match func(line string) bool) []string {
+	var diagnostics []string
+	seen := make(map[string]bool)
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || seen[trimmed] || !match(trimmed) {
+			continue
+		}
+		seen[trimmed] = true
+		diagnostics = append(diagnostics, line)
+		if len(diagnostics) >= maxDiagnosticLines {
+			break
+		}
+	}
+	return diagnostics
+}
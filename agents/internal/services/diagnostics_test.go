@@ -0,0 +1,89 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractDiagnostics(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []string
+	}{
+		{
+			name: "go build",
+			output: "go: downloading github.com/gin-gonic/gin v1.10.0\n# demo/internal/handlers\n" +
+				"internal/handlers/user.go:12:5: undefined: userService\n" +
+				"internal/handlers/user.go:30:2: missing return\nexit status 1",
+			want: []string{"internal/handlers/user.go:12:5: undefined: userService", "internal/handlers/user.go:30:2: missing return"},
+		},
+		{
+			name:   "go test",
+			output: "=== RUN   TestLogin\n    user_test.go:20: status = 500, want 200\n--- FAIL: TestLogin (0.00s)\nFAIL\tdemo/internal/handlers\t0.01s",
+			want:   []string{"    user_test.go:20: status = 500, want 200", "--- FAIL: TestLogin (0.00s)", "FAIL\tdemo/internal/handlers\t0.01s"},
+		},
+		{
+			name: "vue-tsc and vite",
+			output: "> vue-tsc && vite build\n\x1b[96msrc/pages/Home.vue\x1b[0m:\x1b[93m12\x1b[0m:\x1b[93m7\x1b[0m - \x1b[91merror\x1b[0m\x1b[90m TS2304: \x1b[0mCannot find name 'foo'.\n" +
+				"src/router/index.ts(3,20): error TS2307: Cannot find module '@/pages/Missing.vue'.\n" +
+				"error during build:\n[vite]: Rollup failed to resolve import \"axios\" from \"src/api.ts\".",
+			want: []string{
+				"src/pages/Home.vue:12:7 - error TS2304: Cannot find name 'foo'.",
+				"src/router/index.ts(3,20): error TS2307: Cannot find module '@/pages/Missing.vue'.",
+				"error during build:",
+				"[vite]: Rollup failed to resolve import \"axios\" from \"src/api.ts\".",
+			},
+		},
+		{
+			name: "docker compose build",
+			output: "#8 [backend 4/6] RUN go build -o server ./cmd/server\n#8 2.31 cmd/server/main.go:9:2: \"fmt\" imported and not used\n" +
+				"#8 ERROR: process \"/bin/sh -c go build -o server ./cmd/server\" did not complete successfully: exit code: 1\n" +
+				"failed to solve: process \"/bin/sh -c go build -o server ./cmd/server\" did not complete successfully: exit code: 1\n" +
+				"make: *** [Makefile:140: build-dev] Error 17",
+			want: []string{
+				"#8 ERROR: process \"/bin/sh -c go build -o server ./cmd/server\" did not complete successfully: exit code: 1",
+				"failed to solve: process \"/bin/sh -c go build -o server ./cmd/server\" did not complete successfully: exit code: 1",
+				"make: *** [Makefile:140: build-dev] Error 17",
+			},
+		},
+		{
+			name:   "generic error keyword",
+			output: "starting\nconnection refused: cannot find host db\ndone",
+			want:   []string{"connection refused: cannot find host db"},
+		},
+		{
+			name:   "tail when nothing matches",
+			output: "step 1\nstep 2\n\nexit status 2",
+			want:   []string{"step 1", "step 2", "exit status 2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractDiagnostics(tt.output); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("extractDiagnostics() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractDiagnosticsLimitsAndDeduplicates(t *testing.T) {
+	var lines []string
+	for i := 0; i < maxDiagnosticLines*2; i++ {
+		lines = append(lines, "main.go:1:1: duplicate", "main.go:"+strings.Repeat("9", i%5+1)+":1: error "+strings.Repeat("x", i))
+	}
+	got := extractDiagnostics(strings.Join(lines, "\n"))
+	if len(got) != maxDiagnosticLines {
+		t.Fatalf("len = %d, want %d", len(got), maxDiagnosticLines)
+	}
+	duplicates := 0
+	for _, line := range got {
+		if line == "main.go:1:1: duplicate" {
+			duplicates++
+		}
+	}
+	if duplicates != 1 {
+		t.Errorf("duplicate lines = %d, want 1", duplicates)
+	}
+}
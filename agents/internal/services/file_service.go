@@ -20,12 +20,14 @@ type FileService interface {
 
 type fileService struct {
 	commandService CommandService
+	cliAdapters    CliAdapterRegistry
 	workspacePath  string
 }
 
-func NewFileService(commandService CommandService, workspacePath string) FileService {
+func NewFileService(commandService CommandService, cliAdapters CliAdapterRegistry, workspacePath string) FileService {
 	return &fileService{
 		commandService: commandService,
+		cliAdapters:    cliAdapters,
 		workspacePath:  workspacePath,
 	}
 }
@@ -45,17 +47,5 @@ func (s *fileService) GetWorkspacePath() string {
 
 // DetectCliTool 检测项目使用的 CLI 工具类型
 func (s *fileService) DetectCliTool(projectGuid string) string {
-	projectPath := s.GetProjectPath(projectGuid)
-
-	if utils.IsDirectoryExists(filepath.Join(projectPath, ".claude")) {
-		return common.CliToolClaudeCode
-	}
-	if utils.IsDirectoryExists(filepath.Join(projectPath, ".qwen")) {
-		return common.CliToolQwenCode
-	}
-	if utils.IsDirectoryExists(filepath.Join(projectPath, ".gemini")) {
-		return common.CliToolGemini
-	}
-
-	return common.CliToolClaudeCode // 默认
+	return s.cliAdapters.Detect(s.GetProjectPath(projectGuid))
 }
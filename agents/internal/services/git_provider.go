@@ -0,0 +1,363 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/cache"
+	"github.com/lighthought/app-maker/shared-models/common"
+	"github.com/lighthought/app-maker/shared-models/logger"
+
+	"github.com/lighthought/app-maker/agents/internal/config"
+)
+
+// MergeRequestReq 创建、合并合并请求的参数
+type MergeRequestReq struct {
+	ProjectGuid  string
+	RemoteURL    string // 项目 origin 地址，GitLab 据此解析项目路径，没有远程仓库时为空
+	SourceBranch string
+	TargetBranch string
+	Title        string
+	Description  string
+}
+
+// MergeConflictError 合并冲突，Files 为冲突的文件
+type MergeConflictError struct {
+	SourceBranch string
+	TargetBranch string
+	Files        []string
+}
+
+func (e *MergeConflictError) Error() string {
+	if len(e.Files) == 0 {
+		return fmt.Sprintf("分支 %s 合并到 %s 存在冲突", e.SourceBranch, e.TargetBranch)
+	}
+	return fmt.Sprintf("分支 %s 合并到 %s 存在冲突: %s", e.SourceBranch, e.TargetBranch, strings.Join(e.Files, ", "))
+}
+
+// GitProvider 合并请求提供方，新增一种代码托管平台只需要实现该接口
+type GitProvider interface {
+	// 提供方名称，与 common.GitProviderXXX 一致
+	Name() string
+
+	// 创建合并请求，源分支已有打开的合并请求时直接返回
+	CreateMergeRequest(ctx context.Context, req *MergeRequestReq) (*agent.MergeRequestInfo, error)
+
+	// 合并合并请求，调用前工作区已切换到目标分支；冲突时返回 *MergeConflictError
+	MergeMergeRequest(ctx context.Context, req *MergeRequestReq, mr *agent.MergeRequestInfo) error
+}
+
+// NewGitProvider 按配置创建合并请求提供方，未配置 GitLab 令牌时使用本地提供方
+func NewGitProvider(cfg config.GitWorkflowConfig, commandService CommandService, cacheInstance cache.Cache) GitProvider {
+	local := &localGitProvider{
+		commandService: commandService,
+		cacheInstance:  cacheInstance,
+	}
+	if cfg.Provider == common.GitProviderGitLab {
+		if cfg.GitlabToken != "" {
+			return &gitlabProvider{
+				baseURL:    strings.TrimRight(cfg.GitlabURL, "/"),
+				token:      cfg.GitlabToken,
+				httpClient: &http.Client{Timeout: 30 * time.Second},
+				local:      local,
+			}
+		}
+		logger.Warn("未配置 GitLab 访问令牌，使用本地合并请求")
+	}
+	return local
+}
+
+// 本地合并请求在 Redis 中的保留时间
+const localMergeRequestExpiration = 30 * 24 * time.Hour
+
+// localGitProvider 本地提供方：合并请求记录在 Redis 中，在工作区执行 git merge 并推送，离线可用
+type localGitProvider struct {
+	commandService CommandService
+	cacheInstance  cache.Cache
+}
+
+func (p *localGitProvider) Name() string {
+	return common.GitProviderLocal
+}
+
+// CreateMergeRequest 记录合并请求，源分支已有打开的合并请求时直接返回；调用方持有项目工作区锁，同一项目不会并发创建
+func (p *localGitProvider) CreateMergeRequest(ctx context.Context, req *MergeRequestReq) (*agent.MergeRequestInfo, error) {
+	if p.cacheInstance == nil {
+		return nil, fmt.Errorf("cache instance is nil")
+	}
+
+	key := cache.GetProjectMergeRequestCacheKey(req.ProjectGuid, req.SourceBranch)
+	var existing agent.MergeRequestInfo
+	if err := p.cacheInstance.Get(key, &existing); err == nil && existing.State == common.MergeRequestStateOpened {
+		return &existing, nil
+	}
+
+	sequence, err := p.cacheInstance.Incr(cache.GetProjectMergeRequestSeqCacheKey(req.ProjectGuid))
+	if err != nil {
+		return nil, fmt.Errorf("生成合并请求编号失败: %w", err)
+	}
+	mr := &agent.MergeRequestInfo{
+		Provider:     p.Name(),
+		ID:           strconv.FormatInt(sequence, 10),
+		Title:        req.Title,
+		SourceBranch: req.SourceBranch,
+		TargetBranch: req.TargetBranch,
+		State:        common.MergeRequestStateOpened,
+	}
+	if err := p.cacheInstance.Set(key, mr, localMergeRequestExpiration); err != nil {
+		return nil, fmt.Errorf("保存合并请求失败: %w", err)
+	}
+	return mr, nil
+}
+
+// MergeMergeRequest 在工作区合并源分支，有远程仓库时推送目标分支
+func (p *localGitProvider) MergeMergeRequest(ctx context.Context, req *MergeRequestReq, mr *agent.MergeRequestInfo) error {
+	message := fmt.Sprintf("Merge branch '%s' into %s", req.SourceBranch, req.TargetBranch)
+	if result := p.commandService.SimpleExecute(ctx, req.ProjectGuid, "git", "merge", "--no-ff", "-m", message, req.SourceBranch); !result.Success {
+		files := listConflictFiles(ctx, p.commandService, req.ProjectGuid)
+		p.commandService.SimpleExecute(ctx, req.ProjectGuid, "git", "merge", "--abort")
+		if len(files) > 0 {
+			return &MergeConflictError{SourceBranch: req.SourceBranch, TargetBranch: req.TargetBranch, Files: files}
+		}
+		return fmt.Errorf("合并分支失败: %s", result.Error)
+	}
+
+	if req.RemoteURL != "" {
+		if result := p.commandService.SimpleExecute(ctx, req.ProjectGuid, "git", "push", "origin", req.TargetBranch); !result.Success {
+			return fmt.Errorf("推送分支 %s 失败: %s", req.TargetBranch, result.Error)
+		}
+	}
+
+	mr.State = common.MergeRequestStateMerged
+	// 分支已合并，合并请求状态保存失败不影响结果
+	if err := p.cacheInstance.Set(cache.GetProjectMergeRequestCacheKey(req.ProjectGuid, req.SourceBranch), mr, localMergeRequestExpiration); err != nil {
+		logger.Warn("保存合并请求状态失败",
+			logger.String("GUID", req.ProjectGuid),
+			logger.String("mergeRequest", mr.ID),
+			logger.String("error", err.Error()))
+	}
+	return nil
+}
+
+// gitlabProvider 通过 GitLab REST API 创建、合并合并请求
+type gitlabProvider struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+	local      GitProvider // 项目没有 origin 远程仓库时使用本地合并请求
+}
+
+// gitlabMergeRequest GitLab 合并请求接口的返回
+type gitlabMergeRequest struct {
+	IID          int    `json:"iid"`
+	Title        string `json:"title"`
+	State        string `json:"state"`
+	WebURL       string `json:"web_url"`
+	SourceBranch string `json:"source_branch"`
+	TargetBranch string `json:"target_branch"`
+}
+
+const (
+	gitlabMergeRetryTimes    = 5               // GitLab 异步检查可合并状态，合并前最多重试的次数
+	gitlabMergeRetryInterval = 2 * time.Second // 重试间隔
+)
+
+func (p *gitlabProvider) Name() string {
+	return common.GitProviderGitLab
+}
+
+// CreateMergeRequest 创建合并请求，已存在打开的合并请求时查询并返回
+func (p *gitlabProvider) CreateMergeRequest(ctx context.Context, req *MergeRequestReq) (*agent.MergeRequestInfo, error) {
+	if req.RemoteURL == "" {
+		logger.Warn("项目没有远程仓库，使用本地合并请求", logger.String("GUID", req.ProjectGuid))
+		return p.local.CreateMergeRequest(ctx, req)
+	}
+
+	projectPath, err := gitlabProjectPath(req.RemoteURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var created gitlabMergeRequest
+	status, err := p.do(ctx, http.MethodPost, "/projects/"+url.PathEscape(projectPath)+"/merge_requests", map[string]interface{}{
+		"source_branch":        req.SourceBranch,
+		"target_branch":        req.TargetBranch,
+		"title":                req.Title,
+		"description":          req.Description,
+		"remove_source_branch": true,
+	}, &created)
+	if status == http.StatusConflict {
+		return p.findOpenedMergeRequest(ctx, projectPath, req)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("创建合并请求失败: %w", err)
+	}
+	return p.toMergeRequestInfo(&created), nil
+}
+
+// MergeMergeRequest 合并合并请求，GitLab 还在检查可合并状态时稍后重试
+func (p *gitlabProvider) MergeMergeRequest(ctx context.Context, req *MergeRequestReq, mr *agent.MergeRequestInfo) error {
+	if req.RemoteURL == "" || mr.Provider == common.GitProviderLocal {
+		return p.local.MergeMergeRequest(ctx, req, mr)
+	}
+
+	projectPath, err := gitlabProjectPath(req.RemoteURL)
+	if err != nil {
+		return err
+	}
+
+	endpoint := "/projects/" + url.PathEscape(projectPath) + "/merge_requests/" + mr.ID + "/merge"
+	for attempt := 1; ; attempt++ {
+		status, err := p.do(ctx, http.MethodPut, endpoint, map[string]interface{}{
+			"should_remove_source_branch": true,
+		}, nil)
+		if err == nil {
+			mr.State = common.MergeRequestStateMerged
+			return nil
+		}
+
+		switch status {
+		case http.StatusNotAcceptable, http.StatusConflict:
+			return &MergeConflictError{SourceBranch: req.SourceBranch, TargetBranch: req.TargetBranch}
+		case http.StatusMethodNotAllowed, http.StatusUnprocessableEntity:
+			if attempt < gitlabMergeRetryTimes {
+				if err := sleepContext(ctx, gitlabMergeRetryInterval); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		return fmt.Errorf("合并请求 !%s 合并失败: %w", mr.ID, err)
+	}
+}
+
+// findOpenedMergeRequest 查询源分支上打开的合并请求
+func (p *gitlabProvider) findOpenedMergeRequest(ctx context.Context, projectPath string, req *MergeRequestReq) (*agent.MergeRequestInfo, error) {
+	query := url.Values{}
+	query.Set("state", common.MergeRequestStateOpened)
+	query.Set("source_branch", req.SourceBranch)
+	query.Set("target_branch", req.TargetBranch)
+
+	var list []gitlabMergeRequest
+	endpoint := "/projects/" + url.PathEscape(projectPath) + "/merge_requests?" + query.Encode()
+	if _, err := p.do(ctx, http.MethodGet, endpoint, nil, &list); err != nil {
+		return nil, fmt.Errorf("查询合并请求失败: %w", err)
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("分支 %s 没有打开的合并请求", req.SourceBranch)
+	}
+	return p.toMergeRequestInfo(&list[0]), nil
+}
+
+func (p *gitlabProvider) toMergeRequestInfo(mr *gitlabMergeRequest) *agent.MergeRequestInfo {
+	return &agent.MergeRequestInfo{
+		Provider:     p.Name(),
+		ID:           strconv.Itoa(mr.IID),
+		URL:          mr.WebURL,
+		Title:        mr.Title,
+		SourceBranch: mr.SourceBranch,
+		TargetBranch: mr.TargetBranch,
+		State:        mr.State,
+	}
+}
+
+// do 调用 GitLab API，返回 HTTP 状态码，out 不为空时解析响应
+func (p *gitlabProvider) do(ctx context.Context, method, endpoint string, body interface{}, out interface{}) (int, error) {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, fmt.Errorf("序列化请求体失败: %w", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+"/api/v4"+endpoint, reqBody)
+	if err != nil {
+		return 0, fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("PRIVATE-TOKEN", p.token)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("发送请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, fmt.Errorf("读取响应失败: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("HTTP 错误 %d: %s", resp.StatusCode, string(respBody))
+	}
+	if out != nil {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return resp.StatusCode, fmt.Errorf("解析响应失败: %w", err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// gitlabProjectPath 从仓库地址解析 GitLab 项目路径，如 git@gitlab:app-maker/xxx.git -> app-maker/xxx
+func gitlabProjectPath(remoteURL string) (string, error) {
+	remoteURL = strings.TrimSpace(remoteURL)
+	var path string
+	if strings.Contains(remoteURL, "://") {
+		parsed, err := url.Parse(remoteURL)
+		if err != nil {
+			return "", fmt.Errorf("解析仓库地址失败: %w", err)
+		}
+		path = parsed.Path
+	} else if index := strings.Index(remoteURL, ":"); index > 0 {
+		path = remoteURL[index+1:]
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if path == "" {
+		return "", fmt.Errorf("无法从仓库地址解析 GitLab 项目路径: %s", remoteURL)
+	}
+	return path, nil
+}
+
+// listConflictFiles 获取合并冲突的文件
+func listConflictFiles(ctx context.Context, commandService CommandService, projectGuid string) []string {
+	result := commandService.SimpleExecute(ctx, projectGuid, "git", "diff", "--name-only", "--diff-filter=U")
+	if !result.Success {
+		return nil
+	}
+	return splitLines(result.Output)
+}
+
+// splitLines 按行拆分命令输出，去掉空行
+func splitLines(output string) []string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// sleepContext 等待指定时间，context 取消时立即返回
+func sleepContext(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lighthought/app-maker/shared-models/common"
+
+	"github.com/lighthought/app-maker/agents/internal/config"
+)
+
+func TestGitlabProjectPath(t *testing.T) {
+	tests := []struct {
+		remoteURL string
+		want      string
+		wantErr   bool
+	}{
+		{remoteURL: "http://gitlab.app-maker.localhost/app-maker/demo.git", want: "app-maker/demo"},
+		{remoteURL: "https://gitlab.example.com/group/sub/demo", want: "group/sub/demo"},
+		{remoteURL: "ssh://git@gitlab.example.com:2222/group/demo.git", want: "group/demo"},
+		{remoteURL: "git@gitlab.example.com:group/demo.git", want: "group/demo"},
+		{remoteURL: " git@gitlab:app-maker/demo.git\n", want: "app-maker/demo"},
+		{remoteURL: "", wantErr: true},
+		{remoteURL: "http://gitlab.example.com/", wantErr: true},
+		{remoteURL: "demo", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.remoteURL, func(t *testing.T) {
+			got, err := gitlabProjectPath(tt.remoteURL)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("gitlabProjectPath(%q) err = %v, wantErr %v", tt.remoteURL, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("gitlabProjectPath(%q) = %q, want %q", tt.remoteURL, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalGitProviderPersistsMergeRequests(t *testing.T) {
+	cacheInstance, _ := newTestCache(t)
+	req := &MergeRequestReq{ProjectGuid: "p1", SourceBranch: "appmaker/develop_story/1.1", TargetBranch: "master", Title: "feat"}
+
+	first, err := NewGitProvider(config.GitWorkflowConfig{}, nil, cacheInstance).CreateMergeRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("CreateMergeRequest() err = %v", err)
+	}
+
+	// 服务重启后仍能找到打开的合并请求，编号不会重复
+	restarted := NewGitProvider(config.GitWorkflowConfig{}, nil, cacheInstance)
+	again, err := restarted.CreateMergeRequest(context.Background(), req)
+	if err != nil || again.ID != first.ID {
+		t.Fatalf("CreateMergeRequest() after restart = %+v, %v, want ID %s", again, err, first.ID)
+	}
+	other, err := restarted.CreateMergeRequest(context.Background(), &MergeRequestReq{ProjectGuid: "p1", SourceBranch: "appmaker/develop_story/1.2", TargetBranch: "master"})
+	if err != nil || other.ID == first.ID {
+		t.Errorf("CreateMergeRequest() other branch = %+v, %v", other, err)
+	}
+}
+
+func TestGitlabProviderFallsBackToLocalWithoutRemote(t *testing.T) {
+	cacheInstance, _ := newTestCache(t)
+	provider := NewGitProvider(config.GitWorkflowConfig{
+		Provider:    common.GitProviderGitLab,
+		GitlabURL:   "http://gitlab.invalid",
+		GitlabToken: "token",
+	}, nil, cacheInstance)
+	if provider.Name() != common.GitProviderGitLab {
+		t.Fatalf("provider = %s, want gitlab", provider.Name())
+	}
+
+	mr, err := provider.CreateMergeRequest(context.Background(), &MergeRequestReq{ProjectGuid: "p1", SourceBranch: "appmaker/chat/all", TargetBranch: "master"})
+	if err != nil {
+		t.Fatalf("CreateMergeRequest() err = %v", err)
+	}
+	if mr.Provider != common.GitProviderLocal {
+		t.Errorf("merge request provider = %s, want local", mr.Provider)
+	}
+}
@@ -2,64 +2,633 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
+	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/common"
 	"github.com/lighthought/app-maker/shared-models/logger"
+	"github.com/lighthought/app-maker/shared-models/utils"
+
+	"github.com/lighthought/app-maker/agents/internal/config"
 )
 
+// GitService 阶段、故事分支工作流：每次 Agent 执行在 appmaker/<stage>/<story-number> 分支上进行，
+// 构建和测试通过后通过 GitProvider 创建合并请求并合并回主干
 type GitService interface {
-	CommitAndPush(ctx context.Context, projectGuid string, commitMsg string) error
+	// 从主干切出阶段、故事分支，分支已存在时继续在该分支上工作，返回分支名
+	StartBranch(ctx context.Context, projectGuid, devStage, storyNumber string) (string, error)
+
+	// 提交分支上的变更，构建和测试通过后创建合并请求并合并回主干；结束后工作区切回主干。cliTool 为 mock 时跳过构建和测试
+	CommitAndMerge(ctx context.Context, projectGuid, branch, commitMsg, cliTool string) (*agent.GitBranchResult, error)
+
+	// 任务未完成时把分支上的变更提交到该分支并切回主干，保证工作区干净，下次执行同一阶段、故事时继续在该分支上工作
+	SuspendBranch(ctx context.Context, projectGuid, branch, commitMsg string) error
+
+	// 获取主干分支的最新提交
+	GetBaseHead(ctx context.Context, projectGuid string) (*agent.GitHeadInfo, error)
+
+	// 把主干分支硬重置到指定提交并强制推送，删除所有阶段、故事分支，避免重新执行时继续使用回滚前的分支
+	ResetBaseBranch(ctx context.Context, projectGuid, commitSha string) (*agent.GitHeadInfo, error)
+
+	// 工作区切换到指定提交（HEAD 游离），丢弃未提交的修改，返回完整的提交 SHA；用于重新部署历史提交
+	CheckoutCommit(ctx context.Context, projectGuid, commitSha string) (string, error)
+
+	// 工作区切回主干分支，丢弃未提交的修改
+	CheckoutBaseBranch(ctx context.Context, projectGuid string) error
+
+	// 把工作区的所有文件（包括未跟踪、不包括忽略的文件）写成 tree 对象，不修改暂存区，返回 tree 的 SHA
+	SnapshotWorkTree(ctx context.Context, projectGuid string) (string, error)
+
+	// 比较两个工作区快照，返回 unified diff
+	DiffSnapshots(ctx context.Context, projectGuid, from, to string) (string, error)
+
+	// 比较两个提交，返回 unified diff；from 为空时返回 to 这一个提交引入的变更
+	DiffCommits(ctx context.Context, projectGuid, from, to string) (string, error)
 }
 
 type gitService struct {
 	commandService CommandService
+	lockService    ProjectLockService
+	provider       GitProvider
+	baseBranch     string
+	verifyCommands []string
+	skipVerify     bool
+	verifyTimeout  time.Duration
+	workspacePath  string
 }
 
+// 分支名中不允许出现的字符
+var invalidRefChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// 校验输出只保留最后几行，避免把整个构建日志写进任务结果
+const verifyOutputTailLines = 50
+
 // NewGitService 创建Git服务
-func NewGitService(commandService CommandService) GitService {
+func NewGitService(commandService CommandService, lockService ProjectLockService, provider GitProvider,
+	cfg config.GitWorkflowConfig, workspacePath string) GitService {
 	return &gitService{
 		commandService: commandService,
+		lockService:    lockService,
+		provider:       provider,
+		baseBranch:     cfg.BaseBranch,
+		verifyCommands: cfg.VerifyCommands,
+		skipVerify:     cfg.SkipVerify,
+		verifyTimeout:  cfg.VerifyTimeout,
+		workspacePath:  workspacePath,
+	}
+}
+
+// StartBranch 切出阶段、故事分支
+func (s *gitService) StartBranch(ctx context.Context, projectGuid, devStage, storyNumber string) (string, error) {
+	if err := s.checkProjectLock(ctx, projectGuid); err != nil {
+		return "", err
+	}
+
+	branch := branchName(devStage, storyNumber)
+	if err := s.commitPendingChanges(ctx, projectGuid, branch); err != nil {
+		return "", err
+	}
+
+	if s.branchExists(ctx, projectGuid, branch) {
+		if result := s.commandService.SimpleExecute(ctx, projectGuid, "git", "checkout", branch); !result.Success {
+			return "", fmt.Errorf("切换到分支 %s 失败: %s", branch, result.Error)
+		}
+		logger.Info("继续使用已有分支", logger.String("GUID", projectGuid), logger.String("branch", branch))
+		return branch, nil
+	}
+
+	baseBranch := s.getBaseBranch(ctx, projectGuid)
+	if result := s.commandService.SimpleExecute(ctx, projectGuid, "git", "checkout", baseBranch); !result.Success {
+		return "", fmt.Errorf("切换到主干分支 %s 失败: %s", baseBranch, result.Error)
+	}
+	s.pullBranch(ctx, projectGuid, baseBranch)
+	if result := s.commandService.SimpleExecute(ctx, projectGuid, "git", "checkout", "-b", branch); !result.Success {
+		return "", fmt.Errorf("创建分支 %s 失败: %s", branch, result.Error)
+	}
+
+	logger.Info("已创建分支", logger.String("GUID", projectGuid), logger.String("branch", branch), logger.String("baseBranch", baseBranch))
+	return branch, nil
+}
+
+// CommitAndMerge 提交分支并合并回主干
+func (s *gitService) CommitAndMerge(ctx context.Context, projectGuid, branch, commitMsg, cliTool string) (*agent.GitBranchResult, error) {
+	baseBranch := s.getBaseBranch(ctx, projectGuid)
+	result := &agent.GitBranchResult{
+		Branch:     branch,
+		BaseBranch: baseBranch,
+		Status:     common.GitMergeStatusFailed,
+	}
+	fail := func(status string, err error) (*agent.GitBranchResult, error) {
+		result.Status = status
+		result.Error = err.Error()
+		// 未合并的分支保留在本地和远程仓库，工作区切回主干，下次执行同一阶段、故事时继续在该分支上工作
+		s.commandService.SimpleExecute(ctx, projectGuid, "git", "checkout", baseBranch)
+		return result, err
+	}
+
+	logger.Info("开始提交并合并分支", logger.String("GUID", projectGuid), logger.String("branch", branch))
+	if err := s.checkProjectLock(ctx, projectGuid); err != nil {
+		return fail(common.GitMergeStatusFailed, err)
+	}
+	if err := s.commit(ctx, projectGuid, commitMsg); err != nil {
+		return fail(common.GitMergeStatusFailed, err)
+	}
+	result.CommitSha = s.revParse(ctx, projectGuid, "HEAD")
+
+	ahead, err := s.hasCommitsAhead(ctx, projectGuid, baseBranch, branch)
+	if err != nil {
+		return fail(common.GitMergeStatusFailed, err)
+	}
+	if !ahead {
+		logger.Info("分支没有新的提交，跳过合并", logger.String("GUID", projectGuid), logger.String("branch", branch))
+		s.commandService.SimpleExecute(ctx, projectGuid, "git", "checkout", baseBranch)
+		s.commandService.SimpleExecute(ctx, projectGuid, "git", "branch", "-D", branch)
+		result.Status = common.GitMergeStatusNoChanges
+		return result, nil
+	}
+
+	// 推送分支，推送前再次确认仍持有工作区锁
+	remoteURL := s.getRemoteURL(ctx, projectGuid)
+	if remoteURL != "" {
+		if err := s.checkProjectLock(ctx, projectGuid); err != nil {
+			return fail(common.GitMergeStatusFailed, err)
+		}
+		if res := s.commandService.SimpleExecute(ctx, projectGuid, "git", "push", "-u", "origin", branch); !res.Success {
+			return fail(common.GitMergeStatusFailed, fmt.Errorf("推送分支 %s 失败: %s", branch, res.Error))
+		}
+	}
+
+	// 构建和测试通过后才合并
+	if s.skipVerify || cliTool == common.CliToolMock {
+		logger.Info("跳过合并前的构建和测试", logger.String("GUID", projectGuid), logger.String("cliTool", cliTool))
+		result.VerifySkipped = true
+	} else if err := s.verify(ctx, projectGuid, baseBranch, branch); err != nil {
+		return fail(common.GitMergeStatusVerifyFailed, err)
+	}
+
+	mergeReq := &MergeRequestReq{
+		ProjectGuid:  projectGuid,
+		RemoteURL:    remoteURL,
+		SourceBranch: branch,
+		TargetBranch: baseBranch,
+		Title:        mergeRequestTitle(branch, commitMsg),
+		Description:  commitMsg,
+	}
+	mr, err := s.provider.CreateMergeRequest(ctx, mergeReq)
+	if err != nil {
+		return fail(common.GitMergeStatusFailed, err)
+	}
+	result.MergeRequest = mr
+
+	// 切回主干并更新，先在本地试合并，冲突时列出冲突文件
+	if res := s.commandService.SimpleExecute(ctx, projectGuid, "git", "checkout", baseBranch); !res.Success {
+		return fail(common.GitMergeStatusFailed, fmt.Errorf("切换到主干分支 %s 失败: %s", baseBranch, res.Error))
+	}
+	s.pullBranch(ctx, projectGuid, baseBranch)
+	if files := s.probeConflicts(ctx, projectGuid, branch); len(files) > 0 {
+		result.ConflictFiles = files
+		return fail(common.GitMergeStatusConflict, &MergeConflictError{SourceBranch: branch, TargetBranch: baseBranch, Files: files})
+	}
+
+	if err := s.checkProjectLock(ctx, projectGuid); err != nil {
+		return fail(common.GitMergeStatusFailed, err)
+	}
+	if err := s.provider.MergeMergeRequest(ctx, mergeReq, mr); err != nil {
+		var conflictErr *MergeConflictError
+		if errors.As(err, &conflictErr) {
+			result.ConflictFiles = conflictErr.Files
+			return fail(common.GitMergeStatusConflict, err)
+		}
+		return fail(common.GitMergeStatusFailed, err)
+	}
+
+	// 远程合并后拉取主干，本地分支已合并，删除后下次从主干重新切出
+	s.pullBranch(ctx, projectGuid, baseBranch)
+	s.commandService.SimpleExecute(ctx, projectGuid, "git", "branch", "-D", branch)
+
+	result.Status = common.GitMergeStatusMerged
+	logger.Info("分支已合并回主干",
+		logger.String("GUID", projectGuid),
+		logger.String("branch", branch),
+		logger.String("baseBranch", baseBranch),
+		logger.String("provider", mr.Provider),
+		logger.String("mergeRequest", mr.ID))
+	return result, nil
+}
+
+// SuspendBranch 提交分支上未完成的变更并切回主干
+func (s *gitService) SuspendBranch(ctx context.Context, projectGuid, branch, commitMsg string) error {
+	if err := s.checkProjectLock(ctx, projectGuid); err != nil {
+		return err
+	}
+	// 已经切走（如合并失败后已切回主干）时不再处理
+	if current := s.currentBranch(ctx, projectGuid); current != branch {
+		return nil
+	}
+	if err := s.commit(ctx, projectGuid, commitMsg); err != nil {
+		return fmt.Errorf("提交分支 %s 上未完成的变更失败: %w", branch, err)
+	}
+
+	baseBranch := s.getBaseBranch(ctx, projectGuid)
+	if result := s.commandService.SimpleExecute(ctx, projectGuid, "git", "checkout", baseBranch); !result.Success {
+		return fmt.Errorf("切换到主干分支 %s 失败: %s", baseBranch, result.Error)
+	}
+	logger.Info("任务未完成，变更已提交到分支，工作区切回主干",
+		logger.String("GUID", projectGuid), logger.String("branch", branch), logger.String("baseBranch", baseBranch))
+	return nil
+}
+
+// GetBaseHead 获取主干分支的最新提交
+func (s *gitService) GetBaseHead(ctx context.Context, projectGuid string) (*agent.GitHeadInfo, error) {
+	baseBranch := s.getBaseBranch(ctx, projectGuid)
+	commitSha := s.revParse(ctx, projectGuid, "refs/heads/"+baseBranch)
+	if commitSha == "" {
+		return nil, fmt.Errorf("获取主干分支 %s 的最新提交失败", baseBranch)
+	}
+	return &agent.GitHeadInfo{BaseBranch: baseBranch, CommitSha: commitSha}, nil
+}
+
+// ResetBaseBranch 主干分支硬重置到指定提交
+func (s *gitService) ResetBaseBranch(ctx context.Context, projectGuid, commitSha string) (*agent.GitHeadInfo, error) {
+	if err := s.checkProjectLock(ctx, projectGuid); err != nil {
+		return nil, err
+	}
+	if result := s.commandService.SimpleExecute(ctx, projectGuid, "git", "cat-file", "-e", commitSha+"^{commit}"); !result.Success {
+		return nil, fmt.Errorf("提交 %s 不存在", commitSha)
+	}
+
+	baseBranch := s.getBaseBranch(ctx, projectGuid)
+	if result := s.commandService.SimpleExecute(ctx, projectGuid, "git", "checkout", "-f", baseBranch); !result.Success {
+		return nil, fmt.Errorf("切换到主干分支 %s 失败: %s", baseBranch, result.Error)
+	}
+	if result := s.commandService.SimpleExecute(ctx, projectGuid, "git", "reset", "--hard", commitSha); !result.Success {
+		return nil, fmt.Errorf("重置主干分支 %s 失败: %s", baseBranch, result.Error)
+	}
+	if result := s.commandService.SimpleExecute(ctx, projectGuid, "git", "clean", "-fd"); !result.Success {
+		logger.Warn("清理未跟踪的文件失败", logger.String("GUID", projectGuid), logger.String("error", result.Error))
+	}
+
+	remoteURL := s.getRemoteURL(ctx, projectGuid)
+	for _, branch := range s.listWorkflowBranches(ctx, projectGuid) {
+		s.commandService.SimpleExecute(ctx, projectGuid, "git", "branch", "-D", branch)
+		if remoteURL != "" {
+			// 远程分支可能不存在（已合并后删除或从未推送），失败只记录日志
+			if result := s.commandService.SimpleExecute(ctx, projectGuid, "git", "push", "origin", "--delete", branch); !result.Success {
+				logger.Info("删除远程分支失败", logger.String("GUID", projectGuid), logger.String("branch", branch), logger.String("error", result.Error))
+			}
+		}
+	}
+
+	if remoteURL != "" {
+		if err := s.checkProjectLock(ctx, projectGuid); err != nil {
+			return nil, err
+		}
+		if result := s.commandService.SimpleExecute(ctx, projectGuid, "git", "push", "--force", "origin", baseBranch); !result.Success {
+			return nil, fmt.Errorf("强制推送主干分支 %s 失败: %s", baseBranch, result.Error)
+		}
+	}
+
+	logger.Info("主干分支已回滚",
+		logger.String("GUID", projectGuid), logger.String("baseBranch", baseBranch), logger.String("commitSha", commitSha))
+	return s.GetBaseHead(ctx, projectGuid)
+}
+
+// CheckoutCommit 工作区切换到指定提交
+func (s *gitService) CheckoutCommit(ctx context.Context, projectGuid, commitSha string) (string, error) {
+	if err := s.checkProjectLock(ctx, projectGuid); err != nil {
+		return "", err
+	}
+	fullSha := s.revParse(ctx, projectGuid, commitSha+"^{commit}")
+	if fullSha == "" {
+		return "", fmt.Errorf("提交 %s 不存在", commitSha)
+	}
+	if result := s.commandService.SimpleExecute(ctx, projectGuid, "git", "checkout", "-f", "--detach", fullSha); !result.Success {
+		return "", fmt.Errorf("切换到提交 %s 失败: %s", commitSha, result.Error)
+	}
+	logger.Info("工作区已切换到提交", logger.String("GUID", projectGuid), logger.String("commitSha", fullSha))
+	return fullSha, nil
+}
+
+// CheckoutBaseBranch 工作区切回主干分支
+func (s *gitService) CheckoutBaseBranch(ctx context.Context, projectGuid string) error {
+	baseBranch := s.getBaseBranch(ctx, projectGuid)
+	if result := s.commandService.SimpleExecute(ctx, projectGuid, "git", "checkout", "-f", baseBranch); !result.Success {
+		return fmt.Errorf("切换到主干分支 %s 失败: %s", baseBranch, result.Error)
+	}
+	return nil
+}
+
+// SnapshotWorkTree 使用临时暂存区写入 tree 对象，项目的暂存区和工作区都不受影响
+func (s *gitService) SnapshotWorkTree(ctx context.Context, projectGuid string) (string, error) {
+	indexFile, err := os.CreateTemp("", "app-maker-index-*")
+	if err != nil {
+		return "", fmt.Errorf("创建临时暂存区失败: %w", err)
+	}
+	indexPath := indexFile.Name()
+	defer os.Remove(indexPath)
+
+	// 复制项目的暂存区，git add 可以复用其中的文件状态，不用重新计算所有文件的哈希
+	index, err := os.ReadFile(filepath.Join(s.workspacePath, projectGuid, ".git", "index"))
+	if err == nil {
+		_, err = indexFile.Write(index)
+	}
+	indexFile.Close()
+	if err != nil {
+		// 空文件不是有效的暂存区，删除后 git 会新建
+		os.Remove(indexPath)
+	}
+
+	env := []string{"GIT_INDEX_FILE=" + indexPath}
+	if result := s.commandService.StreamExecuteWithEnv(ctx, projectGuid, env, nil, "git", "add", "-A"); !result.Success {
+		return "", fmt.Errorf("暂存工作区文件失败: %s", result.Error)
+	}
+	result := s.commandService.StreamExecuteWithEnv(ctx, projectGuid, env, nil, "git", "write-tree")
+	if !result.Success {
+		return "", fmt.Errorf("写入工作区快照失败: %s", result.Error)
+	}
+	return strings.TrimSpace(result.Output), nil
+}
+
+// DiffSnapshots 比较两个工作区快照
+func (s *gitService) DiffSnapshots(ctx context.Context, projectGuid, from, to string) (string, error) {
+	result := s.commandService.SimpleExecute(ctx, projectGuid, "git", "diff", "--no-color", from, to)
+	if !result.Success {
+		return "", fmt.Errorf("比较工作区快照失败: %s", result.Error)
+	}
+	return result.Output, nil
+}
+
+// DiffCommits 比较两个提交，from 为空时与 to 的父提交比较
+func (s *gitService) DiffCommits(ctx context.Context, projectGuid, from, to string) (string, error) {
+	args := []string{"diff", "--no-color", from, to}
+	if from == "" {
+		args = []string{"show", "--no-color", "--format=", to}
+	}
+	result := s.commandService.SimpleExecute(ctx, projectGuid, "git", args...)
+	if !result.Success {
+		return "", fmt.Errorf("获取提交 %s 的变更失败: %s", to, result.Error)
 	}
+	return result.Output, nil
 }
 
-// CommitAndPush 提交并推送代码
-func (s *gitService) CommitAndPush(ctx context.Context, projectGuid string, commitMsg string) error {
-	logger.Info("开始提交并推送代码", logger.String("GUID", projectGuid))
+// listWorkflowBranches 列出本地的阶段、故事分支
+func (s *gitService) listWorkflowBranches(ctx context.Context, projectGuid string) []string {
+	result := s.commandService.SimpleExecute(ctx, projectGuid, "git", "for-each-ref", "--format=%(refname:short)", "refs/heads/"+common.GitBranchPrefix+"/")
+	if !result.Success {
+		return nil
+	}
+	return splitLines(result.Output)
+}
+
+// commitPendingChanges 处理上次任务异常退出留下的未提交变更：在其他阶段、故事分支上时提交到该分支；
+// 在主干或目标分支上时保留在工作区，切换后带到目标分支，随本次任务一起提交和校验
+func (s *gitService) commitPendingChanges(ctx context.Context, projectGuid, branch string) error {
+	status := s.commandService.SimpleExecute(ctx, projectGuid, "git", "status", "--porcelain")
+	if !status.Success || status.Output == "" {
+		return nil
+	}
 
+	current := s.currentBranch(ctx, projectGuid)
+	if current == branch || !strings.HasPrefix(current, common.GitBranchPrefix+"/") {
+		logger.Warn("工作区有未提交的变更，将带到分支并随本次任务提交",
+			logger.String("GUID", projectGuid), logger.String("currentBranch", current), logger.String("branch", branch))
+		return nil
+	}
+
+	commitMsg := utils.BuildCommitMessage("wip: save unfinished changes on "+current, "", nil)
+	if err := s.commit(ctx, projectGuid, commitMsg); err != nil {
+		return fmt.Errorf("提交分支 %s 上未完成的变更失败: %w", current, err)
+	}
+	logger.Warn("工作区有未提交的变更，已提交到原分支",
+		logger.String("GUID", projectGuid), logger.String("currentBranch", current), logger.String("branch", branch))
+	return nil
+}
+
+// commit 提交当前分支上的所有变更，没有变更时跳过
+func (s *gitService) commit(ctx context.Context, projectGuid, commitMsg string) error {
 	// 添加所有文件
 	if result := s.commandService.SimpleExecute(ctx, projectGuid, "git", "add", "."); !result.Success {
 		return fmt.Errorf("添加文件到Git失败: %s", result.Error)
 	}
 
-	// 检查是否有变更， 暂时跳过，在大量文件提交的时候，存在直接退出的情况
 	if !s.hasChanges(ctx, projectGuid) {
 		logger.Info("没有文件变更，跳过提交", logger.String("GUID", projectGuid))
 		return nil
 	}
 
-	// 提交变更
 	if commitMsg == "" {
 		commitMsg = fmt.Sprintf("Auto commit by App Maker - %s", projectGuid)
 	}
-
 	if result := s.commandService.SimpleExecute(ctx, projectGuid, "git", "commit", "-m", commitMsg); !result.Success {
 		return fmt.Errorf("提交代码失败: %s", result.Error)
 	}
+	return nil
+}
+
+// verify 执行构建和测试，只修改了文档时跳过
+func (s *gitService) verify(ctx context.Context, projectGuid, baseBranch, branch string) error {
+	changed := s.commandService.SimpleExecute(ctx, projectGuid, "git", "diff", "--name-only", baseBranch+"..."+branch)
+	if changed.Success && onlyDocuments(splitLines(changed.Output)) {
+		logger.Info("只修改了文档，跳过构建和测试", logger.String("GUID", projectGuid), logger.String("branch", branch))
+		return nil
+	}
 
-	// 推送到远程仓库
-	if result := s.commandService.SimpleExecute(ctx, projectGuid, "git", "push", "-u", "origin", "master"); !result.Success {
-		// 如果master分支不存在，尝试main分支
-		if result := s.commandService.SimpleExecute(ctx, projectGuid, "git", "push", "-u", "origin", "main"); !result.Success {
-			return fmt.Errorf("推送代码失败: %s", result.Error)
+	commands, err := s.getVerifyCommands(projectGuid)
+	if err != nil {
+		return err
+	}
+	for _, command := range commands {
+		logger.Info("合并前校验", logger.String("GUID", projectGuid), logger.String("command", command.Name))
+		if err := s.runVerifyCommand(ctx, command); err != nil {
+			return err
 		}
 	}
+	return nil
+}
 
-	logger.Info("项目文档、代码提交并推送完成", logger.String("GUID", projectGuid), logger.String("commitMessage", commitMsg))
+// runVerifyCommand 执行单条校验命令，使用独立的超时时间，不受 CLI 命令超时的影响
+func (s *gitService) runVerifyCommand(ctx context.Context, command projectCommand) error {
+	if s.verifyTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.verifyTimeout)
+		defer cancel()
+	}
+	// CI=true 让 vitest、jest 等测试框架不进入 watch 模式
+	result := s.commandService.StreamExecuteWithEnv(ctx, command.Subfolder, []string{"CI=true"}, nil, command.Process, command.Args...)
+	if !result.Success {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("%s 超时（%s）\n%s", command.Name, s.verifyTimeout, tailLines(result.Output, verifyOutputTailLines))
+		}
+		return fmt.Errorf("%s 失败: %s\n%s", command.Name, result.Error, tailLines(result.Output, verifyOutputTailLines))
+	}
 	return nil
 }
 
+// getVerifyCommands 获取合并前执行的命令：优先使用配置，否则使用项目清单中各组件的构建、代码检查和测试命令
+func (s *gitService) getVerifyCommands(projectGuid string) ([]projectCommand, error) {
+	if len(s.verifyCommands) > 0 {
+		commands := make([]projectCommand, 0, len(s.verifyCommands))
+		for _, command := range s.verifyCommands {
+			commands = append(commands, projectCommand{Name: command, Subfolder: projectGuid, Process: "sh", Args: []string{"-c", command}})
+		}
+		return commands, nil
+	}
+
+	manifest, err := loadProjectManifest(filepath.Join(s.workspacePath, projectGuid))
+	if err != nil {
+		return nil, err
+	}
+	return manifest.verifyCommands(projectGuid), nil
+}
+
+// probeConflicts 在主干上试合并分支，返回冲突的文件，试合并的结果会被撤销
+func (s *gitService) probeConflicts(ctx context.Context, projectGuid, branch string) []string {
+	result := s.commandService.SimpleExecute(ctx, projectGuid, "git", "merge", "--no-commit", "--no-ff", branch)
+	var files []string
+	if !result.Success {
+		files = listConflictFiles(ctx, s.commandService, projectGuid)
+	}
+	s.commandService.SimpleExecute(ctx, projectGuid, "git", "merge", "--abort")
+	return files
+}
+
+// getBaseBranch 获取主干分支：优先使用配置，否则依次检测远程默认分支、master、main
+func (s *gitService) getBaseBranch(ctx context.Context, projectGuid string) string {
+	if s.baseBranch != "" {
+		return s.baseBranch
+	}
+	if result := s.commandService.SimpleExecute(ctx, projectGuid, "git", "symbolic-ref", "--short", "refs/remotes/origin/HEAD"); result.Success {
+		if branch := strings.TrimPrefix(result.Output, "origin/"); branch != "" {
+			return branch
+		}
+	}
+	for _, branch := range []string{"master", "main"} {
+		if s.branchExists(ctx, projectGuid, branch) {
+			return branch
+		}
+	}
+	return "master"
+}
+
+// pullBranch 拉取远程分支，没有远程仓库或拉取失败时只记录日志
+func (s *gitService) pullBranch(ctx context.Context, projectGuid, branch string) {
+	if s.getRemoteURL(ctx, projectGuid) == "" {
+		return
+	}
+	if result := s.commandService.SimpleExecute(ctx, projectGuid, "git", "pull", "--no-rebase", "origin", branch); !result.Success {
+		logger.Warn("拉取分支失败", logger.String("GUID", projectGuid), logger.String("branch", branch), logger.String("error", result.Error))
+	}
+}
+
+// getRemoteURL 获取 origin 地址，没有远程仓库时为空
+func (s *gitService) getRemoteURL(ctx context.Context, projectGuid string) string {
+	result := s.commandService.SimpleExecute(ctx, projectGuid, "git", "remote", "get-url", "origin")
+	if !result.Success {
+		return ""
+	}
+	return result.Output
+}
+
+func (s *gitService) branchExists(ctx context.Context, projectGuid, branch string) bool {
+	return s.commandService.SimpleExecute(ctx, projectGuid, "git", "rev-parse", "--verify", "--quiet", "refs/heads/"+branch).Success
+}
+
+func (s *gitService) revParse(ctx context.Context, projectGuid, ref string) string {
+	result := s.commandService.SimpleExecute(ctx, projectGuid, "git", "rev-parse", ref)
+	if !result.Success {
+		return ""
+	}
+	return result.Output
+}
+
+// currentBranch 获取当前分支名，HEAD 游离时为 HEAD
+func (s *gitService) currentBranch(ctx context.Context, projectGuid string) string {
+	result := s.commandService.SimpleExecute(ctx, projectGuid, "git", "rev-parse", "--abbrev-ref", "HEAD")
+	if !result.Success {
+		return ""
+	}
+	return strings.TrimSpace(result.Output)
+}
+
+// hasCommitsAhead 分支上是否有主干没有的提交
+func (s *gitService) hasCommitsAhead(ctx context.Context, projectGuid, baseBranch, branch string) (bool, error) {
+	result := s.commandService.SimpleExecute(ctx, projectGuid, "git", "rev-list", "--count", baseBranch+".."+branch)
+	if !result.Success {
+		return false, fmt.Errorf("比较分支 %s 和 %s 失败: %s", branch, baseBranch, result.Error)
+	}
+	count := strings.TrimSpace(result.Output)
+	return count != "" && count != "0", nil
+}
+
+// checkProjectLock 校验当前上下文持有该项目的工作区锁，且 fencing token 仍是当前持有者，避免锁过期后覆盖其他任务的提交
+func (s *gitService) checkProjectLock(ctx context.Context, projectGuid string) error {
+	if s.lockService == nil {
+		return nil
+	}
+	lock := projectLockFromContext(ctx)
+	if lock == nil || lock.ProjectGuid != projectGuid {
+		return fmt.Errorf("未持有项目 %s 的工作区锁", projectGuid)
+	}
+	return s.lockService.CheckFencingToken(projectGuid, lock.FencingToken)
+}
+
 // hasChanges 检查是否有文件变更
 func (s *gitService) hasChanges(ctx context.Context, projectDir string) bool {
 	result := s.commandService.SimpleExecute(ctx, projectDir, "git", "diff", "--cached", "--quiet")
 	return !result.Success
 }
+
+// branchName 阶段、故事分支名，如 appmaker/develop_story/1.1；没有故事编号时为 appmaker/<stage>/all
+func branchName(devStage, storyNumber string) string {
+	if devStage == "" || devStage == string(common.DevStatusUnknown) {
+		devStage = common.GitBranchChat
+	}
+	if storyNumber == "" {
+		storyNumber = common.GitBranchAllStory
+	}
+	return common.GitBranchPrefix + "/" + sanitizeRef(devStage) + "/" + sanitizeRef(storyNumber)
+}
+
+// sanitizeRef 把不能出现在分支名中的字符替换为 -
+func sanitizeRef(name string) string {
+	name = invalidRefChars.ReplaceAllString(name, "-")
+	name = strings.ReplaceAll(name, "..", ".")
+	return strings.Trim(name, ".-")
+}
+
+// mergeRequestTitle 合并请求标题：提交信息的第一行，为空时使用分支名
+func mergeRequestTitle(branch, commitMsg string) string {
+	title := strings.TrimSpace(strings.SplitN(strings.TrimSpace(commitMsg), "\n", 2)[0])
+	if title == "" {
+		return "Merge " + branch
+	}
+	if runes := []rune(title); len(runes) > 100 {
+		title = string(runes[:100]) + "..."
+	}
+	return title
+}
+
+// onlyDocuments 变更是否只包含文档
+func onlyDocuments(files []string) bool {
+	for _, file := range files {
+		if !strings.HasPrefix(file, "docs/") && !strings.HasSuffix(strings.ToLower(file), ".md") {
+			return false
+		}
+	}
+	return true
+}
+
+// tailLines 保留输出的最后 n 行
+func tailLines(output string, n int) string {
+	lines := strings.Split(output, "\n")
+	if len(lines) <= n {
+		return output
+	}
+	return strings.Join(lines[len(lines)-n:], "\n")
+}
@@ -0,0 +1,255 @@
+package services
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lighthought/app-maker/agents/internal/config"
+)
+
+func TestBranchName(t *testing.T) {
+	tests := []struct {
+		devStage    string
+		storyNumber string
+		want        string
+	}{
+		{devStage: "develop_story", storyNumber: "1.1", want: "appmaker/develop_story/1.1"},
+		{devStage: "prd_generating", want: "appmaker/prd_generating/all"},
+		{want: "appmaker/chat/all"},
+		{devStage: "unknown", want: "appmaker/chat/all"},
+		{devStage: "develop_story", storyNumber: "Story 2: 登录", want: "appmaker/develop_story/Story-2"},
+		{devStage: "develop_story", storyNumber: "../1..2", want: "appmaker/develop_story/1.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := branchName(tt.devStage, tt.storyNumber); got != tt.want {
+				t.Errorf("branchName(%q, %q) = %q, want %q", tt.devStage, tt.storyNumber, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeRef(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "1.1", want: "1.1"},
+		{name: "feature/login", want: "feature-login"},
+		{name: "a b~c^d:e?f*g[h", want: "a-b-c-d-e-f-g-h"},
+		{name: "a..b", want: "a.b"},
+		{name: ".hidden-", want: "hidden"},
+		{name: "中文", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeRef(tt.name); got != tt.want {
+				t.Errorf("sanitizeRef(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+// newTestGitProject 在临时工作空间中初始化只有一个提交的 master 分支
+func newTestGitProject(t *testing.T) (*gitService, string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	workspace := t.TempDir()
+	projectGuid := "p1"
+	if err := os.MkdirAll(filepath.Join(workspace, projectGuid), 0755); err != nil {
+		t.Fatal(err)
+	}
+	commandService := NewCommandService(config.CommandConfig{Timeout: time.Minute}, workspace)
+	git := NewGitService(commandService, nil, nil, config.GitWorkflowConfig{BaseBranch: "master"}, workspace).(*gitService)
+
+	ctx := context.Background()
+	for _, args := range [][]string{
+		{"init", "-b", "master"},
+		{"commit", "--allow-empty", "-m", "init"},
+	} {
+		if result := commandService.SimpleExecute(ctx, projectGuid, "git", args...); !result.Success {
+			t.Fatalf("git %v: %s", args, result.Error)
+		}
+	}
+	return git, filepath.Join(workspace, projectGuid)
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGitServiceSuspendAndResumeBranch(t *testing.T) {
+	git, projectPath := newTestGitProject(t)
+	ctx := context.Background()
+
+	branch, err := git.StartBranch(ctx, "p1", "develop_story", "1.1")
+	if err != nil {
+		t.Fatalf("StartBranch() err = %v", err)
+	}
+	writeTestFile(t, filepath.Join(projectPath, "main.go"), "package main\n")
+
+	// 任务失败：变更提交到分支，工作区切回主干且干净
+	if err := git.SuspendBranch(ctx, "p1", branch, "wip(dev): save unfinished changes"); err != nil {
+		t.Fatalf("SuspendBranch() err = %v", err)
+	}
+	if current := git.currentBranch(ctx, "p1"); current != "master" {
+		t.Errorf("current branch = %s, want master", current)
+	}
+	if status := git.commandService.SimpleExecute(ctx, "p1", "git", "status", "--porcelain"); status.Output != "" {
+		t.Errorf("workspace is dirty: %s", status.Output)
+	}
+	if ahead, err := git.hasCommitsAhead(ctx, "p1", "master", branch); err != nil || !ahead {
+		t.Errorf("hasCommitsAhead() = %v, %v, want true", ahead, err)
+	}
+
+	// 重新执行时继续在该分支上工作
+	if _, err := git.StartBranch(ctx, "p1", "develop_story", "1.1"); err != nil {
+		t.Fatalf("StartBranch() again err = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(projectPath, "main.go")); err != nil {
+		t.Errorf("unfinished change should be on the branch: %v", err)
+	}
+}
+
+func TestGitServiceStartBranchCommitsPendingChanges(t *testing.T) {
+	git, projectPath := newTestGitProject(t)
+	ctx := context.Background()
+
+	first, err := git.StartBranch(ctx, "p1", "develop_story", "1.1")
+	if err != nil {
+		t.Fatalf("StartBranch() err = %v", err)
+	}
+	// 模拟进程异常退出，分支上留下未提交的变更
+	writeTestFile(t, filepath.Join(projectPath, "story1.txt"), "story 1.1")
+
+	if _, err := git.StartBranch(ctx, "p1", "develop_story", "1.2"); err != nil {
+		t.Fatalf("StartBranch() err = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(projectPath, "story1.txt")); !os.IsNotExist(err) {
+		t.Error("changes of story 1.1 should not be carried to story 1.2")
+	}
+	stash := git.commandService.SimpleExecute(ctx, "p1", "git", "stash", "list")
+	if strings.TrimSpace(stash.Output) != "" {
+		t.Errorf("changes should not be stashed: %s", stash.Output)
+	}
+	log := git.commandService.SimpleExecute(ctx, "p1", "git", "log", "--format=%s", "-1", first)
+	if !strings.HasPrefix(log.Output, "wip:") {
+		t.Errorf("last commit on %s = %q, want wip commit", first, log.Output)
+	}
+}
+
+func TestGitServiceHasCommitsAheadError(t *testing.T) {
+	git, _ := newTestGitProject(t)
+	if _, err := git.hasCommitsAhead(context.Background(), "p1", "master", "missing-branch"); err == nil {
+		t.Error("hasCommitsAhead() with missing branch should return error")
+	}
+}
+
+func TestGitServiceResetBaseBranch(t *testing.T) {
+	git, projectPath := newTestGitProject(t)
+	ctx := context.Background()
+
+	before, err := git.GetBaseHead(ctx, "p1")
+	if err != nil {
+		t.Fatalf("GetBaseHead() err = %v", err)
+	}
+
+	// 阶段执行后主干多了提交，还留下一个未合并的分支
+	writeTestFile(t, filepath.Join(projectPath, "broken.go"), "package main\n")
+	git.commandService.SimpleExecute(ctx, "p1", "git", "add", ".")
+	git.commandService.SimpleExecute(ctx, "p1", "git", "commit", "-m", "feat: damage")
+	branch, _ := git.StartBranch(ctx, "p1", "develop_story", "1.1")
+	writeTestFile(t, filepath.Join(projectPath, "untracked.txt"), "x")
+
+	head, err := git.ResetBaseBranch(ctx, "p1", before.CommitSha)
+	if err != nil {
+		t.Fatalf("ResetBaseBranch() err = %v", err)
+	}
+	if head.CommitSha != before.CommitSha || head.BaseBranch != "master" {
+		t.Errorf("head = %+v, want %+v", head, before)
+	}
+	if git.currentBranch(ctx, "p1") != "master" || git.branchExists(ctx, "p1", branch) {
+		t.Errorf("workflow branch %s should be deleted and workspace on master", branch)
+	}
+	for _, file := range []string{"broken.go", "untracked.txt"} {
+		if _, err := os.Stat(filepath.Join(projectPath, file)); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed by rollback", file)
+		}
+	}
+
+	if _, err := git.ResetBaseBranch(ctx, "p1", "0000000000000000000000000000000000000000"); err == nil {
+		t.Error("ResetBaseBranch() to missing commit should fail")
+	}
+}
+
+func TestGitServiceCheckoutCommit(t *testing.T) {
+	git, projectPath := newTestGitProject(t)
+	ctx := context.Background()
+
+	first, _ := git.GetBaseHead(ctx, "p1")
+	writeTestFile(t, filepath.Join(projectPath, "feature.go"), "package main\n")
+	git.commandService.SimpleExecute(ctx, "p1", "git", "add", ".")
+	git.commandService.SimpleExecute(ctx, "p1", "git", "commit", "-m", "feat: feature")
+	latest, _ := git.GetBaseHead(ctx, "p1")
+
+	// 使用短 SHA 重新部署历史提交
+	sha, err := git.CheckoutCommit(ctx, "p1", first.CommitSha[:8])
+	if err != nil || sha != first.CommitSha {
+		t.Fatalf("CheckoutCommit() = %q, %v, want %s", sha, err, first.CommitSha)
+	}
+	if _, err := os.Stat(filepath.Join(projectPath, "feature.go")); !os.IsNotExist(err) {
+		t.Error("feature.go should not exist at the first commit")
+	}
+	if head, _ := git.GetBaseHead(ctx, "p1"); head.CommitSha != latest.CommitSha {
+		t.Errorf("base branch moved to %s, want %s", head.CommitSha, latest.CommitSha)
+	}
+
+	if err := git.CheckoutBaseBranch(ctx, "p1"); err != nil {
+		t.Fatalf("CheckoutBaseBranch() err = %v", err)
+	}
+	if git.currentBranch(ctx, "p1") != "master" || git.revParse(ctx, "p1", "HEAD") != latest.CommitSha {
+		t.Errorf("workspace should be back on master at %s", latest.CommitSha)
+	}
+
+	if _, err := git.CheckoutCommit(ctx, "p1", "0000000000000000000000000000000000000000"); err == nil {
+		t.Error("CheckoutCommit() to missing commit should fail")
+	}
+}
+
+func TestGitServiceDiffCommits(t *testing.T) {
+	git, projectPath := newTestGitProject(t)
+	ctx := context.Background()
+
+	first, _ := git.GetBaseHead(ctx, "p1")
+	for _, content := range []string{"package main\n", "package main\n\nfunc main() {}\n"} {
+		writeTestFile(t, filepath.Join(projectPath, "main.go"), content)
+		git.commandService.SimpleExecute(ctx, "p1", "git", "add", ".")
+		git.commandService.SimpleExecute(ctx, "p1", "git", "commit", "-m", "feat: update main")
+	}
+	latest, _ := git.GetBaseHead(ctx, "p1")
+
+	// from 为空时只包含最后一个提交的变更
+	diff, err := git.DiffCommits(ctx, "p1", "", latest.CommitSha)
+	if err != nil || !strings.Contains(diff, "+func main() {}") || strings.Contains(diff, "+package main") {
+		t.Errorf("DiffCommits(last) = %q, %v", diff, err)
+	}
+	diff, err = git.DiffCommits(ctx, "p1", first.CommitSha, latest.CommitSha)
+	if err != nil || !strings.Contains(diff, "+package main") || !strings.Contains(diff, "+func main() {}") {
+		t.Errorf("DiffCommits(range) = %q, %v", diff, err)
+	}
+}
@@ -0,0 +1,359 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/lighthought/app-maker/shared-models/utils"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectManifestFileName 项目根目录下的项目清单文件
+const ProjectManifestFileName = "app-maker.yaml"
+
+// 当前支持的项目清单版本
+const projectManifestVersion = 1
+
+// ProjectManifest 项目清单：声明项目由哪些组件组成，以及准备环境、构建、测试、启动的命令。
+// 项目没有清单文件时按目录结构检测 Go 后端和 npm 前端
+type ProjectManifest struct {
+	Version    int                 `yaml:"version"`
+	Components []ManifestComponent `yaml:"components"`
+	Deploy     ManifestDeploy      `yaml:"deploy"`
+}
+
+// ManifestComponent 项目组件，命令在组件目录下执行
+type ManifestComponent struct {
+	Name       string   `yaml:"name"`
+	Path       string   `yaml:"path"`        // 相对项目根目录，为空表示项目根目录
+	Setup      []string `yaml:"setup"`       // 准备环境：安装依赖、生成构建产物
+	Outputs    []string `yaml:"outputs"`     // setup 生成的目录、文件，相对组件目录；都存在时跳过 setup，工作区闲置时会被清理
+	Build      []string `yaml:"build"`       // 合并前校验的构建命令
+	Lint       []string `yaml:"lint"`        // 合并前校验的代码检查命令
+	Test       []string `yaml:"test"`        // 合并前校验的测试命令
+	TestReport []string `yaml:"test_report"` // 自动测试输出 JSON 报告的命令（go test -json、ESLint、Vitest/Jest），为空时执行 lint、test
+	Run        []string `yaml:"run"`         // 部署时启动组件，deploy 未配置时使用
+	Ports      []int    `yaml:"ports"`       // 组件监听的端口
+	HealthURL  string   `yaml:"health_url"`  // 组件启动后的健康检查地址，返回 2xx、3xx 视为就绪
+	Logs       string   `yaml:"logs"`        // 输出组件最近日志的命令，健康检查失败时附在部署结果中
+}
+
+// ManifestDeploy 部署命令，在项目根目录执行；environments 中声明的环境使用该环境的命令，其余环境使用 build、run，
+// 都为空时依次执行各组件的 build、run
+type ManifestDeploy struct {
+	ManifestDeployTarget `yaml:",inline"`
+	Environments         map[string]ManifestDeployTarget `yaml:"environments"` // 按部署环境（dev、staging、prod）覆盖的命令
+}
+
+// ManifestDeployTarget 一个部署环境的构建和启动命令
+type ManifestDeployTarget struct {
+	Build []string `yaml:"build"`
+	Run   []string `yaml:"run"`
+}
+
+// projectCommand 在工作空间中执行的项目命令
+type projectCommand struct {
+	Name      string
+	Component string // 所属组件，部署命令为空
+	Subfolder string // 相对工作空间的执行目录
+	Process   string
+	Args      []string
+}
+
+// 出现这些字符的命令交给 sh -c 执行，其余直接执行，不依赖 shell
+const shellMetaChars = "|&;<>()$`\\\"'*?[]#~=%\n"
+
+// commandLine 在项目根目录执行该命令的命令行，用于提示 Agent 修复
+func (c projectCommand) commandLine(projectGuid string) string {
+	line := strings.Join(append([]string{c.Process}, c.Args...), " ")
+	if c.Process == "sh" && len(c.Args) == 2 && c.Args[0] == "-c" {
+		line = c.Args[1]
+	}
+	if dir := strings.TrimPrefix(strings.TrimPrefix(c.Subfolder, projectGuid), "/"); dir != "" {
+		line = "cd " + dir + " && " + line
+	}
+	return line
+}
+
+// newProjectCommand 把清单中的命令行转换为可执行的命令
+func newProjectCommand(name, subfolder, command string) projectCommand {
+	fields := strings.Fields(command)
+	if len(fields) == 0 || strings.ContainsAny(command, shellMetaChars) {
+		return projectCommand{Name: name, Subfolder: subfolder, Process: "sh", Args: []string{"-c", command}}
+	}
+	return projectCommand{Name: name, Subfolder: subfolder, Process: fields[0], Args: fields[1:]}
+}
+
+// loadProjectManifest 读取项目清单，文件不存在时按项目结构检测
+func loadProjectManifest(projectPath string) (*ProjectManifest, error) {
+	data, err := os.ReadFile(filepath.Join(projectPath, ProjectManifestFileName))
+	if os.IsNotExist(err) {
+		return detectProjectManifest(projectPath), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取 %s 失败: %w", ProjectManifestFileName, err)
+	}
+
+	var manifest ProjectManifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", ProjectManifestFileName, err)
+	}
+	if err := manifest.validate(); err != nil {
+		return nil, fmt.Errorf("%s 无效: %w", ProjectManifestFileName, err)
+	}
+	return &manifest, nil
+}
+
+// validate 校验清单版本和组件
+func (m *ProjectManifest) validate() error {
+	if m.Version != projectManifestVersion {
+		return fmt.Errorf("不支持的版本 %d，当前支持版本 %d", m.Version, projectManifestVersion)
+	}
+	names := make(map[string]bool)
+	for _, component := range m.Components {
+		if component.Name == "" {
+			return fmt.Errorf("组件缺少 name")
+		}
+		if names[component.Name] {
+			return fmt.Errorf("组件 %s 重复", component.Name)
+		}
+		names[component.Name] = true
+		if component.Path != "" && !filepath.IsLocal(component.Path) {
+			return fmt.Errorf("组件 %s 的 path 必须是项目内的相对路径", component.Name)
+		}
+		for _, output := range component.Outputs {
+			if !filepath.IsLocal(output) {
+				return fmt.Errorf("组件 %s 的 outputs 必须是组件目录内的相对路径", component.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// detectProjectManifest 按项目结构检测：backend/go.mod 为 Go 后端，frontend/package.json 为 npm 前端，
+// 根目录 Makefile 中的 build-<环境>、run-<环境> 目标作为该环境的部署命令，如 make build-dev、make run-dev
+func detectProjectManifest(projectPath string) *ProjectManifest {
+	manifest := &ProjectManifest{Version: projectManifestVersion}
+	if utils.IsFileExists(filepath.Join(projectPath, "backend", "go.mod")) {
+		manifest.Components = append(manifest.Components, ManifestComponent{
+			Name:    "backend",
+			Path:    "backend",
+			Setup:   []string{"go mod download", "go build -o server ./cmd/server"},
+			Outputs: []string{"server"},
+			Build:   []string{"go build ./..."},
+			Test:    []string{"go test ./..."},
+			// 测试报告使用 go test -json，同时输出覆盖率
+			TestReport: []string{"go test -json -cover ./..."},
+		})
+	}
+	if scripts := readPackageScripts(filepath.Join(projectPath, "frontend", "package.json")); scripts != nil {
+		component := ManifestComponent{
+			Name:    "frontend",
+			Path:    "frontend",
+			Setup:   []string{"npm install"},
+			Outputs: []string{"node_modules"},
+		}
+		if _, ok := scripts["build"]; ok {
+			component.Build = []string{"npm run build"}
+		}
+		if _, ok := scripts["test"]; ok {
+			component.Test = []string{"npm run test"}
+		}
+		component.TestReport = detectNpmTestReport(scripts)
+		manifest.Components = append(manifest.Components, component)
+	}
+	manifest.Deploy.Environments = detectMakeDeployTargets(filepath.Join(projectPath, "Makefile"))
+	return manifest
+}
+
+// detectNpmTestReport 按 package.json 中 lint、test 脚本使用的工具，追加参数输出 JSON 报告
+func detectNpmTestReport(scripts map[string]string) []string {
+	var commands []string
+	if strings.Contains(scripts["lint"], "eslint") {
+		commands = append(commands, "npm run --silent lint -- -f json")
+	}
+	switch test := scripts["test"]; {
+	case strings.Contains(test, "vitest"):
+		commands = append(commands, "npm run --silent test -- --run --reporter=json")
+	case strings.Contains(test, "jest"):
+		commands = append(commands, "npm run --silent test -- --json")
+	}
+	return commands
+}
+
+// Makefile 中的部署目标，如 build-dev:、run-prod:
+var makeDeployTarget = regexp.MustCompile(`(?m)^(build|run)-([A-Za-z0-9_]+)\s*:`)
+
+// detectMakeDeployTargets 按 build-<环境>、run-<环境> 的命名读取 Makefile 中各环境的部署命令，只有 build 目标的环境忽略
+func detectMakeDeployTargets(makefilePath string) map[string]ManifestDeployTarget {
+	data, err := os.ReadFile(makefilePath)
+	if err != nil {
+		return nil
+	}
+	targets := make(map[string]ManifestDeployTarget)
+	for _, match := range makeDeployTarget.FindAllStringSubmatch(string(data), -1) {
+		stage, environment := match[1], match[2]
+		target := targets[environment]
+		command := "make " + stage + "-" + environment
+		if stage == "build" {
+			target.Build = []string{command}
+		} else {
+			target.Run = []string{command}
+		}
+		targets[environment] = target
+	}
+	for environment, target := range targets {
+		if len(target.Run) == 0 {
+			delete(targets, environment)
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+	return targets
+}
+
+// subfolder 组件相对工作空间的执行目录
+func (c *ManifestComponent) subfolder(projectGuid string) string {
+	if c.Path == "" {
+		return projectGuid
+	}
+	return path.Join(projectGuid, filepath.ToSlash(c.Path))
+}
+
+// outputPaths setup 生成的目录、文件，相对项目根目录
+func (c *ManifestComponent) outputPaths() []string {
+	paths := make([]string, 0, len(c.Outputs))
+	for _, output := range c.Outputs {
+		paths = append(paths, path.Join(filepath.ToSlash(c.Path), filepath.ToSlash(output)))
+	}
+	return paths
+}
+
+// outputsExist setup 生成的目录、文件是否都存在，没有声明 outputs 时返回 false
+func (c *ManifestComponent) outputsExist(projectPath string) bool {
+	if len(c.Outputs) == 0 {
+		return false
+	}
+	for _, output := range c.outputPaths() {
+		if _, err := os.Stat(filepath.Join(projectPath, filepath.FromSlash(output))); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// setupCommands 组件准备环境的命令
+func (c *ManifestComponent) setupCommands(projectGuid string) []projectCommand {
+	return c.commands(projectGuid, "setup", c.Setup)
+}
+
+// commands 把组件的一组命令转换为可执行的命令，名称为“组件 阶段: 命令”
+func (c *ManifestComponent) commands(projectGuid, stage string, lines []string) []projectCommand {
+	commands := make([]projectCommand, 0, len(lines))
+	for _, line := range lines {
+		name := fmt.Sprintf("%s %s: %s", c.Name, stage, line)
+		command := newProjectCommand(name, c.subfolder(projectGuid), line)
+		command.Component = c.Name
+		commands = append(commands, command)
+	}
+	return commands
+}
+
+// verifyCommands 合并前依次执行各组件的构建、代码检查和测试
+func (m *ProjectManifest) verifyCommands(projectGuid string) []projectCommand {
+	var commands []projectCommand
+	for i := range m.Components {
+		component := &m.Components[i]
+		commands = append(commands, component.commands(projectGuid, "build", component.Build)...)
+		commands = append(commands, component.commands(projectGuid, "lint", component.Lint)...)
+		commands = append(commands, component.commands(projectGuid, "test", component.Test)...)
+	}
+	return commands
+}
+
+// testCommands 自动测试阶段依次执行的命令：各组件的 test_report，没有时使用 lint 和 test
+func (m *ProjectManifest) testCommands(projectGuid string) []projectCommand {
+	var commands []projectCommand
+	for i := range m.Components {
+		component := &m.Components[i]
+		if len(component.TestReport) > 0 {
+			commands = append(commands, component.commands(projectGuid, "test_report", component.TestReport)...)
+			continue
+		}
+		commands = append(commands, component.commands(projectGuid, "lint", component.Lint)...)
+		commands = append(commands, component.commands(projectGuid, "test", component.Test)...)
+	}
+	return commands
+}
+
+// deployCommands 部署到指定环境的构建和启动命令，优先使用 deploy 中该环境的命令，其次 deploy 的默认命令，
+// 否则依次使用各组件的 build、run
+func (m *ProjectManifest) deployCommands(projectGuid, environment string) (build, run []projectCommand) {
+	target, ok := m.Deploy.Environments[environment]
+	if !ok {
+		target = m.Deploy.ManifestDeployTarget
+	}
+	if len(target.Build) > 0 || len(target.Run) > 0 {
+		for _, line := range target.Build {
+			build = append(build, newProjectCommand("deploy build: "+line, projectGuid, line))
+		}
+		for _, line := range target.Run {
+			run = append(run, newProjectCommand("deploy run: "+line, projectGuid, line))
+		}
+		return build, run
+	}
+	for i := range m.Components {
+		component := &m.Components[i]
+		build = append(build, component.commands(projectGuid, "build", component.Build)...)
+		run = append(run, component.commands(projectGuid, "run", component.Run)...)
+	}
+	return build, run
+}
+
+// accessURL 组件访问地址：健康检查地址的协议和主机部分，没有时使用第一个端口
+func (c *ManifestComponent) accessURL() string {
+	if c.HealthURL != "" {
+		if parsed, err := url.Parse(c.HealthURL); err == nil && parsed.Scheme != "" && parsed.Host != "" {
+			return parsed.Scheme + "://" + parsed.Host
+		}
+	}
+	if len(c.Ports) > 0 {
+		return fmt.Sprintf("http://localhost:%d", c.Ports[0])
+	}
+	return ""
+}
+
+// componentOfOutput 查找生成该路径（相对项目根目录）的组件
+func (m *ProjectManifest) componentOfOutput(outputPath string) *ManifestComponent {
+	for i := range m.Components {
+		for _, output := range m.Components[i].outputPaths() {
+			if output == outputPath {
+				return &m.Components[i]
+			}
+		}
+	}
+	return nil
+}
+
+// readPackageScripts 读取 package.json 中的 scripts，文件不存在时返回 nil
+func readPackageScripts(packagePath string) map[string]string {
+	data, err := os.ReadFile(packagePath)
+	if err != nil {
+		return nil
+	}
+	var pkg struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil || pkg.Scripts == nil {
+		return map[string]string{}
+	}
+	return pkg.Scripts
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadProjectManifest(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		wantErr  string
+	}{
+		{name: "valid", manifest: "version: 1\ncomponents:\n  - name: api\n    path: services/api\n    build: [\"cargo build\"]\n"},
+		{name: "invalid yaml", manifest: "version: [", wantErr: "解析 app-maker.yaml 失败"},
+		{name: "unsupported version", manifest: "version: 2\n", wantErr: "不支持的版本 2"},
+		{name: "missing name", manifest: "version: 1\ncomponents:\n  - path: api\n", wantErr: "组件缺少 name"},
+		{name: "duplicate name", manifest: "version: 1\ncomponents:\n  - name: api\n  - name: api\n", wantErr: "组件 api 重复"},
+		{name: "path outside project", manifest: "version: 1\ncomponents:\n  - name: api\n    path: ../other\n", wantErr: "path 必须是项目内的相对路径"},
+		{name: "output outside component", manifest: "version: 1\ncomponents:\n  - name: api\n    outputs: [\"/tmp\"]\n", wantErr: "outputs 必须是组件目录内的相对路径"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projectPath := t.TempDir()
+			writeTestFile(t, filepath.Join(projectPath, ProjectManifestFileName), tt.manifest)
+
+			manifest, err := loadProjectManifest(projectPath)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("loadProjectManifest() err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadProjectManifest() err = %v", err)
+			}
+			commands := manifest.verifyCommands("p1")
+			if len(commands) != 1 || commands[0].Subfolder != "p1/services/api" || commands[0].Process != "cargo" {
+				t.Errorf("verifyCommands() = %+v", commands)
+			}
+		})
+	}
+}
+
+func TestDetectProjectManifest(t *testing.T) {
+	projectPath := t.TempDir()
+	for _, dir := range []string{"backend", "frontend"} {
+		if err := os.MkdirAll(filepath.Join(projectPath, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeTestFile(t, filepath.Join(projectPath, "backend", "go.mod"), "module demo\n")
+	writeTestFile(t, filepath.Join(projectPath, "frontend", "package.json"), `{"scripts": {"build": "vite build"}}`)
+	writeTestFile(t, filepath.Join(projectPath, "Makefile"), "build-dev: network-create\n\tdocker-compose build\nrun-dev:\nbuild-prod:\nrun-prod:\nbuild-test:\n")
+
+	manifest, err := loadProjectManifest(projectPath)
+	if err != nil {
+		t.Fatalf("loadProjectManifest() err = %v", err)
+	}
+
+	var names []string
+	for _, command := range manifest.verifyCommands("p1") {
+		names = append(names, command.Name)
+	}
+	wantNames := []string{"backend build: go build ./...", "backend test: go test ./...", "frontend build: npm run build"}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Errorf("verify commands = %v, want %v", names, wantNames)
+	}
+
+	// 按 Makefile 目标命名选择环境的部署命令，没有 run 目标的环境不部署
+	for environment, want := range map[string][]string{"dev": {"make build-dev", "make run-dev"}, "prod": {"make build-prod", "make run-prod"}} {
+		build, run := manifest.deployCommands("p1", environment)
+		if len(build) != 1 || len(run) != 1 || build[0].commandLine("p1") != want[0] || run[0].commandLine("p1") != want[1] {
+			t.Errorf("%s deploy commands = %+v, %+v", environment, build, run)
+		}
+	}
+	for _, environment := range []string{"staging", "test"} {
+		if _, run := manifest.deployCommands("p1", environment); len(run) != 0 {
+			t.Errorf("%s deploy run = %+v, want none", environment, run)
+		}
+	}
+
+	// 产物都存在时跳过 setup
+	frontend := manifest.componentOfOutput("frontend/node_modules")
+	if frontend == nil || frontend.outputsExist(projectPath) {
+		t.Fatalf("frontend component = %+v", frontend)
+	}
+	if err := os.MkdirAll(filepath.Join(projectPath, "frontend", "node_modules"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if !frontend.outputsExist(projectPath) {
+		t.Error("outputsExist() = false after npm install")
+	}
+}
+
+func TestManifestDeployCommandsFallBackToComponents(t *testing.T) {
+	manifest := &ProjectManifest{Version: 1, Components: []ManifestComponent{
+		{Name: "api", Path: "api", Build: []string{"go build -o server ."}, Run: []string{"./server > server.log 2>&1 &"}},
+		{Name: "web", Path: "web", Run: []string{"npm run preview"}},
+	}}
+
+	build, run := manifest.deployCommands("p1", "dev")
+	if len(build) != 1 || build[0].Subfolder != "p1/api" || build[0].Process != "go" {
+		t.Errorf("build = %+v", build)
+	}
+	if len(run) != 2 {
+		t.Fatalf("run = %+v", run)
+	}
+	// 包含 shell 语法的命令交给 sh -c 执行
+	if run[0].Process != "sh" || run[0].commandLine("p1") != "cd api && ./server > server.log 2>&1 &" {
+		t.Errorf("run[0] = %+v, command line %q", run[0], run[0].commandLine("p1"))
+	}
+	if run[1].Process != "npm" || !reflect.DeepEqual(run[1].Args, []string{"run", "preview"}) {
+		t.Errorf("run[1] = %+v", run[1])
+	}
+}
+
+func TestManifestDeployEnvironments(t *testing.T) {
+	projectPath := t.TempDir()
+	writeTestFile(t, filepath.Join(projectPath, ProjectManifestFileName), `version: 1
+deploy:
+  build: ["make build-dev"]
+  run: ["make run-dev"]
+  environments:
+    prod:
+      build: ["make build-prod"]
+      run: ["make run-prod"]
+`)
+	manifest, err := loadProjectManifest(projectPath)
+	if err != nil {
+		t.Fatalf("loadProjectManifest() err = %v", err)
+	}
+
+	// 未声明的环境使用默认命令
+	for environment, want := range map[string]string{"dev": "make run-dev", "staging": "make run-dev", "prod": "make run-prod"} {
+		_, run := manifest.deployCommands("p1", environment)
+		if len(run) != 1 || run[0].commandLine("p1") != want {
+			t.Errorf("%s deploy run = %+v, want %q", environment, run, want)
+		}
+	}
+}
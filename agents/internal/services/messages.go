@@ -0,0 +1,113 @@
+package services
+
+import "github.com/lighthought/app-maker/shared-models/common"
+
+// Agent 服务发送给后端的消息 key
+const (
+	messageKeyMockDeploySkipped       = "mock_deploy_skipped"
+	messageKeyMockDependenciesSkipped = "mock_dependencies_skipped"
+	messageKeyTaskQueued              = "task_queued"
+	messageKeyTaskStarted             = "task_started"
+	messageKeyTaskDone                = "task_done"
+	messageKeyTaskCancelled           = "task_cancelled"
+	messageKeyCommitAndMergeFailed    = "commit_and_merge_failed"
+	messageKeyCheckGitFailed          = "check_git_failed"
+	messageKeyInstallBmadFailed       = "install_bmad_failed"
+	messageKeyInstallDepsFailed       = "install_dependencies_failed"
+	messageKeySetupDone               = "setup_done"
+	messageKeyBuildProject            = "build_project"
+	messageKeyStartProject            = "start_project"
+	messageKeyCommandFailed           = "command_failed"
+	messageKeyCommandSucceeded        = "command_succeeded"
+	messageKeyFixCommandPrompt        = "fix_command_prompt"
+	messageKeyNoDeployCommands        = "no_deploy_commands"
+	messageKeyFixAttemptsExhausted    = "fix_attempts_exhausted"
+	messageKeyDeployHealthy           = "deploy_healthy"
+	messageKeyDeployUnhealthy         = "deploy_unhealthy"
+	messageKeyDeployPreviewURL        = "deploy_preview_url"
+	messageKeyMockTestSkipped         = "mock_test_skipped"
+	messageKeyRunTests                = "run_tests"
+	messageKeyNoTestCommands          = "no_test_commands"
+	messageKeyTestReportSummary       = "test_report_summary"
+	messageKeyTestReportCoverage      = "test_report_coverage"
+	messageKeyQAGateMissing           = "qa_gate_missing"
+	messageKeyStoryFileMissing        = "story_file_missing"
+	messageKeyCodeReviewFixPrompt     = "code_review_fix_prompt"
+	messageKeyCodeReviewBlocked       = "code_review_blocked"
+	messageKeyCodeReviewNoFindings    = "code_review_no_findings"
+)
+
+// Agent 服务发送给后端的消息，按项目输出语言区分
+var agentMessages = map[string]map[string]string{
+	common.LanguageZhCN: {
+		messageKeyMockDeploySkipped:       "[mock] 已跳过构建和启动项目",
+		messageKeyMockDependenciesSkipped: "* [mock] 已跳过安装代码依赖\n",
+		messageKeyTaskQueued:              "正在执行代理任务...",
+		messageKeyTaskStarted:             "任务开始执行",
+		messageKeyTaskDone:                "任务执行完成",
+		messageKeyTaskCancelled:           "任务已取消",
+		messageKeyCommitAndMergeFailed:    "项目文档、代码提交并合并失败: %s",
+		messageKeyCheckGitFailed:          "检查 git 仓库失败: %s",
+		messageKeyInstallBmadFailed:       "安装 bmad-method 失败: %s",
+		messageKeyInstallDepsFailed:       "安装代码依赖失败: %s",
+		messageKeySetupDone:               "项目环境准备完成",
+		messageKeyBuildProject:            "构建项目",
+		messageKeyStartProject:            "启动项目",
+		messageKeyCommandFailed:           "%s失败: %s",
+		messageKeyCommandSucceeded:        "%s成功",
+		messageKeyFixCommandPrompt:        "%s失败了（第 %d/%d 次修复），请根据下面的错误修复问题，修复后执行 '%s' 确认通过：\n%s",
+		messageKeyNoDeployCommands:        "项目清单 " + ProjectManifestFileName + " 没有声明 %s 环境的部署命令",
+		messageKeyFixAttemptsExhausted:    "%s失败，Dev Agent 修复 %d 次后仍未通过:\n%s",
+		messageKeyDeployHealthy:           "项目已启动，所有组件通过健康检查",
+		messageKeyDeployUnhealthy:         "项目启动后，组件 %s 未通过健康检查",
+		messageKeyDeployPreviewURL:        "预览地址: %s",
+		messageKeyMockTestSkipped:         "[mock] 已跳过执行项目测试",
+		messageKeyRunTests:                "运行测试",
+		messageKeyNoTestCommands:          "项目清单 " + ProjectManifestFileName + " 没有声明测试命令",
+		messageKeyTestReportSummary:       "测试用例共 %d 个：通过 %d，失败 %d，跳过 %d",
+		messageKeyTestReportCoverage:      "，语句覆盖率 %.1f%%",
+		messageKeyQAGateMissing:           "QA 没有在 %s 中给出门禁结论",
+		messageKeyStoryFileMissing:        "SM 没有写出故事文件 %s",
+		messageKeyCodeReviewFixPrompt:     "代码评审发现了需要修改的问题（第 %d/%d 轮修复），请逐条修复下面的评审意见，修复后确保构建和测试通过：\n%s",
+		messageKeyCodeReviewBlocked:       "代码评审仍有 %d 条阻塞的意见，Dev Agent 修复 %d 轮后仍未解决:\n%s",
+		messageKeyCodeReviewNoFindings:    "评审 Agent 没有给出 json 格式的评审意见",
+	},
+	common.LanguageEnUS: {
+		messageKeyMockDeploySkipped:       "[mock] Skipped building and starting the project",
+		messageKeyMockDependenciesSkipped: "* [mock] Skipped installing code dependencies\n",
+		messageKeyTaskQueued:              "Running agent task...",
+		messageKeyTaskStarted:             "Task started",
+		messageKeyTaskDone:                "Task completed",
+		messageKeyTaskCancelled:           "Task cancelled",
+		messageKeyCommitAndMergeFailed:    "Failed to commit and merge project docs and code: %s",
+		messageKeyCheckGitFailed:          "Failed to check the git repository: %s",
+		messageKeyInstallBmadFailed:       "Failed to install bmad-method: %s",
+		messageKeyInstallDepsFailed:       "Failed to install code dependencies: %s",
+		messageKeySetupDone:               "Project environment is ready",
+		messageKeyBuildProject:            "Build project",
+		messageKeyStartProject:            "Start project",
+		messageKeyCommandFailed:           "%s failed: %s",
+		messageKeyCommandSucceeded:        "%s succeeded",
+		messageKeyFixCommandPrompt:        "%s failed (fix attempt %d/%d). Please fix the errors below, then run '%s' to confirm it passes:\n%s",
+		messageKeyNoDeployCommands:        "The project manifest " + ProjectManifestFileName + " declares no deploy commands for the %s environment",
+		messageKeyFixAttemptsExhausted:    "%s still failed after %d fix attempts by the Dev agent:\n%s",
+		messageKeyDeployHealthy:           "The project is running and all components passed the health check",
+		messageKeyDeployUnhealthy:         "After starting the project, components %s did not pass the health check",
+		messageKeyDeployPreviewURL:        "Preview URL: %s",
+		messageKeyMockTestSkipped:         "[mock] Skipped running the project tests",
+		messageKeyRunTests:                "Run tests",
+		messageKeyNoTestCommands:          "The project manifest " + ProjectManifestFileName + " declares no test commands",
+		messageKeyTestReportSummary:       "%d test cases: %d passed, %d failed, %d skipped",
+		messageKeyTestReportCoverage:      ", statement coverage %.1f%%",
+		messageKeyQAGateMissing:           "QA gave no gate decision in %s",
+		messageKeyStoryFileMissing:        "SM did not write the story file %s",
+		messageKeyCodeReviewFixPrompt:     "The code review found issues that must be changed (fix round %d/%d). Please fix the review comments below one by one, and make sure the build and tests pass afterwards:\n%s",
+		messageKeyCodeReviewBlocked:       "The code review still has %d blocking comments after %d fix rounds by the Dev agent:\n%s",
+		messageKeyCodeReviewNoFindings:    "The reviewer agent gave no review comments in json format",
+	},
+}
+
+// getAgentMessage 获取指定语言的消息，不支持的语言使用默认语言
+func getAgentMessage(language, key string) string {
+	return agentMessages[common.NormalizeLanguage(language)][key]
+}
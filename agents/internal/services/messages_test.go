@@ -0,0 +1,37 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/lighthought/app-maker/shared-models/common"
+)
+
+func TestLocalizedMessagesComplete(t *testing.T) {
+	tables := map[string]map[string]map[string]string{
+		"agentMessages":   agentMessages,
+		"commitSummaries": commitSummaries,
+	}
+	for tableName, table := range tables {
+		for _, language := range common.SupportedLanguages {
+			if len(table[language]) == 0 {
+				t.Errorf("%s has no messages for %s", tableName, language)
+			}
+		}
+		for key := range table[common.DefaultLanguage] {
+			for _, language := range common.SupportedLanguages {
+				if table[language][key] == "" {
+					t.Errorf("%s[%s] is missing key %q", tableName, language, key)
+				}
+			}
+		}
+	}
+}
+
+func TestGetAgentMessageFallsBackToDefaultLanguage(t *testing.T) {
+	if got, want := getAgentMessage("fr-FR", messageKeyTaskDone), agentMessages[common.DefaultLanguage][messageKeyTaskDone]; got != want {
+		t.Errorf("getAgentMessage(fr-FR) = %q, want %q", got, want)
+	}
+	if got := getAgentMessage(common.LanguageEnUS, messageKeyTaskDone); got != "Task completed" {
+		t.Errorf("getAgentMessage(en-US) = %q", got)
+	}
+}
@@ -0,0 +1,26 @@
+//go:build !windows
+
+package services
+
+import (
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// processWaitDelay 进程被杀掉后等待输出读取完成的时间
+const processWaitDelay = 5 * time.Second
+
+// setProcessGroup 让命令在独立的进程组中运行，
+// 超时或取消时杀掉整个进程组，避免 CLI 派生的 node、npm 等子进程残留
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		if cmd.Process == nil {
+			return nil
+		}
+		// 负数 pid 表示整个进程组
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = processWaitDelay
+}
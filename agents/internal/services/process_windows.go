@@ -0,0 +1,16 @@
+//go:build windows
+
+package services
+
+import (
+	"os/exec"
+	"time"
+)
+
+// processWaitDelay 进程被杀掉后等待输出读取完成的时间
+const processWaitDelay = 5 * time.Second
+
+// setProcessGroup Windows 下没有进程组，取消时只杀掉主进程
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.WaitDelay = processWaitDelay
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/cache"
+	"github.com/lighthought/app-maker/shared-models/common"
+	"github.com/lighthought/app-maker/shared-models/logger"
+	"github.com/lighthought/app-maker/shared-models/tasks"
+	"github.com/lighthought/app-maker/shared-models/utils"
+
+	"github.com/hibiken/asynq"
+)
+
+// ProjectLockService 项目工作区锁服务：同一项目同一时间只允许一个任务修改工作区（运行 CLI、提交代码）
+type ProjectLockService interface {
+	// 获取项目工作区锁，锁被其他任务持有时返回持有者和 false
+	Acquire(projectGuid, taskID, taskType string) (*agent.ProjectLockInfo, bool, error)
+
+	// 续期，锁已不属于该持有者时返回 false
+	Refresh(lock *agent.ProjectLockInfo) (bool, error)
+
+	// 释放锁，只释放自己持有的锁
+	Release(lock *agent.ProjectLockInfo) error
+
+	// 获取锁的当前持有者，无人持有时返回 nil
+	GetHolder(projectGuid string) *agent.ProjectLockInfo
+
+	// 校验 fencing token 是否仍是当前持有者，写工作区前调用
+	CheckFencingToken(projectGuid string, fencingToken int64) error
+}
+
+type projectLockService struct {
+	cacheInstance cache.Cache
+}
+
+// NewProjectLockService 创建项目工作区锁服务
+func NewProjectLockService(cacheInstance cache.Cache) ProjectLockService {
+	return &projectLockService{cacheInstance: cacheInstance}
+}
+
+// Acquire 获取项目工作区锁
+func (s *projectLockService) Acquire(projectGuid, taskID, taskType string) (*agent.ProjectLockInfo, bool, error) {
+	if s.cacheInstance == nil {
+		return nil, false, fmt.Errorf("cache instance is nil")
+	}
+
+	key := cache.GetProjectWorkspaceLockCacheKey(projectGuid)
+	// 同一任务重试时（如 worker 崩溃后恢复），接管自己遗留的锁
+	if holder := s.GetHolder(projectGuid); holder != nil && holder.TaskID == taskID {
+		s.cacheInstance.CompareAndDelete(key, holder)
+	}
+
+	fencingToken, err := s.cacheInstance.Incr(cache.GetProjectWorkspaceFenceCacheKey(projectGuid))
+	if err != nil {
+		return nil, false, fmt.Errorf("生成 fencing token 失败: %w", err)
+	}
+
+	lock := &agent.ProjectLockInfo{
+		ProjectGuid:  projectGuid,
+		TaskID:       taskID,
+		TaskType:     taskType,
+		FencingToken: fencingToken,
+		AcquiredAt:   utils.GetCurrentTime(),
+	}
+	ok, err := s.cacheInstance.SetNX(key, lock, common.ProjectLockTTL)
+	if err != nil {
+		return nil, false, fmt.Errorf("获取项目工作区锁失败: %w", err)
+	}
+	if !ok {
+		return s.GetHolder(projectGuid), false, nil
+	}
+	return lock, true, nil
+}
+
+// Refresh 续期
+func (s *projectLockService) Refresh(lock *agent.ProjectLockInfo) (bool, error) {
+	if s.cacheInstance == nil {
+		return false, fmt.Errorf("cache instance is nil")
+	}
+	return s.cacheInstance.CompareAndExpire(cache.GetProjectWorkspaceLockCacheKey(lock.ProjectGuid), lock, common.ProjectLockTTL)
+}
+
+// Release 释放锁
+func (s *projectLockService) Release(lock *agent.ProjectLockInfo) error {
+	if s.cacheInstance == nil {
+		return fmt.Errorf("cache instance is nil")
+	}
+	_, err := s.cacheInstance.CompareAndDelete(cache.GetProjectWorkspaceLockCacheKey(lock.ProjectGuid), lock)
+	return err
+}
+
+// GetHolder 获取锁的当前持有者
+func (s *projectLockService) GetHolder(projectGuid string) *agent.ProjectLockInfo {
+	if s.cacheInstance == nil {
+		return nil
+	}
+	var holder agent.ProjectLockInfo
+	if err := s.cacheInstance.Get(cache.GetProjectWorkspaceLockCacheKey(projectGuid), &holder); err != nil {
+		return nil
+	}
+	return &holder
+}
+
+// CheckFencingToken 校验 fencing token
+func (s *projectLockService) CheckFencingToken(projectGuid string, fencingToken int64) error {
+	holder := s.GetHolder(projectGuid)
+	if holder == nil || holder.FencingToken != fencingToken {
+		return fmt.Errorf("项目工作区锁已失效，fencing token %d 不是当前持有者", fencingToken)
+	}
+	return nil
+}
+
+type projectLockContextKey struct{}
+
+// withProjectLock 把持有的锁放入上下文，供写工作区的方法校验 fencing token
+func withProjectLock(ctx context.Context, lock *agent.ProjectLockInfo) context.Context {
+	return context.WithValue(ctx, projectLockContextKey{}, lock)
+}
+
+// projectLockFromContext 获取上下文中持有的锁，不在锁保护下执行时返回 nil
+func projectLockFromContext(ctx context.Context) *agent.ProjectLockInfo {
+	lock, _ := ctx.Value(projectLockContextKey{}).(*agent.ProjectLockInfo)
+	return lock
+}
+
+// runWithProjectLock 获取项目工作区锁后执行 fn，执行期间定时续期，锁丢失时取消 fn 的上下文；
+// 上下文已持有同一项目的锁时直接执行，锁被占用时调用 onLocked 并返回 ErrProjectLocked
+func runWithProjectLock(ctx context.Context, lockService ProjectLockService, projectGuid, taskID, taskType string,
+	onLocked func(message string), fn func(ctx context.Context) error) error {
+	if held := projectLockFromContext(ctx); held != nil && held.ProjectGuid == projectGuid {
+		return fn(ctx)
+	}
+
+	lock, ok, err := lockService.Acquire(projectGuid, taskID, taskType)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		message := "等待项目工作区锁"
+		if lock != nil {
+			message = fmt.Sprintf("等待项目工作区锁，当前由任务 %s(%s) 持有", lock.TaskID, lock.TaskType)
+		}
+		if onLocked != nil {
+			onLocked(message)
+		}
+		return fmt.Errorf("%s: %w", message, ErrProjectLocked)
+	}
+	defer func() {
+		if err := lockService.Release(lock); err != nil {
+			logger.Warn("释放项目工作区锁失败",
+				logger.String("projectGuid", lock.ProjectGuid),
+				logger.String("taskID", lock.TaskID),
+				logger.String("error", err.Error()))
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(withProjectLock(ctx, lock))
+	defer cancel()
+	go keepProjectLock(ctx, cancel, lockService, lock)
+
+	return fn(ctx)
+}
+
+// ErrProjectLocked 项目工作区被其他任务占用
+var ErrProjectLocked = errors.New("项目工作区被其他任务占用")
+
+// IsProjectLockedError 是否为未拿到项目工作区锁的错误，这类任务重新排队，不计入失败和重试次数
+func IsProjectLockedError(err error) bool {
+	return errors.Is(err, ErrProjectLocked)
+}
+
+// ProjectLockRetryDelay 未拿到锁的任务按固定延迟重新排队，其余错误使用默认的重试策略
+func ProjectLockRetryDelay(n int, err error, task *asynq.Task) time.Duration {
+	if IsProjectLockedError(err) {
+		return common.ProjectLockRetryDelay
+	}
+	return asynq.DefaultRetryDelayFunc(n, err, task)
+}
+
+// NewProjectLockMiddleware 创建项目工作区锁中间件：修改工作区的任务执行前获取项目锁，执行期间定时续期，
+// 锁被占用时返回 ErrProjectLocked 让任务重新排队，锁丢失时取消任务
+func NewProjectLockMiddleware(lockService ProjectLockService) asynq.MiddlewareFunc {
+	return func(next asynq.Handler) asynq.Handler {
+		return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+			var payload struct {
+				ProjectGuid string `json:"project_guid"`
+			}
+			if err := json.Unmarshal(task.Payload(), &payload); err != nil || payload.ProjectGuid == "" {
+				return next.ProcessTask(ctx, task)
+			}
+
+			taskID, _ := asynq.GetTaskID(ctx)
+			onLocked := func(message string) {
+				tasks.UpdateResult(task.ResultWriter(), common.CommonStatusPending, 0, message)
+			}
+			return runWithProjectLock(ctx, lockService, payload.ProjectGuid, taskID, task.Type(), onLocked,
+				func(ctx context.Context) error {
+					return next.ProcessTask(ctx, task)
+				})
+		})
+	}
+}
+
+// keepProjectLock 定时续期，锁丢失时取消任务，避免两个任务同时修改工作区
+func keepProjectLock(ctx context.Context, cancel context.CancelFunc, lockService ProjectLockService, lock *agent.ProjectLockInfo) {
+	ticker := time.NewTicker(common.ProjectLockRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ok, err := lockService.Refresh(lock)
+			if err != nil {
+				logger.Warn("项目工作区锁续期失败",
+					logger.String("projectGuid", lock.ProjectGuid),
+					logger.String("taskID", lock.TaskID),
+					logger.String("error", err.Error()))
+				continue
+			}
+			if !ok {
+				logger.Error("项目工作区锁已丢失，取消任务",
+					logger.String("projectGuid", lock.ProjectGuid),
+					logger.String("taskID", lock.TaskID))
+				cancel()
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestProjectLockAcquireAndRelease(t *testing.T) {
+	cacheInstance, _ := newTestCache(t)
+	lockService := NewProjectLockService(cacheInstance)
+
+	first, ok, err := lockService.Acquire("p1", "task-1", "agent:execute")
+	if err != nil || !ok {
+		t.Fatalf("Acquire() = %v, %v", ok, err)
+	}
+
+	holder, ok, err := lockService.Acquire("p1", "task-2", "agent:execute")
+	if err != nil || ok {
+		t.Fatalf("second Acquire() = %v, %v, want locked", ok, err)
+	}
+	if holder == nil || holder.TaskID != "task-1" {
+		t.Errorf("holder = %+v, want task-1", holder)
+	}
+
+	// 其他项目不受影响
+	if _, ok, err := lockService.Acquire("p2", "task-3", "agent:execute"); err != nil || !ok {
+		t.Errorf("Acquire(p2) = %v, %v", ok, err)
+	}
+
+	if ok, err := lockService.Refresh(first); err != nil || !ok {
+		t.Errorf("Refresh() = %v, %v", ok, err)
+	}
+	if err := lockService.Release(first); err != nil {
+		t.Fatalf("Release() err = %v", err)
+	}
+	if ok, _ := lockService.Refresh(first); ok {
+		t.Error("Refresh() after release should fail")
+	}
+
+	second, ok, err := lockService.Acquire("p1", "task-2", "agent:execute")
+	if err != nil || !ok {
+		t.Fatalf("Acquire() after release = %v, %v", ok, err)
+	}
+	if second.FencingToken <= first.FencingToken {
+		t.Errorf("fencing token = %d, want > %d", second.FencingToken, first.FencingToken)
+	}
+
+	// 旧持有者释放不会删掉新持有者的锁
+	if err := lockService.Release(first); err != nil {
+		t.Fatalf("stale Release() err = %v", err)
+	}
+	if holder := lockService.GetHolder("p1"); holder == nil || holder.TaskID != "task-2" {
+		t.Errorf("holder after stale release = %+v", holder)
+	}
+}
+
+func TestProjectLockTakeoverBySameTask(t *testing.T) {
+	cacheInstance, _ := newTestCache(t)
+	lockService := NewProjectLockService(cacheInstance)
+
+	first, _, _ := lockService.Acquire("p1", "task-1", "agent:execute")
+	retried, ok, err := lockService.Acquire("p1", "task-1", "agent:execute")
+	if err != nil || !ok {
+		t.Fatalf("retry Acquire() = %v, %v", ok, err)
+	}
+	if err := lockService.CheckFencingToken("p1", first.FencingToken); err == nil {
+		t.Error("old fencing token should be rejected after takeover")
+	}
+	if err := lockService.CheckFencingToken("p1", retried.FencingToken); err != nil {
+		t.Errorf("CheckFencingToken() err = %v", err)
+	}
+}
+
+func TestGitServiceCheckProjectLock(t *testing.T) {
+	cacheInstance, _ := newTestCache(t)
+	lockService := NewProjectLockService(cacheInstance)
+	git := &gitService{lockService: lockService}
+
+	current, _, _ := lockService.Acquire("p1", "task-1", "agent:execute")
+	stale := *current
+	stale.FencingToken--
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		project string
+		wantErr bool
+	}{
+		{name: "no lock in context", ctx: context.Background(), project: "p1", wantErr: true},
+		{name: "current holder", ctx: withProjectLock(context.Background(), current), project: "p1"},
+		{name: "stale fencing token", ctx: withProjectLock(context.Background(), &stale), project: "p1", wantErr: true},
+		{name: "lock of another project", ctx: withProjectLock(context.Background(), current), project: "p2", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := git.checkProjectLock(tt.ctx, tt.project)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkProjectLock() err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunWithProjectLock(t *testing.T) {
+	cacheInstance, _ := newTestCache(t)
+	lockService := NewProjectLockService(cacheInstance)
+
+	err := runWithProjectLock(context.Background(), lockService, "p1", "task-1", "agent:execute", nil,
+		func(ctx context.Context) error {
+			held := projectLockFromContext(ctx)
+			if held == nil || held.TaskID != "task-1" {
+				t.Errorf("lock in context = %+v", held)
+			}
+
+			// 已持有锁时直接复用，不会被自己阻塞
+			reentered := false
+			if err := runWithProjectLock(ctx, lockService, "p1", "chat-1", "agent:chat", nil, func(ctx context.Context) error {
+				reentered = true
+				return nil
+			}); err != nil || !reentered {
+				t.Errorf("nested run = %v, reentered %v", err, reentered)
+			}
+
+			// 其他调用方拿不到锁
+			var lockedMessage string
+			err := runWithProjectLock(context.Background(), lockService, "p1", "chat-2", "agent:chat",
+				func(message string) { lockedMessage = message },
+				func(ctx context.Context) error {
+					t.Error("fn should not run while locked")
+					return nil
+				})
+			if !IsProjectLockedError(err) || lockedMessage == "" {
+				t.Errorf("concurrent run err = %v, message %q", err, lockedMessage)
+			}
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("runWithProjectLock() err = %v", err)
+	}
+	if holder := lockService.GetHolder("p1"); holder != nil {
+		t.Errorf("lock should be released, holder = %+v", holder)
+	}
+
+	wantErr := errors.New("boom")
+	if err := runWithProjectLock(context.Background(), lockService, "p1", "task-2", "agent:execute", nil,
+		func(ctx context.Context) error { return wantErr }); !errors.Is(err, wantErr) {
+		t.Errorf("err = %v, want %v", err, wantErr)
+	}
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"path/filepath"
 	"strings"
@@ -13,12 +14,23 @@ import (
 	"github.com/lighthought/app-maker/shared-models/tasks"
 	"github.com/lighthought/app-maker/shared-models/utils"
 
+	"github.com/lighthought/app-maker/agents/internal/api/models"
+
 	"github.com/hibiken/asynq"
 )
 
+// Dev Agent 每次修复记录的代码修改上限
+const maxFixDiffBytes = 16 * 1024
+
 // ProjectService 项目服务
 type ProjectService interface {
 	ProcessTask(ctx context.Context, task *asynq.Task) error
+
+	// 获取项目主干分支的最新提交
+	GetGitHead(ctx context.Context, projectGuid string) (*agent.GitHeadInfo, error)
+
+	// 持有项目工作区锁，把主干分支回滚到指定提交并强制推送；工作区被其他任务占用时返回 ErrProjectLocked
+	RollbackGit(ctx context.Context, projectGuid, commitSha string) (*agent.GitHeadInfo, error)
 }
 
 // projectService 项目服务实现
@@ -27,18 +39,36 @@ type projectService struct {
 	agentTaskService AgentTaskService
 	fileService      FileService
 	redisService     RedisService
+	gitService       GitService
+	lockService      ProjectLockService
+	workspaceService WorkspaceService
+	cliAdapters      CliAdapterRegistry
+	healthChecker    DeployHealthChecker
+	fixMaxAttempts   int
 }
 
 // NewProjectService 创建项目服务
 func NewProjectService(commandService CommandService,
 	agentTaskService AgentTaskService,
 	redisService RedisService,
-	fileService FileService) ProjectService {
+	fileService FileService,
+	gitService GitService,
+	lockService ProjectLockService,
+	workspaceService WorkspaceService,
+	cliAdapters CliAdapterRegistry,
+	healthChecker DeployHealthChecker,
+	fixMaxAttempts int) ProjectService {
 	return &projectService{
 		commandService:   commandService,
 		agentTaskService: agentTaskService,
 		redisService:     redisService,
 		fileService:      fileService,
+		gitService:       gitService,
+		lockService:      lockService,
+		workspaceService: workspaceService,
+		cliAdapters:      cliAdapters,
+		healthChecker:    healthChecker,
+		fixMaxAttempts:   fixMaxAttempts,
 	}
 }
 
@@ -51,11 +81,35 @@ func (s *projectService) ProcessTask(ctx context.Context, task *asynq.Task) erro
 	// 部署项目
 	case common.TaskTypeProjectDeploy:
 		return s.projectDeploy(ctx, task)
+	// 运行项目测试
+	case common.TaskTypeProjectTest:
+		return s.projectTest(ctx, task)
 	default:
 		return fmt.Errorf("unexpected task type %s", task.Type())
 	}
 }
 
+// GetGitHead 获取项目主干分支的最新提交
+func (s *projectService) GetGitHead(ctx context.Context, projectGuid string) (*agent.GitHeadInfo, error) {
+	return s.gitService.GetBaseHead(ctx, projectGuid)
+}
+
+// RollbackGit 回滚项目主干分支
+func (s *projectService) RollbackGit(ctx context.Context, projectGuid, commitSha string) (*agent.GitHeadInfo, error) {
+	var head *agent.GitHeadInfo
+	err := runWithProjectLock(ctx, s.lockService, projectGuid, "rollback-"+utils.GenerateUUID(), common.TaskTypeProjectRollback, nil,
+		func(ctx context.Context) error {
+			// 回滚不经过任务中间件，工作区已被回收时先重新克隆
+			if err := s.workspaceService.EnsureWorkspace(ctx, projectGuid); err != nil {
+				return err
+			}
+			var err error
+			head, err = s.gitService.ResetBaseBranch(ctx, projectGuid, commitSha)
+			return err
+		})
+	return head, err
+}
+
 // checkGitRepository 检查项目的 gitlab 环境
 func (s *projectService) checkGitRepository(ctx context.Context, req agent.SetupProjEnvReq, projectPath string) (string, error) {
 	var markdownResult string = "项目开发环境初始化：\n"
@@ -90,20 +144,14 @@ func (s *projectService) checkGitRepository(ctx context.Context, req agent.Setup
 // 检查、安装 bmad-method
 func (s *projectService) installBmad(ctx context.Context, req agent.SetupProjEnvReq,
 	projectPath, markdownResult string) (string, error) {
-	// 优先使用请求参数
 	installBmad := req.SetupBmadMethod
 	bmadCliType := req.BmadCliType
-	// 如果请求参数为空，检测本地目录
-	if bmadCliType == "" {
-		bmadCliType = s.fileService.DetectCliTool(req.ProjectGuid)
-	}
-
-	cliDirMap := map[string]string{
-		common.CliToolClaudeCode: ".claude",
-		common.CliToolQwenCode:   ".qwen",
-		common.CliToolGemini:     ".gemini",
+	cliDir := s.cliAdapters.Get(bmadCliType).ConfigDir()
+	if cliDir == "" {
+		// mock 等进程内 CLI 不需要安装 bmad-method
+		markdownResult += fmt.Sprintf("* agent (%s) 无需安装\n", bmadCliType)
+		return markdownResult, nil
 	}
-	cliDir := cliDirMap[bmadCliType]
 	needInstall := installBmad || !utils.IsDirectoryExists(filepath.Join(projectPath, cliDir))
 
 	if needInstall {
@@ -125,41 +173,35 @@ func (s *projectService) installBmad(ctx context.Context, req agent.SetupProjEnv
 	return markdownResult, nil
 }
 
-// 安装代码依赖
+// 安装代码依赖：依次执行项目清单中各组件的 setup 命令，setup 的产物都已存在时跳过
 func (s *projectService) installCodeDependencies(ctx context.Context, req agent.SetupProjEnvReq,
 	projectPath, markdownResult string) (string, error) {
-	// 安装 frontend 代码依赖
-	var frontendModulePath = filepath.Join(projectPath, "frontend", "node_modules")
-	if !utils.IsDirectoryExists(frontendModulePath) {
-		subPath := req.ProjectGuid + "/frontend"
-		res := s.commandService.SimpleExecute(ctx, subPath, "npm", "install")
-		if !res.Success {
-			logger.Error("frontend 安装失败", logger.String("error", res.Error))
-			return "", fmt.Errorf("frontend 安装失败: %s", res.Error)
+	manifest, err := loadProjectManifest(projectPath)
+	if err != nil {
+		return "", err
+	}
+
+	for i := range manifest.Components {
+		component := &manifest.Components[i]
+		if len(component.Setup) == 0 {
+			continue
+		}
+		if component.outputsExist(projectPath) {
+			logger.Info(component.Name+" 已安装过", logger.String("projectPath", projectPath))
+			markdownResult += fmt.Sprintf("* %s 已安装过\n", component.Name)
+			continue
 		}
 
-		logger.Info("frontend 安装成功", logger.String("projectPath", projectPath))
-		markdownResult += "* frontend 安装成功\n"
-	} else {
-		logger.Info("frontend node_modules 已存在", logger.String("projectPath", projectPath))
-		markdownResult += "* frontend 已安装过\n"
-	}
-
-	// 安装 backend 代码依赖
-	if !utils.IsFileExists(filepath.Join(projectPath, "backend", "server")) {
-		subPath := req.ProjectGuid + "/backend"
-		goMod := s.commandService.SimpleExecute(ctx, subPath, "go", "mod", "download")
-		build := s.commandService.SimpleExecute(ctx, subPath, "go", "build", "-o", "server", "./cmd/server")
-		if !goMod.Success || !build.Success {
-			logger.Error("backend 安装失败", logger.String("error", goMod.Error+build.Error))
-			return "", fmt.Errorf("backend 安装失败: %s", goMod.Error+build.Error)
+		for _, command := range component.setupCommands(req.ProjectGuid) {
+			res := s.commandService.SimpleExecute(ctx, command.Subfolder, command.Process, command.Args...)
+			if !res.Success {
+				logger.Error(component.Name+" 安装失败", logger.String("command", command.Name), logger.String("error", res.Error))
+				return "", fmt.Errorf("%s 安装失败: %s", command.Name, res.Error)
+			}
 		}
 
-		logger.Info("backend 安装成功", logger.String("projectPath", projectPath))
-		markdownResult += "* backend 安装成功\n"
-	} else {
-		logger.Info("backend 已安装过", logger.String("projectPath", projectPath))
-		markdownResult += "* backend 已安装过\n"
+		logger.Info(component.Name+" 安装成功", logger.String("projectPath", projectPath))
+		markdownResult += fmt.Sprintf("* %s 安装成功\n", component.Name)
 	}
 	return markdownResult, nil
 }
@@ -176,68 +218,189 @@ func (s *projectService) agentSetupProject(ctx context.Context, task *asynq.Task
 		ProjectGUID: req.ProjectGuid,
 		AgentType:   common.AgentTypePM,
 		DevStage:    common.DevStatusSetupAgents,
+		Language:    req.Language,
+	}
+
+	// 保存项目的模型配置，后续执行 CLI 时注入到进程环境变量
+	if err := s.redisService.SaveModelConfig(req.ProjectGuid, req.GetModelConfig()); err != nil {
+		logger.Warn("保存项目模型配置失败", logger.String("projectGuid", req.ProjectGuid), logger.String("error", err.Error()))
 	}
 
 	var projectPath = s.fileService.GetProjectPath(req.ProjectGuid)
 	markdownResult, err := s.checkGitRepository(ctx, req, projectPath)
 	if err != nil {
 		logger.Error("检查 git 仓库失败", logger.String("error", err.Error()))
-		tasks.UpdateResult(task.ResultWriter(), common.CommonStatusFailed, 0, "检查 git 仓库失败: "+err.Error())
-		s.redisService.PublishTaskStatus(&payload, task.ResultWriter().TaskID(), common.CommonStatusFailed, "检查 git 仓库失败: "+err.Error())
+		message := fmt.Sprintf(getAgentMessage(req.Language, messageKeyCheckGitFailed), err.Error())
+		tasks.UpdateResult(task.ResultWriter(), common.CommonStatusFailed, 0, message)
+		s.redisService.PublishTaskStatus(&payload, task.ResultWriter().TaskID(), common.CommonStatusFailed, message)
 		return err
 	}
 
+	// 与部署、测试一致，依次使用请求参数、项目模型配置、项目目录检测的 CLI
+	req.BmadCliType = s.resolveCliTool(req.ProjectGuid, req.BmadCliType)
+
 	// 2.检查、安装 bmad-method
 	markdownResult, err = s.installBmad(ctx, req, projectPath, markdownResult)
 	if err != nil {
 		logger.Error("安装 bmad-method 失败", logger.String("error", err.Error()))
-		tasks.UpdateResult(task.ResultWriter(), common.CommonStatusFailed, 0, "安装 bmad-method 失败: "+err.Error())
-		s.redisService.PublishTaskStatus(&payload, task.ResultWriter().TaskID(), common.CommonStatusFailed, "安装 bmad-method 失败: "+err.Error())
+		message := fmt.Sprintf(getAgentMessage(req.Language, messageKeyInstallBmadFailed), err.Error())
+		tasks.UpdateResult(task.ResultWriter(), common.CommonStatusFailed, 0, message)
+		s.redisService.PublishTaskStatus(&payload, task.ResultWriter().TaskID(), common.CommonStatusFailed, message)
 		return err
 	}
 
-	// 3. 安装代码依赖
-	markdownResult, err = s.installCodeDependencies(ctx, req, projectPath, markdownResult)
+	// 3. 安装代码依赖，mock CLI 离线运行，不安装依赖
+	if req.BmadCliType == common.CliToolMock {
+		markdownResult += getAgentMessage(req.Language, messageKeyMockDependenciesSkipped)
+	} else {
+		markdownResult, err = s.installCodeDependencies(ctx, req, projectPath, markdownResult)
+	}
 	if err != nil {
 		logger.Error("安装代码依赖失败", logger.String("error", err.Error()))
-		tasks.UpdateResult(task.ResultWriter(), common.CommonStatusFailed, 0, "安装代码依赖失败: "+err.Error())
-		s.redisService.PublishTaskStatus(&payload, task.ResultWriter().TaskID(), common.CommonStatusFailed, "安装代码依赖失败: "+err.Error())
+		message := fmt.Sprintf(getAgentMessage(req.Language, messageKeyInstallDepsFailed), err.Error())
+		tasks.UpdateResult(task.ResultWriter(), common.CommonStatusFailed, 0, message)
+		s.redisService.PublishTaskStatus(&payload, task.ResultWriter().TaskID(), common.CommonStatusFailed, message)
 		return fmt.Errorf("安装代码依赖失败: %s", err.Error())
 	}
 
 	tasks.UpdateResult(task.ResultWriter(), common.CommonStatusDone, 100, markdownResult)
-	s.redisService.PublishTaskStatus(&payload, task.ResultWriter().TaskID(), common.CommonStatusDone, "项目环境准备完成")
+	s.redisService.PublishTaskStatus(&payload, task.ResultWriter().TaskID(), common.CommonStatusDone, getAgentMessage(req.Language, messageKeySetupDone))
 	logger.Info("项目环境准备完成", logger.String("projectGuid", req.ProjectGuid))
 	return nil
 }
 
-// chatAfterExecuteFailed 聊天后执行失败
-func (s *projectService) chatAfterExecuteFailed(ctx context.Context, projectGuid, cmdDesc, process string, cmd ...string) (string, error) {
-	logger.Info("执行命令",
-		logger.String("projectGuid", projectGuid),
-		logger.String("process", process),
-		logger.String("cmd", strings.Join(cmd, " ")))
+// commandCheck 检查命令的执行结果，返回需要 Dev Agent 修复的问题，为空表示通过
+type commandCheck func(result *models.CommandResult) []string
 
-	buildResult := s.commandService.SimpleExecute(ctx, projectGuid, process, cmd...)
-	if !buildResult.Success {
+// checkCommandSucceeded 命令执行成功即通过，失败时从输出中提取错误
+func checkCommandSucceeded(result *models.CommandResult) []string {
+	if result.Success {
+		return nil
+	}
+	if diagnostics := extractDiagnostics(result.Output + "\n" + result.Error); len(diagnostics) > 0 {
+		return diagnostics
+	}
+	return []string{result.Error}
+}
+
+// executeWithAgentFix 执行命令，失败时从输出中提取错误交给 Dev Agent 修复并重新执行，最多修复 maxAttempts 次；
+// 返回命令输出和每次修复的记录，cmdDesc 和返回的错误按项目输出语言生成
+func (s *projectService) executeWithAgentFix(ctx context.Context, projectGuid, language, cmdDesc string,
+	maxAttempts int, command projectCommand) (string, []*agent.FixAttempt, error) {
+	return s.executeUntilPassed(ctx, projectGuid, language, cmdDesc, "", maxAttempts, command, checkCommandSucceeded)
+}
+
+// executeUntilPassed 执行命令，check 未通过时把问题交给 Dev Agent 修复并重新执行，最多修复 maxAttempts 次；
+// fixGuide 不为空时放在修复提示词的开头
+func (s *projectService) executeUntilPassed(ctx context.Context, projectGuid, language, cmdDesc, fixGuide string,
+	maxAttempts int, command projectCommand, check commandCheck) (string, []*agent.FixAttempt, error) {
+	commandLine := command.commandLine(projectGuid)
+	logger.Info("执行命令", logger.String("projectGuid", projectGuid), logger.String("command", commandLine))
+
+	var attempts []*agent.FixAttempt
+	result := s.commandService.SimpleExecute(ctx, command.Subfolder, command.Process, command.Args...)
+	for diagnostics := check(&result); len(diagnostics) > 0; diagnostics = check(&result) {
 		logger.Error(cmdDesc+"失败",
 			logger.String("projectGuid", projectGuid),
-			logger.String("error", buildResult.Error),
-			logger.String("output", buildResult.Output),
-		)
-		prompt := cmdDesc + "失败了，帮我修复下，最后执行 '" + process + " " + strings.Join(cmd, " ") + "' 命令" + buildResult.Error
-		result, err := s.agentTaskService.ChatWithAgent(ctx, projectGuid, common.AgentTypeDev,
-			prompt)
+			logger.String("command", commandLine),
+			logger.String("error", result.Error),
+			logger.Int("attempts", len(attempts)))
+		if ctx.Err() != nil {
+			return "", attempts, fmt.Errorf(getAgentMessage(language, messageKeyCommandFailed), cmdDesc, result.Error)
+		}
+		if len(attempts) >= maxAttempts {
+			if len(attempts) == 0 {
+				return "", attempts, fmt.Errorf(getAgentMessage(language, messageKeyCommandFailed), cmdDesc, strings.Join(diagnostics, "\n"))
+			}
+			return "", attempts, fmt.Errorf(getAgentMessage(language, messageKeyFixAttemptsExhausted),
+				cmdDesc, len(attempts), strings.Join(diagnostics, "\n"))
+		}
+
+		attempt := &agent.FixAttempt{
+			Attempt:     len(attempts) + 1,
+			Command:     commandLine,
+			Error:       result.Error,
+			Diagnostics: diagnostics,
+			StartedAt:   utils.GetCurrentTime(),
+		}
+		attempts = append(attempts, attempt)
+
+		before, snapshotErr := s.gitService.SnapshotWorkTree(ctx, projectGuid)
+		prompt := fmt.Sprintf(getAgentMessage(language, messageKeyFixCommandPrompt),
+			cmdDesc, attempt.Attempt, maxAttempts, commandLine, strings.Join(diagnostics, "\n"))
+		if fixGuide != "" {
+			prompt = fixGuide + "\n\n" + prompt
+		}
+		chatResult, err := s.agentTaskService.ChatWithAgent(ctx, projectGuid, common.AgentTypeDev, prompt)
+		if err == nil && !chatResult.Success {
+			err = errors.New(chatResult.Error)
+		}
+		if snapshotErr == nil {
+			attempt.Diff = s.diffSince(ctx, projectGuid, before)
+		}
+		attempt.CompletedAt = utils.GetCurrentTime()
 		if err != nil {
-			return "", fmt.Errorf("%s失败: %s", cmdDesc, err.Error())
+			// Dev Agent 不可用时继续重试没有意义
+			attempt.AgentError = err.Error()
+			return "", attempts, fmt.Errorf(getAgentMessage(language, messageKeyCommandFailed), cmdDesc, err.Error())
 		}
-		if !result.Success {
 
-			return "", fmt.Errorf("%s失败: %s", cmdDesc, result.Error)
+		// 不信任 Dev Agent 的结论，重新执行命令确认是否修复
+		result = s.commandService.SimpleExecute(ctx, command.Subfolder, command.Process, command.Args...)
+		attempt.Fixed = len(check(&result)) == 0
+	}
+	return result.Output, attempts, nil
+}
+
+// diffSince 获取从快照 before 到当前工作区的修改，过长时截断
+func (s *projectService) diffSince(ctx context.Context, projectGuid, before string) string {
+	after, err := s.gitService.SnapshotWorkTree(ctx, projectGuid)
+	if err != nil {
+		logger.Warn("获取工作区快照失败", logger.String("projectGuid", projectGuid), logger.String("error", err.Error()))
+		return ""
+	}
+	diff, err := s.gitService.DiffSnapshots(ctx, projectGuid, before, after)
+	if err != nil {
+		logger.Warn("比较工作区快照失败", logger.String("projectGuid", projectGuid), logger.String("error", err.Error()))
+		return ""
+	}
+	return truncateDiff(diff, maxFixDiffBytes)
+}
+
+// truncateDiff diff 超过 limit 字节时截断，保证不截断在 UTF-8 字符中间
+func truncateDiff(diff string, limit int) string {
+	if len(diff) <= limit {
+		return diff
+	}
+	return strings.ToValidUTF8(diff[:limit], "") + "\n... (truncated)"
+}
+
+// runDeployCommands 依次执行部署命令，失败时让 Dev Agent 修复，最多修复 fixMaxAttempts 次，返回各命令的输出和修复记录
+func (s *projectService) runDeployCommands(ctx context.Context, req agent.DeployReq, cmdDesc string,
+	fixMaxAttempts int, commands []projectCommand) (string, []*agent.FixAttempt, error) {
+	outputs := make([]string, 0, len(commands))
+	var attempts []*agent.FixAttempt
+	for _, command := range commands {
+		output, commandAttempts, err := s.executeWithAgentFix(ctx, req.ProjectGuid, req.Language, cmdDesc, fixMaxAttempts, command)
+		attempts = append(attempts, commandAttempts...)
+		if err != nil {
+			return "", attempts, err
 		}
-		buildResult = *result
+		outputs = append(outputs, output)
 	}
-	return buildResult.Output, nil
+	return strings.Join(outputs, "\n"), attempts, nil
+}
+
+// resolveCliTool 获取项目使用的 CLI：请求参数、项目模型配置、项目目录检测
+func (s *projectService) resolveCliTool(projectGuid, cliTool string) string {
+	if cliTool != "" {
+		return cliTool
+	}
+	// 这里只取 CLI 类型，API Token 无法解密时由执行 CLI 的任务报错
+	if modelConfig, _ := s.redisService.GetModelConfig(projectGuid); modelConfig != nil && modelConfig.CliTool != "" {
+		return modelConfig.CliTool
+	}
+	return s.fileService.DetectCliTool(projectGuid)
 }
 
 // 部署项目
@@ -246,32 +409,179 @@ func (s *projectService) projectDeploy(ctx context.Context, task *asynq.Task) er
 	if err := json.Unmarshal(task.Payload(), &req); err != nil {
 		return fmt.Errorf("json.Unmarshal failed: %v: %w", err, asynq.SkipRetry)
 	}
-	logger.Info("开始执行项目部署", logger.String("projectGuid", req.ProjectGuid))
+	if req.Environment == "" {
+		req.Environment = common.DeployEnvironmentDev
+	}
+	logger.Info("开始执行项目部署", logger.String("projectGuid", req.ProjectGuid),
+		logger.String("environment", req.Environment), logger.String("commitSha", req.CommitSha))
 
 	payload := tasks.AgentExecuteTaskPayload{
 		ProjectGUID: req.ProjectGuid,
 		AgentType:   common.AgentTypeDev,
 		DevStage:    common.DevStatusDeploy,
+		Language:    req.Language,
+	}
+
+	// mock CLI 离线运行，不执行真实的构建和启动
+	if s.resolveCliTool(req.ProjectGuid, req.CliTool) == common.CliToolMock {
+		message := getAgentMessage(req.Language, messageKeyMockDeploySkipped)
+		tasks.UpdateResult(task.ResultWriter(), common.CommonStatusDone, 100, message)
+		s.redisService.PublishTaskStatus(&payload, task.ResultWriter().TaskID(), common.CommonStatusDone, message)
+		logger.Info("mock 项目跳过部署", logger.String("projectGuid", req.ProjectGuid))
+		return nil
 	}
-	// 1. 执行 make build-dev 构建项目
-	buildResult, err2 := s.chatAfterExecuteFailed(ctx, req.ProjectGuid, "构建项目", "make", "build-dev")
+
+	// 重新部署历史提交：工作区切换到该提交，部署结束后切回主干；历史提交的构建失败不交给 Dev Agent 修复
+	commitSha, fixMaxAttempts := req.CommitSha, s.fixMaxAttempts
+	if req.CommitSha != "" {
+		sha, err := s.gitService.CheckoutCommit(ctx, req.ProjectGuid, req.CommitSha)
+		if err != nil {
+			tasks.UpdateResult(task.ResultWriter(), common.CommonStatusFailed, 0, err.Error())
+			s.redisService.PublishTaskStatus(&payload, task.ResultWriter().TaskID(), common.CommonStatusFailed, err.Error())
+			return fmt.Errorf("%w: %w", err, asynq.SkipRetry)
+		}
+		defer func() {
+			if err := s.gitService.CheckoutBaseBranch(ctx, req.ProjectGuid); err != nil {
+				logger.Warn("部署后切回主干分支失败", logger.String("projectGuid", req.ProjectGuid), logger.String("error", err.Error()))
+			}
+		}()
+		commitSha, fixMaxAttempts = sha, 0
+	}
+
+	manifest, err := loadProjectManifest(s.fileService.GetProjectPath(req.ProjectGuid))
+	if err != nil {
+		tasks.UpdateResult(task.ResultWriter(), common.CommonStatusFailed, 0, err.Error())
+		s.redisService.PublishTaskStatus(&payload, task.ResultWriter().TaskID(), common.CommonStatusFailed, err.Error())
+		return fmt.Errorf("%w: %w", err, asynq.SkipRetry)
+	}
+	buildCommands, runCommands := manifest.deployCommands(req.ProjectGuid, req.Environment)
+	if len(runCommands) == 0 {
+		message := fmt.Sprintf(getAgentMessage(req.Language, messageKeyNoDeployCommands), req.Environment)
+		tasks.UpdateResult(task.ResultWriter(), common.CommonStatusFailed, 0, message)
+		s.redisService.PublishTaskStatus(&payload, task.ResultWriter().TaskID(), common.CommonStatusFailed, message)
+		return fmt.Errorf("%s: %w", message, asynq.SkipRetry)
+	}
+
+	// 构建、启动和健康检查失败时 Dev Agent 已经尝试修复，重试只会重复执行，直接失败
+	// 1. 执行项目清单中的构建命令
+	buildDesc := getAgentMessage(req.Language, messageKeyBuildProject)
+	buildResult, attempts, err2 := s.runDeployCommands(ctx, req, buildDesc, fixMaxAttempts, buildCommands)
 	if err2 != nil {
-		tasks.UpdateResult(task.ResultWriter(), common.CommonStatusFailed, 0, "构建项目失败: "+err2.Error())
-		s.redisService.PublishTaskStatus(&payload, task.ResultWriter().TaskID(), common.CommonStatusFailed, "构建项目失败: "+err2.Error())
-		return err2
+		tasks.UpdateResultWithFixAttempts(task.ResultWriter(), common.CommonStatusFailed, 0, err2.Error(), attempts)
+		s.redisService.PublishTaskStatus(&payload, task.ResultWriter().TaskID(), common.CommonStatusFailed, err2.Error())
+		return fmt.Errorf("%w: %w", err2, asynq.SkipRetry)
 	}
-	tasks.UpdateResult(task.ResultWriter(), common.CommonStatusInProgress, 50, buildResult)
-	s.redisService.PublishTaskStatus(&payload, task.ResultWriter().TaskID(), common.CommonStatusInProgress, "构建项目成功")
+	tasks.UpdateResultWithFixAttempts(task.ResultWriter(), common.CommonStatusInProgress, 50, buildResult, attempts)
+	s.redisService.PublishTaskStatus(&payload, task.ResultWriter().TaskID(), common.CommonStatusInProgress,
+		fmt.Sprintf(getAgentMessage(req.Language, messageKeyCommandSucceeded), buildDesc))
 
-	// 2. 执行 make run-dev 启动项目
-	buildResult, err3 := s.chatAfterExecuteFailed(ctx, req.ProjectGuid, "启动项目", "make", "run-dev")
+	// 2. 执行项目清单中的启动命令
+	startDesc := getAgentMessage(req.Language, messageKeyStartProject)
+	runResult, runAttempts, err3 := s.runDeployCommands(ctx, req, startDesc, fixMaxAttempts, runCommands)
+	attempts = append(attempts, runAttempts...)
 	if err3 != nil {
-		tasks.UpdateResult(task.ResultWriter(), common.CommonStatusFailed, 0, "启动项目失败: "+err3.Error())
-		s.redisService.PublishTaskStatus(&payload, task.ResultWriter().TaskID(), common.CommonStatusFailed, "启动项目失败: "+err3.Error())
-		return err3
+		tasks.UpdateResultWithFixAttempts(task.ResultWriter(), common.CommonStatusFailed, 0, err3.Error(), attempts)
+		s.redisService.PublishTaskStatus(&payload, task.ResultWriter().TaskID(), common.CommonStatusFailed, err3.Error())
+		return fmt.Errorf("%w: %w", err3, asynq.SkipRetry)
+	}
+	tasks.UpdateResultWithFixAttempts(task.ResultWriter(), common.CommonStatusInProgress, 80, runResult, attempts)
+
+	// 3. 启动命令返回后组件可能仍在启动或已经退出，轮询健康检查确认
+	deployResp := s.healthChecker.Check(ctx, req.ProjectGuid, manifest, runResult)
+	// 部署主干时 Dev Agent 的修复可能产生新提交，在命令执行完成后读取实际部署的提交
+	if req.CommitSha == "" {
+		if head, err := s.gitService.GetBaseHead(ctx, req.ProjectGuid); err == nil {
+			commitSha = head.CommitSha
+		}
+	}
+	deployResp.Environment, deployResp.CommitSha = req.Environment, commitSha
+	summary := deploySummary(req.Language, deployResp)
+	if deployResp.Status != common.DeployHealthHealthy {
+		tasks.UpdateResultWithDeploy(task.ResultWriter(), common.CommonStatusFailed, 0, summary, attempts, deployResp)
+		s.redisService.PublishTaskStatus(&payload, task.ResultWriter().TaskID(), common.CommonStatusFailed, summary)
+		return fmt.Errorf("%s: %w", summary, asynq.SkipRetry)
+	}
+	tasks.UpdateResultWithDeploy(task.ResultWriter(), common.CommonStatusDone, 100, summary, attempts, deployResp)
+	s.redisService.PublishTaskStatus(&payload, task.ResultWriter().TaskID(), common.CommonStatusDone,
+		fmt.Sprintf(getAgentMessage(req.Language, messageKeyCommandSucceeded), startDesc))
+	logger.Info("项目部署完成", logger.String("projectGuid", req.ProjectGuid), logger.String("previewUrl", deployResp.PreviewURL))
+	return nil
+}
+
+// 运行项目测试：执行项目清单中的测试命令并解析为结构化的测试报告，有失败用例时交给 Dev Agent 修复后重新执行
+func (s *projectService) projectTest(ctx context.Context, task *asynq.Task) error {
+	var req tasks.ProjectTestTaskPayload
+	if err := json.Unmarshal(task.Payload(), &req); err != nil {
+		return fmt.Errorf("json.Unmarshal failed: %v: %w", err, asynq.SkipRetry)
+	}
+	logger.Info("开始执行项目测试", logger.String("projectGuid", req.ProjectGuid))
+
+	payload := tasks.AgentExecuteTaskPayload{
+		ProjectGUID: req.ProjectGuid,
+		AgentType:   common.AgentTypeDev,
+		DevStage:    common.DevStatusRunTest,
+		Language:    req.Language,
+	}
+
+	// mock CLI 离线运行，不执行真实的测试命令
+	if s.resolveCliTool(req.ProjectGuid, req.CliTool) == common.CliToolMock {
+		message := getAgentMessage(req.Language, messageKeyMockTestSkipped)
+		tasks.UpdateResult(task.ResultWriter(), common.CommonStatusDone, 100, message)
+		s.redisService.PublishTaskStatus(&payload, task.ResultWriter().TaskID(), common.CommonStatusDone, message)
+		logger.Info("mock 项目跳过测试", logger.String("projectGuid", req.ProjectGuid))
+		return nil
+	}
+
+	manifest, err := loadProjectManifest(s.fileService.GetProjectPath(req.ProjectGuid))
+	if err != nil {
+		tasks.UpdateResult(task.ResultWriter(), common.CommonStatusFailed, 0, err.Error())
+		s.redisService.PublishTaskStatus(&payload, task.ResultWriter().TaskID(), common.CommonStatusFailed, err.Error())
+		return fmt.Errorf("%w: %w", err, asynq.SkipRetry)
+	}
+	commands := manifest.testCommands(req.ProjectGuid)
+	if len(commands) == 0 {
+		message := getAgentMessage(req.Language, messageKeyNoTestCommands)
+		tasks.UpdateResult(task.ResultWriter(), common.CommonStatusFailed, 0, message)
+		s.redisService.PublishTaskStatus(&payload, task.ResultWriter().TaskID(), common.CommonStatusFailed, message)
+		return fmt.Errorf("%s: %w", message, asynq.SkipRetry)
+	}
+
+	// 依次执行各组件的测试命令，一条命令修复失败不影响其他命令，最终报告包含所有命令的结果
+	cmdDesc := getAgentMessage(req.Language, messageKeyRunTests)
+	var suites []*agent.TestSuite
+	var attempts []*agent.FixAttempt
+	for i, command := range commands {
+		var commandSuites []*agent.TestSuite
+		commandLine := command.commandLine(req.ProjectGuid)
+		check := func(result *models.CommandResult) []string {
+			commandSuites = parseTestSuites(command.Component, commandLine, result.Output, result.Success, checkCommandSucceeded(result))
+			return testFailures(commandSuites)
+		}
+		_, commandAttempts, err := s.executeUntilPassed(ctx, req.ProjectGuid, req.Language, cmdDesc, req.FixPrompt,
+			s.fixMaxAttempts, command, check)
+		suites = append(suites, commandSuites...)
+		attempts = append(attempts, commandAttempts...)
+		if err != nil {
+			logger.Warn("测试命令未通过", logger.String("projectGuid", req.ProjectGuid),
+				logger.String("command", commandLine), logger.String("error", err.Error()))
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		progress := (i + 1) * 100 / (len(commands) + 1)
+		tasks.UpdateResultWithFixAttempts(task.ResultWriter(), common.CommonStatusInProgress, progress, commandLine, attempts)
+	}
+
+	report := newTestReport(suites)
+	summary := testReportSummary(req.Language, report)
+	if report.Status != common.TestStatusPassed || ctx.Err() != nil {
+		tasks.UpdateResultWithTestReport(task.ResultWriter(), common.CommonStatusFailed, 0, summary, attempts, report)
+		s.redisService.PublishTaskStatus(&payload, task.ResultWriter().TaskID(), common.CommonStatusFailed, summary)
+		return fmt.Errorf("%s: %w", summary, asynq.SkipRetry)
 	}
-	tasks.UpdateResult(task.ResultWriter(), common.CommonStatusDone, 100, buildResult)
-	s.redisService.PublishTaskStatus(&payload, task.ResultWriter().TaskID(), common.CommonStatusDone, "启动项目成功")
-	logger.Info("项目部署完成", logger.String("projectGuid", req.ProjectGuid))
+	tasks.UpdateResultWithTestReport(task.ResultWriter(), common.CommonStatusDone, 100, summary, attempts, report)
+	s.redisService.PublishTaskStatus(&payload, task.ResultWriter().TaskID(), common.CommonStatusDone, summary)
+	logger.Info("项目测试完成", logger.String("projectGuid", req.ProjectGuid),
+		logger.Int("total", report.Total), logger.Int("failed", report.Failed))
 	return nil
 }
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/common"
+
+	"github.com/lighthought/app-maker/agents/internal/api/models"
+)
+
+// fakeFixAgent 模拟 Dev Agent：第 fixOnCall 次对话时写入 fixed.txt
+type fakeFixAgent struct {
+	AgentTaskService
+	t           *testing.T
+	projectPath string
+	fixOnCall   int
+	prompts     []string
+}
+
+func (a *fakeFixAgent) ChatWithAgent(ctx context.Context, projectGuid, agentType, message string) (*models.CommandResult, error) {
+	a.prompts = append(a.prompts, message)
+	if len(a.prompts) == a.fixOnCall {
+		writeTestFile(a.t, filepath.Join(a.projectPath, "fixed.txt"), "ok\n")
+	}
+	return &models.CommandResult{Success: true, Output: "done"}, nil
+}
+
+func TestExecuteWithAgentFix(t *testing.T) {
+	tests := []struct {
+		name         string
+		maxAttempts  int
+		fixOnCall    int
+		wantErr      string
+		wantAttempts int
+	}{
+		{name: "fixed on second attempt", maxAttempts: 3, fixOnCall: 2, wantAttempts: 2},
+		{name: "attempts exhausted", maxAttempts: 2, fixOnCall: 3, wantErr: "修复 2 次后仍未通过", wantAttempts: 2},
+		{name: "fix disabled", maxAttempts: 0, fixOnCall: 1, wantErr: "构建项目失败", wantAttempts: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			git, projectPath := newTestGitProject(t)
+			fakeAgent := &fakeFixAgent{t: t, projectPath: projectPath, fixOnCall: tt.fixOnCall}
+			service := &projectService{
+				commandService:   git.commandService,
+				agentTaskService: fakeAgent,
+				gitService:       git,
+			}
+			command := newProjectCommand("check", "p1", "cat fixed.txt")
+
+			output, attempts, err := service.executeWithAgentFix(context.Background(), "p1", "zh-CN", "构建项目", tt.maxAttempts, command)
+			if len(attempts) != tt.wantAttempts {
+				t.Fatalf("attempts = %d, want %d", len(attempts), tt.wantAttempts)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || output != "ok" {
+				t.Fatalf("executeWithAgentFix() = %q, %v", output, err)
+			}
+
+			// 每次修复都把提取的错误发给 Dev Agent，并记录本次的代码修改
+			first, second := attempts[0], attempts[1]
+			if first.Fixed || first.Diff != "" || len(first.Diagnostics) == 0 || !strings.Contains(fakeAgent.prompts[0], "第 1/3 次修复") {
+				t.Errorf("first attempt = %+v, prompt %q", first, fakeAgent.prompts[0])
+			}
+			if !strings.Contains(fakeAgent.prompts[0], first.Diagnostics[0]) {
+				t.Errorf("prompt %q does not contain diagnostics %q", fakeAgent.prompts[0], first.Diagnostics)
+			}
+			if !second.Fixed || !strings.Contains(second.Diff, "+++ b/fixed.txt") || second.Command != "cat fixed.txt" {
+				t.Errorf("second attempt = %+v", second)
+			}
+			// 快照不修改项目的暂存区
+			if status := git.commandService.SimpleExecute(context.Background(), "p1", "git", "status", "--porcelain"); status.Output != "?? fixed.txt" {
+				t.Errorf("git status = %q", status.Output)
+			}
+		})
+	}
+}
+
+func TestExecuteUntilPassedWithTestReport(t *testing.T) {
+	git, projectPath := newTestGitProject(t)
+	fakeAgent := &fakeFixAgent{t: t, projectPath: projectPath, fixOnCall: 1}
+	service := &projectService{
+		commandService:   git.commandService,
+		agentTaskService: fakeAgent,
+		gitService:       git,
+	}
+	command := newProjectCommand("test", "p1", "cat fixed.txt")
+	command.Component = "backend"
+
+	// 每次执行都重新解析测试报告，失败用例作为修复的问题
+	var suites []*agent.TestSuite
+	check := func(result *models.CommandResult) []string {
+		suites = parseTestSuites(command.Component, "cat fixed.txt", result.Output, result.Success, checkCommandSucceeded(result))
+		return testFailures(suites)
+	}
+	_, attempts, err := service.executeUntilPassed(context.Background(), "p1", "zh-CN", "运行测试", "不要删除测试", 2, command, check)
+	if err != nil || len(attempts) != 1 || !attempts[0].Fixed {
+		t.Fatalf("executeUntilPassed() attempts = %+v, err = %v", attempts, err)
+	}
+	if !strings.HasPrefix(fakeAgent.prompts[0], "不要删除测试\n\n") || !strings.Contains(fakeAgent.prompts[0], "FAIL cat fixed.txt: cat fixed.txt") {
+		t.Errorf("prompt = %q", fakeAgent.prompts[0])
+	}
+	if report := newTestReport(suites); report.Status != common.TestStatusPassed || report.Total != 1 {
+		t.Errorf("report = %+v", report)
+	}
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/common"
+	"github.com/lighthought/app-maker/shared-models/logger"
+
+	"gopkg.in/yaml.v3"
+)
+
+// QA Agent 回答中的门禁结论行，如 gate: PASS、**Gate**: FAIL
+var qaGateLinePattern = regexp.MustCompile(`(?im)^[\s*>#-]*gate[\s*]*[:：]\s*\**\s*(pass|concerns|fail)\b`)
+
+// qaGateFile 门禁文件的内容，top_issues 可以是字符串，也可以是带 finding、description 的对象
+type qaGateFile struct {
+	Story        string        `yaml:"story"`
+	Gate         string        `yaml:"gate"`
+	StatusReason string        `yaml:"status_reason"`
+	TopIssues    []interface{} `yaml:"top_issues"`
+}
+
+// readQAGate 读取并解析故事的门禁文件，只接受评审开始后修改的文件，之前评审留下的文件不能决定本次结论；
+// 文件不存在、不是本次写入或没有有效结论时，从 Agent 的回答中查找 gate: 行，仍然没有时按 concerns 处理，不阻止部署但需要关注
+func readQAGate(projectPath, storyNumber, language, answer string, startedAt time.Time) *agent.QAGate {
+	gateFile := agent.QAGateFilePath(storyNumber)
+	gate := &agent.QAGate{StoryNumber: storyNumber, GateFile: gateFile}
+
+	gatePath := filepath.Join(projectPath, filepath.FromSlash(gateFile))
+	info, err := os.Stat(gatePath)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		logger.Warn("读取 QA 门禁文件失败", logger.String("file", gateFile), logger.String("error", err.Error()))
+	case info.ModTime().Before(startedAt.Truncate(time.Second)):
+		// 文件系统的修改时间可能只精确到秒，按秒比较
+		logger.Warn("QA 门禁文件在本次评审开始前写入，忽略", logger.String("file", gateFile),
+			logger.String("modTime", info.ModTime().Format(time.RFC3339)))
+	default:
+		readQAGateFile(gatePath, gateFile, gate)
+	}
+
+	if gate.Gate == "" {
+		// 以回答中最后一个结论为准
+		if matches := qaGateLinePattern.FindAllStringSubmatch(answer, -1); len(matches) > 0 {
+			gate.Gate = normalizeQAGate(matches[len(matches)-1][1])
+		}
+	}
+	if gate.Gate == "" {
+		gate.Gate = common.QAGateConcerns
+		if gate.StatusReason == "" {
+			gate.StatusReason = fmt.Sprintf(getAgentMessage(language, messageKeyQAGateMissing), gateFile)
+		}
+	}
+	return gate
+}
+
+// readQAGateFile 解析门禁文件中的结论、原因和问题列表
+func readQAGateFile(gatePath, gateFile string, gate *agent.QAGate) {
+	data, err := os.ReadFile(gatePath)
+	if err != nil {
+		logger.Warn("读取 QA 门禁文件失败", logger.String("file", gateFile), logger.String("error", err.Error()))
+		return
+	}
+	var content qaGateFile
+	if err := yaml.Unmarshal(data, &content); err != nil {
+		logger.Warn("解析 QA 门禁文件失败", logger.String("file", gateFile), logger.String("error", err.Error()))
+		return
+	}
+	gate.Gate = normalizeQAGate(content.Gate)
+	gate.StatusReason = strings.TrimSpace(content.StatusReason)
+	gate.TopIssues = qaTopIssues(content.TopIssues)
+}
+
+// normalizeQAGate 把 PASS、CONCERNS、FAIL 转换为小写的结论，WAIVED 视为 concerns，无法识别时返回空字符串
+func normalizeQAGate(value string) string {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case common.QAGatePass:
+		return common.QAGatePass
+	case common.QAGateConcerns, "waived":
+		return common.QAGateConcerns
+	case common.QAGateFail:
+		return common.QAGateFail
+	default:
+		return ""
+	}
+}
+
+// qaTopIssues 把门禁文件中的问题列表转换为字符串，对象优先使用 finding、description 字段
+func qaTopIssues(items []interface{}) []string {
+	var issues []string
+	for _, item := range items {
+		var issue string
+		switch value := item.(type) {
+		case string:
+			issue = value
+		case map[string]interface{}:
+			for _, key := range []string{"finding", "description", "issue", "title"} {
+				if text, ok := value[key].(string); ok && text != "" {
+					issue = text
+					break
+				}
+			}
+			if issue == "" {
+				issue = fmt.Sprint(value)
+			}
+		case nil:
+		default:
+			issue = fmt.Sprint(value)
+		}
+		if issue = strings.TrimSpace(issue); issue != "" {
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/common"
+)
+
+func TestReadQAGate(t *testing.T) {
+	startedAt := time.Now()
+	projectPath := t.TempDir()
+	gateFile := filepath.Join(projectPath, filepath.FromSlash(agent.QAGateFilePath("US-001")))
+	if err := os.MkdirAll(filepath.Dir(gateFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := `story: US-001
+gate: FAIL
+status_reason: 登录失败时没有提示
+top_issues:
+  - 缺少错误提示
+  - id: TEST-001
+    severity: medium
+    finding: 没有登录失败的测试
+`
+	if err := os.WriteFile(gateFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 门禁文件优先于回答中的结论
+	gate := readQAGate(projectPath, "US-001", common.LanguageZhCN, "gate: PASS", startedAt)
+	want := &agent.QAGate{
+		StoryNumber:  "US-001",
+		Gate:         common.QAGateFail,
+		StatusReason: "登录失败时没有提示",
+		TopIssues:    []string{"缺少错误提示", "没有登录失败的测试"},
+		GateFile:     "docs/qa/gates/US-001.yml",
+	}
+	if !reflect.DeepEqual(gate, want) {
+		t.Errorf("readQAGate() = %+v, want %+v", gate, want)
+	}
+
+	// 之前评审留下的门禁文件不算数，使用回答中的结论
+	staleTime := startedAt.Add(-time.Hour)
+	if err := os.Chtimes(gateFile, staleTime, staleTime); err != nil {
+		t.Fatal(err)
+	}
+	if gate := readQAGate(projectPath, "US-001", common.LanguageZhCN, "gate: PASS", startedAt); gate.Gate != common.QAGatePass || gate.StatusReason != "" {
+		t.Errorf("readQAGate() with stale file = %+v, want gate pass", gate)
+	}
+}
+
+func TestReadQAGateFallback(t *testing.T) {
+	tests := []struct {
+		name     string
+		answer   string
+		wantGate string
+	}{
+		{name: "answer line", answer: "## 评审完成\n\n**Gate**: CONCERNS\n", wantGate: common.QAGateConcerns},
+		{name: "last answer line wins", answer: "gate: FAIL\n修复后重新评审\ngate: pass", wantGate: common.QAGatePass},
+		{name: "no decision", answer: "评审完成", wantGate: common.QAGateConcerns},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gate := readQAGate(t.TempDir(), "1.1", common.LanguageEnUS, tt.answer, time.Now())
+			if gate.Gate != tt.wantGate || gate.GateFile != "docs/qa/gates/1.1.yml" {
+				t.Errorf("readQAGate() = %+v, want gate %s", gate, tt.wantGate)
+			}
+		})
+	}
+
+	if gate := readQAGate(t.TempDir(), "1.1", common.LanguageEnUS, "", time.Now()); gate.StatusReason != "QA gave no gate decision in docs/qa/gates/1.1.yml" {
+		t.Errorf("status reason = %q", gate.StatusReason)
+	}
+	if path := agent.QAGateFilePath("../../etc/passwd"); path != "docs/qa/gates/..-..-etc-passwd.yml" {
+		t.Errorf("QAGateFilePath() = %q", path)
+	}
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"encoding/json"
 	"fmt"
 
 	"github.com/lighthought/app-maker/shared-models/agent"
@@ -16,31 +17,75 @@ type RedisService interface {
 	// 发布任务状态消息到 Redis Pub/Sub
 	PublishTaskStatus(taskPayload *tasks.AgentExecuteTaskPayload, taskID, status, message string) error
 
+	// 发布任务状态消息，并附带本次 CLI 调用的用量
+	PublishTaskStatusWithUsage(taskPayload *tasks.AgentExecuteTaskPayload, taskID, status, message string, usage *agent.AgentUsage) error
+
+	// 标记任务已被取消
+	MarkTaskCancelled(taskID string) error
+
+	// 任务是否已被取消
+	IsTaskCancelled(taskID string) bool
+
+	// 发布 CLI 输出日志行到任务频道，并写入项目日志环形缓冲区
+	PublishTaskLog(taskPayload *tasks.AgentExecuteTaskPayload, taskID, stream, line string, seq int64) error
+
+	// 获取项目最近的 CLI 输出日志，taskID 为空时返回全部任务
+	GetProjectLogs(projectGuid, taskID string, limit int) ([]*agent.AgentLogMessage, error)
+
 	// 把会话ID保存到缓存中
 	SaveSessionByProjectGuid(projectGuid, agentType, sessionID string)
 
 	// 根据projectGuid从缓存中获取 sessionId
 	GetSessionByProjectGuid(projectGuid, agentType string) string
+
+	// 保存项目模型配置，API Token 加密后写入，不过期，项目设置变更时由后端覆盖
+	SaveModelConfig(projectGuid string, modelConfig *agent.ProjectModelConfig) error
+
+	// 获取项目模型配置，未配置时返回 nil，API Token 无法解密时返回错误
+	GetModelConfig(projectGuid string) (*agent.ProjectModelConfig, error)
+
+	// 删除项目模型配置，项目删除时调用
+	DeleteModelConfig(projectGuid string) error
 }
 
 // publishService 发布服务实现
 type redisService struct {
 	cacheInstance cache.Cache
+	secretKey     string // 加密模型 API Token 的密钥
 }
 
-// NewPublishService 创建发布服务
-func NewRedisService(cacheInstance cache.Cache) RedisService {
+// NewPublishService 创建发布服务，secretKey 为空时随机生成，只用于本地开发，生产环境由配置加载时校验不能为空
+func NewRedisService(cacheInstance cache.Cache, secretKey string) RedisService {
+	if secretKey == "" {
+		logger.Warn("未配置 security.secret_key（AGENTS_SECRET_KEY），使用随机密钥加密模型 API Token，重启后已保存的模型配置无法解密，相关任务会失败，只能用于本地开发")
+		secretKey = utils.GenerateUUID()
+	}
 	return &redisService{
 		cacheInstance: cacheInstance,
+		secretKey:     secretKey,
 	}
 }
 
 // publishTaskStatus 发布任务状态消息到 Redis Pub/Sub
 func (h *redisService) PublishTaskStatus(taskPayload *tasks.AgentExecuteTaskPayload, taskID, status, message string) error {
+	return h.PublishTaskStatusWithUsage(taskPayload, taskID, status, message, nil)
+}
+
+// PublishTaskStatusWithUsage 发布任务状态消息，usage 为空时不上报用量
+func (h *redisService) PublishTaskStatusWithUsage(taskPayload *tasks.AgentExecuteTaskPayload, taskID, status, message string,
+	usage *agent.AgentUsage) error {
 	if h.cacheInstance == nil {
 		return fmt.Errorf("cache instance is nil")
 	}
 
+	// 任务被取消后，CLI 被杀掉导致的失败不再上报，取消状态已由取消接口发布
+	if status == common.CommonStatusFailed && h.IsTaskCancelled(taskID) {
+		logger.Info("任务已取消，忽略失败状态",
+			logger.String("taskID", taskID),
+			logger.String("message", message))
+		return nil
+	}
+
 	statusMsg := &agent.AgentTaskStatusMessage{
 		TaskID:      taskID,
 		ProjectGuid: taskPayload.ProjectGUID,
@@ -49,6 +94,7 @@ func (h *redisService) PublishTaskStatus(taskPayload *tasks.AgentExecuteTaskPayl
 		Message:     message,
 		DevStage:    string(taskPayload.DevStage),
 		Timestamp:   utils.GetCurrentTime(),
+		Usage:       usage,
 	}
 
 	bytes := statusMsg.ToBytes()
@@ -69,6 +115,83 @@ func (h *redisService) PublishTaskStatus(taskPayload *tasks.AgentExecuteTaskPayl
 	return nil
 }
 
+// MarkTaskCancelled 标记任务已被取消
+func (h *redisService) MarkTaskCancelled(taskID string) error {
+	if h.cacheInstance == nil {
+		return fmt.Errorf("cache instance is nil")
+	}
+	return h.cacheInstance.Set(cache.GetAgentTaskCancelledCacheKey(taskID), true, common.CacheExpirationDay)
+}
+
+// IsTaskCancelled 任务是否已被取消
+func (h *redisService) IsTaskCancelled(taskID string) bool {
+	if h.cacheInstance == nil || taskID == "" {
+		return false
+	}
+	return h.cacheInstance.Exists(cache.GetAgentTaskCancelledCacheKey(taskID))
+}
+
+// PublishTaskLog 发布 CLI 输出日志行
+func (h *redisService) PublishTaskLog(taskPayload *tasks.AgentExecuteTaskPayload, taskID, stream, line string, seq int64) error {
+	if h.cacheInstance == nil {
+		return fmt.Errorf("cache instance is nil")
+	}
+
+	logMsg := &agent.AgentLogMessage{
+		TaskID:      taskID,
+		ProjectGuid: taskPayload.ProjectGUID,
+		AgentType:   taskPayload.AgentType,
+		DevStage:    string(taskPayload.DevStage),
+		Stream:      stream,
+		Line:        line,
+		Seq:         seq,
+		Timestamp:   utils.GetCurrentTime(),
+	}
+
+	// 先写入环形缓冲区，保证客户端重连后可以补齐日志
+	key := cache.GetProjectAgentLogCacheKey(taskPayload.ProjectGUID)
+	if err := h.cacheInstance.ListPush(key, logMsg, common.AgentLogBufferSize, common.CacheExpirationDay); err != nil {
+		return fmt.Errorf("写入日志缓冲区失败: %w", err)
+	}
+
+	if err := h.cacheInstance.Publish(cache.GetAgentTaskLogChannel(taskID), logMsg.ToBytes()); err != nil {
+		return fmt.Errorf("发布任务日志失败: %w", err)
+	}
+	return nil
+}
+
+// GetProjectLogs 获取项目最近的 CLI 输出日志
+func (h *redisService) GetProjectLogs(projectGuid, taskID string, limit int) ([]*agent.AgentLogMessage, error) {
+	if h.cacheInstance == nil {
+		return nil, fmt.Errorf("cache instance is nil")
+	}
+	if limit <= 0 || limit > common.AgentLogBufferSize {
+		limit = common.AgentLogBufferSize
+	}
+
+	items, err := h.cacheInstance.ListRange(cache.GetProjectAgentLogCacheKey(projectGuid), 0, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	logs := make([]*agent.AgentLogMessage, 0, len(items))
+	for _, item := range items {
+		var logMsg agent.AgentLogMessage
+		if err := json.Unmarshal([]byte(item), &logMsg); err != nil {
+			continue
+		}
+		if taskID != "" && logMsg.TaskID != taskID {
+			continue
+		}
+		logs = append(logs, &logMsg)
+	}
+
+	if len(logs) > limit {
+		logs = logs[len(logs)-limit:]
+	}
+	return logs, nil
+}
+
 // 把会话ID保存到缓存中
 func (h *redisService) SaveSessionByProjectGuid(projectGuid, agentType, sessionID string) {
 	if h.cacheInstance == nil {
@@ -128,3 +251,76 @@ func (h *redisService) GetSessionByProjectGuid(projectGuid, agentType string) st
 		logger.String("sessionID", sessionID))
 	return sessionID
 }
+
+// SaveModelConfig 保存项目模型配置
+func (h *redisService) SaveModelConfig(projectGuid string, modelConfig *agent.ProjectModelConfig) error {
+	if h.cacheInstance == nil {
+		return fmt.Errorf("cache instance is nil")
+	}
+	if projectGuid == "" || modelConfig == nil {
+		return fmt.Errorf("invalid parameters for saving model config")
+	}
+
+	// API Token 加密后再写入 Redis，避免明文落盘
+	stored := *modelConfig
+	apiToken, err := utils.EncryptString(h.secretKey, modelConfig.ApiToken)
+	if err != nil {
+		return fmt.Errorf("加密模型 API Token 失败: %w", err)
+	}
+	stored.ApiToken = apiToken
+
+	if err := h.cacheInstance.Set(cache.GetProjectModelConfigCacheKey(projectGuid), &stored, 0); err != nil {
+		return fmt.Errorf("保存项目模型配置失败: %w", err)
+	}
+
+	// API Token 属于敏感信息，不写入日志
+	logger.Info("已保存项目模型配置",
+		logger.String("projectGuid", projectGuid),
+		logger.String("cliTool", modelConfig.CliTool),
+		logger.String("modelProvider", modelConfig.ModelProvider),
+		logger.String("aiModel", modelConfig.AiModel))
+	return nil
+}
+
+// GetModelConfig 获取项目模型配置
+func (h *redisService) GetModelConfig(projectGuid string) (*agent.ProjectModelConfig, error) {
+	if h.cacheInstance == nil || projectGuid == "" {
+		return nil, nil
+	}
+
+	key := cache.GetProjectModelConfigCacheKey(projectGuid)
+	if !h.cacheInstance.Exists(key) {
+		return nil, nil
+	}
+	var modelConfig agent.ProjectModelConfig
+	if err := h.cacheInstance.Get(key, &modelConfig); err != nil {
+		return nil, fmt.Errorf("读取项目模型配置失败: %w", err)
+	}
+
+	// 无法解密时不能退回到 agents 进程自身的凭证，由调用方让任务失败
+	apiToken, err := utils.DecryptString(h.secretKey, modelConfig.ApiToken)
+	if err != nil {
+		logger.Error("解密项目模型 API Token 失败",
+			logger.String("projectGuid", projectGuid),
+			logger.String("error", err.Error()))
+		return nil, fmt.Errorf("解密项目模型 API Token 失败，请确认 security.secret_key 没有变更，或在项目设置中重新保存模型配置: %w", err)
+	}
+	modelConfig.ApiToken = apiToken
+	return &modelConfig, nil
+}
+
+// DeleteModelConfig 删除项目模型配置
+func (h *redisService) DeleteModelConfig(projectGuid string) error {
+	if h.cacheInstance == nil {
+		return fmt.Errorf("cache instance is nil")
+	}
+	if projectGuid == "" {
+		return fmt.Errorf("invalid parameters for deleting model config")
+	}
+
+	if err := h.cacheInstance.Delete(cache.GetProjectModelConfigCacheKey(projectGuid)); err != nil {
+		return fmt.Errorf("删除项目模型配置失败: %w", err)
+	}
+	logger.Info("已删除项目模型配置", logger.String("projectGuid", projectGuid))
+	return nil
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/cache"
+)
+
+func TestRedisServiceModelConfig(t *testing.T) {
+	cacheInstance, server := newTestCache(t)
+	redisService := NewRedisService(cacheInstance, "test-secret")
+
+	if err := redisService.SaveModelConfig("p1", &agent.ProjectModelConfig{
+		CliTool:  "claude",
+		ApiToken: "sk-test-token",
+	}); err != nil {
+		t.Fatalf("SaveModelConfig() err = %v", err)
+	}
+
+	// Redis 中只保存密文
+	raw, err := server.Get(cache.GetProjectModelConfigCacheKey("p1"))
+	if err != nil {
+		t.Fatalf("get raw value err = %v", err)
+	}
+	if strings.Contains(raw, "sk-test-token") {
+		t.Errorf("api token stored as plaintext: %s", raw)
+	}
+
+	got, err := redisService.GetModelConfig("p1")
+	if err != nil || got == nil || got.ApiToken != "sk-test-token" || got.CliTool != "claude" {
+		t.Fatalf("GetModelConfig() = %+v, %v", got, err)
+	}
+
+	// 密钥变更后无法解密，返回错误而不是视为未配置
+	if got, err := NewRedisService(cacheInstance, "other-secret").GetModelConfig("p1"); err == nil || got != nil {
+		t.Errorf("GetModelConfig() with other secret = %+v, %v, want error", got, err)
+	}
+
+	// 明文 Token 不是有效的密文
+	if err := cacheInstance.Set(cache.GetProjectModelConfigCacheKey("p2"), &agent.ProjectModelConfig{
+		ApiToken: "sk-plain-token",
+	}, 0); err != nil {
+		t.Fatalf("set plaintext config err = %v", err)
+	}
+	if got, err := redisService.GetModelConfig("p2"); err == nil || got != nil {
+		t.Errorf("GetModelConfig() with plaintext token = %+v, %v, want error", got, err)
+	}
+
+	if err := redisService.DeleteModelConfig("p1"); err != nil {
+		t.Fatalf("DeleteModelConfig() err = %v", err)
+	}
+	if got, err := redisService.GetModelConfig("p1"); err != nil || got != nil {
+		t.Errorf("GetModelConfig() after delete = %+v, %v, want nil", got, err)
+	}
+}
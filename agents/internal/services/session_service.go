@@ -0,0 +1,253 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/cache"
+	"github.com/lighthought/app-maker/shared-models/common"
+	"github.com/lighthought/app-maker/shared-models/logger"
+	"github.com/lighthought/app-maker/shared-models/utils"
+
+	"github.com/lighthought/app-maker/agents/internal/api/models"
+)
+
+// SessionService Agent 会话服务：支持原生会话的 CLI 使用会话ID恢复上下文，
+// 其余 CLI 使用按项目、Agent 保存的对话记录，把精简的历史拼接到提示词中
+type SessionService interface {
+	// 准备本次调用，返回原生会话ID和最终发送给 CLI 的提示词，language 为历史对话说明使用的语言
+	Prepare(adapter CliAdapter, projectGuid, agentType, language, message string) (string, string)
+
+	// 记录本次调用的结果，message 为不含历史的原始消息
+	Record(adapter CliAdapter, projectGuid, agentType, message string, response *models.ClaudeResponse)
+
+	// 获取项目下所有 Agent 的会话
+	ListSessions(projectGuid string) ([]*agent.AgentSessionInfo, error)
+
+	// 重置会话，agentType 为空时重置项目下所有 Agent 的会话
+	ResetSession(projectGuid, agentType string) error
+}
+
+type sessionService struct {
+	cacheInstance cache.Cache
+	redisService  RedisService
+}
+
+// NewSessionService 创建会话服务
+func NewSessionService(cacheInstance cache.Cache, redisService RedisService) SessionService {
+	return &sessionService{
+		cacheInstance: cacheInstance,
+		redisService:  redisService,
+	}
+}
+
+// Prepare 准备本次调用
+func (s *sessionService) Prepare(adapter CliAdapter, projectGuid, agentType, language, message string) (string, string) {
+	if adapter.SupportsSession() {
+		return s.redisService.GetSessionByProjectGuid(projectGuid, agentType), message
+	}
+
+	turns, err := s.getTranscript(projectGuid, agentType)
+	if err != nil || len(turns) == 0 {
+		return "", message
+	}
+	return "", buildHistoryPrompt(turns, language, message)
+}
+
+// Record 记录本次调用的结果
+func (s *sessionService) Record(adapter CliAdapter, projectGuid, agentType, message string, response *models.ClaudeResponse) {
+	if response == nil {
+		return
+	}
+	if adapter.SupportsSession() && response.SessionID != "" {
+		s.redisService.SaveSessionByProjectGuid(projectGuid, agentType, response.SessionID)
+	}
+	if s.cacheInstance == nil {
+		return
+	}
+
+	// 所有 CLI 都保存对话记录，切换 CLI 后依然可以延续上下文
+	turn := &agent.AgentSessionTurn{
+		CliTool:   adapter.Name(),
+		Message:   message,
+		Result:    response.Result,
+		Timestamp: utils.GetCurrentTime(),
+	}
+	key := cache.GetProjectAgentTranscriptCacheKey(projectGuid, agentType)
+	if err := s.cacheInstance.ListPush(key, turn, common.AgentSessionTranscriptSize, common.CacheExpirationWeek); err != nil {
+		logger.Warn("保存对话记录失败",
+			logger.String("projectGuid", projectGuid),
+			logger.String("agentType", agentType),
+			logger.String("error", err.Error()))
+	}
+}
+
+// ListSessions 获取项目下所有 Agent 的会话
+func (s *sessionService) ListSessions(projectGuid string) ([]*agent.AgentSessionInfo, error) {
+	if s.cacheInstance == nil {
+		return nil, fmt.Errorf("cache instance is nil")
+	}
+
+	agentTypes, err := s.listAgentTypes(projectGuid)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*agent.AgentSessionInfo, 0, len(agentTypes))
+	for _, agentType := range agentTypes {
+		info := &agent.AgentSessionInfo{
+			ProjectGuid: projectGuid,
+			AgentType:   agentType,
+			Mode:        common.AgentSessionModeTranscript,
+			SessionID:   s.redisService.GetSessionByProjectGuid(projectGuid, agentType),
+		}
+		if info.SessionID != "" {
+			info.Mode = common.AgentSessionModeNative
+		}
+		if turns, err := s.getTranscript(projectGuid, agentType); err == nil {
+			info.Turns = len(turns)
+			if len(turns) > 0 {
+				info.UpdatedAt = turns[len(turns)-1].Timestamp
+			}
+		}
+		sessions = append(sessions, info)
+	}
+	return sessions, nil
+}
+
+// ResetSession 重置会话
+func (s *sessionService) ResetSession(projectGuid, agentType string) error {
+	if s.cacheInstance == nil {
+		return fmt.Errorf("cache instance is nil")
+	}
+
+	agentTypes := []string{agentType}
+	if agentType == "" {
+		var err error
+		if agentTypes, err = s.listAgentTypes(projectGuid); err != nil {
+			return err
+		}
+	}
+
+	keys := make([]string, 0, len(agentTypes)*2)
+	for _, item := range agentTypes {
+		keys = append(keys,
+			cache.GetProjectAgentSessionCacheKey(projectGuid, item),
+			cache.GetProjectAgentTranscriptCacheKey(projectGuid, item))
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	if err := s.cacheInstance.DeleteMultiple(keys); err != nil {
+		return fmt.Errorf("重置会话失败: %w", err)
+	}
+
+	logger.Info("已重置 Agent 会话",
+		logger.String("projectGuid", projectGuid),
+		logger.String("agentType", agentType))
+	return nil
+}
+
+// 遍历会话键时每次 SCAN 的提示数量
+const sessionScanCount = 100
+
+// listAgentTypes 获取项目下有会话或对话记录的 Agent 类型
+func (s *sessionService) listAgentTypes(projectGuid string) ([]string, error) {
+	sessionPrefix := cache.GetProjectAgentSessionCacheKey(projectGuid, "")
+	transcriptPrefix := cache.GetProjectAgentTranscriptCacheKey(projectGuid, "")
+
+	agentTypeSet := make(map[string]struct{})
+	for _, prefix := range []string{sessionPrefix, transcriptPrefix} {
+		keys, err := s.cacheInstance.Scan(prefix+"*", sessionScanCount)
+		if err != nil {
+			return nil, fmt.Errorf("获取会话列表失败: %w", err)
+		}
+		for _, key := range keys {
+			agentTypeSet[strings.TrimPrefix(key, prefix)] = struct{}{}
+		}
+	}
+
+	agentTypes := make([]string, 0, len(agentTypeSet))
+	for agentType := range agentTypeSet {
+		agentTypes = append(agentTypes, agentType)
+	}
+	sort.Strings(agentTypes)
+	return agentTypes, nil
+}
+
+// getTranscript 获取对话记录
+func (s *sessionService) getTranscript(projectGuid, agentType string) ([]*agent.AgentSessionTurn, error) {
+	if s.cacheInstance == nil {
+		return nil, fmt.Errorf("cache instance is nil")
+	}
+
+	items, err := s.cacheInstance.ListRange(cache.GetProjectAgentTranscriptCacheKey(projectGuid, agentType), 0, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	turns := make([]*agent.AgentSessionTurn, 0, len(items))
+	for _, item := range items {
+		var turn agent.AgentSessionTurn
+		if err := json.Unmarshal([]byte(item), &turn); err != nil {
+			continue
+		}
+		turns = append(turns, &turn)
+	}
+	return turns, nil
+}
+
+// historyPromptText 历史对话提示词的固定文案
+type historyPromptText struct {
+	Header  string
+	Me      string
+	You     string
+	Request string
+}
+
+// 历史对话提示词的固定文案，按输出语言区分
+var historyPromptTexts = map[string]historyPromptText{
+	common.LanguageZhCN: {
+		Header:  "以下是我们之前的对话记录（已精简），请结合上下文处理本次请求：\n",
+		Me:      "我：",
+		You:     "你：",
+		Request: "---\n本次请求：",
+	},
+	common.LanguageEnUS: {
+		Header:  "Here is our previous conversation (condensed). Please handle this request with that context in mind:\n",
+		Me:      "Me: ",
+		You:     "You: ",
+		Request: "---\nThis request: ",
+	},
+}
+
+// buildHistoryPrompt 把最近几轮对话精简后拼接到本次消息前
+func buildHistoryPrompt(turns []*agent.AgentSessionTurn, language, message string) string {
+	if len(turns) > common.AgentSessionHistoryTurns {
+		turns = turns[len(turns)-common.AgentSessionHistoryTurns:]
+	}
+
+	texts := historyPromptTexts[common.NormalizeLanguage(language)]
+	var builder strings.Builder
+	builder.WriteString(texts.Header)
+	for index, turn := range turns {
+		builder.WriteString(fmt.Sprintf("[%d] %s%s\n", index+1, texts.Me, condense(turn.Message)))
+		builder.WriteString(fmt.Sprintf("[%d] %s%s\n", index+1, texts.You, condense(turn.Result)))
+	}
+	builder.WriteString(texts.Request)
+	builder.WriteString(message)
+	return builder.String()
+}
+
+// condense 精简一段对话内容：合并空白，超出长度时截断
+func condense(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) <= common.AgentSessionHistoryChars {
+		return text
+	}
+	return string(runes[:common.AgentSessionHistoryChars]) + "..."
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/lighthought/app-maker/shared-models/cache"
+	"github.com/lighthought/app-maker/shared-models/common"
+
+	"github.com/lighthought/app-maker/agents/internal/api/models"
+)
+
+// newTestCache 基于 miniredis 创建缓存实例
+func newTestCache(t *testing.T) (cache.Cache, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return cache.NewRedisCache(client), server
+}
+
+func TestSessionServiceListAndReset(t *testing.T) {
+	cacheInstance, _ := newTestCache(t)
+	redisService := NewRedisService(cacheInstance, "test-secret")
+	sessionService := NewSessionService(cacheInstance, redisService)
+	registry := NewCliAdapterRegistry("")
+
+	claude := registry.Get(common.CliToolClaudeCode)
+	qwen := registry.Get(common.CliToolQwenCode)
+	sessionService.Record(claude, "p1", "dev", "hi", &models.ClaudeResponse{Result: "hello", SessionID: "s-1"})
+	sessionService.Record(qwen, "p1", "pm", "prd", &models.ClaudeResponse{Result: "done"})
+	sessionService.Record(qwen, "p2", "dev", "other project", &models.ClaudeResponse{Result: "done"})
+
+	sessions, err := sessionService.ListSessions("p1")
+	if err != nil {
+		t.Fatalf("ListSessions() err = %v", err)
+	}
+	if len(sessions) != 2 || sessions[0].AgentType != "dev" || sessions[1].AgentType != "pm" {
+		t.Fatalf("sessions = %+v", sessions)
+	}
+	if sessions[0].Mode != common.AgentSessionModeNative || sessions[0].SessionID != "s-1" || sessions[0].Turns != 1 {
+		t.Errorf("dev session = %+v", sessions[0])
+	}
+	if sessions[1].Mode != common.AgentSessionModeTranscript || sessions[1].Turns != 1 {
+		t.Errorf("pm session = %+v", sessions[1])
+	}
+
+	if err := sessionService.ResetSession("p1", ""); err != nil {
+		t.Fatalf("ResetSession() err = %v", err)
+	}
+	if sessions, _ := sessionService.ListSessions("p1"); len(sessions) != 0 {
+		t.Errorf("sessions after reset = %+v", sessions)
+	}
+	if sessions, _ := sessionService.ListSessions("p2"); len(sessions) != 1 {
+		t.Errorf("other project sessions = %+v", sessions)
+	}
+}
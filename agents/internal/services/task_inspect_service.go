@@ -0,0 +1,318 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/logger"
+	"github.com/lighthought/app-maker/shared-models/tasks"
+
+	"github.com/hibiken/asynq"
+)
+
+const (
+	defaultTaskListLimit  = 100  // 没有指定 limit 时返回的任务数
+	taskListPageSize      = 200  // 每次从队列读取的任务数
+	taskListMaxScan       = 5000 // 每个队列的每种状态最多读取的任务数，避免已完成任务过多时扫描过久
+	taskPayloadTextLimit  = 200  // 负载中字符串的最大字符数，超出部分截断
+	redactedPayloadValue  = "******"
+	truncatedPayloadLabel = "...(truncated)"
+)
+
+// 可以筛选的任务状态，按排查时关注的顺序排列
+var taskListStates = []asynq.TaskState{
+	asynq.TaskStateActive, asynq.TaskStatePending, asynq.TaskStateScheduled,
+	asynq.TaskStateRetry, asynq.TaskStateArchived, asynq.TaskStateCompleted,
+}
+
+// 负载中需要脱敏的字段名，如 api_token、secret_key、password
+var secretPayloadKeyPattern = regexp.MustCompile(`(?i)(token|secret|password|passwd|api_?key|authorization|private_?key|credential)`)
+
+// TaskInspectService 异步任务排查：按状态、项目、Agent 类型列出任务，删除、归档、立即执行任务
+type TaskInspectService interface {
+	// 获取所有队列的统计和符合筛选条件的任务
+	ListTasks(req *agent.TaskListReq) (*agent.TaskListResp, error)
+
+	// 查找任务，queue 为空时在所有队列中查找
+	FindTask(taskID, queue string) (*asynq.TaskInfo, error)
+
+	// 删除未在执行的任务，不通知后端，需要通知时使用取消任务
+	DeleteTask(taskID, queue string) error
+
+	// 归档未在执行的任务，归档的任务不再执行，可以立即执行恢复
+	ArchiveTask(taskID, queue string) error
+
+	// 立即执行等待调度、等待重试或已归档的任务
+	RunTask(taskID, queue string) error
+}
+
+type taskInspectService struct {
+	inspector *asynq.Inspector
+}
+
+// NewTaskInspectService 创建异步任务排查服务
+func NewTaskInspectService(inspector *asynq.Inspector) TaskInspectService {
+	return &taskInspectService{inspector: inspector}
+}
+
+// ListTasks 获取所有队列的统计和符合筛选条件的任务
+func (s *taskInspectService) ListTasks(req *agent.TaskListReq) (*agent.TaskListResp, error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultTaskListLimit
+	}
+
+	existing, err := s.inspector.Queues()
+	if err != nil {
+		return nil, fmt.Errorf("获取队列列表失败: %w", err)
+	}
+	existingQueues := make(map[string]bool, len(existing))
+	for _, queue := range existing {
+		existingQueues[queue] = true
+	}
+
+	resp := &agent.TaskListResp{Queues: []*agent.TaskQueueStats{}, Tasks: []*agent.TaskSummary{}}
+	for _, queue := range tasks.TaskQueueNames {
+		if !existingQueues[queue] {
+			// 还没有任务进入过的队列
+			resp.Queues = append(resp.Queues, &agent.TaskQueueStats{Queue: queue, Latency: "0s"})
+			continue
+		}
+		info, err := s.inspector.GetQueueInfo(queue)
+		if err != nil {
+			return nil, fmt.Errorf("获取队列 %s 统计失败: %w", queue, err)
+		}
+		resp.Queues = append(resp.Queues, newTaskQueueStats(info))
+	}
+
+	for _, queue := range tasks.TaskQueueNames {
+		if !existingQueues[queue] || (req.Queue != "" && req.Queue != queue) {
+			continue
+		}
+		for _, state := range taskListStates {
+			if req.State != "" && req.State != state.String() {
+				continue
+			}
+			truncated, err := s.collectTasks(queue, state, req, limit, resp)
+			if err != nil {
+				return nil, err
+			}
+			if truncated {
+				resp.Truncated = true
+				return resp, nil
+			}
+		}
+	}
+	return resp, nil
+}
+
+// collectTasks 分页读取队列中指定状态的任务，把符合筛选条件的任务加入 resp，达到 limit 时返回 true
+func (s *taskInspectService) collectTasks(queue string, state asynq.TaskState, req *agent.TaskListReq, limit int,
+	resp *agent.TaskListResp) (bool, error) {
+	for page := 1; (page-1)*taskListPageSize < taskListMaxScan; page++ {
+		infos, err := s.listTasksByState(queue, state, asynq.Page(page), asynq.PageSize(taskListPageSize))
+		if errors.Is(err, asynq.ErrQueueNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("获取队列 %s 中 %s 状态的任务失败: %w", queue, state.String(), err)
+		}
+		for _, info := range infos {
+			summary := newTaskSummary(info)
+			if !matchTaskSummary(summary, req) {
+				continue
+			}
+			if len(resp.Tasks) >= limit {
+				return true, nil
+			}
+			resp.Tasks = append(resp.Tasks, summary)
+		}
+		if len(infos) < taskListPageSize {
+			return false, nil
+		}
+	}
+	return false, nil
+}
+
+// listTasksByState 按状态列出队列中的任务
+func (s *taskInspectService) listTasksByState(queue string, state asynq.TaskState, opts ...asynq.ListOption) ([]*asynq.TaskInfo, error) {
+	switch state {
+	case asynq.TaskStateActive:
+		return s.inspector.ListActiveTasks(queue, opts...)
+	case asynq.TaskStatePending:
+		return s.inspector.ListPendingTasks(queue, opts...)
+	case asynq.TaskStateScheduled:
+		return s.inspector.ListScheduledTasks(queue, opts...)
+	case asynq.TaskStateRetry:
+		return s.inspector.ListRetryTasks(queue, opts...)
+	case asynq.TaskStateArchived:
+		return s.inspector.ListArchivedTasks(queue, opts...)
+	case asynq.TaskStateCompleted:
+		return s.inspector.ListCompletedTasks(queue, opts...)
+	default:
+		return nil, fmt.Errorf("不支持的任务状态: %s", state.String())
+	}
+}
+
+// FindTask 查找任务，queue 为空时在所有队列中查找
+func (s *taskInspectService) FindTask(taskID, queue string) (*asynq.TaskInfo, error) {
+	if queue != "" {
+		return s.inspector.GetTaskInfo(queue, taskID)
+	}
+	return tasks.FindTaskInQueues(s.inspector, taskID)
+}
+
+// DeleteTask 删除未在执行的任务
+func (s *taskInspectService) DeleteTask(taskID, queue string) error {
+	info, err := s.findInactiveTask(taskID, queue)
+	if err != nil {
+		return err
+	}
+	if err := s.inspector.DeleteTask(info.Queue, taskID); err != nil {
+		return fmt.Errorf("删除任务失败: %w", err)
+	}
+	logger.Info("已删除任务", logger.String("taskID", taskID), logger.String("queue", info.Queue), logger.String("state", info.State.String()))
+	return nil
+}
+
+// ArchiveTask 归档未在执行的任务
+func (s *taskInspectService) ArchiveTask(taskID, queue string) error {
+	info, err := s.findInactiveTask(taskID, queue)
+	if err != nil {
+		return err
+	}
+	if err := s.inspector.ArchiveTask(info.Queue, taskID); err != nil {
+		return fmt.Errorf("归档任务失败: %w", err)
+	}
+	logger.Info("已归档任务", logger.String("taskID", taskID), logger.String("queue", info.Queue), logger.String("state", info.State.String()))
+	return nil
+}
+
+// RunTask 立即执行等待调度、等待重试或已归档的任务
+func (s *taskInspectService) RunTask(taskID, queue string) error {
+	info, err := s.FindTask(taskID, queue)
+	if err != nil {
+		return err
+	}
+	switch info.State {
+	case asynq.TaskStateScheduled, asynq.TaskStateRetry, asynq.TaskStateArchived:
+	default:
+		return fmt.Errorf("任务状态为 %s，只能立即执行 scheduled、retry、archived 状态的任务", info.State.String())
+	}
+	if err := s.inspector.RunTask(info.Queue, taskID); err != nil {
+		return fmt.Errorf("立即执行任务失败: %w", err)
+	}
+	logger.Info("已立即执行任务", logger.String("taskID", taskID), logger.String("queue", info.Queue), logger.String("state", info.State.String()))
+	return nil
+}
+
+// findInactiveTask 查找不在执行中的任务，执行中的任务需要先取消
+func (s *taskInspectService) findInactiveTask(taskID, queue string) (*asynq.TaskInfo, error) {
+	info, err := s.FindTask(taskID, queue)
+	if err != nil {
+		return nil, err
+	}
+	if info.State == asynq.TaskStateActive {
+		return nil, fmt.Errorf("任务正在执行，请先取消任务")
+	}
+	return info, nil
+}
+
+// newTaskQueueStats 转换队列统计
+func newTaskQueueStats(info *asynq.QueueInfo) *agent.TaskQueueStats {
+	return &agent.TaskQueueStats{
+		Queue:     info.Queue,
+		Size:      info.Size,
+		Pending:   info.Pending,
+		Active:    info.Active,
+		Scheduled: info.Scheduled,
+		Retry:     info.Retry,
+		Archived:  info.Archived,
+		Completed: info.Completed,
+		Processed: info.Processed,
+		Failed:    info.Failed,
+		Paused:    info.Paused,
+		Latency:   info.Latency.Round(time.Second).String(),
+	}
+}
+
+// newTaskSummary 转换任务摘要，负载中的密钥脱敏、过长的文本截断
+func newTaskSummary(info *asynq.TaskInfo) *agent.TaskSummary {
+	summary := &agent.TaskSummary{
+		ID:            info.ID,
+		Queue:         info.Queue,
+		Type:          info.Type,
+		State:         info.State.String(),
+		Retried:       info.Retried,
+		MaxRetry:      info.MaxRetry,
+		LastError:     info.LastErr,
+		LastFailedAt:  formatTaskTime(info.LastFailedAt),
+		NextProcessAt: formatTaskTime(info.NextProcessAt),
+		CompletedAt:   formatTaskTime(info.CompletedAt),
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(info.Payload, &payload); err != nil {
+		return summary
+	}
+	summary.ProjectGuid = payloadString(payload, "project_guid")
+	summary.AgentType = payloadString(payload, "agent_type")
+	summary.DevStage = payloadString(payload, "dev_stage")
+	summary.StoryNumber = payloadString(payload, "story_number")
+	summary.Payload = redactPayload(payload).(map[string]any)
+	return summary
+}
+
+// matchTaskSummary 任务是否符合筛选条件
+func matchTaskSummary(summary *agent.TaskSummary, req *agent.TaskListReq) bool {
+	return (req.ProjectGuid == "" || summary.ProjectGuid == req.ProjectGuid) &&
+		(req.AgentType == "" || summary.AgentType == req.AgentType) &&
+		(req.Type == "" || summary.Type == req.Type)
+}
+
+// redactPayload 递归脱敏负载中的密钥字段，截断过长的字符串
+func redactPayload(value any) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		redacted := make(map[string]any, len(typed))
+		for key, item := range typed {
+			if text, ok := item.(string); ok && text != "" && secretPayloadKeyPattern.MatchString(key) {
+				redacted[key] = redactedPayloadValue
+				continue
+			}
+			redacted[key] = redactPayload(item)
+		}
+		return redacted
+	case []any:
+		redacted := make([]any, len(typed))
+		for i, item := range typed {
+			redacted[i] = redactPayload(item)
+		}
+		return redacted
+	case string:
+		if runes := []rune(typed); len(runes) > taskPayloadTextLimit {
+			return string(runes[:taskPayloadTextLimit]) + truncatedPayloadLabel
+		}
+		return typed
+	default:
+		return value
+	}
+}
+
+// payloadString 读取负载中的字符串字段
+func payloadString(payload map[string]any, key string) string {
+	value, _ := payload[key].(string)
+	return value
+}
+
+// formatTaskTime 格式化任务时间，零值为空
+func formatTaskTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
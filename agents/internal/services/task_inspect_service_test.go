@@ -0,0 +1,124 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hibiken/asynq"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/common"
+	"github.com/lighthought/app-maker/shared-models/tasks"
+)
+
+func TestTaskInspectServiceListAndActions(t *testing.T) {
+	server := miniredis.RunT(t)
+	opt := asynq.RedisClientOpt{Addr: server.Addr()}
+	client := asynq.NewClient(opt)
+	inspector := asynq.NewInspector(opt)
+	t.Cleanup(func() {
+		client.Close()
+		inspector.Close()
+	})
+
+	setup := tasks.NewProjectSetupTask(&agent.SetupProjEnvReq{ProjectGuid: "p1", ApiToken: "sk-secret"})
+	setupInfo, err := client.Enqueue(setup)
+	if err != nil {
+		t.Fatalf("Enqueue() err = %v", err)
+	}
+	message := strings.Repeat("实现故事", 100)
+	story := tasks.NewAgentStoryTaskWithCli("p1", common.AgentTypeDev, &agent.RenderedPrompt{Content: message},
+		common.CliToolClaudeCode, common.DevStatusDevelopStory, "1.2")
+	storyInfo, err := client.Enqueue(story, asynq.ProcessIn(time.Hour))
+	if err != nil {
+		t.Fatalf("Enqueue() err = %v", err)
+	}
+	if _, err := client.Enqueue(tasks.NewAgentExecuteTask("p2", common.AgentTypePM, "prd", common.DevStatusGeneratePRD)); err != nil {
+		t.Fatalf("Enqueue() err = %v", err)
+	}
+
+	service := NewTaskInspectService(inspector)
+	resp, err := service.ListTasks(&agent.TaskListReq{ProjectGuid: "p1"})
+	if err != nil {
+		t.Fatalf("ListTasks() err = %v", err)
+	}
+	if len(resp.Queues) != 3 || len(resp.Tasks) != 2 {
+		t.Fatalf("resp = %+v", resp)
+	}
+	// 活动、排队的任务排在等待调度的任务之前
+	pending, scheduled := resp.Tasks[0], resp.Tasks[1]
+	if pending.ID != setupInfo.ID || pending.State != "pending" || pending.Payload["api_token"] != redactedPayloadValue {
+		t.Errorf("pending = %+v", pending)
+	}
+	if scheduled.ID != storyInfo.ID || scheduled.State != "scheduled" || scheduled.AgentType != common.AgentTypeDev ||
+		scheduled.StoryNumber != "1.2" || !strings.HasSuffix(scheduled.Payload["message"].(string), truncatedPayloadLabel) {
+		t.Errorf("scheduled = %+v", scheduled)
+	}
+
+	resp, err = service.ListTasks(&agent.TaskListReq{State: "pending", AgentType: common.AgentTypePM})
+	if err != nil || len(resp.Tasks) != 1 || resp.Tasks[0].ProjectGuid != "p2" {
+		t.Fatalf("ListTasks(pm) = %+v, %v", resp, err)
+	}
+	resp, err = service.ListTasks(&agent.TaskListReq{Limit: 1})
+	if err != nil || len(resp.Tasks) != 1 || !resp.Truncated {
+		t.Fatalf("ListTasks(limit) = %+v, %v", resp, err)
+	}
+
+	// 排队中的任务只能归档、删除，不能立即执行
+	if err := service.RunTask(setupInfo.ID, ""); err == nil {
+		t.Error("RunTask(pending) err = nil")
+	}
+	if err := service.ArchiveTask(setupInfo.ID, ""); err != nil {
+		t.Fatalf("ArchiveTask() err = %v", err)
+	}
+	if err := service.RunTask(setupInfo.ID, ""); err != nil {
+		t.Fatalf("RunTask(archived) err = %v", err)
+	}
+	if info, err := service.FindTask(setupInfo.ID, ""); err != nil || info.State != asynq.TaskStatePending {
+		t.Fatalf("FindTask() = %+v, %v", info, err)
+	}
+	if err := service.DeleteTask(storyInfo.ID, ""); err != nil {
+		t.Fatalf("DeleteTask() err = %v", err)
+	}
+	if _, err := service.FindTask(storyInfo.ID, ""); err == nil {
+		t.Error("FindTask(deleted) err = nil")
+	}
+}
+
+func TestTaskInspectServiceQueuesByTaskType(t *testing.T) {
+	server := miniredis.RunT(t)
+	opt := asynq.RedisClientOpt{Addr: server.Addr()}
+	client := asynq.NewClient(opt)
+	inspector := asynq.NewInspector(opt)
+	t.Cleanup(func() {
+		client.Close()
+		inspector.Close()
+	})
+
+	// 对话任务进入高优先级队列，部署任务进入低优先级队列
+	deployInfo, err := client.Enqueue(tasks.NewProjectDeployTask(&agent.DeployReq{ProjectGuid: "p3"}))
+	if err != nil {
+		t.Fatalf("Enqueue() err = %v", err)
+	}
+	chatInfo, err := client.Enqueue(tasks.NewAgentChatTask(&agent.ChatReq{ProjectGuid: "p3", AgentType: common.AgentTypePM}))
+	if err != nil {
+		t.Fatalf("Enqueue() err = %v", err)
+	}
+	if chatInfo.Queue != common.TaskQueueNameCritical || deployInfo.Queue != common.TaskQueueNameLow {
+		t.Fatalf("chat queue = %s, deploy queue = %s", chatInfo.Queue, deployInfo.Queue)
+	}
+
+	service := NewTaskInspectService(inspector)
+	resp, err := service.ListTasks(&agent.TaskListReq{ProjectGuid: "p3"})
+	if err != nil || len(resp.Tasks) != 2 || resp.Tasks[0].ID != chatInfo.ID || resp.Tasks[1].ID != deployInfo.ID {
+		t.Fatalf("ListTasks() = %+v, %v", resp, err)
+	}
+	if resp.Queues[1].Queue != common.TaskQueueNameDefault || resp.Queues[1].Size != 0 {
+		t.Errorf("default queue = %+v", resp.Queues[1])
+	}
+	if info, err := service.FindTask(deployInfo.ID, ""); err != nil || info.Queue != common.TaskQueueNameLow {
+		t.Fatalf("FindTask() = %+v, %v", info, err)
+	}
+}
@@ -0,0 +1,437 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/common"
+)
+
+// 测试用例失败原因的最大长度，避免报告过大
+const maxTestMessageBytes = 4 * 1024
+
+// 测试套件的工具类型
+const (
+	testToolGo      = "go"
+	testToolESLint  = "eslint"
+	testToolVitest  = "vitest"
+	testToolJest    = "jest"
+	testToolCommand = "command" // 输出不是已知报告格式的命令，整条命令作为一个用例
+)
+
+// go test -cover 输出的覆盖率，如 coverage: 75.0% of statements
+var goCoveragePattern = regexp.MustCompile(`coverage: ([0-9.]+)% of statements`)
+
+// goTestEvent go test -json 输出的一行
+type goTestEvent struct {
+	Action  string  `json:"Action"`
+	Package string  `json:"Package"`
+	Test    string  `json:"Test"`
+	Elapsed float64 `json:"Elapsed"` // 秒
+	Output  string  `json:"Output"`
+}
+
+// eslintFileResult ESLint JSON 格式中一个文件的结果
+type eslintFileResult struct {
+	FilePath   string `json:"filePath"`
+	ErrorCount int    `json:"errorCount"`
+	Messages   []struct {
+		RuleID   string `json:"ruleId"`
+		Severity int    `json:"severity"` // 1: warning, 2: error
+		Message  string `json:"message"`
+		Line     int    `json:"line"`
+		Column   int    `json:"column"`
+	} `json:"messages"`
+}
+
+// jestReport Jest --json、Vitest --reporter=json 的报告，两者格式相同
+type jestReport struct {
+	NumTotalTests *int `json:"numTotalTests"`
+	TestResults   []struct {
+		Name             string `json:"name"`
+		Status           string `json:"status"`
+		Message          string `json:"message"`
+		StartTime        int64  `json:"startTime"`
+		EndTime          int64  `json:"endTime"`
+		AssertionResults []struct {
+			FullName        string   `json:"fullName"`
+			Title           string   `json:"title"`
+			Status          string   `json:"status"` // passed, failed, pending, skipped, todo
+			Duration        *float64 `json:"duration"`
+			FailureMessages []string `json:"failureMessages"`
+		} `json:"assertionResults"`
+	} `json:"testResults"`
+	CoverageMap map[string]struct {
+		S map[string]int `json:"s"` // 每条语句的执行次数
+	} `json:"coverageMap"`
+}
+
+// parseTestSuites 把一条测试命令的输出解析为测试套件：依次尝试 go test -json、Jest/Vitest JSON、ESLint JSON，
+// 都不是时整条命令作为一个用例；命令失败但报告中没有失败用例时（如编译失败）追加一个失败的用例
+func parseTestSuites(component, commandLine, output string, success bool, diagnostics []string) []*agent.TestSuite {
+	suites := parseGoTestJSON(output)
+	if suites == nil {
+		suites = parseJSONReport(output, commandLine)
+	}
+	if suites == nil {
+		suite := &agent.TestSuite{Name: commandLine, Tool: testToolCommand}
+		suites = []*agent.TestSuite{suite}
+		if success {
+			suite.Cases = append(suite.Cases, &agent.TestCase{Name: commandLine, Status: common.TestStatusPassed})
+		}
+	}
+
+	failed := false
+	for _, suite := range suites {
+		suite.Component = component
+		suite.Command = commandLine
+		summarizeTestSuite(suite)
+		failed = failed || suite.Failed > 0
+	}
+	if !success && !failed {
+		suite := suites[len(suites)-1]
+		suite.Cases = append(suite.Cases, &agent.TestCase{
+			Name:    commandLine,
+			Status:  common.TestStatusFailed,
+			Message: truncateTestMessage(strings.Join(diagnostics, "\n")),
+		})
+		summarizeTestSuite(suite)
+	}
+	return suites
+}
+
+// parseGoTestJSON 解析 go test -json 的输出，每个包为一个套件；没有 go test 事件时返回 nil
+func parseGoTestJSON(output string) []*agent.TestSuite {
+	suites := make(map[string]*agent.TestSuite)
+	var order []string
+	caseOutputs := make(map[string]*strings.Builder)
+	packageFailed := make(map[string]bool)
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+		var event goTestEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil || event.Action == "" || event.Package == "" {
+			continue
+		}
+		suite, ok := suites[event.Package]
+		if !ok {
+			suite = &agent.TestSuite{Name: event.Package, Tool: testToolGo}
+			suites[event.Package] = suite
+			order = append(order, event.Package)
+		}
+
+		key := event.Package + "\x00" + event.Test
+		switch event.Action {
+		case "output", "build-output":
+			if match := goCoveragePattern.FindStringSubmatch(event.Output); match != nil {
+				if coverage, err := strconv.ParseFloat(match[1], 64); err == nil {
+					suite.Coverage = &coverage
+				}
+			}
+			builder, ok := caseOutputs[key]
+			if !ok {
+				builder = &strings.Builder{}
+				caseOutputs[key] = builder
+			}
+			builder.WriteString(event.Output)
+		case "pass", "fail", "skip":
+			if event.Test == "" {
+				suite.DurationMs = int64(event.Elapsed * 1000)
+				packageFailed[event.Package] = event.Action == "fail"
+				continue
+			}
+			testCase := &agent.TestCase{
+				Name:       event.Test,
+				Status:     goTestStatus(event.Action),
+				DurationMs: int64(event.Elapsed * 1000),
+			}
+			if event.Action == "fail" {
+				if builder, ok := caseOutputs[key]; ok {
+					testCase.Message = truncateTestMessage(strings.TrimSpace(builder.String()))
+				}
+			}
+			suite.Cases = append(suite.Cases, testCase)
+		}
+	}
+	if len(order) == 0 {
+		return nil
+	}
+
+	result := make([]*agent.TestSuite, 0, len(order))
+	for _, pkg := range order {
+		suite := suites[pkg]
+		// 包失败但没有失败的用例：编译失败、TestMain 失败或测试进程崩溃
+		if packageFailed[pkg] && !hasFailedCase(suite) {
+			message := ""
+			if builder, ok := caseOutputs[pkg+"\x00"]; ok {
+				message = truncateTestMessage(strings.TrimSpace(builder.String()))
+			}
+			suite.Cases = append(suite.Cases, &agent.TestCase{Name: pkg, Status: common.TestStatusFailed, Message: message})
+		}
+		result = append(result, suite)
+	}
+	return result
+}
+
+// goTestStatus go test -json 的 Action 对应的用例状态
+func goTestStatus(action string) string {
+	switch action {
+	case "pass":
+		return common.TestStatusPassed
+	case "skip":
+		return common.TestStatusSkipped
+	default:
+		return common.TestStatusFailed
+	}
+}
+
+// parseJSONReport 在输出中查找 Jest/Vitest 或 ESLint 的 JSON 报告，npm 等工具在报告前后输出的内容会被忽略；
+// 没有找到报告时返回 nil
+func parseJSONReport(output, commandLine string) []*agent.TestSuite {
+	lines := strings.Split(output, "\n")
+	offset := 0
+	for _, line := range lines {
+		start := offset
+		offset += len(line) + 1
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+			continue
+		}
+		var raw json.RawMessage
+		if err := json.NewDecoder(strings.NewReader(output[start:])).Decode(&raw); err != nil {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "[") {
+			if suites := parseESLintJSON(raw); suites != nil {
+				return suites
+			}
+			continue
+		}
+		if suites := parseJestJSON(raw, commandLine); suites != nil {
+			return suites
+		}
+	}
+	return nil
+}
+
+// parseESLintJSON 解析 ESLint JSON 格式，每个文件为一个用例，有 error 级别的问题时失败
+func parseESLintJSON(raw json.RawMessage) []*agent.TestSuite {
+	var files []eslintFileResult
+	if err := json.Unmarshal(raw, &files); err != nil || len(files) == 0 || files[0].FilePath == "" {
+		return nil
+	}
+	suite := &agent.TestSuite{Name: testToolESLint, Tool: testToolESLint}
+	for _, file := range files {
+		testCase := &agent.TestCase{Name: filepath.Base(file.FilePath), File: file.FilePath, Status: common.TestStatusPassed}
+		if file.ErrorCount > 0 {
+			testCase.Status = common.TestStatusFailed
+			var messages []string
+			for _, message := range file.Messages {
+				if message.Severity < 2 {
+					continue
+				}
+				messages = append(messages, fmt.Sprintf("%d:%d %s (%s)", message.Line, message.Column, message.Message, message.RuleID))
+			}
+			testCase.Message = truncateTestMessage(strings.Join(messages, "\n"))
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+	return []*agent.TestSuite{suite}
+}
+
+// parseJestJSON 解析 Jest/Vitest JSON 报告，每个测试文件为一个套件；有覆盖率数据时计算语句覆盖率
+func parseJestJSON(raw json.RawMessage, commandLine string) []*agent.TestSuite {
+	var report jestReport
+	if err := json.Unmarshal(raw, &report); err != nil || report.NumTotalTests == nil {
+		return nil
+	}
+	// 通过 npm 脚本执行时命令中没有 vitest，--reporter=json 是 Vitest 特有的参数
+	tool := testToolJest
+	if strings.Contains(commandLine, testToolVitest) || strings.Contains(commandLine, "--reporter=json") {
+		tool = testToolVitest
+	}
+
+	// 覆盖率按源文件的语句统计
+	statements, covered := 0, 0
+	for _, fileCoverage := range report.CoverageMap {
+		for _, count := range fileCoverage.S {
+			statements++
+			if count > 0 {
+				covered++
+			}
+		}
+	}
+
+	suites := make([]*agent.TestSuite, 0, len(report.TestResults))
+	for _, result := range report.TestResults {
+		suite := &agent.TestSuite{Name: result.Name, Tool: tool}
+		if result.EndTime > result.StartTime {
+			suite.DurationMs = result.EndTime - result.StartTime
+		}
+		for _, assertion := range result.AssertionResults {
+			name := assertion.FullName
+			if name == "" {
+				name = assertion.Title
+			}
+			testCase := &agent.TestCase{Name: name, File: result.Name, Status: jestTestStatus(assertion.Status)}
+			if assertion.Duration != nil {
+				testCase.DurationMs = int64(*assertion.Duration)
+			}
+			if testCase.Status == common.TestStatusFailed {
+				testCase.Message = truncateTestMessage(stripANSI(strings.Join(assertion.FailureMessages, "\n")))
+			}
+			suite.Cases = append(suite.Cases, testCase)
+		}
+		// 测试文件本身执行失败（如语法错误）时没有用例
+		if result.Status == common.TestStatusFailed && !hasFailedCase(suite) {
+			suite.Cases = append(suite.Cases, &agent.TestCase{
+				Name:    filepath.Base(result.Name),
+				File:    result.Name,
+				Status:  common.TestStatusFailed,
+				Message: truncateTestMessage(stripANSI(result.Message)),
+			})
+		}
+		suites = append(suites, suite)
+	}
+
+	// 覆盖率不对应某个测试文件，作为单独的套件附在报告中
+	if statements > 0 {
+		coverage := float64(covered) * 100 / float64(statements)
+		suites = append(suites, &agent.TestSuite{Name: "coverage", Tool: tool, Coverage: &coverage})
+	}
+	if len(suites) == 0 {
+		suites = append(suites, &agent.TestSuite{Name: tool, Tool: tool})
+	}
+	return suites
+}
+
+// jestTestStatus Jest/Vitest 的用例状态对应的报告状态
+func jestTestStatus(status string) string {
+	switch status {
+	case common.TestStatusPassed:
+		return common.TestStatusPassed
+	case common.TestStatusFailed:
+		return common.TestStatusFailed
+	default:
+		return common.TestStatusSkipped
+	}
+}
+
+// summarizeTestSuite 统计套件的用例数和状态
+func summarizeTestSuite(suite *agent.TestSuite) {
+	suite.Total, suite.Passed, suite.Failed, suite.Skipped = len(suite.Cases), 0, 0, 0
+	for _, testCase := range suite.Cases {
+		switch testCase.Status {
+		case common.TestStatusPassed:
+			suite.Passed++
+		case common.TestStatusFailed:
+			suite.Failed++
+		default:
+			suite.Skipped++
+		}
+	}
+	suite.Status = common.TestStatusPassed
+	if suite.Failed > 0 {
+		suite.Status = common.TestStatusFailed
+	}
+}
+
+// newTestReport 汇总各套件生成测试报告
+func newTestReport(suites []*agent.TestSuite) *agent.TestReport {
+	report := &agent.TestReport{Status: common.TestStatusPassed, Suites: suites}
+	coverageTotal, coverageCount := 0.0, 0
+	for _, suite := range suites {
+		report.Total += suite.Total
+		report.Passed += suite.Passed
+		report.Failed += suite.Failed
+		report.Skipped += suite.Skipped
+		report.DurationMs += suite.DurationMs
+		if suite.Coverage != nil {
+			coverageTotal += *suite.Coverage
+			coverageCount++
+		}
+	}
+	if report.Failed > 0 {
+		report.Status = common.TestStatusFailed
+	}
+	if coverageCount > 0 {
+		coverage := coverageTotal / float64(coverageCount)
+		report.Coverage = &coverage
+	}
+	return report
+}
+
+// testFailures 失败用例的摘要，交给 Dev Agent 修复；每个用例一行名称，失败原因缩进附在后面，总行数不超过 maxDiagnosticLines
+func testFailures(suites []*agent.TestSuite) []string {
+	var failures []string
+	for _, suite := range suites {
+		for _, testCase := range suite.Cases {
+			if testCase.Status != common.TestStatusFailed {
+				continue
+			}
+			failures = append(failures, fmt.Sprintf("FAIL %s: %s", suite.Name, testCase.Name))
+			for _, line := range strings.Split(tailLines(testCase.Message, diagnosticTailLines), "\n") {
+				if strings.TrimSpace(line) != "" {
+					failures = append(failures, "    "+strings.TrimRight(line, " \t\r"))
+				}
+			}
+			if len(failures) >= maxDiagnosticLines {
+				return failures[:maxDiagnosticLines]
+			}
+		}
+	}
+	return failures
+}
+
+// testReportSummary 测试报告的 Markdown 摘要：总体结果、各套件的用例数和覆盖率、失败用例
+func testReportSummary(language string, report *agent.TestReport) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf(getAgentMessage(language, messageKeyTestReportSummary),
+		report.Total, report.Passed, report.Failed, report.Skipped))
+	if report.Coverage != nil {
+		builder.WriteString(fmt.Sprintf(getAgentMessage(language, messageKeyTestReportCoverage), *report.Coverage))
+	}
+	builder.WriteString("\n\n")
+	for _, suite := range report.Suites {
+		builder.WriteString(fmt.Sprintf("* %s `%s`: %s %d/%d", suite.Component, suite.Name, suite.Status, suite.Passed, suite.Total))
+		if suite.Coverage != nil {
+			builder.WriteString(fmt.Sprintf(", coverage %.1f%%", *suite.Coverage))
+		}
+		builder.WriteString("\n")
+	}
+	if failures := testFailures(report.Suites); len(failures) > 0 {
+		builder.WriteString("\n```\n" + strings.Join(failures, "\n") + "\n```\n")
+	}
+	return builder.String()
+}
+
+// hasFailedCase 套件中是否有失败的用例
+func hasFailedCase(suite *agent.TestSuite) bool {
+	for _, testCase := range suite.Cases {
+		if testCase.Status == common.TestStatusFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// stripANSI 去掉终端颜色控制符
+func stripANSI(text string) string {
+	return ansiEscape.ReplaceAllString(text, "")
+}
+
+// truncateTestMessage 截断过长的失败原因，保留开头
+func truncateTestMessage(message string) string {
+	if len(message) <= maxTestMessageBytes {
+		return message
+	}
+	return strings.ToValidUTF8(message[:maxTestMessageBytes], "") + "\n... (truncated)"
+}
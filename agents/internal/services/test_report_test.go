@@ -0,0 +1,149 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lighthought/app-maker/shared-models/common"
+)
+
+func TestParseGoTestJSON(t *testing.T) {
+	output := `go: downloading github.com/gin-gonic/gin v1.10.0
+{"Action":"start","Package":"demo/internal/handlers"}
+{"Action":"run","Package":"demo/internal/handlers","Test":"TestLogin"}
+{"Action":"output","Package":"demo/internal/handlers","Test":"TestLogin","Output":"    user_test.go:20: status = 500, want 200\n"}
+{"Action":"fail","Package":"demo/internal/handlers","Test":"TestLogin","Elapsed":0.02}
+{"Action":"run","Package":"demo/internal/handlers","Test":"TestLogout"}
+{"Action":"pass","Package":"demo/internal/handlers","Test":"TestLogout","Elapsed":0.01}
+{"Action":"skip","Package":"demo/internal/handlers","Test":"TestAdmin","Elapsed":0}
+{"Action":"output","Package":"demo/internal/handlers","Output":"coverage: 62.5% of statements\n"}
+{"Action":"fail","Package":"demo/internal/handlers","Elapsed":0.35}
+{"Action":"output","Package":"demo/internal/models","Output":"# demo/internal/models\nmodels/user.go:3:2: undefined: foo\n"}
+{"Action":"fail","Package":"demo/internal/models","Elapsed":0}
+`
+	suites := parseTestSuites("backend", "go test -json -cover ./...", output, false, nil)
+	if len(suites) != 2 {
+		t.Fatalf("suites = %+v", suites)
+	}
+
+	handlers := suites[0]
+	if handlers.Name != "demo/internal/handlers" || handlers.Component != "backend" || handlers.Tool != testToolGo {
+		t.Errorf("suite = %+v", handlers)
+	}
+	if handlers.Total != 3 || handlers.Passed != 1 || handlers.Failed != 1 || handlers.Skipped != 1 || handlers.DurationMs != 350 {
+		t.Errorf("suite counts = %+v", handlers)
+	}
+	if handlers.Coverage == nil || *handlers.Coverage != 62.5 {
+		t.Errorf("coverage = %v", handlers.Coverage)
+	}
+	if handlers.Cases[0].Status != common.TestStatusFailed || !strings.Contains(handlers.Cases[0].Message, "status = 500") {
+		t.Errorf("failed case = %+v", handlers.Cases[0])
+	}
+
+	// 编译失败的包没有用例，整个包作为一个失败的用例
+	models := suites[1]
+	if models.Status != common.TestStatusFailed || len(models.Cases) != 1 || !strings.Contains(models.Cases[0].Message, "undefined: foo") {
+		t.Errorf("build failed suite = %+v", models)
+	}
+
+	report := newTestReport(suites)
+	if report.Status != common.TestStatusFailed || report.Total != 4 || report.Failed != 2 || report.Passed != 1 {
+		t.Errorf("report = %+v", report)
+	}
+	failures := testFailures(suites)
+	if len(failures) == 0 || failures[0] != "FAIL demo/internal/handlers: TestLogin" {
+		t.Errorf("testFailures() = %v", failures)
+	}
+}
+
+func TestParseJestAndVitestJSON(t *testing.T) {
+	output := `
+> demo@0.0.0 test
+> vitest --run --reporter=json
+
+{"numTotalTests":3,"testResults":[
+  {"name":"/app/src/App.test.ts","status":"failed","startTime":1000,"endTime":1250,"assertionResults":[
+    {"fullName":"App renders title","status":"passed","duration":12},
+    {"fullName":"App logs in","status":"failed","duration":30,"failureMessages":["\u001b[31mAssertionError: expected 500 to be 200\u001b[39m"]},
+    {"fullName":"App todo","status":"todo"}
+  ]},
+  {"name":"/app/src/Broken.test.ts","status":"failed","message":"SyntaxError: Unexpected token","assertionResults":[]}
+],"coverageMap":{"/app/src/App.vue":{"s":{"0":1,"1":0,"2":3,"3":0}}}}
+`
+	suites := parseTestSuites("frontend", "npm run --silent test -- --run --reporter=json", output, false, nil)
+	if len(suites) != 3 {
+		t.Fatalf("suites = %+v", suites)
+	}
+	app := suites[0]
+	if app.Tool != testToolVitest || app.Total != 3 || app.Passed != 1 || app.Failed != 1 || app.Skipped != 1 || app.DurationMs != 250 {
+		t.Errorf("app suite = %+v", app)
+	}
+	if message := app.Cases[1].Message; message != "AssertionError: expected 500 to be 200" {
+		t.Errorf("failure message = %q", message)
+	}
+	if broken := suites[1]; broken.Failed != 1 || broken.Cases[0].Message != "SyntaxError: Unexpected token" {
+		t.Errorf("broken suite = %+v", broken)
+	}
+	if coverage := suites[2].Coverage; coverage == nil || *coverage != 50 {
+		t.Errorf("coverage = %v", coverage)
+	}
+
+	report := newTestReport(suites)
+	if report.Coverage == nil || *report.Coverage != 50 || report.Failed != 2 {
+		t.Errorf("report = %+v", report)
+	}
+}
+
+func TestParseESLintJSON(t *testing.T) {
+	output := `[{"filePath":"/app/src/App.vue","errorCount":0,"messages":[]},` +
+		`{"filePath":"/app/src/api/user.ts","errorCount":1,"messages":[` +
+		`{"ruleId":"no-unused-vars","severity":2,"message":"'foo' is defined but never used.","line":3,"column":7},` +
+		`{"ruleId":"no-console","severity":1,"message":"Unexpected console statement.","line":9,"column":1}]}]`
+
+	suites := parseTestSuites("frontend", "npm run --silent lint -- -f json", output, false, nil)
+	if len(suites) != 1 || suites[0].Tool != testToolESLint || suites[0].Total != 2 || suites[0].Failed != 1 {
+		t.Fatalf("suites = %+v", suites)
+	}
+	// 只有 error 级别的问题作为失败原因
+	want := "3:7 'foo' is defined but never used. (no-unused-vars)"
+	if testCase := suites[0].Cases[1]; testCase.Name != "user.ts" || testCase.Message != want {
+		t.Errorf("case = %+v", testCase)
+	}
+}
+
+func TestParseUnknownOutput(t *testing.T) {
+	// 不是已知报告格式时整条命令作为一个用例，失败原因为提取到的错误行
+	suites := parseTestSuites("backend", "make test", "ok\n", true, nil)
+	if len(suites) != 1 || suites[0].Tool != testToolCommand || suites[0].Status != common.TestStatusPassed || suites[0].Total != 1 {
+		t.Errorf("passed suites = %+v", suites[0])
+	}
+
+	diagnostics := []string{"make: *** [test] Error 1"}
+	suites = parseTestSuites("backend", "make test", "make: *** [test] Error 1\n", false, diagnostics)
+	if len(suites) != 1 || suites[0].Failed != 1 || suites[0].Cases[0].Message != diagnostics[0] {
+		t.Errorf("failed suites = %+v", suites[0])
+	}
+}
+
+func TestManifestTestCommands(t *testing.T) {
+	manifest := &ProjectManifest{Version: 1, Components: []ManifestComponent{
+		{Name: "api", Path: "api", Lint: []string{"go vet ./..."}, Test: []string{"go test ./..."}, TestReport: []string{"go test -json ./..."}},
+		{Name: "web", Path: "web", Lint: []string{"npm run lint"}, Test: []string{"npm run test"}},
+	}}
+
+	var lines []string
+	for _, command := range manifest.testCommands("p1") {
+		lines = append(lines, command.Component+": "+command.commandLine("p1"))
+	}
+	want := []string{"api: cd api && go test -json ./...", "web: cd web && npm run lint", "web: cd web && npm run test"}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("testCommands() = %v, want %v", lines, want)
+	}
+
+	scripts := map[string]string{"lint": "eslint . --ext .vue,.ts", "test": "vitest"}
+	wantReport := []string{"npm run --silent lint -- -f json", "npm run --silent test -- --run --reporter=json"}
+	if got := detectNpmTestReport(scripts); !reflect.DeepEqual(got, wantReport) {
+		t.Errorf("detectNpmTestReport() = %v, want %v", got, wantReport)
+	}
+}
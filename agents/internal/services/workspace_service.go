@@ -0,0 +1,480 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lighthought/app-maker/agents/internal/config"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/cache"
+	"github.com/lighthought/app-maker/shared-models/common"
+	"github.com/lighthought/app-maker/shared-models/logger"
+	"github.com/lighthought/app-maker/shared-models/utils"
+
+	"github.com/hibiken/asynq"
+)
+
+// WorkspaceService 项目工作区生命周期管理：记录工作区的最近活动，清理闲置工作区的依赖目录，
+// 回收超过 TTL 的工作区，并把所有工作区的磁盘占用控制在预算内
+type WorkspaceService interface {
+	// 使用工作区前调用：已回收的工作区重新克隆，被清理的依赖重新安装，并记录活动时间
+	EnsureWorkspace(ctx context.Context, projectGuid string) error
+
+	// 记录工作区活动时间，工作区不存在时忽略
+	Touch(projectGuid string)
+
+	// 获取所有工作区的磁盘占用和最近活动
+	ListWorkspaces(ctx context.Context) (*agent.WorkspaceListResp, error)
+
+	// 执行一次回收：清理、回收闲置工作区，超出磁盘预算时按最久未使用继续清理、回收
+	Cleanup(ctx context.Context) error
+
+	// 启动定时回收，ctx 取消时停止
+	Start(ctx context.Context)
+}
+
+// workspaceRecord 工作区活动记录，工作区回收后保留，用于重新克隆
+type workspaceRecord struct {
+	LastActiveAt time.Time `json:"last_active_at"`
+	RepoURL      string    `json:"repo_url,omitempty"`
+	Evicted      bool      `json:"evicted,omitempty"`
+	PrunedPaths  []string  `json:"pruned_paths,omitempty"`
+}
+
+// 遍历工作区记录时每次 SCAN 的数量
+const workspaceScanCount = 100
+
+// workspaceUsage 一次回收检查中工作区的状态
+type workspaceUsage struct {
+	projectGuid string
+	record      *workspaceRecord
+	size        int64
+}
+
+type workspaceService struct {
+	commandService CommandService
+	lockService    ProjectLockService
+	cacheInstance  cache.Cache
+	workspacePath  string
+	cfg            config.WorkspaceConfig
+}
+
+// NewWorkspaceService 创建项目工作区生命周期管理服务
+func NewWorkspaceService(commandService CommandService, lockService ProjectLockService, cacheInstance cache.Cache,
+	cfg config.WorkspaceConfig, workspacePath string) WorkspaceService {
+	return &workspaceService{
+		commandService: commandService,
+		lockService:    lockService,
+		cacheInstance:  cacheInstance,
+		workspacePath:  workspacePath,
+		cfg:            cfg,
+	}
+}
+
+// EnsureWorkspace 使用工作区前调用，需要在项目工作区锁内执行
+func (s *workspaceService) EnsureWorkspace(ctx context.Context, projectGuid string) error {
+	record := s.getRecord(projectGuid)
+	projectPath := filepath.Join(s.workspacePath, projectGuid)
+
+	if record != nil && record.Evicted && record.RepoURL != "" {
+		// 回收时中途失败可能留下不完整的目录，删除后重新克隆
+		if err := os.RemoveAll(projectPath); err != nil {
+			return fmt.Errorf("删除不完整的工作区失败: %w", err)
+		}
+		result := s.commandService.SimpleExecute(ctx, "", "git", "clone", record.RepoURL, projectGuid)
+		if !result.Success {
+			return fmt.Errorf("重新克隆工作区失败: %s", result.Error)
+		}
+		s.commandService.SimpleExecute(ctx, projectGuid, "git", "config", "core.autocrlf", "false")
+		record.Evicted = false
+		logger.Info("已重新克隆回收的工作区", logger.String("projectGuid", projectGuid))
+	}
+	if !utils.IsDirectoryExists(projectPath) {
+		return nil // 还没有准备过环境，由 checkGitRepository 克隆
+	}
+	if record == nil {
+		record = &workspaceRecord{}
+	}
+
+	// 重新安装被清理的依赖，失败时保留记录下次再试，后续的构建校验会暴露问题
+	record.PrunedPaths = s.restoreOutputs(ctx, projectGuid, record.PrunedPaths)
+	record.LastActiveAt = utils.GetTimeNow()
+	return s.saveRecord(projectGuid, record)
+}
+
+// Touch 记录工作区活动时间
+func (s *workspaceService) Touch(projectGuid string) {
+	if !utils.IsDirectoryExists(filepath.Join(s.workspacePath, projectGuid)) {
+		return
+	}
+	record := s.getRecord(projectGuid)
+	if record == nil {
+		record = &workspaceRecord{}
+	}
+	record.LastActiveAt = utils.GetTimeNow()
+	if err := s.saveRecord(projectGuid, record); err != nil {
+		logger.Warn("记录工作区活动失败", logger.String("projectGuid", projectGuid), logger.String("error", err.Error()))
+	}
+}
+
+// ListWorkspaces 获取所有工作区的磁盘占用和最近活动，包括已回收的工作区
+func (s *workspaceService) ListWorkspaces(ctx context.Context) (*agent.WorkspaceListResp, error) {
+	usages, err := s.scanWorkspaces()
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &agent.WorkspaceListResp{
+		WorkspacePath:   s.workspacePath,
+		DiskBudgetBytes: s.cfg.DiskBudgetMB * 1024 * 1024,
+		Workspaces:      []*agent.WorkspaceInfo{},
+	}
+	seen := make(map[string]bool)
+	for _, usage := range usages {
+		seen[usage.projectGuid] = true
+		resp.TotalBytes += usage.size
+		resp.Workspaces = append(resp.Workspaces, s.toWorkspaceInfo(usage, common.WorkspaceStatusActive))
+	}
+
+	keys, err := s.cacheInstance.Scan(cache.GetProjectWorkspaceCacheKey("*"), workspaceScanCount)
+	if err != nil {
+		return nil, fmt.Errorf("获取工作区记录失败: %w", err)
+	}
+	for _, key := range keys {
+		projectGuid := strings.TrimSuffix(strings.TrimPrefix(key, "project:"), ":workspace")
+		if seen[projectGuid] {
+			continue
+		}
+		if record := s.getRecord(projectGuid); record != nil && record.Evicted {
+			usage := &workspaceUsage{projectGuid: projectGuid, record: record}
+			resp.Workspaces = append(resp.Workspaces, s.toWorkspaceInfo(usage, common.WorkspaceStatusEvicted))
+		}
+	}
+
+	sort.Slice(resp.Workspaces, func(i, j int) bool {
+		return resp.Workspaces[i].LastActiveAt > resp.Workspaces[j].LastActiveAt
+	})
+	return resp, nil
+}
+
+// toWorkspaceInfo 转换为接口返回的工作区信息
+func (s *workspaceService) toWorkspaceInfo(usage *workspaceUsage, status string) *agent.WorkspaceInfo {
+	return &agent.WorkspaceInfo{
+		ProjectGuid:    usage.projectGuid,
+		Status:         status,
+		DiskUsageBytes: usage.size,
+		LastActiveAt:   usage.record.LastActiveAt.UTC().Format(time.RFC3339),
+		Locked:         s.lockService.GetHolder(usage.projectGuid) != nil,
+		PrunedPaths:    usage.record.PrunedPaths,
+	}
+}
+
+// Cleanup 执行一次回收
+func (s *workspaceService) Cleanup(ctx context.Context) error {
+	usages, err := s.scanWorkspaces()
+	if err != nil {
+		return err
+	}
+
+	// 按最久未使用排序，超出预算时优先处理
+	sort.Slice(usages, func(i, j int) bool {
+		return usages[i].record.LastActiveAt.Before(usages[j].record.LastActiveAt)
+	})
+
+	now := utils.GetTimeNow()
+	var total int64
+	var remaining []*workspaceUsage
+	for _, usage := range usages {
+		idle := now.Sub(usage.record.LastActiveAt)
+		if s.cfg.IdleTTL > 0 && idle > s.cfg.IdleTTL && s.evict(ctx, usage) {
+			continue
+		}
+		if s.cfg.PruneAfter > 0 && idle > s.cfg.PruneAfter {
+			s.prune(ctx, usage)
+		}
+		total += usage.size
+		remaining = append(remaining, usage)
+	}
+
+	budget := s.cfg.DiskBudgetMB * 1024 * 1024
+	if budget <= 0 || total <= budget {
+		return nil
+	}
+
+	// 超出预算：先清理依赖目录，仍然超出时回收工作区，都从最久未使用的开始
+	for _, usage := range remaining {
+		if total <= budget {
+			return nil
+		}
+		before := usage.size
+		s.prune(ctx, usage)
+		total -= before - usage.size
+	}
+	for _, usage := range remaining {
+		if total <= budget {
+			return nil
+		}
+		if s.evict(ctx, usage) {
+			total -= usage.size
+		}
+	}
+	if total > budget {
+		logger.Warn("工作区磁盘占用仍超出预算，正在使用或有未推送提交的工作区不会被回收",
+			logger.String("totalBytes", fmt.Sprint(total)),
+			logger.String("budgetBytes", fmt.Sprint(budget)))
+	}
+	return nil
+}
+
+// Start 启动定时回收
+func (s *workspaceService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.cfg.CleanupInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.Cleanup(ctx); err != nil {
+					logger.Warn("回收项目工作区失败", logger.String("error", err.Error()))
+				}
+			}
+		}
+	}()
+}
+
+// scanWorkspaces 扫描工作区目录下的项目仓库，没有活动记录的工作区以当前时间作为最近活动
+func (s *workspaceService) scanWorkspaces() ([]*workspaceUsage, error) {
+	entries, err := os.ReadDir(s.workspacePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取工作区目录失败: %w", err)
+	}
+
+	var usages []*workspaceUsage
+	for _, entry := range entries {
+		projectPath := filepath.Join(s.workspacePath, entry.Name())
+		// 只管理项目仓库，工作区目录下的其他内容不处理
+		if !entry.IsDir() || !utils.IsDirectoryExists(filepath.Join(projectPath, ".git")) {
+			continue
+		}
+		record := s.getRecord(entry.Name())
+		if record != nil && record.Evicted {
+			continue // 回收中途失败留下的目录，下次使用时重新克隆
+		}
+		if record == nil {
+			record = &workspaceRecord{LastActiveAt: utils.GetTimeNow()}
+			if err := s.saveRecord(entry.Name(), record); err != nil {
+				return nil, err
+			}
+		}
+		usages = append(usages, &workspaceUsage{projectGuid: entry.Name(), record: record, size: dirSize(projectPath)})
+	}
+	return usages, nil
+}
+
+// prune 清理工作区中项目清单声明的 setup 产物，正在使用的工作区跳过
+func (s *workspaceService) prune(ctx context.Context, usage *workspaceUsage) {
+	err := s.runLocked(ctx, usage.projectGuid, func(ctx context.Context) error {
+		projectPath := filepath.Join(s.workspacePath, usage.projectGuid)
+		outputs, err := existingOutputs(projectPath)
+		if err != nil {
+			return err
+		}
+		pruned := false
+		for _, output := range outputs {
+			if err := os.RemoveAll(filepath.Join(projectPath, filepath.FromSlash(output))); err != nil {
+				return fmt.Errorf("删除 %s 失败: %w", output, err)
+			}
+			usage.record.PrunedPaths = appendUnique(usage.record.PrunedPaths, output)
+			pruned = true
+		}
+		if !pruned {
+			return nil
+		}
+		usage.size = dirSize(projectPath)
+		return s.saveRecord(usage.projectGuid, usage.record)
+	})
+	if err != nil {
+		logger.Warn("清理工作区依赖失败", logger.String("projectGuid", usage.projectGuid), logger.String("error", err.Error()))
+	}
+}
+
+// evict 回收工作区，只回收有远程仓库、没有未提交和未推送变更的工作区，正在使用的工作区跳过
+func (s *workspaceService) evict(ctx context.Context, usage *workspaceUsage) bool {
+	err := s.runLocked(ctx, usage.projectGuid, func(ctx context.Context) error {
+		projectGuid := usage.projectGuid
+		remote := s.commandService.SimpleExecute(ctx, projectGuid, "git", "remote", "get-url", "origin")
+		if !remote.Success || strings.TrimSpace(remote.Output) == "" {
+			return fmt.Errorf("没有远程仓库，无法重新克隆")
+		}
+		if status := s.commandService.SimpleExecute(ctx, projectGuid, "git", "status", "--porcelain"); !status.Success || strings.TrimSpace(status.Output) != "" {
+			return fmt.Errorf("有未提交的变更")
+		}
+		if unpushed := s.commandService.SimpleExecute(ctx, projectGuid, "git", "log", "--branches", "--not", "--remotes", "--oneline"); !unpushed.Success || strings.TrimSpace(unpushed.Output) != "" {
+			return fmt.Errorf("有未推送的提交")
+		}
+
+		// 先保存记录再删除目录，删除中途失败时下次使用会重新克隆
+		projectPath := filepath.Join(s.workspacePath, projectGuid)
+		usage.record.RepoURL = strings.TrimSpace(remote.Output)
+		usage.record.Evicted = true
+		// 重新克隆后需要重新执行 setup 的产物，清单无效时只重新克隆
+		outputs, _ := existingOutputs(projectPath)
+		for _, output := range outputs {
+			usage.record.PrunedPaths = appendUnique(usage.record.PrunedPaths, output)
+		}
+		if err := s.saveRecord(projectGuid, usage.record); err != nil {
+			return err
+		}
+		return os.RemoveAll(projectPath)
+	})
+	if err != nil {
+		logger.Info("跳过回收工作区", logger.String("projectGuid", usage.projectGuid), logger.String("reason", err.Error()))
+		return false
+	}
+	logger.Info("已回收闲置工作区",
+		logger.String("projectGuid", usage.projectGuid),
+		logger.String("freedBytes", fmt.Sprint(usage.size)))
+	return true
+}
+
+// runLocked 持有项目工作区锁执行回收操作，避免和正在执行的任务冲突
+func (s *workspaceService) runLocked(ctx context.Context, projectGuid string, fn func(ctx context.Context) error) error {
+	return runWithProjectLock(ctx, s.lockService, projectGuid, "workspace-"+utils.GenerateUUID(), common.TaskTypeWorkspaceCleanup, nil, fn)
+}
+
+// restoreOutputs 重新执行被清理产物所属组件的 setup 命令，返回仍未恢复的产物；
+// 项目清单中已不再声明的产物直接忽略
+func (s *workspaceService) restoreOutputs(ctx context.Context, projectGuid string, prunedPaths []string) []string {
+	if len(prunedPaths) == 0 {
+		return nil
+	}
+	manifest, err := loadProjectManifest(filepath.Join(s.workspacePath, projectGuid))
+	if err != nil {
+		logger.Warn("读取项目清单失败，暂不重新安装工作区依赖", logger.String("projectGuid", projectGuid), logger.String("error", err.Error()))
+		return prunedPaths
+	}
+
+	var remaining []string
+	failed := make(map[string]bool)
+	restored := make(map[string]bool)
+	for _, output := range prunedPaths {
+		component := manifest.componentOfOutput(output)
+		if component == nil || restored[component.Name] {
+			continue
+		}
+		if failed[component.Name] {
+			remaining = append(remaining, output)
+			continue
+		}
+		for _, command := range component.setupCommands(projectGuid) {
+			if result := s.commandService.SimpleExecute(ctx, command.Subfolder, command.Process, command.Args...); !result.Success {
+				logger.Warn("重新安装工作区依赖失败",
+					logger.String("projectGuid", projectGuid),
+					logger.String("command", command.Name),
+					logger.String("error", result.Error))
+				failed[component.Name] = true
+				break
+			}
+		}
+		if failed[component.Name] {
+			remaining = append(remaining, output)
+			continue
+		}
+		restored[component.Name] = true
+	}
+	return remaining
+}
+
+// existingOutputs 获取工作区中已存在的 setup 产物，相对项目根目录
+func existingOutputs(projectPath string) ([]string, error) {
+	manifest, err := loadProjectManifest(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	var outputs []string
+	for i := range manifest.Components {
+		for _, output := range manifest.Components[i].outputPaths() {
+			if _, err := os.Stat(filepath.Join(projectPath, filepath.FromSlash(output))); err == nil {
+				outputs = append(outputs, output)
+			}
+		}
+	}
+	return outputs, nil
+}
+
+// getRecord 获取工作区活动记录，没有记录时返回 nil
+func (s *workspaceService) getRecord(projectGuid string) *workspaceRecord {
+	var record workspaceRecord
+	if err := s.cacheInstance.Get(cache.GetProjectWorkspaceCacheKey(projectGuid), &record); err != nil {
+		return nil
+	}
+	return &record
+}
+
+// saveRecord 保存工作区活动记录，不过期
+func (s *workspaceService) saveRecord(projectGuid string, record *workspaceRecord) error {
+	if err := s.cacheInstance.Set(cache.GetProjectWorkspaceCacheKey(projectGuid), record, 0); err != nil {
+		return fmt.Errorf("保存工作区记录失败: %w", err)
+	}
+	return nil
+}
+
+// dirSize 统计目录下所有文件的大小，不跟随符号链接
+func dirSize(path string) int64 {
+	var size int64
+	filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.Type().IsRegular() {
+			if info, err := entry.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// appendUnique 追加不重复的元素
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// NewWorkspaceMiddleware 创建工作区中间件：在项目工作区锁内执行，任务开始前恢复已回收的工作区，结束后记录活动时间
+func NewWorkspaceMiddleware(workspaceService WorkspaceService) asynq.MiddlewareFunc {
+	return func(next asynq.Handler) asynq.Handler {
+		return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+			var payload struct {
+				ProjectGuid string `json:"project_guid"`
+			}
+			if err := json.Unmarshal(task.Payload(), &payload); err != nil || payload.ProjectGuid == "" {
+				return next.ProcessTask(ctx, task)
+			}
+
+			if err := workspaceService.EnsureWorkspace(ctx, payload.ProjectGuid); err != nil {
+				return fmt.Errorf("恢复项目工作区失败: %w", err)
+			}
+			defer workspaceService.Touch(payload.ProjectGuid)
+			return next.ProcessTask(ctx, task)
+		})
+	}
+}
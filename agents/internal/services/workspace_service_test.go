@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lighthought/app-maker/agents/internal/config"
+
+	"github.com/lighthought/app-maker/shared-models/common"
+)
+
+// newTestWorkspaceService 创建使用临时工作区的工作区服务
+func newTestWorkspaceService(t *testing.T, cfg config.WorkspaceConfig) (*workspaceService, string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	cacheInstance, _ := newTestCache(t)
+	workspace := t.TempDir()
+	commandService := NewCommandService(config.CommandConfig{Timeout: time.Minute}, workspace)
+	service := NewWorkspaceService(commandService, NewProjectLockService(cacheInstance), cacheInstance, cfg, workspace).(*workspaceService)
+	return service, workspace
+}
+
+// testWorkspaceManifest 依赖目录由不需要网络的 mkdir 生成
+const testWorkspaceManifest = `version: 1
+components:
+  - name: frontend
+    path: frontend
+    setup: ["mkdir deps"]
+    outputs: ["deps"]
+`
+
+// addTestWorkspace 在工作区下创建一个推送到本地裸仓库的项目，并安装依赖目录
+func addTestWorkspace(t *testing.T, service *workspaceService, projectGuid string, lastActiveAt time.Time) string {
+	t.Helper()
+	ctx := context.Background()
+	remote := filepath.Join(t.TempDir(), projectGuid+".git")
+	projectPath := filepath.Join(service.workspacePath, projectGuid)
+	if err := os.MkdirAll(filepath.Join(projectPath, "frontend", "deps"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(projectPath, "frontend", "deps", "lib.js"), "module.exports = {}\n")
+	writeTestFile(t, filepath.Join(projectPath, "frontend", "index.js"), "console.log('hi')\n")
+	writeTestFile(t, filepath.Join(projectPath, ".gitignore"), "deps/\n")
+	writeTestFile(t, filepath.Join(projectPath, ProjectManifestFileName), testWorkspaceManifest)
+
+	if result := service.commandService.SimpleExecute(ctx, "", "git", "init", "--bare", remote); !result.Success {
+		t.Fatalf("git init --bare: %s", result.Error)
+	}
+	for _, args := range [][]string{
+		{"init", "-b", "master"},
+		{"add", "-A"},
+		{"commit", "-m", "init"},
+		{"remote", "add", "origin", remote},
+		{"push", "-u", "origin", "master"},
+	} {
+		if result := service.commandService.SimpleExecute(ctx, projectGuid, "git", args...); !result.Success {
+			t.Fatalf("git %v: %s", args, result.Error)
+		}
+	}
+	if err := service.saveRecord(projectGuid, &workspaceRecord{LastActiveAt: lastActiveAt}); err != nil {
+		t.Fatal(err)
+	}
+	return projectPath
+}
+
+func TestWorkspaceServiceEvictAndRestore(t *testing.T) {
+	service, _ := newTestWorkspaceService(t, config.WorkspaceConfig{IdleTTL: time.Hour})
+	ctx := context.Background()
+	projectPath := addTestWorkspace(t, service, "p1", time.Now().Add(-2*time.Hour))
+	addTestWorkspace(t, service, "p2", time.Now())
+
+	if err := service.Cleanup(ctx); err != nil {
+		t.Fatalf("Cleanup() err = %v", err)
+	}
+	if _, err := os.Stat(projectPath); !os.IsNotExist(err) {
+		t.Fatalf("idle workspace should be evicted, stat err = %v", err)
+	}
+
+	resp, err := service.ListWorkspaces(ctx)
+	if err != nil {
+		t.Fatalf("ListWorkspaces() err = %v", err)
+	}
+	if len(resp.Workspaces) != 2 {
+		t.Fatalf("workspaces = %+v", resp.Workspaces)
+	}
+	// 按最近活动倒序，回收的工作区排在最后
+	evicted := resp.Workspaces[1]
+	if resp.Workspaces[0].ProjectGuid != "p2" || resp.Workspaces[0].Status != common.WorkspaceStatusActive ||
+		evicted.ProjectGuid != "p1" || evicted.Status != common.WorkspaceStatusEvicted || evicted.DiskUsageBytes != 0 {
+		t.Errorf("workspaces = %+v, %+v", resp.Workspaces[0], evicted)
+	}
+	if resp.TotalBytes != resp.Workspaces[0].DiskUsageBytes {
+		t.Errorf("total = %d, want %d", resp.TotalBytes, resp.Workspaces[0].DiskUsageBytes)
+	}
+
+	// 再次使用时重新克隆并重新安装依赖
+	if err := service.EnsureWorkspace(ctx, "p1"); err != nil {
+		t.Fatalf("EnsureWorkspace() err = %v", err)
+	}
+	for _, path := range []string{"frontend/index.js", "frontend/deps"} {
+		if _, err := os.Stat(filepath.Join(projectPath, filepath.FromSlash(path))); err != nil {
+			t.Errorf("%s not restored: %v", path, err)
+		}
+	}
+	record := service.getRecord("p1")
+	if record == nil || record.Evicted || len(record.PrunedPaths) != 0 || time.Since(record.LastActiveAt) > time.Minute {
+		t.Errorf("record after restore = %+v", record)
+	}
+}
+
+func TestWorkspaceServiceKeepsUnpushedWork(t *testing.T) {
+	service, _ := newTestWorkspaceService(t, config.WorkspaceConfig{IdleTTL: time.Hour, PruneAfter: time.Hour})
+	ctx := context.Background()
+	projectPath := addTestWorkspace(t, service, "p1", time.Now().Add(-2*time.Hour))
+	if result := service.commandService.SimpleExecute(ctx, "p1", "git", "commit", "--allow-empty", "-m", "local"); !result.Success {
+		t.Fatalf("git commit: %s", result.Error)
+	}
+
+	if err := service.Cleanup(ctx); err != nil {
+		t.Fatalf("Cleanup() err = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(projectPath, ".git")); err != nil {
+		t.Fatalf("workspace with unpushed commits should be kept: %v", err)
+	}
+	// 不能回收时仍然清理依赖目录
+	if _, err := os.Stat(filepath.Join(projectPath, "frontend", "deps")); !os.IsNotExist(err) {
+		t.Errorf("dependencies should be pruned, stat err = %v", err)
+	}
+	if record := service.getRecord("p1"); record == nil || len(record.PrunedPaths) != 1 || record.Evicted {
+		t.Errorf("record = %+v", record)
+	}
+}
+
+func TestWorkspaceServiceDiskBudget(t *testing.T) {
+	service, _ := newTestWorkspaceService(t, config.WorkspaceConfig{DiskBudgetMB: 1})
+	ctx := context.Background()
+	oldest := addTestWorkspace(t, service, "p1", time.Now().Add(-2*time.Hour))
+	newest := addTestWorkspace(t, service, "p2", time.Now())
+	// 清理依赖目录后两个工作区合计仍超出 1MB 预算，任意一个单独不超出
+	for _, path := range []string{oldest, newest} {
+		writeTestFile(t, filepath.Join(path, "frontend", "deps", "bundle.js"), string(make([]byte, 400*1024)))
+		writeTestFile(t, filepath.Join(path, "data.bin"), string(make([]byte, 600*1024)))
+		if result := service.commandService.SimpleExecute(ctx, filepath.Base(path), "git", "add", "-A"); !result.Success {
+			t.Fatalf("git add: %s", result.Error)
+		}
+		for _, args := range [][]string{{"commit", "-m", "data"}, {"push"}} {
+			if result := service.commandService.SimpleExecute(ctx, filepath.Base(path), "git", args...); !result.Success {
+				t.Fatalf("git %v: %s", args, result.Error)
+			}
+		}
+	}
+
+	if err := service.Cleanup(ctx); err != nil {
+		t.Fatalf("Cleanup() err = %v", err)
+	}
+	if _, err := os.Stat(oldest); !os.IsNotExist(err) {
+		t.Errorf("least recently used workspace should be evicted, stat err = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(newest, "data.bin")); err != nil {
+		t.Errorf("recent workspace should be kept: %v", err)
+	}
+}
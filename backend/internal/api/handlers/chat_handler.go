@@ -211,6 +211,7 @@ func (h *ChatHandler) SendMessageToAgent(c *gin.Context) {
 		ProjectGuid: projectGuid,
 		AgentType:   req.AgentType,
 		Message:     req.Content,
+		Language:    project.Language,
 	}
 
 	_, err = h.asyncClientService.EnqueueAgentChatTask(chatReq)
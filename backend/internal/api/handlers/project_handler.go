@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -21,18 +22,45 @@ type ProjectHandler struct {
 	asyncClientService services.AsyncClientService
 	commonService      services.ProjectCommonService
 	previewService     services.PreviewService
+	agentService       services.AgentInteractService
+	devService         services.ProjectDevService
+	usageService       services.UsageService
+	promptService      services.PromptService
+	commitService      services.CommitService
+	deploymentService  services.DeploymentService
+	reportService      services.TestReportService
+	qaService          services.QAReviewService
+	reviewService      services.CodeReviewService
 }
 
 // NewProjectHandler 创建项目处理器实例
 func NewProjectHandler(projectService services.ProjectService,
 	asyncClientService services.AsyncClientService,
 	commonService services.ProjectCommonService,
-	previewService services.PreviewService) *ProjectHandler {
+	previewService services.PreviewService,
+	agentService services.AgentInteractService,
+	devService services.ProjectDevService,
+	usageService services.UsageService,
+	promptService services.PromptService,
+	commitService services.CommitService,
+	deploymentService services.DeploymentService,
+	reportService services.TestReportService,
+	qaService services.QAReviewService,
+	reviewService services.CodeReviewService) *ProjectHandler {
 	return &ProjectHandler{
 		projectService:     projectService,
 		asyncClientService: asyncClientService,
 		commonService:      commonService,
 		previewService:     previewService,
+		agentService:       agentService,
+		devService:         devService,
+		usageService:       usageService,
+		promptService:      promptService,
+		commitService:      commitService,
+		deploymentService:  deploymentService,
+		reportService:      reportService,
+		qaService:          qaService,
+		reviewService:      reviewService,
 	}
 }
 
@@ -73,6 +101,16 @@ func (h *ProjectHandler) CreateProject(c *gin.Context) {
 	userID := c.GetString("user_id")
 	logger.Info("获取用户ID", logger.String("userID", userID))
 
+	// 本月用量超出预算时不允许创建新项目
+	if err := h.usageService.CheckBudget(c.Request.Context(), userID); err != nil {
+		if err.Error() == common.MESSAGE_INSUFFICIENT_QUOTA {
+			c.JSON(http.StatusOK, utils.GetErrorResponse(common.INSUFFICIENT_QUOTA, "创建项目失败: "+err.Error()))
+			return
+		}
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "创建项目失败: "+err.Error()))
+		return
+	}
+
 	project, err := h.projectService.CreateProject(c.Request.Context(), &req, userID)
 	if err != nil {
 		logger.Error("创建项目失败",
@@ -367,13 +405,14 @@ func (h *ProjectHandler) DownloadProject(c *gin.Context) {
 
 // DeployProject godoc
 // @Summary 部署项目
-// @Description 部署指定项目
+// @Description 创建部署记录并部署 Agent 工作区主干的最新提交，可选部署环境，默认 dev
 // @Tags 项目管理
 // @Accept json
 // @Produce json
 // @Security Bearer
 // @Param guid path string true "项目GUID"
-// @Success 200 {object} common.Response "项目部署成功"
+// @Param request body models.DeployProjectRequest false "部署选项"
+// @Success 200 {object} common.Response{data=models.Deployment} "项目部署成功"
 // @Failure 400 {object} common.ErrorResponse "请求参数错误"
 // @Failure 401 {object} common.ErrorResponse "未授权"
 // @Failure 403 {object} common.ErrorResponse "访问被拒绝"
@@ -386,11 +425,19 @@ func (h *ProjectHandler) DeployProject(c *gin.Context) {
 		return
 	}
 
+	var req models.DeployProjectRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, utils.GetErrorResponse(common.VALIDATION_ERROR, "请求参数错误: "+err.Error()))
+			return
+		}
+	}
+
 	// 从中间件获取用户ID
 	userID := c.GetString("user_id")
 
 	// 验证用户权限
-	_, err := h.projectService.CheckProjectAccess(c.Request.Context(), projectGuid, userID)
+	project, err := h.projectService.CheckProjectAccess(c.Request.Context(), projectGuid, userID)
 	if err != nil {
 		if err.Error() == common.MESSAGE_ACCESS_DENIED {
 			c.JSON(http.StatusForbidden, utils.GetErrorResponse(common.FORBIDDEN, "访问被拒绝"))
@@ -400,12 +447,235 @@ func (h *ProjectHandler) DeployProject(c *gin.Context) {
 		return
 	}
 
-	taskID, err := h.asyncClientService.EnqueueProjectDeployTask(projectGuid, "dev")
+	deployment, err := h.deploymentService.Deploy(c.Request.Context(), project, userID, req.Environment)
+	if errors.Is(err, services.ErrQAGateFailed) {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.DEPLOYMENT_ERROR, "创建部署项目任务失败: "+err.Error()))
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(common.INTERNAL_ERROR, "创建部署项目任务失败: "+err.Error()))
 		return
 	}
-	c.JSON(http.StatusOK, utils.GetSuccessResponse("创建部署项目任务成功", taskID))
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("创建部署项目任务成功", deployment))
+}
+
+// GetProjectDeployments godoc
+// @Summary 获取项目部署历史
+// @Description 获取项目最近的部署记录，包括环境、提交、状态、耗时、访问地址和日志，按时间倒序
+// @Tags 项目管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param guid path string true "项目GUID"
+// @Param environment query string false "部署环境：dev、staging、prod，为空时返回所有环境"
+// @Success 200 {object} common.Response{data=[]models.Deployment} "获取部署历史成功"
+// @Failure 400 {object} common.ErrorResponse "请求参数错误"
+// @Failure 401 {object} common.ErrorResponse "未授权"
+// @Failure 403 {object} common.ErrorResponse "访问被拒绝"
+// @Failure 500 {object} common.ErrorResponse "服务器内部错误"
+// @Router /api/v1/projects/{guid}/deployments [get]
+func (h *ProjectHandler) GetProjectDeployments(c *gin.Context) {
+	projectGuid := c.Param("guid")
+	if projectGuid == "" {
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(common.VALIDATION_ERROR, "项目GUID不能为空"))
+		return
+	}
+
+	// 验证用户权限
+	project, err := h.projectService.CheckProjectAccess(c.Request.Context(), projectGuid, c.GetString("user_id"))
+	if err != nil {
+		if err.Error() == common.MESSAGE_ACCESS_DENIED {
+			c.JSON(http.StatusForbidden, utils.GetErrorResponse(common.FORBIDDEN, "访问被拒绝"))
+			return
+		}
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "获取项目信息失败: "+err.Error()))
+		return
+	}
+
+	deployments, err := h.deploymentService.ListDeployments(c.Request.Context(), project, c.Query("environment"))
+	if err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "获取部署历史失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("获取部署历史成功", deployments))
+}
+
+// RedeployDeployment godoc
+// @Summary 重新部署历史提交
+// @Description Agents 服务检出历史部署的提交并重新部署到同一环境，完成后恢复到主干，创建新的部署记录
+// @Tags 项目管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param guid path string true "项目GUID"
+// @Param id path string true "部署记录ID"
+// @Success 200 {object} common.Response{data=models.Deployment} "创建重新部署任务成功"
+// @Failure 400 {object} common.ErrorResponse "请求参数错误"
+// @Failure 401 {object} common.ErrorResponse "未授权"
+// @Failure 403 {object} common.ErrorResponse "访问被拒绝"
+// @Failure 500 {object} common.ErrorResponse "服务器内部错误"
+// @Router /api/v1/projects/{guid}/deployments/{id}/redeploy [post]
+func (h *ProjectHandler) RedeployDeployment(c *gin.Context) {
+	projectGuid := c.Param("guid")
+	deploymentID := c.Param("id")
+	if projectGuid == "" || deploymentID == "" {
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(common.VALIDATION_ERROR, "项目GUID和部署记录ID不能为空"))
+		return
+	}
+
+	// 从中间件获取用户ID
+	userID := c.GetString("user_id")
+
+	// 验证用户权限
+	project, err := h.projectService.CheckProjectAccess(c.Request.Context(), projectGuid, userID)
+	if err != nil {
+		if err.Error() == common.MESSAGE_ACCESS_DENIED {
+			c.JSON(http.StatusForbidden, utils.GetErrorResponse(common.FORBIDDEN, "访问被拒绝"))
+			return
+		}
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "获取项目信息失败: "+err.Error()))
+		return
+	}
+
+	deployment, err := h.deploymentService.Redeploy(c.Request.Context(), project, userID, deploymentID)
+	if err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "创建重新部署任务失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("创建重新部署任务成功", deployment))
+}
+
+// GetProjectTestReports godoc
+// @Summary 获取项目测试报告
+// @Description 获取项目最近的自动测试报告，包括各测试套件、用例、失败原因、耗时和覆盖率，按时间倒序
+// @Tags 项目管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param guid path string true "项目GUID"
+// @Param dev_stage query string false "开发阶段，如 run_test，为空时返回所有阶段"
+// @Success 200 {object} common.Response{data=[]models.TestReport} "获取测试报告成功"
+// @Failure 400 {object} common.ErrorResponse "请求参数错误"
+// @Failure 401 {object} common.ErrorResponse "未授权"
+// @Failure 403 {object} common.ErrorResponse "访问被拒绝"
+// @Failure 500 {object} common.ErrorResponse "服务器内部错误"
+// @Router /api/v1/projects/{guid}/test-reports [get]
+func (h *ProjectHandler) GetProjectTestReports(c *gin.Context) {
+	projectGuid := c.Param("guid")
+	if projectGuid == "" {
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(common.VALIDATION_ERROR, "项目GUID不能为空"))
+		return
+	}
+
+	// 验证用户权限
+	project, err := h.projectService.CheckProjectAccess(c.Request.Context(), projectGuid, c.GetString("user_id"))
+	if err != nil {
+		if err.Error() == common.MESSAGE_ACCESS_DENIED {
+			c.JSON(http.StatusForbidden, utils.GetErrorResponse(common.FORBIDDEN, "访问被拒绝"))
+			return
+		}
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "获取项目信息失败: "+err.Error()))
+		return
+	}
+
+	reports, err := h.reportService.ListTestReports(c.Request.Context(), project, c.Query("dev_stage"))
+	if err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "获取测试报告失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("获取测试报告成功", reports))
+}
+
+// GetProjectQAReviews godoc
+// @Summary 获取项目 QA 评审
+// @Description 获取项目用户故事的 QA 评审，包括门禁结论 pass/concerns/fail、原因和主要问题；latest 为 true 时只返回每个故事最新的评审
+// @Tags 项目管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param guid path string true "项目GUID"
+// @Param latest query bool false "是否只返回每个故事最新的评审" default(false)
+// @Success 200 {object} common.Response{data=[]models.QAReview} "获取 QA 评审成功"
+// @Failure 400 {object} common.ErrorResponse "请求参数错误"
+// @Failure 401 {object} common.ErrorResponse "未授权"
+// @Failure 403 {object} common.ErrorResponse "访问被拒绝"
+// @Failure 500 {object} common.ErrorResponse "服务器内部错误"
+// @Router /api/v1/projects/{guid}/qa-reviews [get]
+func (h *ProjectHandler) GetProjectQAReviews(c *gin.Context) {
+	projectGuid := c.Param("guid")
+	if projectGuid == "" {
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(common.VALIDATION_ERROR, "项目GUID不能为空"))
+		return
+	}
+
+	// 验证用户权限
+	project, err := h.projectService.CheckProjectAccess(c.Request.Context(), projectGuid, c.GetString("user_id"))
+	if err != nil {
+		if err.Error() == common.MESSAGE_ACCESS_DENIED {
+			c.JSON(http.StatusForbidden, utils.GetErrorResponse(common.FORBIDDEN, "访问被拒绝"))
+			return
+		}
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "获取项目信息失败: "+err.Error()))
+		return
+	}
+
+	var reviews []*models.QAReview
+	if c.Query("latest") == "true" {
+		reviews, err = h.qaService.ListLatestReviews(c.Request.Context(), project)
+	} else {
+		reviews, err = h.qaService.ListQAReviews(c.Request.Context(), project)
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "获取 QA 评审失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("获取 QA 评审成功", reviews))
+}
+
+// GetProjectReviewComments godoc
+// @Summary 获取项目代码评审意见
+// @Description 获取评审 Dev Agent 提交的代码评审意见，包括文件、行号、严重程度、意见和是否阻塞；story_number 不为空时只返回该故事的意见
+// @Tags 项目管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param guid path string true "项目GUID"
+// @Param story_number query string false "故事编号" example(1.2)
+// @Success 200 {object} common.Response{data=[]models.ReviewComment} "获取代码评审意见成功"
+// @Failure 400 {object} common.ErrorResponse "请求参数错误"
+// @Failure 401 {object} common.ErrorResponse "未授权"
+// @Failure 403 {object} common.ErrorResponse "访问被拒绝"
+// @Failure 500 {object} common.ErrorResponse "服务器内部错误"
+// @Router /api/v1/projects/{guid}/review-comments [get]
+func (h *ProjectHandler) GetProjectReviewComments(c *gin.Context) {
+	projectGuid := c.Param("guid")
+	if projectGuid == "" {
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(common.VALIDATION_ERROR, "项目GUID不能为空"))
+		return
+	}
+
+	// 验证用户权限
+	project, err := h.projectService.CheckProjectAccess(c.Request.Context(), projectGuid, c.GetString("user_id"))
+	if err != nil {
+		if err.Error() == common.MESSAGE_ACCESS_DENIED {
+			c.JSON(http.StatusForbidden, utils.GetErrorResponse(common.FORBIDDEN, "访问被拒绝"))
+			return
+		}
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "获取项目信息失败: "+err.Error()))
+		return
+	}
+
+	comments, err := h.reviewService.ListReviewComments(c.Request.Context(), project, c.Query("story_number"))
+	if err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "获取代码评审意见失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("获取代码评审意见成功", comments))
 }
 
 // GeneratePreviewLink godoc
@@ -515,3 +785,481 @@ func (h *ProjectHandler) GetPreviewByToken(c *gin.Context) {
 		"message":      "项目预览暂未部署",
 	}))
 }
+
+// GetProjectAgentLogs godoc
+// @Summary 获取项目 Agent 输出日志
+// @Description 获取项目最近的 Agent CLI 输出日志，用于 WebSocket 重连后补齐实时日志
+// @Tags 项目管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param guid path string true "项目GUID"
+// @Param task_id query string false "Agent 任务ID，为空时返回项目下所有任务的日志"
+// @Param limit query int false "返回的最大行数" default(500)
+// @Success 200 {object} common.Response{data=[]agent.AgentLogMessage} "获取日志成功"
+// @Failure 400 {object} common.ErrorResponse "请求参数错误"
+// @Failure 401 {object} common.ErrorResponse "未授权"
+// @Failure 403 {object} common.ErrorResponse "访问被拒绝"
+// @Failure 500 {object} common.ErrorResponse "服务器内部错误"
+// @Router /api/v1/projects/{guid}/agent-logs [get]
+func (h *ProjectHandler) GetProjectAgentLogs(c *gin.Context) {
+	projectGuid := c.Param("guid")
+	if projectGuid == "" {
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(common.VALIDATION_ERROR, "项目GUID不能为空"))
+		return
+	}
+
+	// 验证用户权限
+	_, err := h.projectService.CheckProjectAccess(c.Request.Context(), projectGuid, c.GetString("user_id"))
+	if err != nil {
+		if err.Error() == common.MESSAGE_ACCESS_DENIED {
+			c.JSON(http.StatusForbidden, utils.GetErrorResponse(common.FORBIDDEN, "访问被拒绝"))
+			return
+		}
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "获取项目信息失败: "+err.Error()))
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(common.AgentLogBufferSize)))
+	if err != nil {
+		limit = common.AgentLogBufferSize
+	}
+
+	logs, err := h.agentService.GetProjectAgentLogs(c.Request.Context(), projectGuid, c.Query("task_id"), limit)
+	if err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "获取 Agent 日志失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("获取 Agent 日志成功", logs))
+}
+
+// CancelProject godoc
+// @Summary 取消项目当前阶段
+// @Description 终止项目当前阶段正在执行的 Agent 任务，并将阶段和项目置为失败
+// @Tags 项目管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param guid path string true "项目GUID"
+// @Success 200 {object} common.Response "取消成功"
+// @Failure 400 {object} common.ErrorResponse "请求参数错误"
+// @Failure 401 {object} common.ErrorResponse "未授权"
+// @Failure 403 {object} common.ErrorResponse "访问被拒绝"
+// @Failure 500 {object} common.ErrorResponse "服务器内部错误"
+// @Router /api/v1/projects/{guid}/cancel [post]
+func (h *ProjectHandler) CancelProject(c *gin.Context) {
+	projectGuid := c.Param("guid")
+	if projectGuid == "" {
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(common.VALIDATION_ERROR, "项目GUID不能为空"))
+		return
+	}
+
+	// 验证用户权限
+	project, err := h.projectService.CheckProjectAccess(c.Request.Context(), projectGuid, c.GetString("user_id"))
+	if err != nil {
+		if err.Error() == common.MESSAGE_ACCESS_DENIED {
+			c.JSON(http.StatusForbidden, utils.GetErrorResponse(common.FORBIDDEN, "访问被拒绝"))
+			return
+		}
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "获取项目信息失败: "+err.Error()))
+		return
+	}
+
+	if err := h.devService.CancelCurrentStage(c.Request.Context(), project); err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "取消项目失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("取消项目成功", projectGuid))
+}
+
+// RollbackStage godoc
+// @Summary 回滚项目到阶段执行前
+// @Description 将 Agents 工作区和后端仓库强制重置到阶段执行前的提交并推送，该阶段及之后的阶段重置为待执行，可选重新执行该阶段
+// @Tags 项目管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param guid path string true "项目GUID"
+// @Param stageId path string true "阶段ID"
+// @Param request body models.RollbackStageRequest false "回滚选项"
+// @Success 200 {object} common.Response "回滚成功"
+// @Failure 400 {object} common.ErrorResponse "请求参数错误"
+// @Failure 401 {object} common.ErrorResponse "未授权"
+// @Failure 403 {object} common.ErrorResponse "访问被拒绝"
+// @Failure 500 {object} common.ErrorResponse "服务器内部错误"
+// @Router /api/v1/projects/{guid}/stages/{stageId}/rollback [post]
+func (h *ProjectHandler) RollbackStage(c *gin.Context) {
+	projectGuid := c.Param("guid")
+	stageID := c.Param("stageId")
+	if projectGuid == "" || stageID == "" {
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(common.VALIDATION_ERROR, "项目GUID和阶段ID不能为空"))
+		return
+	}
+
+	var req models.RollbackStageRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, utils.GetErrorResponse(common.VALIDATION_ERROR, "请求参数错误: "+err.Error()))
+			return
+		}
+	}
+
+	// 验证用户权限
+	project, err := h.projectService.CheckProjectAccess(c.Request.Context(), projectGuid, c.GetString("user_id"))
+	if err != nil {
+		if err.Error() == common.MESSAGE_ACCESS_DENIED {
+			c.JSON(http.StatusForbidden, utils.GetErrorResponse(common.FORBIDDEN, "访问被拒绝"))
+			return
+		}
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "获取项目信息失败: "+err.Error()))
+		return
+	}
+
+	if err := h.devService.RollbackStage(c.Request.Context(), project, stageID, req.Rerun); err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "回滚项目失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("回滚项目成功", projectGuid))
+}
+
+// GetAgentSessions godoc
+// @Summary 获取项目 Agent 会话列表
+// @Description 获取项目下各 Agent 的会话信息，支持原生会话的 CLI 显示会话ID，其余显示对话记录轮数
+// @Tags 项目管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param guid path string true "项目GUID"
+// @Success 200 {object} common.Response{data=[]agent.AgentSessionInfo} "获取会话列表成功"
+// @Failure 400 {object} common.ErrorResponse "请求参数错误"
+// @Failure 401 {object} common.ErrorResponse "未授权"
+// @Failure 403 {object} common.ErrorResponse "访问被拒绝"
+// @Failure 500 {object} common.ErrorResponse "服务器内部错误"
+// @Router /api/v1/projects/{guid}/agent-sessions [get]
+func (h *ProjectHandler) GetAgentSessions(c *gin.Context) {
+	projectGuid := c.Param("guid")
+	if projectGuid == "" {
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(common.VALIDATION_ERROR, "项目GUID不能为空"))
+		return
+	}
+
+	// 验证用户权限
+	_, err := h.projectService.CheckProjectAccess(c.Request.Context(), projectGuid, c.GetString("user_id"))
+	if err != nil {
+		if err.Error() == common.MESSAGE_ACCESS_DENIED {
+			c.JSON(http.StatusForbidden, utils.GetErrorResponse(common.FORBIDDEN, "访问被拒绝"))
+			return
+		}
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "获取项目信息失败: "+err.Error()))
+		return
+	}
+
+	sessions, err := h.agentService.ListAgentSessions(c.Request.Context(), projectGuid)
+	if err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "获取会话列表失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("获取会话列表成功", sessions))
+}
+
+// ResetAgentSessions godoc
+// @Summary 重置项目 Agent 会话
+// @Description 清除 Agent 的会话和对话记录，agent_type 为空时重置项目下所有 Agent 的会话
+// @Tags 项目管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param guid path string true "项目GUID"
+// @Param agent_type query string false "Agent 类型"
+// @Success 200 {object} common.Response "重置会话成功"
+// @Failure 400 {object} common.ErrorResponse "请求参数错误"
+// @Failure 401 {object} common.ErrorResponse "未授权"
+// @Failure 403 {object} common.ErrorResponse "访问被拒绝"
+// @Failure 500 {object} common.ErrorResponse "服务器内部错误"
+// @Router /api/v1/projects/{guid}/agent-sessions [delete]
+func (h *ProjectHandler) ResetAgentSessions(c *gin.Context) {
+	projectGuid := c.Param("guid")
+	if projectGuid == "" {
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(common.VALIDATION_ERROR, "项目GUID不能为空"))
+		return
+	}
+
+	// 验证用户权限
+	_, err := h.projectService.CheckProjectAccess(c.Request.Context(), projectGuid, c.GetString("user_id"))
+	if err != nil {
+		if err.Error() == common.MESSAGE_ACCESS_DENIED {
+			c.JSON(http.StatusForbidden, utils.GetErrorResponse(common.FORBIDDEN, "访问被拒绝"))
+			return
+		}
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "获取项目信息失败: "+err.Error()))
+		return
+	}
+
+	if err := h.agentService.ResetAgentSession(c.Request.Context(), projectGuid, c.Query("agent_type")); err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "重置会话失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("重置会话成功", projectGuid))
+}
+
+// GetProjectUsage godoc
+// @Summary 获取项目用量
+// @Description 获取项目 Agent 任务的 token 和费用用量，包含累计、按开发阶段汇总和每个任务的用量
+// @Tags 项目管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param guid path string true "项目GUID"
+// @Success 200 {object} common.Response{data=models.ProjectUsageResponse} "获取项目用量成功"
+// @Failure 400 {object} common.ErrorResponse "请求参数错误"
+// @Failure 401 {object} common.ErrorResponse "未授权"
+// @Failure 403 {object} common.ErrorResponse "访问被拒绝"
+// @Failure 500 {object} common.ErrorResponse "服务器内部错误"
+// @Router /api/v1/projects/{guid}/usage [get]
+func (h *ProjectHandler) GetProjectUsage(c *gin.Context) {
+	projectGuid := c.Param("guid")
+	if projectGuid == "" {
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(common.VALIDATION_ERROR, "项目GUID不能为空"))
+		return
+	}
+
+	// 验证用户权限
+	project, err := h.projectService.CheckProjectAccess(c.Request.Context(), projectGuid, c.GetString("user_id"))
+	if err != nil {
+		if err.Error() == common.MESSAGE_ACCESS_DENIED {
+			c.JSON(http.StatusForbidden, utils.GetErrorResponse(common.FORBIDDEN, "访问被拒绝"))
+			return
+		}
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "获取项目信息失败: "+err.Error()))
+		return
+	}
+
+	usage, err := h.usageService.GetProjectUsage(c.Request.Context(), project)
+	if err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "获取项目用量失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("获取项目用量成功", usage))
+}
+
+// GetProjectCommits godoc
+// @Summary 获取项目提交历史
+// @Description 获取项目的提交历史，根据提交信息中的 App-Maker-* trailer 按开发阶段和故事分组，包含变更统计
+// @Tags 项目管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param guid path string true "项目GUID"
+// @Success 200 {object} common.Response{data=models.ProjectCommitsResponse} "获取提交历史成功"
+// @Failure 400 {object} common.ErrorResponse "请求参数错误"
+// @Failure 401 {object} common.ErrorResponse "未授权"
+// @Failure 403 {object} common.ErrorResponse "访问被拒绝"
+// @Failure 500 {object} common.ErrorResponse "服务器内部错误"
+// @Router /api/v1/projects/{guid}/commits [get]
+func (h *ProjectHandler) GetProjectCommits(c *gin.Context) {
+	projectGuid := c.Param("guid")
+	if projectGuid == "" {
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(common.VALIDATION_ERROR, "项目GUID不能为空"))
+		return
+	}
+
+	// 验证用户权限
+	project, err := h.projectService.CheckProjectAccess(c.Request.Context(), projectGuid, c.GetString("user_id"))
+	if err != nil {
+		if err.Error() == common.MESSAGE_ACCESS_DENIED {
+			c.JSON(http.StatusForbidden, utils.GetErrorResponse(common.FORBIDDEN, "访问被拒绝"))
+			return
+		}
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "获取项目信息失败: "+err.Error()))
+		return
+	}
+
+	commits, err := h.commitService.GetProjectCommits(c.Request.Context(), project)
+	if err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "获取提交历史失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("获取提交历史成功", commits))
+}
+
+// GetProjectPrompts godoc
+// @Summary 获取项目提示词模板列表
+// @Description 获取项目使用的所有提示词模板，项目覆盖的模板优先于内置模板
+// @Tags 项目管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param guid path string true "项目GUID"
+// @Success 200 {object} common.Response "获取提示词模板成功"
+// @Failure 400 {object} common.ErrorResponse "请求参数错误"
+// @Failure 401 {object} common.ErrorResponse "未授权"
+// @Failure 403 {object} common.ErrorResponse "访问被拒绝"
+// @Failure 500 {object} common.ErrorResponse "服务器内部错误"
+// @Router /api/v1/projects/{guid}/prompts [get]
+func (h *ProjectHandler) GetProjectPrompts(c *gin.Context) {
+	projectGuid := c.Param("guid")
+	if projectGuid == "" {
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(common.VALIDATION_ERROR, "项目GUID不能为空"))
+		return
+	}
+
+	// 验证用户权限
+	project, err := h.projectService.CheckProjectAccess(c.Request.Context(), projectGuid, c.GetString("user_id"))
+	if err != nil {
+		if err.Error() == common.MESSAGE_ACCESS_DENIED {
+			c.JSON(http.StatusForbidden, utils.GetErrorResponse(common.FORBIDDEN, "访问被拒绝"))
+			return
+		}
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "获取项目信息失败: "+err.Error()))
+		return
+	}
+
+	prompts, err := h.promptService.ListProjectPrompts(c.Request.Context(), project)
+	if err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "获取提示词模板失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("获取提示词模板成功", prompts))
+}
+
+// GetProjectPrompt godoc
+// @Summary 获取项目提示词模板
+// @Description 获取项目使用的提示词模板，项目未覆盖时返回内置模板
+// @Tags 项目管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param guid path string true "项目GUID"
+// @Param name path string true "模板名称，如 dev_implement_story"
+// @Success 200 {object} common.Response "获取提示词模板成功"
+// @Failure 400 {object} common.ErrorResponse "请求参数错误"
+// @Failure 401 {object} common.ErrorResponse "未授权"
+// @Failure 403 {object} common.ErrorResponse "访问被拒绝"
+// @Failure 500 {object} common.ErrorResponse "服务器内部错误"
+// @Router /api/v1/projects/{guid}/prompts/{name} [get]
+func (h *ProjectHandler) GetProjectPrompt(c *gin.Context) {
+	projectGuid := c.Param("guid")
+	if projectGuid == "" {
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(common.VALIDATION_ERROR, "项目GUID不能为空"))
+		return
+	}
+
+	// 验证用户权限
+	project, err := h.projectService.CheckProjectAccess(c.Request.Context(), projectGuid, c.GetString("user_id"))
+	if err != nil {
+		if err.Error() == common.MESSAGE_ACCESS_DENIED {
+			c.JSON(http.StatusForbidden, utils.GetErrorResponse(common.FORBIDDEN, "访问被拒绝"))
+			return
+		}
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "获取项目信息失败: "+err.Error()))
+		return
+	}
+
+	prompt, err := h.promptService.GetProjectPrompt(c.Request.Context(), project, c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.ERROR_CODE, "获取提示词模板失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("获取提示词模板成功", prompt))
+}
+
+// UpdateProjectPrompt godoc
+// @Summary 覆盖项目提示词模板
+// @Description 保存项目覆盖的提示词模板，每次保存版本加 1，之后该项目的 Agent 任务使用覆盖后的模板
+// @Tags 项目管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param guid path string true "项目GUID"
+// @Param name path string true "模板名称，如 dev_implement_story"
+// @Param request body models.UpdateProjectPromptRequest true "模板内容"
+// @Success 200 {object} common.Response "保存提示词模板成功"
+// @Failure 400 {object} common.ErrorResponse "请求参数错误"
+// @Failure 401 {object} common.ErrorResponse "未授权"
+// @Failure 403 {object} common.ErrorResponse "访问被拒绝"
+// @Failure 500 {object} common.ErrorResponse "服务器内部错误"
+// @Router /api/v1/projects/{guid}/prompts/{name} [put]
+func (h *ProjectHandler) UpdateProjectPrompt(c *gin.Context) {
+	projectGuid := c.Param("guid")
+	if projectGuid == "" {
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(common.VALIDATION_ERROR, "项目GUID不能为空"))
+		return
+	}
+
+	var req models.UpdateProjectPromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(common.VALIDATION_ERROR, "请求参数错误: "+err.Error()))
+		return
+	}
+
+	// 验证用户权限
+	userID := c.GetString("user_id")
+	project, err := h.projectService.CheckProjectAccess(c.Request.Context(), projectGuid, userID)
+	if err != nil {
+		if err.Error() == common.MESSAGE_ACCESS_DENIED {
+			c.JSON(http.StatusForbidden, utils.GetErrorResponse(common.FORBIDDEN, "访问被拒绝"))
+			return
+		}
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "获取项目信息失败: "+err.Error()))
+		return
+	}
+
+	prompt, err := h.promptService.SaveProjectPrompt(c.Request.Context(), project, userID, c.Param("name"), req.Content)
+	if err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.ERROR_CODE, "保存提示词模板失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("保存提示词模板成功", prompt))
+}
+
+// ResetProjectPrompt godoc
+// @Summary 恢复内置提示词模板
+// @Description 删除项目覆盖的提示词模板，之后该项目的 Agent 任务使用内置模板
+// @Tags 项目管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param guid path string true "项目GUID"
+// @Param name path string true "模板名称，如 dev_implement_story"
+// @Success 200 {object} common.Response "恢复内置提示词模板成功"
+// @Failure 400 {object} common.ErrorResponse "请求参数错误"
+// @Failure 401 {object} common.ErrorResponse "未授权"
+// @Failure 403 {object} common.ErrorResponse "访问被拒绝"
+// @Failure 500 {object} common.ErrorResponse "服务器内部错误"
+// @Router /api/v1/projects/{guid}/prompts/{name} [delete]
+func (h *ProjectHandler) ResetProjectPrompt(c *gin.Context) {
+	projectGuid := c.Param("guid")
+	if projectGuid == "" {
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(common.VALIDATION_ERROR, "项目GUID不能为空"))
+		return
+	}
+
+	// 验证用户权限
+	project, err := h.projectService.CheckProjectAccess(c.Request.Context(), projectGuid, c.GetString("user_id"))
+	if err != nil {
+		if err.Error() == common.MESSAGE_ACCESS_DENIED {
+			c.JSON(http.StatusForbidden, utils.GetErrorResponse(common.FORBIDDEN, "访问被拒绝"))
+			return
+		}
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "获取项目信息失败: "+err.Error()))
+		return
+	}
+
+	prompt, err := h.promptService.ResetProjectPrompt(c.Request.Context(), project, c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.ERROR_CODE, "恢复内置提示词模板失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("恢复内置提示词模板成功", prompt))
+}
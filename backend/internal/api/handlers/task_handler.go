@@ -44,26 +44,10 @@ func NewTaskHandler(inspector *asynq.Inspector) *TaskHandler {
 func (s *TaskHandler) GetTaskStatus(c *gin.Context) {
 	taskID := c.Param("id")
 
-	// 查询任务信息
-	info, err := s.inspector.GetTaskInfo("default", taskID) // "default" 是队列名
+	// 任务按类型进入不同的队列，在所有队列中查找
+	info, err := tasks.FindTaskInQueues(s.inspector, taskID)
 	if err != nil {
-		completedTasks, err := s.inspector.ListCompletedTasks("default")
-		if err != nil {
-			c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "获取任务状态失败: "+err.Error()))
-			return
-		}
-
-		for _, task := range completedTasks {
-			if task.ID == taskID {
-				logger.Info("任务已完成", logger.String("taskID", taskID))
-				info = task
-				break
-			}
-		}
-	}
-
-	if info == nil {
-		c.JSON(http.StatusNotFound, utils.GetErrorResponse(common.NOT_FOUND, "任务不存在, "+err.Error()))
+		c.JSON(http.StatusNotFound, utils.GetErrorResponse(common.NOT_FOUND, err.Error()))
 		return
 	}
 
@@ -101,12 +85,19 @@ func (s *TaskHandler) GetTaskStatus(c *gin.Context) {
 // @Security Bearer
 // @Param id path string true "任务ID"
 // @Success 200 {object} common.Response "成功响应"
+// @Failure 404 {object} common.ErrorResponse "任务不存在"
 // @Failure 500 {object} common.ErrorResponse "服务器内部错误"
 // @Router /api/v1/tasks/{id}/retry [post]
 func (s *TaskHandler) RetryTask(c *gin.Context) {
 	taskID := c.Param("id")
 
-	err := s.inspector.RunTask("default", taskID)
+	info, err := tasks.FindTaskInQueues(s.inspector, taskID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, utils.GetErrorResponse(common.NOT_FOUND, err.Error()))
+		return
+	}
+
+	err = s.inspector.RunTask(info.Queue, taskID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(common.INTERNAL_ERROR, "重试任务失败: "+err.Error()))
 		return
@@ -15,13 +15,18 @@ import (
 
 // UserHandler 用户处理器
 type UserHandler struct {
-	userService services.UserService
+	userService   services.UserService
+	usageService  services.UsageService
+	promptService services.PromptService
 }
 
 // NewUserHandler 创建用户处理器
-func NewUserHandler(userService services.UserService) *UserHandler {
+func NewUserHandler(userService services.UserService, usageService services.UsageService,
+	promptService services.PromptService) *UserHandler {
 	return &UserHandler{
-		userService: userService,
+		userService:   userService,
+		usageService:  usageService,
+		promptService: promptService,
 	}
 }
 
@@ -298,6 +303,196 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 	c.JSON(http.StatusOK, utils.GetSuccessResponse("删除成功", nil))
 }
 
+// GetUserUsage 获取当前用户本月用量
+// @Summary 获取用户用量
+// @Description 获取当前用户本月的 token 和费用用量、月度预算，以及按项目的汇总
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} common.Response{data=models.UserUsageResponse}
+// @Failure 401 {object} common.ErrorResponse
+// @Failure 500 {object} common.ErrorResponse
+// @Router /api/v1/users/usage [get]
+func (h *UserHandler) GetUserUsage(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, utils.GetErrorResponse(common.UNAUTHORIZED, "未授权"))
+		return
+	}
+
+	usage, err := h.usageService.GetUserUsage(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "获取用户用量失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("获取用户用量成功", usage))
+}
+
+// UpdateUserBudget 设置用户月度预算
+// @Summary 设置用户月度预算
+// @Description 设置指定用户的月度预算（美元），超出后不再发起新的阶段任务（需要管理员权限）
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param user_id path string true "用户ID"
+// @Param request body models.UpdateUserBudgetRequest true "预算设置请求"
+// @Success 200 {object} common.Response
+// @Failure 400 {object} common.ErrorResponse
+// @Failure 401 {object} common.ErrorResponse
+// @Failure 403 {object} common.ErrorResponse
+// @Router /api/v1/users/{user_id}/budget [put]
+func (h *UserHandler) UpdateUserBudget(c *gin.Context) {
+	// 检查权限
+	if c.GetString("user_role") != common.UserRoleAdmin {
+		c.JSON(http.StatusForbidden, utils.GetErrorResponse(common.FORBIDDEN, "权限不足"))
+		return
+	}
+
+	targetUserID := c.Param("user_id")
+	if targetUserID == "" {
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(common.VALIDATION_ERROR, "用户ID不能为空"))
+		return
+	}
+
+	var req models.UpdateUserBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(common.VALIDATION_ERROR, "请求参数错误: "+err.Error()))
+		return
+	}
+
+	if err := h.usageService.SetUserBudget(c.Request.Context(), targetUserID, req.MonthlyBudgetUsd); err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "设置用户预算失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("设置用户预算成功", nil))
+}
+
+// GetUserPrompts 获取当前用户的提示词模板列表
+// @Summary 获取用户提示词模板列表
+// @Description 获取当前用户指定语言的所有提示词模板，用户覆盖的模板优先于内置模板
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param language query string false "模板语言，如 zh-CN、en-US，为空时使用默认语言"
+// @Success 200 {object} common.Response
+// @Failure 401 {object} common.ErrorResponse
+// @Failure 500 {object} common.ErrorResponse
+// @Router /api/v1/users/prompts [get]
+func (h *UserHandler) GetUserPrompts(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, utils.GetErrorResponse(common.UNAUTHORIZED, "未授权"))
+		return
+	}
+
+	prompts, err := h.promptService.ListUserPrompts(c.Request.Context(), userID, c.Query("language"))
+	if err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.INTERNAL_ERROR, "获取提示词模板失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("获取提示词模板成功", prompts))
+}
+
+// GetUserPrompt 获取当前用户的提示词模板
+// @Summary 获取用户提示词模板
+// @Description 获取当前用户指定语言的提示词模板，用户未覆盖时返回内置模板
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param name path string true "模板名称，如 dev_implement_story"
+// @Param language query string false "模板语言，如 zh-CN、en-US，为空时使用默认语言"
+// @Success 200 {object} common.Response
+// @Failure 401 {object} common.ErrorResponse
+// @Failure 500 {object} common.ErrorResponse
+// @Router /api/v1/users/prompts/{name} [get]
+func (h *UserHandler) GetUserPrompt(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, utils.GetErrorResponse(common.UNAUTHORIZED, "未授权"))
+		return
+	}
+
+	prompt, err := h.promptService.GetUserPrompt(c.Request.Context(), userID, c.Param("name"), c.Query("language"))
+	if err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.ERROR_CODE, "获取提示词模板失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("获取提示词模板成功", prompt))
+}
+
+// UpdateUserPrompt 覆盖当前用户的提示词模板
+// @Summary 覆盖用户提示词模板
+// @Description 保存用户覆盖的提示词模板，每次保存版本加 1，之后该用户同语言且未覆盖该模板的项目使用覆盖后的模板
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param name path string true "模板名称，如 dev_implement_story"
+// @Param request body models.UpdateUserPromptRequest true "模板内容"
+// @Success 200 {object} common.Response
+// @Failure 400 {object} common.ErrorResponse
+// @Failure 401 {object} common.ErrorResponse
+// @Failure 500 {object} common.ErrorResponse
+// @Router /api/v1/users/prompts/{name} [put]
+func (h *UserHandler) UpdateUserPrompt(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, utils.GetErrorResponse(common.UNAUTHORIZED, "未授权"))
+		return
+	}
+
+	var req models.UpdateUserPromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(common.VALIDATION_ERROR, "请求参数错误: "+err.Error()))
+		return
+	}
+
+	prompt, err := h.promptService.SaveUserPrompt(c.Request.Context(), userID, c.Param("name"), req.Language, req.Content)
+	if err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.ERROR_CODE, "保存提示词模板失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("保存提示词模板成功", prompt))
+}
+
+// ResetUserPrompt 删除当前用户覆盖的提示词模板
+// @Summary 恢复内置提示词模板
+// @Description 删除用户覆盖的提示词模板，之后该用户的项目使用项目模板或内置模板
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param name path string true "模板名称，如 dev_implement_story"
+// @Param language query string false "模板语言，如 zh-CN、en-US，为空时使用默认语言"
+// @Success 200 {object} common.Response
+// @Failure 401 {object} common.ErrorResponse
+// @Failure 500 {object} common.ErrorResponse
+// @Router /api/v1/users/prompts/{name} [delete]
+func (h *UserHandler) ResetUserPrompt(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, utils.GetErrorResponse(common.UNAUTHORIZED, "未授权"))
+		return
+	}
+
+	prompt, err := h.promptService.ResetUserPrompt(c.Request.Context(), userID, c.Param("name"), c.Query("language"))
+	if err != nil {
+		c.JSON(http.StatusOK, utils.GetErrorResponse(common.ERROR_CODE, "恢复内置提示词模板失败: "+err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.GetSuccessResponse("恢复内置提示词模板成功", prompt))
+}
+
 // RefreshToken 刷新令牌
 // @Summary 刷新令牌
 // @Description 使用刷新令牌获取新的访问令牌
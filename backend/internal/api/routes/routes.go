@@ -94,6 +94,16 @@ func registerUserApiRoutes(routers *gin.RouterGroup, authMiddleware gin.HandlerF
 			users.GET("/settings", userHandler.GetUserSettings)
 			users.PUT("/settings", userHandler.UpdateUserSettings)
 
+			// 用量与预算
+			users.GET("/usage", userHandler.GetUserUsage)
+			users.PUT("/:user_id/budget", userHandler.UpdateUserBudget)
+
+			// 用户级提示词模板，对该用户所有同语言的项目生效
+			users.GET("/prompts", userHandler.GetUserPrompts)
+			users.GET("/prompts/:name", userHandler.GetUserPrompt)
+			users.PUT("/prompts/:name", userHandler.UpdateUserPrompt)
+			users.DELETE("/prompts/:name", userHandler.ResetUserPrompt)
+
 			// 管理员功能
 			users.GET("", userHandler.GetUserList)
 			users.DELETE("/:user_id", userHandler.DeleteUser)
@@ -106,6 +116,14 @@ func registerUserApiRoutes(routers *gin.RouterGroup, authMiddleware gin.HandlerF
 			setGetEmptyEndpoint(users, "/settings", "User settings endpoint - TODO")
 			setPutEmptyEndpoint(users, "/settings", "User update settings endpoint - TODO")
 
+			setGetEmptyEndpoint(users, "/usage", "User usage endpoint - TODO")
+			setPutEmptyEndpoint(users, "/:user_id/budget", "User budget endpoint - TODO")
+
+			setGetEmptyEndpoint(users, "/prompts", "User prompts endpoint - TODO")
+			setGetEmptyEndpoint(users, "/prompts/:name", "User prompt endpoint - TODO")
+			setPutEmptyEndpoint(users, "/prompts/:name", "User prompt update endpoint - TODO")
+			setDeleteEmptyEndpoint(users, "/prompts/:name", "User prompt reset endpoint - TODO")
+
 			setGetEmptyEndpoint(users, "/", "User list endpoint - TODO")
 			setDeleteEmptyEndpoint(users, "/:user_id", "User delete endpoint - TODO")
 		}
@@ -120,15 +138,31 @@ func registerProjectApiRoutes(routers *gin.RouterGroup, authMiddleware gin.Handl
 	{
 		var epicHandler = container.EpicHandler
 		if projectHandler != nil {
-			projects.POST("/", projectHandler.CreateProject)                         // 创建项目
-			projects.GET("/", projectHandler.ListProjects)                           // 获取项目列表
-			projects.GET("/:guid", projectHandler.GetProject)                        // 获取项目详情
-			projects.PUT("/:guid", projectHandler.UpdateProject)                     // 更新项目
-			projects.DELETE("/:guid", projectHandler.DeleteProject)                  // 删除项目
-			projects.GET("/:guid/stages", projectHandler.GetProjectStages)           // 获取项目开发阶段
-			projects.GET("/download/:guid", projectHandler.DownloadProject)          // 下载项目文件
-			projects.POST("/:guid/deploy", projectHandler.DeployProject)             // 部署项目
-			projects.POST("/:guid/preview-link", projectHandler.GeneratePreviewLink) // 生成预览分享链接
+			projects.POST("/", projectHandler.CreateProject)                                    // 创建项目
+			projects.GET("/", projectHandler.ListProjects)                                      // 获取项目列表
+			projects.GET("/:guid", projectHandler.GetProject)                                   // 获取项目详情
+			projects.PUT("/:guid", projectHandler.UpdateProject)                                // 更新项目
+			projects.DELETE("/:guid", projectHandler.DeleteProject)                             // 删除项目
+			projects.GET("/:guid/stages", projectHandler.GetProjectStages)                      // 获取项目开发阶段
+			projects.GET("/download/:guid", projectHandler.DownloadProject)                     // 下载项目文件
+			projects.POST("/:guid/deploy", projectHandler.DeployProject)                        // 部署项目
+			projects.GET("/:guid/deployments", projectHandler.GetProjectDeployments)            // 获取项目部署历史
+			projects.POST("/:guid/deployments/:id/redeploy", projectHandler.RedeployDeployment) // 重新部署历史提交
+			projects.GET("/:guid/test-reports", projectHandler.GetProjectTestReports)           // 获取项目测试报告
+			projects.GET("/:guid/qa-reviews", projectHandler.GetProjectQAReviews)               // 获取项目 QA 评审
+			projects.GET("/:guid/review-comments", projectHandler.GetProjectReviewComments)     // 获取项目代码评审意见
+			projects.POST("/:guid/preview-link", projectHandler.GeneratePreviewLink)            // 生成预览分享链接
+			projects.GET("/:guid/agent-logs", projectHandler.GetProjectAgentLogs)               // 获取 Agent 输出日志
+			projects.POST("/:guid/cancel", projectHandler.CancelProject)                        // 取消项目当前阶段
+			projects.POST("/:guid/stages/:stageId/rollback", projectHandler.RollbackStage)      // 回滚项目到阶段执行前
+			projects.GET("/:guid/agent-sessions", projectHandler.GetAgentSessions)              // 获取 Agent 会话列表
+			projects.DELETE("/:guid/agent-sessions", projectHandler.ResetAgentSessions)         // 重置 Agent 会话
+			projects.GET("/:guid/usage", projectHandler.GetProjectUsage)                        // 获取项目用量
+			projects.GET("/:guid/commits", projectHandler.GetProjectCommits)                    // 获取项目提交历史
+			projects.GET("/:guid/prompts", projectHandler.GetProjectPrompts)                    // 获取项目提示词模板列表
+			projects.GET("/:guid/prompts/:name", projectHandler.GetProjectPrompt)               // 获取项目提示词模板
+			projects.PUT("/:guid/prompts/:name", projectHandler.UpdateProjectPrompt)            // 覆盖项目提示词模板
+			projects.DELETE("/:guid/prompts/:name", projectHandler.ResetProjectPrompt)          // 恢复内置提示词模板
 
 			// Epic 相关路由
 			if epicHandler != nil {
@@ -165,7 +199,23 @@ func registerProjectApiRoutes(routers *gin.RouterGroup, authMiddleware gin.Handl
 			setGetEmptyEndpoint(projects, "/:guid/stages", "Project stages endpoint - TODO")
 			setGetEmptyEndpoint(projects, "/download/:guid", "Project download endpoint - TODO")
 			setPostEmptyEndpoint(projects, "/:guid/deploy", "Project deploy endpoint - TODO")
+			setGetEmptyEndpoint(projects, "/:guid/deployments", "Project deployments endpoint - TODO")
+			setPostEmptyEndpoint(projects, "/:guid/deployments/:id/redeploy", "Project redeploy endpoint - TODO")
+			setGetEmptyEndpoint(projects, "/:guid/test-reports", "Project test reports endpoint - TODO")
+			setGetEmptyEndpoint(projects, "/:guid/qa-reviews", "Project QA reviews endpoint - TODO")
+			setGetEmptyEndpoint(projects, "/:guid/review-comments", "Project review comments endpoint - TODO")
 			setPostEmptyEndpoint(projects, "/:guid/preview-link", "Project preview link endpoint - TODO")
+			setGetEmptyEndpoint(projects, "/:guid/agent-logs", "Project agent logs endpoint - TODO")
+			setPostEmptyEndpoint(projects, "/:guid/cancel", "Project cancel endpoint - TODO")
+			setPostEmptyEndpoint(projects, "/:guid/stages/:stageId/rollback", "Project stage rollback endpoint - TODO")
+			setGetEmptyEndpoint(projects, "/:guid/agent-sessions", "Project agent sessions endpoint - TODO")
+			setDeleteEmptyEndpoint(projects, "/:guid/agent-sessions", "Project agent sessions reset endpoint - TODO")
+			setGetEmptyEndpoint(projects, "/:guid/usage", "Project usage endpoint - TODO")
+			setGetEmptyEndpoint(projects, "/:guid/commits", "Project commits endpoint - TODO")
+			setGetEmptyEndpoint(projects, "/:guid/prompts", "Project prompts endpoint - TODO")
+			setGetEmptyEndpoint(projects, "/:guid/prompts/:name", "Project prompt endpoint - TODO")
+			setPutEmptyEndpoint(projects, "/:guid/prompts/:name", "Project prompt update endpoint - TODO")
+			setDeleteEmptyEndpoint(projects, "/:guid/prompts/:name", "Project prompt reset endpoint - TODO")
 		}
 	}
 }
@@ -21,6 +21,8 @@ type Config struct {
 	JWT      JWTConfig      `mapstructure:"jwt"`      // JWT配置
 	Log      LogConfig      `mapstructure:"log"`      // 日志配置
 	Agents   AgentsConfig   `mapstructure:"agents"`   // Agents配置
+	Usage    UsageConfig    `mapstructure:"usage"`    // 用量与预算配置
+	Prompt   PromptConfig   `mapstructure:"prompt"`   // 提示词模板配置
 }
 
 // AppConfig App配置
@@ -74,7 +76,19 @@ type LogConfig struct {
 
 // Agents server配置
 type AgentsConfig struct {
-	URL string `mapstructure:"url"` // Agents server URL
+	URL       string `mapstructure:"url"`        // Agents server URL
+	KeyID     string `mapstructure:"key_id"`     // 调用 Agents API 的签名密钥ID，对应 Agents 配置的 keyID:secret
+	SecretKey string `mapstructure:"secret_key"` // 调用 Agents API 的签名密钥，为空时不签名
+}
+
+// UsageConfig 用量与预算配置
+type UsageConfig struct {
+	DefaultMonthlyBudgetUsd float64 `mapstructure:"default_monthly_budget_usd"` // 用户默认月度预算（美元），0 表示不限制
+}
+
+// PromptConfig 提示词模板配置
+type PromptConfig struct {
+	TemplatesPath string `mapstructure:"templates_path"` // 自定义提示词模板目录，同名模板覆盖内置模板，为空时只使用内置模板
 }
 
 // Asynq 异步配置
@@ -172,6 +186,12 @@ func setDefaults() {
 
 	// Agents Server 默认
 	viper.SetDefault("agents.url", utils.GetEnvOrDefault("AGENTS_SERVER_URL", "http://localhost:8088"))
+	viper.SetDefault("agents.key_id", utils.GetEnvOrDefault("AGENTS_SERVICE_KEY_ID", "default"))
+	viper.SetDefault("agents.secret_key", utils.GetEnvOrDefault("AGENTS_SERVICE_SECRET", ""))
+
+	viper.SetDefault("usage.default_monthly_budget_usd", 0)
+
+	viper.SetDefault("prompt.templates_path", "")
 }
 
 func validateConfig(config *Config) error {
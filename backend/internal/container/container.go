@@ -17,6 +17,7 @@ import (
 	"github.com/lighthought/app-maker/shared-models/cache"
 	"github.com/lighthought/app-maker/shared-models/common"
 	"github.com/lighthought/app-maker/shared-models/logger"
+	"github.com/lighthought/app-maker/shared-models/prompt"
 
 	"github.com/hibiken/asynq"
 	"gorm.io/gorm"
@@ -50,6 +51,13 @@ type Container struct {
 	EpicService            services.EpicService            // 史诗服务
 	RedisPubSubService     services.RedisPubSubService     // Redis Pub/Sub服务
 	EnvironmentService     services.EnvironmentService     // 环境服务
+	UsageService           services.UsageService           // 用量与预算服务
+	PromptService          services.PromptService          // 提示词模板服务
+	CommitService          services.CommitService          // 提交历史服务
+	DeploymentService      services.DeploymentService      // 部署历史服务
+	TestReportService      services.TestReportService      // 测试报告服务
+	QAReviewService        services.QAReviewService        // QA 评审服务
+	CodeReviewService      services.CodeReviewService      // 代码评审服务
 	AsyncClientService     services.AsyncClientService     // 异步客户端服务
 	AsyncTaskService       services.AsyncTaskService       // 异步任务处理服务
 
@@ -107,12 +115,20 @@ func (c *Container) initServices(cfg *config.Config, db *gorm.DB) {
 	c.MessageService = services.NewMessageService(c.Repositories.MessageRepo)
 	c.PreviewService = services.NewPreviewService(c.Repositories.PreviewTokenRepo)
 	c.UserService = services.NewUserService(c.Repositories.UserRepo, c.JWTService, cfg.JWT.Expire)
+	c.UsageService = services.NewUsageService(c.Repositories, cfg.Usage.DefaultMonthlyBudgetUsd)
+	c.PromptService = services.NewPromptService(c.Repositories, prompt.NewRegistry(cfg.Prompt.TemplatesPath))
 
 	// 会被其他服务引用的服务
 	gitService := services.NewGitService()
 	fileServie := services.NewFileService(gitService, cfg.App.Environment)
 	asyncClientService := services.NewAsyncClientService(c.AsyncClient)
-	agentInteractService := services.NewAgentInteractService(c.Repositories, cfg.Agents.URL)
+	var agentsSigner *auth.RequestSigner
+	if cfg.Agents.SecretKey != "" {
+		agentsSigner = auth.NewRequestSigner(cfg.Agents.KeyID, cfg.Agents.SecretKey)
+	} else {
+		logger.Warn("未配置 agents.secret_key（AGENTS_SERVICE_SECRET），调用 Agents API 不签名，Agents 服务开启签名校验后请求会被拒绝")
+	}
+	agentInteractService := services.NewAgentInteractService(c.Repositories, c.PromptService, cfg.Agents.URL, agentsSigner)
 
 	environmentService := services.NewEnvironmentService(agentInteractService, db, c.CacheInstance)
 	projectTemplateService := services.NewProjectTemplateService(fileServie)
@@ -120,9 +136,16 @@ func (c *Container) initServices(cfg *config.Config, db *gorm.DB) {
 	webSocketService := services.NewWebSocketService(asyncClientService, c.Repositories)
 	projectCommonService := services.NewProjectCommonService(c.Repositories,
 		webSocketService, cfg.App.Environment)
-	projectDevService := services.NewProjectDevService(c.Repositories, asyncClientService, agentInteractService, projectCommonService)
+	qaReviewService := services.NewQAReviewService(c.Repositories)
+	projectDevService := services.NewProjectDevService(c.Repositories, asyncClientService, agentInteractService, projectCommonService, gitService,
+		qaReviewService)
 
 	c.GitService = gitService
+	c.CommitService = services.NewCommitService(c.Repositories, gitService, cfg.App.Environment)
+	c.DeploymentService = services.NewDeploymentService(c.Repositories, asyncClientService, qaReviewService)
+	c.TestReportService = services.NewTestReportService(c.Repositories)
+	c.QAReviewService = qaReviewService
+	c.CodeReviewService = services.NewCodeReviewService(c.Repositories, projectCommonService)
 	c.FileService = fileServie
 	c.EnvironmentService = environmentService
 	c.ProjectTemplateService = projectTemplateService
@@ -134,12 +157,13 @@ func (c *Container) initServices(cfg *config.Config, db *gorm.DB) {
 
 	// 简单引用其他服务
 	c.EpicService = services.NewEpicService(c.Repositories, fileServie)
-	c.RedisPubSubService = services.NewRedisPubSubService(asyncClientService, cfg)
+	c.RedisPubSubService = services.NewRedisPubSubService(asyncClientService, webSocketService, cfg)
 
 	// 需要引用多个其他服务的核心业务服务
 	c.ProjectService = services.NewProjectService(c.Repositories, projectTemplateService,
-		projectCommonService, gitService, asyncClientService, cfg)
-	c.AsyncTaskService = services.NewAsyncTaskService(c.Repositories, projectCommonService, projectDevService, agentInteractService)
+		projectCommonService, gitService, asyncClientService, agentInteractService, cfg)
+	c.AsyncTaskService = services.NewAsyncTaskService(c.Repositories, projectCommonService, projectDevService, agentInteractService, c.UsageService,
+		c.TestReportService, c.QAReviewService, c.CodeReviewService)
 
 	// 如果是本地主机运行，则不用执行，只有容器运行才需要初始化 SSH
 	if cfg.App.Environment != common.EnvironmentLocalDebug {
@@ -154,9 +178,11 @@ func (c *Container) initHandlers() {
 	c.CacheHandler = handlers.NewCacheHandler(c.CacheInstance, c.CachMonitor)
 	c.ChatHandler = handlers.NewChatHandler(c.MessageService, c.FileService, c.ProjectService, c.AsyncClientService)
 	c.FileHandler = handlers.NewFileHandler(c.FileService, c.ProjectService)
-	c.ProjectHandler = handlers.NewProjectHandler(c.ProjectService, c.AsyncClientService, c.ProjectCommonService, c.PreviewService)
+	c.ProjectHandler = handlers.NewProjectHandler(c.ProjectService, c.AsyncClientService, c.ProjectCommonService, c.PreviewService,
+		c.AgentInteractService, c.ProjectDevService, c.UsageService, c.PromptService, c.CommitService, c.DeploymentService,
+		c.TestReportService, c.QAReviewService, c.CodeReviewService)
 	c.TaskHandler = handlers.NewTaskHandler(c.AsyncInspector)
-	c.UserHandler = handlers.NewUserHandler(c.UserService)
+	c.UserHandler = handlers.NewUserHandler(c.UserService, c.UsageService, c.PromptService)
 	c.WebSocketHandler = handlers.NewWebSocketHandler(c.WebSocketService, c.ProjectService, c.JWTService)
 	c.EpicHandler = handlers.NewEpicHandler(c.EpicService)
 	c.HealthHandler = handlers.NewHealthHandler(c.EnvironmentService, c.AgentInteractService, c.WebSocketService)
@@ -229,9 +255,9 @@ func initAsynqWorker(redisClientOpt *asynq.RedisClientOpt, concurrency int, proj
 			Concurrency: concurrency, // 并发 worker 数量
 			// 可以按权重指定优先处理哪些队列
 			Queues: map[string]int{
-				"critical": common.TaskQueueCritical,
-				"default":  common.TaskQueueDefault,
-				"low":      common.TaskQueueLow,
+				common.TaskQueueNameCritical: common.TaskQueueCritical,
+				common.TaskQueueNameDefault:  common.TaskQueueDefault,
+				common.TaskQueueNameLow:      common.TaskQueueLow,
 			},
 		},
 	)
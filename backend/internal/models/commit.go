@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// ProjectCommit 项目提交记录，开发阶段、任务、故事和 Agent 从提交信息的 App-Maker-* trailer 中解析
+type ProjectCommit struct {
+	Hash         string    `json:"hash"`
+	ShortHash    string    `json:"short_hash"`
+	Subject      string    `json:"subject"`
+	Message      string    `json:"message"`
+	Author       string    `json:"author"`
+	CommittedAt  time.Time `json:"committed_at"`
+	DevStage     string    `json:"dev_stage,omitempty"`
+	TaskID       string    `json:"task_id,omitempty"`
+	StoryNumber  string    `json:"story_number,omitempty"`
+	AgentType    string    `json:"agent_type,omitempty"`
+	FilesChanged int       `json:"files_changed"`
+	Insertions   int       `json:"insertions"`
+	Deletions    int       `json:"deletions"`
+}
+
+// CommitStats 提交的变更统计
+type CommitStats struct {
+	CommitCount  int `json:"commit_count"`
+	FilesChanged int `json:"files_changed"`
+	Insertions   int `json:"insertions"`
+	Deletions    int `json:"deletions"`
+}
+
+// Add 累加一个提交的变更统计
+func (s *CommitStats) Add(commit *ProjectCommit) {
+	s.CommitCount++
+	s.FilesChanged += commit.FilesChanged
+	s.Insertions += commit.Insertions
+	s.Deletions += commit.Deletions
+}
+
+// StoryCommits 故事的提交记录
+type StoryCommits struct {
+	StoryNumber string           `json:"story_number"`
+	Stats       CommitStats      `json:"stats"`
+	Commits     []*ProjectCommit `json:"commits"`
+}
+
+// StageCommits 开发阶段的提交记录，关联了故事的提交按故事分组
+type StageCommits struct {
+	DevStageID string           `json:"dev_stage_id"`
+	DevStage   string           `json:"dev_stage"`
+	Stats      CommitStats      `json:"stats"`
+	Commits    []*ProjectCommit `json:"commits"`
+	Stories    []*StoryCommits  `json:"stories"`
+}
+
+// ProjectCommitsResponse 项目提交历史，没有 App-Maker-Stage trailer 的提交放在 others 中
+type ProjectCommitsResponse struct {
+	ProjectGuid string           `json:"project_guid"`
+	Stats       CommitStats      `json:"stats"`
+	Stages      []*StageCommits  `json:"stages"`
+	Others      []*ProjectCommit `json:"others"`
+}
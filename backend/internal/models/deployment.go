@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/common"
+	"github.com/lighthought/app-maker/shared-models/tasks"
+)
+
+// Deployment 项目部署记录，每次部署（包括重新部署历史提交）一条
+type Deployment struct {
+	ID                 string                         `json:"id" gorm:"primaryKey;type:varchar(50);default:public.generate_table_id('DEPLOY', 'public.deployments_id_num_seq')"`
+	ProjectID          string                         `json:"project_id" gorm:"type:varchar(50);not null;index"`
+	ProjectGuid        string                         `json:"project_guid" gorm:"type:varchar(50);index"`
+	UserID             string                         `json:"user_id" gorm:"type:varchar(50)"`                        // 发起部署的用户，开发流程中的部署阶段为项目所有者
+	Environment        string                         `json:"environment" gorm:"size:20;not null;default:'dev'"`      // dev, staging, prod
+	CommitSha          string                         `json:"commit_sha" gorm:"type:varchar(64)"`                     // 部署的提交
+	SourceDeploymentID string                         `json:"source_deployment_id,omitempty" gorm:"type:varchar(50)"` // 重新部署时对应的历史部署
+	Status             string                         `json:"status" gorm:"size:20;not null;default:'pending'"`       // pending, in_progress, done, failed
+	TaskID             string                         `json:"task_id" gorm:"type:varchar(50)"`                        // 后端部署任务ID
+	AgentTaskID        string                         `json:"agent_task_id" gorm:"type:varchar(50)"`                  // Agent 服务部署任务ID
+	PreviewUrl         string                         `json:"preview_url" gorm:"size:500"`                            // 通过健康检查的预览地址
+	Components         []*agent.DeployComponentStatus `json:"components" gorm:"type:text;serializer:json"`            // 各组件的状态和访问地址
+	Logs               string                         `json:"logs" gorm:"type:text"`                                  // Agent 返回的部署结果，包括未通过健康检查的组件日志
+	ErrorMessage       string                         `json:"error_message" gorm:"type:text"`
+	DurationMs         int64                          `json:"duration_ms" gorm:"default:0"`
+	StartedAt          *time.Time                     `json:"started_at"`
+	CompletedAt        *time.Time                     `json:"completed_at"`
+	CreatedAt          time.Time                      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt          time.Time                      `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (Deployment) TableName() string {
+	return "deployments"
+}
+
+// Start 记录部署开始
+func (d *Deployment) Start(taskID string) {
+	now := time.Now()
+	d.Status = common.CommonStatusInProgress
+	d.TaskID = taskID
+	d.StartedAt = &now
+}
+
+// Finish 记录部署结束：状态、耗时，以及 Agent 返回的提交、访问地址、组件状态和日志
+func (d *Deployment) Finish(result *tasks.TaskResult, err error) {
+	now := time.Now()
+	d.Status = common.CommonStatusDone
+	d.CompletedAt = &now
+	if d.StartedAt != nil {
+		d.DurationMs = now.Sub(*d.StartedAt).Milliseconds()
+	}
+	if err != nil {
+		d.Status = common.CommonStatusFailed
+		d.ErrorMessage = err.Error()
+	}
+	if result == nil {
+		return
+	}
+	d.Logs = result.Message
+	if result.Deploy != nil {
+		d.PreviewUrl = result.Deploy.PreviewURL
+		d.Components = result.Deploy.Components
+		if result.Deploy.CommitSha != "" {
+			d.CommitSha = result.Deploy.CommitSha
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/common"
+	"github.com/lighthought/app-maker/shared-models/tasks"
+)
+
+func TestDeploymentFinish(t *testing.T) {
+	deployment := &Deployment{CommitSha: "old"}
+	deployment.Start("task-1")
+	started := deployment.StartedAt.Add(-2 * time.Second)
+	deployment.StartedAt = &started
+
+	deployment.Finish(&tasks.TaskResult{
+		Message: "deployed",
+		Deploy: &agent.DeployResp{
+			Status:     common.DeployHealthHealthy,
+			PreviewURL: "http://localhost:3000",
+			CommitSha:  "abc123",
+			Components: []*agent.DeployComponentStatus{{Name: "frontend", State: common.DeployHealthHealthy}},
+		},
+	}, nil)
+	if deployment.Status != common.CommonStatusDone || deployment.TaskID != "task-1" || deployment.CompletedAt == nil {
+		t.Fatalf("deployment = %+v", deployment)
+	}
+	if deployment.DurationMs < 2000 {
+		t.Errorf("DurationMs = %d, want >= 2000", deployment.DurationMs)
+	}
+	if deployment.CommitSha != "abc123" || deployment.PreviewUrl != "http://localhost:3000" ||
+		deployment.Logs != "deployed" || len(deployment.Components) != 1 {
+		t.Errorf("deployment = %+v", deployment)
+	}
+
+	failed := &Deployment{CommitSha: "abc123"}
+	failed.Finish(nil, errors.New("boom"))
+	if failed.Status != common.CommonStatusFailed || failed.ErrorMessage != "boom" || failed.CommitSha != "abc123" || failed.DurationMs != 0 {
+		t.Errorf("failed deployment = %+v", failed)
+	}
+}
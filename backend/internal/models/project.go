@@ -62,10 +62,13 @@ type Project struct {
 	AiModel               string         `json:"ai_model" gorm:"size:100"`
 	ModelProvider         string         `json:"model_provider" gorm:"size:50"`
 	ModelApiUrl           string         `json:"model_api_url" gorm:"size:500"`
-	ApiToken              string         `json:"api_token,omitempty" gorm:"size:500"`           // API Token，敏感信息
-	WaitingForUserConfirm bool           `json:"waiting_for_user_confirm" gorm:"default:false"` // 是否等待用户确认
-	ConfirmStage          string         `json:"confirm_stage" gorm:"size:50"`                  // 等待确认的阶段
-	AutoGoNext            bool           `json:"auto_go_next" gorm:"default:true"`              // 项目级自动进入下一阶段配置
+	ApiToken              string         `json:"api_token,omitempty" gorm:"size:500"`                                                           // API Token，敏感信息
+	Language              string         `json:"language" gorm:"size:20;default:'zh-CN'"`                                                       // 输出语言，决定提示词、文档和系统消息的语言
+	WaitingForUserConfirm bool           `json:"waiting_for_user_confirm" gorm:"default:false"`                                                 // 是否等待用户确认
+	ConfirmStage          string         `json:"confirm_stage" gorm:"size:50"`                                                                  // 等待确认的阶段
+	AutoGoNext            bool           `json:"auto_go_next" gorm:"default:true"`                                                              // 项目级自动进入下一阶段配置
+	CodeReviewEnabled     bool           `json:"code_review_enabled" gorm:"default:false"`                                                      // 是否评审 Dev Agent 实现故事的提交
+	BlockingSeverities    []string       `json:"review_blocking_severities" gorm:"column:review_blocking_severities;type:text;serializer:json"` // 阻塞的评审严重程度，这些意见自动退回 Dev Agent 修复
 	User                  User           `json:"user,omitempty" gorm:"foreignKey:UserID"`
 	CreatedAt             time.Time      `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt             time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
@@ -85,6 +88,7 @@ func GetDefaultProject(userID, requirements string) *Project {
 		FrontendPort: 3501,
 		RedisPort:    7501,
 		PostgresPort: 5501,
+		Language:     common.DefaultLanguage,
 	}
 	return newProject
 }
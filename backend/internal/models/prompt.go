@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/common"
+)
+
+// ProjectPrompt 项目覆盖的提示词模板，每个项目每个模板一条，每次保存版本加 1
+type ProjectPrompt struct {
+	ID          string    `json:"id" gorm:"primaryKey;type:varchar(50);default:public.generate_table_id('PROMPT', 'public.project_prompts_id_num_seq')"`
+	ProjectID   string    `json:"project_id" gorm:"type:varchar(50);not null;uniqueIndex:idx_project_prompts_project_id_name"`
+	Name        string    `json:"name" gorm:"size:100;not null;uniqueIndex:idx_project_prompts_project_id_name"`
+	Version     int       `json:"version" gorm:"not null;default:1"`
+	BaseVersion int       `json:"base_version" gorm:"not null;default:1"` // 保存时对应的内置模板版本
+	Content     string    `json:"content" gorm:"type:text;not null"`
+	UpdatedBy   string    `json:"updated_by" gorm:"type:varchar(50)"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (ProjectPrompt) TableName() string {
+	return "project_prompts"
+}
+
+// ToPromptTemplate 转换为提示词模板
+func (p *ProjectPrompt) ToPromptTemplate() *agent.PromptTemplate {
+	return &agent.PromptTemplate{
+		Name:        p.Name,
+		Version:     p.Version,
+		Source:      common.PromptSourceProject,
+		BaseVersion: p.BaseVersion,
+		Content:     p.Content,
+		UpdatedAt:   p.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// UserPrompt 用户覆盖的提示词模板，对该用户所有同语言的项目生效，优先级低于项目覆盖的模板
+type UserPrompt struct {
+	ID          string    `json:"id" gorm:"primaryKey;type:varchar(50);default:public.generate_table_id('UPROMPT', 'public.user_prompts_id_num_seq')"`
+	UserID      string    `json:"user_id" gorm:"type:varchar(50);not null;uniqueIndex:idx_user_prompts_user_id_name_language"`
+	Name        string    `json:"name" gorm:"size:100;not null;uniqueIndex:idx_user_prompts_user_id_name_language"`
+	Language    string    `json:"language" gorm:"size:20;not null;uniqueIndex:idx_user_prompts_user_id_name_language"`
+	Version     int       `json:"version" gorm:"not null;default:1"`
+	BaseVersion int       `json:"base_version" gorm:"not null;default:1"` // 保存时对应的内置模板版本
+	Content     string    `json:"content" gorm:"type:text;not null"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (UserPrompt) TableName() string {
+	return "user_prompts"
+}
+
+// ToPromptTemplate 转换为提示词模板
+func (p *UserPrompt) ToPromptTemplate() *agent.PromptTemplate {
+	return &agent.PromptTemplate{
+		Name:        p.Name,
+		Version:     p.Version,
+		Source:      common.PromptSourceUser,
+		Language:    p.Language,
+		BaseVersion: p.BaseVersion,
+		Content:     p.Content,
+		UpdatedAt:   p.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+)
+
+// QAReview 用户故事的 QA 评审结论，每个评审故事的 QA Agent 任务一条，故事最新的 fail 结论阻止部署
+type QAReview struct {
+	ID           string    `json:"id" gorm:"primaryKey;type:varchar(50);default:public.generate_table_id('QAREV', 'public.qa_reviews_id_num_seq')"`
+	ProjectID    string    `json:"project_id" gorm:"type:varchar(50);not null;index"`
+	ProjectGuid  string    `json:"project_guid" gorm:"type:varchar(50);index"`
+	DevStageID   string    `json:"dev_stage_id" gorm:"type:varchar(50)"`
+	StoryNumber  string    `json:"story_number" gorm:"size:100;not null"` // 故事编号，按故事文件评审时为文件名
+	AgentTaskID  string    `json:"agent_task_id" gorm:"type:varchar(50);not null;uniqueIndex"`
+	Gate         string    `json:"gate" gorm:"size:20;not null"` // pass, concerns, fail
+	StatusReason string    `json:"status_reason" gorm:"type:text"`
+	TopIssues    []string  `json:"top_issues" gorm:"type:text;serializer:json"` // QA 给出的主要问题
+	GateFile     string    `json:"gate_file" gorm:"size:500"`                   // 门禁文件，相对项目根目录
+	Summary      string    `json:"summary" gorm:"type:text"`                    // QA Agent 返回的评审摘要
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName 指定表名
+func (QAReview) TableName() string {
+	return "qa_reviews"
+}
+
+// CopyFromAgentGate 从 Agent 返回的门禁结论复制
+func (r *QAReview) CopyFromAgentGate(gate *agent.QAGate) {
+	r.StoryNumber = gate.StoryNumber
+	r.Gate = gate.Gate
+	r.StatusReason = gate.StatusReason
+	r.TopIssues = gate.TopIssues
+	r.GateFile = gate.GateFile
+}
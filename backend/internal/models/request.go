@@ -33,27 +33,58 @@ type UpdateProfileRequest struct {
 
 // UpdateUserSettingsRequest 更新用户设置请求
 type UpdateUserSettingsRequest struct {
-	DefaultCliTool       string `json:"default_cli_tool" binding:"omitempty,oneof=claude-code qwen-code gemini" example:"claude-code"`
+	DefaultCliTool       string `json:"default_cli_tool" binding:"omitempty,oneof=claude-code qwen-code gemini mock" example:"claude-code"`
 	DefaultAiModel       string `json:"default_ai_model" binding:"omitempty" example:"glm-4.6"`
 	DefaultModelProvider string `json:"default_model_provider" binding:"omitempty,oneof=ollama zhipu anthropic openai vllm" example:"zhipu"`
 	DefaultModelApiUrl   string `json:"default_model_api_url" binding:"omitempty,url" example:"https://open.bigmodel.cn/api/anthropic"`
 	DefaultApiToken      string `json:"default_api_token" binding:"omitempty" example:"sk-..."`
-	AutoGoNext           *bool  `json:"auto_go_next" binding:"omitempty" example:"true"` // 自动进入下一阶段配置
+	DefaultLanguage      string `json:"default_language" binding:"omitempty,oneof=zh-CN en-US" example:"zh-CN"` // 新建项目的默认输出语言
+	AutoGoNext           *bool  `json:"auto_go_next" binding:"omitempty" example:"true"`                        // 自动进入下一阶段配置
+}
+
+// UpdateUserBudgetRequest 设置用户月度预算请求
+type UpdateUserBudgetRequest struct {
+	MonthlyBudgetUsd *float64 `json:"monthly_budget_usd" binding:"omitempty,gte=0" example:"50"` // 为空时恢复系统默认预算，0 表示不限制
 }
 
 // CreateProjectRequest 创建项目请求
 type CreateProjectRequest struct {
 	Requirements string `json:"requirements" binding:"required" example:"项目需求描述"`
+	Language     string `json:"language" binding:"omitempty,oneof=zh-CN en-US" example:"en-US"` // 输出语言，为空时使用用户设置的默认语言
 }
 
 // UpdateProjectRequest 更新项目请求
 type UpdateProjectRequest struct {
-	Name          *string `json:"name" binding:"omitempty,min=1,max=200" example:"项目名称"`
-	Description   *string `json:"description" binding:"omitempty" example:"项目描述"`
-	CliTool       *string `json:"cli_tool" binding:"omitempty,oneof=claude-code qwen-code gemini" example:"claude-code"`
-	AiModel       *string `json:"ai_model" binding:"omitempty" example:"glm-4.6"`
-	ModelProvider *string `json:"model_provider" binding:"omitempty,oneof=ollama zhipu anthropic openai vllm" example:"zhipu"`
-	ModelApiUrl   *string `json:"model_api_url" binding:"omitempty" example:"https://open.bigmodel.cn/api/anthropic"`
+	Name               *string  `json:"name" binding:"omitempty,min=1,max=200" example:"项目名称"`
+	Description        *string  `json:"description" binding:"omitempty" example:"项目描述"`
+	CliTool            *string  `json:"cli_tool" binding:"omitempty,oneof=claude-code qwen-code gemini mock" example:"claude-code"`
+	AiModel            *string  `json:"ai_model" binding:"omitempty" example:"glm-4.6"`
+	ModelProvider      *string  `json:"model_provider" binding:"omitempty,oneof=ollama zhipu anthropic openai vllm" example:"zhipu"`
+	ModelApiUrl        *string  `json:"model_api_url" binding:"omitempty" example:"https://open.bigmodel.cn/api/anthropic"`
+	Language           *string  `json:"language" binding:"omitempty,oneof=zh-CN en-US" example:"en-US"`                                             // 输出语言
+	CodeReviewEnabled  *bool    `json:"code_review_enabled" binding:"omitempty" example:"true"`                                                     // 是否评审 Dev Agent 的提交
+	BlockingSeverities []string `json:"review_blocking_severities" binding:"omitempty,dive,oneof=critical high medium low" example:"critical,high"` // 阻塞的评审严重程度，为空数组时评审意见只记录
+}
+
+// UpdateProjectPromptRequest 覆盖项目提示词模板请求
+type UpdateProjectPromptRequest struct {
+	Content string `json:"content" binding:"required" example:"请你基于PRD文档 @{{.PrdPath}} ..."` // text/template 模板内容，可用字段与对应 Agent 请求一致
+}
+
+// RollbackStageRequest 回滚开发阶段请求
+type RollbackStageRequest struct {
+	Rerun bool `json:"rerun" example:"true"` // 回滚后是否重新执行该阶段
+}
+
+// DeployProjectRequest 部署项目请求
+type DeployProjectRequest struct {
+	Environment string `json:"environment" binding:"omitempty,oneof=dev staging prod" example:"dev"` // 部署环境，为空时部署到 dev
+}
+
+// UpdateUserPromptRequest 覆盖用户提示词模板请求
+type UpdateUserPromptRequest struct {
+	Content  string `json:"content" binding:"required" example:"请你基于PRD文档 @{{.PrdPath}} ..."` // text/template 模板内容，可用字段与对应 Agent 请求一致
+	Language string `json:"language" binding:"omitempty,oneof=zh-CN en-US" example:"zh-CN"`   // 模板语言，为空时使用默认语言
 }
 
 // ProjectListRequest 项目列表请求
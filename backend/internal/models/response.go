@@ -2,6 +2,8 @@ package models
 
 import (
 	"time"
+
+	"github.com/lighthought/app-maker/shared-models/common"
 )
 
 // UserInfo 用户信息（用于响应）
@@ -16,36 +18,42 @@ type UserInfo struct {
 
 // ProjectInfo 项目信息（用于响应）
 type ProjectInfo struct {
-	GUID         string    `json:"guid" example:"e080335a93d0456ba9b65ab407710e55"`
-	Name         string    `json:"name" example:"项目名称"`
-	Description  string    `json:"description" example:"项目描述"`
-	Status       string    `json:"status" example:"in_progress"`
-	Requirements string    `json:"requirements" example:"项目需求"`
-	ProjectPath  string    `json:"project_path" example:"/path/to/project"`
-	BackendPort  int       `json:"backend_port" example:"8080"`
-	FrontendPort int       `json:"frontend_port" example:"3000"`
-	PreviewUrl   string    `json:"preview_url" example:"http://guid.app-maker.localhost"`
-	UserID       string    `json:"user_id" example:"USER_00000000001"`
-	User         UserInfo  `json:"user,omitempty"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	GUID               string    `json:"guid" example:"e080335a93d0456ba9b65ab407710e55"`
+	Name               string    `json:"name" example:"项目名称"`
+	Description        string    `json:"description" example:"项目描述"`
+	Status             string    `json:"status" example:"in_progress"`
+	Requirements       string    `json:"requirements" example:"项目需求"`
+	ProjectPath        string    `json:"project_path" example:"/path/to/project"`
+	BackendPort        int       `json:"backend_port" example:"8080"`
+	FrontendPort       int       `json:"frontend_port" example:"3000"`
+	PreviewUrl         string    `json:"preview_url" example:"http://guid.app-maker.localhost"`
+	Language           string    `json:"language" example:"zh-CN"`
+	CodeReviewEnabled  bool      `json:"code_review_enabled" example:"true"`
+	BlockingSeverities []string  `json:"review_blocking_severities" example:"critical,high"`
+	UserID             string    `json:"user_id" example:"USER_00000000001"`
+	User               UserInfo  `json:"user,omitempty"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
 }
 
 // convertToProjectInfo 将Project模型转换为ProjectInfo响应格式
 func ConvertToProjectInfo(project *Project) *ProjectInfo {
 	projectInfo := &ProjectInfo{
-		GUID:         project.GUID,
-		Name:         project.Name,
-		Description:  project.Description,
-		Status:       project.Status,
-		Requirements: project.Requirements,
-		ProjectPath:  project.ProjectPath,
-		BackendPort:  project.BackendPort,
-		FrontendPort: project.FrontendPort,
-		PreviewUrl:   project.PreviewUrl,
-		UserID:       project.UserID,
-		CreatedAt:    project.CreatedAt,
-		UpdatedAt:    project.UpdatedAt,
+		GUID:               project.GUID,
+		Name:               project.Name,
+		Description:        project.Description,
+		Status:             project.Status,
+		Requirements:       project.Requirements,
+		ProjectPath:        project.ProjectPath,
+		BackendPort:        project.BackendPort,
+		FrontendPort:       project.FrontendPort,
+		PreviewUrl:         project.PreviewUrl,
+		Language:           common.NormalizeLanguage(project.Language),
+		CodeReviewEnabled:  project.CodeReviewEnabled,
+		BlockingSeverities: project.BlockingSeverities,
+		UserID:             project.UserID,
+		CreatedAt:          project.CreatedAt,
+		UpdatedAt:          project.UpdatedAt,
 	}
 
 	// 转换用户信息
@@ -78,5 +86,25 @@ type UserSettingsResponse struct {
 	DefaultModelProvider string `json:"default_model_provider" example:"zhipu"`
 	DefaultModelApiUrl   string `json:"default_model_api_url" example:"https://open.bigmodel.cn/api/anthropic"`
 	DefaultApiToken      string `json:"default_api_token,omitempty" example:"sk-***"` // 敏感信息，前端可能需要脱敏显示
+	DefaultLanguage      string `json:"default_language" example:"zh-CN"`             // 新建项目的默认输出语言
 	AutoGoNext           bool   `json:"auto_go_next" example:"true"`                  // 自动进入下一阶段配置
 }
+
+// ProjectUsageResponse 项目用量响应
+type ProjectUsageResponse struct {
+	ProjectGuid string            `json:"project_guid"`
+	Total       UsageSummary      `json:"total"`  // 项目累计用量
+	Stages      []*StageUsage     `json:"stages"` // 按开发阶段汇总，聊天任务的阶段为空
+	Tasks       []*AgentTaskUsage `json:"tasks"`  // 每个 Agent 任务的用量，按时间倒序
+}
+
+// UserUsageResponse 用户本月用量响应
+type UserUsageResponse struct {
+	UserID       string          `json:"user_id"`
+	Month        string          `json:"month" example:"2025-10"`
+	Total        UsageSummary    `json:"total"`
+	BudgetUsd    float64         `json:"budget_usd" example:"50"`      // 月度预算（美元），0 表示不限制
+	RemainingUsd float64         `json:"remaining_usd" example:"12.5"` // 剩余预算，不限制时为 0
+	Exceeded     bool            `json:"exceeded" example:"false"`     // 是否已超出预算
+	Projects     []*ProjectUsage `json:"projects"`                     // 按项目汇总
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+)
+
+// ReviewComment Dev Agent 提交的代码评审意见，每条意见一行，关联到故事、开发阶段和 Agent 任务
+type ReviewComment struct {
+	ID           string    `json:"id" gorm:"primaryKey;type:varchar(50);default:public.generate_table_id('REVCM', 'public.review_comments_id_num_seq')"`
+	ProjectID    string    `json:"project_id" gorm:"type:varchar(50);not null;index"`
+	ProjectGuid  string    `json:"project_guid" gorm:"type:varchar(50);index"`
+	DevStageID   string    `json:"dev_stage_id" gorm:"type:varchar(50)"`
+	StoryNumber  string    `json:"story_number" gorm:"size:100"`
+	AgentTaskID  string    `json:"agent_task_id" gorm:"type:varchar(50);not null;index"`
+	CommitSha    string    `json:"commit_sha" gorm:"size:50"`              // 被评审的 Dev Agent 提交
+	ReviewStatus string    `json:"review_status" gorm:"size:20"`           // 所在评审的结论：passed, fixed, blocked, failed
+	Round        int       `json:"round" gorm:"not null;default:1"`        // 第几轮评审，Dev Agent 每修复一轮重新评审一次
+	FilePath     string    `json:"file_path" gorm:"size:500"`              // 文件，相对项目根目录
+	Line         int       `json:"line" gorm:"not null;default:0"`         // 行号，不针对具体行时为 0
+	Severity     string    `json:"severity" gorm:"size:20;not null"`       // critical, high, medium, low
+	Message      string    `json:"message" gorm:"type:text;not null"`      // 评审意见
+	Blocking     bool      `json:"blocking" gorm:"not null;default:false"` // 是否为项目配置的阻塞严重程度
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName 指定表名
+func (ReviewComment) TableName() string {
+	return "review_comments"
+}
+
+// NewReviewComments 把 Agent 返回的代码评审结果转换为评审意见
+func NewReviewComments(project *Project, devStageID, agentTaskID string, review *agent.CodeReview) []*ReviewComment {
+	comments := make([]*ReviewComment, 0, len(review.Comments))
+	for _, comment := range review.Comments {
+		comments = append(comments, &ReviewComment{
+			ProjectID:    project.ID,
+			ProjectGuid:  project.GUID,
+			DevStageID:   devStageID,
+			StoryNumber:  review.StoryNumber,
+			AgentTaskID:  agentTaskID,
+			CommitSha:    review.CommitSha,
+			ReviewStatus: review.Status,
+			Round:        comment.Round,
+			FilePath:     comment.File,
+			Line:         comment.Line,
+			Severity:     comment.Severity,
+			Message:      comment.Message,
+			Blocking:     comment.Blocking,
+		})
+	}
+	return comments
+}
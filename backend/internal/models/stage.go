@@ -2,6 +2,7 @@ package models
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"github.com/lighthought/app-maker/shared-models/agent"
@@ -13,32 +14,38 @@ import (
 
 // DevStage 开发阶段模型
 type DevStage struct {
-	ID           string         `json:"id" gorm:"primaryKey;type:varchar(50);default:public.generate_table_id('STAGE', 'public.dev_stages_id_num_seq')"`
-	ProjectID    string         `json:"project_id" gorm:"type:varchar(50);not null"`
-	ProjectGuid  string         `json:"project_guid" gorm:"type:varchar(50);"`
-	Name         string         `json:"name" gorm:"size:100;not null"`
-	Status       string         `json:"status" gorm:"size:20;not null;default:'pending'"` // pending, in_progress, completed, failed
-	Progress     int            `json:"progress" gorm:"default:0"`                        // 0-100
-	Description  string         `json:"description" gorm:"type:text"`
-	FailedReason string         `json:"failed_reason" gorm:"type:text"`
-	TaskID       string         `json:"task_id" gorm:"type:varchar(50)"`
-	StartedAt    *time.Time     `json:"started_at"`
-	CompletedAt  *time.Time     `json:"completed_at"`
-	CreatedAt    time.Time      `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt    time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
-	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+	ID            string         `json:"id" gorm:"primaryKey;type:varchar(50);default:public.generate_table_id('STAGE', 'public.dev_stages_id_num_seq')"`
+	ProjectID     string         `json:"project_id" gorm:"type:varchar(50);not null"`
+	ProjectGuid   string         `json:"project_guid" gorm:"type:varchar(50);"`
+	Name          string         `json:"name" gorm:"size:100;not null"`
+	Status        string         `json:"status" gorm:"size:20;not null;default:'pending'"` // pending, in_progress, completed, failed
+	Progress      int            `json:"progress" gorm:"default:0"`                        // 0-100
+	Description   string         `json:"description" gorm:"type:text"`
+	FailedReason  string         `json:"failed_reason" gorm:"type:text"`
+	TaskID        string         `json:"task_id" gorm:"type:varchar(50)"`
+	AgentTaskID   string         `json:"agent_task_id" gorm:"type:text"`          // 阶段在 Agent 服务中的任务ID，用于取消；开发故事阶段每个故事一个任务，以逗号分隔
+	PreCommitSha  string         `json:"pre_commit_sha" gorm:"type:varchar(64)"`  // 阶段开始执行前 Agent 工作区主干的提交，用于回滚
+	PostCommitSha string         `json:"post_commit_sha" gorm:"type:varchar(64)"` // 阶段执行完成后 Agent 工作区主干的提交
+	StartedAt     *time.Time     `json:"started_at"`
+	CompletedAt   *time.Time     `json:"completed_at"`
+	CreatedAt     time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
 type DevStageInfo struct {
-	ID           string `json:"id" gorm:"primaryKey;type:varchar(50);default:public.generate_table_id('STAGE', 'public.dev_stages_id_num_seq')"`
-	ProjectID    string `json:"project_id" gorm:"type:varchar(50);not null"`
-	ProjectGuid  string `json:"project_guid" gorm:"type:varchar(50);"`
-	Name         string `json:"name" gorm:"size:100;not null"`
-	Status       string `json:"status" gorm:"size:20;not null;default:'pending'"` // pending, in_progress, completed, failed
-	Progress     int    `json:"progress" gorm:"default:0"`                        // 0-100
-	Description  string `json:"description" gorm:"type:text"`
-	FailedReason string `json:"failed_reason" gorm:"type:text"`
-	TaskID       string `json:"task_id" gorm:"type:varchar(50)"`
+	ID            string `json:"id" gorm:"primaryKey;type:varchar(50);default:public.generate_table_id('STAGE', 'public.dev_stages_id_num_seq')"`
+	ProjectID     string `json:"project_id" gorm:"type:varchar(50);not null"`
+	ProjectGuid   string `json:"project_guid" gorm:"type:varchar(50);"`
+	Name          string `json:"name" gorm:"size:100;not null"`
+	Status        string `json:"status" gorm:"size:20;not null;default:'pending'"` // pending, in_progress, completed, failed
+	Progress      int    `json:"progress" gorm:"default:0"`                        // 0-100
+	Description   string `json:"description" gorm:"type:text"`
+	FailedReason  string `json:"failed_reason" gorm:"type:text"`
+	TaskID        string `json:"task_id" gorm:"type:varchar(50)"`
+	AgentTaskID   string `json:"agent_task_id" gorm:"type:text"`
+	PreCommitSha  string `json:"pre_commit_sha" gorm:"type:varchar(64)"`
+	PostCommitSha string `json:"post_commit_sha" gorm:"type:varchar(64)"`
 }
 
 func (ds *DevStageInfo) CopyFromDevStage(other *DevStage) {
@@ -51,6 +58,9 @@ func (ds *DevStageInfo) CopyFromDevStage(other *DevStage) {
 	ds.Description = other.Description
 	ds.FailedReason = other.FailedReason
 	ds.TaskID = other.TaskID
+	ds.AgentTaskID = other.AgentTaskID
+	ds.PreCommitSha = other.PreCommitSha
+	ds.PostCommitSha = other.PostCommitSha
 }
 
 func (ds *DevStageInfo) Copy(other *DevStageInfo) {
@@ -63,6 +73,43 @@ func (ds *DevStageInfo) Copy(other *DevStageInfo) {
 	ds.Description = other.Description
 	ds.FailedReason = other.FailedReason
 	ds.TaskID = other.TaskID
+	ds.AgentTaskID = other.AgentTaskID
+	ds.PreCommitSha = other.PreCommitSha
+	ds.PostCommitSha = other.PostCommitSha
+}
+
+// GetAgentTaskIDs 阶段在 Agent 服务中的全部任务ID
+func (ds *DevStage) GetAgentTaskIDs() []string {
+	var taskIDs []string
+	for _, taskID := range strings.Split(ds.AgentTaskID, ",") {
+		if taskID = strings.TrimSpace(taskID); taskID != "" {
+			taskIDs = append(taskIDs, taskID)
+		}
+	}
+	return taskIDs
+}
+
+// AddAgentTaskID 追加新创建的 Agent 任务，已存在时不重复追加
+func (ds *DevStage) AddAgentTaskID(taskID string) {
+	taskIDs := ds.GetAgentTaskIDs()
+	for _, id := range taskIDs {
+		if id == taskID {
+			return
+		}
+	}
+	ds.AgentTaskID = strings.Join(append(taskIDs, taskID), ",")
+}
+
+// RemoveAgentTaskID 移除已结束的 Agent 任务，返回剩余未结束的任务ID
+func (ds *DevStage) RemoveAgentTaskID(taskID string) []string {
+	var remaining []string
+	for _, id := range ds.GetAgentTaskIDs() {
+		if id != taskID {
+			remaining = append(remaining, id)
+		}
+	}
+	ds.AgentTaskID = strings.Join(remaining, ",")
+	return remaining
 }
 
 func (DevStage) TableName() string {
@@ -80,7 +127,7 @@ func NewDevStage(project *Project, stageName common.DevStatus, status string) *D
 		Status:      status,
 		TaskID:      project.CurrentTaskID,
 		Progress:    common.GetProgressByCommonStatus(status),
-		Description: common.GetDevStageDescription(stageName),
+		Description: common.GetDevStageDescription(stageName, project.Language),
 	}
 }
 
@@ -90,6 +137,15 @@ func (ds *DevStage) SetStatus(status string) {
 	ds.FailedReason = ""
 }
 
+// ResetToPending 回滚后将阶段重置为待执行，保留执行前的提交用于再次回滚
+func (ds *DevStage) ResetToPending() {
+	ds.SetStatus(common.CommonStatusPending)
+	ds.AgentTaskID = ""
+	ds.StartedAt = nil
+	ds.CompletedAt = nil
+	ds.PostCommitSha = ""
+}
+
 // BeforeCreate 创建前的钩子
 func (ds *DevStage) BeforeCreate(tx *gorm.DB) error {
 	if ds.ID == "" {
@@ -105,10 +161,10 @@ func (ds *DevStage) BeforeCreate(tx *gorm.DB) error {
 
 // DevStageItem 开发阶段项
 type DevStageItem struct {
-	Name          common.DevStatus                                                              // 阶段名称
-	Desc          string                                                                        // 阶段描述
-	NeedConfirm   bool                                                                          // 是否需要确认
-	SkipInDevMode bool                                                                          // 在开发模式下是否跳过，跳过则不执行
-	ReqHandler    func(context.Context, *Project) (string, error)                               // 阶段执行器
-	RespHandler   func(context.Context, *agent.AgentTaskStatusMessage, *tasks.TaskResult) error // 阶段响应处理器
+	Name          common.DevStatus                                                                        // 阶段名称
+	Desc          string                                                                                  // 阶段描述
+	NeedConfirm   bool                                                                                    // 是否需要确认
+	SkipInDevMode bool                                                                                    // 在开发模式下是否跳过，跳过则不执行
+	ReqHandler    func(context.Context, *Project) (string, error)                                         // 阶段执行器
+	RespHandler   func(context.Context, *Project, *agent.AgentTaskStatusMessage, *tasks.TaskResult) error // 阶段响应处理器，project 为处理该响应时加载的项目
 }
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDevStageAgentTaskIDs(t *testing.T) {
+	stage := &DevStage{AgentTaskID: "task-1, task-2,,task-3"}
+	if got := stage.GetAgentTaskIDs(); !reflect.DeepEqual(got, []string{"task-1", "task-2", "task-3"}) {
+		t.Fatalf("GetAgentTaskIDs() = %v", got)
+	}
+
+	if remaining := stage.RemoveAgentTaskID("task-2"); !reflect.DeepEqual(remaining, []string{"task-1", "task-3"}) {
+		t.Errorf("RemoveAgentTaskID() = %v", remaining)
+	}
+	if stage.AgentTaskID != "task-1,task-3" {
+		t.Errorf("AgentTaskID = %q", stage.AgentTaskID)
+	}
+
+	// 移除不存在的任务不影响剩余任务
+	stage.RemoveAgentTaskID("unknown")
+	stage.RemoveAgentTaskID("task-1")
+	if remaining := stage.RemoveAgentTaskID("task-3"); len(remaining) != 0 || stage.AgentTaskID != "" {
+		t.Errorf("remaining = %v, AgentTaskID = %q", remaining, stage.AgentTaskID)
+	}
+
+	stage.AddAgentTaskID("task-4")
+	stage.AddAgentTaskID("task-5")
+	stage.AddAgentTaskID("task-4")
+	if stage.AgentTaskID != "task-4,task-5" {
+		t.Errorf("AgentTaskID = %q, want task-4,task-5", stage.AgentTaskID)
+	}
+}
+
+func TestDevStageResetToPending(t *testing.T) {
+	now := time.Now()
+	stage := &DevStage{
+		Status:        "failed",
+		Progress:      100,
+		FailedReason:  "boom",
+		AgentTaskID:   "task-1",
+		StartedAt:     &now,
+		CompletedAt:   &now,
+		PreCommitSha:  "aaa",
+		PostCommitSha: "bbb",
+	}
+	stage.ResetToPending()
+
+	if stage.Status != "pending" || stage.FailedReason != "" || stage.AgentTaskID != "" {
+		t.Errorf("stage = %+v, want pending without task", stage)
+	}
+	if stage.StartedAt != nil || stage.CompletedAt != nil || stage.PostCommitSha != "" {
+		t.Errorf("stage = %+v, want execution fields cleared", stage)
+	}
+	if stage.PreCommitSha != "aaa" {
+		t.Errorf("PreCommitSha = %q, want kept for another rollback", stage.PreCommitSha)
+	}
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+)
+
+// TestReport 项目自动测试报告，每个测试 Agent 任务一条，关联项目和开发阶段
+type TestReport struct {
+	ID          string             `json:"id" gorm:"primaryKey;type:varchar(50);default:public.generate_table_id('TREPORT', 'public.test_reports_id_num_seq')"`
+	ProjectID   string             `json:"project_id" gorm:"type:varchar(50);not null;index"`
+	ProjectGuid string             `json:"project_guid" gorm:"type:varchar(50);index"`
+	DevStageID  string             `json:"dev_stage_id" gorm:"type:varchar(50);index"`
+	DevStage    string             `json:"dev_stage" gorm:"size:100"`
+	AgentTaskID string             `json:"agent_task_id" gorm:"type:varchar(50);not null;uniqueIndex"`
+	Status      string             `json:"status" gorm:"size:20"` // passed, failed
+	Total       int                `json:"total" gorm:"default:0"`
+	Passed      int                `json:"passed" gorm:"default:0"`
+	Failed      int                `json:"failed" gorm:"default:0"`
+	Skipped     int                `json:"skipped" gorm:"default:0"`
+	DurationMs  int64              `json:"duration_ms" gorm:"default:0"`
+	Coverage    *float64           `json:"coverage,omitempty" gorm:"type:numeric(5,2)"` // 语句覆盖率百分比，没有覆盖率数据时为空
+	Suites      []*agent.TestSuite `json:"suites" gorm:"type:text;serializer:json"`     // 各测试套件、用例和失败原因
+	Summary     string             `json:"summary" gorm:"type:text"`                    // Agent 返回的测试结果摘要
+	CreatedAt   time.Time          `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName 指定表名
+func (TestReport) TableName() string {
+	return "test_reports"
+}
+
+// CopyFromAgentReport 从 Agent 返回的测试报告复制
+func (r *TestReport) CopyFromAgentReport(report *agent.TestReport) {
+	r.Status = report.Status
+	r.Total = report.Total
+	r.Passed = report.Passed
+	r.Failed = report.Failed
+	r.Skipped = report.Skipped
+	r.DurationMs = report.DurationMs
+	r.Coverage = report.Coverage
+	r.Suites = report.Suites
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+)
+
+// AgentTaskUsage Agent 任务用量记录，每个 Agent 任务一条，同时关联用户、项目和开发阶段
+type AgentTaskUsage struct {
+	ID                       string    `json:"id" gorm:"primaryKey;type:varchar(50);default:public.generate_table_id('USAGE', 'public.agent_task_usages_id_num_seq')"`
+	AgentTaskID              string    `json:"agent_task_id" gorm:"type:varchar(50);not null;uniqueIndex"`
+	UserID                   string    `json:"user_id" gorm:"type:varchar(50);not null;index"`
+	ProjectID                string    `json:"project_id" gorm:"type:varchar(50);not null;index"`
+	ProjectGuid              string    `json:"project_guid" gorm:"type:varchar(50)"`
+	DevStageID               string    `json:"dev_stage_id" gorm:"type:varchar(50);index"` // 聊天任务没有开发阶段
+	DevStage                 string    `json:"dev_stage" gorm:"size:100"`
+	AgentType                string    `json:"agent_type" gorm:"size:50"`
+	CliTool                  string    `json:"cli_tool" gorm:"size:50"`
+	Status                   string    `json:"status" gorm:"size:20"` // 任务结束时的状态：done, failed
+	InputTokens              int       `json:"input_tokens" gorm:"default:0"`
+	OutputTokens             int       `json:"output_tokens" gorm:"default:0"`
+	CacheCreationInputTokens int       `json:"cache_creation_input_tokens" gorm:"default:0"`
+	CacheReadInputTokens     int       `json:"cache_read_input_tokens" gorm:"default:0"`
+	CostUsd                  float64   `json:"cost_usd" gorm:"type:numeric(12,6);default:0"`
+	DurationMs               int       `json:"duration_ms" gorm:"default:0"`
+	UsageUnknown             bool      `json:"usage_unknown" gorm:"default:false"` // CLI 不输出用量，token 和费用未知，不计入预算
+	CreatedAt                time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName 指定表名
+func (AgentTaskUsage) TableName() string {
+	return "agent_task_usages"
+}
+
+// CopyFromAgentUsage 从 Agent 上报的用量复制
+func (u *AgentTaskUsage) CopyFromAgentUsage(usage *agent.AgentUsage) {
+	u.CliTool = usage.CliTool
+	u.InputTokens = usage.InputTokens
+	u.OutputTokens = usage.OutputTokens
+	u.CacheCreationInputTokens = usage.CacheCreationInputTokens
+	u.CacheReadInputTokens = usage.CacheReadInputTokens
+	u.CostUsd = usage.CostUsd
+	u.DurationMs = usage.DurationMs
+	u.UsageUnknown = usage.UsageUnknown
+}
+
+// UsageSummary 用量汇总
+type UsageSummary struct {
+	TaskCount                int64   `json:"task_count"`
+	InputTokens              int64   `json:"input_tokens"`
+	OutputTokens             int64   `json:"output_tokens"`
+	CacheCreationInputTokens int64   `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int64   `json:"cache_read_input_tokens"`
+	CostUsd                  float64 `json:"cost_usd"`
+	DurationMs               int64   `json:"duration_ms"`
+	UnknownUsageCount        int64   `json:"unknown_usage_count"` // 用量未知的任务数，这些任务的 token 和费用没有计入
+}
+
+// StageUsage 开发阶段用量汇总
+type StageUsage struct {
+	DevStageID   string `json:"dev_stage_id"`
+	DevStage     string `json:"dev_stage"`
+	UsageSummary `gorm:"embedded"`
+}
+
+// ProjectUsage 项目用量汇总
+type ProjectUsage struct {
+	ProjectID    string `json:"project_id"`
+	ProjectGuid  string `json:"project_guid"`
+	UsageSummary `gorm:"embedded"`
+}
@@ -18,8 +18,10 @@ type User struct {
 	DefaultAiModel       string         `json:"default_ai_model" gorm:"size:100;default:'glm-4.6'"`
 	DefaultModelProvider string         `json:"default_model_provider" gorm:"size:50;default:'zhipu'"`
 	DefaultModelApiUrl   string         `json:"default_model_api_url" gorm:"size:500"`
-	DefaultApiToken      string         `json:"default_api_token,omitempty" gorm:"size:500"` // API Token，敏感信息
-	AutoGoNext           bool           `json:"auto_go_next" gorm:"default:true"`            // 自动进入下一阶段配置
+	DefaultApiToken      string         `json:"default_api_token,omitempty" gorm:"size:500"`     // API Token，敏感信息
+	DefaultLanguage      string         `json:"default_language" gorm:"size:20;default:'zh-CN'"` // 新建项目的默认输出语言
+	AutoGoNext           bool           `json:"auto_go_next" gorm:"default:true"`                // 自动进入下一阶段配置
+	MonthlyBudgetUsd     *float64       `json:"monthly_budget_usd" gorm:"type:numeric(12,4)"`    // 月度预算（美元），为空时使用系统默认预算，0 表示不限制
 	CreatedAt            time.Time      `json:"created_at"`
 	UpdatedAt            time.Time      `json:"updated_at"`
 	DeletedAt            gorm.DeletedAt `json:"-" gorm:"index"`
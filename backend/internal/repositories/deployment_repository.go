@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/lighthought/app-maker/backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// DeploymentRepository 部署记录仓库接口
+type DeploymentRepository interface {
+	// Create 创建部署记录
+	Create(ctx context.Context, deployment *models.Deployment) error
+
+	// GetByID 获取部署记录
+	GetByID(ctx context.Context, id string) (*models.Deployment, error)
+
+	// Update 更新部署记录
+	Update(ctx context.Context, deployment *models.Deployment) error
+
+	// ListByProjectGuid 获取项目的部署记录，可按环境过滤，按时间倒序
+	ListByProjectGuid(ctx context.Context, projectGuid, environment string, limit int) ([]*models.Deployment, error)
+}
+
+type deploymentRepository struct {
+	db *gorm.DB
+}
+
+// NewDeploymentRepository 创建部署记录仓库实例
+func NewDeploymentRepository(db *gorm.DB) DeploymentRepository {
+	return &deploymentRepository{db: db}
+}
+
+func (r *deploymentRepository) Create(ctx context.Context, deployment *models.Deployment) error {
+	return r.db.WithContext(ctx).Create(deployment).Error
+}
+
+func (r *deploymentRepository) GetByID(ctx context.Context, id string) (*models.Deployment, error) {
+	var deployment models.Deployment
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&deployment).Error; err != nil {
+		return nil, err
+	}
+	return &deployment, nil
+}
+
+func (r *deploymentRepository) Update(ctx context.Context, deployment *models.Deployment) error {
+	return r.db.WithContext(ctx).Save(deployment).Error
+}
+
+func (r *deploymentRepository) ListByProjectGuid(ctx context.Context, projectGuid, environment string, limit int) ([]*models.Deployment, error) {
+	var deployments []*models.Deployment
+	query := r.db.WithContext(ctx).
+		Where("project_guid = ?", projectGuid)
+	if environment != "" {
+		query = query.Where("environment = ?", environment)
+	}
+	query = query.Order("created_at DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err := query.Find(&deployments).Error
+	return deployments, err
+}
@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"github.com/lighthought/app-maker/backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// PromptRepository 项目提示词模板仓库接口
+type PromptRepository interface {
+	// GetByProjectIDAndName 获取项目覆盖的提示词模板，项目未覆盖时返回 nil
+	GetByProjectIDAndName(ctx context.Context, projectID, name string) (*models.ProjectPrompt, error)
+
+	// ListByProjectID 获取项目覆盖的所有提示词模板
+	ListByProjectID(ctx context.Context, projectID string) ([]*models.ProjectPrompt, error)
+
+	// Create 创建项目提示词模板
+	Create(ctx context.Context, prompt *models.ProjectPrompt) error
+
+	// Update 更新项目提示词模板
+	Update(ctx context.Context, prompt *models.ProjectPrompt) error
+
+	// DeleteByProjectIDAndName 删除项目覆盖的提示词模板
+	DeleteByProjectIDAndName(ctx context.Context, projectID, name string) error
+}
+
+type promptRepository struct {
+	db *gorm.DB
+}
+
+// NewPromptRepository 创建项目提示词模板仓库实例
+func NewPromptRepository(db *gorm.DB) PromptRepository {
+	return &promptRepository{db: db}
+}
+
+func (r *promptRepository) GetByProjectIDAndName(ctx context.Context, projectID, name string) (*models.ProjectPrompt, error) {
+	var prompt models.ProjectPrompt
+	err := r.db.WithContext(ctx).
+		Where("project_id = ? AND name = ?", projectID, name).
+		First(&prompt).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &prompt, nil
+}
+
+func (r *promptRepository) ListByProjectID(ctx context.Context, projectID string) ([]*models.ProjectPrompt, error) {
+	var prompts []*models.ProjectPrompt
+	err := r.db.WithContext(ctx).
+		Where("project_id = ?", projectID).
+		Order("name ASC").
+		Find(&prompts).Error
+	return prompts, err
+}
+
+func (r *promptRepository) Create(ctx context.Context, prompt *models.ProjectPrompt) error {
+	return r.db.WithContext(ctx).Create(prompt).Error
+}
+
+func (r *promptRepository) Update(ctx context.Context, prompt *models.ProjectPrompt) error {
+	return r.db.WithContext(ctx).Save(prompt).Error
+}
+
+func (r *promptRepository) DeleteByProjectIDAndName(ctx context.Context, projectID, name string) error {
+	return r.db.WithContext(ctx).
+		Where("project_id = ? AND name = ?", projectID, name).
+		Delete(&models.ProjectPrompt{}).Error
+}
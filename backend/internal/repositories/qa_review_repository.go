@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/lighthought/app-maker/backend/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// QAReviewRepository QA 评审仓库接口
+type QAReviewRepository interface {
+	// Create 创建 QA 评审，同一 Agent 任务重复上报时忽略
+	Create(ctx context.Context, review *models.QAReview) error
+
+	// ListByProjectGuid 获取项目的 QA 评审，按时间倒序
+	ListByProjectGuid(ctx context.Context, projectGuid string, limit int) ([]*models.QAReview, error)
+
+	// ListLatestByProjectGuid 获取项目每个故事最新的 QA 评审，按故事编号排序
+	ListLatestByProjectGuid(ctx context.Context, projectGuid string) ([]*models.QAReview, error)
+}
+
+type qaReviewRepository struct {
+	db *gorm.DB
+}
+
+// NewQAReviewRepository 创建 QA 评审仓库实例
+func NewQAReviewRepository(db *gorm.DB) QAReviewRepository {
+	return &qaReviewRepository{db: db}
+}
+
+func (r *qaReviewRepository) Create(ctx context.Context, review *models.QAReview) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "agent_task_id"}}, DoNothing: true}).
+		Create(review).Error
+}
+
+func (r *qaReviewRepository) ListByProjectGuid(ctx context.Context, projectGuid string, limit int) ([]*models.QAReview, error) {
+	var reviews []*models.QAReview
+	query := r.db.WithContext(ctx).
+		Where("project_guid = ?", projectGuid).
+		Order("created_at DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err := query.Find(&reviews).Error
+	return reviews, err
+}
+
+func (r *qaReviewRepository) ListLatestByProjectGuid(ctx context.Context, projectGuid string) ([]*models.QAReview, error) {
+	var reviews []*models.QAReview
+	err := r.db.WithContext(ctx).
+		Select("DISTINCT ON (story_number) *").
+		Where("project_guid = ?", projectGuid).
+		Order("story_number, created_at DESC").
+		Find(&reviews).Error
+	return reviews, err
+}
@@ -3,23 +3,37 @@ package repositories
 import "gorm.io/gorm"
 
 type Repository struct {
-	EpicRepo         EpicRepository
-	MessageRepo      MessageRepository
-	PreviewTokenRepo PreviewTokenRepository
-	ProjectRepo      ProjectRepository
-	ProjectStageRepo StageRepository
-	StoryRepo        StoryRepository
-	UserRepo         UserRepository
+	DeploymentRepo    DeploymentRepository
+	EpicRepo          EpicRepository
+	MessageRepo       MessageRepository
+	PreviewTokenRepo  PreviewTokenRepository
+	ProjectRepo       ProjectRepository
+	ProjectStageRepo  StageRepository
+	PromptRepo        PromptRepository
+	QAReviewRepo      QAReviewRepository
+	ReviewCommentRepo ReviewCommentRepository
+	StoryRepo         StoryRepository
+	TestReportRepo    TestReportRepository
+	UsageRepo         UsageRepository
+	UserPromptRepo    UserPromptRepository
+	UserRepo          UserRepository
 }
 
 func NewRepositories(db *gorm.DB) *Repository {
 	return &Repository{
-		EpicRepo:         NewEpicRepository(db),
-		MessageRepo:      NewMessageRepository(db),
-		PreviewTokenRepo: NewPreviewTokenRepository(db),
-		ProjectRepo:      NewProjectRepository(db),
-		ProjectStageRepo: NewStageRepository(db),
-		StoryRepo:        NewStoryRepository(db),
-		UserRepo:         NewUserRepository(db),
+		DeploymentRepo:    NewDeploymentRepository(db),
+		EpicRepo:          NewEpicRepository(db),
+		MessageRepo:       NewMessageRepository(db),
+		PreviewTokenRepo:  NewPreviewTokenRepository(db),
+		ProjectRepo:       NewProjectRepository(db),
+		ProjectStageRepo:  NewStageRepository(db),
+		PromptRepo:        NewPromptRepository(db),
+		QAReviewRepo:      NewQAReviewRepository(db),
+		ReviewCommentRepo: NewReviewCommentRepository(db),
+		StoryRepo:         NewStoryRepository(db),
+		TestReportRepo:    NewTestReportRepository(db),
+		UsageRepo:         NewUsageRepository(db),
+		UserPromptRepo:    NewUserPromptRepository(db),
+		UserRepo:          NewUserRepository(db),
 	}
 }
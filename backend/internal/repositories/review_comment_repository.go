@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/lighthought/app-maker/backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ReviewCommentRepository 代码评审意见仓库接口
+type ReviewCommentRepository interface {
+	// ReplaceByAgentTask 保存 Agent 任务的代码评审意见，同一任务重复上报时替换已有的意见
+	ReplaceByAgentTask(ctx context.Context, agentTaskID string, comments []*models.ReviewComment) error
+
+	// ListByProjectGuid 获取项目的代码评审意见，storyNumber 不为空时只返回该故事的，按时间倒序、轮次和行号排序
+	ListByProjectGuid(ctx context.Context, projectGuid, storyNumber string, limit int) ([]*models.ReviewComment, error)
+}
+
+type reviewCommentRepository struct {
+	db *gorm.DB
+}
+
+// NewReviewCommentRepository 创建代码评审意见仓库实例
+func NewReviewCommentRepository(db *gorm.DB) ReviewCommentRepository {
+	return &reviewCommentRepository{db: db}
+}
+
+func (r *reviewCommentRepository) ReplaceByAgentTask(ctx context.Context, agentTaskID string, comments []*models.ReviewComment) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("agent_task_id = ?", agentTaskID).Delete(&models.ReviewComment{}).Error; err != nil {
+			return err
+		}
+		if len(comments) == 0 {
+			return nil
+		}
+		return tx.Create(&comments).Error
+	})
+}
+
+func (r *reviewCommentRepository) ListByProjectGuid(ctx context.Context, projectGuid, storyNumber string, limit int) ([]*models.ReviewComment, error) {
+	var comments []*models.ReviewComment
+	query := r.db.WithContext(ctx).Where("project_guid = ?", projectGuid)
+	if storyNumber != "" {
+		query = query.Where("story_number = ?", storyNumber)
+	}
+	query = query.Order("created_at DESC, round, file_path, line")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err := query.Find(&comments).Error
+	return comments, err
+}
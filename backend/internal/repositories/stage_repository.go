@@ -8,6 +8,7 @@ import (
 	"github.com/lighthought/app-maker/shared-models/common"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // StageRepository 开发阶段仓库接口
@@ -30,9 +31,18 @@ type StageRepository interface {
 	// GetByID 根据ID获取开发阶段
 	GetByID(ctx context.Context, id string) (*models.DevStage, error)
 
-	// Update 更新开发阶段
+	// Update 更新开发阶段，不更新 Agent 任务ID，任务ID只通过下面的方法修改
 	Update(ctx context.Context, stage *models.DevStage) error
 
+	// AddAgentTaskID 追加阶段新创建的 Agent 任务ID
+	AddAgentTaskID(ctx context.Context, id, taskID string) error
+
+	// RemoveAgentTaskID 移除已结束的 Agent 任务ID，返回剩余未结束的任务ID
+	RemoveAgentTaskID(ctx context.Context, id, taskID string) ([]string, error)
+
+	// ClearAgentTaskIDs 清除阶段的 Agent 任务ID
+	ClearAgentTaskIDs(ctx context.Context, id string) error
+
 	// Delete 删除开发阶段
 	Delete(ctx context.Context, id string) error
 
@@ -107,7 +117,49 @@ func (r *stageRepository) GetByID(ctx context.Context, id string) (*models.DevSt
 }
 
 func (r *stageRepository) Update(ctx context.Context, stage *models.DevStage) error {
-	return r.db.WithContext(ctx).Save(stage).Error
+	// 阶段对象可能在 Agent 任务ID变化前加载，保存整个对象会覆盖其他请求追加或移除的任务ID
+	return r.db.WithContext(ctx).Omit("agent_task_id").Save(stage).Error
+}
+
+func (r *stageRepository) AddAgentTaskID(ctx context.Context, id, taskID string) error {
+	_, err := r.updateAgentTaskIDs(ctx, id, func(stage *models.DevStage) {
+		stage.AddAgentTaskID(taskID)
+	})
+	return err
+}
+
+func (r *stageRepository) RemoveAgentTaskID(ctx context.Context, id, taskID string) ([]string, error) {
+	return r.updateAgentTaskIDs(ctx, id, func(stage *models.DevStage) {
+		stage.RemoveAgentTaskID(taskID)
+	})
+}
+
+func (r *stageRepository) ClearAgentTaskIDs(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).
+		Model(&models.DevStage{}).
+		Where("id = ?", id).
+		Update("agent_task_id", "").Error
+}
+
+// updateAgentTaskIDs 锁定阶段记录后修改 Agent 任务ID，同时结束的多个任务不会互相覆盖，返回修改后的任务ID
+func (r *stageRepository) updateAgentTaskIDs(ctx context.Context, id string, update func(stage *models.DevStage)) ([]string, error) {
+	var taskIDs []string
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var stage models.DevStage
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "agent_task_id").
+			Where("id = ?", id).
+			First(&stage).Error; err != nil {
+			return err
+		}
+
+		update(&stage)
+		taskIDs = stage.GetAgentTaskIDs()
+		return tx.Model(&models.DevStage{}).
+			Where("id = ?", id).
+			Update("agent_task_id", stage.AgentTaskID).Error
+	})
+	return taskIDs, err
 }
 
 func (r *stageRepository) Delete(ctx context.Context, id string) error {
@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/lighthought/app-maker/backend/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TestReportRepository 测试报告仓库接口
+type TestReportRepository interface {
+	// Create 创建测试报告，同一 Agent 任务重复上报时忽略
+	Create(ctx context.Context, report *models.TestReport) error
+
+	// ListByProjectGuid 获取项目的测试报告，可按开发阶段过滤，按时间倒序
+	ListByProjectGuid(ctx context.Context, projectGuid, devStage string, limit int) ([]*models.TestReport, error)
+}
+
+type testReportRepository struct {
+	db *gorm.DB
+}
+
+// NewTestReportRepository 创建测试报告仓库实例
+func NewTestReportRepository(db *gorm.DB) TestReportRepository {
+	return &testReportRepository{db: db}
+}
+
+func (r *testReportRepository) Create(ctx context.Context, report *models.TestReport) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "agent_task_id"}}, DoNothing: true}).
+		Create(report).Error
+}
+
+func (r *testReportRepository) ListByProjectGuid(ctx context.Context, projectGuid, devStage string, limit int) ([]*models.TestReport, error) {
+	var reports []*models.TestReport
+	query := r.db.WithContext(ctx).
+		Where("project_guid = ?", projectGuid)
+	if devStage != "" {
+		query = query.Where("dev_stage = ?", devStage)
+	}
+	query = query.Order("created_at DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err := query.Find(&reports).Error
+	return reports, err
+}
@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/lighthought/app-maker/backend/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 用量汇总的查询列
+const usageSummaryColumns = "COUNT(*) AS task_count, " +
+	"COALESCE(SUM(input_tokens), 0) AS input_tokens, " +
+	"COALESCE(SUM(output_tokens), 0) AS output_tokens, " +
+	"COALESCE(SUM(cache_creation_input_tokens), 0) AS cache_creation_input_tokens, " +
+	"COALESCE(SUM(cache_read_input_tokens), 0) AS cache_read_input_tokens, " +
+	"COALESCE(SUM(cost_usd), 0) AS cost_usd, " +
+	"COALESCE(SUM(duration_ms), 0) AS duration_ms, " +
+	"COALESCE(SUM(CASE WHEN usage_unknown THEN 1 ELSE 0 END), 0) AS unknown_usage_count"
+
+// UsageRepository Agent 任务用量仓库接口
+type UsageRepository interface {
+	// Create 创建用量记录，同一 Agent 任务重复上报时忽略
+	Create(ctx context.Context, usage *models.AgentTaskUsage) error
+
+	// ListByProjectID 获取项目的用量记录，按时间倒序
+	ListByProjectID(ctx context.Context, projectID string, limit int) ([]*models.AgentTaskUsage, error)
+
+	// SumByProjectID 汇总项目用量
+	SumByProjectID(ctx context.Context, projectID string) (*models.UsageSummary, error)
+
+	// SumByProjectIDGroupByStage 按开发阶段汇总项目用量
+	SumByProjectIDGroupByStage(ctx context.Context, projectID string) ([]*models.StageUsage, error)
+
+	// SumByUserIDSince 汇总用户从指定时间开始的用量
+	SumByUserIDSince(ctx context.Context, userID string, since time.Time) (*models.UsageSummary, error)
+
+	// SumByUserIDSinceGroupByProject 按项目汇总用户从指定时间开始的用量
+	SumByUserIDSinceGroupByProject(ctx context.Context, userID string, since time.Time) ([]*models.ProjectUsage, error)
+}
+
+type usageRepository struct {
+	db *gorm.DB
+}
+
+// NewUsageRepository 创建用量仓库实例
+func NewUsageRepository(db *gorm.DB) UsageRepository {
+	return &usageRepository{db: db}
+}
+
+func (r *usageRepository) Create(ctx context.Context, usage *models.AgentTaskUsage) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "agent_task_id"}}, DoNothing: true}).
+		Create(usage).Error
+}
+
+func (r *usageRepository) ListByProjectID(ctx context.Context, projectID string, limit int) ([]*models.AgentTaskUsage, error) {
+	var usages []*models.AgentTaskUsage
+	query := r.db.WithContext(ctx).
+		Where("project_id = ?", projectID).
+		Order("created_at DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err := query.Find(&usages).Error
+	return usages, err
+}
+
+func (r *usageRepository) SumByProjectID(ctx context.Context, projectID string) (*models.UsageSummary, error) {
+	var summary models.UsageSummary
+	err := r.db.WithContext(ctx).Model(&models.AgentTaskUsage{}).
+		Select(usageSummaryColumns).
+		Where("project_id = ?", projectID).
+		Scan(&summary).Error
+	if err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+func (r *usageRepository) SumByProjectIDGroupByStage(ctx context.Context, projectID string) ([]*models.StageUsage, error) {
+	var stages []*models.StageUsage
+	err := r.db.WithContext(ctx).Model(&models.AgentTaskUsage{}).
+		Select("dev_stage_id, dev_stage, "+usageSummaryColumns).
+		Where("project_id = ?", projectID).
+		Group("dev_stage_id, dev_stage").
+		Order("MIN(created_at) ASC").
+		Scan(&stages).Error
+	return stages, err
+}
+
+func (r *usageRepository) SumByUserIDSince(ctx context.Context, userID string, since time.Time) (*models.UsageSummary, error) {
+	var summary models.UsageSummary
+	err := r.db.WithContext(ctx).Model(&models.AgentTaskUsage{}).
+		Select(usageSummaryColumns).
+		Where("user_id = ? AND created_at >= ?", userID, since).
+		Scan(&summary).Error
+	if err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+func (r *usageRepository) SumByUserIDSinceGroupByProject(ctx context.Context, userID string, since time.Time) ([]*models.ProjectUsage, error) {
+	var projects []*models.ProjectUsage
+	err := r.db.WithContext(ctx).Model(&models.AgentTaskUsage{}).
+		Select("project_id, project_guid, "+usageSummaryColumns).
+		Where("user_id = ? AND created_at >= ?", userID, since).
+		Group("project_id, project_guid").
+		Order("SUM(cost_usd) DESC").
+		Scan(&projects).Error
+	return projects, err
+}
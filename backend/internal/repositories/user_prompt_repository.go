@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"github.com/lighthought/app-maker/backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// UserPromptRepository 用户提示词模板仓库接口
+type UserPromptRepository interface {
+	// GetByUserIDAndName 获取用户覆盖的指定语言的提示词模板，用户未覆盖时返回 nil
+	GetByUserIDAndName(ctx context.Context, userID, name, language string) (*models.UserPrompt, error)
+
+	// ListByUserID 获取用户覆盖的指定语言的所有提示词模板
+	ListByUserID(ctx context.Context, userID, language string) ([]*models.UserPrompt, error)
+
+	// Create 创建用户提示词模板
+	Create(ctx context.Context, prompt *models.UserPrompt) error
+
+	// Update 更新用户提示词模板
+	Update(ctx context.Context, prompt *models.UserPrompt) error
+
+	// DeleteByUserIDAndName 删除用户覆盖的指定语言的提示词模板
+	DeleteByUserIDAndName(ctx context.Context, userID, name, language string) error
+}
+
+type userPromptRepository struct {
+	db *gorm.DB
+}
+
+// NewUserPromptRepository 创建用户提示词模板仓库实例
+func NewUserPromptRepository(db *gorm.DB) UserPromptRepository {
+	return &userPromptRepository{db: db}
+}
+
+func (r *userPromptRepository) GetByUserIDAndName(ctx context.Context, userID, name, language string) (*models.UserPrompt, error) {
+	var prompt models.UserPrompt
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND name = ? AND language = ?", userID, name, language).
+		First(&prompt).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &prompt, nil
+}
+
+func (r *userPromptRepository) ListByUserID(ctx context.Context, userID, language string) ([]*models.UserPrompt, error) {
+	var prompts []*models.UserPrompt
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND language = ?", userID, language).
+		Order("name ASC").
+		Find(&prompts).Error
+	return prompts, err
+}
+
+func (r *userPromptRepository) Create(ctx context.Context, prompt *models.UserPrompt) error {
+	return r.db.WithContext(ctx).Create(prompt).Error
+}
+
+func (r *userPromptRepository) Update(ctx context.Context, prompt *models.UserPrompt) error {
+	return r.db.WithContext(ctx).Save(prompt).Error
+}
+
+func (r *userPromptRepository) DeleteByUserIDAndName(ctx context.Context, userID, name, language string) error {
+	return r.db.WithContext(ctx).
+		Where("user_id = ? AND name = ? AND language = ?", userID, name, language).
+		Delete(&models.UserPrompt{}).Error
+}
@@ -3,12 +3,14 @@ package services
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/lighthought/app-maker/backend/internal/models"
 	"github.com/lighthought/app-maker/backend/internal/repositories"
 	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/auth"
 	"github.com/lighthought/app-maker/shared-models/client"
 	"github.com/lighthought/app-maker/shared-models/common"
 	"github.com/lighthought/app-maker/shared-models/logger"
@@ -27,9 +29,33 @@ type AgentInteractService interface {
 	// 等待任务完成
 	WaitForTaskCompletion(ctx context.Context, taskID string) (*tasks.TaskResult, error)
 
+	// 获取项目最近的 Agent CLI 输出日志
+	GetProjectAgentLogs(ctx context.Context, projectGuid, taskID string, limit int) ([]*agent.AgentLogMessage, error)
+
+	// 取消 Agent 任务
+	CancelAgentTask(ctx context.Context, taskID string) error
+
+	// 获取项目 Agent 会话列表
+	ListAgentSessions(ctx context.Context, projectGuid string) ([]*agent.AgentSessionInfo, error)
+
+	// 重置项目 Agent 会话，agentType 为空时重置所有 Agent
+	ResetAgentSession(ctx context.Context, projectGuid, agentType string) error
+
 	// 准备项目 Agents 环境
 	SetupAgentsEnviroment(ctx context.Context, project *models.Project) (string, error)
 
+	// 同步项目的模型配置到 Agents 服务
+	UpdateModelConfig(ctx context.Context, project *models.Project) error
+
+	// 删除 Agents 服务保存的项目模型配置
+	DeleteModelConfig(ctx context.Context, projectGuid string) error
+
+	// 获取 Agents 工作区主干分支的最新提交
+	GetGitHead(ctx context.Context, projectGuid string) (*agent.GitHeadInfo, error)
+
+	// 将 Agents 工作区主干分支回滚到指定提交
+	RollbackGit(ctx context.Context, projectGuid, commitSha string) (*agent.GitHeadInfo, error)
+
 	// 检查需求
 	CheckRequirement(ctx context.Context, project *models.Project) (string, error)
 
@@ -54,9 +80,15 @@ type AgentInteractService interface {
 	// 生成前端页面
 	GenerateFrontendPages(ctx context.Context, project *models.Project) (string, error)
 
+	// SM 为待开发的Story起草故事文件
+	DraftStories(ctx context.Context, project *models.Project) (string, error)
+
 	// 开发Story
 	DevelopStories(ctx context.Context, project *models.Project) (string, error)
 
+	// QA 按验收标准评审已开发的Story
+	ReviewStories(ctx context.Context, project *models.Project) (string, error)
+
 	// 修复Bug
 	FixBugs(ctx context.Context, project *models.Project) (string, error)
 
@@ -65,20 +97,28 @@ type AgentInteractService interface {
 
 	// 打包部署
 	PackageProject(ctx context.Context, project *models.Project) (string, error)
+
+	// 部署项目到指定环境，commitSha 不为空时部署该历史提交
+	DeployProject(ctx context.Context, project *models.Project, environment, commitSha string) (string, error)
 }
 
 // Agent 交互服务实现
 type agentInteractService struct {
 	repositories   *repositories.Repository
+	promptService  PromptService
 	agentsURL      string
+	agentsSigner   *auth.RequestSigner // 调用 Agents API 的服务签名，为 nil 时不签名
 	defaultTimeout time.Duration
 }
 
 // NewAgentInteractService 创建 Agent 交互服务
-func NewAgentInteractService(repositories *repositories.Repository, agentsURL string) AgentInteractService {
+func NewAgentInteractService(repositories *repositories.Repository, promptService PromptService, agentsURL string,
+	agentsSigner *auth.RequestSigner) AgentInteractService {
 	return &agentInteractService{
 		repositories:   repositories,
+		promptService:  promptService,
 		agentsURL:      agentsURL,
+		agentsSigner:   agentsSigner,
 		defaultTimeout: time.Duration(5 * time.Minute),
 	}
 }
@@ -95,11 +135,46 @@ func (s *agentInteractService) getCliTool(project *models.Project) string {
 	return cliTool
 }
 
+// codeReviewConfig 获取项目评审 Dev Agent 提交的配置，项目没有开启代码评审时为 nil
+func (s *agentInteractService) codeReviewConfig(ctx context.Context, project *models.Project) *agent.CodeReviewConfig {
+	if !project.CodeReviewEnabled {
+		return nil
+	}
+	return &agent.CodeReviewConfig{
+		BlockingSeverities: project.BlockingSeverities,
+		MaxFixRounds:       codeReviewMaxFixRounds,
+		PromptOverride:     s.promptService.GetOverride(ctx, project, common.PromptNameCodeReview),
+	}
+}
+
 func (s *agentInteractService) getAgentClient(timeout time.Duration) *client.AgentClient {
 	if s.agentsURL == "" {
 		s.agentsURL = utils.GetEnvOrDefault("AGENTS_SERVER_URL", "http://localhost:8088")
 	}
-	return client.NewAgentClient(s.agentsURL, timeout)
+	agentClient := client.NewAgentClient(s.agentsURL, timeout)
+	if s.agentsSigner != nil {
+		agentClient.SetSigner(s.agentsSigner)
+	}
+	agentClient.SetTaskRecorder(s.recordStageAgentTask)
+	return agentClient
+}
+
+// stageAgentTaskContextKey 阶段请求上下文中的阶段ID
+type stageAgentTaskContextKey struct{}
+
+// withStageAgentTasks 返回带阶段ID的上下文，阶段请求创建的 Agent 任务在创建后立即保存到该阶段
+func withStageAgentTasks(ctx context.Context, stageID string) context.Context {
+	return context.WithValue(ctx, stageAgentTaskContextKey{}, stageID)
+}
+
+// recordStageAgentTask 保存阶段请求创建的 Agent 任务ID，在创建下一个任务之前保存，
+// 执行很快的任务在阶段请求返回前结束时也能从阶段中移除
+func (s *agentInteractService) recordStageAgentTask(ctx context.Context, taskID string) error {
+	stageID, _ := ctx.Value(stageAgentTaskContextKey{}).(string)
+	if stageID == "" {
+		return nil
+	}
+	return s.repositories.ProjectStageRepo.AddAgentTaskID(ctx, stageID, taskID)
 }
 
 // ChatWithAgent 与 Agent 对话
@@ -133,6 +208,30 @@ func (s *agentInteractService) WaitForTaskCompletion(ctx context.Context, taskID
 	return agentClient.WaitForTaskCompletion(ctx, taskID)
 }
 
+// GetProjectAgentLogs 获取项目最近的 Agent CLI 输出日志
+func (s *agentInteractService) GetProjectAgentLogs(ctx context.Context, projectGuid, taskID string, limit int) ([]*agent.AgentLogMessage, error) {
+	agentClient := s.getAgentClient(30 * time.Second)
+	return agentClient.GetProjectLogs(ctx, projectGuid, taskID, limit)
+}
+
+// CancelAgentTask 取消 Agent 任务
+func (s *agentInteractService) CancelAgentTask(ctx context.Context, taskID string) error {
+	agentClient := s.getAgentClient(30 * time.Second)
+	return agentClient.CancelTask(ctx, taskID)
+}
+
+// ListAgentSessions 获取项目 Agent 会话列表
+func (s *agentInteractService) ListAgentSessions(ctx context.Context, projectGuid string) ([]*agent.AgentSessionInfo, error) {
+	agentClient := s.getAgentClient(30 * time.Second)
+	return agentClient.ListSessions(ctx, projectGuid)
+}
+
+// ResetAgentSession 重置项目 Agent 会话
+func (s *agentInteractService) ResetAgentSession(ctx context.Context, projectGuid, agentType string) error {
+	agentClient := s.getAgentClient(30 * time.Second)
+	return agentClient.ResetSession(ctx, projectGuid, agentType)
+}
+
 // checkAgentHealthWithTimeout 带超时的 Agent 健康检查
 func (s *agentInteractService) checkAgentHealthWithTimeout(ctx context.Context, timeout time.Duration) error {
 	// 创建带超时的上下文
@@ -146,7 +245,7 @@ func (s *agentInteractService) checkAgentHealthWithTimeout(ctx context.Context,
 	logger.Info("开始检查 Agent 服务健康状态",
 		logger.String("agentsURL", s.agentsURL))
 
-	agentClient := client.NewAgentClient(s.agentsURL, 10*time.Second) // 健康检查的空接口，10s足够了
+	agentClient := s.getAgentClient(10 * time.Second) // 健康检查的空接口，10s足够了
 	err := agentClient.CheckHealth(timeoutCtx)
 	if err != nil {
 		logger.Error("agent health check failed",
@@ -174,7 +273,53 @@ func (s *agentInteractService) IsAgentHealthy(ctx context.Context) bool {
 // pendingAgents 准备项目开发环境
 func (s *agentInteractService) SetupAgentsEnviroment(ctx context.Context,
 	project *models.Project) (string, error) {
-	// 设置 CLI 工具和模型配置，如果项目没有设置则使用用户的默认设置
+	modelConfig := s.getModelConfig(project)
+
+	agentClient := s.getAgentClient(5 * time.Minute)
+	taskID, err := agentClient.SetupProjectEnvironment(ctx, &agent.SetupProjEnvReq{
+		ProjectGuid:     project.GUID,
+		GitlabRepoUrl:   project.GitlabRepoURL,
+		SetupBmadMethod: true,
+		BmadCliType:     modelConfig.CliTool,
+		AiModel:         modelConfig.AiModel,
+		ModelProvider:   modelConfig.ModelProvider,
+		ModelApiUrl:     modelConfig.ModelApiUrl,
+		ApiToken:        modelConfig.ApiToken,
+		Language:        project.Language,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return taskID, nil
+}
+
+// UpdateModelConfig 同步项目的模型配置到 Agents 服务
+func (s *agentInteractService) UpdateModelConfig(ctx context.Context, project *models.Project) error {
+	agentClient := s.getAgentClient(s.defaultTimeout)
+	return agentClient.UpdateModelConfig(ctx, project.GUID, s.getModelConfig(project))
+}
+
+// DeleteModelConfig 删除 Agents 服务保存的项目模型配置，包括 API Token
+func (s *agentInteractService) DeleteModelConfig(ctx context.Context, projectGuid string) error {
+	agentClient := s.getAgentClient(s.defaultTimeout)
+	return agentClient.DeleteModelConfig(ctx, projectGuid)
+}
+
+// GetGitHead 获取 Agents 工作区主干分支的最新提交
+func (s *agentInteractService) GetGitHead(ctx context.Context, projectGuid string) (*agent.GitHeadInfo, error) {
+	agentClient := s.getAgentClient(30 * time.Second)
+	return agentClient.GetGitHead(ctx, projectGuid)
+}
+
+// RollbackGit 将 Agents 工作区主干分支回滚到指定提交，并推送到远程仓库
+func (s *agentInteractService) RollbackGit(ctx context.Context, projectGuid, commitSha string) (*agent.GitHeadInfo, error) {
+	agentClient := s.getAgentClient(s.defaultTimeout)
+	return agentClient.RollbackGit(ctx, projectGuid, &agent.GitRollbackReq{CommitSha: commitSha})
+}
+
+// getModelConfig 获取项目的 CLI 工具和模型配置，项目没有设置时使用用户的默认设置，再没有则使用系统默认值
+func (s *agentInteractService) getModelConfig(project *models.Project) *agent.ProjectModelConfig {
 	cliTool := project.CliTool
 	aiModel := project.AiModel
 	modelProvider := project.ModelProvider
@@ -213,31 +358,24 @@ func (s *agentInteractService) SetupAgentsEnviroment(ctx context.Context,
 		apiToken = project.User.DefaultApiToken
 	}
 
-	agentClient := s.getAgentClient(5 * time.Minute)
-	taskID, err := agentClient.SetupProjectEnvironment(ctx, &agent.SetupProjEnvReq{
-		ProjectGuid:     project.GUID,
-		GitlabRepoUrl:   project.GitlabRepoURL,
-		SetupBmadMethod: true,
-		BmadCliType:     cliTool,
-		AiModel:         aiModel,
-		ModelProvider:   modelProvider,
-		ModelApiUrl:     modelApiUrl,
-		ApiToken:        apiToken,
-	})
-	if err != nil {
-		return "", err
+	return &agent.ProjectModelConfig{
+		CliTool:       cliTool,
+		AiModel:       aiModel,
+		ModelProvider: modelProvider,
+		ModelApiUrl:   modelApiUrl,
+		ApiToken:      apiToken,
 	}
-
-	return taskID, nil
 }
 
 // checkRequirement 检查需求
 func (s *agentInteractService) CheckRequirement(ctx context.Context,
 	project *models.Project) (string, error) {
 	req := &agent.GetProjBriefReq{
-		ProjectGuid:  project.GUID,
-		Requirements: project.Requirements,
-		CliTool:      s.getCliTool(project),
+		ProjectGuid:    project.GUID,
+		Requirements:   project.Requirements,
+		CliTool:        s.getCliTool(project),
+		Language:       project.Language,
+		PromptOverride: s.promptService.GetOverride(ctx, project, common.PromptNameAnalyseProjectBrief),
 	}
 
 	agentClient := s.getAgentClient(s.defaultTimeout)
@@ -253,9 +391,11 @@ func (s *agentInteractService) CheckRequirement(ctx context.Context,
 func (s *agentInteractService) GeneratePRD(ctx context.Context,
 	project *models.Project) (string, error) {
 	generatePrdReq := &agent.GetPRDReq{
-		ProjectGuid:  project.GUID,
-		Requirements: project.Requirements,
-		CliTool:      s.getCliTool(project),
+		ProjectGuid:    project.GUID,
+		Requirements:   project.Requirements,
+		CliTool:        s.getCliTool(project),
+		Language:       project.Language,
+		PromptOverride: s.promptService.GetOverride(ctx, project, common.PromptNamePmPrd),
 	}
 	// 调用 agents-server 生成 PRD 文档，并提交到 GitLab
 	agentClient := s.getAgentClient(s.defaultTimeout)
@@ -271,10 +411,12 @@ func (s *agentInteractService) GeneratePRD(ctx context.Context,
 func (s *agentInteractService) DefineUXStandards(ctx context.Context,
 	project *models.Project) (string, error) {
 	req := &agent.GetUXStandardReq{
-		ProjectGuid:  project.GUID,
-		Requirements: project.Requirements,
-		PrdPath:      PATH_PRD,
-		CliTool:      s.getCliTool(project),
+		ProjectGuid:    project.GUID,
+		Requirements:   project.Requirements,
+		PrdPath:        PATH_PRD,
+		CliTool:        s.getCliTool(project),
+		Language:       project.Language,
+		PromptOverride: s.promptService.GetOverride(ctx, project, common.PromptNameUxStandard),
 	}
 	// 调用 agents-server 定义 UX 标准
 	agentClient := s.getAgentClient(s.defaultTimeout)
@@ -298,7 +440,9 @@ func (s *agentInteractService) DesignArchitecture(ctx context.Context,
 			"2. 后端服务和 API： GO + Gin 框架实现 API、数据库用 PostgreSql、缓存用 Redis。\n" +
 			"3. 部署相关的脚本已经有了，用的 docker，前端用一个 nginx ，配置 /api 重定向到 /backend:port ，这样就能在前端项目中访问后端 API 了。" +
 			" 引用关系是：前端依赖后端，后端依赖 Redis 和 PostgreSql。",
-		CliTool: s.getCliTool(project),
+		CliTool:        s.getCliTool(project),
+		Language:       project.Language,
+		PromptOverride: s.promptService.GetOverride(ctx, project, common.PromptNameArchitectArchitecture),
 	}
 	// 调用 agents-server 设计系统架构
 	agentClient := s.getAgentClient(s.defaultTimeout)
@@ -314,11 +458,13 @@ func (s *agentInteractService) DesignArchitecture(ctx context.Context,
 func (s *agentInteractService) DefineDataModel(ctx context.Context,
 	project *models.Project) (string, error) {
 	req := &agent.GetDatabaseDesignReq{
-		ProjectGuid:   project.GUID,
-		PrdPath:       PATH_PRD,
-		ArchFolder:    "docs/arch",
-		StoriesFolder: FOLDER_STORIES,
-		CliTool:       s.getCliTool(project),
+		ProjectGuid:    project.GUID,
+		PrdPath:        PATH_PRD,
+		ArchFolder:     "docs/arch",
+		StoriesFolder:  FOLDER_STORIES,
+		CliTool:        s.getCliTool(project),
+		Language:       project.Language,
+		PromptOverride: s.promptService.GetOverride(ctx, project, common.PromptNameArchitectDatabase),
 	}
 	// 调用 agents-server 定义数据模型
 	agentClient := s.getAgentClient(s.defaultTimeout)
@@ -334,11 +480,13 @@ func (s *agentInteractService) DefineDataModel(ctx context.Context,
 func (s *agentInteractService) DefineAPIs(ctx context.Context,
 	project *models.Project) (string, error) {
 	req := &agent.GetAPIDefinitionReq{
-		ProjectGuid:   project.GUID,
-		PrdPath:       PATH_PRD,
-		DbFolder:      "docs/db",
-		StoriesFolder: FOLDER_STORIES,
-		CliTool:       s.getCliTool(project),
+		ProjectGuid:    project.GUID,
+		PrdPath:        PATH_PRD,
+		DbFolder:       "docs/db",
+		StoriesFolder:  FOLDER_STORIES,
+		CliTool:        s.getCliTool(project),
+		Language:       project.Language,
+		PromptOverride: s.promptService.GetOverride(ctx, project, common.PromptNameArchitectAPI),
 	}
 	// 调用 agents-server 定义 API 接口
 	agentClient := s.getAgentClient(s.defaultTimeout)
@@ -354,10 +502,12 @@ func (s *agentInteractService) DefineAPIs(ctx context.Context,
 func (s *agentInteractService) PlanEpicsAndStories(ctx context.Context,
 	project *models.Project) (string, error) {
 	req := &agent.GetEpicsAndStoriesReq{
-		ProjectGuid: project.GUID,
-		PrdPath:     PATH_PRD,
-		ArchFolder:  "docs/arch",
-		CliTool:     s.getCliTool(project),
+		ProjectGuid:    project.GUID,
+		PrdPath:        PATH_PRD,
+		ArchFolder:     "docs/arch",
+		CliTool:        s.getCliTool(project),
+		Language:       project.Language,
+		PromptOverride: s.promptService.GetOverride(ctx, project, common.PromptNamePoEpicsAndStories),
 	}
 	// 调用 agents-server 划分 Epics 和 Stories
 	agentClient := s.getAgentClient(s.defaultTimeout)
@@ -400,18 +550,23 @@ func (s *agentInteractService) GenerateFrontendPages(ctx context.Context,
 	}
 
 	// 调用 Dev Agent 生成前端页面
-	message := agentPrompt + " 请基于 @docs/ux/page-prompt.md 中的页面设计提示词," +
-		"在前端项目 frontend/src/pages/ 目录下生成关键页面组件。" +
-		"使用 Vue 3 + TypeScript + Naive UI,遵循现有项目的代码风格和架构。" +
-		"只生成 page-prompt.md 中明确定义的页面，不要生成其他页面。" +
-		"注意：始终用中文回答我。"
+	renderedPrompt, err := s.promptService.Render(ctx, project, common.PromptNameDevGeneratePages, map[string]string{
+		"AgentPrompt":    agentPrompt,
+		"PagePromptPath": pagePromptRelPath,
+	})
+	if err != nil {
+		logger.Error("渲染生成前端页面提示词失败", logger.String("error", err.Error()))
+		return "", err
+	}
 
 	req := &agent.ChatReq{
 		ProjectGuid: project.GUID,
 		AgentType:   common.AgentTypeDev,
-		Message:     message,
+		Message:     renderedPrompt.Content,
 		CliTool:     s.getCliTool(project),
+		Language:    project.Language,
 		DevStage:    string(common.DevStatusGeneratePages),
+		Prompt:      renderedPrompt,
 	}
 
 	agentClient := s.getAgentClient(s.defaultTimeout)
@@ -424,6 +579,72 @@ func (s *agentInteractService) GenerateFrontendPages(ctx context.Context,
 	return taskID, nil
 }
 
+// DraftStories SM 为待开发的 MVP Story 起草故事文件，每个故事一个 Agent 任务，
+// 故事的 FilePath 指向起草的文件，Dev Agent 每次只实现一个故事
+func (s *agentInteractService) DraftStories(ctx context.Context,
+	project *models.Project) (string, error) {
+	mvpEpics, err := s.repositories.EpicRepo.GetMvpEpicsByProject(ctx, project.ID)
+	if err != nil || len(mvpEpics) == 0 {
+		// 没有 MVP Epics 时开发阶段按 Epic 文件开发，跳过起草
+		logger.Warn("数据库中未找到 MVP Epics，跳过起草故事", logger.String("projectGuid", project.GUID))
+		return "", nil
+	}
+
+	req := &agent.DraftStoryReq{
+		ProjectGuid:    project.GUID,
+		PrdPath:        PATH_PRD,
+		ArchFolder:     "docs/arch/",
+		DbFolder:       "docs/db/",
+		ApiFolder:      "docs/api/",
+		UxSpecPath:     PATH_UX_SPEC,
+		CliTool:        s.getCliTool(project),
+		Language:       project.Language,
+		PromptOverride: s.promptService.GetOverride(ctx, project, common.PromptNameSMDraftStory),
+	}
+
+	agentClient := s.getAgentClient(s.defaultTimeout)
+	var taskIDs []string
+	for _, epic := range mvpEpics {
+		req.EpicFile = epicFilePath(epic)
+		iStoryCount := 0
+		for _, story := range epic.Stories {
+			if story.Status == common.CommonStatusDone {
+				continue
+			}
+			// 与开发阶段一致，开发环境只起草第一个 Story
+			if iStoryCount >= 1 && utils.IsDevEnvironment() {
+				continue
+			}
+
+			req.StoryNumber = story.StoryNumber
+			req.StoryTitle = story.Title
+			req.Description = story.Description
+			req.AcceptanceCriteria = story.AcceptanceCriteria
+			req.Depends = story.Depends
+			req.Techs = story.Techs
+			taskID, err := agentClient.DraftStory(ctx, req)
+			if err != nil {
+				logger.Error("Story 起草失败",
+					logger.String("story_number", story.StoryNumber),
+					logger.String("error", err.Error()))
+				s.cancelAgentTasks(ctx, taskIDs)
+				return "", err
+			}
+			iStoryCount++
+			taskIDs = append(taskIDs, taskID)
+
+			// SM 没有写出故事文件时任务失败，阶段随之失败，不会进入开发
+			story.FilePath = agent.StoryFilePath(story.StoryNumber)
+			if err := s.repositories.StoryRepo.Update(ctx, &story); err != nil {
+				logger.Error("更新 Story 文件路径失败", logger.String("error", err.Error()))
+			}
+		}
+	}
+
+	logger.Info("已提交 Story 起草", logger.String("projectGuid", project.GUID), logger.Int("count", len(taskIDs)))
+	return strings.Join(taskIDs, ","), nil
+}
+
 // DevelopStories 开发Story功能 (只实现 MVP Stories)
 func (s *agentInteractService) DevelopStories(ctx context.Context,
 	project *models.Project) (string, error) {
@@ -440,41 +661,53 @@ func (s *agentInteractService) DevelopStories(ctx context.Context,
 	logger.Info("从数据库读取到 MVP Epics", logger.Int("count", len(mvpEpics)))
 
 	req := &agent.ImplementStoryReq{
-		ProjectGuid: project.GUID,
-		PrdPath:     PATH_PRD,
-		ArchFolder:  "docs/arch/",
-		DbFolder:    "docs/db/",
-		ApiFolder:   "docs/api/",
-		UxSpecPath:  PATH_UX_SPEC,
-		EpicFile:    "docs/stories/",
-		StoryFile:   "",
-		CliTool:     s.getCliTool(project),
+		ProjectGuid:    project.GUID,
+		PrdPath:        PATH_PRD,
+		ArchFolder:     "docs/arch/",
+		DbFolder:       "docs/db/",
+		ApiFolder:      "docs/api/",
+		UxSpecPath:     PATH_UX_SPEC,
+		EpicFile:       "docs/stories/",
+		StoryFile:      "",
+		CliTool:        s.getCliTool(project),
+		Language:       project.Language,
+		PromptOverride: s.promptService.GetOverride(ctx, project, common.PromptNameDevImplementStory),
+		Review:         s.codeReviewConfig(ctx, project),
 	}
 
-	developStoryCount := 0
-	var lastResponse *tasks.TaskResult
-
-	// 按 Epic 和 Story 的顺序实现
+	// 按 Epic 和 Story 的顺序实现，记录每个故事的 Agent 任务ID，用于等待全部完成和取消阶段
 	// TODO: 现在 agent 接口改异步了，通过 sub 服务返回，这里需要重新设计，看怎么做到一个个实现 MVP 的 epics 和用户故事逐步开发
+	var taskIDs []string
 	for epicIndex, epic := range mvpEpics {
-		_, err = s.DevelopEpicStories(ctx, project, agentClient, req, epic, epicIndex, len(mvpEpics))
+		epicTaskIDs, err := s.DevelopEpicStories(ctx, project, agentClient, req, epic, epicIndex, len(mvpEpics))
+		taskIDs = append(taskIDs, epicTaskIDs...)
 		if err != nil {
+			s.cancelAgentTasks(ctx, taskIDs)
 			return "", err
 		}
-		if lastResponse != nil {
-			developStoryCount += 1
-		}
 	}
 
-	return "", nil
+	return strings.Join(taskIDs, ","), nil
+}
+
+// cancelAgentTasks 阶段请求中途失败时，取消已经创建的 Agent 任务
+func (s *agentInteractService) cancelAgentTasks(ctx context.Context, taskIDs []string) {
+	for _, taskID := range taskIDs {
+		if err := s.CancelAgentTask(ctx, taskID); err != nil {
+			logger.Warn("取消 Agent 任务失败", logger.String("agentTaskID", taskID), logger.String("error", err.Error()))
+		}
+	}
 }
 
 // 开发单个故事
 func (s *agentInteractService) developSingleStory(ctx context.Context, agentClient *client.AgentClient,
 	req *agent.ImplementStoryReq, story *models.Story) (string, error) {
-	// 设置 Story 文件路径
-	req.StoryFile = story.FilePath
-	req.EpicFile = story.FilePath
+	// SM 起草过的 Story 有自己的故事文件，否则 FilePath 与 Epic 文件相同，由 Dev Agent 在 Epic 中查找
+	req.StoryFile = ""
+	if story.FilePath != req.EpicFile {
+		req.StoryFile = story.FilePath
+	}
+	req.StoryNumber = story.StoryNumber
 
 	logger.Info("开始实现 Story",
 		logger.String("story_number", story.StoryNumber),
@@ -526,17 +759,26 @@ func (s *agentInteractService) updateEpicStatus(ctx context.Context, epic *model
 	return nil
 }
 
+// epicFilePath Epic 文件路径，没有记录时使用故事目录
+func epicFilePath(epic *models.Epic) string {
+	if epic.FilePath == "" {
+		return FOLDER_STORIES + "/"
+	}
+	return epic.FilePath
+}
+
 // 开发单个 epic 下面的用户故事
 func (s *agentInteractService) DevelopEpicStories(ctx context.Context,
 	project *models.Project, agentClient *client.AgentClient,
-	req *agent.ImplementStoryReq, epic *models.Epic, epicIndex, mvpEpicCount int) (string, error) {
+	req *agent.ImplementStoryReq, epic *models.Epic, epicIndex, mvpEpicCount int) ([]string, error) {
 	logger.Info("开始实现 Epic",
 		logger.String("epic_id", epic.ID),
 		logger.String("epic_name", epic.Name),
 		logger.Int("story_count", len(epic.Stories)))
 
-	var taskID string
+	var taskIDs []string
 
+	req.EpicFile = epicFilePath(epic)
 	iStoryCount := 0
 	for storyIndex, story := range epic.Stories {
 		// 跳过已完成的 Story
@@ -560,13 +802,13 @@ func (s *agentInteractService) DevelopEpicStories(ctx context.Context,
 			continue
 		}
 
-		response, err := s.developSingleStory(ctx, agentClient, req, &story)
+		taskID, err := s.developSingleStory(ctx, agentClient, req, &story)
 		if err != nil {
-			return "", err
+			return taskIDs, err
 		}
 
 		iStoryCount++
-		taskID = response
+		taskIDs = append(taskIDs, taskID)
 
 		// 不是最后一个 Story，发送中间消息
 		if !(epicIndex == (mvpEpicCount-1) && storyIndex == len(epic.Stories)-1) {
@@ -578,22 +820,25 @@ func (s *agentInteractService) DevelopEpicStories(ctx context.Context,
 
 	// Epic 完成，更新 Epic 状态
 	s.updateEpicStatus(ctx, epic)
-	return taskID, nil
+	return taskIDs, nil
 }
 
 // DevelopStoriesFromFiles 从文件方式开发 Stories (fallback)
 func (s *agentInteractService) DevelopStoriesFromFiles(ctx context.Context,
 	project *models.Project, agentClient *client.AgentClient) (string, error) {
 	req := &agent.ImplementStoryReq{
-		ProjectGuid: project.GUID,
-		PrdPath:     PATH_PRD,
-		ArchFolder:  "docs/arch/",
-		DbFolder:    "docs/db/",
-		ApiFolder:   "docs/api/",
-		UxSpecPath:  PATH_UX_SPEC,
-		EpicFile:    "docs/stories/",
-		StoryFile:   "",
-		CliTool:     s.getCliTool(project),
+		ProjectGuid:    project.GUID,
+		PrdPath:        PATH_PRD,
+		ArchFolder:     "docs/arch/",
+		DbFolder:       "docs/db/",
+		ApiFolder:      "docs/api/",
+		UxSpecPath:     PATH_UX_SPEC,
+		EpicFile:       "docs/stories/",
+		StoryFile:      "",
+		CliTool:        s.getCliTool(project),
+		Language:       project.Language,
+		PromptOverride: s.promptService.GetOverride(ctx, project, common.PromptNameDevImplementStory),
+		Review:         s.codeReviewConfig(ctx, project),
 	}
 
 	storyFiles, err := utils.GetRelativeFiles(project.ProjectPath, FOLDER_STORIES)
@@ -610,18 +855,20 @@ func (s *agentInteractService) DevelopStoriesFromFiles(ctx context.Context,
 	developStoryCount := 0
 	bDev := (utils.GetEnvOrDefault("ENVIRONMENT", common.EnvironmentDevelopment) == common.EnvironmentDevelopment)
 
-	var taskID string
+	var taskIDs []string
 	// 获取 stories 下的文件，循环开发每个 Story
 	for index, storyFile := range storyFiles {
 		// development 模式，只开发一个
 		if developStoryCount < 1 || !bDev {
 			req.StoryFile = storyFile
 			// 调用 agents-server 开发 Story 功能
-			taskID, err = agentClient.ImplementStory(ctx, req)
+			taskID, err := agentClient.ImplementStory(ctx, req)
 			if err != nil {
+				s.cancelAgentTasks(ctx, taskIDs)
 				return "", err
 			}
 
+			taskIDs = append(taskIDs, taskID)
 			developStoryCount += 1
 		} else {
 			response.Message = "开发需求故事" + storyFile + "已完成"
@@ -634,7 +881,79 @@ func (s *agentInteractService) DevelopStoriesFromFiles(ctx context.Context,
 		}
 	}
 
-	return taskID, nil
+	return strings.Join(taskIDs, ","), nil
+}
+
+// ReviewStories QA 按验收标准评审已开发的 MVP Story，每个故事一个 Agent 任务，门禁结论写入 docs/qa/gates
+func (s *agentInteractService) ReviewStories(ctx context.Context,
+	project *models.Project) (string, error) {
+	req := &agent.QAReviewReq{
+		ProjectGuid:    project.GUID,
+		PrdPath:        PATH_PRD,
+		ArchFolder:     "docs/arch/",
+		CliTool:        s.getCliTool(project),
+		Language:       project.Language,
+		PromptOverride: s.promptService.GetOverride(ctx, project, common.PromptNameQAReview),
+	}
+
+	agentClient := s.getAgentClient(s.defaultTimeout)
+	mvpEpics, err := s.repositories.EpicRepo.GetMvpEpicsByProject(ctx, project.ID)
+	if err != nil || len(mvpEpics) == 0 {
+		logger.Warn("数据库中未找到 MVP Epics，按故事文件评审", logger.String("projectGuid", project.GUID))
+		return s.reviewStoriesFromFiles(ctx, project, agentClient, req)
+	}
+
+	var taskIDs []string
+	for _, epic := range mvpEpics {
+		for _, story := range epic.Stories {
+			// 只评审已经开发的 Story
+			if story.Status != common.CommonStatusDone {
+				continue
+			}
+			req.StoryNumber = story.StoryNumber
+			req.StoryTitle = story.Title
+			req.StoryFile = story.FilePath
+			req.AcceptanceCriteria = story.AcceptanceCriteria
+			taskID, err := agentClient.QAReview(ctx, req)
+			if err != nil {
+				logger.Error("Story 评审失败",
+					logger.String("story_number", story.StoryNumber),
+					logger.String("error", err.Error()))
+				s.cancelAgentTasks(ctx, taskIDs)
+				return "", err
+			}
+			taskIDs = append(taskIDs, taskID)
+		}
+	}
+
+	logger.Info("已提交 Story 评审", logger.String("projectGuid", project.GUID), logger.Int("count", len(taskIDs)))
+	return strings.Join(taskIDs, ","), nil
+}
+
+// reviewStoriesFromFiles 按 docs/stories 下的故事文件评审 (fallback)，故事编号为文件名
+func (s *agentInteractService) reviewStoriesFromFiles(ctx context.Context, project *models.Project,
+	agentClient *client.AgentClient, req *agent.QAReviewReq) (string, error) {
+	storyFiles, err := utils.GetRelativeFiles(project.ProjectPath, FOLDER_STORIES)
+	if err != nil || len(storyFiles) == 0 {
+		logger.Warn("没有找到故事文件，跳过 QA 评审", logger.String("projectGuid", project.GUID))
+		return "", nil
+	}
+
+	var taskIDs []string
+	for _, fileName := range storyFiles {
+		if filepath.Ext(fileName) != ".md" {
+			continue
+		}
+		req.StoryFile = FOLDER_STORIES + "/" + fileName
+		req.StoryNumber = strings.TrimSuffix(fileName, filepath.Ext(fileName))
+		taskID, err := agentClient.QAReview(ctx, req)
+		if err != nil {
+			s.cancelAgentTasks(ctx, taskIDs)
+			return "", err
+		}
+		taskIDs = append(taskIDs, taskID)
+	}
+	return strings.Join(taskIDs, ","), nil
 }
 
 // fixBugs 修复开发问题
@@ -644,6 +963,8 @@ func (s *agentInteractService) FixBugs(ctx context.Context,
 		ProjectGuid:    project.GUID,
 		BugDescription: "修复开发问题",
 		CliTool:        s.getCliTool(project),
+		Language:       project.Language,
+		PromptOverride: s.promptService.GetOverride(ctx, project, common.PromptNameDevFixBug),
 	}
 	// 调用 agents-server 修复问题
 	agentClient := s.getAgentClient(s.defaultTimeout)
@@ -658,8 +979,10 @@ func (s *agentInteractService) FixBugs(ctx context.Context,
 func (s *agentInteractService) RunTests(ctx context.Context,
 	project *models.Project) (string, error) {
 	req := &agent.RunTestReq{
-		ProjectGuid: project.GUID,
-		CliTool:     s.getCliTool(project),
+		ProjectGuid:    project.GUID,
+		CliTool:        s.getCliTool(project),
+		Language:       project.Language,
+		PromptOverride: s.promptService.GetOverride(ctx, project, common.PromptNameDevRunTest),
 	}
 	// 调用 agents-server 执行自动测试
 	agentClient := s.getAgentClient(s.defaultTimeout)
@@ -674,11 +997,19 @@ func (s *agentInteractService) RunTests(ctx context.Context,
 // packageProject 打包项目
 func (s *agentInteractService) PackageProject(ctx context.Context,
 	project *models.Project) (string, error) {
+	return s.DeployProject(ctx, project, common.DeployEnvironmentDev, "")
+}
+
+// DeployProject 部署项目到指定环境
+func (s *agentInteractService) DeployProject(ctx context.Context,
+	project *models.Project, environment, commitSha string) (string, error) {
 	req := &agent.DeployReq{
 		ProjectGuid:   project.GUID,
-		Environment:   "dev",
+		Environment:   environment,
+		CommitSha:     commitSha,
 		DeployOptions: map[string]interface{}{},
 		CliTool:       s.getCliTool(project),
+		Language:      project.Language,
 	}
 
 	agentClient := s.getAgentClient(s.defaultTimeout)
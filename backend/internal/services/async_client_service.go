@@ -25,7 +25,7 @@ type AsyncClientService interface {
 	// 创建项目下载任务
 	EnqueueProjectDownloadTask(projectID, projectGuid, projectPath string) (string, error)
 	// 创建部署项目任务
-	EnqueueProjectDeployTask(projectGuid, environment string) (string, error)
+	EnqueueProjectDeployTask(req *agent.DeployReq) (string, error)
 }
 
 // asynq 异步处理业务实现
@@ -107,11 +107,7 @@ func (s *asyncClientService) EnqueueProjectDownloadTask(projectID, projectGuid,
 }
 
 // EnqueueProjectDeployTask 创建部署项目任务
-func (s *asyncClientService) EnqueueProjectDeployTask(projectGuid, environment string) (string, error) {
-	req := &agent.DeployReq{
-		ProjectGuid: projectGuid,
-		Environment: environment,
-	}
+func (s *asyncClientService) EnqueueProjectDeployTask(req *agent.DeployReq) (string, error) {
 	// 异步方法，返回任务 ID
 	info, err := s.asyncClient.Enqueue(tasks.NewProjectDeployTask(req))
 	if err != nil {
@@ -3,8 +3,10 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/hibiken/asynq"
@@ -29,16 +31,25 @@ type asyncTaskService struct {
 	commonService ProjectCommonService
 	devService    ProjectDevService
 	agentService  AgentInteractService
+	usageService  UsageService
+	reportService TestReportService
+	qaService     QAReviewService
+	reviewService CodeReviewService
 }
 
 // NewAsyncService 创建 asynq 异步处理业务
 func NewAsyncTaskService(repositories *repositories.Repository, commonService ProjectCommonService,
-	devService ProjectDevService, agentService AgentInteractService) AsyncTaskService {
+	devService ProjectDevService, agentService AgentInteractService, usageService UsageService,
+	reportService TestReportService, qaService QAReviewService, reviewService CodeReviewService) AsyncTaskService {
 	return &asyncTaskService{
 		repositories:  repositories,
 		commonService: commonService,
 		devService:    devService,
 		agentService:  agentService,
+		usageService:  usageService,
+		reportService: reportService,
+		qaService:     qaService,
+		reviewService: reviewService,
 	}
 }
 
@@ -107,6 +118,23 @@ func (s *asyncTaskService) handleProjectStageTask(ctx context.Context, t *asynq.
 	}
 	tasks.UpdateResult(resultWriter, common.CommonStatusInProgress, 10, "create stage")
 
+	// 本月用量超出预算时不再发起新的阶段任务，其他错误（如数据库异常）交给 asynq 重试
+	if err := s.usageService.CheckBudget(ctx, project.UserID); err != nil {
+		if err.Error() != common.MESSAGE_INSUFFICIENT_QUOTA {
+			logger.Error("检查用户预算失败", logger.String("stageName", payload.StageName), logger.String("error", err.Error()))
+			return fmt.Errorf("检查用户预算失败: %w", err)
+		}
+		s.commonService.UpdateStageStatus(ctx, stage, common.CommonStatusFailed, err.Error())
+		s.commonService.UpdateProjectToStatus(ctx, project, common.CommonStatusFailed)
+		tasks.UpdateResult(resultWriter, common.CommonStatusFailed, 0, err.Error())
+		logger.Warn("阶段任务被拒绝", logger.String("stageName", payload.StageName), logger.String("error", err.Error()))
+		return asynq.SkipRetry
+	}
+
+	// 记录阶段执行前的提交用于回滚，重试时保留首次执行前的提交
+	if stage.PreCommitSha == "" {
+		stage.PreCommitSha = s.getAgentsCommitSha(ctx, project.GUID)
+	}
 	s.commonService.UpdateStageStatus(ctx, stage, common.CommonStatusInProgress, "")
 	// 更新项目状态
 	if err := s.commonService.UpdateProjectToStage(ctx, project, resultWriter.TaskID(), payload.StageName); err != nil {
@@ -115,11 +143,18 @@ func (s *asyncTaskService) handleProjectStageTask(ctx context.Context, t *asynq.
 	}
 	tasks.UpdateResult(resultWriter, common.CommonStatusInProgress, 30, "set project stage set to "+payload.StageName)
 
-	taskID, err := stageItem.ReqHandler(ctx, project)
+	// 清除上次执行的 Agent 任务ID，本次创建的任务在创建后立即保存到阶段
+	if err := s.repositories.ProjectStageRepo.ClearAgentTaskIDs(ctx, stage.ID); err != nil {
+		return fmt.Errorf("清除阶段 Agent 任务ID失败: %w", err)
+	}
+	taskID, err := stageItem.ReqHandler(withStageAgentTasks(ctx, stage.ID), project)
 	if err != nil {
 		s.commonService.UpdateStageStatus(ctx, stage, common.CommonStatusFailed, err.Error()) // 更新阶段状态为失败
 		s.commonService.UpdateProjectToStatus(ctx, project, common.CommonStatusFailed)        // 更新项目状态为失败
 		logger.Error("阶段任务执行失败", logger.String("error", err.Error()))
+		if errors.Is(err, ErrQAGateFailed) { // 重新评审前重试也无法部署
+			return fmt.Errorf("%w: %s", asynq.SkipRetry, err.Error())
+		}
 		return err
 	}
 
@@ -177,15 +212,26 @@ func (s *asyncTaskService) doStageResponseAndGoToNext(ctx context.Context, stage
 	project *models.Project, stage *models.DevStage, stageName common.DevStatus) error {
 	var err error
 	if stageItem.RespHandler != nil {
-		err = stageItem.RespHandler(ctx, message, response)
+		err = stageItem.RespHandler(ctx, project, message, response)
 	}
 	if err == nil {
+		stage.PostCommitSha = s.getAgentsCommitSha(ctx, project.GUID)
 		s.commonService.UpdateStageStatus(ctx, stage, common.CommonStatusDone, "")
 		s.devService.ProceedToNextStage(ctx, project, stageName)
 	}
 	return err
 }
 
+// getAgentsCommitSha 获取 Agents 工作区主干的最新提交，失败时返回空字符串，不影响阶段执行
+func (s *asyncTaskService) getAgentsCommitSha(ctx context.Context, projectGuid string) string {
+	head, err := s.agentService.GetGitHead(ctx, projectGuid)
+	if err != nil {
+		logger.Warn("获取 Agents 工作区提交失败", logger.String("projectGuid", projectGuid), logger.String("error", err.Error()))
+		return ""
+	}
+	return head.CommitSha
+}
+
 // 处理 agent 任务完成或失败响应
 func (s *asyncTaskService) handleAgentResponseTask(ctx context.Context, t *asynq.Task) error {
 	var message agent.AgentTaskStatusMessage
@@ -195,31 +241,69 @@ func (s *asyncTaskService) handleAgentResponseTask(ctx context.Context, t *asynq
 	}
 
 	resultWriter := t.ResultWriter()
+	// 记录 Agent 任务用量，失败不影响后续流程
+	if err := s.usageService.RecordTaskUsage(ctx, &message); err != nil {
+		logger.Warn("记录 Agent 任务用量失败", logger.String("taskID", message.TaskID), logger.String("error", err.Error()))
+	}
+
 	tasks.UpdateResult(resultWriter, common.CommonStatusInProgress, 10, "获取 Agent 任务结果...")
 	response, err := s.agentService.WaitForTaskCompletion(ctx, message.TaskID)
-	if err != nil {
+	if err != nil && response == nil {
 		return fmt.Errorf("waiting for task completion failed: %s", err.Error())
 	}
-
-	if message.DevStage == string(common.DevStatusUnknown) { // 阵列用 Unknown 表示聊天
-		err := s.devService.OnChatResponse(ctx, &message, response)
-		tasks.UpdateResult(resultWriter, common.CommonStatusDone, 100, "Agent 响应为聊天，已完成.")
-		return err
+	// Agent 任务失败时仍然返回任务结果，按失败处理并保留测试报告等结构化结果
+	if err != nil {
+		message.Status = common.CommonStatusFailed
 	}
 
+	// 项目只加载一次，响应处理器据此获取输出语言等信息
 	project, err := s.repositories.ProjectRepo.GetByGUID(ctx, message.ProjectGuid)
 	if err != nil {
 		tasks.UpdateResult(resultWriter, common.CommonStatusFailed, 0, "获取项目失败")
 		return fmt.Errorf("failed to get project information: %s", err.Error())
 	}
 
+	if message.DevStage == string(common.DevStatusUnknown) { // 阵列用 Unknown 表示聊天
+		err := s.devService.OnChatResponse(ctx, project, &message, response)
+		tasks.UpdateResult(resultWriter, common.CommonStatusDone, 100, "Agent 响应为聊天，已完成.")
+		return err
+	}
+
 	stage, err := s.repositories.ProjectStageRepo.GetByProjectGuidAndName(ctx, message.ProjectGuid, message.DevStage)
 	if err != nil {
 		tasks.UpdateResult(resultWriter, common.CommonStatusFailed, 0, "获取项目阶段失败")
 		return fmt.Errorf("failed to get stage information: %s", err.Error())
 	}
 
+	// 记录测试报告，测试失败时同样记录，失败不影响后续流程
+	if err := s.reportService.RecordStageReport(ctx, project, stage, message.TaskID, response); err != nil {
+		logger.Warn("记录测试报告失败", logger.String("taskID", message.TaskID), logger.String("error", err.Error()))
+	}
+	// 记录 QA 评审的门禁结论，部署前据此检查；丢失 fail 结论会放行部署，失败时交给 asynq 重试
+	if err := s.qaService.RecordStageReview(ctx, project, stage, message.TaskID, response); err != nil {
+		tasks.UpdateResult(resultWriter, common.CommonStatusFailed, 0, "记录 QA 评审失败")
+		return err
+	}
+	// 记录 Dev Agent 提交的代码评审意见，评审阻塞导致任务失败时同样记录，失败不影响后续流程
+	if err := s.reviewService.RecordStageReview(ctx, project, stage, message.TaskID, response); err != nil {
+		logger.Warn("记录代码评审意见失败", logger.String("taskID", message.TaskID), logger.String("error", err.Error()))
+	}
+
+	// 开发故事阶段每个故事一个 Agent 任务，移除已结束的任务；同时结束的任务锁定阶段记录后依次移除，不会互相覆盖
+	remainingTaskIDs, err := s.repositories.ProjectStageRepo.RemoveAgentTaskID(ctx, stage.ID, message.TaskID)
+	if err != nil {
+		tasks.UpdateResult(resultWriter, common.CommonStatusFailed, 0, "更新阶段 Agent 任务ID失败")
+		return fmt.Errorf("failed to remove stage agent task: %s", err.Error())
+	}
+	stage.AgentTaskID = strings.Join(remainingTaskIDs, ",")
+
 	if message.Status == common.CommonStatusFailed {
+		// 一个故事失败时阶段失败，取消其余仍在执行的故事任务
+		for _, taskID := range remainingTaskIDs {
+			if err := s.agentService.CancelAgentTask(ctx, taskID); err != nil {
+				logger.Warn("取消 Agent 任务失败", logger.String("agentTaskID", taskID), logger.String("error", err.Error()))
+			}
+		}
 		s.commonService.UpdateStageStatus(ctx, stage, common.CommonStatusFailed, response.Message)
 		s.commonService.UpdateProjectToStatus(ctx, project, common.CommonStatusFailed)
 		tasks.UpdateResult(resultWriter, common.CommonStatusDone, 100, "Agent 消息为失败，已同步错误信息")
@@ -234,6 +318,15 @@ func (s *asyncTaskService) handleAgentResponseTask(ctx context.Context, t *asynq
 		return nil
 	}
 
+	// 还有故事任务在执行时，等待全部完成后再处理阶段响应
+	if len(remainingTaskIDs) > 0 {
+		logger.Info("阶段还有 Agent 任务在执行",
+			logger.String("stageName", message.DevStage),
+			logger.Int("remaining", len(remainingTaskIDs)))
+		tasks.UpdateResult(resultWriter, common.CommonStatusDone, 100, "Agent 任务已完成，等待阶段内其余任务")
+		return nil
+	}
+
 	stageName := common.DevStatus(message.DevStage)
 	stageItem := s.devService.GetStageItem(stageName)
 	if stageItem == nil {
@@ -332,18 +425,43 @@ func (s *asyncTaskService) handleProjectDeployTask(ctx context.Context, t *asynq
 		return fmt.Errorf("获取项目信息失败: %w", err)
 	}
 
+	deployment, err := s.startDeployment(ctx, project, &req, resultWriter.TaskID())
+	if err != nil {
+		tasks.UpdateResult(resultWriter, common.CommonStatusFailed, 0, err.Error())
+		return fmt.Errorf("%v: %w", err, asynq.SkipRetry)
+	}
+
 	tasks.UpdateResult(resultWriter, common.CommonStatusInProgress, 10, "use agent to package project")
-	// 使用较长的超时时间，因为部署任务可能需要较长时间
-	taskID, err := s.agentService.PackageProject(ctx, project)
+	// 使用较长的超时时间，因为部署任务可能需要较长时间；只有重新部署时才指定提交，否则部署主干的最新提交
+	taskID, err := s.agentService.DeployProject(ctx, project, deployment.Environment, req.CommitSha)
 	if err != nil {
+		s.finishDeployment(ctx, deployment, nil, err)
 		tasks.UpdateResult(resultWriter, common.CommonStatusFailed, 0, MESSAGE_AGENT_CALL_FAILED+err.Error())
 		return err
 	}
+	deployment.AgentTaskID = taskID
+	if err := s.repositories.DeploymentRepo.Update(ctx, deployment); err != nil {
+		logger.Warn("更新部署记录失败", logger.String("deploymentID", deployment.ID), logger.String("error", err.Error()))
+	}
 
 	tasks.UpdateResult(resultWriter, common.CommonStatusInProgress, 30, "agent task id"+taskID)
 	// 部署是独立的任务，这里直接同步等待完成
 	response, err := s.agentService.WaitForTaskCompletion(ctx, taskID)
+	s.finishDeployment(ctx, deployment, response, err)
 	if err != nil {
+		// 组件未通过健康检查时，把各组件的状态和日志发给用户
+		if response != nil && response.Deploy != nil {
+			s.commonService.CreateAndNotifyMessage(ctx, project.GUID, &models.ConversationMessage{
+				ProjectGuid:     project.GUID,
+				Type:            common.ConversationTypeAgent,
+				AgentRole:       common.AgentDev.Role,
+				AgentName:       common.AgentDev.Name,
+				Content:         getProjectMessage(project.Language, messageKeyDeployUnhealthy),
+				IsMarkdown:      true,
+				MarkdownContent: response.Message,
+				IsExpanded:      true,
+			})
+		}
 		tasks.UpdateResult(resultWriter, common.CommonStatusFailed, 0, MESSAGE_AGENT_CALL_FAILED+err.Error())
 		return err
 	}
@@ -353,31 +471,69 @@ func (s *asyncTaskService) handleProjectDeployTask(ctx context.Context, t *asynq
 		Type:            common.ConversationTypeAgent,
 		AgentRole:       common.AgentDev.Role,
 		AgentName:       common.AgentDev.Name,
-		Content:         MESSAGE_STAGE_DEPLOYED,
+		Content:         getProjectMessage(project.Language, string(common.DevStatusDeploy)),
 		IsMarkdown:      true,
 		MarkdownContent: response.Message,
 		IsExpanded:      true,
 	}
 	s.commonService.CreateAndNotifyMessage(ctx, project.GUID, projectMsg)
 
-	// 设置预览 URL
-	if project.PreviewUrl == "" {
-		project.PreviewUrl = fmt.Sprintf("http://%s.app-maker.localhost", project.GUID)
-		if err := s.repositories.ProjectRepo.Update(ctx, project); err != nil {
+	// 开发环境使用 Agent 健康检查通过的地址作为预览 URL，其他环境的地址只记录在部署记录中
+	if deployment.Environment == common.DeployEnvironmentDev {
+		if err := s.commonService.UpdatePreviewUrlFromDeploy(ctx, project, response.Deploy); err != nil {
 			logger.Error("更新项目预览URL失败",
 				logger.String("error", err.Error()),
 				logger.String("projectID", project.ID),
 			)
-		} else {
-			logger.Info("项目预览URL已设置",
-				logger.String("projectID", project.ID),
-				logger.String("previewUrl", project.PreviewUrl),
-			)
-
-			// 通知前端预览URL已设置
-			s.commonService.UpdateAndNotifyProjectInfo(ctx, project)
 		}
 	}
 	tasks.UpdateResult(resultWriter, common.CommonStatusDone, 100, MESSAGE_STAGE_DEPLOYED)
 	return nil
 }
+
+// startDeployment 获取部署任务对应的部署记录并标记为执行中；
+// 没有部署记录的任务（升级前提交的任务）按请求创建一条
+func (s *asyncTaskService) startDeployment(ctx context.Context, project *models.Project,
+	req *agent.DeployReq, taskID string) (*models.Deployment, error) {
+	var deployment *models.Deployment
+	if req.DeploymentID != "" {
+		var err error
+		deployment, err = s.repositories.DeploymentRepo.GetByID(ctx, req.DeploymentID)
+		if err != nil {
+			return nil, fmt.Errorf("获取部署记录失败: %w", err)
+		}
+	} else {
+		deployment = &models.Deployment{
+			ProjectID:   project.ID,
+			ProjectGuid: project.GUID,
+			UserID:      project.UserID,
+			Environment: req.Environment,
+			CommitSha:   req.CommitSha,
+		}
+		if deployment.Environment == "" {
+			deployment.Environment = common.DeployEnvironmentDev
+		}
+		if err := s.repositories.DeploymentRepo.Create(ctx, deployment); err != nil {
+			return nil, fmt.Errorf("创建部署记录失败: %w", err)
+		}
+	}
+
+	// 部署最新提交时记录部署前主干的提交，Agent 返回实际部署的提交后会覆盖
+	if deployment.CommitSha == "" {
+		deployment.CommitSha = s.getAgentsCommitSha(ctx, project.GUID)
+	}
+	deployment.Start(taskID)
+	if err := s.repositories.DeploymentRepo.Update(ctx, deployment); err != nil {
+		return nil, fmt.Errorf("更新部署记录失败: %w", err)
+	}
+	return deployment, nil
+}
+
+// finishDeployment 记录部署结果，失败不影响任务结果
+func (s *asyncTaskService) finishDeployment(ctx context.Context, deployment *models.Deployment,
+	response *tasks.TaskResult, err error) {
+	deployment.Finish(response, err)
+	if err := s.repositories.DeploymentRepo.Update(ctx, deployment); err != nil {
+		logger.Warn("更新部署记录失败", logger.String("deploymentID", deployment.ID), logger.String("error", err.Error()))
+	}
+}
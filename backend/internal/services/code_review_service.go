@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/common"
+	"github.com/lighthought/app-maker/shared-models/logger"
+	"github.com/lighthought/app-maker/shared-models/tasks"
+
+	"github.com/lighthought/app-maker/backend/internal/models"
+	"github.com/lighthought/app-maker/backend/internal/repositories"
+)
+
+// 代码评审意见接口返回的最近意见数
+const projectReviewCommentLimit = 200
+
+// Dev Agent 修复阻塞的评审意见的最大轮数
+const codeReviewMaxFixRounds = 2
+
+// CodeReviewService Dev Agent 提交的代码评审服务接口
+type CodeReviewService interface {
+	// 记录 Agent 任务返回的代码评审意见并发送到项目对话，任务结果没有代码评审时忽略
+	RecordStageReview(ctx context.Context, project *models.Project, stage *models.DevStage,
+		agentTaskID string, result *tasks.TaskResult) error
+
+	// 获取项目的代码评审意见，storyNumber 不为空时只返回该故事的
+	ListReviewComments(ctx context.Context, project *models.Project, storyNumber string) ([]*models.ReviewComment, error)
+}
+
+// codeReviewService Dev Agent 提交的代码评审服务实现
+type codeReviewService struct {
+	repositories  *repositories.Repository
+	commonService ProjectCommonService
+}
+
+// NewCodeReviewService 创建 Dev Agent 提交的代码评审服务
+func NewCodeReviewService(repositories *repositories.Repository, commonService ProjectCommonService) CodeReviewService {
+	return &codeReviewService{repositories: repositories, commonService: commonService}
+}
+
+// RecordStageReview 记录 Agent 任务返回的代码评审意见，同一任务重复上报时替换已有的意见
+func (s *codeReviewService) RecordStageReview(ctx context.Context, project *models.Project, stage *models.DevStage,
+	agentTaskID string, result *tasks.TaskResult) error {
+	if result == nil || result.CodeReview == nil {
+		return nil
+	}
+
+	review := result.CodeReview
+	devStageID := ""
+	if stage != nil {
+		devStageID = stage.ID
+	}
+	comments := models.NewReviewComments(project, devStageID, agentTaskID, review)
+	if err := s.repositories.ReviewCommentRepo.ReplaceByAgentTask(ctx, agentTaskID, comments); err != nil {
+		return fmt.Errorf("保存代码评审意见失败: %w", err)
+	}
+
+	logger.Info("已记录代码评审意见",
+		logger.String("taskID", agentTaskID),
+		logger.String("projectGuid", project.GUID),
+		logger.String("storyNumber", review.StoryNumber),
+		logger.String("status", review.Status),
+		logger.Int("comments", len(comments)))
+
+	projectMsg := &models.ConversationMessage{
+		ProjectGuid:     project.GUID,
+		Type:            common.ConversationTypeAgent,
+		AgentRole:       common.AgentQA.Role,
+		AgentName:       common.AgentQA.Name,
+		Content:         fmt.Sprintf(getProjectMessage(project.Language, messageKeyCodeReviewDone), review.StoryNumber),
+		IsMarkdown:      true,
+		MarkdownContent: formatCodeReview(project.Language, review),
+		IsExpanded:      true,
+	}
+	return s.commonService.CreateAndNotifyMessage(ctx, project.GUID, projectMsg)
+}
+
+// ListReviewComments 获取项目的代码评审意见
+func (s *codeReviewService) ListReviewComments(ctx context.Context, project *models.Project, storyNumber string) ([]*models.ReviewComment, error) {
+	comments, err := s.repositories.ReviewCommentRepo.ListByProjectGuid(ctx, project.GUID, storyNumber, projectReviewCommentLimit)
+	if err != nil {
+		return nil, fmt.Errorf("获取代码评审意见失败: %w", err)
+	}
+	return comments, nil
+}
+
+// formatCodeReview 代码评审的对话消息：评审结论、摘要和意见表格
+func formatCodeReview(language string, review *agent.CodeReview) string {
+	var builder strings.Builder
+	switch review.Status {
+	case common.CodeReviewStatusFixed:
+		builder.WriteString(fmt.Sprintf(getProjectMessage(language, messageKeyCodeReviewFixed), review.FixRounds))
+	case common.CodeReviewStatusBlocked:
+		builder.WriteString(fmt.Sprintf(getProjectMessage(language, messageKeyCodeReviewBlocked), review.FixRounds))
+	case common.CodeReviewStatusFailed:
+		builder.WriteString(fmt.Sprintf(getProjectMessage(language, messageKeyCodeReviewFailed), review.Error))
+	default:
+		builder.WriteString(getProjectMessage(language, messageKeyCodeReviewPassed))
+	}
+	builder.WriteString("\n\n")
+	if review.Summary != "" {
+		builder.WriteString(review.Summary + "\n\n")
+	}
+	if len(review.Comments) == 0 {
+		return strings.TrimRight(builder.String(), "\n")
+	}
+
+	builder.WriteString(getProjectMessage(language, messageKeyCodeReviewHeader))
+	for _, comment := range review.Comments {
+		location := comment.File
+		if comment.Line > 0 {
+			location += fmt.Sprintf(":%d", comment.Line)
+		}
+		blocking := ""
+		if comment.Blocking {
+			blocking = "✔"
+		}
+		message := strings.ReplaceAll(strings.ReplaceAll(comment.Message, "|", "\\|"), "\n", " ")
+		builder.WriteString(fmt.Sprintf("| %d | %s | `%s` | %s | %s |\n", comment.Round, comment.Severity, location, message, blocking))
+	}
+	return builder.String()
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/common"
+	"github.com/lighthought/app-maker/shared-models/tasks"
+
+	"github.com/lighthought/app-maker/backend/internal/models"
+	"github.com/lighthought/app-maker/backend/internal/repositories"
+)
+
+type fakeReviewCommentRepo struct {
+	repositories.ReviewCommentRepository
+	comments map[string][]*models.ReviewComment
+}
+
+func (r *fakeReviewCommentRepo) ReplaceByAgentTask(ctx context.Context, agentTaskID string, comments []*models.ReviewComment) error {
+	if r.comments == nil {
+		r.comments = make(map[string][]*models.ReviewComment)
+	}
+	r.comments[agentTaskID] = comments
+	return nil
+}
+
+type fakeMessageCommonService struct {
+	ProjectCommonService
+	messages []*models.ConversationMessage
+}
+
+func (s *fakeMessageCommonService) CreateAndNotifyMessage(ctx context.Context, projectGuid string,
+	message *models.ConversationMessage) error {
+	s.messages = append(s.messages, message)
+	return nil
+}
+
+func TestCodeReviewServiceRecordStageReview(t *testing.T) {
+	project := &models.Project{ID: "PROJ-1", GUID: "p1", Language: common.LanguageEnUS}
+	stage := &models.DevStage{ID: "STAGE-1", Name: string(common.DevStatusDevelopStory)}
+	repo := &fakeReviewCommentRepo{}
+	commonService := &fakeMessageCommonService{}
+	service := NewCodeReviewService(&repositories.Repository{ReviewCommentRepo: repo}, commonService)
+
+	// 没有代码评审的任务结果不记录
+	if err := service.RecordStageReview(context.Background(), project, stage, "task-0", &tasks.TaskResult{Message: "done"}); err != nil {
+		t.Fatalf("RecordStageReview() err = %v", err)
+	}
+	if len(repo.comments) != 0 || len(commonService.messages) != 0 {
+		t.Fatalf("comments = %+v, messages = %+v, want none", repo.comments, commonService.messages)
+	}
+
+	result := &tasks.TaskResult{
+		Message: "developed",
+		CodeReview: &agent.CodeReview{
+			StoryNumber: "1.2", CommitSha: "abc123", Status: common.CodeReviewStatusFixed, Rounds: 2, FixRounds: 1,
+			Summary: "looks good now",
+			Comments: []*agent.ReviewComment{
+				{Round: 1, File: "internal/user.go", Line: 42, Severity: common.ReviewSeverityHigh, Message: "missing error | check", Blocking: true},
+				{Round: 2, File: "README.md", Severity: common.ReviewSeverityLow, Message: "typo"},
+			},
+		},
+	}
+	if err := service.RecordStageReview(context.Background(), project, stage, "task-1", result); err != nil {
+		t.Fatalf("RecordStageReview() err = %v", err)
+	}
+	comments := repo.comments["task-1"]
+	if len(comments) != 2 {
+		t.Fatalf("comments = %+v", comments)
+	}
+	first := comments[0]
+	if first.ProjectID != "PROJ-1" || first.DevStageID != "STAGE-1" || first.StoryNumber != "1.2" || first.CommitSha != "abc123" ||
+		first.ReviewStatus != common.CodeReviewStatusFixed || first.FilePath != "internal/user.go" || first.Line != 42 || !first.Blocking {
+		t.Errorf("comment = %+v", first)
+	}
+
+	if len(commonService.messages) != 1 {
+		t.Fatalf("messages = %+v", commonService.messages)
+	}
+	message := commonService.messages[0]
+	if message.Content != "Code of story 1.2 reviewed" || message.AgentRole != common.AgentQA.Role {
+		t.Errorf("message = %+v", message)
+	}
+	for _, want := range []string{"after 1 fix rounds", "looks good now", "| 1 | high | `internal/user.go:42` | missing error \\| check | ✔ |", "| 2 | low | `README.md` | typo |  |"} {
+		if !strings.Contains(message.MarkdownContent, want) {
+			t.Errorf("markdown = %q, want %q", message.MarkdownContent, want)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lighthought/app-maker/shared-models/logger"
+	"github.com/lighthought/app-maker/shared-models/utils"
+
+	"github.com/lighthought/app-maker/backend/internal/models"
+	"github.com/lighthought/app-maker/backend/internal/repositories"
+)
+
+// 提交历史接口读取的最近提交数
+const projectCommitLogLimit = 500
+
+// CommitService 项目提交历史服务接口
+type CommitService interface {
+	// 获取项目提交历史，按开发阶段和故事分组，包含变更统计
+	GetProjectCommits(ctx context.Context, project *models.Project) (*models.ProjectCommitsResponse, error)
+}
+
+// commitService 项目提交历史服务实现
+type commitService struct {
+	repositories *repositories.Repository
+	gitService   GitService
+	environment  string
+}
+
+// NewCommitService 创建项目提交历史服务
+func NewCommitService(repositories *repositories.Repository, gitService GitService, environment string) CommitService {
+	return &commitService{
+		repositories: repositories,
+		gitService:   gitService,
+		environment:  environment,
+	}
+}
+
+// GetProjectCommits 获取项目提交历史，按开发阶段和故事分组
+func (s *commitService) GetProjectCommits(ctx context.Context, project *models.Project) (*models.ProjectCommitsResponse, error) {
+	projectPath, err := s.syncRepository(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+
+	gitCommits, err := s.gitService.GetCommitLog(ctx, projectPath, projectCommitLogLimit)
+	if err != nil {
+		return nil, fmt.Errorf("获取提交历史失败: %w", err)
+	}
+
+	stages, err := s.repositories.ProjectStageRepo.GetByProjectGUID(ctx, project.GUID)
+	if err != nil {
+		return nil, fmt.Errorf("获取开发阶段失败: %w", err)
+	}
+
+	return groupProjectCommits(project.GUID, stages, gitCommits), nil
+}
+
+// syncRepository 确保后端有项目代码的最新副本，不存在时克隆，存在时拉取
+func (s *commitService) syncRepository(ctx context.Context, project *models.Project) (string, error) {
+	projectPath := utils.GetProjectPath(project.UserID, project.GUID)
+	if !utils.IsDirectoryExists(projectPath) {
+		if err := s.gitService.Clone(ctx, utils.GetUserProjectsFolder(project.UserID), project.GUID, s.environment); err != nil {
+			return "", fmt.Errorf("克隆项目仓库失败: %w", err)
+		}
+		return projectPath, nil
+	}
+
+	gitConfig := &GitConfig{
+		UserID:      project.UserID,
+		GUID:        project.GUID,
+		ProjectPath: projectPath,
+		Environment: s.environment,
+	}
+	if err := s.gitService.Pull(ctx, gitConfig); err != nil {
+		// 拉取失败时使用本地已有的提交历史
+		logger.Warn("拉取项目代码失败，使用本地提交历史",
+			logger.String("GUID", project.GUID),
+			logger.String("error", err.Error()))
+	}
+	return projectPath, nil
+}
+
+// groupProjectCommits 按开发阶段分组提交，阶段顺序与项目开发阶段一致，阶段内关联了故事的提交再按故事分组
+func groupProjectCommits(projectGuid string, stages []*models.DevStage, gitCommits []*GitCommit) *models.ProjectCommitsResponse {
+	response := &models.ProjectCommitsResponse{
+		ProjectGuid: projectGuid,
+		Stages:      []*models.StageCommits{},
+		Others:      []*models.ProjectCommit{},
+	}
+
+	stageGroups := make(map[string]*models.StageCommits, len(stages))
+	storyGroups := make(map[string]*models.StoryCommits)
+	for _, stage := range stages {
+		group := &models.StageCommits{
+			DevStageID: stage.ID,
+			DevStage:   stage.Name,
+			Commits:    []*models.ProjectCommit{},
+			Stories:    []*models.StoryCommits{},
+		}
+		stageGroups[stage.Name] = group
+		response.Stages = append(response.Stages, group)
+	}
+
+	for _, gitCommit := range gitCommits {
+		commit := newProjectCommit(gitCommit)
+		response.Stats.Add(commit)
+		if commit.DevStage == "" {
+			response.Others = append(response.Others, commit)
+			continue
+		}
+
+		// 提交的阶段不在项目阶段列表中时（如阶段已被删除），单独建立分组
+		stageGroup, ok := stageGroups[commit.DevStage]
+		if !ok {
+			stageGroup = &models.StageCommits{
+				DevStage: commit.DevStage,
+				Commits:  []*models.ProjectCommit{},
+				Stories:  []*models.StoryCommits{},
+			}
+			stageGroups[commit.DevStage] = stageGroup
+			response.Stages = append(response.Stages, stageGroup)
+		}
+		stageGroup.Stats.Add(commit)
+		if commit.StoryNumber == "" {
+			stageGroup.Commits = append(stageGroup.Commits, commit)
+			continue
+		}
+
+		storyKey := commit.DevStage + "/" + commit.StoryNumber
+		storyGroup, ok := storyGroups[storyKey]
+		if !ok {
+			storyGroup = &models.StoryCommits{
+				StoryNumber: commit.StoryNumber,
+				Commits:     []*models.ProjectCommit{},
+			}
+			storyGroups[storyKey] = storyGroup
+			stageGroup.Stories = append(stageGroup.Stories, storyGroup)
+		}
+		storyGroup.Stats.Add(commit)
+		storyGroup.Commits = append(storyGroup.Commits, commit)
+	}
+	return response
+}
+
+// newProjectCommit 由 Git 提交记录生成项目提交，解析 App-Maker-* trailer
+func newProjectCommit(gitCommit *GitCommit) *models.ProjectCommit {
+	commit := &models.ProjectCommit{
+		Hash:         gitCommit.Hash,
+		ShortHash:    gitCommit.Hash,
+		Subject:      strings.SplitN(gitCommit.Message, "\n", 2)[0],
+		Message:      gitCommit.Message,
+		Author:       gitCommit.Author,
+		CommittedAt:  gitCommit.CommittedAt,
+		FilesChanged: gitCommit.FilesChanged,
+		Insertions:   gitCommit.Insertions,
+		Deletions:    gitCommit.Deletions,
+	}
+	if len(commit.ShortHash) > 8 {
+		commit.ShortHash = commit.ShortHash[:8]
+	}
+	if trailers := utils.ParseCommitTrailers(gitCommit.Message); trailers != nil {
+		commit.DevStage = trailers.Stage
+		commit.TaskID = trailers.Task
+		commit.StoryNumber = trailers.Story
+		commit.AgentType = trailers.Agent
+	}
+	return commit
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/lighthought/app-maker/backend/internal/models"
+)
+
+func TestGroupProjectCommits(t *testing.T) {
+	stages := []*models.DevStage{
+		{ID: "STAGE-1", Name: "generate_prd"},
+		{ID: "STAGE-2", Name: "develop_story"},
+	}
+	commits := []*GitCommit{
+		{Hash: "a1b2c3d4e5", Message: "feat(dev): implement story 1.1\n\nApp-Maker-Stage: develop_story\nApp-Maker-Story: 1.1", FilesChanged: 2, Insertions: 10, Deletions: 1},
+		{Hash: "b1", Message: "feat(dev): implement story 1.1\n\nApp-Maker-Stage: develop_story\nApp-Maker-Story: 1.1", FilesChanged: 1, Insertions: 3},
+		{Hash: "c1", Message: "docs(pm): generate PRD\n\nApp-Maker-Stage: generate_prd", FilesChanged: 1, Insertions: 50},
+		{Hash: "d1", Message: "manual fix", FilesChanged: 1, Deletions: 4},
+		{Hash: "e1", Message: "fix(dev): fix reported bug\n\nApp-Maker-Stage: fix_bug", FilesChanged: 1, Insertions: 1},
+	}
+
+	response := groupProjectCommits("guid", stages, commits)
+
+	if response.Stats.CommitCount != 5 || response.Stats.Insertions != 64 || response.Stats.Deletions != 5 {
+		t.Errorf("project stats = %+v", response.Stats)
+	}
+	if len(response.Others) != 1 || response.Others[0].Hash != "d1" {
+		t.Errorf("others = %+v", response.Others)
+	}
+	if len(response.Stages) != 3 {
+		t.Fatalf("stages = %d, want 3", len(response.Stages))
+	}
+
+	prd := response.Stages[0]
+	if prd.DevStageID != "STAGE-1" || len(prd.Commits) != 1 || len(prd.Stories) != 0 {
+		t.Errorf("prd stage = %+v", prd)
+	}
+	dev := response.Stages[1]
+	if len(dev.Commits) != 0 || len(dev.Stories) != 1 {
+		t.Fatalf("develop stage = %+v", dev)
+	}
+	if story := dev.Stories[0]; story.StoryNumber != "1.1" || story.Stats.CommitCount != 2 || story.Stats.FilesChanged != 3 {
+		t.Errorf("story = %+v", story)
+	}
+	if shortHash := dev.Stories[0].Commits[0].ShortHash; shortHash != "a1b2c3d4" {
+		t.Errorf("short hash = %q", shortHash)
+	}
+	if unknown := response.Stages[2]; unknown.DevStage != "fix_bug" || unknown.DevStageID != "" {
+		t.Errorf("unknown stage = %+v", unknown)
+	}
+}
+
+func TestParseNumstat(t *testing.T) {
+	commit := &GitCommit{}
+	parseNumstat(commit, "\n10\t2\tmain.go\n-\t-\tlogo.png\n3\t0\tREADME.md\n")
+	if commit.FilesChanged != 3 || commit.Insertions != 13 || commit.Deletions != 2 {
+		t.Errorf("numstat = %+v", commit)
+	}
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/common"
+
+	"github.com/lighthought/app-maker/backend/internal/models"
+	"github.com/lighthought/app-maker/backend/internal/repositories"
+)
+
+// 部署历史接口返回的最近部署数
+const projectDeploymentLimit = 100
+
+// DeploymentService 项目部署与部署历史服务接口
+type DeploymentService interface {
+	// 创建部署记录并提交部署任务，部署 Agent 工作区主干的最新提交，QA 评审未通过时返回 ErrQAGateFailed
+	Deploy(ctx context.Context, project *models.Project, userID, environment string) (*models.Deployment, error)
+
+	// 重新部署历史部署的提交，环境与历史部署相同
+	Redeploy(ctx context.Context, project *models.Project, userID, deploymentID string) (*models.Deployment, error)
+
+	// 获取项目的部署历史，environment 为空时返回所有环境
+	ListDeployments(ctx context.Context, project *models.Project, environment string) ([]*models.Deployment, error)
+}
+
+// deploymentService 项目部署服务实现
+type deploymentService struct {
+	repositories       *repositories.Repository
+	asyncClientService AsyncClientService
+	qaService          QAReviewService
+}
+
+// NewDeploymentService 创建项目部署服务
+func NewDeploymentService(repositories *repositories.Repository, asyncClientService AsyncClientService,
+	qaService QAReviewService) DeploymentService {
+	return &deploymentService{
+		repositories:       repositories,
+		asyncClientService: asyncClientService,
+		qaService:          qaService,
+	}
+}
+
+// Deploy 创建部署记录并提交部署任务
+func (s *deploymentService) Deploy(ctx context.Context, project *models.Project, userID, environment string) (*models.Deployment, error) {
+	if environment == "" {
+		environment = common.DeployEnvironmentDev
+	}
+	// 重新部署历史提交不检查，已部署过的提交可以回退
+	if err := s.qaService.CheckDeployable(ctx, project); err != nil {
+		return nil, err
+	}
+	return s.enqueue(ctx, project, &models.Deployment{
+		ProjectID:   project.ID,
+		ProjectGuid: project.GUID,
+		UserID:      userID,
+		Environment: environment,
+		Status:      common.CommonStatusPending,
+	})
+}
+
+// Redeploy 重新部署历史部署的提交
+func (s *deploymentService) Redeploy(ctx context.Context, project *models.Project, userID, deploymentID string) (*models.Deployment, error) {
+	source, err := s.repositories.DeploymentRepo.GetByID(ctx, deploymentID)
+	if err != nil {
+		return nil, fmt.Errorf("获取部署记录失败: %w", err)
+	}
+	if source.ProjectGuid != project.GUID {
+		return nil, fmt.Errorf("部署记录不属于该项目: %s", deploymentID)
+	}
+	if source.CommitSha == "" {
+		return nil, fmt.Errorf("部署记录没有提交信息，无法重新部署: %s", deploymentID)
+	}
+
+	return s.enqueue(ctx, project, &models.Deployment{
+		ProjectID:          project.ID,
+		ProjectGuid:        project.GUID,
+		UserID:             userID,
+		Environment:        source.Environment,
+		CommitSha:          source.CommitSha,
+		SourceDeploymentID: source.ID,
+		Status:             common.CommonStatusPending,
+	})
+}
+
+// enqueue 保存部署记录，然后提交部署任务，任务执行时更新记录的状态和结果
+func (s *deploymentService) enqueue(ctx context.Context, project *models.Project, deployment *models.Deployment) (*models.Deployment, error) {
+	if err := s.repositories.DeploymentRepo.Create(ctx, deployment); err != nil {
+		return nil, fmt.Errorf("创建部署记录失败: %w", err)
+	}
+
+	taskID, err := s.asyncClientService.EnqueueProjectDeployTask(&agent.DeployReq{
+		ProjectGuid:  project.GUID,
+		Environment:  deployment.Environment,
+		CommitSha:    deployment.CommitSha,
+		DeploymentID: deployment.ID,
+	})
+	if err != nil {
+		deployment.Finish(nil, err)
+		if updateErr := s.repositories.DeploymentRepo.Update(ctx, deployment); updateErr != nil {
+			return nil, fmt.Errorf("%w; 更新部署记录失败: %v", err, updateErr)
+		}
+		return nil, err
+	}
+
+	// 任务开始执行时会保存任务ID和状态，这里不再更新记录，避免覆盖任务已写入的状态
+	deployment.TaskID = taskID
+	return deployment, nil
+}
+
+// ListDeployments 获取项目的部署历史
+func (s *deploymentService) ListDeployments(ctx context.Context, project *models.Project, environment string) ([]*models.Deployment, error) {
+	return s.repositories.DeploymentRepo.ListByProjectGuid(ctx, project.GUID, environment, projectDeploymentLimit)
+}
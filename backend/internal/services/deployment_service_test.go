@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/common"
+
+	"github.com/lighthought/app-maker/backend/internal/models"
+	"github.com/lighthought/app-maker/backend/internal/repositories"
+)
+
+type fakeDeploymentRepo struct {
+	repositories.DeploymentRepository
+	deployments []*models.Deployment
+}
+
+func (r *fakeDeploymentRepo) Create(ctx context.Context, deployment *models.Deployment) error {
+	deployment.ID = "DEPLOY-new"
+	r.deployments = append(r.deployments, deployment)
+	return nil
+}
+
+func (r *fakeDeploymentRepo) GetByID(ctx context.Context, id string) (*models.Deployment, error) {
+	for _, deployment := range r.deployments {
+		if deployment.ID == id {
+			return deployment, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *fakeDeploymentRepo) Update(ctx context.Context, deployment *models.Deployment) error {
+	return nil
+}
+
+type fakeDeployAsyncClient struct {
+	AsyncClientService
+	requests []*agent.DeployReq
+	err      error
+}
+
+func (s *fakeDeployAsyncClient) EnqueueProjectDeployTask(req *agent.DeployReq) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	s.requests = append(s.requests, req)
+	return "task-1", nil
+}
+
+func TestDeploymentServiceRedeploy(t *testing.T) {
+	project := &models.Project{ID: "PROJ-1", GUID: "p1"}
+	repo := &fakeDeploymentRepo{deployments: []*models.Deployment{
+		{ID: "DEPLOY-1", ProjectGuid: "p1", Environment: common.DeployEnvironmentProd, CommitSha: "abc123"},
+		{ID: "DEPLOY-2", ProjectGuid: "p2", Environment: common.DeployEnvironmentDev, CommitSha: "def456"},
+		{ID: "DEPLOY-3", ProjectGuid: "p1", Environment: common.DeployEnvironmentDev},
+	}}
+	asyncClient := &fakeDeployAsyncClient{}
+	service := NewDeploymentService(&repositories.Repository{DeploymentRepo: repo}, asyncClient,
+		NewQAReviewService(&repositories.Repository{QAReviewRepo: &fakeQAReviewRepo{}}))
+
+	deployment, err := service.Redeploy(context.Background(), project, "USER-1", "DEPLOY-1")
+	if err != nil {
+		t.Fatalf("Redeploy() err = %v", err)
+	}
+	if deployment.Environment != common.DeployEnvironmentProd || deployment.CommitSha != "abc123" ||
+		deployment.SourceDeploymentID != "DEPLOY-1" || deployment.TaskID != "task-1" {
+		t.Errorf("deployment = %+v", deployment)
+	}
+	req := asyncClient.requests[0]
+	if req.ProjectGuid != "p1" || req.Environment != common.DeployEnvironmentProd ||
+		req.CommitSha != "abc123" || req.DeploymentID != deployment.ID {
+		t.Errorf("DeployReq = %+v", req)
+	}
+
+	// 其他项目的部署记录、没有提交的部署记录不能重新部署
+	for _, id := range []string{"DEPLOY-2", "DEPLOY-3", "DEPLOY-404"} {
+		if _, err := service.Redeploy(context.Background(), project, "USER-1", id); err == nil {
+			t.Errorf("Redeploy(%s) should fail", id)
+		}
+	}
+	if len(asyncClient.requests) != 1 {
+		t.Errorf("enqueued %d deploy tasks, want 1", len(asyncClient.requests))
+	}
+}
+
+func TestDeploymentServiceDeployEnqueueFailed(t *testing.T) {
+	repo := &fakeDeploymentRepo{}
+	service := NewDeploymentService(&repositories.Repository{DeploymentRepo: repo},
+		&fakeDeployAsyncClient{err: errors.New("redis down")},
+		NewQAReviewService(&repositories.Repository{QAReviewRepo: &fakeQAReviewRepo{}}))
+
+	if _, err := service.Deploy(context.Background(), &models.Project{ID: "PROJ-1", GUID: "p1"}, "USER-1", ""); err == nil {
+		t.Fatal("Deploy() should fail when enqueue fails")
+	}
+	deployment := repo.deployments[0]
+	if deployment.Environment != common.DeployEnvironmentDev || deployment.Status != common.CommonStatusFailed ||
+		deployment.ErrorMessage != "redis down" {
+		t.Errorf("deployment = %+v", deployment)
+	}
+}
+
+func TestDeploymentServiceDeployQAGateFailed(t *testing.T) {
+	repo := &fakeDeploymentRepo{}
+	asyncClient := &fakeDeployAsyncClient{}
+	qaRepo := &fakeQAReviewRepo{reviews: []*models.QAReview{{ProjectGuid: "p1", StoryNumber: "1.1", Gate: common.QAGateFail}}}
+	service := NewDeploymentService(&repositories.Repository{DeploymentRepo: repo}, asyncClient,
+		NewQAReviewService(&repositories.Repository{QAReviewRepo: qaRepo}))
+
+	// QA 评审未通过时不创建部署记录
+	_, err := service.Deploy(context.Background(), &models.Project{ID: "PROJ-1", GUID: "p1"}, "USER-1", "")
+	if !errors.Is(err, ErrQAGateFailed) {
+		t.Fatalf("Deploy() err = %v, want ErrQAGateFailed", err)
+	}
+	if len(repo.deployments) != 0 || len(asyncClient.requests) != 0 {
+		t.Errorf("deployments = %+v, requests = %+v", repo.deployments, asyncClient.requests)
+	}
+}
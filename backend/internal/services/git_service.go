@@ -6,7 +6,9 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/lighthought/app-maker/shared-models/common"
 	"github.com/lighthought/app-maker/shared-models/logger"
@@ -27,6 +29,10 @@ type GitService interface {
 	Pull(ctx context.Context, config *GitConfig) error
 	// 克隆远程仓库代码
 	Clone(ctx context.Context, userPath, projectGuid, environment string) error
+	// 获取提交历史（不含合并提交），包含每个提交的文件变更统计
+	GetCommitLog(ctx context.Context, projectDir string, maxCount int) ([]*GitCommit, error)
+	// 将本地仓库强制重置到指定提交，丢弃未提交的变更
+	ResetToCommit(ctx context.Context, projectDir, commitSha string) error
 }
 
 // GitService Git操作服务
@@ -58,6 +64,17 @@ type GitConfig struct {
 	Environment   string
 }
 
+// GitCommit Git提交记录
+type GitCommit struct {
+	Hash         string
+	Author       string
+	CommittedAt  time.Time
+	Message      string
+	FilesChanged int
+	Insertions   int
+	Deletions    int
+}
+
 // SetupSSH 配置SSH密钥和known_hosts
 func (s *gitService) SetupSSH() error {
 	logger.Info("配置SSH密钥")
@@ -405,6 +422,25 @@ func (s *gitService) Pull(ctx context.Context, config *GitConfig) error {
 	return nil
 }
 
+// ResetToCommit 将本地仓库强制重置到指定提交，丢弃未提交的变更
+func (s *gitService) ResetToCommit(ctx context.Context, projectDir, commitSha string) error {
+	if !s.isGitRepository(projectDir) {
+		return fmt.Errorf("project directory is not a Git repository: %s", projectDir)
+	}
+
+	// 先拉取远程提交，本地没有目标提交时 reset 会失败
+	if err := s.runGitCommand(ctx, projectDir, "fetch", "origin"); err != nil {
+		logger.Warn("failed to fetch remote before reset", logger.String("projectPath", projectDir))
+	}
+	if err := s.runGitCommand(ctx, projectDir, "reset", "--hard", commitSha); err != nil {
+		return fmt.Errorf("failed to reset repository: %s", err.Error())
+	}
+	if err := s.runGitCommand(ctx, projectDir, "clean", "-fd"); err != nil {
+		return fmt.Errorf("failed to clean repository: %s", err.Error())
+	}
+	return nil
+}
+
 // hasChanges 检查是否有文件变更
 func (s *gitService) hasChanges(projectDir string) bool {
 	cmd := exec.Command("git", "diff", "--cached", "--quiet")
@@ -412,3 +448,63 @@ func (s *gitService) hasChanges(projectDir string) bool {
 	err := cmd.Run()
 	return err != nil // 如果有错误说明有变更
 }
+
+// git log 输出的记录分隔符和字段分隔符
+const (
+	gitLogRecordSeparator = "\x1e"
+	gitLogFieldSeparator  = "\x1f"
+)
+
+// GetCommitLog 获取提交历史（不含合并提交），包含每个提交的文件变更统计
+func (s *gitService) GetCommitLog(ctx context.Context, projectDir string, maxCount int) ([]*GitCommit, error) {
+	if !s.isGitRepository(projectDir) {
+		return nil, fmt.Errorf("project directory is not a Git repository: %s", projectDir)
+	}
+
+	args := []string{"log", "--no-merges", "--numstat", "--format=%x1e%H%x1f%an%x1f%aI%x1f%B%x1f"}
+	if maxCount > 0 {
+		args = append(args, fmt.Sprintf("--max-count=%d", maxCount))
+	}
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = projectDir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git log: %s", err.Error())
+	}
+
+	var commits []*GitCommit
+	for _, record := range strings.Split(string(output), gitLogRecordSeparator) {
+		fields := strings.SplitN(record, gitLogFieldSeparator, 5)
+		if len(fields) < 5 {
+			continue
+		}
+		commit := &GitCommit{
+			Hash:    strings.TrimSpace(fields[0]),
+			Author:  fields[1],
+			Message: strings.TrimSpace(fields[3]),
+		}
+		if committedAt, err := time.Parse(time.RFC3339, fields[2]); err == nil {
+			commit.CommittedAt = committedAt
+		}
+		parseNumstat(commit, fields[4])
+		commits = append(commits, commit)
+	}
+	return commits, nil
+}
+
+// parseNumstat 解析 git log --numstat 的文件变更统计，二进制文件只计入变更文件数
+func parseNumstat(commit *GitCommit, numstat string) {
+	for _, line := range strings.Split(numstat, "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), "\t", 3)
+		if len(parts) < 3 {
+			continue
+		}
+		commit.FilesChanged++
+		if insertions, err := strconv.Atoi(parts[0]); err == nil {
+			commit.Insertions += insertions
+		}
+		if deletions, err := strconv.Atoi(parts[1]); err == nil {
+			commit.Deletions += deletions
+		}
+	}
+}
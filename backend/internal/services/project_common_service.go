@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/lighthought/app-maker/shared-models/agent"
 	"github.com/lighthought/app-maker/shared-models/common"
 	"github.com/lighthought/app-maker/shared-models/logger"
 	"github.com/lighthought/app-maker/shared-models/utils"
@@ -25,6 +26,90 @@ const (
 	FOLDER_STORIES = "docs/stories"
 )
 
+// 项目消息 key，阶段完成消息使用阶段名称作为 key
+const (
+	messageKeyChatDone        = "chat_done"
+	messageKeyConfirmRequired = "confirm_required"
+	messageKeyStageCancelled  = "stage_cancelled"
+	messageKeyDeployUnhealthy = "deploy_unhealthy"
+	messageKeyQAReviewHeader  = "qa_review_header"
+	messageKeyQAGateFailed    = "qa_gate_failed"
+
+	messageKeyCodeReviewDone    = "code_review_done"
+	messageKeyCodeReviewPassed  = "code_review_passed"
+	messageKeyCodeReviewFixed   = "code_review_fixed"
+	messageKeyCodeReviewBlocked = "code_review_blocked"
+	messageKeyCodeReviewFailed  = "code_review_failed"
+	messageKeyCodeReviewHeader  = "code_review_header"
+)
+
+// 项目开发过程中发送给用户的系统消息，按项目输出语言区分
+var projectMessages = map[string]map[string]string{
+	common.LanguageZhCN: {
+		messageKeyChatDone:                         "Agent 已完成",
+		messageKeyConfirmRequired:                  "%s，需要您的确认",
+		messageKeyStageCancelled:                   "用户已取消当前阶段",
+		messageKeyDeployUnhealthy:                  "项目已启动，但有组件未通过健康检查",
+		messageKeyQAReviewHeader:                   "| 故事 | 结论 | 原因 |\n|------|------|------|\n",
+		messageKeyQAGateFailed:                     "故事 %s 未通过 QA 评审，修复并重新评审前不会部署",
+		messageKeyCodeReviewDone:                   "故事 %s 的代码已评审",
+		messageKeyCodeReviewPassed:                 "评审通过，没有阻塞的意见",
+		messageKeyCodeReviewFixed:                  "Dev Agent 修复 %d 轮后评审通过",
+		messageKeyCodeReviewBlocked:                "Dev Agent 修复 %d 轮后仍有阻塞的意见，故事开发失败",
+		messageKeyCodeReviewFailed:                 "代码评审未完成：%s",
+		messageKeyCodeReviewHeader:                 "| 轮次 | 严重程度 | 位置 | 意见 | 阻塞 |\n|------|----------|------|------|------|\n",
+		string(common.DevStatusSetupAgents):        "项目开发环境已准备完成",
+		string(common.DevStatusCheckRequirement):   "项目需求已检查完成",
+		string(common.DevStatusGeneratePRD):        "项目PRD文档已生成",
+		string(common.DevStatusDefineUXStandard):   "项目UX标准已定义",
+		string(common.DevStatusDesignArchitecture): "项目系统架构已设计",
+		string(common.DevStatusDefineDataModel):    "项目数据模型已定义",
+		string(common.DevStatusDefineAPI):          "项目API接口已定义",
+		string(common.DevStatusPlanEpicAndStory):   "项目Epic和Story已划分",
+		string(common.DevStatusGeneratePages):      "前端关键页面已生成",
+		string(common.DevStatusDraftStory):         "项目Story文件已起草",
+		string(common.DevStatusDevelopStory):       MESSAGE_STORY_DEVELOPED,
+		string(common.DevStatusQAReview):           "项目Story已完成 QA 评审",
+		string(common.DevStatusFixBug):             "项目开发问题已修复",
+		string(common.DevStatusRunTest):            "项目自动测试已执行",
+		string(common.DevStatusDeploy):             MESSAGE_STAGE_DEPLOYED,
+	},
+	common.LanguageEnUS: {
+		messageKeyChatDone:                         "Agent finished",
+		messageKeyConfirmRequired:                  "%s, your confirmation is required",
+		messageKeyStageCancelled:                   "The current stage was cancelled by the user",
+		messageKeyDeployUnhealthy:                  "The project started, but some components failed the health check",
+		messageKeyQAReviewHeader:                   "| Story | Gate | Reason |\n|-------|------|--------|\n",
+		messageKeyQAGateFailed:                     "Stories %s failed the QA review and will not be deployed until they are fixed and reviewed again",
+		messageKeyCodeReviewDone:                   "Code of story %s reviewed",
+		messageKeyCodeReviewPassed:                 "Review passed with no blocking comments",
+		messageKeyCodeReviewFixed:                  "Review passed after %d fix rounds by the Dev agent",
+		messageKeyCodeReviewBlocked:                "Blocking comments remain after %d fix rounds by the Dev agent, the story failed",
+		messageKeyCodeReviewFailed:                 "Code review did not complete: %s",
+		messageKeyCodeReviewHeader:                 "| Round | Severity | Location | Comment | Blocking |\n|-------|----------|----------|---------|----------|\n",
+		string(common.DevStatusSetupAgents):        "Project development environment is ready",
+		string(common.DevStatusCheckRequirement):   "Project requirements checked",
+		string(common.DevStatusGeneratePRD):        "Project PRD generated",
+		string(common.DevStatusDefineUXStandard):   "Project UX standard defined",
+		string(common.DevStatusDesignArchitecture): "Project system architecture designed",
+		string(common.DevStatusDefineDataModel):    "Project data model defined",
+		string(common.DevStatusDefineAPI):          "Project API defined",
+		string(common.DevStatusPlanEpicAndStory):   "Project epics and stories planned",
+		string(common.DevStatusGeneratePages):      "Key frontend pages generated",
+		string(common.DevStatusDraftStory):         "Project story files drafted",
+		string(common.DevStatusDevelopStory):       "Project stories developed",
+		string(common.DevStatusQAReview):           "Project stories reviewed by QA",
+		string(common.DevStatusFixBug):             "Project development issues fixed",
+		string(common.DevStatusRunTest):            "Project automated tests executed",
+		string(common.DevStatusDeploy):             "Project packaged and deployed",
+	},
+}
+
+// getProjectMessage 获取指定语言的项目消息，不支持的语言使用默认语言
+func getProjectMessage(language, key string) string {
+	return projectMessages[common.NormalizeLanguage(language)][key]
+}
+
 // 项目阶段基础服务
 type ProjectCommonService interface {
 	// 获取项目开发阶段
@@ -49,6 +134,9 @@ type ProjectCommonService interface {
 	// 确保项目预览URL
 	EnsureProjectPrevieUrl(ctx context.Context, projectGuid string) error
 
+	// 使用部署结果中通过健康检查的预览地址更新项目，部署结果没有地址时按运行环境推测
+	UpdatePreviewUrlFromDeploy(ctx context.Context, project *models.Project, deploy *agent.DeployResp) error
+
 	// 更新并通知项目信息
 	UpdateAndNotifyProjectInfo(ctx context.Context, project *models.Project) error
 
@@ -101,7 +189,7 @@ func (s *projectCommonService) UpdateProjectWaitingForUserConfirm(ctx context.Co
 	s.webSocketService.NotifyProjectInfoUpdate(ctx, project.GUID, project)
 
 	// 通过 WebSocket 通知前端
-	s.webSocketService.NotifyUserConfirmRequired(ctx, project.GUID, stage, message)
+	s.webSocketService.NotifyUserConfirmRequired(ctx, project.GUID, project.Language, stage, message)
 }
 
 // CreateAndNotifyMessage 创建并通知用户消息
@@ -214,6 +302,26 @@ func (s *projectCommonService) EnsureProjectPrevieUrl(ctx context.Context, proje
 	return nil
 }
 
+// UpdatePreviewUrlFromDeploy 使用部署结果中通过健康检查的预览地址更新项目，部署结果没有地址时按运行环境推测
+func (s *projectCommonService) UpdatePreviewUrlFromDeploy(ctx context.Context, project *models.Project, deploy *agent.DeployResp) error {
+	if project == nil {
+		return fmt.Errorf("%s", MESSAGE_PROJECT_IS_NIL)
+	}
+	if deploy == nil || deploy.PreviewURL == "" {
+		return s.EnsureProjectPrevieUrl(ctx, project.GUID)
+	}
+	if project.PreviewUrl == deploy.PreviewURL {
+		return nil
+	}
+
+	project.PreviewUrl = deploy.PreviewURL
+	logger.Info("项目预览URL已设置",
+		logger.String("projectID", project.ID),
+		logger.String("previewUrl", project.PreviewUrl),
+	)
+	return s.UpdateAndNotifyProjectInfo(ctx, project)
+}
+
 // 更新并通知项目信息
 func (s *projectCommonService) UpdateAndNotifyProjectInfo(ctx context.Context, project *models.Project) error {
 	if project == nil {
@@ -260,10 +368,18 @@ func (s *projectCommonService) UpdateStageStatus(ctx context.Context, stage *mod
 		stage.SetStatus(common.CommonStatusInProgress)
 	case common.CommonStatusPaused:
 		stage.SetStatus(common.CommonStatusPaused)
+	case common.CommonStatusPending:
+		stage.ResetToPending()
 	}
 	if err := s.repositories.ProjectStageRepo.Update(ctx, stage); err != nil {
 		return fmt.Errorf("failed to update stage: %s", err.Error())
 	}
+	if status == common.CommonStatusPending {
+		// 重置为待执行时同时清除 Agent 任务ID，Update 不会更新任务ID
+		if err := s.repositories.ProjectStageRepo.ClearAgentTaskIDs(ctx, stage.ID); err != nil {
+			return fmt.Errorf("failed to clear stage agent tasks: %s", err.Error())
+		}
+	}
 
 	s.webSocketService.NotifyProjectStageUpdate(ctx, stage.ProjectGuid, stage)
 	logger.Info("更新阶段状态为完成成功", logger.String("stageID", stage.ID), logger.String("stageName", stage.Name))
@@ -12,6 +12,7 @@ import (
 	"github.com/lighthought/app-maker/shared-models/common"
 	"github.com/lighthought/app-maker/shared-models/logger"
 	"github.com/lighthought/app-maker/shared-models/tasks"
+	"github.com/lighthought/app-maker/shared-models/utils"
 )
 
 // 项目开发服务
@@ -23,11 +24,17 @@ type ProjectDevService interface {
 	GetStageItem(stageName common.DevStatus) *models.DevStageItem
 
 	// 处理 Agent 对话响应
-	OnChatResponse(ctx context.Context, message *agent.AgentTaskStatusMessage, response *tasks.TaskResult) error
+	OnChatResponse(ctx context.Context, project *models.Project, message *agent.AgentTaskStatusMessage, response *tasks.TaskResult) error
 
 	// 进入下一阶段的通用方法
 	ProceedToNextStage(ctx context.Context,
 		project *models.Project, currentStage common.DevStatus) error
+
+	// 取消项目当前阶段
+	CancelCurrentStage(ctx context.Context, project *models.Project) error
+
+	// 将项目回滚到指定阶段执行前的提交，rerun 为 true 时重新执行该阶段
+	RollbackStage(ctx context.Context, project *models.Project, stageID string, rerun bool) error
 }
 
 // 项目开发业务实现
@@ -36,6 +43,8 @@ type projectDevService struct {
 	asyncClientService   AsyncClientService
 	agentInteractService AgentInteractService
 	commonService        ProjectCommonService
+	gitService           GitService
+	qaService            QAReviewService
 	stageItems           []*models.DevStageItem
 }
 
@@ -45,12 +54,16 @@ func NewProjectDevService(
 	asyncClientService AsyncClientService,
 	agentInteractService AgentInteractService,
 	commonService ProjectCommonService,
+	gitService GitService,
+	qaService QAReviewService,
 ) ProjectDevService {
 	return &projectDevService{
 		repositories:         repositories,
 		asyncClientService:   asyncClientService,
 		agentInteractService: agentInteractService,
 		commonService:        commonService,
+		gitService:           gitService,
+		qaService:            qaService,
 	}
 }
 
@@ -76,14 +89,18 @@ func (s *projectDevService) InitStageItems() {
 			ReqHandler: s.agentInteractService.DefineAPIs, RespHandler: s.OnDefineAPIsResponse, SkipInDevMode: true},
 		{Name: common.DevStatusGeneratePages, Desc: "生成前端页面", NeedConfirm: true,
 			ReqHandler: s.agentInteractService.GenerateFrontendPages, RespHandler: s.OnGenerateFrontendPagesResponse},
+		{Name: common.DevStatusDraftStory, Desc: "起草 Story", NeedConfirm: true,
+			ReqHandler: s.agentInteractService.DraftStories, RespHandler: s.OnDraftStoriesResponse, SkipInDevMode: true},
 		{Name: common.DevStatusDevelopStory, Desc: "开发 Story", NeedConfirm: true,
 			ReqHandler: s.agentInteractService.DevelopStories, RespHandler: s.OnDevelopStoriesResponse, SkipInDevMode: true},
+		{Name: common.DevStatusQAReview, Desc: "QA 评审", NeedConfirm: false,
+			ReqHandler: s.agentInteractService.ReviewStories, RespHandler: s.OnQAReviewResponse, SkipInDevMode: true},
 		{Name: common.DevStatusFixBug, Desc: "修复 Bug", NeedConfirm: false,
 			ReqHandler: s.agentInteractService.FixBugs, RespHandler: s.OnFixBugsResponse, SkipInDevMode: true},
 		{Name: common.DevStatusRunTest, Desc: "运行测试", NeedConfirm: false,
 			ReqHandler: s.agentInteractService.RunTests, RespHandler: s.OnRunTestsResponse, SkipInDevMode: true},
 		{Name: common.DevStatusDeploy, Desc: "打包部署", NeedConfirm: false,
-			ReqHandler: s.agentInteractService.PackageProject, RespHandler: s.OnPackageProjectResponse},
+			ReqHandler: s.packageProject, RespHandler: s.OnPackageProjectResponse},
 	}
 }
 
@@ -114,6 +131,127 @@ func (s *projectDevService) ProceedToNextStage(ctx context.Context,
 	return nil
 }
 
+// CancelCurrentStage 取消项目当前阶段，终止 Agent 任务并将阶段和项目置为失败
+func (s *projectDevService) CancelCurrentStage(ctx context.Context, project *models.Project) error {
+	if project == nil {
+		return fmt.Errorf("%s", MESSAGE_PROJECT_IS_NIL)
+	}
+
+	stage, err := s.repositories.ProjectStageRepo.GetByProjectGuidAndName(ctx, project.GUID, project.DevStatus)
+	if err != nil {
+		return fmt.Errorf("获取项目当前阶段失败: %w", err)
+	}
+	if stage.Status != common.CommonStatusInProgress && stage.Status != common.CommonStatusPaused {
+		return fmt.Errorf("当前阶段不在执行中: %s", stage.Status)
+	}
+
+	// 开发故事阶段有多个 Agent 任务，全部取消
+	for _, agentTaskID := range stage.GetAgentTaskIDs() {
+		if err := s.agentInteractService.CancelAgentTask(ctx, agentTaskID); err != nil {
+			// Agent 任务可能已经结束，仍然将阶段置为失败
+			logger.Warn("取消 Agent 任务失败",
+				logger.String("agentTaskID", agentTaskID),
+				logger.String("error", err.Error()))
+		}
+	}
+
+	s.commonService.UpdateStageStatus(ctx, stage, common.CommonStatusFailed, getProjectMessage(project.Language, messageKeyStageCancelled))
+	s.commonService.UpdateProjectToStatus(ctx, project, common.CommonStatusFailed)
+	logger.Info("已取消项目当前阶段",
+		logger.String("projectGuid", project.GUID),
+		logger.String("stageName", stage.Name))
+	return nil
+}
+
+// RollbackStage 将 Agents 工作区和后端仓库回滚到阶段执行前的提交，
+// 该阶段及之后的阶段全部重置为待执行，项目暂停在该阶段
+func (s *projectDevService) RollbackStage(ctx context.Context, project *models.Project, stageID string, rerun bool) error {
+	if project == nil {
+		return fmt.Errorf("%s", MESSAGE_PROJECT_IS_NIL)
+	}
+
+	stage, err := s.repositories.ProjectStageRepo.GetByID(ctx, stageID)
+	if err != nil || stage.ProjectGuid != project.GUID {
+		return fmt.Errorf("项目阶段不存在: %s", stageID)
+	}
+	if stage.PreCommitSha == "" {
+		return fmt.Errorf("阶段没有记录执行前的提交，无法回滚: %s", stage.Name)
+	}
+	stageIndex := s.getStageIndex(common.DevStatus(stage.Name))
+	if stageIndex < 0 {
+		return fmt.Errorf("编排阶段不存在: %s", stage.Name)
+	}
+
+	stages, err := s.repositories.ProjectStageRepo.GetByProjectGUID(ctx, project.GUID)
+	if err != nil {
+		return fmt.Errorf("获取开发阶段失败: %w", err)
+	}
+	for _, item := range stages {
+		if item.Status == common.CommonStatusInProgress && len(item.GetAgentTaskIDs()) > 0 {
+			return fmt.Errorf("阶段正在执行，请先取消: %s", item.Name)
+		}
+	}
+
+	// Agents 工作区是代码的权威来源，回滚后推送到远程仓库
+	head, err := s.agentInteractService.RollbackGit(ctx, project.GUID, stage.PreCommitSha)
+	if err != nil {
+		return fmt.Errorf("回滚 Agents 工作区失败: %w", err)
+	}
+
+	// 后端副本落后时下次同步会重新拉取，重置失败不影响回滚结果
+	projectPath := utils.GetProjectPath(project.UserID, project.GUID)
+	if utils.IsDirectoryExists(projectPath) {
+		if err := s.gitService.ResetToCommit(ctx, projectPath, head.CommitSha); err != nil {
+			logger.Warn("重置后端项目仓库失败",
+				logger.String("projectGuid", project.GUID),
+				logger.String("error", err.Error()))
+		}
+	}
+
+	for _, item := range stages {
+		index := s.getStageIndex(common.DevStatus(item.Name))
+		if index < stageIndex {
+			continue
+		}
+		if index > stageIndex { // 之后的阶段重新执行时再记录执行前的提交
+			item.PreCommitSha = ""
+		}
+		if err := s.commonService.UpdateStageStatus(ctx, item, common.CommonStatusPending, ""); err != nil {
+			return fmt.Errorf("重置阶段状态失败: %w", err)
+		}
+	}
+
+	project.SetDevStatus(common.DevStatus(stage.Name))
+	project.Status = common.CommonStatusPaused
+	project.WaitingForUserConfirm = false
+	project.ConfirmStage = ""
+	if err := s.commonService.UpdateAndNotifyProjectInfo(ctx, project); err != nil {
+		return err
+	}
+	logger.Info("项目已回滚到阶段执行前的提交",
+		logger.String("projectGuid", project.GUID),
+		logger.String("stageName", stage.Name),
+		logger.String("commitSha", head.CommitSha))
+
+	if rerun {
+		item := s.stageItems[stageIndex]
+		if _, err := s.asyncClientService.EnqueueProjectStageTask(item.NeedConfirm, project.GUID, string(item.Name)); err != nil {
+			return fmt.Errorf("重新执行阶段失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// getStageIndex 获取阶段在编排中的顺序，不存在时返回 -1
+func (s *projectDevService) getStageIndex(stageName common.DevStatus) int {
+	for index, item := range s.stageItems {
+		if item.Name == stageName {
+			return index
+		}
+	}
+	return -1
+}
+
 // getNextStage 获取下一阶段
 func (s *projectDevService) getNextStage(currentStage common.DevStatus) *models.DevStageItem {
 	// 定义需要执行的阶段数组，方便调试过程中跳过耗时较多的故事实现等阶段
@@ -141,14 +279,14 @@ func (s *projectDevService) GetStageItem(stageName common.DevStatus) *models.Dev
 }
 
 // OnChatResponse 处理 Agent 对话响应
-func (s *projectDevService) OnChatResponse(ctx context.Context, message *agent.AgentTaskStatusMessage, response *tasks.TaskResult) error {
+func (s *projectDevService) OnChatResponse(ctx context.Context, project *models.Project, message *agent.AgentTaskStatusMessage, response *tasks.TaskResult) error {
 	agentRole := common.GetAgentByAgentType(message.AgentType)
 	projectMsg := &models.ConversationMessage{
 		ProjectGuid:     message.ProjectGuid,
 		Type:            common.ConversationTypeAgent,
 		AgentRole:       agentRole.Role,
 		AgentName:       agentRole.Name,
-		Content:         "Agent 已完成",
+		Content:         getProjectMessage(project.Language, messageKeyChatDone),
 		IsMarkdown:      true,
 		MarkdownContent: response.Message,
 		IsExpanded:      true,
@@ -158,13 +296,13 @@ func (s *projectDevService) OnChatResponse(ctx context.Context, message *agent.A
 }
 
 // OnPendingAgentResponse 处理 Agent 准备项目环境响应
-func (s *projectDevService) OnPendingAgentResponse(ctx context.Context, message *agent.AgentTaskStatusMessage, response *tasks.TaskResult) error {
+func (s *projectDevService) OnPendingAgentResponse(ctx context.Context, project *models.Project, message *agent.AgentTaskStatusMessage, response *tasks.TaskResult) error {
 	projectMsg := &models.ConversationMessage{
 		ProjectGuid:     message.ProjectGuid,
 		Type:            common.ConversationTypeAgent,
 		AgentRole:       common.AgentPM.Role,
 		AgentName:       common.AgentPM.Name,
-		Content:         "项目开发环境已准备完成",
+		Content:         getProjectMessage(project.Language, string(common.DevStatusSetupAgents)),
 		IsMarkdown:      true,
 		MarkdownContent: response.Message,
 		IsExpanded:      true,
@@ -174,13 +312,13 @@ func (s *projectDevService) OnPendingAgentResponse(ctx context.Context, message
 }
 
 // OnCheckRequirementResponse 处理检查需求响应
-func (s *projectDevService) OnCheckRequirementResponse(ctx context.Context, message *agent.AgentTaskStatusMessage, response *tasks.TaskResult) error {
+func (s *projectDevService) OnCheckRequirementResponse(ctx context.Context, project *models.Project, message *agent.AgentTaskStatusMessage, response *tasks.TaskResult) error {
 	projectMsg := &models.ConversationMessage{
 		ProjectGuid:     message.ProjectGuid,
 		Type:            common.ConversationTypeAgent,
 		AgentRole:       common.AgentAnalyst.Role,
 		AgentName:       common.AgentAnalyst.Name,
-		Content:         "项目需求已检查完成",
+		Content:         getProjectMessage(project.Language, string(common.DevStatusCheckRequirement)),
 		IsMarkdown:      true,
 		MarkdownContent: response.Message,
 		IsExpanded:      true,
@@ -189,13 +327,13 @@ func (s *projectDevService) OnCheckRequirementResponse(ctx context.Context, mess
 }
 
 // OnGeneratePRDResponse 处理生成 PRD 响应
-func (s *projectDevService) OnGeneratePRDResponse(ctx context.Context, message *agent.AgentTaskStatusMessage, response *tasks.TaskResult) error {
+func (s *projectDevService) OnGeneratePRDResponse(ctx context.Context, project *models.Project, message *agent.AgentTaskStatusMessage, response *tasks.TaskResult) error {
 	projectMsg := &models.ConversationMessage{
 		ProjectGuid:     message.ProjectGuid,
 		Type:            common.ConversationTypeAgent,
 		AgentRole:       common.AgentPM.Role,
 		AgentName:       common.AgentPM.Name,
-		Content:         "项目PRD文档已生成",
+		Content:         getProjectMessage(project.Language, string(common.DevStatusGeneratePRD)),
 		IsMarkdown:      true,
 		MarkdownContent: response.Message,
 		IsExpanded:      true,
@@ -205,13 +343,13 @@ func (s *projectDevService) OnGeneratePRDResponse(ctx context.Context, message *
 }
 
 // OnDefineUXStandardsResponse 处理定义 UX 标准响应
-func (s *projectDevService) OnDefineUXStandardsResponse(ctx context.Context, message *agent.AgentTaskStatusMessage, response *tasks.TaskResult) error {
+func (s *projectDevService) OnDefineUXStandardsResponse(ctx context.Context, project *models.Project, message *agent.AgentTaskStatusMessage, response *tasks.TaskResult) error {
 	projectMsg := &models.ConversationMessage{
 		ProjectGuid:     message.ProjectGuid,
 		Type:            common.ConversationTypeAgent,
 		AgentRole:       common.AgentUXExpert.Role,
 		AgentName:       common.AgentUXExpert.Name,
-		Content:         "项目UX标准已定义",
+		Content:         getProjectMessage(project.Language, string(common.DevStatusDefineUXStandard)),
 		IsMarkdown:      true,
 		MarkdownContent: response.Message,
 		IsExpanded:      true,
@@ -220,13 +358,13 @@ func (s *projectDevService) OnDefineUXStandardsResponse(ctx context.Context, mes
 }
 
 // OnDesignArchitectureResponse 处理设计系统架构响应
-func (s *projectDevService) OnDesignArchitectureResponse(ctx context.Context, message *agent.AgentTaskStatusMessage, response *tasks.TaskResult) error {
+func (s *projectDevService) OnDesignArchitectureResponse(ctx context.Context, project *models.Project, message *agent.AgentTaskStatusMessage, response *tasks.TaskResult) error {
 	projectMsg := &models.ConversationMessage{
 		ProjectGuid:     message.ProjectGuid,
 		Type:            common.ConversationTypeAgent,
 		AgentRole:       common.AgentArchitect.Role,
 		AgentName:       common.AgentArchitect.Name,
-		Content:         "项目系统架构已设计",
+		Content:         getProjectMessage(project.Language, string(common.DevStatusDesignArchitecture)),
 		IsMarkdown:      true,
 		MarkdownContent: response.Message,
 		IsExpanded:      true,
@@ -236,13 +374,13 @@ func (s *projectDevService) OnDesignArchitectureResponse(ctx context.Context, me
 }
 
 // OnDefineDataModelResponse 处理定义数据模型响应
-func (s *projectDevService) OnDefineDataModelResponse(ctx context.Context, message *agent.AgentTaskStatusMessage, response *tasks.TaskResult) error {
+func (s *projectDevService) OnDefineDataModelResponse(ctx context.Context, project *models.Project, message *agent.AgentTaskStatusMessage, response *tasks.TaskResult) error {
 	projectMsg := &models.ConversationMessage{
 		ProjectGuid:     message.ProjectGuid,
 		Type:            common.ConversationTypeAgent,
 		AgentRole:       common.AgentArchitect.Role,
 		AgentName:       common.AgentArchitect.Name,
-		Content:         "项目数据模型已定义",
+		Content:         getProjectMessage(project.Language, string(common.DevStatusDefineDataModel)),
 		IsMarkdown:      true,
 		MarkdownContent: response.Message,
 		IsExpanded:      true,
@@ -252,13 +390,13 @@ func (s *projectDevService) OnDefineDataModelResponse(ctx context.Context, messa
 }
 
 // OnDefineAPIsResponse 处理定义 API 接口响应
-func (s *projectDevService) OnDefineAPIsResponse(ctx context.Context, message *agent.AgentTaskStatusMessage, response *tasks.TaskResult) error {
+func (s *projectDevService) OnDefineAPIsResponse(ctx context.Context, project *models.Project, message *agent.AgentTaskStatusMessage, response *tasks.TaskResult) error {
 	projectMsg := &models.ConversationMessage{
 		ProjectGuid:     message.ProjectGuid,
 		Type:            common.ConversationTypeAgent,
 		AgentRole:       common.AgentArchitect.Role,
 		AgentName:       common.AgentArchitect.Name,
-		Content:         "项目API接口已定义",
+		Content:         getProjectMessage(project.Language, string(common.DevStatusDefineAPI)),
 		IsMarkdown:      true,
 		MarkdownContent: response.Message,
 		IsExpanded:      true,
@@ -296,16 +434,11 @@ func (s *projectDevService) extractMvpEpicsJSON(content string) (*models.MvpEpic
 }
 
 // SaveMvpEpics 保存 MVP Epics 到数据库
-func (s *projectDevService) saveMvpEpics(ctx context.Context, projectGuid string, mvpData *models.MvpEpicsData) error {
+func (s *projectDevService) saveMvpEpics(ctx context.Context, project *models.Project, mvpData *models.MvpEpicsData) error {
 	if mvpData == nil || len(mvpData.MvpEpics) == 0 {
 		return fmt.Errorf("MVP Epics 数据为空")
 	}
 
-	project, err := s.repositories.ProjectRepo.GetByGUID(ctx, projectGuid)
-	if err != nil {
-		return fmt.Errorf("获取项目失败: %w", err)
-	}
-
 	// 遍历每个 Epic
 	for _, epicItem := range mvpData.MvpEpics {
 		// 创建 Epic
@@ -370,12 +503,12 @@ func (s *projectDevService) saveMvpEpics(ctx context.Context, projectGuid string
 }
 
 // OnPlanEpicsAndStoriesResponse 处理划分 Epic 和 Story 响应
-func (s *projectDevService) OnPlanEpicsAndStoriesResponse(ctx context.Context, message *agent.AgentTaskStatusMessage, response *tasks.TaskResult) error {
+func (s *projectDevService) OnPlanEpicsAndStoriesResponse(ctx context.Context, project *models.Project, message *agent.AgentTaskStatusMessage, response *tasks.TaskResult) error {
 	// 解析返回的 markdown 中的 MVP Epics JSON 信息
 	mvpData, err := s.extractMvpEpicsJSON(response.Message)
 	if err == nil && mvpData != nil {
 		// 保存到数据库
-		if err := s.saveMvpEpics(ctx, message.ProjectGuid, mvpData); err != nil {
+		if err := s.saveMvpEpics(ctx, project, mvpData); err != nil {
 			logger.Error("保存 MVP Epics 失败", logger.String("error", err.Error()))
 		} else {
 			logger.Info("MVP Epics 已保存到数据库")
@@ -389,7 +522,7 @@ func (s *projectDevService) OnPlanEpicsAndStoriesResponse(ctx context.Context, m
 		Type:            common.ConversationTypeAgent,
 		AgentRole:       common.AgentPO.Role,
 		AgentName:       common.AgentPO.Name,
-		Content:         "项目Epic和Story已划分",
+		Content:         getProjectMessage(project.Language, string(common.DevStatusPlanEpicAndStory)),
 		IsMarkdown:      true,
 		MarkdownContent: response.Message,
 		IsExpanded:      true,
@@ -399,28 +532,44 @@ func (s *projectDevService) OnPlanEpicsAndStoriesResponse(ctx context.Context, m
 }
 
 // OnGenerateFrontendPagesResponse 处理生成前端页面响应
-func (s *projectDevService) OnGenerateFrontendPagesResponse(ctx context.Context, message *agent.AgentTaskStatusMessage, response *tasks.TaskResult) error {
+func (s *projectDevService) OnGenerateFrontendPagesResponse(ctx context.Context, project *models.Project, message *agent.AgentTaskStatusMessage, response *tasks.TaskResult) error {
 	projectMsg := &models.ConversationMessage{
 		ProjectGuid:     message.ProjectGuid,
 		Type:            common.ConversationTypeAgent,
 		AgentRole:       common.AgentDev.Role,
 		AgentName:       common.AgentDev.Name,
-		Content:         "前端关键页面已生成",
+		Content:         getProjectMessage(project.Language, string(common.DevStatusGeneratePages)),
+		IsMarkdown:      true,
+		MarkdownContent: response.Message,
+		IsExpanded:      true,
+	}
+	return s.commonService.CreateAndNotifyMessage(ctx, message.ProjectGuid, projectMsg)
+}
+
+// OnDraftStoriesResponse 处理起草 Story 响应
+func (s *projectDevService) OnDraftStoriesResponse(ctx context.Context, project *models.Project, message *agent.AgentTaskStatusMessage, response *tasks.TaskResult) error {
+	projectMsg := &models.ConversationMessage{
+		ProjectGuid:     message.ProjectGuid,
+		Type:            common.ConversationTypeAgent,
+		AgentRole:       common.AgentSM.Role,
+		AgentName:       common.AgentSM.Name,
+		Content:         getProjectMessage(project.Language, string(common.DevStatusDraftStory)),
 		IsMarkdown:      true,
 		MarkdownContent: response.Message,
 		IsExpanded:      true,
 	}
+
 	return s.commonService.CreateAndNotifyMessage(ctx, message.ProjectGuid, projectMsg)
 }
 
 // OnDevelopStoriesResponse 处理开发 Story 响应
-func (s *projectDevService) OnDevelopStoriesResponse(ctx context.Context, message *agent.AgentTaskStatusMessage, response *tasks.TaskResult) error {
+func (s *projectDevService) OnDevelopStoriesResponse(ctx context.Context, project *models.Project, message *agent.AgentTaskStatusMessage, response *tasks.TaskResult) error {
 	projectMsg := &models.ConversationMessage{
 		ProjectGuid:     message.ProjectGuid,
 		Type:            common.ConversationTypeAgent,
 		AgentRole:       common.AgentDev.Role,
 		AgentName:       common.AgentDev.Name,
-		Content:         MESSAGE_STORY_DEVELOPED,
+		Content:         getProjectMessage(project.Language, string(common.DevStatusDevelopStory)),
 		IsMarkdown:      true,
 		MarkdownContent: response.Message,
 		IsExpanded:      true,
@@ -430,13 +579,13 @@ func (s *projectDevService) OnDevelopStoriesResponse(ctx context.Context, messag
 }
 
 // OnFixBugsResponse 处理修复 Bug 响应
-func (s *projectDevService) OnFixBugsResponse(ctx context.Context, message *agent.AgentTaskStatusMessage, response *tasks.TaskResult) error {
+func (s *projectDevService) OnFixBugsResponse(ctx context.Context, project *models.Project, message *agent.AgentTaskStatusMessage, response *tasks.TaskResult) error {
 	projectMsg := &models.ConversationMessage{
 		ProjectGuid:     message.ProjectGuid,
 		Type:            common.ConversationTypeAgent,
 		AgentRole:       common.AgentDev.Role,
 		AgentName:       common.AgentDev.Name,
-		Content:         "项目开发问题已修复",
+		Content:         getProjectMessage(project.Language, string(common.DevStatusFixBug)),
 		IsMarkdown:      true,
 		MarkdownContent: response.Message,
 		IsExpanded:      true,
@@ -445,14 +594,49 @@ func (s *projectDevService) OnFixBugsResponse(ctx context.Context, message *agen
 	return s.commonService.CreateAndNotifyMessage(ctx, message.ProjectGuid, projectMsg)
 }
 
+// OnQAReviewResponse 处理 QA 评审响应，汇总每个故事最新的门禁结论
+func (s *projectDevService) OnQAReviewResponse(ctx context.Context, project *models.Project, message *agent.AgentTaskStatusMessage, response *tasks.TaskResult) error {
+	reviews, err := s.qaService.ListLatestReviews(ctx, project)
+	if err != nil {
+		logger.Error("获取 QA 评审失败", logger.String("error", err.Error()))
+	}
+
+	var builder strings.Builder
+	if failed := failedQAStories(reviews); len(failed) > 0 {
+		builder.WriteString(fmt.Sprintf(getProjectMessage(project.Language, messageKeyQAGateFailed), strings.Join(failed, ", ")) + "\n\n")
+	}
+	if len(reviews) > 0 {
+		builder.WriteString(getProjectMessage(project.Language, messageKeyQAReviewHeader))
+		for _, review := range reviews {
+			reason := strings.ReplaceAll(review.StatusReason, "|", "\\|")
+			builder.WriteString(fmt.Sprintf("| %s | %s | %s |\n", review.StoryNumber, review.Gate, strings.ReplaceAll(reason, "\n", " ")))
+		}
+	} else {
+		builder.WriteString(response.Message)
+	}
+
+	projectMsg := &models.ConversationMessage{
+		ProjectGuid:     message.ProjectGuid,
+		Type:            common.ConversationTypeAgent,
+		AgentRole:       common.AgentQA.Role,
+		AgentName:       common.AgentQA.Name,
+		Content:         getProjectMessage(project.Language, string(common.DevStatusQAReview)),
+		IsMarkdown:      true,
+		MarkdownContent: builder.String(),
+		IsExpanded:      true,
+	}
+
+	return s.commonService.CreateAndNotifyMessage(ctx, message.ProjectGuid, projectMsg)
+}
+
 // OnRunTestsResponse 处理运行测试响应
-func (s *projectDevService) OnRunTestsResponse(ctx context.Context, message *agent.AgentTaskStatusMessage, response *tasks.TaskResult) error {
+func (s *projectDevService) OnRunTestsResponse(ctx context.Context, project *models.Project, message *agent.AgentTaskStatusMessage, response *tasks.TaskResult) error {
 	projectMsg := &models.ConversationMessage{
 		ProjectGuid:     message.ProjectGuid,
 		Type:            common.ConversationTypeAgent,
 		AgentRole:       common.AgentDev.Role,
 		AgentName:       common.AgentDev.Name,
-		Content:         "项目自动测试已执行",
+		Content:         getProjectMessage(project.Language, string(common.DevStatusRunTest)),
 		IsMarkdown:      true,
 		MarkdownContent: response.Message,
 		IsExpanded:      true,
@@ -461,22 +645,51 @@ func (s *projectDevService) OnRunTestsResponse(ctx context.Context, message *age
 	return s.commonService.CreateAndNotifyMessage(ctx, message.ProjectGuid, projectMsg)
 }
 
+// packageProject 打包部署项目，有故事最新的 QA 评审结论为 fail 时不部署
+func (s *projectDevService) packageProject(ctx context.Context, project *models.Project) (string, error) {
+	if err := s.qaService.CheckDeployable(ctx, project); err != nil {
+		return "", err
+	}
+	return s.agentInteractService.PackageProject(ctx, project)
+}
+
 // OnPackageProjectResponse 处理打包部署项目响应
-func (s *projectDevService) OnPackageProjectResponse(ctx context.Context, message *agent.AgentTaskStatusMessage, response *tasks.TaskResult) error {
+func (s *projectDevService) OnPackageProjectResponse(ctx context.Context, project *models.Project, message *agent.AgentTaskStatusMessage, response *tasks.TaskResult) error {
 	projectMsg := &models.ConversationMessage{
 		ProjectGuid:     message.ProjectGuid,
 		Type:            common.ConversationTypeAgent,
 		AgentRole:       common.AgentDev.Role,
 		AgentName:       common.AgentDev.Name,
-		Content:         MESSAGE_STAGE_DEPLOYED,
+		Content:         getProjectMessage(project.Language, string(common.DevStatusDeploy)),
 		IsMarkdown:      true,
 		MarkdownContent: response.Message,
 		IsExpanded:      true,
 	}
 
-	if err := s.commonService.EnsureProjectPrevieUrl(ctx, message.ProjectGuid); err != nil {
-		logger.Error("确保项目预览URL失败", logger.String("error", err.Error()))
+	if err := s.commonService.UpdatePreviewUrlFromDeploy(ctx, project, response.Deploy); err != nil {
+		logger.Error("更新项目预览URL失败", logger.String("error", err.Error()))
 	}
+	s.recordStageDeployment(ctx, project, message, response)
 
 	return s.commonService.CreateAndNotifyMessage(ctx, message.ProjectGuid, projectMsg)
 }
+
+// recordStageDeployment 把开发流程部署阶段的结果记录到部署历史，失败不影响阶段流转
+func (s *projectDevService) recordStageDeployment(ctx context.Context, project *models.Project,
+	message *agent.AgentTaskStatusMessage, response *tasks.TaskResult) {
+	deployment := &models.Deployment{
+		ProjectID:   project.ID,
+		ProjectGuid: project.GUID,
+		UserID:      project.UserID,
+		Environment: common.DeployEnvironmentDev,
+		AgentTaskID: message.TaskID,
+	}
+	if stage, err := s.repositories.ProjectStageRepo.GetByProjectGuidAndName(ctx, project.GUID, message.DevStage); err == nil {
+		deployment.TaskID = stage.TaskID
+		deployment.StartedAt = stage.StartedAt
+	}
+	deployment.Finish(response, nil)
+	if err := s.repositories.DeploymentRepo.Create(ctx, deployment); err != nil {
+		logger.Warn("记录部署历史失败", logger.String("projectGuid", project.GUID), logger.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/common"
+
+	"github.com/lighthought/app-maker/backend/internal/models"
+	"github.com/lighthought/app-maker/backend/internal/repositories"
+)
+
+type fakeStageRepo struct {
+	repositories.StageRepository
+	stages []*models.DevStage
+}
+
+func (r *fakeStageRepo) GetByID(ctx context.Context, id string) (*models.DevStage, error) {
+	for _, stage := range r.stages {
+		if stage.ID == id {
+			return stage, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *fakeStageRepo) GetByProjectGUID(ctx context.Context, projectGuid string) ([]*models.DevStage, error) {
+	return r.stages, nil
+}
+
+func (r *fakeStageRepo) Update(ctx context.Context, stage *models.DevStage) error {
+	return nil
+}
+
+func (r *fakeStageRepo) ClearAgentTaskIDs(ctx context.Context, id string) error {
+	for _, stage := range r.stages {
+		if stage.ID == id {
+			stage.AgentTaskID = ""
+		}
+	}
+	return nil
+}
+
+type fakeProjectRepo struct {
+	repositories.ProjectRepository
+	project *models.Project
+	updates int
+}
+
+func (r *fakeProjectRepo) GetByGUID(ctx context.Context, guid string) (*models.Project, error) {
+	if r.project == nil || r.project.GUID != guid {
+		return nil, errors.New("record not found")
+	}
+	return r.project, nil
+}
+
+func (r *fakeProjectRepo) Update(ctx context.Context, project *models.Project) error {
+	r.updates++
+	return nil
+}
+
+type fakeWebSocketService struct {
+	WebSocketService
+}
+
+func (s *fakeWebSocketService) NotifyProjectStageUpdate(ctx context.Context, projectGUID string, stage *models.DevStage) {
+}
+
+func (s *fakeWebSocketService) NotifyProjectInfoUpdate(ctx context.Context, projectGUID string, info *models.Project) {
+}
+
+type fakeAgentInteractService struct {
+	AgentInteractService
+	rollbackSha string
+}
+
+func (s *fakeAgentInteractService) RollbackGit(ctx context.Context, projectGuid, commitSha string) (*agent.GitHeadInfo, error) {
+	s.rollbackSha = commitSha
+	return &agent.GitHeadInfo{BaseBranch: "master", CommitSha: commitSha}, nil
+}
+
+type fakeAsyncClientService struct {
+	AsyncClientService
+	enqueued []string
+}
+
+func (s *fakeAsyncClientService) EnqueueProjectStageTask(needConfirm bool, projectGuid, stageName string) (string, error) {
+	s.enqueued = append(s.enqueued, stageName)
+	return "task-1", nil
+}
+
+func newRollbackTestService(stages []*models.DevStage) (*projectDevService, *fakeAgentInteractService, *fakeAsyncClientService) {
+	repos := &repositories.Repository{
+		ProjectRepo:      &fakeProjectRepo{},
+		ProjectStageRepo: &fakeStageRepo{stages: stages},
+	}
+	agentService := &fakeAgentInteractService{}
+	asyncClient := &fakeAsyncClientService{}
+	service := &projectDevService{
+		repositories:         repos,
+		asyncClientService:   asyncClient,
+		agentInteractService: agentService,
+		commonService:        NewProjectCommonService(repos, &fakeWebSocketService{}, common.EnvironmentLocalDebug),
+		stageItems: []*models.DevStageItem{
+			{Name: common.DevStatusGeneratePRD, NeedConfirm: true},
+			{Name: common.DevStatusDesignArchitecture, NeedConfirm: true},
+			{Name: common.DevStatusDevelopStory, NeedConfirm: true},
+		},
+	}
+	return service, agentService, asyncClient
+}
+
+func TestRollbackStage(t *testing.T) {
+	newStages := func() []*models.DevStage {
+		return []*models.DevStage{
+			{ID: "S1", ProjectGuid: "P1", Name: string(common.DevStatusGeneratePRD), Status: common.CommonStatusDone, PreCommitSha: "sha-1", PostCommitSha: "sha-2"},
+			{ID: "S2", ProjectGuid: "P1", Name: string(common.DevStatusDesignArchitecture), Status: common.CommonStatusDone, PreCommitSha: "sha-2", PostCommitSha: "sha-3"},
+			{ID: "S3", ProjectGuid: "P1", Name: string(common.DevStatusDevelopStory), Status: common.CommonStatusFailed, PreCommitSha: "sha-3", FailedReason: "boom"},
+		}
+	}
+
+	t.Run("reset stage and later stages", func(t *testing.T) {
+		stages := newStages()
+		service, agentService, asyncClient := newRollbackTestService(stages)
+		project := &models.Project{GUID: "P1", UserID: "USER-1", Status: common.CommonStatusFailed}
+
+		if err := service.RollbackStage(context.Background(), project, "S2", true); err != nil {
+			t.Fatalf("RollbackStage() err = %v", err)
+		}
+		if agentService.rollbackSha != "sha-2" {
+			t.Errorf("rollback sha = %q, want sha-2", agentService.rollbackSha)
+		}
+		if stages[0].Status != common.CommonStatusDone || stages[0].PostCommitSha != "sha-2" {
+			t.Errorf("earlier stage changed: %+v", stages[0])
+		}
+		if stages[1].Status != common.CommonStatusPending || stages[1].PreCommitSha != "sha-2" || stages[1].PostCommitSha != "" {
+			t.Errorf("rolled back stage = %+v", stages[1])
+		}
+		if stages[2].Status != common.CommonStatusPending || stages[2].PreCommitSha != "" || stages[2].FailedReason != "" {
+			t.Errorf("later stage = %+v", stages[2])
+		}
+		if project.DevStatus != string(common.DevStatusDesignArchitecture) || project.Status != common.CommonStatusPaused {
+			t.Errorf("project = %s/%s", project.DevStatus, project.Status)
+		}
+		if len(asyncClient.enqueued) != 1 || asyncClient.enqueued[0] != string(common.DevStatusDesignArchitecture) {
+			t.Errorf("enqueued = %v", asyncClient.enqueued)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		stageID string
+		mutate  func(stages []*models.DevStage)
+	}{
+		{name: "stage of another project", stageID: "S1", mutate: func(stages []*models.DevStage) { stages[0].ProjectGuid = "P2" }},
+		{name: "missing pre commit", stageID: "S1", mutate: func(stages []*models.DevStage) { stages[0].PreCommitSha = "" }},
+		{name: "stage still running", stageID: "S1", mutate: func(stages []*models.DevStage) {
+			stages[2].Status = common.CommonStatusInProgress
+			stages[2].AgentTaskID = "agent-1"
+		}},
+		{name: "unknown stage", stageID: "S9", mutate: func(stages []*models.DevStage) {}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stages := newStages()
+			tt.mutate(stages)
+			service, agentService, asyncClient := newRollbackTestService(stages)
+
+			err := service.RollbackStage(context.Background(), &models.Project{GUID: "P1"}, tt.stageID, true)
+			if err == nil {
+				t.Fatal("RollbackStage() should fail")
+			}
+			if agentService.rollbackSha != "" || len(asyncClient.enqueued) != 0 {
+				t.Errorf("rollback should not reach agents, sha %q, enqueued %v", agentService.rollbackSha, asyncClient.enqueued)
+			}
+		})
+	}
+}
+
+func TestUpdatePreviewUrlFromDeploy(t *testing.T) {
+	project := &models.Project{GUID: "P1", FrontendPort: 3501}
+	projectRepo := &fakeProjectRepo{project: project}
+	service := NewProjectCommonService(&repositories.Repository{ProjectRepo: projectRepo}, &fakeWebSocketService{}, common.EnvironmentLocalDebug)
+	ctx := context.Background()
+
+	deploy := &agent.DeployResp{Status: common.DeployHealthHealthy, PreviewURL: "http://localhost:3601"}
+	if err := service.UpdatePreviewUrlFromDeploy(ctx, project, deploy); err != nil {
+		t.Fatalf("UpdatePreviewUrlFromDeploy() err = %v", err)
+	}
+	if project.PreviewUrl != "http://localhost:3601" || projectRepo.updates != 1 {
+		t.Errorf("preview url = %q, updates = %d", project.PreviewUrl, projectRepo.updates)
+	}
+
+	// 地址未变化时不重复更新
+	if err := service.UpdatePreviewUrlFromDeploy(ctx, project, deploy); err != nil || projectRepo.updates != 1 {
+		t.Errorf("unchanged preview url: err = %v, updates = %d", err, projectRepo.updates)
+	}
+
+	// 部署结果没有地址（mock CLI、旧版 Agent 服务）时按运行环境推测
+	guessed := &models.Project{GUID: "P1", FrontendPort: 3501}
+	projectRepo.project = guessed
+	if err := service.UpdatePreviewUrlFromDeploy(ctx, guessed, nil); err != nil {
+		t.Fatalf("UpdatePreviewUrlFromDeploy(nil) err = %v", err)
+	}
+	if guessed.PreviewUrl != "http://localhost:3501" {
+		t.Errorf("guessed preview url = %q", guessed.PreviewUrl)
+	}
+}
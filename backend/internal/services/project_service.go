@@ -51,10 +51,11 @@ const (
 type projectService struct {
 	repositories *repositories.Repository
 
-	templateService    ProjectTemplateService
-	commonService      ProjectCommonService
-	gitService         GitService
-	asyncClientService AsyncClientService
+	templateService      ProjectTemplateService
+	commonService        ProjectCommonService
+	gitService           GitService
+	asyncClientService   AsyncClientService
+	agentInteractService AgentInteractService
 
 	config *config.Config
 }
@@ -66,15 +67,17 @@ func NewProjectService(
 	commonService ProjectCommonService,
 	gitService GitService,
 	asyncClientService AsyncClientService,
+	agentInteractService AgentInteractService,
 	config *config.Config,
 ) ProjectService {
 	return &projectService{
-		repositories:       repositories,
-		templateService:    templateService,
-		commonService:      commonService,
-		gitService:         gitService,
-		asyncClientService: asyncClientService,
-		config:             config,
+		repositories:         repositories,
+		templateService:      templateService,
+		commonService:        commonService,
+		gitService:           gitService,
+		asyncClientService:   asyncClientService,
+		agentInteractService: agentInteractService,
+		config:               config,
 	}
 }
 
@@ -137,6 +140,7 @@ func (s *projectService) CreateProject(ctx context.Context, req *models.CreatePr
 	)
 
 	newProject := models.GetDefaultProject(userID, req.Requirements)
+	newProject.Language = s.getDefaultLanguage(ctx, userID, req.Language)
 	if err := s.repositories.ProjectRepo.Create(ctx, newProject); err != nil {
 		logger.Error("保存项目到数据库失败",
 			logger.String("error", err.Error()),
@@ -468,6 +472,21 @@ func (s *projectService) HandleProjectInitTask(ctx context.Context, t *asynq.Tas
 	return nil
 }
 
+// getDefaultLanguage 获取新建项目的输出语言：请求指定的语言优先，其次用户设置的默认语言
+func (s *projectService) getDefaultLanguage(ctx context.Context, userID, language string) string {
+	if language != "" {
+		return common.NormalizeLanguage(language)
+	}
+	user, err := s.repositories.UserRepo.GetByID(ctx, userID)
+	if err != nil {
+		logger.Warn("获取用户默认语言失败，使用系统默认语言",
+			logger.String("userID", userID),
+			logger.String("error", err.Error()))
+		return common.DefaultLanguage
+	}
+	return common.NormalizeLanguage(user.DefaultLanguage)
+}
+
 // GetProject 获取项目信息
 func (s *projectService) GetProject(ctx context.Context, projectGuid, userID string) (*models.ProjectInfo, error) {
 	project, err := s.CheckProjectAccess(ctx, projectGuid, userID)
@@ -507,6 +526,15 @@ func (s *projectService) UpdateProject(ctx context.Context, projectGuid string,
 	if req.ModelApiUrl != nil {
 		project.ModelApiUrl = *req.ModelApiUrl
 	}
+	if req.Language != nil {
+		project.Language = *req.Language
+	}
+	if req.CodeReviewEnabled != nil {
+		project.CodeReviewEnabled = *req.CodeReviewEnabled
+	}
+	if req.BlockingSeverities != nil {
+		project.BlockingSeverities = req.BlockingSeverities
+	}
 
 	// 保存更新
 	if err := s.repositories.ProjectRepo.Update(ctx, project); err != nil {
@@ -517,6 +545,17 @@ func (s *projectService) UpdateProject(ctx context.Context, projectGuid string,
 		return nil, fmt.Errorf("failed to update project: %s", err.Error())
 	}
 
+	// 模型配置变更后同步到 Agents 服务，下次执行 CLI 时生效；同步失败不影响本次更新
+	modelConfigChanged := req.CliTool != nil || req.AiModel != nil || req.ModelProvider != nil || req.ModelApiUrl != nil
+	if modelConfigChanged && s.agentInteractService != nil {
+		if err := s.agentInteractService.UpdateModelConfig(ctx, project); err != nil {
+			logger.Warn("同步项目模型配置到 Agents 服务失败",
+				logger.String("projectGuid", projectGuid),
+				logger.String("error", err.Error()),
+			)
+		}
+	}
+
 	// 返回更新后的项目信息
 	return s.GetProject(ctx, projectGuid, userID)
 }
@@ -537,7 +576,20 @@ func (s *projectService) DeleteProject(ctx context.Context, projectGuid, userID
 		s.asyncClientService.EnqueueProjectBackupTask(project.ID, projectGuid, project.ProjectPath)
 	}
 
-	return s.repositories.ProjectRepo.Delete(ctx, project.ID)
+	if err := s.repositories.ProjectRepo.Delete(ctx, project.ID); err != nil {
+		return err
+	}
+
+	// 清除 Agents 服务保存的模型配置和 API Token；失败不影响删除
+	if s.agentInteractService != nil {
+		if err := s.agentInteractService.DeleteModelConfig(ctx, projectGuid); err != nil {
+			logger.Warn("删除 Agents 服务中的项目模型配置失败",
+				logger.String("projectGuid", projectGuid),
+				logger.String("error", err.Error()),
+			)
+		}
+	}
+	return nil
 }
 
 // ListProjects 获取项目列表
@@ -0,0 +1,315 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/common"
+	"github.com/lighthought/app-maker/shared-models/logger"
+	"github.com/lighthought/app-maker/shared-models/prompt"
+
+	"github.com/lighthought/app-maker/backend/internal/models"
+	"github.com/lighthought/app-maker/backend/internal/repositories"
+)
+
+// PromptService 提示词模板服务接口，项目、用户覆盖的模板保存在数据库中，随 Agent 请求一起发送。
+// 模板按 项目 → 用户 → 内置 的顺序生效
+type PromptService interface {
+	// 获取项目的所有提示词模板，项目覆盖的模板优先于用户覆盖的模板和内置模板
+	ListProjectPrompts(ctx context.Context, project *models.Project) ([]*agent.PromptTemplate, error)
+
+	// 获取项目的提示词模板，项目未覆盖时返回用户覆盖的模板或内置模板
+	GetProjectPrompt(ctx context.Context, project *models.Project, name string) (*agent.PromptTemplate, error)
+
+	// 保存项目覆盖的提示词模板，每次保存版本加 1
+	SaveProjectPrompt(ctx context.Context, project *models.Project, userID, name, content string) (*agent.PromptTemplate, error)
+
+	// 删除项目覆盖的提示词模板，返回恢复后生效的用户模板或内置模板
+	ResetProjectPrompt(ctx context.Context, project *models.Project, name string) (*agent.PromptTemplate, error)
+
+	// 获取用户指定语言的所有提示词模板，用户覆盖的模板优先于内置模板
+	ListUserPrompts(ctx context.Context, userID, language string) ([]*agent.PromptTemplate, error)
+
+	// 获取用户指定语言的提示词模板，用户未覆盖时返回内置模板
+	GetUserPrompt(ctx context.Context, userID, name, language string) (*agent.PromptTemplate, error)
+
+	// 保存用户覆盖的提示词模板，对该用户所有同语言的项目生效，每次保存版本加 1
+	SaveUserPrompt(ctx context.Context, userID, name, language, content string) (*agent.PromptTemplate, error)
+
+	// 删除用户覆盖的提示词模板，返回恢复后的内置模板
+	ResetUserPrompt(ctx context.Context, userID, name, language string) (*agent.PromptTemplate, error)
+
+	// 获取项目生效的覆盖模板（项目覆盖优先，其次用户覆盖），都未覆盖或查询失败时返回 nil
+	GetOverride(ctx context.Context, project *models.Project, name string) *agent.PromptTemplate
+
+	// 用项目的提示词模板渲染提示词
+	Render(ctx context.Context, project *models.Project, name string, data interface{}) (*agent.RenderedPrompt, error)
+}
+
+// promptService 提示词模板服务实现
+type promptService struct {
+	repositories   *repositories.Repository
+	promptRegistry prompt.Registry
+}
+
+// NewPromptService 创建提示词模板服务
+func NewPromptService(repositories *repositories.Repository, promptRegistry prompt.Registry) PromptService {
+	return &promptService{
+		repositories:   repositories,
+		promptRegistry: promptRegistry,
+	}
+}
+
+// ListProjectPrompts 获取项目的所有提示词模板
+func (s *promptService) ListProjectPrompts(ctx context.Context, project *models.Project) ([]*agent.PromptTemplate, error) {
+	overrides, err := s.repositories.PromptRepo.ListByProjectID(ctx, project.ID)
+	if err != nil {
+		return nil, fmt.Errorf("获取项目提示词模板失败: %w", err)
+	}
+	userOverrides, err := s.repositories.UserPromptRepo.ListByUserID(ctx, project.UserID, common.NormalizeLanguage(project.Language))
+	if err != nil {
+		return nil, fmt.Errorf("获取用户提示词模板失败: %w", err)
+	}
+	overrideMap := make(map[string]*agent.PromptTemplate, len(overrides)+len(userOverrides))
+	for _, override := range userOverrides {
+		overrideMap[override.Name] = override.ToPromptTemplate()
+	}
+	for _, override := range overrides {
+		overrideMap[override.Name] = override.ToPromptTemplate()
+	}
+
+	templates := s.promptRegistry.List(project.Language)
+	for index, tmpl := range templates {
+		if override, ok := overrideMap[tmpl.Name]; ok {
+			templates[index] = override
+		}
+	}
+	return templates, nil
+}
+
+// GetProjectPrompt 获取项目的提示词模板
+func (s *promptService) GetProjectPrompt(ctx context.Context, project *models.Project, name string) (*agent.PromptTemplate, error) {
+	builtin, err := s.promptRegistry.Get(name, project.Language)
+	if err != nil {
+		return nil, err
+	}
+	override, err := s.resolveOverride(ctx, project, name)
+	if err != nil {
+		return nil, err
+	}
+	if override == nil {
+		return builtin, nil
+	}
+	return override, nil
+}
+
+// SaveProjectPrompt 保存项目覆盖的提示词模板
+func (s *promptService) SaveProjectPrompt(ctx context.Context, project *models.Project,
+	userID, name, content string) (*agent.PromptTemplate, error) {
+	builtin, err := s.promptRegistry.Get(name, project.Language)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.promptRegistry.Validate(name, content); err != nil {
+		return nil, err
+	}
+
+	override, err := s.repositories.PromptRepo.GetByProjectIDAndName(ctx, project.ID, name)
+	if err != nil {
+		return nil, fmt.Errorf("获取项目提示词模板失败: %w", err)
+	}
+	if override == nil {
+		override = &models.ProjectPrompt{
+			ProjectID:   project.ID,
+			Name:        name,
+			Version:     1,
+			BaseVersion: builtin.Version,
+			Content:     content,
+			UpdatedBy:   userID,
+		}
+		if err := s.repositories.PromptRepo.Create(ctx, override); err != nil {
+			return nil, fmt.Errorf("保存项目提示词模板失败: %w", err)
+		}
+	} else {
+		override.Version++
+		override.BaseVersion = builtin.Version
+		override.Content = content
+		override.UpdatedBy = userID
+		if err := s.repositories.PromptRepo.Update(ctx, override); err != nil {
+			return nil, fmt.Errorf("保存项目提示词模板失败: %w", err)
+		}
+	}
+
+	logger.Info("已保存项目提示词模板",
+		logger.String("projectGuid", project.GUID),
+		logger.String("name", name),
+		logger.Int("version", override.Version))
+	return override.ToPromptTemplate(), nil
+}
+
+// ResetProjectPrompt 删除项目覆盖的提示词模板
+func (s *promptService) ResetProjectPrompt(ctx context.Context, project *models.Project, name string) (*agent.PromptTemplate, error) {
+	builtin, err := s.promptRegistry.Get(name, project.Language)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repositories.PromptRepo.DeleteByProjectIDAndName(ctx, project.ID, name); err != nil {
+		return nil, fmt.Errorf("删除项目提示词模板失败: %w", err)
+	}
+
+	logger.Info("已删除项目提示词模板",
+		logger.String("projectGuid", project.GUID),
+		logger.String("name", name))
+
+	// 用户覆盖了该模板时恢复为用户模板
+	userOverride, err := s.repositories.UserPromptRepo.GetByUserIDAndName(ctx, project.UserID, name, common.NormalizeLanguage(project.Language))
+	if err != nil {
+		return nil, fmt.Errorf("获取用户提示词模板失败: %w", err)
+	}
+	if userOverride != nil {
+		return userOverride.ToPromptTemplate(), nil
+	}
+	return builtin, nil
+}
+
+// ListUserPrompts 获取用户指定语言的所有提示词模板
+func (s *promptService) ListUserPrompts(ctx context.Context, userID, language string) ([]*agent.PromptTemplate, error) {
+	language = common.NormalizeLanguage(language)
+	overrides, err := s.repositories.UserPromptRepo.ListByUserID(ctx, userID, language)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户提示词模板失败: %w", err)
+	}
+	overrideMap := make(map[string]*models.UserPrompt, len(overrides))
+	for _, override := range overrides {
+		overrideMap[override.Name] = override
+	}
+
+	templates := s.promptRegistry.List(language)
+	for index, tmpl := range templates {
+		if override, ok := overrideMap[tmpl.Name]; ok {
+			templates[index] = override.ToPromptTemplate()
+		}
+	}
+	return templates, nil
+}
+
+// GetUserPrompt 获取用户指定语言的提示词模板
+func (s *promptService) GetUserPrompt(ctx context.Context, userID, name, language string) (*agent.PromptTemplate, error) {
+	language = common.NormalizeLanguage(language)
+	builtin, err := s.promptRegistry.Get(name, language)
+	if err != nil {
+		return nil, err
+	}
+	override, err := s.repositories.UserPromptRepo.GetByUserIDAndName(ctx, userID, name, language)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户提示词模板失败: %w", err)
+	}
+	if override == nil {
+		return builtin, nil
+	}
+	return override.ToPromptTemplate(), nil
+}
+
+// SaveUserPrompt 保存用户覆盖的提示词模板
+func (s *promptService) SaveUserPrompt(ctx context.Context, userID, name, language, content string) (*agent.PromptTemplate, error) {
+	language = common.NormalizeLanguage(language)
+	builtin, err := s.promptRegistry.Get(name, language)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.promptRegistry.Validate(name, content); err != nil {
+		return nil, err
+	}
+
+	override, err := s.repositories.UserPromptRepo.GetByUserIDAndName(ctx, userID, name, language)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户提示词模板失败: %w", err)
+	}
+	if override == nil {
+		override = &models.UserPrompt{
+			UserID:      userID,
+			Name:        name,
+			Language:    language,
+			Version:     1,
+			BaseVersion: builtin.Version,
+			Content:     content,
+		}
+		if err := s.repositories.UserPromptRepo.Create(ctx, override); err != nil {
+			return nil, fmt.Errorf("保存用户提示词模板失败: %w", err)
+		}
+	} else {
+		override.Version++
+		override.BaseVersion = builtin.Version
+		override.Content = content
+		if err := s.repositories.UserPromptRepo.Update(ctx, override); err != nil {
+			return nil, fmt.Errorf("保存用户提示词模板失败: %w", err)
+		}
+	}
+
+	logger.Info("已保存用户提示词模板",
+		logger.String("userID", userID),
+		logger.String("name", name),
+		logger.String("language", language),
+		logger.Int("version", override.Version))
+	return override.ToPromptTemplate(), nil
+}
+
+// ResetUserPrompt 删除用户覆盖的提示词模板
+func (s *promptService) ResetUserPrompt(ctx context.Context, userID, name, language string) (*agent.PromptTemplate, error) {
+	language = common.NormalizeLanguage(language)
+	builtin, err := s.promptRegistry.Get(name, language)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repositories.UserPromptRepo.DeleteByUserIDAndName(ctx, userID, name, language); err != nil {
+		return nil, fmt.Errorf("删除用户提示词模板失败: %w", err)
+	}
+
+	logger.Info("已恢复内置提示词模板",
+		logger.String("userID", userID),
+		logger.String("name", name),
+		logger.String("language", language))
+	return builtin, nil
+}
+
+// GetOverride 获取项目生效的覆盖模板
+func (s *promptService) GetOverride(ctx context.Context, project *models.Project, name string) *agent.PromptTemplate {
+	override, err := s.resolveOverride(ctx, project, name)
+	if err != nil {
+		logger.Warn("获取覆盖的提示词模板失败，使用内置模板",
+			logger.String("projectGuid", project.GUID),
+			logger.String("name", name),
+			logger.String("error", err.Error()))
+		return nil
+	}
+	return override
+}
+
+// resolveOverride 按 项目 → 用户 的顺序查找覆盖的模板，都未覆盖时返回 nil
+func (s *promptService) resolveOverride(ctx context.Context, project *models.Project, name string) (*agent.PromptTemplate, error) {
+	override, err := s.repositories.PromptRepo.GetByProjectIDAndName(ctx, project.ID, name)
+	if err != nil {
+		return nil, fmt.Errorf("获取项目提示词模板失败: %w", err)
+	}
+	if override != nil {
+		return override.ToPromptTemplate(), nil
+	}
+	if project.UserID == "" {
+		return nil, nil
+	}
+
+	userOverride, err := s.repositories.UserPromptRepo.GetByUserIDAndName(ctx, project.UserID, name, common.NormalizeLanguage(project.Language))
+	if err != nil {
+		return nil, fmt.Errorf("获取用户提示词模板失败: %w", err)
+	}
+	if userOverride == nil {
+		return nil, nil
+	}
+	return userOverride.ToPromptTemplate(), nil
+}
+
+// Render 用项目的提示词模板渲染提示词
+func (s *promptService) Render(ctx context.Context, project *models.Project, name string, data interface{}) (*agent.RenderedPrompt, error) {
+	return s.promptRegistry.Render(name, project.Language, s.GetOverride(ctx, project, name), data)
+}
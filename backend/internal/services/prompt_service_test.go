@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lighthought/app-maker/shared-models/common"
+	"github.com/lighthought/app-maker/shared-models/prompt"
+
+	"github.com/lighthought/app-maker/backend/internal/models"
+	"github.com/lighthought/app-maker/backend/internal/repositories"
+)
+
+type fakePromptRepo struct {
+	repositories.PromptRepository
+	prompts map[string]*models.ProjectPrompt // key 为 projectID/name
+}
+
+func (r *fakePromptRepo) GetByProjectIDAndName(ctx context.Context, projectID, name string) (*models.ProjectPrompt, error) {
+	return r.prompts[projectID+"/"+name], nil
+}
+
+func (r *fakePromptRepo) ListByProjectID(ctx context.Context, projectID string) ([]*models.ProjectPrompt, error) {
+	var list []*models.ProjectPrompt
+	for _, p := range r.prompts {
+		if p.ProjectID == projectID {
+			list = append(list, p)
+		}
+	}
+	return list, nil
+}
+
+type fakeUserPromptRepo struct {
+	repositories.UserPromptRepository
+	prompts map[string]*models.UserPrompt // key 为 userID/name/language
+}
+
+func (r *fakeUserPromptRepo) GetByUserIDAndName(ctx context.Context, userID, name, language string) (*models.UserPrompt, error) {
+	return r.prompts[userID+"/"+name+"/"+language], nil
+}
+
+func (r *fakeUserPromptRepo) ListByUserID(ctx context.Context, userID, language string) ([]*models.UserPrompt, error) {
+	var list []*models.UserPrompt
+	for _, p := range r.prompts {
+		if p.UserID == userID && p.Language == language {
+			list = append(list, p)
+		}
+	}
+	return list, nil
+}
+
+func TestPromptServiceOverrideOrder(t *testing.T) {
+	const name = "dev_fix_bug"
+	repos := &repositories.Repository{
+		PromptRepo: &fakePromptRepo{prompts: map[string]*models.ProjectPrompt{
+			"P1/" + name: {ProjectID: "P1", Name: name, Version: 3, Content: "project"},
+		}},
+		UserPromptRepo: &fakeUserPromptRepo{prompts: map[string]*models.UserPrompt{
+			"U1/" + name + "/zh-CN": {UserID: "U1", Name: name, Language: "zh-CN", Version: 2, Content: "user zh"},
+		}},
+	}
+	service := NewPromptService(repos, prompt.NewRegistry(""))
+
+	tests := []struct {
+		name       string
+		project    *models.Project
+		wantSource string
+		wantText   string
+	}{
+		{name: "project override wins", project: &models.Project{ID: "P1", UserID: "U1", Language: "zh-CN"}, wantSource: common.PromptSourceProject, wantText: "project"},
+		{name: "user override", project: &models.Project{ID: "P2", UserID: "U1", Language: "zh-CN"}, wantSource: common.PromptSourceUser, wantText: "user zh"},
+		{name: "user override of another language", project: &models.Project{ID: "P3", UserID: "U1", Language: "en-US"}, wantSource: common.PromptSourceBuiltin},
+		{name: "builtin", project: &models.Project{ID: "P4", UserID: "U2", Language: "zh-CN"}, wantSource: common.PromptSourceBuiltin},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := service.GetProjectPrompt(context.Background(), tt.project, name)
+			if err != nil {
+				t.Fatalf("GetProjectPrompt() err = %v", err)
+			}
+			if tmpl.Source != tt.wantSource || (tt.wantText != "" && tmpl.Content != tt.wantText) {
+				t.Errorf("GetProjectPrompt() = %+v", tmpl)
+			}
+
+			override := service.GetOverride(context.Background(), tt.project, name)
+			if (override != nil) != (tt.wantSource != common.PromptSourceBuiltin) {
+				t.Errorf("GetOverride() = %+v", override)
+			}
+
+			templates, err := service.ListProjectPrompts(context.Background(), tt.project)
+			if err != nil {
+				t.Fatalf("ListProjectPrompts() err = %v", err)
+			}
+			for _, listed := range templates {
+				if listed.Name == name && listed.Source != tt.wantSource {
+					t.Errorf("ListProjectPrompts() %s source = %s, want %s", name, listed.Source, tt.wantSource)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lighthought/app-maker/shared-models/common"
+	"github.com/lighthought/app-maker/shared-models/logger"
+	"github.com/lighthought/app-maker/shared-models/tasks"
+
+	"github.com/lighthought/app-maker/backend/internal/models"
+	"github.com/lighthought/app-maker/backend/internal/repositories"
+)
+
+// QA 评审接口返回的最近评审数
+const projectQAReviewLimit = 100
+
+// ErrQAGateFailed 有故事最新的 QA 评审结论为 fail，不允许部署
+var ErrQAGateFailed = errors.New("QA 评审未通过，不允许部署")
+
+// QAReviewService 用户故事 QA 评审服务接口
+type QAReviewService interface {
+	// 记录开发阶段 Agent 任务返回的门禁结论，任务结果没有门禁结论时忽略，同一任务只记录一次
+	RecordStageReview(ctx context.Context, project *models.Project, stage *models.DevStage,
+		agentTaskID string, result *tasks.TaskResult) error
+
+	// 获取项目的 QA 评审，按时间倒序
+	ListQAReviews(ctx context.Context, project *models.Project) ([]*models.QAReview, error)
+
+	// 获取项目每个故事最新的 QA 评审
+	ListLatestReviews(ctx context.Context, project *models.Project) ([]*models.QAReview, error)
+
+	// 检查项目是否允许部署，有故事最新的评审结论为 fail 时返回 ErrQAGateFailed
+	CheckDeployable(ctx context.Context, project *models.Project) error
+}
+
+// qaReviewService 用户故事 QA 评审服务实现
+type qaReviewService struct {
+	repositories *repositories.Repository
+}
+
+// NewQAReviewService 创建用户故事 QA 评审服务
+func NewQAReviewService(repositories *repositories.Repository) QAReviewService {
+	return &qaReviewService{repositories: repositories}
+}
+
+// RecordStageReview 记录开发阶段 Agent 任务返回的门禁结论
+func (s *qaReviewService) RecordStageReview(ctx context.Context, project *models.Project, stage *models.DevStage,
+	agentTaskID string, result *tasks.TaskResult) error {
+	if result == nil || result.QAGate == nil {
+		return nil
+	}
+
+	review := &models.QAReview{
+		ProjectID:   project.ID,
+		ProjectGuid: project.GUID,
+		AgentTaskID: agentTaskID,
+		Summary:     result.Message,
+	}
+	if stage != nil {
+		review.DevStageID = stage.ID
+	}
+	review.CopyFromAgentGate(result.QAGate)
+
+	if err := s.repositories.QAReviewRepo.Create(ctx, review); err != nil {
+		return fmt.Errorf("保存 QA 评审失败: %w", err)
+	}
+
+	logger.Info("已记录 QA 评审",
+		logger.String("taskID", agentTaskID),
+		logger.String("projectGuid", project.GUID),
+		logger.String("storyNumber", review.StoryNumber),
+		logger.String("gate", review.Gate))
+	return nil
+}
+
+// ListQAReviews 获取项目的 QA 评审
+func (s *qaReviewService) ListQAReviews(ctx context.Context, project *models.Project) ([]*models.QAReview, error) {
+	reviews, err := s.repositories.QAReviewRepo.ListByProjectGuid(ctx, project.GUID, projectQAReviewLimit)
+	if err != nil {
+		return nil, fmt.Errorf("获取 QA 评审失败: %w", err)
+	}
+	return reviews, nil
+}
+
+// ListLatestReviews 获取项目每个故事最新的 QA 评审
+func (s *qaReviewService) ListLatestReviews(ctx context.Context, project *models.Project) ([]*models.QAReview, error) {
+	reviews, err := s.repositories.QAReviewRepo.ListLatestByProjectGuid(ctx, project.GUID)
+	if err != nil {
+		return nil, fmt.Errorf("获取 QA 评审失败: %w", err)
+	}
+	return reviews, nil
+}
+
+// CheckDeployable 检查项目是否允许部署，故事重新评审通过后不再阻止
+func (s *qaReviewService) CheckDeployable(ctx context.Context, project *models.Project) error {
+	reviews, err := s.ListLatestReviews(ctx, project)
+	if err != nil {
+		return err
+	}
+	if failed := failedQAStories(reviews); len(failed) > 0 {
+		return fmt.Errorf("%w: %s", ErrQAGateFailed, strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// failedQAStories 评审结论为 fail 的故事编号
+func failedQAStories(reviews []*models.QAReview) []string {
+	var failed []string
+	for _, review := range reviews {
+		if review.Gate == common.QAGateFail {
+			failed = append(failed, review.StoryNumber)
+		}
+	}
+	return failed
+}
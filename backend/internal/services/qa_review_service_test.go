@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/common"
+	"github.com/lighthought/app-maker/shared-models/tasks"
+
+	"github.com/lighthought/app-maker/backend/internal/models"
+	"github.com/lighthought/app-maker/backend/internal/repositories"
+)
+
+type fakeQAReviewRepo struct {
+	repositories.QAReviewRepository
+	reviews []*models.QAReview
+}
+
+func (r *fakeQAReviewRepo) Create(ctx context.Context, review *models.QAReview) error {
+	r.reviews = append(r.reviews, review)
+	return nil
+}
+
+// ListLatestByProjectGuid 后记录的评审覆盖同一故事先前的评审
+func (r *fakeQAReviewRepo) ListLatestByProjectGuid(ctx context.Context, projectGuid string) ([]*models.QAReview, error) {
+	var latest []*models.QAReview
+	index := make(map[string]int)
+	for _, review := range r.reviews {
+		if review.ProjectGuid != projectGuid {
+			continue
+		}
+		if i, ok := index[review.StoryNumber]; ok {
+			latest[i] = review
+			continue
+		}
+		index[review.StoryNumber] = len(latest)
+		latest = append(latest, review)
+	}
+	return latest, nil
+}
+
+func TestQAReviewServiceRecordStageReview(t *testing.T) {
+	project := &models.Project{ID: "PROJ-1", GUID: "p1"}
+	stage := &models.DevStage{ID: "STAGE-1", Name: string(common.DevStatusQAReview)}
+	repo := &fakeQAReviewRepo{}
+	service := NewQAReviewService(&repositories.Repository{QAReviewRepo: repo})
+
+	// 没有门禁结论的任务结果不记录
+	if err := service.RecordStageReview(context.Background(), project, stage, "task-0", &tasks.TaskResult{Message: "done"}); err != nil {
+		t.Fatalf("RecordStageReview() err = %v", err)
+	}
+	if len(repo.reviews) != 0 {
+		t.Fatalf("reviews = %+v, want none", repo.reviews)
+	}
+
+	result := &tasks.TaskResult{
+		Message: "reviewed",
+		QAGate: &agent.QAGate{
+			StoryNumber: "1.2", Gate: common.QAGateFail, StatusReason: "AC 3 not implemented",
+			TopIssues: []string{"missing validation"}, GateFile: "docs/qa/gates/1.2.yml",
+		},
+	}
+	if err := service.RecordStageReview(context.Background(), project, stage, "task-1", result); err != nil {
+		t.Fatalf("RecordStageReview() err = %v", err)
+	}
+	if len(repo.reviews) != 1 {
+		t.Fatalf("reviews = %+v", repo.reviews)
+	}
+	review := repo.reviews[0]
+	if review.ProjectID != "PROJ-1" || review.DevStageID != "STAGE-1" || review.AgentTaskID != "task-1" ||
+		review.StoryNumber != "1.2" || review.Gate != common.QAGateFail || review.Summary != "reviewed" ||
+		len(review.TopIssues) != 1 || review.GateFile != "docs/qa/gates/1.2.yml" {
+		t.Errorf("review = %+v", review)
+	}
+}
+
+func TestQAReviewServiceCheckDeployable(t *testing.T) {
+	project := &models.Project{ID: "PROJ-1", GUID: "p1"}
+	repo := &fakeQAReviewRepo{reviews: []*models.QAReview{
+		{ProjectGuid: "p1", StoryNumber: "1.1", Gate: common.QAGatePass},
+		{ProjectGuid: "p1", StoryNumber: "1.2", Gate: common.QAGateFail},
+		{ProjectGuid: "p1", StoryNumber: "1.3", Gate: common.QAGateConcerns},
+	}}
+	service := NewQAReviewService(&repositories.Repository{QAReviewRepo: repo})
+
+	err := service.CheckDeployable(context.Background(), project)
+	if !errors.Is(err, ErrQAGateFailed) {
+		t.Fatalf("CheckDeployable() err = %v, want ErrQAGateFailed", err)
+	}
+
+	// 重新评审通过后允许部署
+	repo.reviews = append(repo.reviews, &models.QAReview{ProjectGuid: "p1", StoryNumber: "1.2", Gate: common.QAGatePass})
+	if err := service.CheckDeployable(context.Background(), project); err != nil {
+		t.Errorf("CheckDeployable() err = %v", err)
+	}
+}
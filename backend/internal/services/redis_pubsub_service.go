@@ -4,10 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/lighthought/app-maker/backend/internal/config"
 	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/cache"
 	"github.com/lighthought/app-maker/shared-models/common"
 	"github.com/lighthought/app-maker/shared-models/logger"
 
@@ -22,27 +24,31 @@ type RedisPubSubService interface {
 	Stop() error
 	// 处理 Agent 任务状态消息
 	HandleAgentTaskStatus(ctx context.Context, message *agent.AgentTaskStatusMessage) error
+	// 处理 Agent CLI 输出日志消息
+	HandleAgentLog(ctx context.Context, message *agent.AgentLogMessage) error
 }
 
 // redisPubSubService Redis Pub/Sub 服务实现
 type redisPubSubService struct {
-	redisClient  *redis.Client
-	asyncService AsyncClientService
-	pubsub       *redis.PubSub
-	stopChan     chan struct{}
+	redisClient      *redis.Client
+	asyncService     AsyncClientService
+	webSocketService WebSocketService
+	pubsub           *redis.PubSub
+	stopChan         chan struct{}
 }
 
 // NewRedisPubSubService 创建 Redis Pub/Sub 服务
-func NewRedisPubSubService(asyncService AsyncClientService, cfg *config.Config) RedisPubSubService {
+func NewRedisPubSubService(asyncService AsyncClientService, webSocketService WebSocketService, cfg *config.Config) RedisPubSubService {
 	redisClient := redis.NewClient(&redis.Options{
 		Addr:     fmt.Sprintf("%s:%d", cfg.Redis.Host, cfg.Redis.Port),
 		Password: cfg.Redis.Password,
 		DB:       common.CacheDbDatabase,
 	})
 	return &redisPubSubService{
-		redisClient:  redisClient,
-		asyncService: asyncService,
-		stopChan:     make(chan struct{}),
+		redisClient:      redisClient,
+		asyncService:     asyncService,
+		webSocketService: webSocketService,
+		stopChan:         make(chan struct{}),
 	}
 }
 
@@ -55,11 +61,18 @@ func (s *redisPubSubService) Start(ctx context.Context) error {
 	// 订阅 Agent 任务状态频道
 	s.pubsub = s.redisClient.Subscribe(ctx, common.RedisPubSubChannelAgentTask)
 
+	// 按模式订阅所有 Agent 任务日志频道
+	logPattern := cache.GetAgentTaskLogChannel("*")
+	if err := s.pubsub.PSubscribe(ctx, logPattern); err != nil {
+		return fmt.Errorf("failed to subscribe agent log channel: %s", err.Error())
+	}
+
 	// 启动消息处理协程
 	go s.messageHandler(ctx)
 
 	logger.Info("Redis Pub/Sub 服务已启动",
-		logger.String("channel", common.RedisPubSubChannelAgentTask))
+		logger.String("channel", common.RedisPubSubChannelAgentTask),
+		logger.String("logPattern", logPattern))
 
 	return nil
 }
@@ -103,7 +116,7 @@ func (s *redisPubSubService) messageHandler(ctx context.Context) {
 			}
 
 			// 处理消息
-			if err := s.processMessage(ctx, msg.Payload); err != nil {
+			if err := s.processMessage(ctx, msg.Channel, msg.Payload); err != nil {
 				logger.Error("处理 Redis Pub/Sub 消息失败",
 					logger.String("payload", msg.Payload),
 					logger.String("error", err.Error()))
@@ -113,7 +126,16 @@ func (s *redisPubSubService) messageHandler(ctx context.Context) {
 }
 
 // processMessage 处理接收到的消息
-func (s *redisPubSubService) processMessage(ctx context.Context, payload string) error {
+func (s *redisPubSubService) processMessage(ctx context.Context, channel, payload string) error {
+	// Agent CLI 输出日志
+	if strings.HasPrefix(channel, common.RedisPubSubChannelAgentLog+":") {
+		var logMsg agent.AgentLogMessage
+		if err := json.Unmarshal([]byte(payload), &logMsg); err != nil {
+			return fmt.Errorf("failed to deserialize agent log message: %s", err.Error())
+		}
+		return s.HandleAgentLog(ctx, &logMsg)
+	}
+
 	var statusMsg agent.AgentTaskStatusMessage
 	if err := json.Unmarshal([]byte(payload), &statusMsg); err != nil {
 		return fmt.Errorf("failed to deserialize task status message: %s", err.Error())
@@ -170,6 +192,14 @@ func (s *redisPubSubService) HandleAgentTaskStatus(ctx context.Context, message
 		}
 		return nil
 
+	case common.CommonStatusCancelled:
+		// 取消由后端发起，阶段和项目状态已在取消时更新
+		logger.Info("Agent 任务已取消",
+			logger.String("taskID", message.TaskID),
+			logger.String("projectGuid", message.ProjectGuid),
+			logger.String("agentType", message.AgentType))
+		return nil
+
 	default:
 		logger.Warn("未知的 Agent 任务状态",
 			logger.String("taskID", message.TaskID),
@@ -178,3 +208,16 @@ func (s *redisPubSubService) HandleAgentTaskStatus(ctx context.Context, message
 
 	return nil
 }
+
+// HandleAgentLog 处理 Agent CLI 输出日志消息，直接转发到项目的 WebSocket 客户端
+func (s *redisPubSubService) HandleAgentLog(ctx context.Context, message *agent.AgentLogMessage) error {
+	if message == nil {
+		return fmt.Errorf("message is nil")
+	}
+	if s.webSocketService == nil {
+		return fmt.Errorf("websocket service is nil")
+	}
+
+	s.webSocketService.NotifyAgentLog(ctx, message.ProjectGuid, message)
+	return nil
+}
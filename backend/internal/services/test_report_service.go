@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lighthought/app-maker/shared-models/logger"
+	"github.com/lighthought/app-maker/shared-models/tasks"
+
+	"github.com/lighthought/app-maker/backend/internal/models"
+	"github.com/lighthought/app-maker/backend/internal/repositories"
+)
+
+// 测试报告接口返回的最近报告数
+const projectTestReportLimit = 100
+
+// TestReportService 项目测试报告服务接口
+type TestReportService interface {
+	// 记录开发阶段 Agent 任务返回的测试报告，任务结果没有测试报告时忽略，同一任务只记录一次
+	RecordStageReport(ctx context.Context, project *models.Project, stage *models.DevStage,
+		agentTaskID string, result *tasks.TaskResult) error
+
+	// 获取项目的测试报告，devStage 为空时返回所有阶段
+	ListTestReports(ctx context.Context, project *models.Project, devStage string) ([]*models.TestReport, error)
+}
+
+// testReportService 项目测试报告服务实现
+type testReportService struct {
+	repositories *repositories.Repository
+}
+
+// NewTestReportService 创建项目测试报告服务
+func NewTestReportService(repositories *repositories.Repository) TestReportService {
+	return &testReportService{repositories: repositories}
+}
+
+// RecordStageReport 记录开发阶段 Agent 任务返回的测试报告
+func (s *testReportService) RecordStageReport(ctx context.Context, project *models.Project, stage *models.DevStage,
+	agentTaskID string, result *tasks.TaskResult) error {
+	if result == nil || result.TestReport == nil {
+		return nil
+	}
+
+	report := &models.TestReport{
+		ProjectID:   project.ID,
+		ProjectGuid: project.GUID,
+		AgentTaskID: agentTaskID,
+		Summary:     result.Message,
+	}
+	if stage != nil {
+		report.DevStageID = stage.ID
+		report.DevStage = stage.Name
+	}
+	report.CopyFromAgentReport(result.TestReport)
+
+	if err := s.repositories.TestReportRepo.Create(ctx, report); err != nil {
+		return fmt.Errorf("保存测试报告失败: %w", err)
+	}
+
+	logger.Info("已记录测试报告",
+		logger.String("taskID", agentTaskID),
+		logger.String("projectGuid", project.GUID),
+		logger.String("status", report.Status),
+		logger.Int("total", report.Total),
+		logger.Int("failed", report.Failed))
+	return nil
+}
+
+// ListTestReports 获取项目的测试报告
+func (s *testReportService) ListTestReports(ctx context.Context, project *models.Project, devStage string) ([]*models.TestReport, error) {
+	reports, err := s.repositories.TestReportRepo.ListByProjectGuid(ctx, project.GUID, devStage, projectTestReportLimit)
+	if err != nil {
+		return nil, fmt.Errorf("获取测试报告失败: %w", err)
+	}
+	return reports, nil
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/common"
+	"github.com/lighthought/app-maker/shared-models/tasks"
+
+	"github.com/lighthought/app-maker/backend/internal/models"
+	"github.com/lighthought/app-maker/backend/internal/repositories"
+)
+
+type fakeTestReportRepo struct {
+	repositories.TestReportRepository
+	reports []*models.TestReport
+}
+
+func (r *fakeTestReportRepo) Create(ctx context.Context, report *models.TestReport) error {
+	r.reports = append(r.reports, report)
+	return nil
+}
+
+func TestTestReportServiceRecordStageReport(t *testing.T) {
+	project := &models.Project{ID: "PROJ-1", GUID: "p1"}
+	stage := &models.DevStage{ID: "STAGE-1", Name: string(common.DevStatusRunTest)}
+	repo := &fakeTestReportRepo{}
+	service := NewTestReportService(&repositories.Repository{TestReportRepo: repo})
+
+	// 没有测试报告的任务结果不记录
+	if err := service.RecordStageReport(context.Background(), project, stage, "task-0", &tasks.TaskResult{Message: "done"}); err != nil {
+		t.Fatalf("RecordStageReport() err = %v", err)
+	}
+	if len(repo.reports) != 0 {
+		t.Fatalf("reports = %+v, want none", repo.reports)
+	}
+
+	coverage := 62.5
+	result := &tasks.TaskResult{
+		Status:  common.CommonStatusFailed,
+		Message: "1 failed",
+		TestReport: &agent.TestReport{
+			Status: common.TestStatusFailed, Total: 3, Passed: 1, Failed: 1, Skipped: 1, DurationMs: 350, Coverage: &coverage,
+			Suites: []*agent.TestSuite{{Name: "demo/internal/handlers", Component: "backend", Tool: "go", Failed: 1}},
+		},
+	}
+	if err := service.RecordStageReport(context.Background(), project, stage, "task-1", result); err != nil {
+		t.Fatalf("RecordStageReport() err = %v", err)
+	}
+	if len(repo.reports) != 1 {
+		t.Fatalf("reports = %+v", repo.reports)
+	}
+	report := repo.reports[0]
+	if report.ProjectID != "PROJ-1" || report.DevStageID != "STAGE-1" || report.DevStage != "run_test" ||
+		report.AgentTaskID != "task-1" || report.Summary != "1 failed" {
+		t.Errorf("report = %+v", report)
+	}
+	if report.Status != common.TestStatusFailed || report.Total != 3 || report.Failed != 1 ||
+		report.Coverage == nil || *report.Coverage != coverage || len(report.Suites) != 1 {
+		t.Errorf("report counts = %+v", report)
+	}
+}
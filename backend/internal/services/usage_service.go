@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/common"
+	"github.com/lighthought/app-maker/shared-models/logger"
+
+	"github.com/lighthought/app-maker/backend/internal/models"
+	"github.com/lighthought/app-maker/backend/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+// 项目用量接口返回的最近任务数
+const projectUsageTaskLimit = 200
+
+// UsageService Agent 用量与预算服务接口
+type UsageService interface {
+	// 记录 Agent 任务上报的用量，同一任务只记录一次
+	RecordTaskUsage(ctx context.Context, message *agent.AgentTaskStatusMessage) error
+
+	// 获取项目用量，包含累计、按阶段汇总和每个任务的用量
+	GetProjectUsage(ctx context.Context, project *models.Project) (*models.ProjectUsageResponse, error)
+
+	// 获取用户本月用量和预算
+	GetUserUsage(ctx context.Context, userID string) (*models.UserUsageResponse, error)
+
+	// 检查用户本月预算，超出时返回 common.MESSAGE_INSUFFICIENT_QUOTA 错误
+	CheckBudget(ctx context.Context, userID string) error
+
+	// 设置用户月度预算，为空时恢复系统默认预算
+	SetUserBudget(ctx context.Context, userID string, budgetUsd *float64) error
+}
+
+// usageService 用量与预算服务实现
+type usageService struct {
+	repositories     *repositories.Repository
+	defaultBudgetUsd float64
+}
+
+// NewUsageService 创建用量与预算服务，defaultBudgetUsd 为用户未单独设置时的月度预算，0 表示不限制
+func NewUsageService(repositories *repositories.Repository, defaultBudgetUsd float64) UsageService {
+	return &usageService{
+		repositories:     repositories,
+		defaultBudgetUsd: defaultBudgetUsd,
+	}
+}
+
+// RecordTaskUsage 记录 Agent 任务上报的用量
+func (s *usageService) RecordTaskUsage(ctx context.Context, message *agent.AgentTaskStatusMessage) error {
+	if message == nil || message.Usage == nil {
+		return nil
+	}
+
+	project, err := s.repositories.ProjectRepo.GetByGUID(ctx, message.ProjectGuid)
+	if err != nil {
+		return fmt.Errorf("获取项目失败: %w", err)
+	}
+
+	usage := &models.AgentTaskUsage{
+		AgentTaskID: message.TaskID,
+		UserID:      project.UserID,
+		ProjectID:   project.ID,
+		ProjectGuid: project.GUID,
+		AgentType:   message.AgentType,
+		Status:      message.Status,
+	}
+	usage.CopyFromAgentUsage(message.Usage)
+
+	// 聊天任务没有开发阶段
+	if message.DevStage != "" && message.DevStage != string(common.DevStatusUnknown) {
+		usage.DevStage = message.DevStage
+		if stage, err := s.repositories.ProjectStageRepo.GetByProjectGuidAndName(ctx, message.ProjectGuid, message.DevStage); err == nil {
+			usage.DevStageID = stage.ID
+		}
+	}
+
+	if err := s.repositories.UsageRepo.Create(ctx, usage); err != nil {
+		return fmt.Errorf("保存 Agent 任务用量失败: %w", err)
+	}
+
+	logger.Info("已记录 Agent 任务用量",
+		logger.String("taskID", message.TaskID),
+		logger.String("projectGuid", message.ProjectGuid),
+		logger.String("devStage", message.DevStage),
+		logger.Int("inputTokens", usage.InputTokens),
+		logger.Int("outputTokens", usage.OutputTokens),
+		logger.String("costUsd", fmt.Sprintf("%.6f", usage.CostUsd)))
+	if usage.UsageUnknown {
+		logger.Warn("CLI 未输出 token 用量，本次任务不计入预算",
+			logger.String("taskID", message.TaskID),
+			logger.String("cliTool", usage.CliTool))
+	}
+	return nil
+}
+
+// GetProjectUsage 获取项目用量
+func (s *usageService) GetProjectUsage(ctx context.Context, project *models.Project) (*models.ProjectUsageResponse, error) {
+	total, err := s.repositories.UsageRepo.SumByProjectID(ctx, project.ID)
+	if err != nil {
+		return nil, fmt.Errorf("汇总项目用量失败: %w", err)
+	}
+
+	stages, err := s.repositories.UsageRepo.SumByProjectIDGroupByStage(ctx, project.ID)
+	if err != nil {
+		return nil, fmt.Errorf("按阶段汇总项目用量失败: %w", err)
+	}
+
+	taskUsages, err := s.repositories.UsageRepo.ListByProjectID(ctx, project.ID, projectUsageTaskLimit)
+	if err != nil {
+		return nil, fmt.Errorf("获取项目用量记录失败: %w", err)
+	}
+
+	return &models.ProjectUsageResponse{
+		ProjectGuid: project.GUID,
+		Total:       *total,
+		Stages:      stages,
+		Tasks:       taskUsages,
+	}, nil
+}
+
+// GetUserUsage 获取用户本月用量和预算
+func (s *usageService) GetUserUsage(ctx context.Context, userID string) (*models.UserUsageResponse, error) {
+	user, err := s.repositories.UserRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, errors.New(common.MESSAGE_USER_NOT_FOUND)
+	}
+
+	monthStart := getMonthStart(time.Now())
+	total, err := s.repositories.UsageRepo.SumByUserIDSince(ctx, userID, monthStart)
+	if err != nil {
+		return nil, fmt.Errorf("汇总用户用量失败: %w", err)
+	}
+
+	projects, err := s.repositories.UsageRepo.SumByUserIDSinceGroupByProject(ctx, userID, monthStart)
+	if err != nil {
+		return nil, fmt.Errorf("按项目汇总用户用量失败: %w", err)
+	}
+
+	budget := s.getUserBudget(user)
+	response := &models.UserUsageResponse{
+		UserID:    userID,
+		Month:     monthStart.Format("2006-01"),
+		Total:     *total,
+		BudgetUsd: budget,
+		Projects:  projects,
+	}
+	if budget > 0 {
+		response.Exceeded = total.CostUsd >= budget
+		if !response.Exceeded {
+			response.RemainingUsd = budget - total.CostUsd
+		}
+	}
+	return response, nil
+}
+
+// CheckBudget 检查用户本月预算
+func (s *usageService) CheckBudget(ctx context.Context, userID string) error {
+	user, err := s.repositories.UserRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New(common.MESSAGE_USER_NOT_FOUND)
+		}
+		return fmt.Errorf("获取用户失败: %w", err)
+	}
+
+	budget := s.getUserBudget(user)
+	if budget <= 0 {
+		return nil
+	}
+
+	total, err := s.repositories.UsageRepo.SumByUserIDSince(ctx, userID, getMonthStart(time.Now()))
+	if err != nil {
+		return fmt.Errorf("汇总用户用量失败: %w", err)
+	}
+
+	if total.CostUsd >= budget {
+		logger.Warn("用户本月用量已超出预算",
+			logger.String("userID", userID),
+			logger.String("budgetUsd", fmt.Sprintf("%.2f", budget)),
+			logger.String("costUsd", fmt.Sprintf("%.6f", total.CostUsd)))
+		return errors.New(common.MESSAGE_INSUFFICIENT_QUOTA)
+	}
+	return nil
+}
+
+// SetUserBudget 设置用户月度预算
+func (s *usageService) SetUserBudget(ctx context.Context, userID string, budgetUsd *float64) error {
+	user, err := s.repositories.UserRepo.GetByID(ctx, userID)
+	if err != nil {
+		return errors.New(common.MESSAGE_USER_NOT_FOUND)
+	}
+
+	user.MonthlyBudgetUsd = budgetUsd
+	return s.repositories.UserRepo.Update(ctx, user)
+}
+
+// getUserBudget 获取用户月度预算，用户未单独设置时使用系统默认预算
+func (s *usageService) getUserBudget(user *models.User) float64 {
+	if user.MonthlyBudgetUsd != nil {
+		return *user.MonthlyBudgetUsd
+	}
+	return s.defaultBudgetUsd
+}
+
+// getMonthStart 获取所在月份的第一天零点
+func getMonthStart(now time.Time) time.Time {
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+}
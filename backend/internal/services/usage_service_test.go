@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lighthought/app-maker/shared-models/common"
+
+	"github.com/lighthought/app-maker/backend/internal/models"
+	"github.com/lighthought/app-maker/backend/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+type fakeUserRepo struct {
+	repositories.UserRepository
+	user *models.User
+	err  error
+}
+
+func (r *fakeUserRepo) GetByID(ctx context.Context, id string) (*models.User, error) {
+	return r.user, r.err
+}
+
+type fakeUsageRepo struct {
+	repositories.UsageRepository
+	costUsd float64
+	err     error
+}
+
+func (r *fakeUsageRepo) SumByUserIDSince(ctx context.Context, userID string, since time.Time) (*models.UsageSummary, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &models.UsageSummary{CostUsd: r.costUsd}, nil
+}
+
+func TestCheckBudget(t *testing.T) {
+	budget := func(v float64) *float64 { return &v }
+	dbErr := errors.New("connection refused")
+
+	tests := []struct {
+		name          string
+		defaultBudget float64
+		user          *models.User
+		userErr       error
+		costUsd       float64
+		sumErr        error
+		wantErr       string
+		wantQuota     bool
+	}{
+		{name: "unlimited", user: &models.User{}, costUsd: 100},
+		{name: "under default budget", defaultBudget: 10, user: &models.User{}, costUsd: 9.99},
+		{name: "default budget reached", defaultBudget: 10, user: &models.User{}, costUsd: 10, wantQuota: true},
+		{name: "user budget overrides default", defaultBudget: 10, user: &models.User{MonthlyBudgetUsd: budget(20)}, costUsd: 15},
+		{name: "user budget zero means unlimited", defaultBudget: 10, user: &models.User{MonthlyBudgetUsd: budget(0)}, costUsd: 15},
+		{name: "user budget exceeded", user: &models.User{MonthlyBudgetUsd: budget(5)}, costUsd: 6, wantQuota: true},
+		{name: "user not found", userErr: gorm.ErrRecordNotFound, wantErr: common.MESSAGE_USER_NOT_FOUND},
+		{name: "user lookup failed", userErr: dbErr, wantErr: "获取用户失败: connection refused"},
+		{name: "usage sum failed", defaultBudget: 10, user: &models.User{}, sumErr: dbErr, wantErr: "汇总用户用量失败: connection refused"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewUsageService(&repositories.Repository{
+				UserRepo:  &fakeUserRepo{user: tt.user, err: tt.userErr},
+				UsageRepo: &fakeUsageRepo{costUsd: tt.costUsd, err: tt.sumErr},
+			}, tt.defaultBudget)
+
+			err := service.CheckBudget(context.Background(), "USER-1")
+			switch {
+			case tt.wantQuota:
+				if err == nil || err.Error() != common.MESSAGE_INSUFFICIENT_QUOTA {
+					t.Errorf("CheckBudget() error = %v, want %s", err, common.MESSAGE_INSUFFICIENT_QUOTA)
+				}
+			case tt.wantErr != "":
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("CheckBudget() error = %v, want %s", err, tt.wantErr)
+				}
+			default:
+				if err != nil {
+					t.Errorf("CheckBudget() error = %v, want nil", err)
+				}
+			}
+		})
+	}
+}
@@ -325,6 +325,7 @@ func (s *userService) GetUserSettings(ctx context.Context, userID string) (*mode
 		DefaultModelProvider: user.DefaultModelProvider,
 		DefaultModelApiUrl:   user.DefaultModelApiUrl,
 		DefaultApiToken:      user.DefaultApiToken,
+		DefaultLanguage:      common.NormalizeLanguage(user.DefaultLanguage),
 		AutoGoNext:           user.AutoGoNext,
 	}, nil
 }
@@ -352,6 +353,9 @@ func (s *userService) UpdateUserSettings(ctx context.Context, userID string, req
 	if req.DefaultApiToken != "" {
 		user.DefaultApiToken = req.DefaultApiToken
 	}
+	if req.DefaultLanguage != "" {
+		user.DefaultLanguage = req.DefaultLanguage
+	}
 	if req.AutoGoNext != nil {
 		user.AutoGoNext = *req.AutoGoNext
 	}
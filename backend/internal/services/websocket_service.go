@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/lighthought/app-maker/shared-models/agent"
 	"github.com/lighthought/app-maker/shared-models/common"
 
 	"github.com/lighthought/app-maker/shared-models/logger"
@@ -28,7 +29,8 @@ type WebSocketService interface {
 	NotifyProjectStageUpdate(ctx context.Context, projectGUID string, stage *models.DevStage)
 	NotifyProjectMessage(ctx context.Context, projectGUID string, message *models.ConversationMessage)
 	NotifyProjectInfoUpdate(ctx context.Context, projectGUID string, info *models.Project)
-	NotifyUserConfirmRequired(ctx context.Context, projectGUID string, stage common.DevStatus, message string)
+	NotifyUserConfirmRequired(ctx context.Context, projectGUID, language string, stage common.DevStatus, message string)
+	NotifyAgentLog(ctx context.Context, projectGUID string, log *agent.AgentLogMessage)
 
 	// 启动和停止
 	Start(ctx context.Context) error
@@ -258,14 +260,15 @@ func (s *webSocketService) NotifyProjectInfoUpdate(ctx context.Context, projectG
 }
 
 // NotifyUserConfirmRequired 通知用户确认需求
-func (s *webSocketService) NotifyUserConfirmRequired(ctx context.Context, projectGUID string, stage common.DevStatus, message string) {
+func (s *webSocketService) NotifyUserConfirmRequired(ctx context.Context, projectGUID, language string, stage common.DevStatus, message string) {
+	stageDescription := common.GetDevStageDescription(stage, language)
 	// 创建用户确认需求的消息
 	agentMessage := &models.ConversationMessage{
 		ProjectGuid:     projectGUID,
 		Type:            common.ConversationTypeAgent,
 		AgentRole:       common.AgentPM.Role,
 		AgentName:       common.AgentPM.Name,
-		Content:         fmt.Sprintf("%s，需要您的确认", common.GetDevStageDescription(stage)),
+		Content:         fmt.Sprintf(getProjectMessage(language, messageKeyConfirmRequired), stageDescription),
 		IsMarkdown:      true,
 		MarkdownContent: message,
 		IsExpanded:      true,
@@ -282,10 +285,33 @@ func (s *webSocketService) NotifyUserConfirmRequired(ctx context.Context, projec
 	logger.Info("用户确认需求通知已发送",
 		logger.String("projectGUID", projectGUID),
 		logger.String("stage", string(stage)),
-		logger.String("stageDescription", common.GetDevStageDescription(stage)),
+		logger.String("stageDescription", stageDescription),
 	)
 }
 
+// NotifyAgentLog 通知 Agent CLI 输出日志
+// 日志行数量大、无需落库，直接广播，不经过异步任务
+func (s *webSocketService) NotifyAgentLog(ctx context.Context, projectGUID string, log *agent.AgentLogMessage) {
+	if projectGUID == "" || log == nil {
+		return
+	}
+
+	message := &models.WebSocketMessage{
+		Type:        common.WebSocketMessageTypeAgentLog,
+		ProjectGUID: projectGUID,
+		Data:        log,
+		Timestamp:   utils.GetCurrentTime(),
+		ID:          fmt.Sprintf("%s_%d", log.TaskID, log.Seq),
+	}
+	select {
+	case s.hub.Broadcast <- message:
+	default:
+		logger.Warn("[ws] 广播队列已满，丢弃 Agent 日志",
+			logger.String("projectGUID", projectGUID),
+			logger.String("taskID", log.TaskID))
+	}
+}
+
 // Start 启动 WebSocket 服务
 func (s *webSocketService) Start(ctx context.Context) error {
 	logger.Info("WebSocket 服务启动中...")
@@ -71,7 +71,9 @@ CREATE TABLE IF NOT EXISTS users (
     default_model_provider VARCHAR(50) DEFAULT 'zhipu',
     default_model_api_url VARCHAR(500),
     default_api_token VARCHAR(500),
+    default_language VARCHAR(20) DEFAULT 'zh-CN',
     auto_go_next BOOLEAN NOT NULL DEFAULT TRUE,
+    monthly_budget_usd NUMERIC(12,4),
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     deleted_at TIMESTAMP
@@ -115,9 +117,12 @@ CREATE TABLE IF NOT EXISTS projects (
     model_provider VARCHAR(50),
     model_api_url VARCHAR(500),
     api_token VARCHAR(500),
+    language VARCHAR(20) DEFAULT 'zh-CN',
     waiting_for_user_confirm BOOLEAN NOT NULL DEFAULT FALSE,
     confirm_stage VARCHAR(50) DEFAULT NULL,
     auto_go_next BOOLEAN NOT NULL DEFAULT TRUE,
+    code_review_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    review_blocking_severities TEXT,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     deleted_at TIMESTAMP
@@ -168,6 +173,9 @@ CREATE TABLE IF NOT EXISTS dev_stages (
     description TEXT,
     failed_reason TEXT,
     task_id VARCHAR(50),
+    agent_task_id TEXT, -- 开发故事阶段为逗号分隔的多个任务ID
+    pre_commit_sha VARCHAR(64), -- 阶段执行前的提交，用于回滚
+    post_commit_sha VARCHAR(64), -- 阶段执行完成后的提交
     started_at TIMESTAMP,
     completed_at TIMESTAMP,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
@@ -250,6 +258,190 @@ CREATE TABLE IF NOT EXISTS epic_stories (
     UNIQUE(epic_id, story_number)
 );
 
+-- 创建 Agent 任务用量ID序列
+CREATE SEQUENCE IF NOT EXISTS public.agent_task_usages_id_num_seq
+    INCREMENT BY 1            -- 步长
+    START 1                   -- 起始值    
+    MINVALUE 1
+    MAXVALUE 99999999999      -- 11位数字容量
+    CACHE 1;
+
+-- 创建 Agent 任务用量表
+CREATE TABLE IF NOT EXISTS agent_task_usages (
+    id VARCHAR(50) PRIMARY KEY DEFAULT public.generate_table_id('USAGE', 'public.agent_task_usages_id_num_seq'),
+    agent_task_id VARCHAR(50) UNIQUE NOT NULL,
+    user_id VARCHAR(50) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    project_id VARCHAR(50) NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    project_guid VARCHAR(50),
+    dev_stage_id VARCHAR(50),
+    dev_stage VARCHAR(100),
+    agent_type VARCHAR(50),
+    cli_tool VARCHAR(50),
+    status VARCHAR(20),
+    input_tokens INTEGER NOT NULL DEFAULT 0,
+    output_tokens INTEGER NOT NULL DEFAULT 0,
+    cache_creation_input_tokens INTEGER NOT NULL DEFAULT 0,
+    cache_read_input_tokens INTEGER NOT NULL DEFAULT 0,
+    cost_usd NUMERIC(12,6) NOT NULL DEFAULT 0,
+    duration_ms INTEGER NOT NULL DEFAULT 0,
+    usage_unknown BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 创建项目提示词模板ID序列
+CREATE SEQUENCE IF NOT EXISTS public.project_prompts_id_num_seq
+    INCREMENT BY 1            -- 步长
+    START 1                   -- 起始值    
+    MINVALUE 1
+    MAXVALUE 99999999999      -- 11位数字容量
+    CACHE 1;
+
+-- 创建项目提示词模板表，保存项目覆盖的提示词模板
+CREATE TABLE IF NOT EXISTS project_prompts (
+    id VARCHAR(50) PRIMARY KEY DEFAULT public.generate_table_id('PROMPT', 'public.project_prompts_id_num_seq'),
+    project_id VARCHAR(50) NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    base_version INTEGER NOT NULL DEFAULT 1,
+    content TEXT NOT NULL,
+    updated_by VARCHAR(50),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT idx_project_prompts_project_id_name UNIQUE (project_id, name)
+);
+
+-- 创建用户提示词模板ID序列
+CREATE SEQUENCE IF NOT EXISTS public.user_prompts_id_num_seq
+    INCREMENT BY 1            -- 步长
+    START 1                   -- 起始值    
+    MINVALUE 1
+    MAXVALUE 99999999999      -- 11位数字容量
+    CACHE 1;
+
+-- 创建用户提示词模板表，保存用户覆盖的提示词模板，对该用户所有同语言的项目生效
+CREATE TABLE IF NOT EXISTS user_prompts (
+    id VARCHAR(50) PRIMARY KEY DEFAULT public.generate_table_id('UPROMPT', 'public.user_prompts_id_num_seq'),
+    user_id VARCHAR(50) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    language VARCHAR(20) NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    base_version INTEGER NOT NULL DEFAULT 1,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT idx_user_prompts_user_id_name_language UNIQUE (user_id, name, language)
+);
+
+-- 创建部署记录ID序列
+CREATE SEQUENCE IF NOT EXISTS public.deployments_id_num_seq
+    INCREMENT BY 1            -- 步长
+    START 1                   -- 起始值    
+    MINVALUE 1
+    MAXVALUE 99999999999      -- 11位数字容量
+    CACHE 1;
+
+-- 创建部署记录表，每次部署（包括重新部署历史提交）一条
+CREATE TABLE IF NOT EXISTS deployments (
+    id VARCHAR(50) PRIMARY KEY DEFAULT public.generate_table_id('DEPLOY', 'public.deployments_id_num_seq'),
+    project_id VARCHAR(50) NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    project_guid VARCHAR(50),
+    user_id VARCHAR(50),
+    environment VARCHAR(20) NOT NULL DEFAULT 'dev',
+    commit_sha VARCHAR(64),
+    source_deployment_id VARCHAR(50),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    task_id VARCHAR(50),
+    agent_task_id VARCHAR(50),
+    preview_url VARCHAR(500),
+    components TEXT,
+    logs TEXT,
+    error_message TEXT,
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 创建测试报告ID序列
+CREATE SEQUENCE IF NOT EXISTS public.test_reports_id_num_seq
+    INCREMENT BY 1            -- 步长
+    START 1                   -- 起始值    
+    MINVALUE 1
+    MAXVALUE 99999999999      -- 11位数字容量
+    CACHE 1;
+
+-- 创建测试报告表，每个测试 Agent 任务一条
+CREATE TABLE IF NOT EXISTS test_reports (
+    id VARCHAR(50) PRIMARY KEY DEFAULT public.generate_table_id('TREPORT', 'public.test_reports_id_num_seq'),
+    project_id VARCHAR(50) NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    project_guid VARCHAR(50),
+    dev_stage_id VARCHAR(50),
+    dev_stage VARCHAR(100),
+    agent_task_id VARCHAR(50) NOT NULL UNIQUE,
+    status VARCHAR(20),
+    total INTEGER NOT NULL DEFAULT 0,
+    passed INTEGER NOT NULL DEFAULT 0,
+    failed INTEGER NOT NULL DEFAULT 0,
+    skipped INTEGER NOT NULL DEFAULT 0,
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    coverage NUMERIC(5,2),
+    suites TEXT,
+    summary TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 创建 QA 评审ID序列
+CREATE SEQUENCE IF NOT EXISTS public.qa_reviews_id_num_seq
+    INCREMENT BY 1            -- 步长
+    START 1                   -- 起始值    
+    MINVALUE 1
+    MAXVALUE 99999999999      -- 11位数字容量
+    CACHE 1;
+
+-- 创建 QA 评审表，每个评审故事的 QA Agent 任务一条，故事最新的 fail 结论阻止部署
+CREATE TABLE IF NOT EXISTS qa_reviews (
+    id VARCHAR(50) PRIMARY KEY DEFAULT public.generate_table_id('QAREV', 'public.qa_reviews_id_num_seq'),
+    project_id VARCHAR(50) NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    project_guid VARCHAR(50),
+    dev_stage_id VARCHAR(50),
+    story_number VARCHAR(100) NOT NULL,
+    agent_task_id VARCHAR(50) NOT NULL UNIQUE,
+    gate VARCHAR(20) NOT NULL,
+    status_reason TEXT,
+    top_issues TEXT,
+    gate_file VARCHAR(500),
+    summary TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 创建代码评审意见ID序列
+CREATE SEQUENCE IF NOT EXISTS public.review_comments_id_num_seq
+    INCREMENT BY 1            -- 步长
+    START 1                   -- 起始值    
+    MINVALUE 1
+    MAXVALUE 99999999999      -- 11位数字容量
+    CACHE 1;
+
+-- 创建代码评审意见表，评审 Dev Agent 提交的每条意见一行，同一 Agent 任务重复上报时替换
+CREATE TABLE IF NOT EXISTS review_comments (
+    id VARCHAR(50) PRIMARY KEY DEFAULT public.generate_table_id('REVCM', 'public.review_comments_id_num_seq'),
+    project_id VARCHAR(50) NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    project_guid VARCHAR(50),
+    dev_stage_id VARCHAR(50),
+    story_number VARCHAR(100),
+    agent_task_id VARCHAR(50) NOT NULL,
+    commit_sha VARCHAR(50),
+    review_status VARCHAR(20),
+    round INTEGER NOT NULL DEFAULT 1,
+    file_path VARCHAR(500),
+    line INTEGER NOT NULL DEFAULT 0,
+    severity VARCHAR(20) NOT NULL,
+    message TEXT NOT NULL,
+    blocking BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
 -- 插入默认管理员用户
 -- 密码: Admin123!@# (使用 pgcrypto 加密)
 INSERT INTO users (email, username, password, role, status) VALUES 
@@ -287,6 +479,10 @@ CREATE INDEX IF NOT EXISTS idx_preview_tokens_project_id ON preview_tokens(proje
 CREATE INDEX IF NOT EXISTS idx_preview_tokens_expires_at ON preview_tokens(expires_at);
 CREATE INDEX IF NOT EXISTS idx_preview_tokens_created_at ON preview_tokens(created_at);
 
+CREATE INDEX IF NOT EXISTS idx_agent_task_usages_user_id_created_at ON agent_task_usages(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_agent_task_usages_project_id ON agent_task_usages(project_id);
+CREATE INDEX IF NOT EXISTS idx_agent_task_usages_dev_stage_id ON agent_task_usages(dev_stage_id);
+
 CREATE INDEX IF NOT EXISTS idx_project_epics_project_id ON project_epics(project_id);
 CREATE INDEX IF NOT EXISTS idx_project_epics_project_guid ON project_epics(project_guid);
 CREATE INDEX IF NOT EXISTS idx_project_epics_status ON project_epics(status);
@@ -295,6 +491,21 @@ CREATE INDEX IF NOT EXISTS idx_epic_stories_epic_id ON epic_stories(epic_id);
 CREATE INDEX IF NOT EXISTS idx_epic_stories_status ON epic_stories(status);
 CREATE INDEX IF NOT EXISTS idx_epic_stories_display_order ON epic_stories(epic_id, display_order);
 
+CREATE INDEX IF NOT EXISTS idx_deployments_project_id ON deployments(project_id);
+CREATE INDEX IF NOT EXISTS idx_deployments_project_guid_created_at ON deployments(project_guid, created_at);
+CREATE INDEX IF NOT EXISTS idx_deployments_status ON deployments(status);
+
+CREATE INDEX IF NOT EXISTS idx_test_reports_project_id ON test_reports(project_id);
+CREATE INDEX IF NOT EXISTS idx_test_reports_project_guid_created_at ON test_reports(project_guid, created_at);
+CREATE INDEX IF NOT EXISTS idx_test_reports_dev_stage_id ON test_reports(dev_stage_id);
+
+CREATE INDEX IF NOT EXISTS idx_qa_reviews_project_id ON qa_reviews(project_id);
+CREATE INDEX IF NOT EXISTS idx_qa_reviews_project_guid_story ON qa_reviews(project_guid, story_number, created_at);
+
+CREATE INDEX IF NOT EXISTS idx_review_comments_project_id ON review_comments(project_id);
+CREATE INDEX IF NOT EXISTS idx_review_comments_agent_task_id ON review_comments(agent_task_id);
+CREATE INDEX IF NOT EXISTS idx_review_comments_project_guid_story ON review_comments(project_guid, story_number, created_at);
+
 -- 项目确认相关索引
 CREATE INDEX IF NOT EXISTS idx_projects_waiting_confirm ON projects(waiting_for_user_confirm);
 CREATE INDEX IF NOT EXISTS idx_projects_confirm_stage ON projects(confirm_stage);
@@ -316,7 +527,10 @@ CREATE TRIGGER update_project_msgs_updated_at BEFORE UPDATE ON project_msgs FOR
 CREATE TRIGGER update_dev_stages_updated_at BEFORE UPDATE ON dev_stages FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 CREATE TRIGGER update_project_epics_updated_at BEFORE UPDATE ON project_epics FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 CREATE TRIGGER update_epic_stories_updated_at BEFORE UPDATE ON epic_stories FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
--- Note: preview_tokens 表没有 updated_at 字段，所以不需要触发器
+CREATE TRIGGER update_project_prompts_updated_at BEFORE UPDATE ON project_prompts FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_user_prompts_updated_at BEFORE UPDATE ON user_prompts FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_deployments_updated_at BEFORE UPDATE ON deployments FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+-- Note: preview_tokens、agent_task_usages、test_reports、qa_reviews、review_comments 表没有 updated_at 字段，所以不需要触发器
 
 -- 显示创建的表
 \dt
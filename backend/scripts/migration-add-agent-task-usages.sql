@@ -0,0 +1,70 @@
+-- Migration Script: Add Agent Task Usage Accounting And User Budgets
+-- Date: 2026-10-16
+-- Description: Adds agent_task_usages to record token and cost usage per agent task, and monthly_budget_usd to users
+
+\c autocodeweb;
+
+-- ============================================================================
+-- Create agent_task_usages table
+-- ============================================================================
+
+CREATE SEQUENCE IF NOT EXISTS public.agent_task_usages_id_num_seq
+    INCREMENT BY 1
+    START 1
+    MINVALUE 1
+    MAXVALUE 99999999999
+    CACHE 1;
+
+CREATE TABLE IF NOT EXISTS agent_task_usages (
+    id VARCHAR(50) PRIMARY KEY DEFAULT public.generate_table_id('USAGE', 'public.agent_task_usages_id_num_seq'),
+    agent_task_id VARCHAR(50) UNIQUE NOT NULL,
+    user_id VARCHAR(50) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    project_id VARCHAR(50) NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    project_guid VARCHAR(50),
+    dev_stage_id VARCHAR(50),
+    dev_stage VARCHAR(100),
+    agent_type VARCHAR(50),
+    cli_tool VARCHAR(50),
+    status VARCHAR(20),
+    input_tokens INTEGER NOT NULL DEFAULT 0,
+    output_tokens INTEGER NOT NULL DEFAULT 0,
+    cache_creation_input_tokens INTEGER NOT NULL DEFAULT 0,
+    cache_read_input_tokens INTEGER NOT NULL DEFAULT 0,
+    cost_usd NUMERIC(12,6) NOT NULL DEFAULT 0,
+    duration_ms INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_task_usages_user_id_created_at ON agent_task_usages(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_agent_task_usages_project_id ON agent_task_usages(project_id);
+CREATE INDEX IF NOT EXISTS idx_agent_task_usages_dev_stage_id ON agent_task_usages(dev_stage_id);
+
+COMMENT ON TABLE agent_task_usages IS 'Agent 任务用量表';
+COMMENT ON COLUMN agent_task_usages.cost_usd IS 'CLI 上报的费用（美元）';
+
+-- ============================================================================
+-- Add monthly_budget_usd field to users table
+-- ============================================================================
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.columns 
+        WHERE table_name = 'users' AND column_name = 'monthly_budget_usd'
+    ) THEN
+        ALTER TABLE users ADD COLUMN monthly_budget_usd NUMERIC(12,4);
+        RAISE NOTICE 'Added monthly_budget_usd column to users table';
+    END IF;
+END $$;
+
+COMMENT ON COLUMN users.monthly_budget_usd IS '月度预算（美元），为空时使用系统默认预算，0 表示不限制';
+
+\echo ''
+\echo '=========================================='
+\echo 'Migration completed successfully!'
+\echo '=========================================='
+\echo 'Added tables:'
+\echo '  - agent_task_usages'
+\echo 'Added fields:'
+\echo '  - users: monthly_budget_usd'
+\echo '=========================================='
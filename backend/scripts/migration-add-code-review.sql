@@ -0,0 +1,67 @@
+-- Migration Script: Add Code Review
+-- Date: 2026-10-16
+-- Description: Adds the code review settings to projects and review_comments to store the findings
+--              of reviewing every Dev agent commit; blocking findings are sent back to the Dev agent
+
+\c autocodeweb;
+
+-- ============================================================================
+-- Add code review fields to projects table
+-- ============================================================================
+
+ALTER TABLE projects
+ADD COLUMN IF NOT EXISTS code_review_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE projects
+ADD COLUMN IF NOT EXISTS review_blocking_severities TEXT;
+
+COMMENT ON COLUMN projects.code_review_enabled IS '是否评审 Dev Agent 的每次提交';
+COMMENT ON COLUMN projects.review_blocking_severities IS '阻塞的评审严重程度（JSON），有这些严重程度的意见时退回 Dev Agent 修复';
+
+-- ============================================================================
+-- Create review_comments table
+-- ============================================================================
+
+CREATE SEQUENCE IF NOT EXISTS public.review_comments_id_num_seq
+    INCREMENT BY 1
+    START 1
+    MINVALUE 1
+    MAXVALUE 99999999999
+    CACHE 1;
+
+CREATE TABLE IF NOT EXISTS review_comments (
+    id VARCHAR(50) PRIMARY KEY DEFAULT public.generate_table_id('REVCM', 'public.review_comments_id_num_seq'),
+    project_id VARCHAR(50) NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    project_guid VARCHAR(50),
+    dev_stage_id VARCHAR(50),
+    story_number VARCHAR(100),
+    agent_task_id VARCHAR(50) NOT NULL,
+    commit_sha VARCHAR(50),
+    review_status VARCHAR(20),
+    round INTEGER NOT NULL DEFAULT 1,
+    file_path VARCHAR(500),
+    line INTEGER NOT NULL DEFAULT 0,
+    severity VARCHAR(20) NOT NULL,
+    message TEXT NOT NULL,
+    blocking BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_review_comments_project_id ON review_comments(project_id);
+CREATE INDEX IF NOT EXISTS idx_review_comments_agent_task_id ON review_comments(agent_task_id);
+CREATE INDEX IF NOT EXISTS idx_review_comments_project_guid_story ON review_comments(project_guid, story_number, created_at);
+
+COMMENT ON TABLE review_comments IS 'Dev Agent 提交的代码评审意见表';
+COMMENT ON COLUMN review_comments.severity IS '严重程度：critical、high、medium、low';
+COMMENT ON COLUMN review_comments.review_status IS '所在评审的结论：passed、fixed、blocked、failed';
+COMMENT ON COLUMN review_comments.blocking IS '是否为项目配置的阻塞严重程度';
+
+\echo ''
+\echo '=========================================='
+\echo 'Migration completed successfully!'
+\echo '=========================================='
+\echo 'Added fields:'
+\echo '  - projects: code_review_enabled, review_blocking_severities'
+\echo 'Added tables:'
+\echo '  - review_comments'
+\echo '=========================================='
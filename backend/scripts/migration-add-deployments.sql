@@ -0,0 +1,58 @@
+-- Migration Script: Add Deployment History
+-- Date: 2026-10-16
+-- Description: Adds deployments to record every deploy per environment with commit, status, duration, URLs and logs
+
+\c autocodeweb;
+
+-- ============================================================================
+-- Create deployments table
+-- ============================================================================
+
+CREATE SEQUENCE IF NOT EXISTS public.deployments_id_num_seq
+    INCREMENT BY 1
+    START 1
+    MINVALUE 1
+    MAXVALUE 99999999999
+    CACHE 1;
+
+CREATE TABLE IF NOT EXISTS deployments (
+    id VARCHAR(50) PRIMARY KEY DEFAULT public.generate_table_id('DEPLOY', 'public.deployments_id_num_seq'),
+    project_id VARCHAR(50) NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    project_guid VARCHAR(50),
+    user_id VARCHAR(50),
+    environment VARCHAR(20) NOT NULL DEFAULT 'dev',
+    commit_sha VARCHAR(64),
+    source_deployment_id VARCHAR(50),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    task_id VARCHAR(50),
+    agent_task_id VARCHAR(50),
+    preview_url VARCHAR(500),
+    components TEXT,
+    logs TEXT,
+    error_message TEXT,
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_deployments_project_id ON deployments(project_id);
+CREATE INDEX IF NOT EXISTS idx_deployments_project_guid_created_at ON deployments(project_guid, created_at);
+CREATE INDEX IF NOT EXISTS idx_deployments_status ON deployments(status);
+
+DROP TRIGGER IF EXISTS update_deployments_updated_at ON deployments;
+CREATE TRIGGER update_deployments_updated_at BEFORE UPDATE ON deployments FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE deployments IS '项目部署记录表';
+COMMENT ON COLUMN deployments.environment IS '部署环境：dev、staging、prod';
+COMMENT ON COLUMN deployments.components IS '各组件的健康检查状态（JSON）';
+COMMENT ON COLUMN deployments.source_deployment_id IS '重新部署时对应的历史部署';
+
+\echo ''
+\echo '=========================================='
+\echo 'Migration completed successfully!'
+\echo '=========================================='
+\echo 'Added tables:'
+\echo '  - deployments'
+\echo '=========================================='
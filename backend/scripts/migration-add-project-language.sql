@@ -0,0 +1,32 @@
+-- Migration Script: Add Project Output Language
+-- Date: 2026-10-16
+-- Description: Adds the output language to projects and the default language to user settings,
+--              used to render agent prompts, stage descriptions and system messages
+
+\c autocodeweb;
+
+-- ============================================================================
+-- Add language fields to users and projects tables
+-- ============================================================================
+
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS default_language VARCHAR(20) DEFAULT 'zh-CN';
+
+ALTER TABLE projects
+ADD COLUMN IF NOT EXISTS language VARCHAR(20) DEFAULT 'zh-CN';
+
+COMMENT ON COLUMN users.default_language IS '新建项目的默认输出语言，如 zh-CN、en-US';
+COMMENT ON COLUMN projects.language IS '项目输出语言，决定提示词、文档、阶段描述和系统消息使用的语言';
+
+-- 已有数据保持中文输出
+UPDATE users SET default_language = 'zh-CN' WHERE default_language IS NULL;
+UPDATE projects SET language = 'zh-CN' WHERE language IS NULL;
+
+\echo ''
+\echo '=========================================='
+\echo 'Migration completed successfully!'
+\echo '=========================================='
+\echo 'Added fields:'
+\echo '  - users: default_language'
+\echo '  - projects: language'
+\echo '=========================================='
@@ -0,0 +1,52 @@
+-- Migration Script: Add Project Prompt Template Overrides
+-- Date: 2026-10-16
+-- Description: Adds project_prompts to store per-project overrides of the versioned agent prompt templates
+
+\c autocodeweb;
+
+-- ============================================================================
+-- Create project_prompts table
+-- ============================================================================
+
+CREATE SEQUENCE IF NOT EXISTS public.project_prompts_id_num_seq
+    INCREMENT BY 1
+    START 1
+    MINVALUE 1
+    MAXVALUE 99999999999
+    CACHE 1;
+
+CREATE TABLE IF NOT EXISTS project_prompts (
+    id VARCHAR(50) PRIMARY KEY DEFAULT public.generate_table_id('PROMPT', 'public.project_prompts_id_num_seq'),
+    project_id VARCHAR(50) NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    base_version INTEGER NOT NULL DEFAULT 1,
+    content TEXT NOT NULL,
+    updated_by VARCHAR(50),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT idx_project_prompts_project_id_name UNIQUE (project_id, name)
+);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_trigger WHERE tgname = 'update_project_prompts_updated_at'
+    ) THEN
+        CREATE TRIGGER update_project_prompts_updated_at BEFORE UPDATE ON project_prompts
+            FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+        RAISE NOTICE 'Added update_project_prompts_updated_at trigger';
+    END IF;
+END $$;
+
+COMMENT ON TABLE project_prompts IS '项目提示词模板表，保存项目覆盖的 Agent 提示词模板';
+COMMENT ON COLUMN project_prompts.version IS '项目模板版本，每次保存加 1';
+COMMENT ON COLUMN project_prompts.base_version IS '保存时对应的内置模板版本';
+
+\echo ''
+\echo '=========================================='
+\echo 'Migration completed successfully!'
+\echo '=========================================='
+\echo 'Added tables:'
+\echo '  - project_prompts'
+\echo '=========================================='
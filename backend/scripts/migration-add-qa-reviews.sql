@@ -0,0 +1,46 @@
+-- Migration Script: Add QA Reviews
+-- Date: 2026-10-16
+-- Description: Adds qa_reviews to store the pass/concerns/fail gate decision of every QA story review; the latest fail of a story blocks deployment
+
+\c autocodeweb;
+
+-- ============================================================================
+-- Create qa_reviews table
+-- ============================================================================
+
+CREATE SEQUENCE IF NOT EXISTS public.qa_reviews_id_num_seq
+    INCREMENT BY 1
+    START 1
+    MINVALUE 1
+    MAXVALUE 99999999999
+    CACHE 1;
+
+CREATE TABLE IF NOT EXISTS qa_reviews (
+    id VARCHAR(50) PRIMARY KEY DEFAULT public.generate_table_id('QAREV', 'public.qa_reviews_id_num_seq'),
+    project_id VARCHAR(50) NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    project_guid VARCHAR(50),
+    dev_stage_id VARCHAR(50),
+    story_number VARCHAR(100) NOT NULL,
+    agent_task_id VARCHAR(50) NOT NULL UNIQUE,
+    gate VARCHAR(20) NOT NULL,
+    status_reason TEXT,
+    top_issues TEXT,
+    gate_file VARCHAR(500),
+    summary TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_qa_reviews_project_id ON qa_reviews(project_id);
+CREATE INDEX IF NOT EXISTS idx_qa_reviews_project_guid_story ON qa_reviews(project_guid, story_number, created_at);
+
+COMMENT ON TABLE qa_reviews IS '用户故事 QA 评审表';
+COMMENT ON COLUMN qa_reviews.gate IS '门禁结论：pass、concerns、fail，故事最新的 fail 阻止部署';
+COMMENT ON COLUMN qa_reviews.top_issues IS 'QA 给出的主要问题（JSON）';
+
+\echo ''
+\echo '=========================================='
+\echo 'Migration completed successfully!'
+\echo '=========================================='
+\echo 'Added tables:'
+\echo '  - qa_reviews'
+\echo '=========================================='
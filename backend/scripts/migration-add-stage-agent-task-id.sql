@@ -0,0 +1,31 @@
+-- Migration Script: Add Agent Task ID Field To Dev Stages
+-- Date: 2026-10-16
+-- Description: Adds agent_task_id to dev_stages so a running stage can be cancelled on the agents server.
+--              The develop-story stage runs one agent task per story, so the column holds a comma separated list.
+
+\c autocodeweb;
+
+-- ============================================================================
+-- Add agent_task_id field to dev_stages table
+-- ============================================================================
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.columns 
+        WHERE table_name = 'dev_stages' AND column_name = 'agent_task_id'
+    ) THEN
+        ALTER TABLE dev_stages ADD COLUMN agent_task_id TEXT;
+        RAISE NOTICE 'Added agent_task_id column to dev_stages table';
+    ELSE
+        ALTER TABLE dev_stages ALTER COLUMN agent_task_id TYPE TEXT;
+    END IF;
+END $$;
+
+\echo ''
+\echo '=========================================='
+\echo 'Migration completed successfully!'
+\echo '=========================================='
+\echo 'Added fields:'
+\echo '  - dev_stages: agent_task_id'
+\echo '=========================================='
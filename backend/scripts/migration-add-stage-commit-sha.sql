@@ -0,0 +1,37 @@
+-- Migration Script: Add Commit SHA Fields To Dev Stages
+-- Date: 2026-10-16
+-- Description: Adds pre_commit_sha and post_commit_sha to dev_stages so a project can be rolled back
+--              to the commit recorded before a stage started.
+
+\c autocodeweb;
+
+-- ============================================================================
+-- Add commit sha fields to dev_stages table
+-- ============================================================================
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.columns 
+        WHERE table_name = 'dev_stages' AND column_name = 'pre_commit_sha'
+    ) THEN
+        ALTER TABLE dev_stages ADD COLUMN pre_commit_sha VARCHAR(64);
+        RAISE NOTICE 'Added pre_commit_sha column to dev_stages table';
+    END IF;
+
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.columns 
+        WHERE table_name = 'dev_stages' AND column_name = 'post_commit_sha'
+    ) THEN
+        ALTER TABLE dev_stages ADD COLUMN post_commit_sha VARCHAR(64);
+        RAISE NOTICE 'Added post_commit_sha column to dev_stages table';
+    END IF;
+END $$;
+
+\echo ''
+\echo '=========================================='
+\echo 'Migration completed successfully!'
+\echo '=========================================='
+\echo 'Added fields:'
+\echo '  - dev_stages: pre_commit_sha, post_commit_sha'
+\echo '=========================================='
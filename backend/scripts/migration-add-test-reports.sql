@@ -0,0 +1,51 @@
+-- Migration Script: Add Test Reports
+-- Date: 2026-10-16
+-- Description: Adds test_reports to store the structured report (suites, cases, failures, durations, coverage) of every test run per stage
+
+\c autocodeweb;
+
+-- ============================================================================
+-- Create test_reports table
+-- ============================================================================
+
+CREATE SEQUENCE IF NOT EXISTS public.test_reports_id_num_seq
+    INCREMENT BY 1
+    START 1
+    MINVALUE 1
+    MAXVALUE 99999999999
+    CACHE 1;
+
+CREATE TABLE IF NOT EXISTS test_reports (
+    id VARCHAR(50) PRIMARY KEY DEFAULT public.generate_table_id('TREPORT', 'public.test_reports_id_num_seq'),
+    project_id VARCHAR(50) NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    project_guid VARCHAR(50),
+    dev_stage_id VARCHAR(50),
+    dev_stage VARCHAR(100),
+    agent_task_id VARCHAR(50) NOT NULL UNIQUE,
+    status VARCHAR(20),
+    total INTEGER NOT NULL DEFAULT 0,
+    passed INTEGER NOT NULL DEFAULT 0,
+    failed INTEGER NOT NULL DEFAULT 0,
+    skipped INTEGER NOT NULL DEFAULT 0,
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    coverage NUMERIC(5,2),
+    suites TEXT,
+    summary TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_test_reports_project_id ON test_reports(project_id);
+CREATE INDEX IF NOT EXISTS idx_test_reports_project_guid_created_at ON test_reports(project_guid, created_at);
+CREATE INDEX IF NOT EXISTS idx_test_reports_dev_stage_id ON test_reports(dev_stage_id);
+
+COMMENT ON TABLE test_reports IS '项目自动测试报告表';
+COMMENT ON COLUMN test_reports.coverage IS '语句覆盖率百分比，没有覆盖率数据时为空';
+COMMENT ON COLUMN test_reports.suites IS '各测试套件、用例和失败原因（JSON）';
+
+\echo ''
+\echo '=========================================='
+\echo 'Migration completed successfully!'
+\echo '=========================================='
+\echo 'Added tables:'
+\echo '  - test_reports'
+\echo '=========================================='
@@ -0,0 +1,29 @@
+-- Migration Script: Add Usage Unknown Field To Agent Task Usages
+-- Date: 2026-10-17
+-- Description: Adds usage_unknown to agent_task_usages so tasks run by CLIs that do not report
+--              token usage (qwen, gemini) are marked instead of being recorded as zero cost.
+
+\c autocodeweb;
+
+-- ============================================================================
+-- Add usage_unknown field to agent_task_usages table
+-- ============================================================================
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.columns 
+        WHERE table_name = 'agent_task_usages' AND column_name = 'usage_unknown'
+    ) THEN
+        ALTER TABLE agent_task_usages ADD COLUMN usage_unknown BOOLEAN NOT NULL DEFAULT FALSE;
+        RAISE NOTICE 'Added usage_unknown column to agent_task_usages table';
+    END IF;
+END $$;
+
+\echo ''
+\echo '=========================================='
+\echo 'Migration completed successfully!'
+\echo '=========================================='
+\echo 'Added fields:'
+\echo '  - agent_task_usages: usage_unknown'
+\echo '=========================================='
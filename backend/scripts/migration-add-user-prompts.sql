@@ -0,0 +1,53 @@
+-- Migration Script: Add User Prompt Template Overrides
+-- Date: 2026-10-16
+-- Description: Adds user_prompts to store per-user overrides that apply to all projects of the user in the same language
+
+\c autocodeweb;
+
+-- ============================================================================
+-- Create user_prompts table
+-- ============================================================================
+
+CREATE SEQUENCE IF NOT EXISTS public.user_prompts_id_num_seq
+    INCREMENT BY 1
+    START 1
+    MINVALUE 1
+    MAXVALUE 99999999999
+    CACHE 1;
+
+CREATE TABLE IF NOT EXISTS user_prompts (
+    id VARCHAR(50) PRIMARY KEY DEFAULT public.generate_table_id('UPROMPT', 'public.user_prompts_id_num_seq'),
+    user_id VARCHAR(50) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    language VARCHAR(20) NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    base_version INTEGER NOT NULL DEFAULT 1,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT idx_user_prompts_user_id_name_language UNIQUE (user_id, name, language)
+);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_trigger WHERE tgname = 'update_user_prompts_updated_at'
+    ) THEN
+        CREATE TRIGGER update_user_prompts_updated_at BEFORE UPDATE ON user_prompts
+            FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+        RAISE NOTICE 'Added update_user_prompts_updated_at trigger';
+    END IF;
+END $$;
+
+COMMENT ON TABLE user_prompts IS '用户提示词模板表，保存用户覆盖的 Agent 提示词模板，对该用户所有同语言的项目生效';
+COMMENT ON COLUMN user_prompts.language IS '模板语言，如 zh-CN、en-US';
+COMMENT ON COLUMN user_prompts.version IS '用户模板版本，每次保存加 1';
+COMMENT ON COLUMN user_prompts.base_version IS '保存时对应的内置模板版本';
+
+\echo ''
+\echo '=========================================='
+\echo 'Migration completed successfully!'
+\echo '=========================================='
+\echo 'Added tables:'
+\echo '  - user_prompts'
+\echo '=========================================='
@@ -334,7 +334,10 @@ export default {
     defineApi: 'API Design',
     planEpicAndStory: 'Task Planning',
     generatePages: 'Generate Pages',
+    draftStory: 'Story Drafting',
     developStory: 'Function Development',
+    codeReview: 'Code Review',
+    qaReview: 'QA Review',
     fixBug: 'Bug Fix',
     runTest: 'Auto Test',
     deploy: 'Project Deployment',
@@ -334,7 +334,10 @@ export default {
     defineApi: 'API设计',
     planEpicAndStory: '任务规划',
     generatePages: '生成前端页面',
+    draftStory: '故事起草',
     developStory: '功能开发',
+    codeReview: '代码评审',
+    qaReview: 'QA 评审',
     fixBug: '问题修复',
     runTest: '自动测试',
     deploy: '项目部署',
@@ -34,6 +34,11 @@ export class AppConfig {
     return 1000 * (import.meta.env.VITE_API_TIMEOUT || 60)
   }
 
+  // mock CLI 只在开发、测试构建中可选，生产环境不显示
+  isMockCliEnabled(): boolean {
+    return import.meta.env.MODE === 'development' || import.meta.env.MODE === 'test'
+  }
+
   // 获取当前配置信息
   getConfig(): { apiLogEnabled: boolean; apiBaseUrl: string; apiTimeout: number } {
     return {
@@ -2,6 +2,8 @@ package agent
 
 import (
 	"encoding/json"
+	"regexp"
+	"strings"
 )
 
 // 项目环境准备请求
@@ -14,6 +16,7 @@ type SetupProjEnvReq struct {
 	ModelProvider   string `json:"model_provider" example:"zhipu"`
 	ModelApiUrl     string `json:"model_api_url" example:"https://open.bigmodel.cn/api/anthropic"`
 	ApiToken        string `json:"api_token" example:"sk-..."`
+	Language        string `json:"language" example:"zh-CN"` // 输出语言，为空时使用默认语言
 }
 
 func (a *SetupProjEnvReq) ToBytes() []byte {
@@ -24,95 +27,224 @@ func (a *SetupProjEnvReq) ToBytes() []byte {
 	return bytes
 }
 
+// GetModelConfig 获取环境准备请求中的项目模型配置
+func (a *SetupProjEnvReq) GetModelConfig() *ProjectModelConfig {
+	return &ProjectModelConfig{
+		CliTool:       a.BmadCliType,
+		AiModel:       a.AiModel,
+		ModelProvider: a.ModelProvider,
+		ModelApiUrl:   a.ModelApiUrl,
+		ApiToken:      a.ApiToken,
+	}
+}
+
+// 项目模型配置，agents 服务按项目保存，每次执行 CLI 时注入为对应的环境变量
+type ProjectModelConfig struct {
+	CliTool       string `json:"cli_tool" example:"claude-code"`
+	AiModel       string `json:"ai_model" example:"glm-4.6"`
+	ModelProvider string `json:"model_provider" example:"zhipu"`
+	ModelApiUrl   string `json:"model_api_url" example:"https://open.bigmodel.cn/api/anthropic"`
+	ApiToken      string `json:"api_token" example:"sk-..."`
+}
+
 // 获取项目概览请求
 type GetProjBriefReq struct {
-	Requirements string `json:"requirements" binding:"required" example:"项目需求描述"`
-	ProjectGuid  string `json:"project_guid" binding:"required" example:"1234567890"`
-	CliTool      string `json:"cli_tool" example:"claude-code"`
+	Requirements   string          `json:"requirements" binding:"required" example:"项目需求描述"`
+	ProjectGuid    string          `json:"project_guid" binding:"required" example:"1234567890"`
+	CliTool        string          `json:"cli_tool" example:"claude-code"`
+	Language       string          `json:"language" example:"zh-CN"`  // 输出语言，为空时使用默认语言
+	PromptOverride *PromptTemplate `json:"prompt_override,omitempty"` // 项目覆盖的提示词模板，为空时使用内置模板
 }
 
 // 获取 PRD 请求
 type GetPRDReq struct {
-	ProjectGuid  string `json:"project_guid" binding:"required" example:"1234567890"`
-	Requirements string `json:"requirements" binding:"required" example:"项目需求描述"`
-	CliTool      string `json:"cli_tool" example:"claude-code"`
+	ProjectGuid    string          `json:"project_guid" binding:"required" example:"1234567890"`
+	Requirements   string          `json:"requirements" binding:"required" example:"项目需求描述"`
+	CliTool        string          `json:"cli_tool" example:"claude-code"`
+	Language       string          `json:"language" example:"zh-CN"`  // 输出语言，为空时使用默认语言
+	PromptOverride *PromptTemplate `json:"prompt_override,omitempty"` // 项目覆盖的提示词模板，为空时使用内置模板
 }
 
 // 获取 Epics 和 Stories 请求
 type GetEpicsAndStoriesReq struct {
-	ProjectGuid string `json:"project_guid" binding:"required" example:"1234567890"`
-	PrdPath     string `json:"prd_path" binding:"required" example:"docs/PRD.md"`
-	ArchFolder  string `json:"arch_folder" binding:"required" example:"docs/arch"`
-	CliTool     string `json:"cli_tool" example:"claude-code"`
+	ProjectGuid    string          `json:"project_guid" binding:"required" example:"1234567890"`
+	PrdPath        string          `json:"prd_path" binding:"required" example:"docs/PRD.md"`
+	ArchFolder     string          `json:"arch_folder" binding:"required" example:"docs/arch"`
+	CliTool        string          `json:"cli_tool" example:"claude-code"`
+	Language       string          `json:"language" example:"zh-CN"`  // 输出语言，为空时使用默认语言
+	PromptOverride *PromptTemplate `json:"prompt_override,omitempty"` // 项目覆盖的提示词模板，为空时使用内置模板
 }
 
 // 获取 UX 标准请求
 type GetUXStandardReq struct {
-	ProjectGuid  string `json:"project_guid" binding:"required" example:"1234567890"`
-	Requirements string `json:"requirements" binding:"required" example:"项目需求描述"`
-	PrdPath      string `json:"prd_path" binding:"required" example:"docs/PRD.md"`
-	CliTool      string `json:"cli_tool" example:"claude-code"`
+	ProjectGuid    string          `json:"project_guid" binding:"required" example:"1234567890"`
+	Requirements   string          `json:"requirements" binding:"required" example:"项目需求描述"`
+	PrdPath        string          `json:"prd_path" binding:"required" example:"docs/PRD.md"`
+	CliTool        string          `json:"cli_tool" example:"claude-code"`
+	Language       string          `json:"language" example:"zh-CN"`  // 输出语言，为空时使用默认语言
+	PromptOverride *PromptTemplate `json:"prompt_override,omitempty"` // 项目覆盖的提示词模板，为空时使用内置模板
 }
 
 // 获取架构设计请求
 type GetArchitectureReq struct {
-	ProjectGuid             string `json:"project_guid" binding:"required" example:"1234567890"`
-	PrdPath                 string `json:"prd_path" binding:"required" example:"docs/PRD.md"`
-	UxSpecPath              string `json:"ux_spec_path" binding:"required" example:"docs/ux/ux-spec.md"`
-	TemplateArchDescription string `json:"template_arch_description" binding:"required" example:"templates/architecture-template-v2.yaml"`
-	CliTool                 string `json:"cli_tool" example:"claude-code"`
+	ProjectGuid             string          `json:"project_guid" binding:"required" example:"1234567890"`
+	PrdPath                 string          `json:"prd_path" binding:"required" example:"docs/PRD.md"`
+	UxSpecPath              string          `json:"ux_spec_path" binding:"required" example:"docs/ux/ux-spec.md"`
+	TemplateArchDescription string          `json:"template_arch_description" binding:"required" example:"templates/architecture-template-v2.yaml"`
+	CliTool                 string          `json:"cli_tool" example:"claude-code"`
+	Language                string          `json:"language" example:"zh-CN"`  // 输出语言，为空时使用默认语言
+	PromptOverride          *PromptTemplate `json:"prompt_override,omitempty"` // 项目覆盖的提示词模板，为空时使用内置模板
 }
 
 // 获取数据库设计请求
 type GetDatabaseDesignReq struct {
-	ProjectGuid   string `json:"project_guid" binding:"required" example:"1234567890"`
-	PrdPath       string `json:"prd_path" binding:"required" example:"docs/PRD.md"`
-	ArchFolder    string `json:"arch_folder" binding:"required" example:"docs/arch"`
-	StoriesFolder string `json:"stories_folder" binding:"required" example:"docs/stories"`
-	CliTool       string `json:"cli_tool" example:"claude-code"`
+	ProjectGuid    string          `json:"project_guid" binding:"required" example:"1234567890"`
+	PrdPath        string          `json:"prd_path" binding:"required" example:"docs/PRD.md"`
+	ArchFolder     string          `json:"arch_folder" binding:"required" example:"docs/arch"`
+	StoriesFolder  string          `json:"stories_folder" binding:"required" example:"docs/stories"`
+	CliTool        string          `json:"cli_tool" example:"claude-code"`
+	Language       string          `json:"language" example:"zh-CN"`  // 输出语言，为空时使用默认语言
+	PromptOverride *PromptTemplate `json:"prompt_override,omitempty"` // 项目覆盖的提示词模板，为空时使用内置模板
 }
 
 // 获取 API 定义请求
 type GetAPIDefinitionReq struct {
-	ProjectGuid   string `json:"project_guid" binding:"required" example:"1234567890"`
-	PrdPath       string `json:"prd_path" binding:"required" example:"docs/PRD.md"`
-	DbFolder      string `json:"db_folder" binding:"required" example:"docs/db"`
-	StoriesFolder string `json:"stories_folder" binding:"required" example:"docs/stories"`
-	CliTool       string `json:"cli_tool" example:"claude-code"`
+	ProjectGuid    string          `json:"project_guid" binding:"required" example:"1234567890"`
+	PrdPath        string          `json:"prd_path" binding:"required" example:"docs/PRD.md"`
+	DbFolder       string          `json:"db_folder" binding:"required" example:"docs/db"`
+	StoriesFolder  string          `json:"stories_folder" binding:"required" example:"docs/stories"`
+	CliTool        string          `json:"cli_tool" example:"claude-code"`
+	Language       string          `json:"language" example:"zh-CN"`  // 输出语言，为空时使用默认语言
+	PromptOverride *PromptTemplate `json:"prompt_override,omitempty"` // 项目覆盖的提示词模板，为空时使用内置模板
 }
 
 // 实现用户故事请求
 type ImplementStoryReq struct {
-	ProjectGuid string `json:"project_guid" binding:"required" example:"1234567890"`
-	PrdPath     string `json:"prd_path" binding:"required" example:"docs/PRD.md"`
-	ArchFolder  string `json:"arch_folder" binding:"required" example:"docs/arch"`
-	DbFolder    string `json:"db_folder" binding:"required" example:"docs/db"`
-	ApiFolder   string `json:"api_folder" binding:"required" example:"docs/api"`
-	UxSpecPath  string `json:"ux_spec_path" binding:"required" example:"docs/ux/ux-spec.md"`
-	EpicFile    string `json:"epic_file" binding:"required" example:"docs/epics/epic.md"`
-	StoryFile   string `json:"story_file" example:"docs/stories/story.md"`
-	CliTool     string `json:"cli_tool" example:"claude-code"`
+	ProjectGuid    string            `json:"project_guid" binding:"required" example:"1234567890"`
+	PrdPath        string            `json:"prd_path" binding:"required" example:"docs/PRD.md"`
+	ArchFolder     string            `json:"arch_folder" binding:"required" example:"docs/arch"`
+	DbFolder       string            `json:"db_folder" binding:"required" example:"docs/db"`
+	ApiFolder      string            `json:"api_folder" binding:"required" example:"docs/api"`
+	UxSpecPath     string            `json:"ux_spec_path" binding:"required" example:"docs/ux/ux-spec.md"`
+	EpicFile       string            `json:"epic_file" binding:"required" example:"docs/epics/epic.md"`
+	StoryFile      string            `json:"story_file" example:"docs/stories/story.md"`
+	StoryNumber    string            `json:"story_number" example:"1.1"` // 故事编号，用于命名故事分支，为空时所有故事共用一个分支
+	CliTool        string            `json:"cli_tool" example:"claude-code"`
+	Language       string            `json:"language" example:"zh-CN"`  // 输出语言，为空时使用默认语言
+	PromptOverride *PromptTemplate   `json:"prompt_override,omitempty"` // 项目覆盖的提示词模板，为空时使用内置模板
+	Review         *CodeReviewConfig `json:"review,omitempty"`          // 代码评审配置，为空时不评审 Dev Agent 的提交
+}
+
+// CodeReviewConfig 代码评审配置：Dev Agent 的提交合并后由评审 Agent 评审该提交的 diff，
+// 有阻塞级别的评审意见时把意见交给 Dev Agent 修复并重新评审修复的提交
+type CodeReviewConfig struct {
+	BlockingSeverities []string        `json:"blocking_severities" binding:"omitempty,dive,oneof=critical high medium low" example:"critical,high"` // 阻塞的严重程度，为空时评审意见只记录不修复
+	MaxFixRounds       int             `json:"max_fix_rounds" example:"2"`                                                                          // Dev Agent 最多修复几轮，用完后仍有阻塞意见时任务失败
+	PromptOverride     *PromptTemplate `json:"prompt_override,omitempty"`                                                                           // 项目覆盖的评审提示词模板，为空时使用内置模板
+}
+
+// IsBlocking 该严重程度的评审意见是否阻塞
+func (c *CodeReviewConfig) IsBlocking(severity string) bool {
+	for _, blocking := range c.BlockingSeverities {
+		if strings.EqualFold(blocking, severity) {
+			return true
+		}
+	}
+	return false
+}
+
+// 起草用户故事请求，SM 为一个故事生成可以独立开发的故事文件
+type DraftStoryReq struct {
+	ProjectGuid        string          `json:"project_guid" binding:"required" example:"1234567890"`
+	StoryNumber        string          `json:"story_number" binding:"required" example:"1.1"` // 故事编号，用于命名故事文件
+	StoryTitle         string          `json:"story_title" binding:"required" example:"用户登录"`
+	Description        string          `json:"description" example:"作为用户，我希望使用账号密码登录"`
+	AcceptanceCriteria string          `json:"acceptance_criteria" example:"1. 输入正确的账号密码可以登录"`
+	Depends            string          `json:"depends" example:"1.0"`
+	Techs              string          `json:"techs" example:"JWT"`
+	EpicFile           string          `json:"epic_file" binding:"required" example:"docs/stories/epic1-xxx-stories.md"`
+	PrdPath            string          `json:"prd_path" binding:"required" example:"docs/PRD.md"`
+	ArchFolder         string          `json:"arch_folder" binding:"required" example:"docs/arch"`
+	DbFolder           string          `json:"db_folder" example:"docs/db"`
+	ApiFolder          string          `json:"api_folder" example:"docs/api"`
+	UxSpecPath         string          `json:"ux_spec_path" example:"docs/ux/ux-spec.md"`
+	StoryFile          string          `json:"-"` // 故事文件，由 agents 按故事编号生成
+	CliTool            string          `json:"cli_tool" example:"claude-code"`
+	Language           string          `json:"language" example:"zh-CN"`  // 输出语言，为空时使用默认语言
+	PromptOverride     *PromptTemplate `json:"prompt_override,omitempty"` // 项目覆盖的提示词模板，为空时使用内置模板
+}
+
+// 用户故事文件所在目录，Epic 文件和 SM 起草的故事文件都在这里
+const StoriesFolder = "docs/stories"
+
+// StoryFilePath SM 起草的故事文件，相对项目根目录，如 docs/stories/1.1.story.md
+func StoryFilePath(storyNumber string) string {
+	return StoriesFolder + "/" + unsafeStoryNumberChars.ReplaceAllString(storyNumber, "-") + ".story.md"
+}
+
+// QA 门禁文件所在目录，每个故事一个 <story-number>.yml
+const QAGatesFolder = "docs/qa/gates"
+
+// 故事编号中不能出现在文件名里的字符
+var unsafeStoryNumberChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// QAGateFilePath 故事的 QA 门禁文件，相对项目根目录，如 docs/qa/gates/1.1.yml
+func QAGateFilePath(storyNumber string) string {
+	return QAGatesFolder + "/" + unsafeStoryNumberChars.ReplaceAllString(storyNumber, "-") + ".yml"
+}
+
+// QA 评审用户故事请求
+type QAReviewReq struct {
+	ProjectGuid        string          `json:"project_guid" binding:"required" example:"1234567890"`
+	StoryNumber        string          `json:"story_number" binding:"required" example:"1.1"` // 故事编号，用于命名故事分支和门禁文件
+	StoryTitle         string          `json:"story_title" example:"用户登录"`
+	StoryFile          string          `json:"story_file" binding:"required" example:"docs/stories/story.md"`
+	AcceptanceCriteria string          `json:"acceptance_criteria" example:"1. 输入正确的账号密码可以登录"` // 为空时以故事文件中的验收标准为准
+	PrdPath            string          `json:"prd_path" binding:"required" example:"docs/PRD.md"`
+	ArchFolder         string          `json:"arch_folder" binding:"required" example:"docs/arch"`
+	GateFile           string          `json:"-"` // 门禁文件，由 agents 按故事编号生成
+	CliTool            string          `json:"cli_tool" example:"claude-code"`
+	Language           string          `json:"language" example:"zh-CN"`  // 输出语言，为空时使用默认语言
+	PromptOverride     *PromptTemplate `json:"prompt_override,omitempty"` // 项目覆盖的提示词模板，为空时使用内置模板
+}
+
+// QA 测试计划请求
+type QATestPlanReq struct {
+	ProjectGuid    string          `json:"project_guid" binding:"required" example:"1234567890"`
+	PrdPath        string          `json:"prd_path" binding:"required" example:"docs/PRD.md"`
+	ArchFolder     string          `json:"arch_folder" binding:"required" example:"docs/arch"`
+	StoriesFolder  string          `json:"stories_folder" binding:"required" example:"docs/stories"`
+	CliTool        string          `json:"cli_tool" example:"claude-code"`
+	Language       string          `json:"language" example:"zh-CN"`  // 输出语言，为空时使用默认语言
+	PromptOverride *PromptTemplate `json:"prompt_override,omitempty"` // 项目覆盖的提示词模板，为空时使用内置模板
 }
 
 // 修复 bug 请求
 type FixBugReq struct {
-	ProjectGuid    string `json:"project_guid" binding:"required" example:"1234567890"`
-	BugDescription string `json:"bug_description" binding:"required" example:"bug description"`
-	CliTool        string `json:"cli_tool" example:"claude-code"`
+	ProjectGuid    string          `json:"project_guid" binding:"required" example:"1234567890"`
+	BugDescription string          `json:"bug_description" binding:"required" example:"bug description"`
+	CliTool        string          `json:"cli_tool" example:"claude-code"`
+	Language       string          `json:"language" example:"zh-CN"`  // 输出语言，为空时使用默认语言
+	PromptOverride *PromptTemplate `json:"prompt_override,omitempty"` // 项目覆盖的提示词模板，为空时使用内置模板
 }
 
 // 运行测试请求
 type RunTestReq struct {
-	ProjectGuid string `json:"project_guid" validate:"required" example:"1234567890"`
-	CliTool     string `json:"cli_tool" example:"claude-code"`
+	ProjectGuid    string          `json:"project_guid" validate:"required" example:"1234567890"`
+	CliTool        string          `json:"cli_tool" example:"claude-code"`
+	Language       string          `json:"language" example:"zh-CN"`  // 输出语言，为空时使用默认语言
+	PromptOverride *PromptTemplate `json:"prompt_override,omitempty"` // 项目覆盖的提示词模板，为空时使用内置模板
 }
 
 // 部署请求
 type DeployReq struct {
 	ProjectGuid   string                 `json:"project_guid" validate:"required" example:"1234567890"`
-	Environment   string                 `json:"environment,omitempty" example:"dev"` // dev, staging, prod
+	Environment   string                 `json:"environment,omitempty" example:"dev"` // dev, staging, prod，为空时为 dev
+	CommitSha     string                 `json:"commit_sha,omitempty"`                // 部署指定的历史提交，为空时部署主干最新提交
+	DeploymentID  string                 `json:"deployment_id,omitempty"`             // 后端的部署记录ID
 	DeployOptions map[string]interface{} `json:"deploy_options,omitempty"`
 	CliTool       string                 `json:"cli_tool" example:"claude-code"`
+	Language      string                 `json:"language" example:"zh-CN"` // 输出语言，为空时使用默认语言
 }
 
 func (a *DeployReq) ToBytes() []byte {
@@ -125,11 +257,13 @@ func (a *DeployReq) ToBytes() []byte {
 
 // 与 Agent 对话请求
 type ChatReq struct {
-	ProjectGuid string `json:"project_guid" binding:"required" example:"1234567890"`
-	AgentType   string `json:"agent_type" binding:"required" example:"dev"`
-	Message     string `json:"message" binding:"required" example:"确认，继续执行"`
-	CliTool     string `json:"cli_tool" example:"claude-code"`
-	DevStage    string `json:"dev_stage" example:"initializing"`
+	ProjectGuid string          `json:"project_guid" binding:"required" example:"1234567890"`
+	AgentType   string          `json:"agent_type" binding:"required" example:"dev"`
+	Message     string          `json:"message" binding:"required" example:"确认，继续执行"`
+	CliTool     string          `json:"cli_tool" example:"claude-code"`
+	Language    string          `json:"language" example:"zh-CN"` // 输出语言，为空时使用默认语言
+	DevStage    string          `json:"dev_stage" example:"initializing"`
+	Prompt      *RenderedPrompt `json:"prompt,omitempty"` // 由模板渲染出 Message 时记录模板信息
 }
 
 func (a *ChatReq) ToBytes() []byte {
@@ -139,3 +273,18 @@ func (a *ChatReq) ToBytes() []byte {
 	}
 	return bytes
 }
+
+// 回滚项目请求，主干分支硬重置到指定提交并强制推送
+type GitRollbackReq struct {
+	CommitSha string `json:"commit_sha" binding:"required" example:"3f2c1a9"`
+}
+
+// TaskListReq 异步任务列表的筛选条件，为空的条件不筛选
+type TaskListReq struct {
+	State       string `form:"state" binding:"omitempty,oneof=pending active scheduled retry archived completed" example:"retry"`
+	Queue       string `form:"queue" example:"default"`
+	ProjectGuid string `form:"project_guid" example:"e080335a93d0456ba9b65ab407710e55"`
+	AgentType   string `form:"agent_type" example:"dev"`
+	Type        string `form:"type" example:"agent:execute"`
+	Limit       int    `form:"limit" binding:"omitempty,min=1,max=500" example:"100"`
+}
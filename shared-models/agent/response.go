@@ -68,18 +68,289 @@ type BackendHealthResp struct {
 	Agent     *AgentHealthResp `json:"agent,omitempty"`
 }
 
+// AgentSessionInfo 项目 Agent 会话信息
+type AgentSessionInfo struct {
+	ProjectGuid string `json:"project_guid"`
+	AgentType   string `json:"agent_type"`
+	Mode        string `json:"mode"`                 // native: CLI 原生会话; transcript: 对话记录
+	SessionID   string `json:"session_id,omitempty"` // CLI 原生会话ID
+	Turns       int    `json:"turns"`                // 已记录的对话轮数
+	UpdatedAt   string `json:"updated_at,omitempty"` // 最后一轮对话的时间
+}
+
+// AgentSessionTurn 一轮对话记录
+type AgentSessionTurn struct {
+	CliTool   string `json:"cli_tool"`
+	Message   string `json:"message"`
+	Result    string `json:"result"`
+	Timestamp string `json:"timestamp"`
+}
+
+// MergeRequestInfo 合并请求信息
+type MergeRequestInfo struct {
+	Provider     string `json:"provider"`      // 提供方：gitlab, local
+	ID           string `json:"id"`            // 合并请求编号，GitLab 为项目内的 iid
+	URL          string `json:"url,omitempty"` // 合并请求地址，local 为空
+	Title        string `json:"title"`
+	SourceBranch string `json:"source_branch"`
+	TargetBranch string `json:"target_branch"`
+	State        string `json:"state"` // opened, merged
+}
+
+// GitBranchResult 阶段、故事分支的提交和合并结果
+type GitBranchResult struct {
+	Branch        string            `json:"branch"`                   // 阶段、故事分支，如 appmaker/develop_story/1.1
+	BaseBranch    string            `json:"base_branch"`              // 合并的目标分支
+	CommitSha     string            `json:"commit_sha,omitempty"`     // 分支上的最新提交
+	Status        string            `json:"status"`                   // merged, no_changes, verify_failed, conflict, failed
+	MergeRequest  *MergeRequestInfo `json:"merge_request,omitempty"`  // 合并请求
+	ConflictFiles []string          `json:"conflict_files,omitempty"` // 合并冲突的文件
+	VerifySkipped bool              `json:"verify_skipped,omitempty"` // 合并前是否跳过了构建和测试
+	Error         string            `json:"error,omitempty"`          // 失败原因
+}
+
+// FixAttempt 命令失败后 Dev Agent 的一次修复尝试
+type FixAttempt struct {
+	Attempt     int      `json:"attempt"`               // 第几次修复，从 1 开始
+	Command     string   `json:"command"`               // 失败的命令，在项目根目录执行
+	Error       string   `json:"error"`                 // 命令的错误，如 exit status 2
+	Diagnostics []string `json:"diagnostics"`           // 从命令输出中提取的错误行，发送给 Dev Agent
+	Diff        string   `json:"diff,omitempty"`        // Dev Agent 本次修改的代码，过长时截断
+	AgentError  string   `json:"agent_error,omitempty"` // Dev Agent 执行失败的原因
+	Fixed       bool     `json:"fixed"`                 // 修复后重新执行命令是否成功
+	StartedAt   string   `json:"started_at"`
+	CompletedAt string   `json:"completed_at"`
+}
+
+// DeployComponentStatus 部署后组件的健康检查结果
+type DeployComponentStatus struct {
+	Name      string `json:"name"`                 // 项目清单中的组件名称
+	State     string `json:"state"`                // healthy, unhealthy, unchecked
+	URL       string `json:"url,omitempty"`        // 组件访问地址
+	HealthURL string `json:"health_url,omitempty"` // 健康检查地址
+	Ports     []int  `json:"ports,omitempty"`      // 组件监听的端口
+	Error     string `json:"error,omitempty"`      // 最后一次健康检查失败的原因
+	LogTail   string `json:"log_tail,omitempty"`   // 健康检查失败时组件日志的最后几行
+	CheckedAt string `json:"checked_at"`
+}
+
+// DeployResp 部署结果：启动后各组件的健康检查结果和访问地址
+type DeployResp struct {
+	Status      string                   `json:"status"`                // healthy: 所有组件就绪；unhealthy: 有组件未通过健康检查
+	Environment string                   `json:"environment"`           // 部署环境：dev, staging, prod
+	CommitSha   string                   `json:"commit_sha,omitempty"`  // 部署的提交
+	PreviewURL  string                   `json:"preview_url,omitempty"` // 项目预览地址，优先使用前端组件的地址
+	Components  []*DeployComponentStatus `json:"components"`
+}
+
+// TestReport 自动测试报告：按套件汇总 go test -json、ESLint JSON、Vitest/Jest JSON 报告的结果
+type TestReport struct {
+	Status     string       `json:"status"`             // passed: 全部通过；failed: 有失败的用例或命令
+	Total      int          `json:"total"`              // 用例总数
+	Passed     int          `json:"passed"`             // 通过的用例数
+	Failed     int          `json:"failed"`             // 失败的用例数
+	Skipped    int          `json:"skipped"`            // 跳过的用例数
+	DurationMs int64        `json:"duration_ms"`        // 所有套件的耗时
+	Coverage   *float64     `json:"coverage,omitempty"` // 有覆盖率的套件的平均语句覆盖率（%）
+	Suites     []*TestSuite `json:"suites"`
+}
+
+// TestSuite 测试套件：Go 包、测试文件，或一次 ESLint、无法解析报告的命令
+type TestSuite struct {
+	Name       string      `json:"name"`
+	Component  string      `json:"component"`          // 项目清单中的组件名称
+	Tool       string      `json:"tool"`               // go, eslint, vitest, jest, command
+	Command    string      `json:"command"`            // 产生该套件的命令
+	Status     string      `json:"status"`             // passed, failed
+	Total      int         `json:"total"`              // 用例总数
+	Passed     int         `json:"passed"`             // 通过的用例数
+	Failed     int         `json:"failed"`             // 失败的用例数
+	Skipped    int         `json:"skipped"`            // 跳过的用例数
+	DurationMs int64       `json:"duration_ms"`        // 套件耗时
+	Coverage   *float64    `json:"coverage,omitempty"` // 语句覆盖率（%）
+	Cases      []*TestCase `json:"cases"`
+}
+
+// TestCase 测试用例，ESLint 报告中每个文件为一个用例
+type TestCase struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`            // passed, failed, skipped
+	DurationMs int64  `json:"duration_ms"`       // 用例耗时
+	File       string `json:"file,omitempty"`    // 用例所在文件
+	Message    string `json:"message,omitempty"` // 失败原因，过长时截断
+}
+
+// QAGate QA Agent 评审用户故事后给出的门禁结论，从故事的门禁文件中解析
+type QAGate struct {
+	StoryNumber  string   `json:"story_number"`
+	Gate         string   `json:"gate"`                 // pass, concerns, fail
+	StatusReason string   `json:"status_reason"`        // 结论的原因
+	TopIssues    []string `json:"top_issues,omitempty"` // 主要问题
+	GateFile     string   `json:"gate_file"`            // 门禁文件，相对项目根目录
+}
+
+// CodeReview 评审 Dev Agent 提交的结果，包括每轮评审的意见和修复后的结论
+type CodeReview struct {
+	StoryNumber string           `json:"story_number"`
+	CommitSha   string           `json:"commit_sha"`        // 被评审的 Dev Agent 提交
+	Status      string           `json:"status"`            // passed, fixed, blocked, failed
+	Rounds      int              `json:"rounds"`            // 评审轮数，每轮修复后重新评审一次
+	FixRounds   int              `json:"fix_rounds"`        // Dev Agent 修复的轮数
+	Summary     string           `json:"summary,omitempty"` // 最后一轮评审的摘要
+	Error       string           `json:"error,omitempty"`   // 评审或修复失败的原因
+	Comments    []*ReviewComment `json:"comments"`          // 所有轮次的评审意见
+}
+
+// ReviewComment 一条代码评审意见
+type ReviewComment struct {
+	Round    int    `json:"round"`    // 第几轮评审，从 1 开始
+	File     string `json:"file"`     // 文件，相对项目根目录
+	Line     int    `json:"line"`     // 行号，不针对具体行时为 0
+	Severity string `json:"severity"` // critical, high, medium, low
+	Message  string `json:"message"`
+	Blocking bool   `json:"blocking"` // 是否为阻塞的严重程度，阻塞的意见交给 Dev Agent 修复
+}
+
+// GitHeadInfo 项目主干分支的最新提交
+type GitHeadInfo struct {
+	BaseBranch string `json:"base_branch"` // 主干分支
+	CommitSha  string `json:"commit_sha"`  // 主干分支的最新提交
+}
+
+// ProjectLockInfo 项目工作区锁的持有者
+type ProjectLockInfo struct {
+	ProjectGuid  string `json:"project_guid"`
+	TaskID       string `json:"task_id"`       // 持有锁的任务ID
+	TaskType     string `json:"task_type"`     // 持有锁的任务类型
+	FencingToken int64  `json:"fencing_token"` // 单调递增的 fencing token，写工作区前校验
+	AcquiredAt   string `json:"acquired_at"`
+}
+
+// WorkspaceInfo 项目工作区的磁盘占用和最近活动
+type WorkspaceInfo struct {
+	ProjectGuid    string   `json:"project_guid"`
+	Status         string   `json:"status"`                 // active: 在磁盘上；evicted: 已回收，再次使用时重新克隆
+	DiskUsageBytes int64    `json:"disk_usage_bytes"`       // 磁盘占用，已回收的工作区为 0
+	LastActiveAt   string   `json:"last_active_at"`         // 最近一次有任务使用工作区的时间
+	Locked         bool     `json:"locked"`                 // 是否有任务正在使用
+	PrunedPaths    []string `json:"pruned_paths,omitempty"` // 已清理、下次使用时重新安装的依赖目录
+}
+
+// WorkspaceListResp 项目工作区列表
+type WorkspaceListResp struct {
+	WorkspacePath   string           `json:"workspace_path"`
+	TotalBytes      int64            `json:"total_bytes"`       // 所有工作区的磁盘占用
+	DiskBudgetBytes int64            `json:"disk_budget_bytes"` // 磁盘预算，0 表示不限制
+	Workspaces      []*WorkspaceInfo `json:"workspaces"`        // 按最近活动倒序
+}
+
+// TaskQueueStats 异步任务队列的统计
+type TaskQueueStats struct {
+	Queue     string `json:"queue"`
+	Size      int    `json:"size"` // 未完成的任务数：pending、active、scheduled、retry、archived
+	Pending   int    `json:"pending"`
+	Active    int    `json:"active"`
+	Scheduled int    `json:"scheduled"`
+	Retry     int    `json:"retry"`
+	Archived  int    `json:"archived"`
+	Completed int    `json:"completed"`
+	Processed int    `json:"processed"` // 当天处理的任务数
+	Failed    int    `json:"failed"`    // 当天失败的任务数
+	Paused    bool   `json:"paused"`
+	Latency   string `json:"latency"` // 最早的 pending 任务已等待的时间
+}
+
+// TaskSummary 异步任务摘要，负载中的密钥已脱敏，过长的文本已截断
+type TaskSummary struct {
+	ID            string         `json:"id"`
+	Queue         string         `json:"queue"`
+	Type          string         `json:"type"`
+	State         string         `json:"state"` // pending、active、scheduled、retry、archived、completed
+	ProjectGuid   string         `json:"project_guid,omitempty"`
+	AgentType     string         `json:"agent_type,omitempty"`
+	DevStage      string         `json:"dev_stage,omitempty"`
+	StoryNumber   string         `json:"story_number,omitempty"`
+	Payload       map[string]any `json:"payload,omitempty"`
+	Retried       int            `json:"retried"`
+	MaxRetry      int            `json:"max_retry"`
+	LastError     string         `json:"last_error,omitempty"`
+	LastFailedAt  string         `json:"last_failed_at,omitempty"`
+	NextProcessAt string         `json:"next_process_at,omitempty"`
+	CompletedAt   string         `json:"completed_at,omitempty"`
+}
+
+// TaskListResp 异步任务列表
+type TaskListResp struct {
+	Queues    []*TaskQueueStats `json:"queues"`    // 所有队列的统计
+	Tasks     []*TaskSummary    `json:"tasks"`     // 符合筛选条件的任务，按队列、状态排列
+	Truncated bool              `json:"truncated"` // 符合条件的任务超过 limit，只返回了前 limit 个
+}
+
+// PromptTemplate 提示词模板
+type PromptTemplate struct {
+	Name        string `json:"name"`                   // 模板名称
+	Version     int    `json:"version"`                // 版本：内置模板为文件头中声明的版本，项目、用户模板每次保存加 1
+	Source      string `json:"source"`                 // 来源：builtin, project, user
+	Language    string `json:"language,omitempty"`     // 模板语言，如 zh-CN、en-US，项目模板为空
+	BaseVersion int    `json:"base_version,omitempty"` // 项目、用户模板保存时对应的内置模板版本，用于判断内置模板是否已更新
+	Content     string `json:"content"`                // text/template 模板内容
+	UpdatedAt   string `json:"updated_at,omitempty"`   // 项目、用户模板的更新时间
+}
+
+// RenderedPrompt 渲染后的提示词，记录在任务上
+type RenderedPrompt struct {
+	Name     string `json:"name"`     // 模板名称
+	Version  int    `json:"version"`  // 模板版本
+	Source   string `json:"source"`   // 模板来源：builtin, project
+	Language string `json:"language"` // 输出语言
+	Content  string `json:"content"`  // 渲染结果
+}
+
 // AgentTaskStatusMessage Agent 任务状态消息（用于 Redis Pub/Sub）
 type AgentTaskStatusMessage struct {
+	TaskID      string      `json:"task_id"`         // 任务ID
+	ProjectGuid string      `json:"project_guid"`    // 项目GUID
+	AgentType   string      `json:"agent_type"`      // Agent类型
+	Status      string      `json:"status"`          // 任务状态
+	DevStage    string      `json:"dev_stage"`       // 开发阶段：initializing, preparing, developing, testing, deploying, completed, failed
+	Message     string      `json:"message"`         // 状态消息
+	Timestamp   string      `json:"timestamp"`       // 时间戳
+	Usage       *AgentUsage `json:"usage,omitempty"` // CLI 用量，任务结束时上报
+}
+
+// AgentUsage 一次 CLI 调用的 token 和费用用量
+type AgentUsage struct {
+	CliTool                  string  `json:"cli_tool"`
+	InputTokens              int     `json:"input_tokens"`
+	OutputTokens             int     `json:"output_tokens"`
+	CacheCreationInputTokens int     `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int     `json:"cache_read_input_tokens"`
+	CostUsd                  float64 `json:"cost_usd"`
+	DurationMs               int     `json:"duration_ms"`
+	UsageUnknown             bool    `json:"usage_unknown,omitempty"` // CLI 不输出用量，token 和费用未知
+}
+
+func (a *AgentTaskStatusMessage) ToBytes() []byte {
+	bytes, err := json.Marshal(a)
+	if err != nil {
+		return nil
+	}
+	return bytes
+}
+
+// AgentLogMessage Agent 命令行输出日志（按行发布到 Redis Pub/Sub）
+type AgentLogMessage struct {
 	TaskID      string `json:"task_id"`      // 任务ID
 	ProjectGuid string `json:"project_guid"` // 项目GUID
 	AgentType   string `json:"agent_type"`   // Agent类型
-	Status      string `json:"status"`       // 任务状态
-	DevStage    string `json:"dev_stage"`    // 开发阶段：initializing, preparing, developing, testing, deploying, completed, failed
-	Message     string `json:"message"`      // 状态消息
+	DevStage    string `json:"dev_stage"`    // 开发阶段
+	Stream      string `json:"stream"`       // 输出流：stdout, stderr
+	Line        string `json:"line"`         // 日志行内容
+	Seq         int64  `json:"seq"`          // 行序号，从 1 开始
 	Timestamp   string `json:"timestamp"`    // 时间戳
 }
 
-func (a *AgentTaskStatusMessage) ToBytes() []byte {
+func (a *AgentLogMessage) ToBytes() []byte {
 	bytes, err := json.Marshal(a)
 	if err != nil {
 		return nil
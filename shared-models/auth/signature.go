@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lighthought/app-maker/shared-models/common"
+)
+
+// DefaultReplayWindow 签名时间与服务端时间允许的默认偏差，窗口内同一 nonce 只能使用一次
+const DefaultReplayWindow = 5 * time.Minute
+
+var (
+	ErrMissingSignature = errors.New("缺少服务签名")
+	ErrUnknownKey       = errors.New("未知的签名密钥")
+	ErrExpiredSignature = errors.New("签名时间超出允许范围")
+	ErrInvalidSignature = errors.New("服务签名无效")
+	ErrReplayedRequest  = errors.New("签名请求已被使用")
+)
+
+// ServiceKey 服务间调用的签名密钥
+type ServiceKey struct {
+	ID     string
+	Secret string
+}
+
+// ParseServiceKeys 解析 keyID:secret 格式的密钥列表，轮换密钥时同时配置新旧密钥
+func ParseServiceKeys(values []string) ([]ServiceKey, error) {
+	var keys []ServiceKey
+	for i, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		id, secret, ok := strings.Cut(value, ":")
+		if !ok || strings.TrimSpace(id) == "" || secret == "" {
+			// 不输出配置内容，避免密钥写入日志
+			return nil, fmt.Errorf("第 %d 个服务签名密钥格式应为 keyID:secret", i+1)
+		}
+		keys = append(keys, ServiceKey{ID: strings.TrimSpace(id), Secret: secret})
+	}
+	return keys, nil
+}
+
+// RequestSigner 服务间调用的请求签名：HMAC-SHA256(方法、路径和查询、时间、nonce、请求体摘要)
+type RequestSigner struct {
+	key ServiceKey
+	now func() time.Time
+}
+
+// NewRequestSigner 创建请求签名器
+func NewRequestSigner(keyID, secret string) *RequestSigner {
+	return &RequestSigner{key: ServiceKey{ID: keyID, Secret: secret}, now: time.Now}
+}
+
+// Sign 为请求设置签名请求头，body 为请求体，没有请求体时为 nil
+func (s *RequestSigner) Sign(req *http.Request, body []byte) error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("生成签名 nonce 失败: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(s.now().Unix(), 10)
+	nonceHex := hex.EncodeToString(nonce)
+	payload := signaturePayload(req.Method, req.URL.RequestURI(), timestamp, nonceHex, body)
+
+	req.Header.Set(common.HeaderServiceKeyID, s.key.ID)
+	req.Header.Set(common.HeaderServiceTimestamp, timestamp)
+	req.Header.Set(common.HeaderServiceNonce, nonceHex)
+	req.Header.Set(common.HeaderServiceSignature, computeSignature(s.key.Secret, payload))
+	return nil
+}
+
+// RequestVerifier 服务间调用的签名校验，接受配置的所有密钥，拒绝超出时间窗口和窗口内重复使用的请求
+type RequestVerifier struct {
+	keys   map[string]string
+	window time.Duration
+	now    func() time.Time
+
+	mu     sync.Mutex
+	nonces map[string]time.Time // keyID:nonce -> 过期时间
+}
+
+// NewRequestVerifier 创建签名校验器，window 不大于 0 时使用 DefaultReplayWindow
+func NewRequestVerifier(keys []ServiceKey, window time.Duration) *RequestVerifier {
+	if window <= 0 {
+		window = DefaultReplayWindow
+	}
+	verifier := &RequestVerifier{
+		keys:   make(map[string]string, len(keys)),
+		window: window,
+		now:    time.Now,
+		nonces: make(map[string]time.Time),
+	}
+	for _, key := range keys {
+		verifier.keys[key.ID] = key.Secret
+	}
+	return verifier
+}
+
+// Enabled 是否配置了签名密钥
+func (v *RequestVerifier) Enabled() bool {
+	return len(v.keys) > 0
+}
+
+// Verify 校验请求签名，body 为已读取的请求体，成功时返回签名使用的密钥ID
+func (v *RequestVerifier) Verify(req *http.Request, body []byte) (string, error) {
+	keyID := req.Header.Get(common.HeaderServiceKeyID)
+	timestamp := req.Header.Get(common.HeaderServiceTimestamp)
+	nonce := req.Header.Get(common.HeaderServiceNonce)
+	signature := req.Header.Get(common.HeaderServiceSignature)
+	if keyID == "" || timestamp == "" || nonce == "" || signature == "" {
+		return "", ErrMissingSignature
+	}
+
+	secret, ok := v.keys[keyID]
+	if !ok {
+		return "", ErrUnknownKey
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", ErrInvalidSignature
+	}
+	now := v.now()
+	signedAt := time.Unix(seconds, 0)
+	if signedAt.Before(now.Add(-v.window)) || signedAt.After(now.Add(v.window)) {
+		return "", ErrExpiredSignature
+	}
+
+	expected := computeSignature(secret, signaturePayload(req.Method, req.URL.RequestURI(), timestamp, nonce, body))
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return "", ErrInvalidSignature
+	}
+
+	// 签名正确后再记录 nonce，避免伪造的请求占满 nonce 缓存
+	if !v.useNonce(keyID+":"+nonce, signedAt.Add(v.window), now) {
+		return "", ErrReplayedRequest
+	}
+	return keyID, nil
+}
+
+// useNonce 记录 nonce 直到签名过期，已使用过时返回 false
+func (v *RequestVerifier) useNonce(key string, expireAt, now time.Time) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for nonce, expire := range v.nonces {
+		if expire.Before(now) {
+			delete(v.nonces, nonce)
+		}
+	}
+	if _, used := v.nonces[key]; used {
+		return false
+	}
+	v.nonces[key] = expireAt
+	return true
+}
+
+// signaturePayload 签名内容，每项一行，请求体使用 SHA-256 摘要
+func signaturePayload(method, requestURI, timestamp, nonce string, body []byte) string {
+	digest := sha256.Sum256(body)
+	return strings.Join([]string{method, requestURI, timestamp, nonce, hex.EncodeToString(digest[:])}, "\n")
+}
+
+// computeSignature 计算 HMAC-SHA256 签名，十六进制
+func computeSignature(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lighthought/app-maker/shared-models/common"
+)
+
+func newSignedRequest(t *testing.T, signer *RequestSigner, body string) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, "http://agents:8088/api/v1/agent/dev/implstory?async=true", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := signer.Sign(req, []byte(body)); err != nil {
+		t.Fatalf("Sign() err = %v", err)
+	}
+	return req
+}
+
+func TestRequestSignatureVerify(t *testing.T) {
+	now := time.Unix(1760000000, 0)
+	signer := NewRequestSigner("k2", "secret-2")
+	signer.now = func() time.Time { return now }
+	// 轮换期间新旧密钥同时有效
+	verifier := NewRequestVerifier([]ServiceKey{{ID: "k1", Secret: "secret-1"}, {ID: "k2", Secret: "secret-2"}}, time.Minute)
+	verifier.now = func() time.Time { return now.Add(30 * time.Second) }
+
+	body := `{"project_guid":"p1"}`
+	req := newSignedRequest(t, signer, body)
+	if keyID, err := verifier.Verify(req, []byte(body)); err != nil || keyID != "k2" {
+		t.Fatalf("Verify() = %q, %v", keyID, err)
+	}
+
+	// 同一签名请求不能重放
+	if _, err := verifier.Verify(req, []byte(body)); !errors.Is(err, ErrReplayedRequest) {
+		t.Errorf("replayed Verify() err = %v, want ErrReplayedRequest", err)
+	}
+
+	// 请求体被篡改
+	req = newSignedRequest(t, signer, body)
+	if _, err := verifier.Verify(req, []byte(`{"project_guid":"p2"}`)); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("tampered Verify() err = %v, want ErrInvalidSignature", err)
+	}
+
+	// 超出时间窗口
+	verifier.now = func() time.Time { return now.Add(2 * time.Minute) }
+	req = newSignedRequest(t, signer, body)
+	if _, err := verifier.Verify(req, []byte(body)); !errors.Is(err, ErrExpiredSignature) {
+		t.Errorf("expired Verify() err = %v, want ErrExpiredSignature", err)
+	}
+
+	// 已移除的密钥
+	verifier = NewRequestVerifier([]ServiceKey{{ID: "k3", Secret: "secret-3"}}, time.Minute)
+	verifier.now = func() time.Time { return now }
+	req = newSignedRequest(t, signer, body)
+	if _, err := verifier.Verify(req, []byte(body)); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("rotated Verify() err = %v, want ErrUnknownKey", err)
+	}
+
+	req.Header.Del(common.HeaderServiceSignature)
+	if _, err := verifier.Verify(req, []byte(body)); !errors.Is(err, ErrMissingSignature) {
+		t.Errorf("unsigned Verify() err = %v, want ErrMissingSignature", err)
+	}
+}
+
+func TestParseServiceKeys(t *testing.T) {
+	keys, err := ParseServiceKeys([]string{" k2:secret:with:colons ", "", "k1:secret-1"})
+	if err != nil {
+		t.Fatalf("ParseServiceKeys() err = %v", err)
+	}
+	if len(keys) != 2 || keys[0].ID != "k2" || keys[0].Secret != "secret:with:colons" || keys[1].ID != "k1" {
+		t.Errorf("keys = %+v", keys)
+	}
+
+	if _, err := ParseServiceKeys([]string{"only-a-secret"}); err == nil || strings.Contains(err.Error(), "only-a-secret") {
+		t.Errorf("ParseServiceKeys() err = %v, want error without the secret", err)
+	}
+}
@@ -22,12 +22,24 @@ type Cache interface {
 	SetMultiple(values map[string]interface{}, expiration time.Duration) error
 	DeleteMultiple(keys []string) error
 
+	// 列表操作
+	ListPush(key string, value interface{}, maxLen int64, expiration time.Duration) error
+	ListRange(key string, start, stop int64) ([]string, error)
+
+	// 分布式锁操作，value 按 JSON 序列化后比较
+	SetNX(key string, value interface{}, expiration time.Duration) (bool, error)
+	Incr(key string) (int64, error)
+	CompareAndDelete(key string, expected interface{}) (bool, error)
+	CompareAndExpire(key string, expected interface{}, expiration time.Duration) (bool, error)
+
 	// Pub/Sub 操作
 	Publish(channel string, message interface{}) error
 	Subscribe(channel string) *redis.PubSub
 
 	// 键管理
 	Keys(pattern string) ([]string, error)
+	// 使用 SCAN 增量遍历匹配模式的键，不会像 KEYS 一样阻塞 Redis
+	Scan(pattern string, count int64) ([]string, error)
 	Expire(key string, expiration time.Duration) error
 	TTL(key string) (time.Duration, error)
 
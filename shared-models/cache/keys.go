@@ -3,6 +3,8 @@ package cache
 import (
 	"fmt"
 	"strings"
+
+	"github.com/lighthought/app-maker/shared-models/common"
 )
 
 // User 用户相关缓存键
@@ -35,6 +37,61 @@ func GetProjectAgentSessionCacheKey(projectGuid, agentType string) string {
 	return GetProjectCacheKey(projectGuid, "sessions:"+agentType)
 }
 
+// ProjectAgentTranscript 项目 Agent 对话记录缓存键
+func GetProjectAgentTranscriptCacheKey(projectGuid, agentType string) string {
+	return GetProjectCacheKey(projectGuid, "transcripts:"+agentType)
+}
+
+// ProjectAgentLog 项目 Agent 命令行输出日志缓存键（环形缓冲区）
+func GetProjectAgentLogCacheKey(projectGuid string) string {
+	return GetProjectCacheKey(projectGuid, "agent_logs")
+}
+
+// ProjectModelConfig 项目模型配置缓存键
+func GetProjectModelConfigCacheKey(projectGuid string) string {
+	return GetProjectCacheKey(projectGuid, "model_config")
+}
+
+// ProjectWorkspaceLock 项目工作区锁缓存键
+func GetProjectWorkspaceLockCacheKey(projectGuid string) string {
+	return GetProjectCacheKey(projectGuid, "workspace_lock")
+}
+
+// ProjectWorkspaceFence 项目工作区锁 fencing token 计数器缓存键
+func GetProjectWorkspaceFenceCacheKey(projectGuid string) string {
+	return GetProjectCacheKey(projectGuid, "workspace_fence")
+}
+
+// ProjectWorkspace 项目工作区活动记录缓存键，工作区回收后保留用于重新克隆
+func GetProjectWorkspaceCacheKey(projectGuid string) string {
+	return GetProjectCacheKey(projectGuid, "workspace")
+}
+
+// ProjectMergeRequest 项目本地合并请求缓存键，按源分支保存最近一次
+func GetProjectMergeRequestCacheKey(projectGuid, sourceBranch string) string {
+	return GetProjectCacheKey(projectGuid, "merge_requests:"+sourceBranch)
+}
+
+// ProjectMergeRequestSeq 项目本地合并请求编号计数器缓存键
+func GetProjectMergeRequestSeqCacheKey(projectGuid string) string {
+	return GetProjectCacheKey(projectGuid, "merge_request_seq")
+}
+
+// AgentTaskLogChannel Agent 任务日志 Pub/Sub 频道
+func GetAgentTaskLogChannel(taskID string) string {
+	return BuildCacheKey(common.RedisPubSubChannelAgentLog, taskID)
+}
+
+// AgentTask Agent 任务相关缓存键
+func GetAgentTaskCacheKey(taskID string, suffix string) string {
+	return fmt.Sprintf("agent_task:%s:%s", taskID, suffix)
+}
+
+// AgentTaskCancelled Agent 任务取消标记缓存键
+func GetAgentTaskCancelledCacheKey(taskID string) string {
+	return GetAgentTaskCacheKey(taskID, "cancelled")
+}
+
 // RateLimit 限流相关缓存键
 func GetRateLimitCacheKey(clientIP string) string {
 	return fmt.Sprintf("rate_limit:%s", clientIP)
@@ -99,6 +99,91 @@ func (c *RedisCache) DeleteMultiple(keys []string) error {
 	return c.client.Del(c.ctx, keys...).Err()
 }
 
+// ListPush 追加到列表尾部，并只保留最后 maxLen 个元素
+func (c *RedisCache) ListPush(key string, value interface{}, maxLen int64, expiration time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to serialize value: %s", err.Error())
+	}
+
+	pipe := c.client.Pipeline()
+	pipe.RPush(c.ctx, key, data)
+	if maxLen > 0 {
+		pipe.LTrim(c.ctx, key, -maxLen, -1)
+	}
+	if expiration > 0 {
+		pipe.Expire(c.ctx, key, expiration)
+	}
+	_, err = pipe.Exec(c.ctx)
+	return err
+}
+
+// ListRange 获取列表指定范围的元素
+func (c *RedisCache) ListRange(key string, start, stop int64) ([]string, error) {
+	result, err := c.client.LRange(c.ctx, key, start, stop).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get list range: %s", err.Error())
+	}
+	return result, nil
+}
+
+// 值相等时删除键
+var compareAndDeleteScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// 值相等时设置过期时间
+var compareAndExpireScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+// SetNX 键不存在时设置缓存值，返回是否设置成功
+func (c *RedisCache) SetNX(key string, value interface{}, expiration time.Duration) (bool, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false, fmt.Errorf("failed to serialize value: %s", err.Error())
+	}
+
+	return c.client.SetNX(c.ctx, key, data, expiration).Result()
+}
+
+// Incr 整数值自增 1，返回自增后的值
+func (c *RedisCache) Incr(key string) (int64, error) {
+	return c.client.Incr(c.ctx, key).Result()
+}
+
+// CompareAndDelete 当前值与 expected 相等时删除键，返回是否删除
+func (c *RedisCache) CompareAndDelete(key string, expected interface{}) (bool, error) {
+	data, err := json.Marshal(expected)
+	if err != nil {
+		return false, fmt.Errorf("failed to serialize value: %s", err.Error())
+	}
+
+	result, err := compareAndDeleteScript.Run(c.ctx, c.client, []string{key}, data).Int()
+	if err != nil {
+		return false, err
+	}
+	return result > 0, nil
+}
+
+// CompareAndExpire 当前值与 expected 相等时设置过期时间，返回是否设置
+func (c *RedisCache) CompareAndExpire(key string, expected interface{}, expiration time.Duration) (bool, error) {
+	data, err := json.Marshal(expected)
+	if err != nil {
+		return false, fmt.Errorf("failed to serialize value: %s", err.Error())
+	}
+
+	result, err := compareAndExpireScript.Run(c.ctx, c.client, []string{key}, data, expiration.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return result > 0, nil
+}
+
 // 发布消息到 Redis Pub/Sub
 func (c *RedisCache) Publish(channel string, message interface{}) error {
 	return c.client.Publish(c.ctx, channel, message).Err()
@@ -114,6 +199,19 @@ func (c *RedisCache) Keys(pattern string) ([]string, error) {
 	return c.client.Keys(c.ctx, pattern).Result()
 }
 
+// Scan 使用 SCAN 增量遍历匹配模式的键，count 为每次遍历的提示数量
+func (c *RedisCache) Scan(pattern string, count int64) ([]string, error) {
+	var keys []string
+	iter := c.client.Scan(c.ctx, 0, pattern, count).Iterator()
+	for iter.Next(c.ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan keys: %s", err.Error())
+	}
+	return keys, nil
+}
+
 // Expire 设置键的过期时间
 func (c *RedisCache) Expire(key string, expiration time.Duration) error {
 	return c.client.Expire(c.ctx, key, expiration).Err()
@@ -4,9 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/auth"
 	"github.com/lighthought/app-maker/shared-models/common"
 	"github.com/lighthought/app-maker/shared-models/logger"
 	"github.com/lighthought/app-maker/shared-models/tasks"
@@ -14,7 +17,8 @@ import (
 
 // AgentClient Agent 服务客户端
 type AgentClient struct {
-	httpClient *HTTPClient
+	httpClient   *HTTPClient
+	taskRecorder func(ctx context.Context, taskID string) error
 }
 
 // NewAgentClient 创建 Agent 客户端
@@ -29,6 +33,16 @@ func (c *AgentClient) SetHeader(key, value string) {
 	c.httpClient.SetHeader(key, value)
 }
 
+// SetSigner 设置服务签名器，Agents 服务配置了签名密钥时必须设置
+func (c *AgentClient) SetSigner(signer *auth.RequestSigner) {
+	c.httpClient.SetSigner(signer)
+}
+
+// SetTaskRecorder 设置任务记录函数，异步接口创建任务后立即调用，记录失败时取消刚创建的任务
+func (c *AgentClient) SetTaskRecorder(recorder func(ctx context.Context, taskID string) error) {
+	c.taskRecorder = recorder
+}
+
 // parseResponseData 安全地解析响应数据到目标结构体
 func parseResponseData(resp *common.Response, target interface{}) error {
 	// 将 Data 转换为 JSON 字节
@@ -160,6 +174,15 @@ func (c *AgentClient) innerPost(ctx context.Context, url string, req interface{}
 	}
 
 	taskID := resp.Data.(string)
+	if c.taskRecorder != nil {
+		if err := c.taskRecorder(ctx, taskID); err != nil {
+			// 没有记录的任务无法跟踪和取消，直接取消
+			if cancelErr := c.CancelTask(ctx, taskID); cancelErr != nil {
+				logger.Warn("取消未记录的任务失败", logger.String("taskID", taskID), logger.String("error", cancelErr.Error()))
+			}
+			return "", fmt.Errorf("记录任务失败: %w", err)
+		}
+	}
 	return taskID, nil
 }
 
@@ -203,6 +226,11 @@ func (c *AgentClient) GetEpicsAndStories(ctx context.Context, req *agent.GetEpic
 	return c.innerPost(ctx, "/api/v1/agent/po/epicsandstories", req)
 }
 
+// DraftStory SM 为一个用户故事起草故事文件
+func (c *AgentClient) DraftStory(ctx context.Context, req *agent.DraftStoryReq) (string, error) {
+	return c.innerPost(ctx, "/api/v1/agent/sm/draft-story", req)
+}
+
 // ImplementStory 实现用户故事
 func (c *AgentClient) ImplementStory(ctx context.Context, req *agent.ImplementStoryReq) (string, error) {
 	return c.innerPost(ctx, "/api/v1/agent/dev/implstory", req)
@@ -223,7 +251,159 @@ func (c *AgentClient) Deploy(ctx context.Context, req *agent.DeployReq) (string,
 	return c.innerPost(ctx, "/api/v1/agent/dev/deploy", req)
 }
 
+// QAReview QA 按验收标准评审用户故事并写入门禁文件
+func (c *AgentClient) QAReview(ctx context.Context, req *agent.QAReviewReq) (string, error) {
+	return c.innerPost(ctx, "/api/v1/agent/qa/review", req)
+}
+
+// QATestPlan QA 生成测试计划
+func (c *AgentClient) QATestPlan(ctx context.Context, req *agent.QATestPlanReq) (string, error) {
+	return c.innerPost(ctx, "/api/v1/agent/qa/test-plan", req)
+}
+
 // ChatWithAgent 与 Agent 对话
 func (c *AgentClient) ChatWithAgent(ctx context.Context, req *agent.ChatReq) (string, error) {
 	return c.innerPost(ctx, "/api/v1/agent/chat", req)
 }
+
+// CancelTask 取消 Agent 任务
+func (c *AgentClient) CancelTask(ctx context.Context, taskID string) error {
+	resp, err := c.httpClient.Post(ctx, "/api/v1/tasks/"+taskID+"/cancel", nil)
+	if err != nil {
+		return err
+	}
+
+	if resp.Code != common.SUCCESS_CODE {
+		return fmt.Errorf("取消任务失败: %s", resp.Message)
+	}
+	return nil
+}
+
+// GetProjectLogs 获取项目最近的 CLI 输出日志，taskID 为空时返回项目下所有任务的日志
+func (c *AgentClient) GetProjectLogs(ctx context.Context, projectGuid, taskID string, limit int) ([]*agent.AgentLogMessage, error) {
+	query := url.Values{}
+	if taskID != "" {
+		query.Set("task_id", taskID)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	endpoint := "/api/v1/project/" + projectGuid + "/logs"
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	resp, err := c.httpClient.Get(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Code != common.SUCCESS_CODE {
+		return nil, fmt.Errorf("获取项目日志失败: %s", resp.Message)
+	}
+
+	var logs []*agent.AgentLogMessage
+	if err := parseResponseData(resp, &logs); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// UpdateModelConfig 更新项目模型配置，之后的 CLI 执行使用新的配置
+func (c *AgentClient) UpdateModelConfig(ctx context.Context, projectGuid string, req *agent.ProjectModelConfig) error {
+	resp, err := c.httpClient.Post(ctx, "/api/v1/project/"+projectGuid+"/model-config", req)
+	if err != nil {
+		return err
+	}
+
+	if resp.Code != common.SUCCESS_CODE {
+		return fmt.Errorf("更新项目模型配置失败: %s", resp.Message)
+	}
+	return nil
+}
+
+// DeleteModelConfig 删除项目模型配置，项目删除时调用，清除 agents 保存的 API Token
+func (c *AgentClient) DeleteModelConfig(ctx context.Context, projectGuid string) error {
+	resp, err := c.httpClient.Delete(ctx, "/api/v1/project/"+projectGuid+"/model-config")
+	if err != nil {
+		return err
+	}
+
+	if resp.Code != common.SUCCESS_CODE {
+		return fmt.Errorf("删除项目模型配置失败: %s", resp.Message)
+	}
+	return nil
+}
+
+// GetGitHead 获取项目主干分支的最新提交
+func (c *AgentClient) GetGitHead(ctx context.Context, projectGuid string) (*agent.GitHeadInfo, error) {
+	resp, err := c.httpClient.Get(ctx, "/api/v1/project/"+projectGuid+"/git/head")
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Code != common.SUCCESS_CODE {
+		return nil, fmt.Errorf("获取项目最新提交失败: %s", resp.Message)
+	}
+
+	var head agent.GitHeadInfo
+	if err := parseResponseData(resp, &head); err != nil {
+		return nil, err
+	}
+	return &head, nil
+}
+
+// RollbackGit 把项目主干分支硬重置到指定提交并强制推送，返回回滚后的主干提交
+func (c *AgentClient) RollbackGit(ctx context.Context, projectGuid string, req *agent.GitRollbackReq) (*agent.GitHeadInfo, error) {
+	resp, err := c.httpClient.Post(ctx, "/api/v1/project/"+projectGuid+"/git/rollback", req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Code != common.SUCCESS_CODE {
+		return nil, fmt.Errorf("回滚项目失败: %s", resp.Message)
+	}
+
+	var head agent.GitHeadInfo
+	if err := parseResponseData(resp, &head); err != nil {
+		return nil, err
+	}
+	return &head, nil
+}
+
+// ListSessions 获取项目 Agent 会话列表
+func (c *AgentClient) ListSessions(ctx context.Context, projectGuid string) ([]*agent.AgentSessionInfo, error) {
+	resp, err := c.httpClient.Get(ctx, "/api/v1/project/"+projectGuid+"/sessions")
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Code != common.SUCCESS_CODE {
+		return nil, fmt.Errorf("获取会话列表失败: %s", resp.Message)
+	}
+
+	var sessions []*agent.AgentSessionInfo
+	if err := parseResponseData(resp, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// ResetSession 重置项目 Agent 会话，agentType 为空时重置项目下所有 Agent 的会话
+func (c *AgentClient) ResetSession(ctx context.Context, projectGuid, agentType string) error {
+	endpoint := "/api/v1/project/" + projectGuid + "/sessions"
+	if agentType != "" {
+		endpoint += "?agent_type=" + url.QueryEscape(agentType)
+	}
+
+	resp, err := c.httpClient.Delete(ctx, endpoint)
+	if err != nil {
+		return err
+	}
+
+	if resp.Code != common.SUCCESS_CODE {
+		return fmt.Errorf("重置会话失败: %s", resp.Message)
+	}
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/lighthought/app-maker/shared-models/auth"
 	"github.com/lighthought/app-maker/shared-models/common"
 )
 
@@ -17,6 +18,7 @@ type HTTPClient struct {
 	baseURL    string
 	httpClient *http.Client
 	headers    map[string]string
+	signer     *auth.RequestSigner
 }
 
 // NewHTTPClient 创建新的 HTTP 客户端
@@ -35,6 +37,11 @@ func (c *HTTPClient) SetHeader(key, value string) {
 	c.headers[key] = value
 }
 
+// SetSigner 设置请求签名器，设置后每个请求都带上服务签名
+func (c *HTTPClient) SetSigner(signer *auth.RequestSigner) {
+	c.signer = signer
+}
+
 // Post 发送 POST 请求
 func (c *HTTPClient) Post(ctx context.Context, endpoint string, body interface{}) (*common.Response, error) {
 	return c.request(ctx, http.MethodPost, endpoint, body)
@@ -45,14 +52,20 @@ func (c *HTTPClient) Get(ctx context.Context, endpoint string) (*common.Response
 	return c.request(ctx, http.MethodGet, endpoint, nil)
 }
 
+// Delete 发送 DELETE 请求
+func (c *HTTPClient) Delete(ctx context.Context, endpoint string) (*common.Response, error) {
+	return c.request(ctx, http.MethodDelete, endpoint, nil)
+}
+
 // request 统一请求方法
 func (c *HTTPClient) request(ctx context.Context, method, endpoint string, body interface{}) (*common.Response, error) {
 	url := c.baseURL + endpoint
 
 	var reqBody io.Reader
+	var jsonData []byte
 	if body != nil {
-		jsonData, err := json.Marshal(body)
-		if err != nil {
+		var err error
+		if jsonData, err = json.Marshal(body); err != nil {
 			return nil, fmt.Errorf("序列化请求体失败: %w", err)
 		}
 		reqBody = bytes.NewReader(jsonData)
@@ -71,6 +84,12 @@ func (c *HTTPClient) request(ctx context.Context, method, endpoint string, body
 		req.Header.Set(key, value)
 	}
 
+	if c.signer != nil {
+		if err := c.signer.Sign(req, jsonData); err != nil {
+			return nil, err
+		}
+	}
+
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("发送请求失败: %w", err)
@@ -1,6 +1,7 @@
 package common
 
 import (
+	"strings"
 	"time"
 
 	"github.com/lighthought/app-maker/shared-models/agent"
@@ -40,6 +41,7 @@ const (
 	MESSAGE_USER_OR_PASSWORD_ERROR    = "用户不存在或密码错误"
 	MESSAGE_OLD_PASSWORD_ERROR        = "旧密码错误"
 	MESSAGE_AI_RESPONSE_FORMAT_ERROR  = "AI response format error"
+	MESSAGE_INSUFFICIENT_QUOTA        = "本月用量已超出预算"
 )
 
 // 用户状态
@@ -74,6 +76,14 @@ const (
 	DefaultApiPrefix   = "/api/v1"
 )
 
+// 服务间调用（如后端调用 Agents API）的签名请求头
+const (
+	HeaderServiceKeyID     = "X-Service-Key-Id"    // 签名密钥ID，用于密钥轮换
+	HeaderServiceTimestamp = "X-Service-Timestamp" // 签名时间，Unix 秒
+	HeaderServiceNonce     = "X-Service-Nonce"     // 随机数，重放窗口内只能使用一次
+	HeaderServiceSignature = "X-Service-Signature" // HMAC-SHA256 签名，十六进制
+)
+
 // 通用状态
 const (
 	CommonStatusPending    = "pending"
@@ -81,6 +91,7 @@ const (
 	CommonStatusDone       = "done"
 	CommonStatusFailed     = "failed"
 	CommonStatusPaused     = "paused"
+	CommonStatusCancelled  = "cancelled"
 )
 
 func GetProgressByCommonStatus(commandStatus string) int {
@@ -95,6 +106,8 @@ func GetProgressByCommonStatus(commandStatus string) int {
 		return 0
 	case CommonStatusPaused:
 		return 50
+	case CommonStatusCancelled:
+		return 0
 	default:
 		return 0
 	}
@@ -115,7 +128,10 @@ const (
 	DevStatusDefineDataModel    = DevStatus("define_data_model")   // 数据模型定义中
 	DevStatusDefineAPI          = DevStatus("define_api")          // API接口定义中
 	DevStatusGeneratePages      = DevStatus("generate_pages")      // 生成前端页面
+	DevStatusDraftStory         = DevStatus("draft_story")         // Story起草中
 	DevStatusDevelopStory       = DevStatus("develop_story")       // Story开发中
+	DevStatusCodeReview         = DevStatus("code_review")         // 评审 Dev 提交的代码，在 Story 开发任务内执行
+	DevStatusQAReview           = DevStatus("qa_review")           // QA 评审中
 	DevStatusFixBug             = DevStatus("fix_bug")             // 问题修复中
 	DevStatusRunTest            = DevStatus("run_test")            // 自动测试中
 	DevStatusDeploy             = DevStatus("deploy")              // 部署中
@@ -125,46 +141,63 @@ const (
 	DevStatusUnknown            = DevStatus("unknown")             // 未知
 )
 
-// 获取开发阶段描述
-func GetDevStageDescription(devStage DevStatus) string {
-	switch devStage {
-	case DevStatusInitializing:
-		return "等待开始开发"
-	case DevStatusSetupEnvironment:
-		return "正在初始化开发环境"
-	case DevStatusSetupAgents:
-		return "准备项目 Agents 环境"
-	case DevStatusCheckRequirement:
-		return "正在检查需求"
-	case DevStatusGeneratePRD:
-		return "正在生成PRD文档"
-	case DevStatusDefineUXStandard:
-		return "正在定义UX标准"
-	case DevStatusDesignArchitecture:
-		return "正在设计系统架构"
-	case DevStatusDefineDataModel:
-		return "正在定义数据模型"
-	case DevStatusDefineAPI:
-		return "正在定义API接口"
-	case DevStatusPlanEpicAndStory:
-		return "正在划分Epic和Story"
-	case DevStatusDevelopStory:
-		return "正在开发Story功能"
-	case DevStatusGeneratePages:
-		return "正在生成前端页面"
-	case DevStatusFixBug:
-		return "正在修复开发问题"
-	case DevStatusRunTest:
-		return "正在执行自动测试"
-	case DevStatusDeploy:
-		return "正在部署项目"
-	case DevStatusDone:
-		return "项目开发完成"
-	case DevStatusFailed:
-		return "项目开发失败"
-	default:
-		return "未知状态"
+// 开发阶段描述，按输出语言区分
+var devStageDescriptions = map[string]map[DevStatus]string{
+	LanguageZhCN: {
+		DevStatusInitializing:       "等待开始开发",
+		DevStatusSetupEnvironment:   "正在初始化开发环境",
+		DevStatusSetupAgents:        "准备项目 Agents 环境",
+		DevStatusCheckRequirement:   "正在检查需求",
+		DevStatusGeneratePRD:        "正在生成PRD文档",
+		DevStatusDefineUXStandard:   "正在定义UX标准",
+		DevStatusDesignArchitecture: "正在设计系统架构",
+		DevStatusDefineDataModel:    "正在定义数据模型",
+		DevStatusDefineAPI:          "正在定义API接口",
+		DevStatusPlanEpicAndStory:   "正在划分Epic和Story",
+		DevStatusDraftStory:         "正在起草Story开发文档",
+		DevStatusDevelopStory:       "正在开发Story功能",
+		DevStatusCodeReview:         "正在评审Story提交的代码",
+		DevStatusQAReview:           "正在按验收标准评审Story",
+		DevStatusGeneratePages:      "正在生成前端页面",
+		DevStatusFixBug:             "正在修复开发问题",
+		DevStatusRunTest:            "正在执行自动测试",
+		DevStatusDeploy:             "正在部署项目",
+		DevStatusDone:               "项目开发完成",
+		DevStatusFailed:             "项目开发失败",
+		DevStatusUnknown:            "未知状态",
+	},
+	LanguageEnUS: {
+		DevStatusInitializing:       "Waiting to start development",
+		DevStatusSetupEnvironment:   "Initializing the development environment",
+		DevStatusSetupAgents:        "Preparing the project agents environment",
+		DevStatusCheckRequirement:   "Checking requirements",
+		DevStatusGeneratePRD:        "Generating the PRD",
+		DevStatusDefineUXStandard:   "Defining the UX standard",
+		DevStatusDesignArchitecture: "Designing the system architecture",
+		DevStatusDefineDataModel:    "Defining the data model",
+		DevStatusDefineAPI:          "Defining the API",
+		DevStatusPlanEpicAndStory:   "Planning epics and stories",
+		DevStatusDraftStory:         "Drafting story files",
+		DevStatusDevelopStory:       "Developing stories",
+		DevStatusCodeReview:         "Reviewing the code committed for stories",
+		DevStatusQAReview:           "Reviewing stories against acceptance criteria",
+		DevStatusGeneratePages:      "Generating frontend pages",
+		DevStatusFixBug:             "Fixing development issues",
+		DevStatusRunTest:            "Running automated tests",
+		DevStatusDeploy:             "Deploying the project",
+		DevStatusDone:               "Project development completed",
+		DevStatusFailed:             "Project development failed",
+		DevStatusUnknown:            "Unknown status",
+	},
+}
+
+// 获取开发阶段描述，language 为项目输出语言，不支持的语言使用默认语言
+func GetDevStageDescription(devStage DevStatus, language string) string {
+	descriptions := devStageDescriptions[NormalizeLanguage(language)]
+	if description, ok := descriptions[devStage]; ok {
+		return description
 	}
+	return descriptions[DevStatusUnknown]
 }
 
 // 获取开发阶段进度
@@ -190,10 +223,14 @@ func GetDevStageProgress(devStage DevStatus) int {
 		return 40
 	case DevStatusPlanEpicAndStory:
 		return 45
-	case DevStatusDevelopStory:
+	case DevStatusDraftStory:
+		return 55
+	case DevStatusDevelopStory, DevStatusCodeReview:
 		return 60
 	case DevStatusGeneratePages:
 		return 65
+	case DevStatusQAReview:
+		return 70
 	case DevStatusFixBug:
 		return 75
 	case DevStatusRunTest:
@@ -278,6 +315,7 @@ const (
 	WebSocketMessageTypeProjectMessage     = "project_message"
 	WebSocketMessageTypeProjectInfoUpdate  = "project_info_update"
 	WebSocketMessageTypeAgentMessage       = "agent_message"
+	WebSocketMessageTypeAgentLog           = "agent_log"
 	//WebSocketMessageTypeUserFeedback         = "user_feedback"
 	WebSocketMessageTypeUserFeedbackResponse = "user_feedback_response"
 	WebSocketMessageTypeError                = "error"
@@ -286,24 +324,164 @@ const (
 // Redis Pub/Sub 频道常量
 const (
 	RedisPubSubChannelAgentTask = "agent:task:status" // Agent 任务状态频道
+	RedisPubSubChannelAgentLog  = "agent:task:log"    // Agent 任务日志频道前缀，实际频道为 agent:task:log:<taskID>
+)
+
+// Agent 命令行输出日志
+const (
+	AgentLogStreamStdout = "stdout" // 标准输出
+	AgentLogStreamStderr = "stderr" // 标准错误输出
+	AgentLogBufferSize   = 500      // 每个项目保留的最近日志行数（环形缓冲区大小）
+)
+
+// Agent 会话
+const (
+	AgentSessionModeNative     = "native"     // CLI 原生会话，通过会话ID恢复上下文
+	AgentSessionModeTranscript = "transcript" // 对话记录，把精简的历史拼接到提示词中
+	AgentSessionTranscriptSize = 20           // 每个 Agent 保留的对话轮数
+	AgentSessionHistoryTurns   = 5            // 拼接到提示词中的历史轮数
+	AgentSessionHistoryChars   = 600          // 历史中每段内容保留的最大字符数
+)
+
+// 提示词模板名称，与 shared-models/prompt/templates 下的文件名一致
+const (
+	PromptNameAnalyseProjectBrief   = "analyse_project_brief"  // 项目简介和市场研究
+	PromptNamePmPrd                 = "pm_prd"                 // PRD 文档
+	PromptNameUxStandard            = "ux_standard"            // UX 标准
+	PromptNameArchitectArchitecture = "architect_architecture" // 架构设计
+	PromptNameArchitectDatabase     = "architect_database"     // 数据模型设计
+	PromptNameArchitectAPI          = "architect_api"          // API 接口定义
+	PromptNamePoEpicsAndStories     = "po_epics_and_stories"   // Epics 和 Stories
+	PromptNameSMDraftStory          = "sm_draft_story"         // 起草用户故事文件
+	PromptNameDevImplementStory     = "dev_implement_story"    // 实现用户故事
+	PromptNameDevFixBug             = "dev_fix_bug"            // 修复 Bug
+	PromptNameDevRunTest            = "dev_run_test"           // 运行测试
+	PromptNameDevGeneratePages      = "dev_generate_pages"     // 生成前端页面
+	PromptNameQAReview              = "qa_review"              // QA 评审用户故事
+	PromptNameCodeReview            = "code_review"            // 评审 Dev Agent 的提交
+	PromptNameQATestPlan            = "qa_test_plan"           // QA 测试计划
+)
+
+// Git 分支工作流：每个阶段或故事在 appmaker/<stage>/<story-number> 分支上执行，构建和测试通过后合并回主干
+const (
+	GitBranchPrefix   = "appmaker" // 阶段、故事分支前缀
+	GitBranchAllStory = "all"      // 不区分故事的阶段使用的分支后缀
+	GitBranchChat     = "chat"     // 对话（没有开发阶段）使用的分支
+
+	GitProviderGitLab = "gitlab" // 通过 GitLab API 创建、合并合并请求
+	GitProviderLocal  = "local"  // 进程内记录合并请求，在工作区本地合并，离线可用
+
+	GitMergeStatusMerged       = "merged"        // 已合并回主干
+	GitMergeStatusNoChanges    = "no_changes"    // 没有变更，无需合并
+	GitMergeStatusVerifyFailed = "verify_failed" // 构建或测试失败，未合并
+	GitMergeStatusConflict     = "conflict"      // 合并冲突，未合并
+	GitMergeStatusFailed       = "failed"        // 提交、推送或合并失败
+
+	MergeRequestStateOpened = "opened"
+	MergeRequestStateMerged = "merged"
+)
+
+// 提交信息 trailer，把提交关联回开发阶段、任务、故事和 Agent
+const (
+	CommitTrailerStage = "App-Maker-Stage"
+	CommitTrailerTask  = "App-Maker-Task"
+	CommitTrailerStory = "App-Maker-Story"
+	CommitTrailerAgent = "App-Maker-Agent"
+)
+
+// 提示词模板来源
+const (
+	PromptSourceBuiltin = "builtin" // 内置模板
+	PromptSourceProject = "project" // 项目覆盖的模板
+	PromptSourceUser    = "user"    // 用户覆盖的模板，对该用户的所有项目生效
+)
+
+// 项目工作区状态
+const (
+	WorkspaceStatusActive  = "active"  // 工作区在磁盘上
+	WorkspaceStatusEvicted = "evicted" // 闲置已回收，再次使用时重新克隆
+)
+
+// 部署环境
+const (
+	DeployEnvironmentDev     = "dev"
+	DeployEnvironmentStaging = "staging"
+	DeployEnvironmentProd    = "prod"
+)
+
+// 自动测试报告、测试用例的状态
+const (
+	TestStatusPassed  = "passed"
+	TestStatusFailed  = "failed"
+	TestStatusSkipped = "skipped"
+)
+
+// QA 门禁结论，fail 时阻止部署
+const (
+	QAGatePass     = "pass"     // 满足验收标准
+	QAGateConcerns = "concerns" // 有问题但不阻止部署
+	QAGateFail     = "fail"     // 未满足验收标准，阻止部署
+)
+
+// 代码评审意见的严重程度，从高到低
+const (
+	ReviewSeverityCritical = "critical" // 安全漏洞、数据丢失、功能不可用
+	ReviewSeverityHigh     = "high"     // 逻辑错误、未处理的错误、与验收标准不符
+	ReviewSeverityMedium   = "medium"   // 缺少测试、边界情况、性能问题
+	ReviewSeverityLow      = "low"      // 命名、风格、可读性
+)
+
+// ReviewSeverities 所有代码评审严重程度，从高到低
+var ReviewSeverities = []string{ReviewSeverityCritical, ReviewSeverityHigh, ReviewSeverityMedium, ReviewSeverityLow}
+
+// 代码评审结论
+const (
+	CodeReviewStatusPassed  = "passed"  // 没有阻塞的评审意见
+	CodeReviewStatusFixed   = "fixed"   // 阻塞的评审意见已由 Dev Agent 修复
+	CodeReviewStatusBlocked = "blocked" // 修复轮数用完后仍有阻塞的评审意见
+	CodeReviewStatusFailed  = "failed"  // 评审或修复执行失败
+)
+
+// 部署后组件的健康状态
+const (
+	DeployHealthHealthy   = "healthy"   // 健康检查通过
+	DeployHealthUnhealthy = "unhealthy" // 超时仍未通过健康检查
+	DeployHealthUnchecked = "unchecked" // 组件没有声明健康检查地址和端口
+)
+
+// 项目工作区锁
+const (
+	ProjectLockTTL             = time.Minute      // 锁的过期时间，持有期间定时续期
+	ProjectLockRefreshInterval = 20 * time.Second // 续期间隔
+	ProjectLockRetryDelay      = 15 * time.Second // 未拿到锁的任务重新排队的延迟
 )
 
 // 任务类型常量
 const (
-	TaskTypeProjectDownload    = "project:download" // 下载项目
-	TaskTypeProjectBackup      = "project:backup"   // 备份项目
-	TaskTypeProjectInit        = "project:init"     // 初始化项目
-	TaskTypeProjectDeploy      = "project:deploy"   // 部署项目
-	TaskTypeWebSocketBroadcast = "ws:broadcast"     // WebSocket 消息广播
-	TaskTypeAgentExecute       = "agent:execute"    // 代理执行任务
-	TaskTypeAgentSetup         = "agent:setup"      // 项目环境准备任务
-	TaskTypeAgentChat          = "agent:chat"       // 与 Agent 对话任务
+	TaskTypeProjectDownload    = "project:download"  // 下载项目
+	TaskTypeProjectBackup      = "project:backup"    // 备份项目
+	TaskTypeProjectInit        = "project:init"      // 初始化项目
+	TaskTypeProjectDeploy      = "project:deploy"    // 部署项目
+	TaskTypeProjectTest        = "project:test"      // 执行项目的测试命令并生成测试报告
+	TaskTypeWebSocketBroadcast = "ws:broadcast"      // WebSocket 消息广播
+	TaskTypeAgentExecute       = "agent:execute"     // 代理执行任务
+	TaskTypeAgentSetup         = "agent:setup"       // 项目环境准备任务
+	TaskTypeAgentChat          = "agent:chat"        // 与 Agent 对话任务
+	TaskTypeProjectRollback    = "project:rollback"  // 回滚项目到阶段执行前的提交
+	TaskTypeWorkspaceCleanup   = "workspace:cleanup" // 回收闲置的项目工作区
 
 	// 项目开发阶段任务类型
 	TaskTypeProjectStage      = "project:stage"       // 项目开发阶段任务
 	TaskTypeAgentTaskResponse = "agent_task:response" // Agent 任务响应消息任务 (agent->pub -> backend(sub) -> stage_service)
 )
 
+// 任务队列名称
+const (
+	TaskQueueNameCritical = "critical"
+	TaskQueueNameDefault  = "default"
+	TaskQueueNameLow      = "low"
+)
+
 // 任务优先级
 const (
 	TaskQueueCritical = 6 // 高优先级
@@ -332,6 +510,7 @@ const (
 	CliToolClaudeCode = "claude-code"
 	CliToolQwenCode   = "qwen-code"
 	CliToolGemini     = "gemini"
+	CliToolMock       = "mock" // 离线调试用，回放剧本，不调用大模型
 )
 
 // 模型提供商类型
@@ -361,11 +540,35 @@ var DefaultAPIUrlByProvider = map[string]string{
 	ModelProviderVLLM:      "http://localhost:8000",
 }
 
+// 项目输出语言，决定提示词、文档、阶段描述和系统消息使用的语言
+const (
+	LanguageZhCN    = "zh-CN"
+	LanguageEnUS    = "en-US"
+	DefaultLanguage = LanguageZhCN
+)
+
+// 支持的输出语言列表
+var SupportedLanguages = []string{
+	LanguageZhCN,
+	LanguageEnUS,
+}
+
+// NormalizeLanguage 规范化输出语言，为空或不支持时返回默认语言
+func NormalizeLanguage(language string) string {
+	for _, supported := range SupportedLanguages {
+		if strings.EqualFold(language, supported) {
+			return supported
+		}
+	}
+	return DefaultLanguage
+}
+
 // 支持的 CLI 工具列表
 var SupportedCliTools = []string{
 	CliToolClaudeCode,
 	CliToolQwenCode,
 	CliToolGemini,
+	CliToolMock,
 }
 
 // 支持的模型提供商列表
@@ -0,0 +1,22 @@
+package common
+
+import "testing"
+
+func TestNormalizeLanguage(t *testing.T) {
+	tests := []struct {
+		language string
+		want     string
+	}{
+		{language: "", want: DefaultLanguage},
+		{language: "zh-CN", want: LanguageZhCN},
+		{language: "en-US", want: LanguageEnUS},
+		{language: "EN-us", want: LanguageEnUS},
+		{language: "en", want: DefaultLanguage},
+		{language: "fr-FR", want: DefaultLanguage},
+	}
+	for _, tt := range tests {
+		if got := NormalizeLanguage(tt.language); got != tt.want {
+			t.Errorf("NormalizeLanguage(%q) = %q, want %q", tt.language, got, tt.want)
+		}
+	}
+}
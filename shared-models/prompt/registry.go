@@ -0,0 +1,230 @@
+package prompt
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/common"
+	"github.com/lighthought/app-maker/shared-models/logger"
+)
+
+//go:embed templates/*.tmpl
+var builtinTemplates embed.FS
+
+const templateExt = ".tmpl"
+
+// 模板文件头中声明的版本，如 {{- /* version: 2 */ -}}
+var versionPattern = regexp.MustCompile(`^\{\{-?\s*/\*\s*version:\s*(\d+)\s*\*/\s*-?\}\}`)
+
+// Registry 提示词模板注册表：内置模板随代码发布，模板目录下的同名文件覆盖内置模板，
+// 项目或用户覆盖的模板由调用方传入，渲染失败时回退到内置模板。
+// 模板文件名为 <name>.tmpl（默认语言）或 <name>.<language>.tmpl，如 pm_prd.en-US.tmpl
+type Registry interface {
+	// 渲染提示词，override 为空或名称不一致时使用对应语言的内置模板
+	Render(name, language string, override *agent.PromptTemplate, data interface{}) (*agent.RenderedPrompt, error)
+
+	// 获取内置模板，指定语言没有模板时使用默认语言的模板
+	Get(name, language string) (*agent.PromptTemplate, error)
+
+	// 获取指定语言的所有内置模板，按名称排序
+	List(language string) []*agent.PromptTemplate
+
+	// 校验模板语法
+	Validate(name, content string) error
+}
+
+type registry struct {
+	templates map[string]*agent.PromptTemplate // key 为 templateKey(name, language)，创建后只读
+}
+
+// NewRegistry 创建提示词模板注册表，templatesPath 为自定义模板目录，为空时只使用内置模板
+func NewRegistry(templatesPath string) Registry {
+	r := &registry{templates: make(map[string]*agent.PromptTemplate)}
+
+	entries, err := builtinTemplates.ReadDir("templates")
+	if err != nil {
+		logger.Error("读取内置提示词模板失败", logger.String("error", err.Error()))
+	}
+	for _, entry := range entries {
+		data, err := builtinTemplates.ReadFile("templates/" + entry.Name())
+		if err != nil {
+			logger.Error("读取内置提示词模板失败", logger.String("file", entry.Name()), logger.String("error", err.Error()))
+			continue
+		}
+		r.add(entry.Name(), string(data))
+	}
+
+	if templatesPath != "" {
+		r.loadDir(templatesPath)
+	}
+	return r
+}
+
+// loadDir 加载自定义模板目录，同名模板覆盖内置模板
+func (r *registry) loadDir(templatesPath string) {
+	files, err := filepath.Glob(filepath.Join(templatesPath, "*"+templateExt))
+	if err != nil {
+		logger.Warn("读取提示词模板目录失败", logger.String("path", templatesPath), logger.String("error", err.Error()))
+		return
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			logger.Warn("读取提示词模板失败", logger.String("file", file), logger.String("error", err.Error()))
+			continue
+		}
+		name, _ := splitFileName(filepath.Base(file))
+		if err := r.Validate(name, string(data)); err != nil {
+			logger.Warn("提示词模板语法错误，使用内置模板", logger.String("file", file), logger.String("error", err.Error()))
+			continue
+		}
+		r.add(filepath.Base(file), string(data))
+		logger.Info("加载自定义提示词模板", logger.String("name", name), logger.String("file", file))
+	}
+}
+
+func (r *registry) add(fileName, content string) {
+	content = normalize(content)
+	name, language := splitFileName(fileName)
+	r.templates[templateKey(name, language)] = &agent.PromptTemplate{
+		Name:     name,
+		Version:  parseVersion(content),
+		Source:   common.PromptSourceBuiltin,
+		Language: language,
+		Content:  content,
+	}
+}
+
+// Render 渲染提示词
+func (r *registry) Render(name, language string, override *agent.PromptTemplate, data interface{}) (*agent.RenderedPrompt, error) {
+	language = common.NormalizeLanguage(language)
+	if override != nil && override.Name == name && override.Content != "" {
+		content, err := execute(name, override.Content, data)
+		if err == nil {
+			return &agent.RenderedPrompt{
+				Name:     name,
+				Version:  override.Version,
+				Source:   override.Source,
+				Language: language,
+				Content:  content,
+			}, nil
+		}
+		logger.Warn("渲染项目提示词模板失败，使用内置模板",
+			logger.String("name", name),
+			logger.Int("version", override.Version),
+			logger.String("error", err.Error()))
+	}
+
+	builtin, err := r.Get(name, language)
+	if err != nil {
+		return nil, err
+	}
+	content, err := execute(name, builtin.Content, data)
+	if err != nil {
+		return nil, err
+	}
+	return &agent.RenderedPrompt{
+		Name:     name,
+		Version:  builtin.Version,
+		Source:   builtin.Source,
+		Language: builtin.Language,
+		Content:  content,
+	}, nil
+}
+
+// Get 获取内置模板
+func (r *registry) Get(name, language string) (*agent.PromptTemplate, error) {
+	tmpl, ok := r.templates[templateKey(name, common.NormalizeLanguage(language))]
+	if !ok {
+		tmpl, ok = r.templates[templateKey(name, common.DefaultLanguage)]
+	}
+	if !ok {
+		return nil, fmt.Errorf("提示词模板 %s 不存在", name)
+	}
+	copied := *tmpl
+	return &copied, nil
+}
+
+// List 获取指定语言的所有内置模板
+func (r *registry) List(language string) []*agent.PromptTemplate {
+	list := make([]*agent.PromptTemplate, 0, len(r.templates))
+	for _, tmpl := range r.templates {
+		if tmpl.Language != common.DefaultLanguage {
+			continue
+		}
+		if localized, err := r.Get(tmpl.Name, language); err == nil {
+			list = append(list, localized)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Validate 校验模板语法
+func (r *registry) Validate(name, content string) error {
+	if strings.TrimSpace(content) == "" {
+		return fmt.Errorf("提示词模板 %s 内容为空", name)
+	}
+	if _, err := template.New(name).Parse(normalize(content)); err != nil {
+		return fmt.Errorf("解析提示词模板 %s 失败: %w", name, err)
+	}
+	return nil
+}
+
+// splitFileName 从模板文件名中解析模板名称和语言，如 pm_prd.en-US.tmpl -> pm_prd, en-US
+func splitFileName(fileName string) (string, string) {
+	base := strings.TrimSuffix(fileName, templateExt)
+	if index := strings.LastIndex(base, "."); index > 0 {
+		language := base[index+1:]
+		// 不支持的语言保留原样，不会覆盖默认语言的模板
+		if normalized := common.NormalizeLanguage(language); strings.EqualFold(normalized, language) {
+			language = normalized
+		}
+		return base[:index], language
+	}
+	return base, common.DefaultLanguage
+}
+
+func templateKey(name, language string) string {
+	return name + "." + language
+}
+
+// parseVersion 解析模板文件头中声明的版本，未声明时为 1
+func parseVersion(content string) int {
+	matches := versionPattern.FindStringSubmatch(strings.TrimSpace(content))
+	if len(matches) < 2 {
+		return 1
+	}
+	version, err := strconv.Atoi(matches[1])
+	if err != nil || version <= 0 {
+		return 1
+	}
+	return version
+}
+
+// execute 执行模板，结果去掉首尾空白
+func execute(name, content string, data interface{}) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(normalize(content))
+	if err != nil {
+		return "", fmt.Errorf("解析提示词模板 %s 失败: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("渲染提示词模板 %s 失败: %w", name, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// normalize 统一换行符，避免 Windows 下编辑的模板把 \r 带进提示词
+func normalize(content string) string {
+	return strings.ReplaceAll(content, "\r\n", "\n")
+}
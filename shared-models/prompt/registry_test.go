@@ -0,0 +1,104 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lighthought/app-maker/shared-models/agent"
+	"github.com/lighthought/app-maker/shared-models/common"
+)
+
+func TestSplitFileName(t *testing.T) {
+	tests := []struct {
+		fileName     string
+		wantName     string
+		wantLanguage string
+	}{
+		{fileName: "pm_prd.tmpl", wantName: "pm_prd", wantLanguage: common.DefaultLanguage},
+		{fileName: "pm_prd.en-US.tmpl", wantName: "pm_prd", wantLanguage: "en-US"},
+		{fileName: "pm_prd.en-us.tmpl", wantName: "pm_prd", wantLanguage: "en-US"},
+		{fileName: "pm_prd.fr-FR.tmpl", wantName: "pm_prd", wantLanguage: "fr-FR"},
+		{fileName: ".tmpl", wantName: "", wantLanguage: common.DefaultLanguage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fileName, func(t *testing.T) {
+			name, language := splitFileName(tt.fileName)
+			if name != tt.wantName || language != tt.wantLanguage {
+				t.Errorf("splitFileName(%q) = %q, %q, want %q, %q", tt.fileName, name, language, tt.wantName, tt.wantLanguage)
+			}
+		})
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		content string
+		want    int
+	}{
+		{content: "{{- /* version: 3 */ -}}\nhello", want: 3},
+		{content: "  {{/* version: 2 */}}\nhello", want: 2},
+		{content: "{{- /* version: 0 */ -}}", want: 1},
+		{content: "hello", want: 1},
+	}
+	for _, tt := range tests {
+		if got := parseVersion(tt.content); got != tt.want {
+			t.Errorf("parseVersion(%q) = %d, want %d", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestRegistryLoadDirAndRender(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"custom.tmpl":       "{{- /* version: 2 */ -}}\n你好 {{.Name}}",
+		"custom.en-US.tmpl": "Hello {{.Name}}",
+		"broken.tmpl":       "{{ .Name",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	registry := NewRegistry(dir)
+	data := map[string]string{"Name": "app"}
+
+	if _, err := registry.Get("broken", common.DefaultLanguage); err == nil {
+		t.Error("template with syntax error should not be loaded")
+	}
+
+	tests := []struct {
+		name        string
+		language    string
+		override    *agent.PromptTemplate
+		wantContent string
+		wantSource  string
+		wantVersion int
+	}{
+		{name: "default language", language: "", wantContent: "你好 app", wantSource: common.PromptSourceBuiltin, wantVersion: 2},
+		{name: "localized", language: "en-US", wantContent: "Hello app", wantSource: common.PromptSourceBuiltin, wantVersion: 1},
+		{
+			name:        "user override",
+			language:    "en-US",
+			override:    &agent.PromptTemplate{Name: "custom", Version: 4, Source: common.PromptSourceUser, Content: "Hi {{.Name}}"},
+			wantContent: "Hi app", wantSource: common.PromptSourceUser, wantVersion: 4,
+		},
+		{
+			name:        "broken override falls back to builtin",
+			language:    "en-US",
+			override:    &agent.PromptTemplate{Name: "custom", Version: 5, Source: common.PromptSourceProject, Content: "{{.Missing}}"},
+			wantContent: "Hello app", wantSource: common.PromptSourceBuiltin, wantVersion: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rendered, err := registry.Render("custom", tt.language, tt.override, data)
+			if err != nil {
+				t.Fatalf("Render() err = %v", err)
+			}
+			if rendered.Content != tt.wantContent || rendered.Source != tt.wantSource || rendered.Version != tt.wantVersion {
+				t.Errorf("Render() = %+v", rendered)
+			}
+		})
+	}
+}
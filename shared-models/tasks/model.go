@@ -3,6 +3,7 @@ package tasks
 import (
 	"encoding/json"
 
+	"github.com/lighthought/app-maker/shared-models/agent"
 	"github.com/lighthought/app-maker/shared-models/common"
 )
 
@@ -13,6 +14,16 @@ type TaskResult struct {
 	Progress  int    `json:"progress"` // 百分比
 	Message   string `json:"message"`
 	UpdatedAt string `json:"updated_at"`
+
+	LockHolder *agent.ProjectLockInfo `json:"lock_holder,omitempty"` // 项目工作区锁的当前持有者，仅在查询任务状态时返回
+	Prompt     *agent.RenderedPrompt  `json:"prompt,omitempty"`      // 任务使用的提示词，仅在查询任务状态时返回
+	Git        *agent.GitBranchResult `json:"git,omitempty"`         // 阶段、故事分支的提交和合并结果
+
+	FixAttempts []*agent.FixAttempt `json:"fix_attempts,omitempty"` // 部署命令失败后 Dev Agent 的修复记录
+	Deploy      *agent.DeployResp   `json:"deploy,omitempty"`       // 部署后各组件的健康检查结果和访问地址
+	TestReport  *agent.TestReport   `json:"test_report,omitempty"`  // 自动测试报告
+	QAGate      *agent.QAGate       `json:"qa_gate,omitempty"`      // QA 评审故事的门禁结论
+	CodeReview  *agent.CodeReview   `json:"code_review,omitempty"`  // Dev Agent 提交的代码评审结果
 }
 
 func (t *TaskResult) ToBytes() []byte {
@@ -23,6 +34,20 @@ func (t *TaskResult) ToBytes() []byte {
 	return bytes
 }
 
+// ProjectTestTaskPayload 项目自动测试任务的负载
+type ProjectTestTaskPayload struct {
+	agent.RunTestReq
+	FixPrompt string `json:"fix_prompt"` // 测试失败时交给 Dev Agent 的修复要求，由 dev_run_test 提示词模板渲染
+}
+
+func (p *ProjectTestTaskPayload) ToBytes() []byte {
+	bytes, err := json.Marshal(p)
+	if err != nil {
+		return nil
+	}
+	return bytes
+}
+
 // 只有项目ID的负载
 type ProjectTaskPayload struct {
 	ProjectID   string `json:"project_id"`
@@ -73,11 +98,15 @@ func (p *WebSocketTaskPayload) ToBytes() []byte {
 
 // 代理执行任务负载
 type AgentExecuteTaskPayload struct {
-	ProjectGUID string           `json:"project_guid"`
-	AgentType   string           `json:"agent_type"`
-	Message     string           `json:"message"`
-	DevStage    common.DevStatus `json:"dev_stage"`
-	CliTool     string           `json:"cli_tool"`
+	ProjectGUID string                  `json:"project_guid"`
+	AgentType   string                  `json:"agent_type"`
+	Message     string                  `json:"message"`
+	DevStage    common.DevStatus        `json:"dev_stage"`
+	CliTool     string                  `json:"cli_tool"`
+	Prompt      *agent.RenderedPrompt   `json:"prompt,omitempty"`       // 渲染 Message 的提示词模板
+	Language    string                  `json:"language,omitempty"`     // 输出语言
+	StoryNumber string                  `json:"story_number,omitempty"` // 故事编号，用于命名故事分支
+	Review      *agent.CodeReviewConfig `json:"review,omitempty"`       // 代码评审配置，为空时不评审本次提交
 }
 
 func (a *AgentExecuteTaskPayload) ToBytes() []byte {
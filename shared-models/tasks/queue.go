@@ -0,0 +1,52 @@
+package tasks
+
+import (
+	"fmt"
+
+	"github.com/lighthought/app-maker/shared-models/common"
+
+	"github.com/hibiken/asynq"
+)
+
+// TaskQueueNames 所有任务队列，按优先级从高到低
+var TaskQueueNames = []string{common.TaskQueueNameCritical, common.TaskQueueNameDefault, common.TaskQueueNameLow}
+
+// 按任务类型选择队列，未列出的任务类型进入默认队列
+// 用户对话和 WebSocket 推送需要及时响应，不能排在耗时几十分钟的开发阶段任务后面；
+// 备份、下载、部署不影响开发进度，放到低优先级队列
+var taskTypeQueues = map[string]string{
+	common.TaskTypeAgentChat:          common.TaskQueueNameCritical,
+	common.TaskTypeWebSocketBroadcast: common.TaskQueueNameCritical,
+	common.TaskTypeAgentTaskResponse:  common.TaskQueueNameCritical,
+
+	common.TaskTypeProjectInit:  common.TaskQueueNameDefault,
+	common.TaskTypeProjectStage: common.TaskQueueNameDefault,
+	common.TaskTypeAgentExecute: common.TaskQueueNameDefault,
+	common.TaskTypeAgentSetup:   common.TaskQueueNameDefault,
+	common.TaskTypeProjectTest:  common.TaskQueueNameDefault,
+
+	common.TaskTypeProjectBackup:   common.TaskQueueNameLow,
+	common.TaskTypeProjectDownload: common.TaskQueueNameLow,
+	common.TaskTypeProjectDeploy:   common.TaskQueueNameLow,
+}
+
+// GetTaskQueue 获取任务类型对应的队列
+func GetTaskQueue(taskType string) string {
+	if queue, ok := taskTypeQueues[taskType]; ok {
+		return queue
+	}
+	return common.TaskQueueNameDefault
+}
+
+// FindTaskInQueues 在所有队列中查找任务，包括保留期内已完成的任务
+func FindTaskInQueues(inspector *asynq.Inspector, taskID string) (*asynq.TaskInfo, error) {
+	var lastErr error
+	for _, queue := range TaskQueueNames {
+		info, err := inspector.GetTaskInfo(queue, taskID)
+		if err == nil {
+			return info, nil
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("任务不存在: %w", lastErr)
+}
@@ -13,7 +13,6 @@ import (
 )
 
 const (
-	taskQueueDefault  = "default"
 	taskMaxRetry      = 1
 	taskRetentionHour = 4 * time.Hour
 )
@@ -27,7 +26,7 @@ func NewProjectDownloadTask(projectID, projectGuid, projectPath string) *asynq.T
 	}
 	return asynq.NewTask(common.TaskTypeProjectDownload,
 		payload.ToBytes(),
-		asynq.Queue(taskQueueDefault),
+		asynq.Queue(GetTaskQueue(common.TaskTypeProjectDownload)),
 		asynq.MaxRetry(taskMaxRetry),
 		asynq.Retention(taskRetentionHour))
 }
@@ -42,7 +41,7 @@ func NewProjectBackupTask(projectID, projectGuid, projectPath string) *asynq.Tas
 
 	return asynq.NewTask(common.TaskTypeProjectBackup,
 		payload.ToBytes(),
-		asynq.Queue(taskQueueDefault),
+		asynq.Queue(GetTaskQueue(common.TaskTypeProjectBackup)),
 		asynq.MaxRetry(taskMaxRetry),
 		asynq.Retention(taskRetentionHour))
 }
@@ -56,7 +55,7 @@ func NewProjectInitTask(projectID, projectGuid, projectPath string) *asynq.Task
 	}
 	return asynq.NewTask(common.TaskTypeProjectInit,
 		payload.ToBytes(),
-		asynq.Queue(taskQueueDefault),
+		asynq.Queue(GetTaskQueue(common.TaskTypeProjectInit)),
 		asynq.MaxRetry(taskMaxRetry),
 		asynq.Retention(taskRetentionHour))
 }
@@ -79,7 +78,7 @@ func NewWebSocketBroadcastTask(projectGUID, messageType, targetID string) *asynq
 
 	return asynq.NewTask(common.TaskTypeWebSocketBroadcast,
 		payload.ToBytes(),
-		asynq.Queue(taskQueueDefault),
+		asynq.Queue(GetTaskQueue(common.TaskTypeWebSocketBroadcast)),
 		asynq.MaxRetry(taskMaxRetry),
 		asynq.Retention(taskRetentionHour))
 }
@@ -94,23 +93,39 @@ func NewAgentExecuteTask(projectGUID, agentType, message string, stageName commo
 	}
 	return asynq.NewTask(common.TaskTypeAgentExecute,
 		payload.ToBytes(),
-		asynq.Queue(taskQueueDefault),
+		asynq.Queue(GetTaskQueue(common.TaskTypeAgentExecute)),
 		asynq.MaxRetry(taskMaxRetry),
 		asynq.Retention(taskRetentionHour))
 }
 
-// 创建带CLI工具的代理执行任务
-func NewAgentExecuteTaskWithCli(projectGUID, agentType, message, cliTool string, stageName common.DevStatus) *asynq.Task {
+// 创建带CLI工具的代理执行任务，消息为渲染后的提示词
+func NewAgentExecuteTaskWithCli(projectGUID, agentType string, prompt *agent.RenderedPrompt, cliTool string, stageName common.DevStatus) *asynq.Task {
+	return NewAgentStoryTaskWithCli(projectGUID, agentType, prompt, cliTool, stageName, "")
+}
+
+// 创建实现指定故事的代理执行任务，storyNumber 用于命名故事分支
+func NewAgentStoryTaskWithCli(projectGUID, agentType string, prompt *agent.RenderedPrompt, cliTool string, stageName common.DevStatus,
+	storyNumber string) *asynq.Task {
+	return NewAgentStoryTaskWithReview(projectGUID, agentType, prompt, cliTool, stageName, storyNumber, nil)
+}
+
+// 创建实现指定故事并评审提交的代理执行任务，review 为空时不评审
+func NewAgentStoryTaskWithReview(projectGUID, agentType string, prompt *agent.RenderedPrompt, cliTool string, stageName common.DevStatus,
+	storyNumber string, review *agent.CodeReviewConfig) *asynq.Task {
 	payload := AgentExecuteTaskPayload{
 		ProjectGUID: projectGUID,
 		AgentType:   agentType,
-		Message:     message,
+		Message:     prompt.Content,
 		DevStage:    stageName,
 		CliTool:     cliTool,
+		Prompt:      prompt,
+		Language:    prompt.Language,
+		StoryNumber: storyNumber,
+		Review:      review,
 	}
 	return asynq.NewTask(common.TaskTypeAgentExecute,
 		payload.ToBytes(),
-		asynq.Queue(taskQueueDefault),
+		asynq.Queue(GetTaskQueue(common.TaskTypeAgentExecute)),
 		asynq.MaxRetry(taskMaxRetry),
 		asynq.Retention(taskRetentionHour))
 }
@@ -119,7 +134,7 @@ func NewAgentExecuteTaskWithCli(projectGUID, agentType, message, cliTool string,
 func NewProjectSetupTask(req *agent.SetupProjEnvReq) *asynq.Task {
 	return asynq.NewTask(common.TaskTypeAgentSetup,
 		req.ToBytes(),
-		asynq.Queue(taskQueueDefault),
+		asynq.Queue(GetTaskQueue(common.TaskTypeAgentSetup)),
 		asynq.MaxRetry(taskMaxRetry),
 		asynq.Retention(taskRetentionHour))
 }
@@ -128,7 +143,16 @@ func NewProjectSetupTask(req *agent.SetupProjEnvReq) *asynq.Task {
 func NewProjectDeployTask(req *agent.DeployReq) *asynq.Task {
 	return asynq.NewTask(common.TaskTypeProjectDeploy,
 		req.ToBytes(),
-		asynq.Queue(taskQueueDefault),
+		asynq.Queue(GetTaskQueue(common.TaskTypeProjectDeploy)),
+		asynq.MaxRetry(taskMaxRetry),
+		asynq.Retention(taskRetentionHour))
+}
+
+// 创建项目自动测试任务
+func NewProjectTestTask(payload *ProjectTestTaskPayload) *asynq.Task {
+	return asynq.NewTask(common.TaskTypeProjectTest,
+		payload.ToBytes(),
+		asynq.Queue(GetTaskQueue(common.TaskTypeProjectTest)),
 		asynq.MaxRetry(taskMaxRetry),
 		asynq.Retention(taskRetentionHour))
 }
@@ -137,7 +161,7 @@ func NewProjectDeployTask(req *agent.DeployReq) *asynq.Task {
 func NewAgentChatTask(req *agent.ChatReq) *asynq.Task {
 	return asynq.NewTask(common.TaskTypeAgentChat,
 		req.ToBytes(),
-		asynq.Queue(taskQueueDefault),
+		asynq.Queue(GetTaskQueue(common.TaskTypeAgentChat)),
 		asynq.MaxRetry(taskMaxRetry),
 		asynq.Retention(taskRetentionHour))
 }
@@ -151,7 +175,7 @@ func NewProjectStageTask(requireConfirm bool, projectGuid, stageName string) *as
 	}
 	return asynq.NewTask(common.TaskTypeProjectStage,
 		payload.ToBytes(),
-		asynq.Queue(taskQueueDefault),
+		asynq.Queue(GetTaskQueue(common.TaskTypeProjectStage)),
 		asynq.MaxRetry(taskMaxRetry),
 		asynq.Retention(taskRetentionHour))
 }
@@ -160,7 +184,7 @@ func NewProjectStageTask(requireConfirm bool, projectGuid, stageName string) *as
 func NewAgentTaskResponseTask(message *agent.AgentTaskStatusMessage) *asynq.Task {
 	return asynq.NewTask(common.TaskTypeAgentTaskResponse,
 		message.ToBytes(),
-		asynq.Queue(taskQueueDefault),
+		asynq.Queue(GetTaskQueue(common.TaskTypeAgentTaskResponse)),
 		asynq.MaxRetry(taskMaxRetry),
 		asynq.Retention(taskRetentionHour))
 }
@@ -168,22 +192,56 @@ func NewAgentTaskResponseTask(message *agent.AgentTaskStatusMessage) *asynq.Task
 // updateResult 是一个帮助函数，用于将任务进度更新到Redis。
 // 这里假设使用一个Redis Hash结构，key为`task:progress:<task_id>`。
 func UpdateResult(resultWriter *asynq.ResultWriter, status string, progress int, message string) {
+	UpdateResultWithGit(resultWriter, status, progress, message, nil)
+}
+
+// UpdateResultWithGit 更新任务进度，并附带阶段、故事分支的提交和合并结果
+func UpdateResultWithGit(resultWriter *asynq.ResultWriter, status string, progress int, message string, git *agent.GitBranchResult) {
+	writeResult(resultWriter, &TaskResult{Status: status, Progress: progress, Message: message, Git: git})
+}
+
+// UpdateResultWithFixAttempts 更新任务进度，并附带 Dev Agent 修复失败命令的记录
+func UpdateResultWithFixAttempts(resultWriter *asynq.ResultWriter, status string, progress int, message string, attempts []*agent.FixAttempt) {
+	writeResult(resultWriter, &TaskResult{Status: status, Progress: progress, Message: message, FixAttempts: attempts})
+}
+
+// UpdateResultWithDeploy 更新部署任务进度，并附带修复记录和各组件的健康检查结果
+func UpdateResultWithDeploy(resultWriter *asynq.ResultWriter, status string, progress int, message string,
+	attempts []*agent.FixAttempt, deploy *agent.DeployResp) {
+	writeResult(resultWriter, &TaskResult{Status: status, Progress: progress, Message: message, FixAttempts: attempts, Deploy: deploy})
+}
+
+// UpdateResultWithTestReport 更新自动测试任务进度，并附带修复记录和测试报告
+func UpdateResultWithTestReport(resultWriter *asynq.ResultWriter, status string, progress int, message string,
+	attempts []*agent.FixAttempt, report *agent.TestReport) {
+	writeResult(resultWriter, &TaskResult{Status: status, Progress: progress, Message: message, FixAttempts: attempts, TestReport: report})
+}
+
+// UpdateResultWithQAGate 更新 QA 评审任务进度，并附带故事分支的合并结果和门禁结论
+func UpdateResultWithQAGate(resultWriter *asynq.ResultWriter, status string, progress int, message string,
+	git *agent.GitBranchResult, gate *agent.QAGate) {
+	writeResult(resultWriter, &TaskResult{Status: status, Progress: progress, Message: message, Git: git, QAGate: gate})
+}
+
+// UpdateResultWithCodeReview 更新实现故事任务进度，并附带故事分支的合并结果和代码评审结果
+func UpdateResultWithCodeReview(resultWriter *asynq.ResultWriter, status string, progress int, message string,
+	git *agent.GitBranchResult, review *agent.CodeReview) {
+	writeResult(resultWriter, &TaskResult{Status: status, Progress: progress, Message: message, Git: git, CodeReview: review})
+}
+
+// writeResult 写入任务结果
+func writeResult(resultWriter *asynq.ResultWriter, data *TaskResult) {
 	if resultWriter == nil {
 		logger.Error("resultWriter is nil, can't update result")
 		return
 	}
 
-	data := TaskResult{
-		TaskID:    resultWriter.TaskID(),
-		Status:    status,
-		Progress:  progress,
-		Message:   message,
-		UpdatedAt: utils.GetCurrentTime(),
-	}
+	data.TaskID = resultWriter.TaskID()
+	data.UpdatedAt = utils.GetCurrentTime()
 	resultWriter.Write(data.ToBytes())
 	logger.Info("更新任务进度",
 		logger.String("taskID", resultWriter.TaskID()),
-		logger.String("status", status),
-		logger.Int("progress", progress),
-		logger.String("message", message))
+		logger.String("status", data.Status),
+		logger.Int("progress", data.Progress),
+		logger.String("message", data.Message))
 }
@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"strings"
+
+	"github.com/lighthought/app-maker/shared-models/common"
+)
+
+// CommitTrailers 写入提交信息的 App Maker trailer
+type CommitTrailers struct {
+	Stage string // 开发阶段
+	Task  string // 异步任务ID
+	Story string // 故事编号
+	Agent string // Agent 类型
+}
+
+// BuildCommitMessage 拼接提交信息：标题、正文和 trailer，为空的 trailer 不写入
+func BuildCommitMessage(subject, body string, trailers *CommitTrailers) string {
+	var builder strings.Builder
+	builder.WriteString(strings.TrimSpace(subject))
+	if body = strings.TrimSpace(body); body != "" {
+		builder.WriteString("\n\n")
+		builder.WriteString(body)
+	}
+	if trailers == nil {
+		return builder.String()
+	}
+
+	lines := make([]string, 0, 4)
+	for _, item := range [][2]string{
+		{common.CommitTrailerStage, trailers.Stage},
+		{common.CommitTrailerTask, trailers.Task},
+		{common.CommitTrailerStory, trailers.Story},
+		{common.CommitTrailerAgent, trailers.Agent},
+	} {
+		if item[1] != "" {
+			lines = append(lines, item[0]+": "+item[1])
+		}
+	}
+	if len(lines) > 0 {
+		builder.WriteString("\n\n")
+		builder.WriteString(strings.Join(lines, "\n"))
+	}
+	return builder.String()
+}
+
+// ParseCommitTrailers 从提交信息的最后一段解析 App Maker trailer，正文中同名的行不会被当作 trailer，
+// 最后一段不是 trailer 段或没有任何 App Maker trailer 时返回 nil
+func ParseCommitTrailers(message string) *CommitTrailers {
+	message = strings.TrimSpace(strings.ReplaceAll(message, "\r\n", "\n"))
+	paragraphs := strings.Split(message, "\n\n")
+	if len(paragraphs) < 2 {
+		return nil // 只有标题，没有 trailer 段
+	}
+
+	var trailers CommitTrailers
+	found := false
+	for _, line := range strings.Split(paragraphs[len(paragraphs)-1], "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil // trailer 段的每一行都必须是 Key: value
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case common.CommitTrailerStage:
+			trailers.Stage = value
+		case common.CommitTrailerTask:
+			trailers.Task = value
+		case common.CommitTrailerStory:
+			trailers.Story = value
+		case common.CommitTrailerAgent:
+			trailers.Agent = value
+		default:
+			continue
+		}
+		found = true
+	}
+	if !found {
+		return nil
+	}
+	return &trailers
+}
@@ -0,0 +1,79 @@
+package utils
+
+import "testing"
+
+func TestBuildCommitMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		subject  string
+		body     string
+		trailers *CommitTrailers
+		want     string
+	}{
+		{
+			name:    "subject only",
+			subject: "docs(pm): generate PRD",
+			want:    "docs(pm): generate PRD",
+		},
+		{
+			name:     "body and trailers",
+			subject:  "feat(dev): implement story 1.1",
+			body:     "added login page\n",
+			trailers: &CommitTrailers{Stage: "develop_story", Task: "task-1", Story: "1.1", Agent: "dev"},
+			want: "feat(dev): implement story 1.1\n\nadded login page\n\n" +
+				"App-Maker-Stage: develop_story\nApp-Maker-Task: task-1\nApp-Maker-Story: 1.1\nApp-Maker-Agent: dev",
+		},
+		{
+			name:     "empty trailers are skipped",
+			subject:  "chore: apply agent chat changes",
+			trailers: &CommitTrailers{Agent: "dev"},
+			want:     "chore: apply agent chat changes\n\nApp-Maker-Agent: dev",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BuildCommitMessage(tt.subject, tt.body, tt.trailers); got != tt.want {
+				t.Errorf("BuildCommitMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCommitTrailers(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    *CommitTrailers
+	}{
+		{
+			name:    "no trailers",
+			message: "fix typo",
+		},
+		{
+			name:    "trailers in last paragraph",
+			message: "feat(dev): implement story 1.1\n\nbody\n\nApp-Maker-Stage: develop_story\nApp-Maker-Story: 1.1\nSigned-off-by: John",
+			want:    &CommitTrailers{Stage: "develop_story", Story: "1.1"},
+		},
+		{
+			name:    "crlf line endings",
+			message: "docs: add brief\r\n\r\nApp-Maker-Stage: check_requirement\r\nApp-Maker-Agent: analyst\r\n",
+			want:    &CommitTrailers{Stage: "check_requirement", Agent: "analyst"},
+		},
+		{
+			name:    "trailer-like line in body is ignored",
+			message: "docs: explain trailers\n\nApp-Maker-Stage: fake\n\nsee the docs for details",
+		},
+		{
+			name:    "last paragraph with prose is not a trailer block",
+			message: "docs: explain trailers\n\nApp-Maker-Stage: fake\nthis line is prose",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseCommitTrailers(tt.message)
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("ParseCommitTrailers() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// 加密后密文的前缀，用于识别 EncryptString 生成的密文
+const encryptedValuePrefix = "enc:v1:"
+
+// IsEncryptedString 判断字符串是否为 EncryptString 生成的密文
+func IsEncryptedString(value string) bool {
+	return strings.HasPrefix(value, encryptedValuePrefix)
+}
+
+// EncryptString 使用 AES-GCM 加密字符串，密钥由 secret 经 SHA-256 派生，空字符串原样返回
+func EncryptString(secret, plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	gcm, err := newGCM(secret)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("生成随机数失败: %w", err)
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedValuePrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptString 解密 EncryptString 生成的密文，空字符串原样返回
+func DecryptString(secret, ciphertext string) (string, error) {
+	if ciphertext == "" {
+		return "", nil
+	}
+	if !IsEncryptedString(ciphertext) {
+		return "", fmt.Errorf("不是有效的密文")
+	}
+	gcm, err := newGCM(secret)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(ciphertext, encryptedValuePrefix))
+	if err != nil {
+		return "", fmt.Errorf("密文解码失败: %w", err)
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("密文长度不足")
+	}
+	nonce, data := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, data, nil)
+	if err != nil {
+		return "", fmt.Errorf("解密失败，密钥可能已变更: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// newGCM 根据 secret 创建 AES-256-GCM
+func newGCM(secret string) (cipher.AEAD, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("加密密钥为空")
+	}
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, fmt.Errorf("创建加密器失败: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,34 @@
+package utils
+
+import "testing"
+
+func TestEncryptDecryptString(t *testing.T) {
+	ciphertext, err := EncryptString("secret", "sk-test-token")
+	if err != nil {
+		t.Fatalf("EncryptString() err = %v", err)
+	}
+	if !IsEncryptedString(ciphertext) || ciphertext == "sk-test-token" {
+		t.Fatalf("EncryptString() = %q, want ciphertext", ciphertext)
+	}
+
+	again, _ := EncryptString("secret", "sk-test-token")
+	if again == ciphertext {
+		t.Error("EncryptString() should use a random nonce")
+	}
+
+	if got, err := DecryptString("secret", ciphertext); err != nil || got != "sk-test-token" {
+		t.Errorf("DecryptString() = %q, %v", got, err)
+	}
+	if _, err := DecryptString("other", ciphertext); err == nil {
+		t.Error("DecryptString() with wrong secret should fail")
+	}
+	if _, err := DecryptString("secret", "sk-plaintext"); err == nil {
+		t.Error("DecryptString() of plaintext should fail")
+	}
+	if _, err := EncryptString("", "sk-test-token"); err == nil {
+		t.Error("EncryptString() with empty secret should fail")
+	}
+	if got, err := EncryptString("secret", ""); err != nil || got != "" {
+		t.Errorf("EncryptString(\"\") = %q, %v", got, err)
+	}
+}